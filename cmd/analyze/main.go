@@ -0,0 +1,12 @@
+// Command analyze is equivalent to "slither analyze".
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run("analyze", os.Args[1:]))
+}
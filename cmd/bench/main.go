@@ -0,0 +1,12 @@
+// Command bench is equivalent to "slither bench".
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run("bench", os.Args[1:]))
+}
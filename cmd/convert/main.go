@@ -0,0 +1,12 @@
+// Command convert is equivalent to "slither convert" and is kept for existing scripts.
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run("convert", os.Args[1:]))
+}
@@ -0,0 +1,12 @@
+// Command eval is equivalent to "slither eval".
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run("eval", os.Args[1:]))
+}
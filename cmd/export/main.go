@@ -0,0 +1,12 @@
+// Command export is equivalent to "slither export".
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run("export", os.Args[1:]))
+}
@@ -0,0 +1,12 @@
+// Command selfplay is equivalent to "slither selfplay".
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run("selfplay", os.Args[1:]))
+}
@@ -0,0 +1,14 @@
+// Command slither is the single entry point for training, playing and
+// managing SlitherRL models. Run "slither help" for the list of subcommands.
+package main
+
+import (
+	"os"
+
+	"autonomous-snake/internal/cli"
+	_ "autonomous-snake/internal/cli/playcmd"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:]))
+}
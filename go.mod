@@ -2,7 +2,13 @@ module autonomous-snake
 
 go 1.24.0
 
-require github.com/hajimehoshi/ebiten/v2 v2.9.5
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/hajimehoshi/ebiten/v2 v2.9.5
+	go.etcd.io/bbolt v1.4.3
+	gonum.org/v1/gonum v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20250923094054-ea854a63cce1 // indirect
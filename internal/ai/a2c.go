@@ -0,0 +1,286 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+
+	"autonomous-snake/internal/config"
+)
+
+// A2CAgent learns with advantage actor-critic. It holds the shared actor
+// and critic; workers, each playing its own games, collect
+// A2C.RolloutSteps transitions on their own copy of the networks, compute
+// the actor-critic gradient from n-step advantages and apply it to the
+// shared networks, then take the updated weights back. With several
+// workers running in goroutines the updates interleave asynchronously
+// (A3C); see NewWorker.
+//
+// The agent is itself a worker playing on the shared networks, so on its
+// own it trains like any other Agent. A saved actor plays greedily like any
+// DQN model; the critic is saved beside it, see CriticPath.
+type A2CAgent struct {
+	Actor  *QNetwork
+	Critic *QNetwork
+
+	Gamma float64
+	A2C   config.A2CConfig
+
+	// MaxGradNorm > 0 clips each update's gradient to this norm
+	MaxGradNorm float64
+
+	// WeightDecay > 0 shrinks both networks' weights after every update,
+	// an L2 penalty, see config.RegularizationConfig
+	WeightDecay float64
+
+	// LRSchedule, when set, updates both networks' learning rate from
+	// StepCount before every update
+	LRSchedule LRSchedule
+
+	// StepCount counts environment steps across all workers
+	StepCount int
+
+	mu    sync.Mutex // Guards the shared networks, StepCount and stats
+	stats a2cStats
+	self  *A2CWorker
+	rng   *rand.Rand
+}
+
+// A2CStats summarizes the agent's recent updates
+type A2CStats struct {
+	Updates    int     // Updates applied so far, by all workers
+	PolicyLoss float64 // Mean policy gradient loss over the window
+	ValueLoss  float64 // Mean halved squared error of the critic over the window
+	Entropy    float64 // Mean policy entropy over the window
+}
+
+// a2cStats keeps the last DiagnosticsWindow updates' losses
+type a2cStats struct {
+	updates                          int
+	policyLoss, valueLoss, entropies rollingWindow
+}
+
+// NewA2CAgent creates an A2C agent with the given configuration
+func NewA2CAgent(cfg config.TrainingConfig, seed int64) *A2CAgent {
+	rng := rand.New(rand.NewSource(seed))
+	a := &A2CAgent{
+		Gamma:       cfg.Gamma,
+		A2C:         cfg.A2C,
+		MaxGradNorm: cfg.MaxGradNorm,
+		WeightDecay: cfg.Regularization.WeightDecay,
+		LRSchedule:  NewLRSchedule(cfg.LRSchedule, cfg.LearningRate),
+		stats: a2cStats{
+			policyLoss: newRollingWindow(DiagnosticsWindow),
+			valueLoss:  newRollingWindow(DiagnosticsWindow),
+			entropies:  newRollingWindow(DiagnosticsWindow),
+		},
+		rng: rng,
+	}
+	a.setNetworks(newActorCriticNetworks(cfg, rng))
+	return a
+}
+
+// setNetworks installs the shared networks and the agent's own worker
+func (a *A2CAgent) setNetworks(actor, critic *QNetwork) {
+	a.Actor, a.Critic = actor, critic
+	a.self = &A2CWorker{
+		actorCritic: newActorCritic(actor, critic),
+		Rollout:     NewRolloutBuffer(),
+		agent:       a,
+		rng:         a.rng,
+	}
+}
+
+// NewWorker creates a worker with its own copy of the networks, for
+// playing in another goroutine. Workers are safe to use concurrently with
+// each other, Save and Stats, but not with the agent's own Agent methods.
+func (a *A2CAgent) NewWorker(seed int64) *A2CWorker {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &A2CWorker{
+		actorCritic: newActorCritic(a.Actor.Clone(), a.Critic.Clone()),
+		Rollout:     NewRolloutBuffer(),
+		agent:       a,
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+// SelectAction samples an action from the policy
+func (a *A2CAgent) SelectAction(state []float64) Action {
+	return a.self.SelectAction(state)
+}
+
+// SelectActionGreedy returns the most likely action
+func (a *A2CAgent) SelectActionGreedy(state []float64) Action {
+	return Action(MaxIndex(a.self.policy(state)))
+}
+
+// Remember stores a transition of snake 0, see RememberSnake
+func (a *A2CAgent) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
+	a.self.Remember(state, action, reward, nextState, done)
+}
+
+// RememberSnake stores a transition on the snake's trajectory
+func (a *A2CAgent) RememberSnake(snakeID int, state []float64, action Action, reward float64, nextState []float64, done bool) {
+	a.self.RememberSnake(snakeID, state, action, reward, nextState, done)
+}
+
+// Train counts a step and updates the networks once a full rollout has
+// been collected
+func (a *A2CAgent) Train() float64 {
+	return a.self.Train()
+}
+
+// DecayEpsilon marks the end of an episode; A2C has no epsilon
+func (a *A2CAgent) DecayEpsilon() {
+	a.self.DecayEpsilon()
+}
+
+// apply adds worker w's accumulated gradient, the sum over n transitions,
+// to the shared networks and copies the result back to w's networks
+func (a *A2CAgent) apply(w *A2CWorker, n int, policyLoss, valueLoss, entropy float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.StepCount += w.steps
+	w.steps = 0
+	if a.LRSchedule != nil {
+		lr := a.LRSchedule.Rate(a.StepCount)
+		a.Actor.LearningRate, a.Critic.LearningRate = lr, lr
+	}
+	scale := 1.0 / float64(n)
+	applyClipped(a.Actor, w.actorGrads, scale, a.MaxGradNorm)
+	applyClipped(a.Critic, w.criticGrads, scale, a.MaxGradNorm)
+	a.Actor.decayWeights(a.WeightDecay)
+	a.Critic.decayWeights(a.WeightDecay)
+	if w.Actor != a.Actor {
+		w.Actor.CopyFrom(a.Actor)
+		w.Critic.CopyFrom(a.Critic)
+	}
+
+	a.stats.updates++
+	a.stats.policyLoss.add(policyLoss * scale)
+	a.stats.valueLoss.add(valueLoss * scale)
+	a.stats.entropies.add(entropy * scale)
+}
+
+// Stats summarizes the recent updates of all workers
+func (a *A2CAgent) Stats() A2CStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return A2CStats{
+		Updates:    a.stats.updates,
+		PolicyLoss: mean(a.stats.policyLoss.values),
+		ValueLoss:  mean(a.stats.valueLoss.values),
+		Entropy:    mean(a.stats.entropies.values),
+	}
+}
+
+// LearningRate returns the actor's current learning rate
+func (a *A2CAgent) LearningRate() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.Actor.LearningRate
+}
+
+// Encoder returns the state encoder the agent's networks expect
+func (a *A2CAgent) Encoder() Encoder {
+	return a.Actor.StateEncoder()
+}
+
+// Save writes the actor to path and the critic to CriticPath(path)
+func (a *A2CAgent) Save(path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.self.save(path)
+}
+
+// Snapshot returns a copy of the shared actor
+func (a *A2CAgent) Snapshot() *QNetwork {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.Actor.Clone()
+}
+
+// Load replaces the shared networks like PPOAgent.Load. Workers created
+// earlier keep playing on copies of the old networks.
+func (a *A2CAgent) Load(path string) error {
+	actor, critic, err := loadActorCritic(path, a.rng.Int63())
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.setNetworks(actor, critic)
+	return nil
+}
+
+// A2CWorker collects rollouts for an A2CAgent and applies its gradients to
+// the agent's shared networks. It implements Agent, so the training loop
+// plays with it like with any agent; Save and Load act on the shared agent.
+type A2CWorker struct {
+	actorCritic
+	Rollout *RolloutBuffer
+
+	agent *A2CAgent
+	steps int // Environment steps since the last update
+	rng   *rand.Rand
+}
+
+// SelectAction samples an action from the worker's copy of the policy
+func (w *A2CWorker) SelectAction(state []float64) Action {
+	return w.sample(state, w.rng)
+}
+
+// Remember stores a transition of snake 0, see RememberSnake
+func (w *A2CWorker) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
+	w.RememberSnake(0, state, action, reward, nextState, done)
+}
+
+// RememberSnake stores a transition on the snake's trajectory, along with
+// the critic's values of its states
+func (w *A2CWorker) RememberSnake(snakeID int, state []float64, action Action, reward float64, nextState []float64, done bool) {
+	w.Rollout.Add(snakeID, w.record(state, action, reward, nextState, done))
+}
+
+// Train counts a step and, once the worker has collected a full rollout,
+// applies its gradient to the shared networks. It returns the rollout's
+// mean policy loss, or 0.
+func (w *A2CWorker) Train() float64 {
+	w.steps++
+	if w.Rollout.Len() < w.agent.A2C.RolloutSteps {
+		return 0
+	}
+	steps := w.Rollout.computeAdvantages(w.agent.Gamma, w.agent.A2C.Lambda)
+	w.zeroGradients()
+	policyLoss, valueLoss, entropy := 0.0, 0.0, 0.0
+	for _, s := range steps {
+		probs := w.policy(s.State)
+		policyLoss -= s.Advantage * math.Log(math.Max(probs[s.Action], minProb))
+		entropy += w.accumulatePolicy(probs, s.Action, s.Advantage, w.agent.A2C.EntropyCoef)
+		valueLoss += w.accumulateValue(s.State, s.Return)
+	}
+	w.agent.apply(w, len(steps), policyLoss, valueLoss, entropy)
+	w.Rollout.Clear()
+	return policyLoss / float64(len(steps))
+}
+
+// DecayEpsilon marks the end of an episode, closing the open trajectories
+func (w *A2CWorker) DecayEpsilon() {
+	w.Rollout.EndEpisode()
+}
+
+// Save saves the shared agent, see A2CAgent.Save
+func (w *A2CWorker) Save(path string) error {
+	return w.agent.Save(path)
+}
+
+// Load loads the shared agent, see A2CAgent.Load
+func (w *A2CWorker) Load(path string) error {
+	return w.agent.Load(path)
+}
+
+// Encoder returns the state encoder the networks expect
+func (w *A2CWorker) Encoder() Encoder {
+	return w.Actor.StateEncoder()
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestA2CWorkers(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	cfg.A2C.RolloutSteps = 4
+	agent := NewA2CAgent(cfg, 1)
+	before := agent.Actor.Clone()
+	w1, w2 := agent.NewWorker(2), agent.NewWorker(3)
+
+	state := make([]float64, cfg.InputSize)
+	for i := range state {
+		state[i] = float64(i%5) / 4
+	}
+	for step := 0; step < cfg.A2C.RolloutSteps; step++ {
+		w1.RememberSnake(step%2, state, w1.SelectAction(state), 1, state, false)
+		w1.Train()
+	}
+
+	// The rollout's update lands on the shared networks and comes back to
+	// the worker that made it, while the other worker plays on its copy
+	if equalFloats(agent.Actor.W3, before.W3) {
+		t.Fatal("worker update left the shared actor unchanged")
+	}
+	if !equalFloats(w1.Actor.W3, agent.Actor.W3) || !equalFloats(w1.Critic.W3, agent.Critic.W3) {
+		t.Error("updating worker didn't take the shared weights back")
+	}
+	if !equalFloats(w2.Actor.W3, before.W3) {
+		t.Error("idle worker's copy changed")
+	}
+	if agent.StepCount != cfg.A2C.RolloutSteps || w1.Rollout.Len() != 0 {
+		t.Errorf("step count %d, rollout %d after one update", agent.StepCount, w1.Rollout.Len())
+	}
+	if stats := agent.Stats(); stats.Updates != 1 || stats.Entropy <= 0 {
+		t.Errorf("stats after one update: %+v", stats)
+	}
+}
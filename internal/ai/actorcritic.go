@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"autonomous-snake/internal/config"
+)
+
+// actorCritic pairs a policy network (the actor), whose outputs are action
+// logits, with a state value network (the critic), plus the scratch space
+// to run and train them. It is the shared core of the PPO and A2C agents.
+//
+// The actor is an ordinary QNetwork, so a saved actor plays greedily like
+// any DQN model. The critic is saved beside it, see CriticPath.
+type actorCritic struct {
+	Actor  *QNetwork
+	Critic *QNetwork
+
+	// Scratch buffers
+	actorCache, criticCache *forwardCache
+	actorGrads, criticGrads *gradients
+	probs, dLogits, dValue  []float64
+}
+
+// newActorCriticNetworks creates a fresh actor and critic for cfg
+func newActorCriticNetworks(cfg config.TrainingConfig, rng *rand.Rand) (actor, critic *QNetwork) {
+	if shape, ok := convShapeFor(cfg); ok {
+		actor = NewConvQNetwork(shape, cfg.HiddenSize2, cfg.OutputSize, cfg.LearningRate, rng.Int63())
+		critic = NewConvQNetwork(shape, cfg.HiddenSize2, 1, cfg.LearningRate, rng.Int63())
+	} else {
+		actor = NewQNetwork(cfg.InputSize, cfg.HiddenSize1, cfg.HiddenSize2, cfg.OutputSize, cfg.LearningRate, rng.Int63())
+		critic = NewQNetwork(cfg.InputSize, cfg.HiddenSize1, cfg.HiddenSize2, 1, cfg.LearningRate, rng.Int63())
+	}
+	if cfg.Encoder != "" {
+		actor.Encoder = cfg.Encoder
+		critic.Encoder = cfg.Encoder
+	}
+	return actor, critic
+}
+
+// newActorCritic wraps the networks and sizes the scratch buffers
+func newActorCritic(actor, critic *QNetwork) actorCritic {
+	return actorCritic{
+		Actor:       actor,
+		Critic:      critic,
+		actorCache:  newForwardCache(actor),
+		criticCache: newForwardCache(critic),
+		actorGrads:  newGradients(actor),
+		criticGrads: newGradients(critic),
+		probs:       make([]float64, actor.OutputSize),
+		dLogits:     make([]float64, actor.OutputSize),
+		dValue:      make([]float64, 1),
+	}
+}
+
+// policy runs the actor and returns its action probabilities for state.
+// The slice is reused by the next call.
+func (ac *actorCritic) policy(state []float64) []float64 {
+	softmaxInto(ac.probs, ac.Actor.forwardWith(ac.actorCache, state))
+	return ac.probs
+}
+
+// value returns the critic's estimate for state
+func (ac *actorCritic) value(state []float64) float64 {
+	return ac.Critic.forwardWith(ac.criticCache, state)[0]
+}
+
+// sample draws an action from the policy
+func (ac *actorCritic) sample(state []float64, rng *rand.Rand) Action {
+	probs := ac.policy(state)
+	u := rng.Float64()
+	for j, p := range probs {
+		if u < p {
+			return Action(j)
+		}
+		u -= p
+	}
+	return Action(len(probs) - 1)
+}
+
+// record builds the rollout step for a transition, with the policy's
+// probability of the action and the critic's values as they are now
+func (ac *actorCritic) record(state []float64, action Action, reward float64, nextState []float64, done bool) rolloutStep {
+	probs := ac.policy(state)
+	return rolloutStep{
+		State:     append([]float64(nil), state...),
+		Action:    action,
+		Reward:    reward,
+		Done:      done,
+		LogProb:   math.Log(math.Max(probs[action], minProb)),
+		Value:     ac.value(state),
+		NextValue: ac.value(nextState),
+	}
+}
+
+// accumulatePolicy adds the actor gradient of -weight·log π(action) minus
+// entropyCoef times the policy entropy, for the probabilities of the last
+// policy call, and returns the entropy. For A2C the weight is the
+// advantage; PPO's ratio·advantage surrogate passes the advantage times the
+// ratio.
+func (ac *actorCritic) accumulatePolicy(probs []float64, action Action, weight, entropyCoef float64) float64 {
+	entropy := 0.0
+	for _, p := range probs {
+		if p > 0 {
+			entropy -= p * math.Log(p)
+		}
+	}
+
+	// d(loss)/d(logit j): the policy term is -weight·(1[j=a] - p_j) and
+	// the entropy bonus -c·H adds c·p_j·(log p_j + H)
+	for j, p := range probs {
+		d := entropyCoef * p * (math.Log(math.Max(p, minProb)) + entropy)
+		if weight != 0 {
+			indicator := 0.0
+			if j == int(action) {
+				indicator = 1
+			}
+			d -= weight * (indicator - p)
+		}
+		ac.dLogits[j] = d
+	}
+	ac.Actor.accumulateGradients(ac.actorCache, ac.dLogits, ac.actorGrads)
+	return entropy
+}
+
+// accumulateValue adds the critic gradient of the halved squared error
+// between its value of state and target, and returns that loss
+func (ac *actorCritic) accumulateValue(state []float64, target float64) float64 {
+	diff := ac.value(state) - target
+	ac.dValue[0] = diff
+	ac.Critic.accumulateGradients(ac.criticCache, ac.dValue, ac.criticGrads)
+	return 0.5 * diff * diff
+}
+
+// zeroGradients resets both networks' gradient buffers
+func (ac *actorCritic) zeroGradients() {
+	ac.actorGrads.zero()
+	ac.criticGrads.zero()
+}
+
+// applyClipped applies the gradient g, scaled by scale and clipped to
+// maxNorm when it is positive, to net
+func applyClipped(net *QNetwork, g *gradients, scale, maxNorm float64) {
+	if maxNorm > 0 {
+		if norm := gradientNorm(g, scale); norm > maxNorm {
+			scale *= maxNorm / norm
+		}
+	}
+	net.applyGradients(g, scale)
+}
+
+// CriticPath returns where the critic of an actor saved at path is kept:
+// the same name with ".critic" before the extension
+func CriticPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".critic" + ext
+}
+
+// save writes the actor to path and the critic to CriticPath(path)
+func (ac *actorCritic) save(path string) error {
+	if err := ac.Actor.Save(path); err != nil {
+		return err
+	}
+	return ac.Critic.Save(CriticPath(path))
+}
+
+// loadActorCritic loads the actor saved at path, which may be any standard
+// or dueling network, e.g. a DQN model to start from. The critic is loaded
+// from CriticPath(path) if it exists and is created from seed otherwise.
+func loadActorCritic(path string, seed int64) (actor, critic *QNetwork, err error) {
+	actor, err = LoadNetwork(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actor.Atoms > 0 || actor.Recurrent || actor.OutputSize != NumActions {
+		return nil, nil, fmt.Errorf("%s is not a policy network", path)
+	}
+
+	critic, err = LoadNetwork(CriticPath(path))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if actor.convolutional() {
+			critic = NewConvQNetwork(actor.Conv, actor.HiddenSize2, 1, actor.LearningRate, seed)
+		} else {
+			critic = NewQNetwork(actor.InputSize, actor.HiddenSize1, actor.HiddenSize2, 1, actor.LearningRate, seed)
+		}
+		critic.Encoder = actor.Encoder
+	case err != nil:
+		return nil, nil, fmt.Errorf("critic: %w", err)
+	case critic.OutputSize != 1 || critic.InputSize != actor.InputSize:
+		return nil, nil, fmt.Errorf("critic %s doesn't fit actor %s", CriticPath(path), path)
+	}
+	return actor, critic, nil
+}
@@ -1,17 +1,61 @@
 package ai
 
 import (
+	"fmt"
+	"math"
 	"math/rand"
+	"sync"
 
 	"autonomous-snake/internal/config"
 )
 
+// Agent is a learning agent as driven by the training loop: it picks
+// actions for encoded states, stores transitions and trains on them.
+// Train is called after every step and DecayEpsilon after every episode.
+type Agent interface {
+	SelectAction(state []float64) Action
+	Remember(state []float64, action Action, reward float64, nextState []float64, done bool)
+	Train() float64
+	DecayEpsilon()
+	Save(path string) error
+	Load(path string) error
+	Encoder() Encoder
+}
+
+// SnakeAgent is an Agent that keeps each snake's transitions apart, as
+// on-policy methods need whole trajectories. Training loops call
+// RememberSnake instead of Remember when an agent provides it.
+type SnakeAgent interface {
+	Agent
+	RememberSnake(snakeID int, state []float64, action Action, reward float64, nextState []float64, done bool)
+}
+
+// RecurrentAgent is a SnakeAgent that remembers each snake's earlier states
+// when acting. Training loops call SelectActionSnake instead of
+// SelectAction when an agent provides it.
+type RecurrentAgent interface {
+	SnakeAgent
+	SelectActionSnake(snakeID int, state []float64) Action
+}
+
+// MaskedAgent is an Agent that can choose among a subset of actions.
+// Training loops with action masking call SelectActionMasked instead of
+// SelectAction when an agent provides it.
+type MaskedAgent interface {
+	Agent
+	SelectActionMasked(state []float64, mask ActionMask) Action
+}
+
 // DQNAgent implements the Deep Q-Network algorithm
 type DQNAgent struct {
 	PolicyNet    *QNetwork
 	TargetNet    *QNetwork
 	ReplayBuffer *ReplayBuffer
 
+	// Prioritized, when set, shares ReplayBuffer's storage and samples it
+	// by TD error; see PrioritizedReplayBuffer
+	Prioritized *PrioritizedReplayBuffer
+
 	// Hyperparameters
 	Gamma        float64 // Discount factor
 	Epsilon      float64 // Current exploration rate
@@ -19,19 +63,77 @@ type DQNAgent struct {
 	EpsilonDecay float64
 	BatchSize    int
 
+	// EpsilonSchedule, when set, recomputes Epsilon from StepCount on every
+	// Train call and DecayEpsilon does nothing
+	EpsilonSchedule EpsilonSchedule
+
+	// DoubleDQN picks the next action with the policy network and values
+	// it with the target network instead of taking the target's max,
+	// which curbs overestimated Q-values
+	DoubleDQN bool
+
+	// Loss selects Huber loss and gradient clipping for training updates
+	Loss LossOptions
+
+	// Conservative > 0 adds the CQL penalty of that weight to every
+	// update, for training from logged transitions only; see
+	// conservativeLoss
+	Conservative float64
+
+	// Regularization sets the policy network's dropout and the weight
+	// decay applied after every batch
+	Regularization config.RegularizationConfig
+
+	// LRSchedule, when set, updates the policy network's learning rate
+	// from StepCount before every batch
+	LRSchedule LRSchedule
+
 	// Training state
 	StepCount     int
 	TargetUpdate  int // Steps between target network updates
 	TrainInterval int // Steps between training updates
 
+	// GradWorkers > 1 splits each batch across that many goroutines and
+	// applies one averaged update. Otherwise samples are applied one by one,
+	// unless MiniBatch asks for the averaged update on a single goroutine.
+	GradWorkers int
+	MiniBatch   bool
+	workers     []*gradWorker
+
+	// Recent losses, TD errors and gradient norms, see Diagnostics
+	stats    *trainingStats
+	tdErrors []float64 // Scratch for the current batch's TD errors
+
 	rng *rand.Rand
 }
 
+// gradWorker holds one goroutine's private buffers for parallel training
+type gradWorker struct {
+	policyCache *forwardCache
+	targetCache *forwardCache
+	dOutput     []float64
+	grads       *gradients
+	loss        float64
+}
+
 // NewDQNAgent creates a new DQN agent with the given configuration
 func NewDQNAgent(cfg config.TrainingConfig, seed int64) *DQNAgent {
+	newNetwork := NewQNetwork
+	if cfg.Dueling {
+		newNetwork = NewDuelingQNetwork
+	}
+	return newAgent(cfg, seed, newNetwork)
+}
+
+// networkConstructor builds a network with the given dimensions, learning
+// rate and seed, like NewQNetwork
+type networkConstructor func(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork
+
+// newAgent creates an agent whose policy network is built by newNetwork
+func newAgent(cfg config.TrainingConfig, seed int64, newNetwork networkConstructor) *DQNAgent {
 	rng := rand.New(rand.NewSource(seed))
 
-	policyNet := NewQNetwork(
+	policyNet := newNetwork(
 		cfg.InputSize,
 		cfg.HiddenSize1,
 		cfg.HiddenSize2,
@@ -39,25 +141,59 @@ func NewDQNAgent(cfg config.TrainingConfig, seed int64) *DQNAgent {
 		cfg.LearningRate,
 		rng.Int63(),
 	)
+	if cfg.Encoder != "" {
+		policyNet.Encoder = cfg.Encoder
+	}
+	if shape, ok := convShapeFor(cfg); ok {
+		policyNet.makeConvolutional(shape)
+	}
+	if cfg.Noisy {
+		policyNet.MakeNoisy(NoisySigma0)
+	}
+	policyNet.SetDropout(cfg.Regularization.Dropout)
 
 	targetNet := policyNet.Clone()
 
-	replayBuffer := NewReplayBuffer(cfg.BufferSize, rng.Int63())
+	var prioritized *PrioritizedReplayBuffer
+	var replayBuffer *ReplayBuffer
+	if cfg.PrioritizedReplay {
+		prioritized = NewPrioritizedReplayBuffer(cfg.BufferSize, cfg.PriorityAlpha, cfg.PriorityBeta, rng.Int63())
+		replayBuffer = prioritized.ReplayBuffer
+	} else {
+		replayBuffer = NewReplayBuffer(cfg.BufferSize, rng.Int63())
+	}
 
-	return &DQNAgent{
+	agent := &DQNAgent{
 		PolicyNet:     policyNet,
 		TargetNet:     targetNet,
 		ReplayBuffer:  replayBuffer,
+		Prioritized:   prioritized,
 		Gamma:         cfg.Gamma,
 		Epsilon:       cfg.EpsilonStart,
 		EpsilonMin:    cfg.EpsilonMin,
 		EpsilonDecay:  cfg.EpsilonDecay,
 		BatchSize:     cfg.BatchSize,
+		DoubleDQN:     cfg.DoubleDQN,
+		Loss:          LossOptions{HuberDelta: cfg.HuberDelta, MaxGradNorm: cfg.MaxGradNorm},
+		Conservative:  cfg.Conservative,
+		LRSchedule:    NewLRSchedule(cfg.LRSchedule, cfg.LearningRate),
 		StepCount:     0,
 		TargetUpdate:  cfg.TargetUpdate,
 		TrainInterval: 4, // Train every 4 steps
+		GradWorkers:   cfg.GradWorkers,
+		MiniBatch:     cfg.MiniBatch,
+		stats:         newTrainingStats(cfg.BatchSize),
 		rng:           rng,
 	}
+	agent.Regularization = cfg.Regularization
+	agent.EpsilonSchedule = NewEpsilonSchedule(cfg.EpsilonSchedule, cfg.EpsilonStart, cfg.EpsilonMin)
+	if cfg.Noisy {
+		// Weight noise replaces epsilon-greedy exploration
+		policyNet.SetNoise(true)
+		agent.Epsilon, agent.EpsilonMin = 0, 0
+		agent.EpsilonSchedule = nil
+	}
+	return agent
 }
 
 // SelectAction chooses an action using epsilon-greedy policy
@@ -68,19 +204,45 @@ func (a *DQNAgent) SelectAction(state []float64) Action {
 	}
 
 	// Exploit: choose best action according to Q-network
-	qValues := a.PolicyNet.Forward(state)
-	return Action(MaxIndex(qValues))
+	return a.SelectActionGreedy(state)
 }
 
-// SelectActionGreedy chooses the best action (no exploration)
+// SelectActionGreedy chooses the best action (no exploration). The
+// Q-values stay in the network's scratch buffers, so it allocates nothing.
 func (a *DQNAgent) SelectActionGreedy(state []float64) Action {
-	qValues := a.PolicyNet.Forward(state)
+	qValues, _ := a.PolicyNet.ForwardWithCache(state)
 	return Action(MaxIndex(qValues))
 }
 
+// SelectActionMasked chooses an action like SelectAction, among the
+// actions mask allows
+func (a *DQNAgent) SelectActionMasked(state []float64, mask ActionMask) Action {
+	if a.rng.Float64() < a.Epsilon {
+		return mask.Random(a.rng)
+	}
+	return a.SelectActionGreedyMasked(state, mask)
+}
+
+// SelectActionGreedyMasked chooses the best action mask allows
+func (a *DQNAgent) SelectActionGreedyMasked(state []float64, mask ActionMask) Action {
+	qValues, _ := a.PolicyNet.ForwardWithCache(state)
+	return mask.Best(qValues)
+}
+
+// SelectActionsGreedy picks the best action for each state with a single
+// batched forward pass
+func (a *DQNAgent) SelectActionsGreedy(states [][]float64) []Action {
+	qValues := a.PolicyNet.ForwardBatch(states)
+	actions := make([]Action, len(qValues))
+	for i, q := range qValues {
+		actions[i] = Action(MaxIndex(q))
+	}
+	return actions
+}
+
 // Remember stores an experience in the replay buffer
 func (a *DQNAgent) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
-	a.ReplayBuffer.Add(Experience{
+	a.remember(Experience{
 		State:     state,
 		Action:    action,
 		Reward:    reward,
@@ -89,12 +251,30 @@ func (a *DQNAgent) Remember(state []float64, action Action, reward float64, next
 	})
 }
 
+// remember stores exp in whichever replay buffer the agent uses
+func (a *DQNAgent) remember(exp Experience) {
+	if a.Prioritized != nil {
+		a.Prioritized.Add(exp)
+		return
+	}
+	a.ReplayBuffer.Add(exp)
+}
+
 // Train performs a training step if enough experiences are available
 func (a *DQNAgent) Train() float64 {
+	return a.trainStep(a.ReplayBuffer.Size(), a.TrainBatch)
+}
+
+// trainStep counts a step and, when one is due and the stored transitions
+// fill a batch, runs trainBatch and syncs the target network
+func (a *DQNAgent) trainStep(stored int, trainBatch func() float64) float64 {
 	a.StepCount++
+	if a.EpsilonSchedule != nil {
+		a.Epsilon = a.EpsilonSchedule.Epsilon(a.StepCount)
+	}
 
 	// Don't train if not enough experiences
-	if a.ReplayBuffer.Size() < a.BatchSize {
+	if stored < a.BatchSize {
 		return 0.0
 	}
 
@@ -103,48 +283,204 @@ func (a *DQNAgent) Train() float64 {
 		return 0.0
 	}
 
-	// Sample batch
-	batch := a.ReplayBuffer.Sample(a.BatchSize)
-
-	// Train on batch
-	totalLoss := 0.0
-	for _, exp := range batch {
-		loss := a.trainOnExperience(exp)
-		totalLoss += loss
+	if a.LRSchedule != nil {
+		a.PolicyNet.LearningRate = a.LRSchedule.Rate(a.StepCount)
 	}
+	loss := trainBatch()
 
 	// Update target network periodically
 	if a.StepCount%a.TargetUpdate == 0 {
 		a.UpdateTargetNetwork()
 	}
 
-	return totalLoss / float64(len(batch))
+	return loss
 }
 
-// trainOnExperience trains on a single experience
-func (a *DQNAgent) trainOnExperience(exp Experience) float64 {
-	// Compute target Q-value
-	var targetQ float64
-	if exp.Done {
-		targetQ = exp.Reward
+// TrainBatch samples one batch from the replay buffer and applies an update,
+// leaving the step count and target network alone. The buffer must hold at
+// least BatchSize experiences. With prioritized replay each sample's loss is
+// scaled by its importance sampling weight and the sampled priorities are
+// updated from the new TD errors.
+func (a *DQNAgent) TrainBatch() float64 {
+	if a.Conservative > 0 {
+		return a.trainBatch(a.conservativeLoss)
+	}
+	return a.trainBatch(a.dqnLoss)
+}
+
+// sampleLoss computes one experience's loss, scaled by weight, and its
+// absolute TD error, running the networks with the given buffers. It
+// leaves the gradient of the loss w.r.t. the policy network's output in
+// dOutput, after a forward pass of the experience's state through
+// policyCache.
+type sampleLoss func(exp Experience, weight float64, policyCache, targetCache *forwardCache, dOutput []float64) (float64, float64)
+
+// trainBatch samples a batch and applies the update computed by computeLoss
+func (a *DQNAgent) trainBatch(computeLoss sampleLoss) float64 {
+	// Sample batch
+	var batch []Experience
+	var indices []int
+	var weights []float64
+	if a.Prioritized != nil {
+		batch, indices, weights = a.Prioritized.Sample(a.BatchSize)
+	} else {
+		batch = a.ReplayBuffer.Sample(a.BatchSize)
+	}
+	if cap(a.tdErrors) < len(batch) {
+		a.tdErrors = make([]float64, len(batch))
+	}
+	tdErrors := a.tdErrors[:len(batch)]
+
+	// Train on batch
+	totalLoss := 0.0
+	if a.GradWorkers > 1 || a.MiniBatch {
+		totalLoss = a.trainParallel(batch, weights, tdErrors, computeLoss)
 	} else {
-		// Use target network for stability (Double DQN style)
-		nextQValues := a.TargetNet.Forward(exp.NextState)
-		maxNextQ := Max(nextQValues)
-		targetQ = exp.Reward + a.Gamma*maxNextQ
+		// Samples are applied one at a time, so the batch gradient is
+		// recovered from how far the weights moved
+		a.stats.snapshotParams(a.PolicyNet)
+		policyCache, targetCache := a.PolicyNet.scratch(), a.TargetNet.scratch()
+		for i, exp := range batch {
+			weight := 1.0
+			if weights != nil {
+				weight = weights[i]
+			}
+			loss, tdError := computeLoss(exp, weight, policyCache, targetCache, policyCache.dOutput)
+			a.PolicyNet.backwardOutput(policyCache, a.Loss.MaxGradNorm)
+			totalLoss += loss
+			tdErrors[i] = tdError
+		}
+		if lr := a.PolicyNet.LearningRate; lr > 0 {
+			a.stats.gradNorms.add(a.stats.updateNorm(a.PolicyNet) / (lr * float64(len(batch))))
+		}
+	}
+	a.PolicyNet.decayWeights(a.Regularization.WeightDecay)
+	for _, e := range tdErrors {
+		a.stats.tdErrors.add(e)
 	}
+	if a.Prioritized != nil {
+		a.Prioritized.UpdatePriorities(indices, tdErrors)
+	}
+
+	loss := totalLoss / float64(len(batch))
+	a.stats.updates++
+	a.stats.losses.add(loss)
+	return loss
+}
+
+// targetValue computes the TD target for an experience, running the
+// networks with the given buffers
+func (a *DQNAgent) targetValue(exp Experience, policyCache, targetCache *forwardCache) float64 {
+	if exp.Done {
+		return exp.Reward
+	}
+	// Use target network for stability
+	nextQValues := a.TargetNet.forwardWith(targetCache, exp.NextState)
+	if a.DoubleDQN {
+		next := MaxIndex(a.PolicyNet.forwardWith(policyCache, exp.NextState))
+		return exp.Reward + a.Gamma*nextQValues[next]
+	}
+	maxNextQ := Max(nextQValues)
+	return exp.Reward + a.Gamma*maxNextQ
+}
+
+// dqnLoss is the one-step Q-learning loss, a sampleLoss: the TD error of
+// the taken action's Q-value
+func (a *DQNAgent) dqnLoss(exp Experience, weight float64, policyCache, targetCache *forwardCache, dOutput []float64) (float64, float64) {
+	// Compute target Q-value
+	targetQ := a.targetValue(exp, policyCache, targetCache)
 
 	// Forward pass with cache
-	output, cache := a.PolicyNet.ForwardWithCache(exp.State)
+	output := a.PolicyNet.forwardWith(policyCache, exp.State)
 
-	// Compute loss for logging
-	currentQ := output[exp.Action]
-	loss := (currentQ - targetQ) * (currentQ - targetQ) * 0.5
+	diff := output[exp.Action] - targetQ
+	loss, grad := a.Loss.Loss(diff)
 
-	// Backward pass
-	a.PolicyNet.Backward(cache, output, int(exp.Action), targetQ)
+	// Only the taken action has an error
+	for j := range dOutput {
+		dOutput[j] = 0
+	}
+	dOutput[exp.Action] = weight * grad
 
-	return loss
+	return weight * loss, math.Abs(diff)
+}
+
+// trainParallel computes gradients for the batch across GradWorkers
+// goroutines, each with private activation and gradient buffers, then merges
+// them and applies a single update averaged over the batch. Each sample's
+// loss comes from computeLoss, weighted by weights when it is non-nil, and
+// its TD error is written to tdErrors. With one worker the gradients are
+// accumulated on the calling goroutine.
+func (a *DQNAgent) trainParallel(batch []Experience, weights, tdErrors []float64, computeLoss sampleLoss) float64 {
+	numWorkers := max(a.GradWorkers, 1)
+	if numWorkers > len(batch) {
+		numWorkers = len(batch)
+	}
+	for len(a.workers) < numWorkers {
+		a.workers = append(a.workers, &gradWorker{
+			policyCache: newForwardCache(a.PolicyNet),
+			targetCache: newForwardCache(a.TargetNet),
+			dOutput:     make([]float64, a.PolicyNet.OutputSize),
+			grads:       newGradients(a.PolicyNet),
+		})
+	}
+
+	chunk := (len(batch) + numWorkers - 1) / numWorkers
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		start := w * chunk
+		end := start + chunk
+		if end > len(batch) {
+			end = len(batch)
+		}
+		worker := a.workers[w]
+		worker.grads.zero()
+		worker.loss = 0
+		if start >= end {
+			continue
+		}
+
+		accumulate := func() {
+			for i, exp := range batch[start:end] {
+				weight := 1.0
+				if weights != nil {
+					weight = weights[start+i]
+				}
+				loss, tdError := computeLoss(exp, weight, worker.policyCache, worker.targetCache, worker.dOutput)
+				worker.loss += loss
+				tdErrors[start+i] = tdError
+				a.PolicyNet.accumulateGradients(worker.policyCache, worker.dOutput, worker.grads)
+			}
+		}
+		if numWorkers == 1 {
+			accumulate()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accumulate()
+		}()
+	}
+	wg.Wait()
+
+	// Merge into the first worker's accumulator and apply the mean gradient
+	total := a.workers[0]
+	totalLoss := total.loss
+	for _, worker := range a.workers[1:numWorkers] {
+		total.grads.add(worker.grads)
+		totalLoss += worker.loss
+	}
+	// The diagnostics record the norm before clipping
+	scale := 1.0 / float64(len(batch))
+	norm := gradientNorm(total.grads, scale)
+	a.stats.gradNorms.add(norm)
+	if maxNorm := a.Loss.MaxGradNorm; maxNorm > 0 && norm > maxNorm {
+		scale *= maxNorm / norm
+	}
+	a.PolicyNet.applyGradients(total.grads, scale)
+
+	return totalLoss
 }
 
 // UpdateTargetNetwork copies weights from policy network to target network
@@ -152,8 +488,12 @@ func (a *DQNAgent) UpdateTargetNetwork() {
 	a.TargetNet.CopyFrom(a.PolicyNet)
 }
 
-// DecayEpsilon reduces exploration rate
+// DecayEpsilon reduces exploration rate after an episode, unless a
+// step-based EpsilonSchedule is in charge
 func (a *DQNAgent) DecayEpsilon() {
+	if a.EpsilonSchedule != nil {
+		return
+	}
 	a.Epsilon *= a.EpsilonDecay
 	if a.Epsilon < a.EpsilonMin {
 		a.Epsilon = a.EpsilonMin
@@ -165,20 +505,103 @@ func (a *DQNAgent) SetEpsilon(eps float64) {
 	a.Epsilon = eps
 }
 
-// Save saves the agent's policy network
+// Save saves the agent's checkpoint, see Checkpoint, which model loaders
+// read as its policy network. Paths ending in ".bin", ".json" or ".npz" get
+// just the policy network in that format, for fast loading, reading by
+// hand or other tools.
 func (a *DQNAgent) Save(path string) error {
-	return a.PolicyNet.Save(path)
+	if saveOptionsForPath(path).Codec != CodecGob {
+		return a.PolicyNet.Save(path)
+	}
+	return a.Checkpoint().Save(path)
+}
+
+// Checkpoint returns the agent's training state, sharing its networks
+func (a *DQNAgent) Checkpoint() *AgentCheckpoint {
+	return &AgentCheckpoint{Policy: a.PolicyNet, Target: a.TargetNet, AgentState: a.GetState()}
+}
+
+// SaveReplay writes the replay buffer to path with codec, see
+// ReplayBuffer.Save
+func (a *DQNAgent) SaveReplay(path string, codec Codec) error {
+	return a.ReplayBuffer.Save(path, codec)
+}
+
+// LoadReplay replaces the replay buffer's experiences with those saved at
+// path, which must match the policy network's input size
+func (a *DQNAgent) LoadReplay(path string) error {
+	exps, err := ReadExperiences(path)
+	if err != nil {
+		return err
+	}
+	if len(exps) > 0 && len(exps[0].State) != a.PolicyNet.InputSize {
+		return fmt.Errorf("%s holds states of size %d, the network takes %d", path, len(exps[0].State), a.PolicyNet.InputSize)
+	}
+	if a.Prioritized != nil {
+		a.Prioritized.replace(exps)
+		return nil
+	}
+	a.ReplayBuffer.replace(exps)
+	return nil
+}
+
+// Snapshot returns a copy of the policy network
+func (a *DQNAgent) Snapshot() *QNetwork {
+	return a.PolicyNet.Clone()
 }
 
-// Load loads weights into the agent's networks
+// Load replaces the agent's networks with the model at path. The model's
+// architecture and state encoder come from the file, so they need not
+// match the configuration the agent was created with. Noisy models train
+// with their noise on. A checkpoint also restores the target network,
+// epsilon and step count, resuming training where it was saved.
 func (a *DQNAgent) Load(path string) error {
-	net, err := LoadNetwork(path)
+	c, err := LoadCheckpoint(path)
 	if err != nil {
 		return err
 	}
+	net := c.Policy
+	if net.Atoms > 0 {
+		return fmt.Errorf("%s is a distributional model; train it with a C51Agent", path)
+	}
+	if net.Recurrent {
+		return fmt.Errorf("%s is a recurrent model; train it with a DRQNAgent", path)
+	}
+	if net.Heads > 0 {
+		return fmt.Errorf("%s is a bootstrapped model; train it with a BootstrapAgent", path)
+	}
+	a.setNetwork(net)
+	a.restore(c)
+	return nil
+}
+
+// restore resumes training from c's target network and agent state, when
+// it has them
+func (a *DQNAgent) restore(c *AgentCheckpoint) {
+	if c.Target == nil {
+		return
+	}
+	a.TargetNet.CopyFrom(c.Target)
+	a.SetState(c.AgentState)
+}
+
+// setNetwork makes net the policy network, with noise and dropout on for
+// training, and a copy of it the target network
+func (a *DQNAgent) setNetwork(net *QNetwork) {
+	net.SetNoise(true)
+	net.SetDropout(a.Regularization.Dropout)
 	a.PolicyNet = net
 	a.TargetNet = net.Clone()
-	return nil
+}
+
+// Encoder returns the state encoder the agent's network expects
+func (a *DQNAgent) Encoder() Encoder {
+	return a.PolicyNet.StateEncoder()
+}
+
+// LearningRate returns the policy network's current learning rate
+func (a *DQNAgent) LearningRate() float64 {
+	return a.PolicyNet.LearningRate
 }
 
 // GetQValues returns Q-values for all actions given a state
@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestDoubleDQNTarget(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	agent := NewDQNAgent(cfg, 1)
+	agent.Gamma = 0.5
+
+	// Outputs that ignore the input: the online network rates turning left
+	// best and the target network going straight
+	for net, values := range map[*QNetwork][]float64{
+		agent.PolicyNet: {1, 5, 2},
+		agent.TargetNet: {8, 3, 4},
+	} {
+		clear(net.W3)
+		copy(net.B3, values)
+	}
+	exp := Experience{State: make([]float64, cfg.InputSize), Reward: 1, NextState: make([]float64, cfg.InputSize)}
+
+	tests := []struct {
+		double bool
+		done   bool
+		want   float64
+	}{
+		// The target network's value of the online network's best move
+		{double: true, want: 1 + 0.5*3},
+		// The target network's own best value
+		{double: false, want: 1 + 0.5*8},
+		{double: true, done: true, want: 1},
+	}
+	for _, tt := range tests {
+		agent.DoubleDQN = tt.double
+		exp.Done = tt.done
+		got := agent.targetValue(exp, newForwardCache(agent.PolicyNet), newForwardCache(agent.TargetNet))
+		if got != tt.want {
+			t.Errorf("double %v, done %v: target %v, want %v", tt.double, tt.done, got, tt.want)
+		}
+	}
+}
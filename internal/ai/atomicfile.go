@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes a file through write into a temporary file beside
+// path and renames it over path once it is complete and synced, so a crash
+// or failed encode mid-save leaves the previous file intact
+func WriteFileAtomic(path string, write func(w io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	err = write(file)
+	if err == nil {
+		err = file.Chmod(0644)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// RotateBackups keeps up to keep earlier versions of the file at path
+// before it is overwritten: path.1 becomes path.2 and so on, the oldest is
+// dropped, and the current file becomes path.1. The current file stays in
+// place until the next save replaces it. A missing file or keep of 0 does
+// nothing.
+func RotateBackups(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	backup := func(i int) string { return fmt.Sprintf("%s.%d", path, i) }
+	if err := os.Remove(backup(keep)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := keep - 1; i >= 1; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	// A hard link keeps the current file in place; filesystems without
+	// them get it moved
+	if err := os.Link(path, backup(1)); err != nil {
+		return os.Rename(path, backup(1))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.npz")
+	net := NewQNetwork(22, 8, 6, 3, 0.01, 1)
+	if err := net.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A save that fails partway leaves the old file and no temporary one
+	if err := NewRecurrentQNetwork(22, 8, 6, 3, 0.01, 2).Save(path); err == nil {
+		t.Fatal("saved a recurrent network as npz")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("failed save changed the file")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("failed save left %d files", len(entries))
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.gob")
+	if err := RotateBackups(path, 2); err != nil {
+		t.Fatalf("rotating a missing file: %v", err)
+	}
+
+	for _, content := range []string{"a", "b", "c", "d"} {
+		if err := RotateBackups(path, 2); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFileAtomic(path, func(w io.Writer) error {
+			_, err := w.Write([]byte(content))
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{"model.gob": "d", "model.gob.1": "c", "model.gob.2": "b"} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("%d files, want the model and 2 backups", len(entries))
+	}
+}
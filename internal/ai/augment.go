@@ -0,0 +1,40 @@
+package ai
+
+import "math/rand"
+
+// ObservationNoise perturbs encoded states during training so the policy
+// doesn't rely on any single feature being exact: each feature gets
+// Gaussian noise with standard deviation Std and is zeroed with probability
+// Dropout.
+type ObservationNoise struct {
+	Std     float64
+	Dropout float64
+	rng     *rand.Rand
+}
+
+// NewObservationNoise creates a noise source with the given settings
+func NewObservationNoise(std, dropout float64, seed int64) *ObservationNoise {
+	return &ObservationNoise{
+		Std:     std,
+		Dropout: dropout,
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Enabled reports whether Apply changes anything
+func (n *ObservationNoise) Enabled() bool {
+	return n.Std > 0 || n.Dropout > 0
+}
+
+// Apply perturbs features in place
+func (n *ObservationNoise) Apply(features []float64) {
+	for i := range features {
+		if n.Dropout > 0 && n.rng.Float64() < n.Dropout {
+			features[i] = 0
+			continue
+		}
+		if n.Std > 0 {
+			features[i] += n.rng.NormFloat64() * n.Std
+		}
+	}
+}
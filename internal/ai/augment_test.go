@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+)
+
+// noisy returns features perturbed by a fresh noise source
+func noisy(std, dropout float64, seed int64, features []float64) []float64 {
+	out := slices.Clone(features)
+	NewObservationNoise(std, dropout, seed).Apply(out)
+	return out
+}
+
+func TestObservationNoise(t *testing.T) {
+	features := []float64{1, -0.5, 0.25, 0, 2, 3, -1, 0.75}
+
+	// The same seed perturbs the same way; another seed doesn't
+	a, b := noisy(0.1, 0.2, 7, features), noisy(0.1, 0.2, 7, features)
+	if !slices.Equal(a, b) {
+		t.Errorf("seed 7 gave %v and %v", a, b)
+	}
+	if c := noisy(0.1, 0.2, 8, features); slices.Equal(a, c) {
+		t.Errorf("seeds 7 and 8 both gave %v", a)
+	}
+
+	// Without dropout nothing is zeroed, and without noise nothing moves
+	for i, v := range noisy(0.1, 0, 3, features) {
+		if v == features[i] || v == 0 {
+			t.Errorf("feature %d: %v with noise from %v", i, v, features[i])
+		}
+	}
+	if got := noisy(0, 0, 3, features); !slices.Equal(got, features) {
+		t.Errorf("disabled noise changed %v to %v", features, got)
+	}
+	if NewObservationNoise(0, 0, 3).Enabled() || !NewObservationNoise(0, 0.1, 3).Enabled() || !NewObservationNoise(0.1, 0, 3).Enabled() {
+		t.Error("Enabled doesn't follow the settings")
+	}
+
+	// Full dropout zeroes everything, noise or not
+	for i, v := range noisy(0.5, 1, 3, features) {
+		if v != 0 {
+			t.Errorf("feature %d = %v with full dropout", i, v)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Backend provides the dense linear algebra kernels QNetwork runs on. All
+// matrices use the flat row-major [out][in] layout described in linalg.go.
+// The pure-Go backend is always available; others are compiled in with
+// build tags (see backend_blas.go) and register themselves at init.
+type Backend interface {
+	Name() string
+
+	// MatVec computes output = W·input + bias
+	MatVec(weights, bias, input, output []float64)
+
+	// BatchMatVec computes outputs[k] = W·inputs[k] + bias for every input
+	BatchMatVec(weights, bias []float64, inputs, outputs [][]float64)
+
+	// MatTVec computes dInput = Wᵀ·dOutput
+	MatTVec(weights, dOutput, dInput []float64)
+
+	// Rank1Update computes W += alpha · dOutput ⊗ input
+	Rank1Update(weights []float64, alpha float64, dOutput, input []float64)
+
+	// Axpy computes y += alpha · x
+	Axpy(alpha float64, x, y []float64)
+}
+
+// DefaultBackendName is the pure-Go backend
+const DefaultBackendName = "go"
+
+// backends holds every compiled-in backend by name
+var backends = map[string]Backend{
+	DefaultBackendName: goBackend{},
+}
+
+// backend is the active backend used by every network
+var backend Backend = goBackend{}
+
+// RegisterBackend makes a backend selectable by name. It is meant to be
+// called from init functions of build-tagged files.
+func RegisterBackend(b Backend) {
+	backends[b.Name()] = b
+}
+
+// Backends returns the names of the compiled-in backends
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetBackend selects the backend used by all networks. An empty name
+// selects the default. It must not be called while networks are in use.
+func SetBackend(name string) error {
+	if name == "" {
+		name = DefaultBackendName
+	}
+	b, ok := backends[name]
+	if !ok {
+		return fmt.Errorf("unknown backend %q (available: %v)", name, Backends())
+	}
+	backend = b
+	return nil
+}
+
+// ActiveBackend returns the name of the backend in use
+func ActiveBackend() string {
+	return backend.Name()
+}
+
+// goBackend runs the pure-Go kernels from linalg.go
+type goBackend struct{}
+
+// Name returns the backend's name
+func (goBackend) Name() string { return DefaultBackendName }
+
+// MatVec computes output = W·input + bias
+func (goBackend) MatVec(weights, bias, input, output []float64) {
+	matVec(weights, bias, input, output)
+}
+
+// BatchMatVec computes W·input + bias for a batch of inputs
+func (goBackend) BatchMatVec(weights, bias []float64, inputs, outputs [][]float64) {
+	batchMatVec(weights, bias, inputs, outputs)
+}
+
+// MatTVec computes dInput = Wᵀ·dOutput
+func (goBackend) MatTVec(weights, dOutput, dInput []float64) {
+	matTVec(weights, dOutput, dInput)
+}
+
+// Rank1Update computes W += alpha · dOutput ⊗ input
+func (goBackend) Rank1Update(weights []float64, alpha float64, dOutput, input []float64) {
+	rank1Update(weights, alpha, dOutput, input)
+}
+
+// Axpy computes y += alpha · x
+func (goBackend) Axpy(alpha float64, x, y []float64) {
+	axpy(alpha, x, y)
+}
@@ -0,0 +1,65 @@
+//go:build blas
+
+package ai
+
+import (
+	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/blas/blas64"
+)
+
+// BLASBackendName selects the gonum BLAS backend
+const BLASBackendName = "blas"
+
+func init() {
+	RegisterBackend(blasBackend{})
+}
+
+// blasBackend runs the kernels through gonum's blas64 package. By default
+// that is gonum's own assembly-optimized implementation; programs can route
+// it to a native library (OpenBLAS, MKL) with blas64.Use and
+// gonum.org/v1/netlib. It pays off for large layers such as grid or
+// convolutional encoders; the default 22-128-64-3 network is too small to
+// benefit much.
+type blasBackend struct{}
+
+// Name returns the backend's name
+func (blasBackend) Name() string { return BLASBackendName }
+
+// general wraps a flat [out][in] matrix
+func general(weights []float64, out int) blas64.General {
+	in := len(weights) / out
+	return blas64.General{Rows: out, Cols: in, Stride: in, Data: weights}
+}
+
+// vector wraps a slice as a unit-stride vector
+func vector(x []float64) blas64.Vector {
+	return blas64.Vector{N: len(x), Inc: 1, Data: x}
+}
+
+// MatVec computes output = W·input + bias
+func (blasBackend) MatVec(weights, bias, input, output []float64) {
+	copy(output, bias)
+	blas64.Gemv(blas.NoTrans, 1, general(weights, len(bias)), vector(input), 1, vector(output))
+}
+
+// BatchMatVec computes W·input + bias for a batch of inputs
+func (b blasBackend) BatchMatVec(weights, bias []float64, inputs, outputs [][]float64) {
+	for k, input := range inputs {
+		b.MatVec(weights, bias, input, outputs[k])
+	}
+}
+
+// MatTVec computes dInput = Wᵀ·dOutput
+func (blasBackend) MatTVec(weights, dOutput, dInput []float64) {
+	blas64.Gemv(blas.Trans, 1, general(weights, len(dOutput)), vector(dOutput), 0, vector(dInput))
+}
+
+// Rank1Update computes W += alpha · dOutput ⊗ input
+func (blasBackend) Rank1Update(weights []float64, alpha float64, dOutput, input []float64) {
+	blas64.Ger(alpha, vector(dOutput), vector(input), general(weights, len(dOutput)))
+}
+
+// Axpy computes y += alpha · x
+func (blasBackend) Axpy(alpha float64, x, y []float64) {
+	blas64.Axpy(alpha, vector(x), vector(y[:len(x)]))
+}
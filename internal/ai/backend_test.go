@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSetBackend(t *testing.T) {
+	defer SetBackend(ActiveBackend())
+
+	if !slices.Contains(Backends(), DefaultBackendName) {
+		t.Fatalf("Backends() = %v, want %q among them", Backends(), DefaultBackendName)
+	}
+	if err := SetBackend(DefaultBackendName); err != nil {
+		t.Fatalf("SetBackend(%q): %v", DefaultBackendName, err)
+	}
+	if err := SetBackend("no-such-backend"); err == nil {
+		t.Error("SetBackend accepted an unknown backend")
+	}
+	if got := ActiveBackend(); got != DefaultBackendName {
+		t.Errorf("after an unknown backend, ActiveBackend() = %q, want %q", got, DefaultBackendName)
+	}
+
+	// Every compiled-in backend computes what the pure-Go one does
+	weights := []float64{1, 2, 3, 4, 5, 6}
+	bias := []float64{0.5, -1}
+	input := []float64{1, -1, 2}
+	want := []float64{5.5, 10} // [1-2+6+0.5, 4-5+12-1]
+	for _, name := range Backends() {
+		if err := SetBackend(name); err != nil {
+			t.Errorf("SetBackend(%q) of a listed backend: %v", name, err)
+			continue
+		}
+		if got := ActiveBackend(); got != name {
+			t.Errorf("SetBackend(%q) left %q active", name, got)
+		}
+		output := make([]float64, 2)
+		backend.MatVec(weights, bias, input, output)
+		for i := range want {
+			if math.Abs(output[i]-want[i]) > 1e-12 {
+				t.Errorf("%s: MatVec = %v, want %v", name, output, want)
+				break
+			}
+		}
+	}
+
+	if err := SetBackend(""); err != nil {
+		t.Errorf("SetBackend(\"\"): %v", err)
+	}
+	if got := ActiveBackend(); got != DefaultBackendName {
+		t.Errorf("SetBackend(\"\") selected %q, want %q", got, DefaultBackendName)
+	}
+}
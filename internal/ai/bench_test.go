@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// benchStates plays a few random games and returns the states seen
+func benchStates(n int) []*game.GameState {
+	g := game.NewGame(config.DefaultGameConfig(), 42)
+	rng := rand.New(rand.NewSource(1))
+	states := make([]*game.GameState, 0, n)
+	for len(states) < n {
+		if g.State.GameOver {
+			g.Reset()
+		}
+		states = append(states, g.Clone().State)
+		var actions [2]game.Direction
+		for id, snake := range g.State.Snakes {
+			actions[id] = ActionToDirection(snake.Direction, Action(rng.Intn(int(NumActions))))
+		}
+		g.Step(actions)
+	}
+	return states
+}
+
+func BenchmarkEncodeState(b *testing.B) {
+	states := benchStates(256)
+	features := make([]float64, StateSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		EncodeStateInto(features, states[i%len(states)], i%2)
+	}
+}
+
+func BenchmarkForward(b *testing.B) {
+	forBackends(b, func(b *testing.B) {
+		cfg := config.DefaultTrainingConfig()
+		net := NewQNetwork(cfg.InputSize, cfg.HiddenSize1, cfg.HiddenSize2, cfg.OutputSize, cfg.LearningRate, 1)
+		input := EncodeState(benchStates(1)[0], 0)
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			net.Forward(input)
+		}
+	})
+}
+
+func BenchmarkForwardWithCache(b *testing.B) {
+	forBackends(b, func(b *testing.B) {
+		cfg := config.DefaultTrainingConfig()
+		net := NewQNetwork(cfg.InputSize, cfg.HiddenSize1, cfg.HiddenSize2, cfg.OutputSize, cfg.LearningRate, 1)
+		input := EncodeState(benchStates(1)[0], 0)
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			net.ForwardWithCache(input)
+		}
+	})
+}
+
+func BenchmarkBackward(b *testing.B) {
+	forBackends(b, func(b *testing.B) {
+		cfg := config.DefaultTrainingConfig()
+		net := NewQNetwork(cfg.InputSize, cfg.HiddenSize1, cfg.HiddenSize2, cfg.OutputSize, cfg.LearningRate, 1)
+		cache := newForwardCache(net)
+		states := benchStates(256)
+		inputs := make([][]float64, len(states))
+		for i, state := range states {
+			inputs[i] = EncodeState(state, i%2)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			output := net.forwardWith(cache, inputs[i%len(inputs)])
+			net.Backward(cache, output, i%int(NumActions), 0.5)
+		}
+	})
+}
+
+func BenchmarkReplaySample(b *testing.B) {
+	cfg := config.DefaultTrainingConfig()
+	for _, size := range []int{cfg.BatchSize * 10, cfg.BufferSize} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			rb := NewReplayBuffer(size, 1)
+			state := make([]float64, StateSize)
+			for range size {
+				rb.Add(Experience{State: state, NextState: state})
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				rb.Sample(cfg.BatchSize)
+			}
+		})
+	}
+}
+
+func BenchmarkAct(b *testing.B) {
+	cfg := config.DefaultTrainingConfig()
+	policy := NewNetworkPolicy(NewQNetwork(cfg.InputSize, cfg.HiddenSize1, cfg.HiddenSize2, cfg.OutputSize, cfg.LearningRate, 1), 0, 1)
+	policy.Mask = true
+	states := benchStates(256)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		policy.Act(states[i%len(states)], i%2)
+	}
+}
+
+func BenchmarkTrainBatch(b *testing.B) {
+	forBackends(b, func(b *testing.B) {
+		for _, workers := range []int{1, 4} {
+			b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+				cfg := config.DefaultTrainingConfig()
+				cfg.GradWorkers = workers
+				agent := NewDQNAgent(cfg, 1)
+				agent.TrainInterval = 1
+
+				rng := rand.New(rand.NewSource(2))
+				states := benchStates(cfg.BatchSize * 4)
+				for i := 0; i+1 < len(states); i++ {
+					agent.Remember(EncodeState(states[i], 0), Action(rng.Intn(int(NumActions))),
+						rng.Float64()*2-1, EncodeState(states[i+1], 0), states[i+1].GameOver)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					agent.Train()
+				}
+			})
+		}
+	})
+}
+
+// forBackends runs bench once per compiled-in backend, so builds with
+// -tags blas compare the two
+func forBackends(b *testing.B, bench func(b *testing.B)) {
+	defer SetBackend(ActiveBackend())
+	for _, name := range Backends() {
+		b.Run("backend="+name, func(b *testing.B) {
+			if err := SetBackend(name); err != nil {
+				b.Fatal(err)
+			}
+			bench(b)
+		})
+	}
+}
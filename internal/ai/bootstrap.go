@@ -0,0 +1,227 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"autonomous-snake/internal/config"
+)
+
+// maxHeads is the most Q-value heads a bootstrapped network may have, one
+// per bit of Experience.Heads
+const maxHeads = 64
+
+// BootstrapAgent is Bootstrapped DQN: a network with several Q-value heads
+// on a shared trunk, each trained on its own random subset of the replayed
+// transitions. An episode follows one head drawn at its start, so the
+// snake commits to that head's idea of the game for a whole episode
+// instead of dithering like epsilon-greedy does; the heads disagree where
+// experience is thin, which is where that exploration goes. Greedy play,
+// evaluation and saved models use the mean of the heads.
+//
+// Each transition carries a bootstrap mask picking the heads it trains,
+// drawn when it is stored. Every other part of training, replay and
+// Double DQN included, is DQNAgent's.
+type BootstrapAgent struct {
+	*DQNAgent
+
+	// MaskProb is the probability of each head training on a transition
+	MaskProb float64
+
+	active int // Head followed this episode
+}
+
+// NewBootstrapAgent creates a Bootstrapped DQN agent with cfg.Bootstrap's
+// heads and mask probability
+func NewBootstrapAgent(cfg config.TrainingConfig, seed int64) *BootstrapAgent {
+	heads := cfg.Bootstrap.Heads
+	newNetwork := func(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork {
+		return NewBootstrapQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize, heads, lr, seed)
+	}
+	b := &BootstrapAgent{DQNAgent: newAgent(cfg, seed, newNetwork), MaskProb: cfg.Bootstrap.MaskProb}
+	b.active = b.rng.Intn(heads)
+	return b
+}
+
+// NewBootstrapQNetwork creates a network with heads sets of Q-values for
+// actions actions
+func NewBootstrapQNetwork(inputSize, hiddenSize1, hiddenSize2, actions, heads int, lr float64, seed int64) *QNetwork {
+	net := NewQNetwork(inputSize, hiddenSize1, hiddenSize2, actions*heads, lr, seed)
+	net.Heads = heads
+	return net
+}
+
+// checkHeads validates a network's head count against its other settings
+func checkHeads(heads, outputSize int, dueling bool, atoms int, recurrent bool) error {
+	switch {
+	case heads == 0:
+		return nil
+	case heads < 0 || heads > maxHeads || outputSize%heads != 0:
+		return fmt.Errorf("bootstrap: %d outputs can't hold %d heads", outputSize, heads)
+	case dueling || atoms > 0 || recurrent:
+		return fmt.Errorf("bootstrap: dueling, distributional and recurrent networks can't have several heads")
+	}
+	return nil
+}
+
+// headMean writes the mean of every head's Q-values to q
+func (n *QNetwork) headMean(heads, q []float64) {
+	for j := range q {
+		q[j] = 0
+	}
+	for k := 0; k < n.Heads; k++ {
+		for j, v := range heads[k*len(q) : (k+1)*len(q)] {
+			q[j] += v
+		}
+	}
+	for j := range q {
+		q[j] /= float64(n.Heads)
+	}
+}
+
+// head returns head k's Q-values in a bootstrapped network's output
+func (n *QNetwork) head(output []float64, k int) []float64 {
+	actions := n.Actions()
+	return output[k*actions : (k+1)*actions]
+}
+
+// SelectAction chooses an action epsilon-greedily by the active head
+func (b *BootstrapAgent) SelectAction(state []float64) Action {
+	if b.rng.Float64() < b.Epsilon {
+		return Action(b.rng.Intn(NumActions))
+	}
+	return Action(MaxIndex(b.activeValues(state)))
+}
+
+// SelectActionMasked chooses an action like SelectAction, among the
+// actions mask allows
+func (b *BootstrapAgent) SelectActionMasked(state []float64, mask ActionMask) Action {
+	if b.rng.Float64() < b.Epsilon {
+		return mask.Random(b.rng)
+	}
+	return mask.Best(b.activeValues(state))
+}
+
+// activeValues returns the active head's Q-values for state, valid until
+// the next forward pass
+func (b *BootstrapAgent) activeValues(state []float64) []float64 {
+	_, cache := b.PolicyNet.ForwardWithCache(state)
+	return b.PolicyNet.head(cache.output, b.active)
+}
+
+// Remember stores an experience with a freshly drawn bootstrap mask
+func (b *BootstrapAgent) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
+	b.remember(Experience{
+		State:     state,
+		Action:    action,
+		Reward:    reward,
+		NextState: nextState,
+		Done:      done,
+		Heads:     b.bootstrapMask(),
+	})
+}
+
+// bootstrapMask picks each head with probability MaskProb, and one at
+// random if none was picked so no transition goes to waste
+func (b *BootstrapAgent) bootstrapMask() uint64 {
+	heads := b.PolicyNet.Heads
+	var mask uint64
+	for k := 0; k < heads; k++ {
+		if b.rng.Float64() < b.MaskProb {
+			mask |= 1 << k
+		}
+	}
+	if mask == 0 {
+		mask = 1 << b.rng.Intn(heads)
+	}
+	return mask
+}
+
+// DecayEpsilon ends an episode: it decays the exploration rate like
+// DQNAgent.DecayEpsilon and draws the head to follow next
+func (b *BootstrapAgent) DecayEpsilon() {
+	b.DQNAgent.DecayEpsilon()
+	b.active = b.rng.Intn(b.PolicyNet.Heads)
+}
+
+// Train performs a training step if enough experiences are available
+func (b *BootstrapAgent) Train() float64 {
+	return b.trainStep(b.ReplayBuffer.Size(), b.TrainBatch)
+}
+
+// TrainBatch samples one batch and updates the heads each experience's
+// mask picks, like DQNAgent.TrainBatch
+func (b *BootstrapAgent) TrainBatch() float64 {
+	return b.trainBatch(b.bootLoss)
+}
+
+// Load replaces the agent's networks with the bootstrapped model at path
+func (b *BootstrapAgent) Load(path string) error {
+	c, err := LoadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	if c.Policy.Heads == 0 {
+		return fmt.Errorf("%s is not a bootstrapped model", path)
+	}
+	b.setNetwork(c.Policy)
+	b.restore(c)
+	b.active = b.rng.Intn(c.Policy.Heads)
+	return nil
+}
+
+// bootLoss is the one-step Q-learning loss of every head the experience's
+// mask picks, each against its own target, a sampleLoss. The loss and TD
+// error are averaged over those heads.
+func (b *BootstrapAgent) bootLoss(exp Experience, weight float64, policyCache, targetCache *forwardCache, dOutput []float64) (float64, float64) {
+	net := b.PolicyNet
+	all := uint64(1)<<net.Heads - 1
+	heads := exp.Heads & all
+	if heads == 0 {
+		heads = all
+	}
+	for j := range dOutput {
+		dOutput[j] = 0
+	}
+
+	// Collect each head's target in its taken action's error slot
+	if !exp.Done {
+		b.TargetNet.forwardWith(targetCache, exp.NextState)
+		if b.DoubleDQN {
+			net.forwardWith(policyCache, exp.NextState)
+		}
+	}
+	for k := 0; k < net.Heads; k++ {
+		if heads&(1<<k) == 0 {
+			continue
+		}
+		target := exp.Reward
+		if !exp.Done {
+			next := net.head(targetCache.output, k)
+			if b.DoubleDQN {
+				target += b.Gamma * next[MaxIndex(net.head(policyCache.output, k))]
+			} else {
+				target += b.Gamma * Max(next)
+			}
+		}
+		net.head(dOutput, k)[exp.Action] = target
+	}
+
+	// Replace the targets with the loss gradients
+	net.forwardWith(policyCache, exp.State)
+	n := float64(bits.OnesCount64(heads))
+	loss, tdError := 0.0, 0.0
+	for k := 0; k < net.Heads; k++ {
+		if heads&(1<<k) == 0 {
+			continue
+		}
+		i := k*net.Actions() + int(exp.Action)
+		diff := policyCache.output[i] - dOutput[i]
+		l, grad := b.Loss.Loss(diff)
+		loss += l
+		tdError += math.Abs(diff)
+		dOutput[i] = weight * grad / n
+	}
+	return weight * loss / n, tdError / n
+}
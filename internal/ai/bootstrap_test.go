@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestBootstrapAgent(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	cfg.Bootstrap = config.BootstrapConfig{Heads: 3, MaskProb: 0.5}
+	cfg.BatchSize = 4
+	agent := NewBootstrapAgent(cfg, 1)
+	net := agent.PolicyNet
+	input := make([]float64, cfg.InputSize)
+	for i := range input {
+		input[i] = float64(i%5) / 4
+	}
+
+	// Forward returns the mean of the heads
+	cache := newForwardCache(net)
+	q := append([]float64(nil), net.forwardWith(cache, input)...)
+	for j := range q {
+		want := 0.0
+		for k := 0; k < net.Heads; k++ {
+			want += net.head(cache.output, k)[j] / float64(net.Heads)
+		}
+		if math.Abs(q[j]-want) > 1e-12 {
+			t.Errorf("Q[%d] = %v, want %v", j, q[j], want)
+		}
+	}
+
+	// Stored masks pick at least one head and no head beyond the last
+	for range 100 {
+		if mask := agent.bootstrapMask(); mask == 0 || mask >= 1<<net.Heads {
+			t.Fatalf("bootstrap mask %b for %d heads", mask, net.Heads)
+		}
+	}
+
+	// Only the heads a transition's mask picks train on it
+	for range cfg.BatchSize {
+		agent.remember(Experience{State: input, Action: 1, Reward: 1, NextState: input, Heads: 0b101})
+	}
+	before := append([]float64(nil), net.W3...)
+	agent.TrainBatch()
+	actions := net.Actions()
+	for k := 0; k < net.Heads; k++ {
+		for a := 0; a < actions; a++ {
+			row := (k*actions + a) * net.HiddenSize2
+			changed := !equalFloats(net.W3[row:row+net.HiddenSize2], before[row:row+net.HiddenSize2])
+			if want := a == 1 && k != 1; changed != want {
+				t.Errorf("head %d action %d output weights changed: %v, want %v", k, a, changed, want)
+			}
+		}
+	}
+
+	// Saved models keep their heads
+	want := net.Forward(input)
+	dir := t.TempDir()
+	for _, codec := range []Codec{CodecGob, CodecJSON, CodecFlat} {
+		path := filepath.Join(dir, "model."+string(codec))
+		if err := net.SaveWithOptions(path, SaveOptions{Codec: codec, Precision: Float64}); err != nil {
+			t.Fatalf("%s: save: %v", codec, err)
+		}
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if loaded.Heads != net.Heads || loaded.Actions() != NumActions {
+			t.Errorf("%s: loaded %d heads of %d actions", codec, loaded.Heads, loaded.Actions())
+		}
+		if got := loaded.Forward(input); !equalFloats(got, want) {
+			t.Errorf("%s: loaded Forward = %v, want %v", codec, got, want)
+		}
+	}
+	if err := NewDQNAgent(cfg, 1).Load(filepath.Join(dir, "model.gob")); err == nil {
+		t.Error("DQNAgent loaded a bootstrapped model")
+	}
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+
+	"autonomous-snake/internal/config"
+)
+
+// C51Agent learns a distribution over returns for every action with the
+// categorical (C51) algorithm instead of their expected values. It shares
+// DQNAgent's replay, exploration and target network handling and acts on
+// the distributions' means; only the network head and the loss differ.
+//
+// The loss is the cross-entropy between the taken action's predicted
+// distribution and a target distribution: the target network's
+// distribution for the best next action, shifted by the reward, discounted
+// and projected back onto the support. Its KL divergence part stands in
+// for the TD error in diagnostics and prioritized replay. Huber loss does
+// not apply; gradient clipping does.
+type C51Agent struct {
+	*DQNAgent
+}
+
+// NewC51Agent creates a C51 agent whose support is set by cfg.Distribution
+func NewC51Agent(cfg config.TrainingConfig, seed int64) *C51Agent {
+	dist := cfg.Distribution
+	newNetwork := func(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork {
+		return NewDistributionalQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize, dist.Atoms, dist.VMin, dist.VMax, lr, seed)
+	}
+	return &C51Agent{DQNAgent: newAgent(cfg, seed, newNetwork)}
+}
+
+// Train performs a training step if enough experiences are available
+func (c *C51Agent) Train() float64 {
+	return c.trainStep(c.ReplayBuffer.Size(), c.TrainBatch)
+}
+
+// TrainBatch samples one batch and applies a C51 update, like
+// DQNAgent.TrainBatch
+func (c *C51Agent) TrainBatch() float64 {
+	return c.trainBatch(c.c51Loss)
+}
+
+// Load replaces the agent's networks with the distributional model at path
+func (c *C51Agent) Load(path string) error {
+	ckpt, err := LoadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	if ckpt.Policy.Atoms == 0 {
+		return fmt.Errorf("%s is not a distributional model", path)
+	}
+	c.setNetwork(ckpt.Policy)
+	c.restore(ckpt)
+	return nil
+}
+
+// c51Loss is the categorical cross-entropy loss, a sampleLoss. It returns
+// the weighted KL divergence from the target distribution, which differs
+// from the cross-entropy only by the target's entropy.
+func (c *C51Agent) c51Loss(exp Experience, weight float64, policyCache, targetCache *forwardCache, dOutput []float64) (float64, float64) {
+	net := c.PolicyNet
+	for j := range dOutput {
+		dOutput[j] = 0
+	}
+
+	// Build the target distribution in the taken action's error slice
+	lo, hi := int(exp.Action)*net.Atoms, (int(exp.Action)+1)*net.Atoms
+	target := dOutput[lo:hi]
+	if exp.Done {
+		net.projectReturn(target, exp.Reward, 1)
+	} else {
+		next := MaxIndex(c.TargetNet.forwardWith(targetCache, exp.NextState))
+		if c.DoubleDQN {
+			next = MaxIndex(net.forwardWith(policyCache, exp.NextState))
+		}
+		nextProbs := targetCache.probs[next*net.Atoms : (next+1)*net.Atoms]
+		net.projectDistribution(target, nextProbs, exp.Reward, c.Gamma)
+	}
+
+	// The softmax cross-entropy gradient w.r.t. the logits is p - m
+	net.forwardWith(policyCache, exp.State)
+	kl := 0.0
+	for i, p := range policyCache.probs[lo:hi] {
+		m := target[i]
+		if m > 0 {
+			kl += m * (math.Log(m) - math.Log(math.Max(p, minProb)))
+		}
+		target[i] = weight * (p - m)
+	}
+	return weight * kl, kl
+}
@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestProjectDistribution(t *testing.T) {
+	net := NewDistributionalQNetwork(22, 8, 6, 3, 11, -5, 5, 0.01, 1)
+	probs := []float64{0, 0.1, 0.2, 0.3, 0.4, 0, 0, 0, 0, 0, 0}
+
+	// Inside the support, projection keeps the mass and shifts the mean
+	dst := make([]float64, net.Atoms)
+	net.projectDistribution(dst, probs, 0.3, 0.9)
+	mean := func(p []float64) (mass, mean float64) {
+		for i, v := range p {
+			mass += v
+			mean += v * (net.VMin + float64(i)*net.atomSpacing())
+		}
+		return mass, mean
+	}
+	_, before := mean(probs)
+	if mass, after := mean(dst); math.Abs(mass-1) > 1e-12 || math.Abs(after-(0.3+0.9*before)) > 1e-12 {
+		t.Errorf("projected mass %v mean %v, want 1 and %v", mass, after, 0.3+0.9*before)
+	}
+
+	// Returns beyond the support land on its ends
+	dst = make([]float64, net.Atoms)
+	net.projectReturn(dst, 100, 1)
+	net.projectReturn(dst, -100, 0.5)
+	if dst[net.Atoms-1] != 1 || dst[0] != 0.5 {
+		t.Errorf("clipped returns gave %v", dst)
+	}
+}
+
+func TestC51Loss(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	agent := NewC51Agent(cfg, 1)
+	net := agent.PolicyNet
+	input := make([]float64, cfg.InputSize)
+	for i := range input {
+		input[i] = float64(i%5) / 4
+	}
+
+	// Forward returns each action's mean return
+	cache := newForwardCache(net)
+	q := append([]float64(nil), net.forwardWith(cache, input)...)
+	for j := range q {
+		want := 0.0
+		for i := 0; i < net.Atoms; i++ {
+			want += cache.probs[j*net.Atoms+i] * (net.VMin + float64(i)*net.atomSpacing())
+		}
+		if math.Abs(q[j]-want) > 1e-12 {
+			t.Errorf("Q[%d] = %v, want %v", j, q[j], want)
+		}
+	}
+
+	// The logit gradient matches finite differences of the cross-entropy
+	exp := Experience{State: input, Action: 1, Reward: 0.5, NextState: input}
+	dOutput := make([]float64, net.OutputSize)
+	agent.c51Loss(exp, 1, cache, newForwardCache(agent.TargetNet), dOutput)
+	// dOutput holds p - m for the taken action, so recover the target m
+	lo := int(exp.Action) * net.Atoms
+	target := make([]float64, net.Atoms)
+	for i := range target {
+		target[i] = cache.probs[lo+i] - dOutput[lo+i]
+	}
+	crossEntropy := func() float64 {
+		c := newForwardCache(net)
+		net.forwardWith(c, input)
+		loss := 0.0
+		for i, m := range target {
+			loss -= m * math.Log(c.probs[lo+i])
+		}
+		return loss
+	}
+	g := newGradients(net)
+	net.accumulateGradients(cache, dOutput, g)
+	for k, p := range net.params() {
+		for _, i := range []int{0, len(p) / 2, len(p) - 1} {
+			orig := p[i]
+			p[i] = orig + 1e-6
+			up := crossEntropy()
+			p[i] = orig - 1e-6
+			down := crossEntropy()
+			p[i] = orig
+			if numeric := (up - down) / 2e-6; math.Abs(numeric-g.params()[k][i]) > 1e-6 {
+				t.Errorf("param %d[%d]: gradient %v, finite difference %v", k, i, g.params()[k][i], numeric)
+			}
+		}
+	}
+
+	// Saved models keep their support
+	want := net.Forward(input)
+	dir := t.TempDir()
+	for _, codec := range []Codec{CodecGob, CodecJSON, CodecFlat} {
+		path := filepath.Join(dir, "model."+string(codec))
+		if err := net.SaveWithOptions(path, SaveOptions{Codec: codec, Precision: Float64}); err != nil {
+			t.Fatalf("%s: save: %v", codec, err)
+		}
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if loaded.Atoms != net.Atoms || loaded.VMin != net.VMin || loaded.VMax != net.VMax {
+			t.Errorf("%s: loaded support %d [%v, %v]", codec, loaded.Atoms, loaded.VMin, loaded.VMax)
+		}
+		if got := loaded.Forward(input); !equalFloats(got, want) {
+			t.Errorf("%s: loaded Forward = %v, want %v", codec, got, want)
+		}
+	}
+	if err := NewDQNAgent(cfg, 1).Load(filepath.Join(dir, "model.gob")); err == nil {
+		t.Error("DQNAgent loaded a distributional model")
+	}
+}
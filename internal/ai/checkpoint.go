@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Agent checkpoint layout
+//
+//	[4]byte  magic "SLCK"
+//	uint16   checkpoint format version (little-endian)
+//
+// followed by a gob checkpointFile. The networks inside are complete model
+// files, so a checkpoint loads anywhere a model does: LoadNetwork returns
+// its policy network.
+
+// checkpointMagic identifies agent checkpoint files
+const checkpointMagic = "SLCK"
+
+// CheckpointFormatVersion is the current agent checkpoint version
+const CheckpointFormatVersion = 1
+
+// AgentCheckpoint is what a DQNAgent needs to resume training where it
+// stopped: both networks and the exploration and step counters that drive
+// the schedules. Training uses plain SGD, so the learning rate, saved with
+// the policy network, is the whole optimizer state.
+type AgentCheckpoint struct {
+	Policy *QNetwork
+	Target *QNetwork // nil when loaded from a plain model file
+	AgentState
+}
+
+// checkpointFile is the gob payload of a checkpoint
+type checkpointFile struct {
+	Policy    []byte
+	Target    []byte
+	Epsilon   float64
+	StepCount int
+}
+
+// Save writes the checkpoint to path, replacing it only once the new one is
+// complete
+func (c *AgentCheckpoint) Save(path string) error {
+	file := checkpointFile{Epsilon: c.Epsilon, StepCount: c.StepCount}
+	var err error
+	if file.Policy, err = modelBytes(c.Policy); err != nil {
+		return err
+	}
+	if file.Target, err = modelBytes(c.Target); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(checkpointMagic)
+	buf.Write(binary.LittleEndian.AppendUint16(nil, CheckpointFormatVersion))
+	if err := gob.NewEncoder(&buf).Encode(file); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// LoadCheckpoint reads an agent checkpoint. A plain model file gives a
+// checkpoint with only its policy network, to train from scratch.
+func LoadCheckpoint(path string) (*AgentCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(checkpointMagic)) {
+		_, net, err := decodeNetwork(data)
+		if err != nil {
+			return nil, err
+		}
+		return &AgentCheckpoint{Policy: net}, nil
+	}
+	_, c, err := decodeCheckpoint(data)
+	return c, err
+}
+
+// decodeCheckpoint decodes a checkpoint file, describing it by its policy
+// network's encoding
+func decodeCheckpoint(data []byte) (ModelInfo, *AgentCheckpoint, error) {
+	if len(data) < 6 {
+		return ModelInfo{}, nil, errors.New("truncated checkpoint header")
+	}
+	if version := int(binary.LittleEndian.Uint16(data[4:6])); version > CheckpointFormatVersion {
+		return ModelInfo{}, nil, fmt.Errorf("checkpoint format version %d is newer than supported version %d", version, CheckpointFormatVersion)
+	}
+	var file checkpointFile
+	if err := gob.NewDecoder(bytes.NewReader(data[6:])).Decode(&file); err != nil {
+		return ModelInfo{}, nil, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	info, policy, err := decodeNetwork(file.Policy)
+	if err != nil {
+		return info, nil, fmt.Errorf("checkpoint policy network: %w", err)
+	}
+	_, target, err := decodeNetwork(file.Target)
+	if err != nil {
+		return info, nil, fmt.Errorf("checkpoint target network: %w", err)
+	}
+	if !sameShape(policy, target) {
+		return info, nil, errors.New("checkpoint target network doesn't match its policy network")
+	}
+	info.Kind = ModelCheckpoint
+	return info, &AgentCheckpoint{
+		Policy:     policy,
+		Target:     target,
+		AgentState: AgentState{Epsilon: file.Epsilon, StepCount: file.StepCount},
+	}, nil
+}
+
+// modelBytes returns net encoded as a model file in the default format
+func modelBytes(net *QNetwork) ([]byte, error) {
+	var buf bytes.Buffer
+	err := net.writeModel(&buf, DefaultSaveOptions())
+	return buf.Bytes(), err
+}
+
+// sameShape reports whether two networks have the same parameter layout
+func sameShape(a, b *QNetwork) bool {
+	pa, pb := a.params(), b.params()
+	if len(pa) != len(pb) || a.Architecture() != b.Architecture() {
+		return false
+	}
+	for k := range pa {
+		if len(pa[k]) != len(pb[k]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestAgentCheckpoint(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	agent := NewDQNAgent(cfg, 1)
+	agent.SetState(AgentState{Epsilon: 0.25, StepCount: 1234})
+	agent.TargetNet.W3[0] += 1 // Out of sync with the policy network
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.gob")
+	if err := agent.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Loading resumes the target network and counters
+	resumed := NewDQNAgent(cfg, 2)
+	if err := resumed.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := resumed.GetState(); got != agent.GetState() {
+		t.Errorf("resumed state %+v, want %+v", got, agent.GetState())
+	}
+	if !equalFloats(resumed.PolicyNet.W3, agent.PolicyNet.W3) || !equalFloats(resumed.TargetNet.W3, agent.TargetNet.W3) {
+		t.Error("resumed networks differ from the saved ones")
+	}
+
+	// Model loaders read the policy network
+	net, err := LoadNetwork(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalFloats(net.W1, agent.PolicyNet.W1) {
+		t.Error("LoadNetwork didn't return the checkpoint's policy network")
+	}
+	if info, err := InspectModel(path); err != nil || info.Kind != ModelCheckpoint {
+		t.Errorf("InspectModel = %v, %v, want an agent checkpoint", info.Kind, err)
+	}
+
+	// A plain model starts a fresh run
+	modelPath := filepath.Join(dir, "model.gob")
+	if err := agent.PolicyNet.Save(modelPath); err != nil {
+		t.Fatal(err)
+	}
+	fresh := NewDQNAgent(cfg, 3)
+	if err := fresh.Load(modelPath); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.StepCount != 0 || fresh.Epsilon != cfg.EpsilonStart || !equalFloats(fresh.TargetNet.W3, agent.PolicyNet.W3) {
+		t.Errorf("plain model resumed at step %d, epsilon %v", fresh.StepCount, fresh.Epsilon)
+	}
+}
@@ -0,0 +1,35 @@
+package ai
+
+import "math"
+
+// conservativeLoss is dqnLoss plus the conservative Q-learning (CQL)
+// penalty, a sampleLoss:
+//
+//	Conservative · (log Σ_b exp Q(s,b) − Q(s,a))
+//
+// Learning only from logged transitions, Q-learning's max happily picks
+// actions the data never tried, whose values nothing corrects. The penalty
+// pushes every Q-value down in proportion to its softmax weight and the
+// logged action's back up, so the learned policy stays close to what the
+// data supports.
+func (a *DQNAgent) conservativeLoss(exp Experience, weight float64, policyCache, targetCache *forwardCache, dOutput []float64) (float64, float64) {
+	loss, tdError := a.dqnLoss(exp, weight, policyCache, targetCache, dOutput)
+
+	// dqnLoss left the state's Q-values in the cache
+	q := policyCache.output
+	var buf [NumActions]float64
+	probs := buf[:len(q)]
+	softmaxInto(probs, q)
+	maxQ := Max(q)
+	sum := 0.0
+	for _, v := range q {
+		sum += math.Exp(v - maxQ)
+	}
+	alpha := weight * a.Conservative
+	loss += alpha * (maxQ + math.Log(sum) - q[exp.Action])
+	for j, p := range probs {
+		dOutput[j] += alpha * p
+	}
+	dOutput[exp.Action] -= alpha
+	return loss, tdError
+}
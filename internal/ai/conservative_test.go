@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestConservativeLoss(t *testing.T) {
+	// Logged data that only ever went straight, for no reward
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	cfg.BatchSize = 16
+	cfg.LearningRate = 0.01
+	rng := rand.New(rand.NewSource(1))
+	states := make([][]float64, 32)
+	for i := range states {
+		states[i] = make([]float64, cfg.InputSize)
+		for j := range states[i] {
+			states[i][j] = rng.Float64()
+		}
+	}
+	train := func(conservative float64) *DQNAgent {
+		cfg.Conservative = conservative
+		agent := NewDQNAgent(cfg, 2)
+		for _, state := range states {
+			agent.Remember(state, GoStraight, 0, state, true)
+		}
+		for range 300 {
+			agent.TrainBatch()
+		}
+		return agent
+	}
+
+	// The penalty pushes the actions the data never took below the one it
+	// did, which plain Q-learning leaves wherever they started
+	plain, conservative := train(0), train(1)
+	unseen := func(agent *DQNAgent) (sum float64) {
+		for _, state := range states {
+			q := agent.GetQValues(state)
+			sum += q[TurnLeft] + q[TurnRight]
+		}
+		return sum
+	}
+	if got, want := unseen(conservative), unseen(plain); got >= want {
+		t.Errorf("unseen actions' Q-values sum to %v with the penalty, %v without", got, want)
+	}
+	for _, state := range states {
+		if q := conservative.GetQValues(state); MaxIndex(q) != int(GoStraight) {
+			t.Errorf("Q-values %v rank an unseen action first", q)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+
+	"autonomous-snake/internal/config"
+)
+
+// ConvShape describes the convolutional first layer of a network: Filters
+// 3×3 filters over Channels input planes of Height×Width cells, zero padded
+// so every feature map has the planes' size. Inputs are stored plane by
+// plane and row by row, and so are the feature maps that form hidden layer
+// 1. Filters is 0 for dense networks.
+type ConvShape struct {
+	Channels int
+	Height   int
+	Width    int
+	Filters  int
+}
+
+// convKernel is the width and height of every filter
+const convKernel = 3
+
+// InputSize returns the number of inputs the layer reads
+func (s ConvShape) InputSize() int {
+	return s.Channels * s.Height * s.Width
+}
+
+// MapSize returns the number of outputs, all feature maps together
+func (s ConvShape) MapSize() int {
+	return s.Filters * s.Height * s.Width
+}
+
+// patchSize returns the number of weights per filter
+func (s ConvShape) patchSize() int {
+	return s.Channels * convKernel * convKernel
+}
+
+// PlaneEncoder is an Encoder whose output is a stack of board-shaped
+// planes, which convolutional networks can read
+type PlaneEncoder interface {
+	Encoder
+	Planes() (channels, height, width int)
+}
+
+// convShapeFor returns the first layer cfg asks for: a convolution with
+// cfg.ConvFilters filters when its encoder produces planes
+func convShapeFor(cfg config.TrainingConfig) (ConvShape, bool) {
+	if cfg.ConvFilters <= 0 {
+		return ConvShape{}, false
+	}
+	enc, err := LookupEncoder(cfg.Encoder)
+	if err != nil {
+		return ConvShape{}, false
+	}
+	planes, ok := enc.(PlaneEncoder)
+	if !ok {
+		return ConvShape{}, false
+	}
+	channels, height, width := planes.Planes()
+	return ConvShape{Channels: channels, Height: height, Width: width, Filters: cfg.ConvFilters}, true
+}
+
+// NewConvQNetwork creates a network whose layer 1 is a convolution of the
+// given shape
+func NewConvQNetwork(shape ConvShape, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork {
+	net := NewQNetwork(shape.InputSize(), shape.Filters, hiddenSize2, outputSize, lr, seed)
+	net.makeConvolutional(shape)
+	return net
+}
+
+// convolutional reports whether layer 1 is a convolution
+func (n *QNetwork) convolutional() bool {
+	return n.Conv.Filters > 0
+}
+
+// makeConvolutional replaces layer 1 with freshly initialized filters of
+// the given shape, and layer 2 to match the new hidden layer 1
+func (n *QNetwork) makeConvolutional(shape ConvShape) {
+	n.Conv = shape
+	n.InputSize = shape.InputSize()
+	n.HiddenSize1 = shape.MapSize()
+	n.W1 = xavierInit(shape.patchSize(), shape.Filters, n.rng)
+	n.B1 = make([]float64, shape.Filters)
+	n.W2 = xavierInit(n.HiddenSize1, n.layer2Size(), n.rng)
+	n.cache, n.batch, n.grads = nil, nil, nil
+}
+
+// layer1Dims returns the fan-in and fan-out of W1 in a network with the
+// given sizes: one row per hidden unit, or one patch per filter when layer
+// 1 is a convolution
+func (s ConvShape) layer1Dims(inputSize, hiddenSize1 int) (in, out int) {
+	if s.Filters > 0 {
+		return s.patchSize(), s.Filters
+	}
+	return inputSize, hiddenSize1
+}
+
+// checkConv checks a convolutional layer against the network's declared
+// sizes and rejects layer combinations it doesn't support
+func checkConv(shape ConvShape, inputSize, hiddenSize1 int, noisy bool) error {
+	switch {
+	case shape.Filters == 0:
+		return nil
+	case shape.Filters < 0 || shape.Channels <= 0 || shape.Height <= 0 || shape.Width <= 0:
+		return fmt.Errorf("convolution: invalid shape %+v", shape)
+	case shape.InputSize() != inputSize || shape.MapSize() != hiddenSize1:
+		return fmt.Errorf("convolution: shape %+v doesn't fit %d inputs and %d hidden units", shape, inputSize, hiddenSize1)
+	case noisy:
+		return errors.New("convolutional networks can't be noisy")
+	}
+	return nil
+}
+
+// convForward runs the convolution on cache.input into cache.z1
+func (n *QNetwork) convForward(cache *forwardCache) {
+	s := n.Conv
+	cells, patch := s.Height*s.Width, s.patchSize()
+	for f := 0; f < s.Filters; f++ {
+		out := cache.z1[f*cells : (f+1)*cells]
+		for i := range out {
+			out[i] = n.B1[f]
+		}
+		kernel := n.W1[f*patch : (f+1)*patch]
+		for c := 0; c < s.Channels; c++ {
+			plane := cache.input[c*cells : (c+1)*cells]
+			for k, w := range kernel[c*convKernel*convKernel : (c+1)*convKernel*convKernel] {
+				dy, dx := k/convKernel-1, k%convKernel-1
+				shiftAdd(out, plane, s.Height, s.Width, dy, dx, w)
+			}
+		}
+	}
+}
+
+// convAccumulate adds the gradient of the filters and their biases, given
+// the feature maps' error in cache.dZ1
+func (n *QNetwork) convAccumulate(cache *forwardCache, g *gradients) {
+	s := n.Conv
+	cells, patch := s.Height*s.Width, s.patchSize()
+	for f := 0; f < s.Filters; f++ {
+		dOut := cache.dZ1[f*cells : (f+1)*cells]
+		for _, d := range dOut {
+			g.B1[f] += d
+		}
+		kernel := g.W1[f*patch : (f+1)*patch]
+		for c := 0; c < s.Channels; c++ {
+			plane := cache.input[c*cells : (c+1)*cells]
+			for k := range kernel[c*convKernel*convKernel : (c+1)*convKernel*convKernel] {
+				dy, dx := k/convKernel-1, k%convKernel-1
+				kernel[c*convKernel*convKernel+k] += shiftDot(dOut, plane, s.Height, s.Width, dy, dx)
+			}
+		}
+	}
+}
+
+// shiftAdd adds w·plane[y+dy][x+dx] to out[y][x] wherever both cells lie on
+// the height×width grid
+func shiftAdd(out, plane []float64, height, width, dy, dx int, w float64) {
+	x0, x1 := max(0, -dx), min(width, width-dx)
+	for y := max(0, -dy); y < min(height, height-dy); y++ {
+		dst := out[y*width+x0 : y*width+x1]
+		src := plane[(y+dy)*width+x0+dx:]
+		for x := range dst {
+			dst[x] += w * src[x]
+		}
+	}
+}
+
+// shiftDot returns the sum of out[y][x]·plane[y+dy][x+dx] over the cells
+// where both lie on the height×width grid
+func shiftDot(out, plane []float64, height, width, dy, dx int) float64 {
+	sum := 0.0
+	x0, x1 := max(0, -dx), min(width, width-dx)
+	for y := max(0, -dy); y < min(height, height-dy); y++ {
+		dst := out[y*width+x0 : y*width+x1]
+		src := plane[(y+dy)*width+x0+dx:]
+		for x, d := range dst {
+			sum += d * src[x]
+		}
+	}
+	return sum
+}
@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"math/rand"
+
+	"autonomous-snake/internal/config"
+)
+
+// Curiosity is an intrinsic curiosity module (ICM) rewarding transitions
+// the agent can't yet predict. It learns an embedding φ of encoded states
+// through an inverse model, which guesses the action taken between φ(s)
+// and φ(s'), so the embedding keeps what the snake's moves affect and
+// ignores the rest. A forward model predicts φ(s') from φ(s) and the
+// action; its error, scaled by Weight, is the intrinsic reward. Rarely seen
+// situations, such as far corners of a large board, stay poorly predicted
+// and so keep drawing the agent back until it has learned them.
+//
+// Both models train online, one step per rewarded transition. The forward
+// model's loss doesn't reach the embedding, which would otherwise learn to
+// make every transition predictable.
+type Curiosity struct {
+	Weight float64
+
+	embed     *QNetwork // State → φ
+	inverse   *QNetwork // φ(s), φ(s') → action logits
+	predictor *QNetwork // φ(s), one-hot action → φ(s'), the forward model
+
+	// Scratch buffers
+	stateCache, nextCache        *forwardCache
+	inverseCache, predictorCache *forwardCache
+	embedGrads, inverseGrads     *gradients
+	predictorGrads               *gradients
+	inverseInput, predictorInput []float64
+	dInverse                     []float64
+}
+
+// NewCuriosity creates a curiosity module for states of inputSize values
+func NewCuriosity(cfg config.CuriosityConfig, inputSize int, seed int64) *Curiosity {
+	rng := rand.New(rand.NewSource(seed))
+	features, hidden := cfg.Features, cfg.Hidden
+	c := &Curiosity{
+		Weight:    cfg.Weight,
+		embed:     NewQNetwork(inputSize, hidden, hidden, features, cfg.LearningRate, rng.Int63()),
+		inverse:   NewQNetwork(2*features, hidden, hidden, NumActions, cfg.LearningRate, rng.Int63()),
+		predictor: NewQNetwork(features+NumActions, hidden, hidden, features, cfg.LearningRate, rng.Int63()),
+	}
+	c.stateCache, c.nextCache = newForwardCache(c.embed), newForwardCache(c.embed)
+	c.inverseCache, c.predictorCache = newForwardCache(c.inverse), newForwardCache(c.predictor)
+	c.embedGrads = newGradients(c.embed)
+	c.inverseGrads, c.predictorGrads = newGradients(c.inverse), newGradients(c.predictor)
+	c.inverseInput, c.predictorInput = make([]float64, 2*features), make([]float64, features+NumActions)
+	c.dInverse = make([]float64, 2*features)
+	return c
+}
+
+// Reward returns the intrinsic reward for taking action in state and
+// reaching nextState, then trains both models on the transition
+func (c *Curiosity) Reward(state []float64, action Action, nextState []float64) float64 {
+	features := c.embed.OutputSize
+	phi := c.embed.forwardWith(c.stateCache, state)
+	nextPhi := c.embed.forwardWith(c.nextCache, nextState)
+
+	// Forward model: its squared error is the surprise
+	copy(c.predictorInput, phi)
+	for a := 0; a < NumActions; a++ {
+		c.predictorInput[features+a] = boolToFloat(Action(a) == action)
+	}
+	predicted := c.predictor.forwardWith(c.predictorCache, c.predictorInput)
+	surprise := 0.0
+	dPredicted := c.predictorCache.dOutput
+	for j := range predicted {
+		dPredicted[j] = predicted[j] - nextPhi[j]
+		surprise += dPredicted[j] * dPredicted[j] / 2
+	}
+	reward := c.Weight * surprise
+
+	// Inverse model: cross-entropy of the taken action
+	copy(c.inverseInput, phi)
+	copy(c.inverseInput[features:], nextPhi)
+	logits := c.inverse.forwardWith(c.inverseCache, c.inverseInput)
+	probs := c.inverseCache.dOutput
+	softmaxInto(probs, logits)
+	probs[action]--
+
+	// Backpropagate, the inverse loss reaching the embedding of both states
+	c.predictorGrads.zero()
+	c.predictor.accumulateGradients(c.predictorCache, dPredicted, c.predictorGrads)
+	c.inverseGrads.zero()
+	c.inverse.accumulateGradients(c.inverseCache, probs, c.inverseGrads)
+	c.inverse.inputGradient(c.inverseCache, c.dInverse)
+	c.embedGrads.zero()
+	c.embed.accumulateGradients(c.stateCache, c.dInverse[:features], c.embedGrads)
+	c.embed.accumulateGradients(c.nextCache, c.dInverse[features:], c.embedGrads)
+
+	c.predictor.applyGradients(c.predictorGrads, 1)
+	c.inverse.applyGradients(c.inverseGrads, 1)
+	c.embed.applyGradients(c.embedGrads, 1)
+	return reward
+}
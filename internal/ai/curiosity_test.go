@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestCuriosity(t *testing.T) {
+	cfg := config.DefaultTrainingConfig().Curiosity
+	cfg.Weight = 1
+	cfg.LearningRate = 0.01
+	c := NewCuriosity(cfg, 22, 1)
+
+	state, next, other := make([]float64, 22), make([]float64, 22), make([]float64, 22)
+	for i := range state {
+		state[i] = float64(i%5) / 4
+		next[i] = float64((i+1)%5) / 4
+		other[i] = float64(i%3) / 2
+	}
+
+	// A transition seen over and over becomes predictable and stops
+	// paying, while an unseen one still surprises
+	first := c.Reward(state, TurnLeft, next)
+	var last float64
+	for i := 0; i < 300; i++ {
+		last = c.Reward(state, TurnLeft, next)
+	}
+	if !(last < first/10) {
+		t.Errorf("reward %v after training, %v at first", last, first)
+	}
+	if novel := c.Reward(next, TurnRight, other); novel <= last {
+		t.Errorf("novel transition rewarded %v, familiar one %v", novel, last)
+	}
+
+	// The inverse model learns which action links the states
+	for i := 0; i < 300; i++ {
+		c.Reward(state, TurnLeft, next)
+		c.Reward(state, TurnRight, other)
+	}
+	copy(c.inverseInput, c.embed.Forward(state))
+	copy(c.inverseInput[cfg.Features:], c.embed.Forward(other))
+	if got := Action(MaxIndex(c.inverse.Forward(c.inverseInput))); got != TurnRight {
+		t.Errorf("inverse model guessed %v, want %v", got, TurnRight)
+	}
+}
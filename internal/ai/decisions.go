@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"autonomous-snake/internal/game"
+)
+
+// QValuer is implemented by policies that can report their action values
+type QValuer interface {
+	QValues(state *game.GameState, snakeID int) []float64
+}
+
+// Decision is one logged action choice with the values behind it
+type Decision struct {
+	Game   int       `json:"game"`
+	Turn   int       `json:"turn"`
+	Snake  int       `json:"snake"`
+	Hash   uint64    `json:"hash"` // game.GameState.Hash of the position
+	Q      []float64 `json:"q"`    // Indexed by Action
+	Action Action    `json:"action"`
+}
+
+// Greedy returns the highest-valued action
+func (d Decision) Greedy() Action {
+	return Action(MaxIndex(d.Q))
+}
+
+// Margin is the gap between the best and second-best Q-value; small
+// margins mark decisions the network is unsure about
+func (d Decision) Margin() float64 {
+	best, second := d.Q[0], d.Q[1]
+	if second > best {
+		best, second = second, best
+	}
+	for _, q := range d.Q[2:] {
+		if q > best {
+			best, second = q, best
+		} else if q > second {
+			second = q
+		}
+	}
+	return best - second
+}
+
+// DecisionWriter streams decisions as JSON lines
+type DecisionWriter struct {
+	enc *json.Encoder
+}
+
+// NewDecisionWriter returns a writer appending to w
+func NewDecisionWriter(w io.Writer) *DecisionWriter {
+	return &DecisionWriter{enc: json.NewEncoder(w)}
+}
+
+// Write appends a decision
+func (dw *DecisionWriter) Write(d Decision) error {
+	return dw.enc.Encode(d)
+}
+
+// ReadDecisions reads every decision in a JSON lines stream
+func ReadDecisions(r io.Reader) ([]Decision, error) {
+	var decisions []Decision
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		var d Decision
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(d.Q) != NumActions {
+			return nil, fmt.Errorf("line %d: expected %d Q-values, got %d", line, NumActions, len(d.Q))
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, scanner.Err()
+}
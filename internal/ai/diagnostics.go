@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"math"
+	"sort"
+)
+
+// DiagnosticsWindow is how many recent batch updates the training
+// diagnostics summarize
+const DiagnosticsWindow = 100
+
+// Diagnostics summarizes the agent's recent training updates
+type Diagnostics struct {
+	Updates  int     // Batch updates applied so far
+	AvgLoss  float64 // Mean batch loss over the window
+	GradNorm float64 // Mean L2 norm of the per-sample average gradient over the window
+
+	// TDError describes |Q(s,a) - target| for every sample in the window
+	TDError TDErrorStats
+
+	// TargetDivergence is ||policy - target|| / ||target|| over all
+	// parameters: how far the policy has drifted since the last sync
+	TargetDivergence float64
+}
+
+// TDErrorStats describes a distribution of absolute TD errors
+type TDErrorStats struct {
+	Mean, Std     float64
+	P50, P90, Max float64
+}
+
+// trainingStats records recent losses, gradient norms and TD errors
+type trainingStats struct {
+	updates   int
+	losses    rollingWindow
+	gradNorms rollingWindow
+	tdErrors  rollingWindow
+
+	// before holds the policy parameters at the start of a sequential
+	// batch so its gradient norm can be recovered from the weight change
+	before []float64
+}
+
+// newTrainingStats creates stats sized for the given batch size
+func newTrainingStats(batchSize int) *trainingStats {
+	return &trainingStats{
+		losses:    newRollingWindow(DiagnosticsWindow),
+		gradNorms: newRollingWindow(DiagnosticsWindow),
+		tdErrors:  newRollingWindow(DiagnosticsWindow * max(batchSize, 1)),
+	}
+}
+
+// Diagnostics summarizes the recent training updates
+func (a *DQNAgent) Diagnostics() Diagnostics {
+	d := Diagnostics{
+		Updates:          a.stats.updates,
+		AvgLoss:          mean(a.stats.losses.values),
+		GradNorm:         mean(a.stats.gradNorms.values),
+		TargetDivergence: paramDistance(a.PolicyNet, a.TargetNet),
+	}
+
+	errs := append([]float64(nil), a.stats.tdErrors.values...)
+	if len(errs) > 0 {
+		sort.Float64s(errs)
+		d.TDError.Mean = mean(errs)
+		for _, e := range errs {
+			d.TDError.Std += (e - d.TDError.Mean) * (e - d.TDError.Mean)
+		}
+		d.TDError.Std = math.Sqrt(d.TDError.Std / float64(len(errs)))
+		d.TDError.P50 = errs[len(errs)/2]
+		d.TDError.P90 = errs[len(errs)*9/10]
+		d.TDError.Max = errs[len(errs)-1]
+	}
+	return d
+}
+
+// snapshotParams copies the policy parameters into stats.before
+func (s *trainingStats) snapshotParams(n *QNetwork) {
+	s.before = s.before[:0]
+	for _, p := range n.params() {
+		s.before = append(s.before, p...)
+	}
+}
+
+// updateNorm returns ||after - before|| for the snapshot taken by
+// snapshotParams
+func (s *trainingStats) updateNorm(n *QNetwork) float64 {
+	sum, k := 0.0, 0
+	for _, p := range n.params() {
+		for _, v := range p {
+			d := v - s.before[k]
+			sum += d * d
+			k++
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// paramDistance returns ||a - b|| / ||b|| over all parameters
+func paramDistance(a, b *QNetwork) float64 {
+	diff, norm := 0.0, 0.0
+	bp := b.params()
+	for k, p := range a.params() {
+		for i, v := range p {
+			d := v - bp[k][i]
+			diff += d * d
+			norm += bp[k][i] * bp[k][i]
+		}
+	}
+	if norm == 0 {
+		return 0
+	}
+	return math.Sqrt(diff / norm)
+}
+
+// gradientNorm returns the L2 norm of g scaled by scale
+func gradientNorm(g *gradients, scale float64) float64 {
+	sum := 0.0
+	for _, p := range g.params() {
+		for _, v := range p {
+			sum += v * v
+		}
+	}
+	return math.Sqrt(sum) * scale
+}
+
+// rollingWindow keeps the most recent values up to a fixed capacity
+type rollingWindow struct {
+	values []float64
+	next   int
+}
+
+// newRollingWindow creates an empty window holding up to size values
+func newRollingWindow(size int) rollingWindow {
+	return rollingWindow{values: make([]float64, 0, size)}
+}
+
+// add appends v, overwriting the oldest value once the window is full
+func (w *rollingWindow) add(v float64) {
+	if len(w.values) < cap(w.values) {
+		w.values = append(w.values, v)
+		return
+	}
+	w.values[w.next] = v
+	w.next = (w.next + 1) % len(w.values)
+}
+
+// mean returns the average of values, or 0 when empty
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
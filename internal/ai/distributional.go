@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+)
+
+// Distributional (C51) networks predict a categorical distribution over
+// returns for every action instead of its mean. Layer 3 outputs Atoms
+// logits per action, action-major; a softmax over each action's logits
+// gives the probability of every atom of the support, and the Q-value is
+// the distribution's mean. Forward and ForwardBatch return those means, so
+// a distributional network plays like any other.
+
+// minProb keeps the log of a vanishing atom probability finite
+const minProb = 1e-12
+
+// NewDistributionalQNetwork creates a network predicting atoms return
+// probabilities from vMin to vMax for each of actions actions
+func NewDistributionalQNetwork(inputSize, hiddenSize1, hiddenSize2, actions, atoms int, vMin, vMax, lr float64, seed int64) *QNetwork {
+	net := NewQNetwork(inputSize, hiddenSize1, hiddenSize2, actions*atoms, lr, seed)
+	net.Atoms = atoms
+	net.VMin, net.VMax = vMin, vMax
+	return net
+}
+
+// Actions returns the number of actions the network values
+func (n *QNetwork) Actions() int {
+	switch {
+	case n.Atoms > 0:
+		return n.OutputSize / n.Atoms
+	case n.Heads > 0:
+		return n.OutputSize / n.Heads
+	}
+	return n.OutputSize
+}
+
+// checkSupport reports a distributional head whose dimensions or support
+// don't fit together
+func checkSupport(atoms, outputSize int, vMin, vMax float64, dueling bool) error {
+	switch {
+	case atoms == 0:
+		return nil
+	case atoms < 2 || outputSize%atoms != 0:
+		return fmt.Errorf("distribution: %d outputs can't hold %d atoms per action", outputSize, atoms)
+	case !(vMin < vMax):
+		return fmt.Errorf("distribution: invalid support [%v, %v]", vMin, vMax)
+	case dueling:
+		return fmt.Errorf("distribution: dueling networks can't be distributional")
+	}
+	return nil
+}
+
+// atomSpacing returns the distance between neighbouring atoms
+func (n *QNetwork) atomSpacing() float64 {
+	return (n.VMax - n.VMin) / float64(n.Atoms-1)
+}
+
+// expectedValues turns logits into per-action atom probabilities and their
+// mean returns
+func (n *QNetwork) expectedValues(logits, probs, q []float64) {
+	dz := n.atomSpacing()
+	for j := range q {
+		lo, hi := j*n.Atoms, (j+1)*n.Atoms
+		softmaxInto(probs[lo:hi], logits[lo:hi])
+		q[j] = 0
+		for i, p := range probs[lo:hi] {
+			q[j] += p * (n.VMin + float64(i)*dz)
+		}
+	}
+}
+
+// softmaxInto writes softmax(x) into dst
+func softmaxInto(dst, x []float64) {
+	maxX := Max(x)
+	sum := 0.0
+	for i, v := range x {
+		dst[i] = math.Exp(v - maxX)
+		sum += dst[i]
+	}
+	for i := range dst {
+		dst[i] /= sum
+	}
+}
+
+// projectDistribution adds the distribution probs, shifted to
+// reward + discount·z, onto the atoms of dst
+func (n *QNetwork) projectDistribution(dst, probs []float64, reward, discount float64) {
+	dz := n.atomSpacing()
+	for i, p := range probs {
+		n.projectReturn(dst, reward+discount*(n.VMin+float64(i)*dz), p)
+	}
+}
+
+// projectReturn adds mass at return g, clipped to the support, onto the
+// atoms of dst, splitting it between the two nearest atoms
+func (n *QNetwork) projectReturn(dst []float64, g, mass float64) {
+	g = math.Max(n.VMin, math.Min(n.VMax, g))
+	b := math.Min((g-n.VMin)/n.atomSpacing(), float64(n.Atoms-1))
+	l, u := math.Floor(b), math.Ceil(b)
+	if l == u {
+		dst[int(l)] += mass
+		return
+	}
+	dst[int(l)] += mass * (u - b)
+	dst[int(u)] += mass * (b - l)
+}
@@ -0,0 +1,236 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+
+	"autonomous-snake/internal/config"
+)
+
+// DRQNAgent is a deep recurrent Q-network agent: its network's second
+// layer is a GRU, so a snake's hidden state can remember what happened
+// earlier in the episode, such as how the opponent has been moving. It
+// shares DQNAgent's exploration, loss and target network handling.
+//
+// Every snake being played has its own hidden state, cleared when its
+// trajectory ends. Transitions are replayed as sequences from a
+// SequenceReplayBuffer: both networks first run over the burn-in
+// transitions from a zero state, then the TD errors of the sequence are
+// backpropagated through time. Each update trains on about BatchSize
+// transitions. Noisy layers, prioritized replay and parallel gradient
+// workers are not supported.
+type DRQNAgent struct {
+	*DQNAgent
+	Sequences *SequenceReplayBuffer
+	Sequence  config.SequenceConfig
+
+	// Hidden states of the snakes being played, by snake ID
+	memory map[int]*forwardCache
+
+	// Training scratch: a policy cache per sequence step, and caches for
+	// the target network and for evaluating next states
+	policyCaches           []*forwardCache
+	targetCache, nextCache *forwardCache
+	policyNextCache        *forwardCache
+	grads                  *gradients
+}
+
+// NewDRQNAgent creates a recurrent agent with the given configuration.
+// cfg.Dueling adds a value stream to the head.
+func NewDRQNAgent(cfg config.TrainingConfig, seed int64) *DRQNAgent {
+	newNetwork := func(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork {
+		var net *QNetwork
+		if cfg.Dueling {
+			net = NewDuelingQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize, lr, seed)
+		} else {
+			net = NewQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize, lr, seed)
+		}
+		net.makeRecurrent()
+		return net
+	}
+	d := &DRQNAgent{DQNAgent: newAgent(cfg, seed, newNetwork), Sequence: cfg.Sequence}
+	d.Sequences = NewSequenceReplayBuffer(cfg.BufferSize, d.rng.Int63())
+	return d
+}
+
+// SelectAction chooses an action for snake 0, see SelectActionSnake
+func (d *DRQNAgent) SelectAction(state []float64) Action {
+	return d.SelectActionSnake(0, state)
+}
+
+// SelectActionSnake steps the snake's hidden state and chooses an action
+// epsilon-greedily
+func (d *DRQNAgent) SelectActionSnake(snakeID int, state []float64) Action {
+	// The network runs even when exploring, to keep the memory current
+	qValues := d.PolicyNet.forwardWith(d.memoryFor(snakeID), state)
+	if d.rng.Float64() < d.Epsilon {
+		return Action(d.rng.Intn(NumActions))
+	}
+	return Action(MaxIndex(qValues))
+}
+
+// SelectActionGreedy chooses the best action for snake 0, stepping its
+// hidden state
+func (d *DRQNAgent) SelectActionGreedy(state []float64) Action {
+	return Action(MaxIndex(d.PolicyNet.forwardWith(d.memoryFor(0), state)))
+}
+
+// memoryFor returns the buffers holding the snake's hidden state
+func (d *DRQNAgent) memoryFor(snakeID int) *forwardCache {
+	cache := d.memory[snakeID]
+	if cache == nil {
+		if d.memory == nil {
+			d.memory = make(map[int]*forwardCache)
+		}
+		cache = newForwardCache(d.PolicyNet)
+		d.memory[snakeID] = cache
+	}
+	return cache
+}
+
+// Remember stores a transition of snake 0, see RememberSnake
+func (d *DRQNAgent) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
+	d.RememberSnake(0, state, action, reward, nextState, done)
+}
+
+// RememberSnake stores a transition on the snake's trajectory, clearing
+// its hidden state when the trajectory ends
+func (d *DRQNAgent) RememberSnake(snakeID int, state []float64, action Action, reward float64, nextState []float64, done bool) {
+	d.Sequences.Add(snakeID, Experience{
+		State:     state,
+		Action:    action,
+		Reward:    reward,
+		NextState: nextState,
+		Done:      done,
+	})
+	if done {
+		if cache := d.memory[snakeID]; cache != nil {
+			cache.resetState()
+		}
+	}
+}
+
+// Train performs a training step if enough transitions are stored
+func (d *DRQNAgent) Train() float64 {
+	return d.trainStep(d.Sequences.Size(), d.TrainBatch)
+}
+
+// DecayEpsilon ends the episode: trajectories in progress are closed, every
+// hidden state is cleared and exploration decays like DQNAgent's
+func (d *DRQNAgent) DecayEpsilon() {
+	d.Sequences.EndEpisode()
+	for _, cache := range d.memory {
+		cache.resetState()
+	}
+	d.DQNAgent.DecayEpsilon()
+}
+
+// TrainBatch samples BatchSize/Sequence.Length sequences and applies one
+// update, leaving the step count and target network alone
+func (d *DRQNAgent) TrainBatch() float64 {
+	if d.grads == nil {
+		d.grads = newGradients(d.PolicyNet)
+		d.targetCache, d.nextCache = newForwardCache(d.TargetNet), newForwardCache(d.TargetNet)
+		d.policyNextCache = newForwardCache(d.PolicyNet)
+	}
+	d.grads.zero()
+
+	totalLoss, steps := 0.0, 0
+	for _, seq := range d.Sequences.Sample(max(d.BatchSize/d.Sequence.Length, 1), d.Sequence.Length, d.Sequence.BurnIn) {
+		totalLoss += d.accumulateSequence(seq)
+		steps += len(seq.Steps)
+	}
+
+	// The diagnostics record the norm before clipping
+	scale := 1.0 / float64(steps)
+	norm := gradientNorm(d.grads, scale)
+	d.stats.gradNorms.add(norm)
+	if maxNorm := d.Loss.MaxGradNorm; maxNorm > 0 && norm > maxNorm {
+		scale *= maxNorm / norm
+	}
+	d.PolicyNet.applyGradients(d.grads, scale)
+	d.PolicyNet.decayWeights(d.Regularization.WeightDecay)
+
+	loss := totalLoss / float64(steps)
+	d.stats.updates++
+	d.stats.losses.add(loss)
+	return loss
+}
+
+// accumulateSequence adds the gradient of the sequence's summed TD loss,
+// backpropagated through time, to d.grads and returns the loss
+func (d *DRQNAgent) accumulateSequence(seq Sequence) float64 {
+	for len(d.policyCaches) < len(seq.Steps) {
+		d.policyCaches = append(d.policyCaches, newForwardCache(d.PolicyNet))
+	}
+	caches := d.policyCaches[:len(seq.Steps)]
+
+	// Warm up both hidden states without training on the burn-in
+	caches[0].resetState()
+	d.targetCache.resetState()
+	for _, exp := range seq.BurnIn {
+		d.PolicyNet.forwardWith(caches[0], exp.State)
+		d.TargetNet.forwardWith(d.targetCache, exp.State)
+	}
+
+	// Unroll both networks over the sequence. Each next state is valued
+	// from the hidden state after the step it follows.
+	loss := 0.0
+	for t, exp := range seq.Steps {
+		if t > 0 {
+			copy(caches[t].h2, caches[t-1].h2)
+		}
+		output := d.PolicyNet.forwardWith(caches[t], exp.State)
+		d.TargetNet.forwardWith(d.targetCache, exp.State)
+
+		targetQ := exp.Reward
+		if !exp.Done {
+			copy(d.nextCache.h2, d.targetCache.h2)
+			nextQValues := d.TargetNet.forwardWith(d.nextCache, exp.NextState)
+			next := MaxIndex(nextQValues)
+			if d.DoubleDQN {
+				copy(d.policyNextCache.h2, caches[t].h2)
+				next = MaxIndex(d.PolicyNet.forwardWith(d.policyNextCache, exp.NextState))
+			}
+			targetQ += d.Gamma * nextQValues[next]
+		}
+
+		diff := output[exp.Action] - targetQ
+		stepLoss, grad := d.Loss.Loss(diff)
+		loss += stepLoss
+		d.stats.tdErrors.add(math.Abs(diff))
+
+		// Only the taken action has an error
+		dOutput := caches[t].dOutput
+		for j := range dOutput {
+			dOutput[j] = 0
+		}
+		dOutput[exp.Action] = grad
+	}
+
+	// Backpropagate from the last step, carrying the hidden state's
+	// gradient to the step before
+	var dHidden []float64
+	for t := len(caches) - 1; t >= 0; t-- {
+		d.PolicyNet.accumulateStep(caches[t], caches[t].dOutput, dHidden, d.grads)
+		dHidden = caches[t].dHPrev
+	}
+	return loss
+}
+
+// Load replaces the agent's networks with the recurrent model at path
+func (d *DRQNAgent) Load(path string) error {
+	c, err := LoadCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	if !c.Policy.Recurrent || c.Policy.Atoms > 0 {
+		return fmt.Errorf("%s is not a recurrent Q-network", path)
+	}
+	d.setNetwork(c.Policy)
+	d.restore(c)
+
+	// Scratch buffers are sized for the old networks
+	d.memory, d.policyCaches, d.grads = nil, nil, nil
+	return nil
+}
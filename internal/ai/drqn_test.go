@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"math"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestSequenceReplay(t *testing.T) {
+	// Snake 0 plays 1 2 3 4 and dies, snake 1 plays 10 11 12 and is cut off
+	// by the end of the episode, then snake 0 plays 20 21 in the next one
+	b := NewSequenceReplayBuffer(8, 1)
+	step := func(snake int, reward float64, done bool) {
+		b.Add(snake, Experience{State: []float64{reward}, Reward: reward, Done: done})
+	}
+	for i := 0; i < 3; i++ {
+		step(0, float64(1+i), false)
+		step(1, float64(10+i), false)
+	}
+	step(0, 4, true)
+	b.EndEpisode()
+	step(0, 20, false)
+	step(0, 21, false)
+
+	// The oldest trajectory was dropped to stay within capacity
+	if b.Size() != 5 {
+		t.Fatalf("size %d, want 5", b.Size())
+	}
+	for _, seq := range b.Sample(200, 2, 1) {
+		if len(seq.Steps) == 0 || len(seq.Steps) > 2 || len(seq.BurnIn) > 1 {
+			t.Fatalf("sequence of %d steps after %d burn-in steps", len(seq.Steps), len(seq.BurnIn))
+		}
+		all := append(append([]Experience(nil), seq.BurnIn...), seq.Steps...)
+		for k := 1; k < len(all); k++ {
+			if all[k].Reward != all[k-1].Reward+1 {
+				t.Fatalf("sequence crosses trajectories: %v then %v", all[k-1].Reward, all[k].Reward)
+			}
+		}
+		if seq.Steps[0].Reward < 10 {
+			t.Fatalf("sampled the dropped trajectory")
+		}
+	}
+}
+
+func TestDRQNAgent(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	cfg.BatchSize = 8
+	cfg.Sequence = config.SequenceConfig{Length: 4, BurnIn: 2}
+	agent := NewDRQNAgent(cfg, 1)
+	agent.Epsilon = 0
+
+	state := make([]float64, cfg.InputSize)
+	for i := range state {
+		state[i] = float64(i%5) / 4
+	}
+
+	// Each snake has its own memory, cleared when its trajectory ends
+	first := append([]float64(nil), agent.PolicyNet.forwardWith(agent.memoryFor(0), state)...)
+	agent.SelectActionSnake(1, state)
+	if got := agent.memoryFor(1).output; !equalFloats(got, first) {
+		t.Errorf("snake 1's first step: Q = %v, want %v", got, first)
+	}
+	if agent.SelectActionSnake(0, state); equalFloats(agent.memoryFor(0).output, first) {
+		t.Error("snake 0's second step gave the same Q-values as its first")
+	}
+	agent.RememberSnake(0, state, TurnLeft, -1, state, true)
+	if got := agent.PolicyNet.forwardWith(agent.memoryFor(0), state); !equalFloats(got, first) {
+		t.Errorf("after done: Q = %v, want %v", got, first)
+	}
+
+	for i := 0; i < 20; i++ {
+		agent.RememberSnake(i%2, state, Action(i%3), float64(i%3)-1, state, i == 17)
+	}
+	before := agent.PolicyNet.Clone()
+	loss := agent.TrainBatch()
+	if math.IsNaN(loss) || loss <= 0 {
+		t.Errorf("loss %v", loss)
+	}
+	if equalFloats(agent.PolicyNet.U2, before.U2) {
+		t.Error("update left the recurrent weights unchanged")
+	}
+	if err := agent.PolicyNet.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"fmt"
+
+	"autonomous-snake/internal/game"
+)
+
+// DefaultEncoderName is the 22-feature encoder used by EncodeState. Models
+// saved before encoders were recorded use it.
+const DefaultEncoderName = "features"
+
+// Encoder turns a game state into network input from one snake's view.
+// Networks record the name of the encoder they were trained with, so a
+// loaded model brings its input format along.
+type Encoder interface {
+	Name() string
+	Size() int
+	Encode(dst []float64, state *game.GameState, snakeID int)
+}
+
+// encoders holds the built-in encoders by name
+var encoders = map[string]Encoder{
+	DefaultEncoderName: featureEncoder{},
+	GridEncoderName:    gridEncoder{},
+	WindowEncoderName:  windowEncoder{n: DefaultWindow},
+	FoodEncoderName:    foodEncoder{},
+}
+
+// DefaultEncoder returns the 22-feature encoder
+func DefaultEncoder() Encoder {
+	return featureEncoder{}
+}
+
+// LookupEncoder returns the encoder with the given name. An empty name
+// selects the default encoder. Window widths and stacking are part of the
+// name, see WindowEncoder and StackedEncoderName, and every stacked name
+// gets a new StateStack.
+func LookupEncoder(name string) (Encoder, error) {
+	if name == "" {
+		name = DefaultEncoderName
+	}
+	if base, k, ok, err := parseStack(name); ok {
+		if err != nil {
+			return nil, err
+		}
+		enc, err := LookupEncoder(base)
+		if err != nil {
+			return nil, err
+		}
+		stack := NewStateStack(enc, k)
+		if _, planes := enc.(PlaneEncoder); planes {
+			return planeStack{stack}, nil
+		}
+		return stack, nil
+	}
+	if enc, ok, err := parseWindow(name); ok {
+		return enc, err
+	}
+	enc, ok := encoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown state encoder %q", name)
+	}
+	return enc, nil
+}
+
+// featureEncoder is the hand-crafted feature vector from state.go
+type featureEncoder struct{}
+
+// Name returns the encoder's name
+func (featureEncoder) Name() string { return DefaultEncoderName }
+
+// Size returns the number of features
+func (featureEncoder) Size() int { return StateSize }
+
+// Encode writes the features into dst
+func (featureEncoder) Encode(dst []float64, state *game.GameState, snakeID int) {
+	EncodeStateInto(dst, state, snakeID)
+}
@@ -0,0 +1,388 @@
+package ai
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"unsafe"
+)
+
+// Flat binary model layout
+//
+// A fixed 64-byte header followed by the raw parameter arrays in the
+// network's in-memory order (W1, B1, W2, B2, W3, B3, then WV, BV for
+// dueling networks, U2 for recurrent ones, then the same again as noise
+// scales for noisy networks), little-endian:
+//
+//	[4]byte  magic "SLRF"
+//	uint16   format version
+//	uint8    bytes per value (4 = float32, 8 = float64)
+//	uint8    flags (bit 0 = dueling, bit 1 = noisy, bit 2 = recurrent,
+//	         bit 3 = convolutional, bit 4 = bootstrapped; version 2 and
+//	         later)
+//	uint32   input size, hidden size 1, hidden size 2, output size
+//	float64  learning rate
+//	[16]byte encoder name, zero padded (empty means the default encoder)
+//	uint32   atoms per action, 0 unless distributional, or the number of
+//	         heads of a bootstrapped network (version 2 and later)
+//	float32  support minimum and maximum of distributional networks
+//	uint8    channels, height, width and filters of a convolutional
+//	         layer 1, zero otherwise (version 2 and later)
+//
+// The header size keeps the arrays 8-byte aligned. Files are selected by
+// the ".bin" extension in Save or by CodecFlat in SaveWithOptions.
+
+// flatMagic identifies flat binary model files
+const flatMagic = "SLRF"
+
+// flatHeaderSize is the size of the flat format header in bytes
+const flatHeaderSize = 64
+
+// flatExtension makes Save write the flat format
+const flatExtension = ".bin"
+
+// CodecFlat stores raw little-endian arrays with a small header
+const CodecFlat Codec = "flat"
+
+// flatHeader is the decoded flat format header
+type flatHeader struct {
+	version      int
+	valueSize    int
+	inputSize    int
+	hiddenSize1  int
+	hiddenSize2  int
+	outputSize   int
+	learningRate float64
+	encoder      string
+	dueling      bool
+	noisy        bool
+	recurrent    bool
+	conv         ConvShape
+	atoms        int
+	vMin, vMax   float64
+	heads        int
+}
+
+// flatEncoderOffset and flatEncoderLen locate the encoder name in the header
+const (
+	flatEncoderOffset = 32
+	flatEncoderLen    = 16
+)
+
+// flatAtomsOffset locates the distributional support in the header
+const flatAtomsOffset = 48
+
+// flatConvOffset locates the convolution's shape in the header
+const flatConvOffset = 60
+
+// Header flag bits
+const (
+	flatDueling = 1 << iota
+	flatNoisy
+	flatRecurrent
+	flatConv
+	flatHeads
+)
+
+// saveOptionsForPath picks the default format for a file name
+func saveOptionsForPath(path string) SaveOptions {
+	if strings.HasSuffix(path, flatExtension) {
+		return SaveOptions{Codec: CodecFlat, Precision: Float32}
+	}
+	if strings.HasSuffix(path, npzExtension) {
+		return SaveOptions{Codec: CodecNPZ, Precision: Float64}
+	}
+	if strings.HasSuffix(path, jsonExtension) {
+		return SaveOptions{Codec: CodecJSON, Precision: Float64}
+	}
+	return DefaultSaveOptions()
+}
+
+// paramSizes returns the length of each array in the flat payload
+func (h flatHeader) paramSizes() []int {
+	layer2Size := h.hiddenSize2
+	if h.recurrent {
+		layer2Size *= 3
+	}
+	in1, out1 := h.conv.layer1Dims(h.inputSize, h.hiddenSize1)
+	sizes := []int{
+		out1 * in1, out1,
+		layer2Size * h.hiddenSize1, layer2Size,
+		h.outputSize * h.hiddenSize2, h.outputSize,
+	}
+	if h.dueling {
+		sizes = append(sizes, h.hiddenSize2, 1)
+	}
+	if h.recurrent {
+		sizes = append(sizes, layer2Size*h.hiddenSize2)
+	}
+	if h.noisy {
+		sizes = append(sizes, sizes...)
+	}
+	return sizes
+}
+
+// paramCount returns the number of values in the flat payload
+func (h flatHeader) paramCount() int {
+	count := 0
+	for _, size := range h.paramSizes() {
+		count += size
+	}
+	return count
+}
+
+// writeFlat writes the network in the flat binary format
+func (n *QNetwork) writeFlat(w io.Writer, precision Precision) error {
+	valueSize := 8
+	switch precision {
+	case Float32:
+		valueSize = 4
+	case Float64:
+	default:
+		return fmt.Errorf("unknown precision %q", precision)
+	}
+
+	header := make([]byte, flatHeaderSize)
+	copy(header, flatMagic)
+	binary.LittleEndian.PutUint16(header[4:], uint16(n.formatVersion()))
+	header[6] = byte(valueSize)
+	if n.Dueling {
+		header[7] |= flatDueling
+	}
+	if n.Noisy {
+		header[7] |= flatNoisy
+	}
+	if n.Recurrent {
+		header[7] |= flatRecurrent
+	}
+	if n.convolutional() {
+		header[7] |= flatConv
+		for k, v := range []int{n.Conv.Channels, n.Conv.Height, n.Conv.Width, n.Conv.Filters} {
+			if v > math.MaxUint8 {
+				return fmt.Errorf("convolution shape %+v is too large for the flat format", n.Conv)
+			}
+			header[flatConvOffset+k] = byte(v)
+		}
+	}
+	binary.LittleEndian.PutUint32(header[8:], uint32(n.InputSize))
+	binary.LittleEndian.PutUint32(header[12:], uint32(n.HiddenSize1))
+	binary.LittleEndian.PutUint32(header[16:], uint32(n.HiddenSize2))
+	binary.LittleEndian.PutUint32(header[20:], uint32(n.OutputSize))
+	binary.LittleEndian.PutUint64(header[24:], math.Float64bits(n.LearningRate))
+	if len(n.Encoder) > flatEncoderLen {
+		return fmt.Errorf("encoder name %q is too long for the flat format", n.Encoder)
+	}
+	copy(header[flatEncoderOffset:], n.Encoder)
+	binary.LittleEndian.PutUint32(header[flatAtomsOffset:], uint32(n.Atoms))
+	if n.Heads > 0 {
+		header[7] |= flatHeads
+		binary.LittleEndian.PutUint32(header[flatAtomsOffset:], uint32(n.Heads))
+	}
+	binary.LittleEndian.PutUint32(header[flatAtomsOffset+4:], math.Float32bits(float32(n.VMin)))
+	binary.LittleEndian.PutUint32(header[flatAtomsOffset+8:], math.Float32bits(float32(n.VMax)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, param := range n.params() {
+		buf := make([]byte, len(param)*valueSize)
+		for i, v := range param {
+			if valueSize == 4 {
+				binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(v)))
+			} else {
+				binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(v))
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeFlatHeader parses and checks the flat format header
+func decodeFlatHeader(data []byte) (flatHeader, error) {
+	if len(data) < flatHeaderSize {
+		return flatHeader{}, errors.New("truncated flat model header")
+	}
+	h := flatHeader{
+		version:      int(binary.LittleEndian.Uint16(data[4:])),
+		valueSize:    int(data[6]),
+		inputSize:    int(binary.LittleEndian.Uint32(data[8:])),
+		hiddenSize1:  int(binary.LittleEndian.Uint32(data[12:])),
+		hiddenSize2:  int(binary.LittleEndian.Uint32(data[16:])),
+		outputSize:   int(binary.LittleEndian.Uint32(data[20:])),
+		learningRate: math.Float64frombits(binary.LittleEndian.Uint64(data[24:])),
+		encoder:      encoderOrDefault(strings.TrimRight(string(data[flatEncoderOffset:flatEncoderOffset+flatEncoderLen]), "\x00")),
+		dueling:      data[7]&flatDueling != 0,
+		noisy:        data[7]&flatNoisy != 0,
+		recurrent:    data[7]&flatRecurrent != 0,
+		atoms:        int(binary.LittleEndian.Uint32(data[flatAtomsOffset:])),
+		vMin:         float64(math.Float32frombits(binary.LittleEndian.Uint32(data[flatAtomsOffset+4:]))),
+		vMax:         float64(math.Float32frombits(binary.LittleEndian.Uint32(data[flatAtomsOffset+8:]))),
+	}
+	if data[7]&flatHeads != 0 {
+		h.heads, h.atoms = h.atoms, 0
+	}
+	if data[7]&flatConv != 0 {
+		c := data[flatConvOffset:]
+		h.conv = ConvShape{Channels: int(c[0]), Height: int(c[1]), Width: int(c[2]), Filters: int(c[3])}
+	}
+	if h.version > ModelFormatVersion {
+		return h, fmt.Errorf("model format version %d is newer than supported version %d", h.version, ModelFormatVersion)
+	}
+	if h.valueSize != 4 && h.valueSize != 8 {
+		return h, fmt.Errorf("unsupported value size %d", h.valueSize)
+	}
+	if err := checkSupport(h.atoms, h.outputSize, h.vMin, h.vMax, h.dueling); err != nil {
+		return h, err
+	}
+	if err := checkHeads(h.heads, h.outputSize, h.dueling, h.atoms, h.recurrent); err != nil {
+		return h, err
+	}
+	if err := checkRecurrent(h.recurrent, h.noisy); err != nil {
+		return h, err
+	}
+	if err := checkConv(h.conv, h.inputSize, h.hiddenSize1, h.noisy); err != nil {
+		return h, err
+	}
+	if want := flatHeaderSize + h.paramCount()*h.valueSize; len(data) != want {
+		return h, fmt.Errorf("flat model has %d bytes, expected %d", len(data), want)
+	}
+	return h, nil
+}
+
+// info describes the header as a ModelInfo
+func (h flatHeader) info() ModelInfo {
+	info := ModelInfo{Kind: ModelVersioned, Version: h.version, Codec: CodecFlat, Precision: Float64}
+	if h.valueSize == 4 {
+		info.Precision = Float32
+	}
+	return info
+}
+
+// decodeFlat builds a network from a flat format file's contents
+func decodeFlat(data []byte) (ModelInfo, *QNetwork, error) {
+	h, err := decodeFlatHeader(data)
+	if err != nil {
+		return ModelInfo{}, nil, err
+	}
+
+	net := &QNetwork{
+		InputSize:    h.inputSize,
+		HiddenSize1:  h.hiddenSize1,
+		HiddenSize2:  h.hiddenSize2,
+		OutputSize:   h.outputSize,
+		LearningRate: h.learningRate,
+		Encoder:      h.encoder,
+		Dueling:      h.dueling,
+		Noisy:        h.noisy,
+		Recurrent:    h.recurrent,
+		Conv:         h.conv,
+		Atoms:        h.atoms,
+		VMin:         h.vMin,
+		VMax:         h.vMax,
+		Heads:        h.heads,
+		rng:          rand.New(rand.NewSource(0)),
+	}
+	net.allocParams()
+
+	offset := flatHeaderSize
+	for _, param := range net.params() {
+		for i := range param {
+			if h.valueSize == 4 {
+				param[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[offset:])))
+			} else {
+				param[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[offset:]))
+			}
+			offset += h.valueSize
+		}
+	}
+	return h.info(), net, nil
+}
+
+// mapFlat builds a network whose weights alias data directly instead of
+// being copied. It requires a float64 file and a little-endian host; data
+// must stay valid (and unmodified) for the lifetime of the network.
+func mapFlat(data []byte) (*QNetwork, error) {
+	h, err := decodeFlatHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if h.valueSize != 8 {
+		return nil, errors.New("memory mapping requires a float64 flat model (convert with -codec flat -precision float64)")
+	}
+	if !hostLittleEndian() {
+		return nil, errors.New("memory mapping requires a little-endian host")
+	}
+
+	net := &QNetwork{
+		InputSize:    h.inputSize,
+		HiddenSize1:  h.hiddenSize1,
+		HiddenSize2:  h.hiddenSize2,
+		OutputSize:   h.outputSize,
+		LearningRate: h.learningRate,
+		Encoder:      h.encoder,
+		Dueling:      h.dueling,
+		Noisy:        h.noisy,
+		Recurrent:    h.recurrent,
+		Conv:         h.conv,
+		Atoms:        h.atoms,
+		VMin:         h.vMin,
+		VMax:         h.vMax,
+		Heads:        h.heads,
+		rng:          rand.New(rand.NewSource(0)),
+		readOnly:     true,
+	}
+
+	offset := flatHeaderSize
+	sizes := h.paramSizes()
+	params := make([][]float64, len(sizes))
+	for k, size := range sizes {
+		params[k] = unsafe.Slice((*float64)(unsafe.Pointer(&data[offset])), size)
+		offset += size * 8
+	}
+	net.W1, net.B1, net.W2, net.B2, net.W3, net.B3 = params[0], params[1], params[2], params[3], params[4], params[5]
+	next := 6
+	if h.dueling {
+		net.WV, net.BV = params[6], params[7]
+		next += 2
+	}
+	if h.recurrent {
+		net.U2 = params[next]
+	}
+	if h.noisy {
+		net.Sigma = params[len(params)/2:]
+	}
+	return net, nil
+}
+
+// hostLittleEndian reports whether the machine stores integers little-endian
+func hostLittleEndian() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}
+
+// MappedNetwork is a read-only network backed by a memory-mapped flat model
+// file. Many processes (or many models in one process) can share the page
+// cache instead of each holding a heap copy of the weights. Any attempt to
+// train or overwrite it panics; Clone it to get a writable copy.
+type MappedNetwork struct {
+	*QNetwork
+	release func() error
+}
+
+// Close unmaps the file. The network must not be used afterwards.
+func (m *MappedNetwork) Close() error {
+	m.QNetwork = nil
+	if m.release == nil {
+		return nil
+	}
+	err := m.release()
+	m.release = nil
+	return err
+}
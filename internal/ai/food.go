@@ -0,0 +1,60 @@
+package ai
+
+import "autonomous-snake/internal/game"
+
+// FoodEncoderName is the encoder that adds what the food is worth and how
+// long it lasts to the 22 features, see EncodeStateFoodInto
+const FoodEncoderName = "food"
+
+// FoodFeatureSize is the number of food features after the 22
+const FoodFeatureSize = 6
+
+// EncodeStateFoodInto writes the 22 features of EncodeStateInto and then
+// the food features into dst, which must have length
+// StateSize+FoodFeatureSize: whether the nearest pellet, the one features
+// 7-10 point at, is golden, the direction of the nearest golden pellet as
+// up, down, left and right, and the fraction of its lifespan the nearest
+// pellet has left before it expires or moves, see GameState.FoodTimeLeft.
+// A dead snake sees all zeros.
+func EncodeStateFoodInto(dst []float64, state *game.GameState, snakeID int) {
+	EncodeStateInto(dst, state, snakeID)
+	food := dst[StateSize : StateSize+FoodFeatureSize]
+	clear(food)
+
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return
+	}
+	head := snake.Head()
+	if nearest, ok := state.NearestFood(head); ok {
+		food[0] = boolToFloat(state.IsGolden(nearest))
+		food[5] = state.FoodTimeLeft(nearest)
+	}
+
+	golden, found := game.Position{}, false
+	for _, pos := range state.Golden {
+		if !found || game.ManhattanDistance(head, pos) < game.ManhattanDistance(head, golden) {
+			golden, found = pos, true
+		}
+	}
+	if found {
+		food[1] = boolToFloat(golden.Y < head.Y)
+		food[2] = boolToFloat(golden.Y > head.Y)
+		food[3] = boolToFloat(golden.X < head.X)
+		food[4] = boolToFloat(golden.X > head.X)
+	}
+}
+
+// foodEncoder is the features-plus-food encoder
+type foodEncoder struct{}
+
+// Name returns the encoder's name
+func (foodEncoder) Name() string { return FoodEncoderName }
+
+// Size returns the number of features
+func (foodEncoder) Size() int { return StateSize + FoodFeatureSize }
+
+// Encode writes the features and food features into dst
+func (foodEncoder) Encode(dst []float64, state *game.GameState, snakeID int) {
+	EncodeStateFoodInto(dst, state, snakeID)
+}
@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestFoodEncoder(t *testing.T) {
+	// Snake 0 heads right with a plain pellet just ahead and a golden one
+	// further off, above and to its left
+	state := &game.GameState{
+		Width:  10,
+		Height: 10,
+		Snakes: [2]*game.Snake{
+			game.NewSnake(0, game.Position{X: 5, Y: 5}, game.Right, 3),
+			game.NewSnake(1, game.Position{X: 1, Y: 8}, game.Right, 1),
+		},
+		Food:      game.Food{Position: game.Position{X: 6, Y: 5}, Active: true},
+		ExtraFood: []game.Position{{X: 1, Y: 1}},
+		Golden:    []game.Position{{X: 1, Y: 1}},
+	}
+	enc, err := LookupEncoder(FoodEncoderName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]float64, enc.Size())
+	enc.Encode(dst, state, 0)
+
+	features := EncodeState(state, 0)
+	for i, v := range features {
+		if dst[i] != v {
+			t.Fatalf("feature %d is %v, want %v", i, dst[i], v)
+		}
+	}
+	want := []float64{0, 1, 0, 1, 0, 1}
+	for i, v := range want {
+		if got := dst[StateSize+i]; got != v {
+			t.Errorf("food feature %d is %v, want %v", i, got, v)
+		}
+	}
+
+	// Once the golden pellet is the nearest, the first feature says so, and
+	// the last how much of its lifespan is left
+	state.Food.Active = false
+	state.Turn, state.FoodLifespan = 5, 8
+	state.FoodExpires = map[game.Position]int{{X: 1, Y: 1}: 7}
+	enc.Encode(dst, state, 0)
+	if dst[StateSize] != 1 {
+		t.Error("nearest pellet is golden but the feature is 0")
+	}
+	if got := dst[StateSize+5]; got != 0.25 {
+		t.Errorf("time left %v, want 0.25", got)
+	}
+}
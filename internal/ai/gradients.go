@@ -0,0 +1,137 @@
+package ai
+
+// gradients accumulates loss gradients for every network parameter, using the
+// same flat layout as QNetwork so they can be applied in one update
+type gradients struct {
+	W1, B1 []float64
+	W2, B2 []float64
+	W3, B3 []float64
+	WV, BV []float64   // Dueling networks only
+	U2     []float64   // Recurrent networks only
+	Sigma  [][]float64 // Noisy networks only
+}
+
+// newGradients allocates zeroed gradient buffers shaped like the network
+func newGradients(n *QNetwork) *gradients {
+	g := &gradients{
+		W1: make([]float64, len(n.W1)),
+		B1: make([]float64, len(n.B1)),
+		W2: make([]float64, len(n.W2)),
+		B2: make([]float64, len(n.B2)),
+		W3: make([]float64, len(n.W3)),
+		B3: make([]float64, len(n.B3)),
+	}
+	if n.Dueling {
+		g.WV = make([]float64, len(n.WV))
+		g.BV = make([]float64, len(n.BV))
+	}
+	if n.Recurrent {
+		g.U2 = make([]float64, len(n.U2))
+	}
+	for _, sigma := range n.Sigma {
+		g.Sigma = append(g.Sigma, make([]float64, len(sigma)))
+	}
+	return g
+}
+
+// params returns the gradient buffers in the same order as QNetwork.params
+func (g *gradients) params() [][]float64 {
+	params := [][]float64{g.W1, g.B1, g.W2, g.B2, g.W3, g.B3}
+	if g.WV != nil {
+		params = append(params, g.WV, g.BV)
+	}
+	if g.U2 != nil {
+		params = append(params, g.U2)
+	}
+	return append(params, g.Sigma...)
+}
+
+// zero resets all gradients
+func (g *gradients) zero() {
+	for _, p := range g.params() {
+		for i := range p {
+			p[i] = 0
+		}
+	}
+}
+
+// add accumulates other into g
+func (g *gradients) add(other *gradients) {
+	dst := g.params()
+	for k, src := range other.params() {
+		backend.Axpy(1, src, dst[k])
+	}
+}
+
+// accumulateGradients backpropagates dOutput through the activations in
+// cache and adds the parameter gradients to g without touching the weights.
+// For dueling networks dOutput is overwritten with the advantage gradient.
+func (n *QNetwork) accumulateGradients(cache *forwardCache, dOutput []float64, g *gradients) {
+	n.accumulateStep(cache, dOutput, nil, g)
+}
+
+// accumulateStep is accumulateGradients for one step of a sequence through
+// a recurrent network: dHidden, if not nil, is the gradient w.r.t. the
+// step's hidden state from later steps. The gradient w.r.t. the previous
+// hidden state is left in cache.dHPrev.
+func (n *QNetwork) accumulateStep(cache *forwardCache, dOutput, dHidden []float64, g *gradients) {
+	// Layer 3, and the value stream beside it
+	if n.Dueling {
+		splitStreamGradient(dOutput, cache.dValue)
+		backend.Rank1Update(g.WV, 1, cache.dValue, cache.h2)
+		backend.Axpy(1, cache.dValue, g.BV)
+		n.linearBack(cache, 3, n.WV, cache.dValue, cache.dHV)
+		n.noisyAccumulate(cache, 3, g)
+	}
+	backend.Rank1Update(g.W3, 1, dOutput, cache.h2)
+	backend.Axpy(1, dOutput, g.B3)
+	n.linearBack(cache, 2, n.W3, dOutput, cache.dH2)
+	n.noisyAccumulate(cache, 2, g)
+	if n.Dueling {
+		backend.Axpy(1, cache.dHV, cache.dH2)
+	}
+	if n.Recurrent {
+		if dHidden != nil {
+			backend.Axpy(1, dHidden, cache.dH2)
+		}
+		n.gruBackward(cache)
+		accumulateRecurrent(cache, g, n.HiddenSize2)
+	} else {
+		reluBackward(cache.dZ2, cache.dH2, cache.z2)
+	}
+
+	// Layer 2
+	backend.Rank1Update(g.W2, 1, cache.dZ2, cache.h1)
+	backend.Axpy(1, cache.dZ2, g.B2)
+	n.linearBack(cache, 1, n.W2, cache.dZ2, cache.dH1)
+	n.noisyAccumulate(cache, 1, g)
+	dropBackward(cache)
+	reluBackward(cache.dZ1, cache.dH1, cache.z1)
+
+	// Layer 1
+	if n.convolutional() {
+		n.convAccumulate(cache, g)
+		return
+	}
+	backend.Rank1Update(g.W1, 1, cache.dZ1, cache.input)
+	backend.Axpy(1, cache.dZ1, g.B1)
+	n.linearBack(cache, 0, n.W1, cache.dZ1, nil)
+	n.noisyAccumulate(cache, 0, g)
+}
+
+// inputGradient writes the gradient w.r.t. the input of the pass last
+// backpropagated through cache by accumulateGradients to dInput. Layer 1
+// must be dense.
+func (n *QNetwork) inputGradient(cache *forwardCache, dInput []float64) {
+	backend.MatTVec(n.W1, cache.dZ1, dInput)
+}
+
+// applyGradients performs one SGD step, W -= lr * scale * dW
+func (n *QNetwork) applyGradients(g *gradients, scale float64) {
+	n.checkWritable()
+	alpha := -n.LearningRate * scale
+	weights := n.params()
+	for k, grad := range g.params() {
+		backend.Axpy(alpha, grad, weights[k])
+	}
+}
@@ -0,0 +1,142 @@
+package ai
+
+import "autonomous-snake/internal/game"
+
+// GridEncoderName is the encoder that emits the whole board as planes, see
+// EncodeStateGrid
+const GridEncoderName = "grid"
+
+// GridMaxBoard is the widest and tallest board the grid encoder shows in
+// full; GridSize leaves room for a wall border around it
+const (
+	GridMaxBoard = 20
+	GridSize     = GridMaxBoard + 2
+)
+
+// Grid planes, in encoding order. GridPlanes is their number.
+const (
+	PlaneOwnBody = iota
+	PlaneOwnHead
+	PlaneOpponent
+	PlaneFood
+	PlaneWalls
+	GridPlanes
+)
+
+// GridStateSize is the number of values in a grid encoding
+const GridStateSize = GridPlanes * GridSize * GridSize
+
+// EncodeStateGrid converts the game state to board planes from the
+// specified snake's view, see EncodeStateGridInto
+func EncodeStateGrid(state *game.GameState, snakeID int) []float64 {
+	planes := make([]float64, GridStateSize)
+	EncodeStateGridInto(planes, state, snakeID)
+	return planes
+}
+
+// EncodeStateGridInto writes GridPlanes planes of GridSize×GridSize cells
+// into planes, which must have length GridStateSize: the snake's body
+// without its head, its head, the opponent's body, food, and walls,
+// including a board map's obstacles. Each
+// cell is 1 where the plane's object is and 0 elsewhere.
+//
+// The board is rotated so the snake heads up the planes, making turns the
+// same whichever way it faces, and drawn one cell in from the edges. Cells
+// off the board are walls; boards bigger than GridMaxBoard are cut off at
+// the far edges. A dead snake sees all zeros, like EncodeStateInto.
+func EncodeStateGridInto(planes []float64, state *game.GameState, snakeID int) {
+	planes = planes[:GridStateSize]
+	clear(planes)
+
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return
+	}
+	view := gridView{width: state.Width, height: state.Height, dir: snake.Direction}
+	plane := func(k int) []float64 {
+		return planes[k*GridSize*GridSize : (k+1)*GridSize*GridSize]
+	}
+	mark := func(k int, pos game.Position) {
+		if cell, ok := view.cell(pos); ok {
+			plane(k)[cell] = 1
+		}
+	}
+
+	walls := plane(PlaneWalls)
+	for i := range walls {
+		walls[i] = 1
+	}
+	for y := 0; y < state.Height; y++ {
+		for x := 0; x < state.Width; x++ {
+			if cell, ok := view.cell(game.Position{X: x, Y: y}); ok {
+				walls[cell] = 0
+			}
+		}
+	}
+	for _, pos := range state.Obstacles {
+		mark(PlaneWalls, pos)
+	}
+
+	for i, segment := range snake.Body {
+		if i == 0 {
+			mark(PlaneOwnHead, segment)
+		} else {
+			mark(PlaneOwnBody, segment)
+		}
+	}
+	if other := state.Snakes[1-snakeID]; other.Alive {
+		for _, segment := range other.Body {
+			mark(PlaneOpponent, segment)
+		}
+	}
+	if state.Food.Active {
+		mark(PlaneFood, state.Food.Position)
+	}
+	for _, food := range state.ExtraFood {
+		mark(PlaneFood, food)
+	}
+}
+
+// gridView maps board positions to plane cells for a snake heading dir
+type gridView struct {
+	width, height int
+	dir           game.Direction
+}
+
+// cell returns the index of pos within a plane, or false if it falls
+// outside the planes
+func (v gridView) cell(pos game.Position) (int, bool) {
+	x, y := pos.X, pos.Y
+	switch v.dir {
+	case game.Down:
+		x, y = v.width-1-x, v.height-1-y
+	case game.Left:
+		x, y = v.height-1-y, x
+	case game.Right:
+		x, y = y, v.width-1-x
+	}
+	x, y = x+1, y+1
+	if x < 0 || y < 0 || x >= GridSize || y >= GridSize {
+		return 0, false
+	}
+	return y*GridSize + x, true
+}
+
+// gridEncoder is the board-plane encoder
+type gridEncoder struct{}
+
+// Name returns the encoder's name
+func (gridEncoder) Name() string { return GridEncoderName }
+
+// Size returns the number of values in all planes
+func (gridEncoder) Size() int { return GridStateSize }
+
+// Planes returns the number and size of the planes
+func (gridEncoder) Planes() (channels, height, width int) {
+	return GridPlanes, GridSize, GridSize
+}
+
+// Encode writes the planes into dst
+func (gridEncoder) Encode(dst []float64, state *game.GameState, snakeID int) {
+	EncodeStateGridInto(dst, state, snakeID)
+}
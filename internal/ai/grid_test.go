@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestGridEncoder(t *testing.T) {
+	// A 6x4 board with snake 0 heading right towards food, and snake 1
+	// heading down in the corner
+	state := &game.GameState{
+		Width:  6,
+		Height: 4,
+		Snakes: [2]*game.Snake{
+			{ID: 0, Body: []game.Position{{X: 2, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}}, Direction: game.Right, Alive: true},
+			{ID: 1, Body: []game.Position{{X: 5, Y: 3}, {X: 5, Y: 2}}, Direction: game.Down, Alive: true},
+		},
+		Food: game.Food{Position: game.Position{X: 4, Y: 1}, Active: true},
+	}
+	planes := EncodeStateGrid(state, 0)
+	at := func(plane, x, y int) float64 {
+		return planes[plane*GridSize*GridSize+y*GridSize+x]
+	}
+	count := func(plane int) int {
+		n := 0
+		for _, v := range planes[plane*GridSize*GridSize : (plane+1)*GridSize*GridSize] {
+			n += int(v)
+		}
+		return n
+	}
+
+	// Rotated so snake 0 heads up: the board is 4 cells wide and 6 tall,
+	// inside the wall border, with the food straight ahead and the body
+	// trailing behind
+	cells := []struct {
+		name        string
+		plane, x, y int
+	}{
+		{"head", PlaneOwnHead, 2, 4},
+		{"neck", PlaneOwnBody, 2, 5},
+		{"tail", PlaneOwnBody, 3, 5},
+		{"food", PlaneFood, 2, 2},
+		{"opponent head", PlaneOpponent, 4, 1},
+		{"opponent tail", PlaneOpponent, 3, 1},
+		{"corner wall", PlaneWalls, 0, 0},
+		{"right wall", PlaneWalls, 5, 3},
+		{"bottom wall", PlaneWalls, 2, 7},
+	}
+	for _, c := range cells {
+		if at(c.plane, c.x, c.y) != 1 {
+			t.Errorf("%s: cell (%d,%d) of plane %d is empty", c.name, c.x, c.y, c.plane)
+		}
+	}
+	wantCounts := map[int]int{
+		PlaneOwnHead:  1,
+		PlaneOwnBody:  2,
+		PlaneOpponent: 2,
+		PlaneFood:     1,
+		PlaneWalls:    GridSize*GridSize - 6*4,
+	}
+	for plane, want := range wantCounts {
+		if got := count(plane); got != want {
+			t.Errorf("plane %d has %d cells set, want %d", plane, got, want)
+		}
+	}
+
+	// Snake 1 sees itself heading up too
+	planes = EncodeStateGrid(state, 1)
+	if at(PlaneOwnHead, 1, 1) != 1 || at(PlaneOwnBody, 1, 2) != 1 || at(PlaneOpponent, 4, 3) != 1 {
+		t.Error("snake 1's view is not rotated to head up")
+	}
+
+	// Dead snakes see nothing; dead opponents disappear
+	state.Snakes[1].Alive = false
+	if planes = EncodeStateGrid(state, 0); count(PlaneOpponent) != 0 {
+		t.Error("dead opponent still drawn")
+	}
+	if planes = EncodeStateGrid(state, 1); count(PlaneWalls) != 0 {
+		t.Error("dead snake's view is not empty")
+	}
+
+	enc, err := LookupEncoder(GridEncoderName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Size() != GridStateSize {
+		t.Errorf("Size = %d, want %d", enc.Size(), GridStateSize)
+	}
+}
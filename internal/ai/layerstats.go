@@ -0,0 +1,156 @@
+package ai
+
+import "math"
+
+// LayerStats describes the health of one layer of a network, to tell a
+// diverging or dying network apart from one that is merely slow to learn
+type LayerStats struct {
+	Name         string  // layer1, layer2, output, or value for a dueling network's value stream
+	WeightNorm   float64 // L2 norm of the layer's weights and biases
+	GradNorm     float64 // L2 norm of the mean loss gradient over the probe batch; agents only
+	DeadFraction float64 // Share of the layer's ReLU units that output 0 for every probe state; hidden ReLU layers only
+}
+
+// LayerStatsAgent is an Agent that can measure its network's layers on its
+// replay buffer
+type LayerStatsAgent interface {
+	Agent
+	LayerStats() []LayerStats
+}
+
+// layerArrays names a layer and the indices of its arrays in params
+type layerArrays struct {
+	name   string
+	arrays []int
+}
+
+// layers groups the network's parameter arrays by layer. Noisy networks'
+// sigmas are left out, so norms cover the mean weights.
+func (n *QNetwork) layers() []layerArrays {
+	layers := []layerArrays{{"layer1", []int{0, 1}}, {"layer2", []int{2, 3}}, {"output", []int{4, 5}}}
+	next := 6
+	if n.Dueling {
+		layers = append(layers, layerArrays{"value", []int{6, 7}})
+		next = 8
+	}
+	if n.Recurrent {
+		layers[1].arrays = append(layers[1].arrays, next)
+	}
+	return layers
+}
+
+// LayerStats returns each layer's weight norm and the share of its ReLU
+// units that stay at 0 over states, running the mean weights without
+// dropout. Layer 2 of a recurrent network is a GRU and has no dead units.
+func (n *QNetwork) LayerStats(states [][]float64) []LayerStats {
+	layers := n.layers()
+	stats := make([]LayerStats, len(layers))
+	norms := layerNorms(layers, n.params())
+	for i, layer := range layers {
+		stats[i] = LayerStats{Name: layer.name, WeightNorm: norms[i]}
+	}
+	if len(states) == 0 {
+		return stats
+	}
+
+	cache := newProbeCache(n)
+	alive1 := make([]bool, len(cache.h1))
+	alive2 := make([]bool, len(cache.h2))
+	for _, state := range states {
+		n.forwardWith(cache, state)
+		for i, h := range cache.h1 {
+			alive1[i] = alive1[i] || h > 0
+		}
+		for i, h := range cache.h2 {
+			alive2[i] = alive2[i] || h > 0
+		}
+	}
+	stats[0].DeadFraction = deadFraction(alive1)
+	if !n.Recurrent {
+		stats[1].DeadFraction = deadFraction(alive2)
+	}
+	return stats
+}
+
+// LayerStats measures the policy network on up to a batch of experiences
+// spread over the replay buffer: LayerStats of their states plus the
+// gradient of the training loss, before clipping. It leaves the weights,
+// the buffer and every random source alone.
+func (a *DQNAgent) LayerStats() []LayerStats {
+	if a.Conservative > 0 {
+		return a.layerStats(a.conservativeLoss)
+	}
+	return a.layerStats(a.dqnLoss)
+}
+
+// LayerStats measures the network like DQNAgent.LayerStats, on the
+// distributional loss
+func (c *C51Agent) LayerStats() []LayerStats {
+	return c.layerStats(c.c51Loss)
+}
+
+// LayerStats measures the network like DQNAgent.LayerStats, on the loss of
+// the heads each experience's mask picks
+func (b *BootstrapAgent) LayerStats() []LayerStats {
+	return b.layerStats(b.bootLoss)
+}
+
+// layerStats measures the policy network with computeLoss as the training
+// loss
+func (a *DQNAgent) layerStats(computeLoss sampleLoss) []LayerStats {
+	batch := a.ReplayBuffer.spread(a.BatchSize)
+	states := make([][]float64, len(batch))
+	for i, exp := range batch {
+		states[i] = exp.State
+	}
+	stats := a.PolicyNet.LayerStats(states)
+	if len(batch) == 0 {
+		return stats
+	}
+
+	policyCache, targetCache := newProbeCache(a.PolicyNet), newProbeCache(a.TargetNet)
+	grads := newGradients(a.PolicyNet)
+	for _, exp := range batch {
+		computeLoss(exp, 1, policyCache, targetCache, policyCache.dOutput)
+		a.PolicyNet.accumulateGradients(policyCache, policyCache.dOutput, grads)
+	}
+	for i, norm := range layerNorms(a.PolicyNet.layers(), grads.params()) {
+		stats[i].GradNorm = norm / float64(len(batch))
+	}
+	return stats
+}
+
+// layerNorms returns the L2 norm of each layer's arrays in params
+func layerNorms(layers []layerArrays, params [][]float64) []float64 {
+	norms := make([]float64, len(layers))
+	for i, layer := range layers {
+		for _, k := range layer.arrays {
+			norms[i] += dot(params[k], params[k])
+		}
+		norms[i] = math.Sqrt(norms[i])
+	}
+	return norms
+}
+
+// deadFraction returns the share of units that never activated
+func deadFraction(alive []bool) float64 {
+	dead := 0
+	for _, a := range alive {
+		if !a {
+			dead++
+		}
+	}
+	return float64(dead) / float64(len(alive))
+}
+
+// spread returns up to n experiences evenly spaced over the buffer, oldest
+// first, without drawing on its random source
+func (rb *ReplayBuffer) spread(n int) []Experience {
+	n = min(n, rb.size)
+	exps := make([]Experience, n)
+	start := rb.position - rb.size + rb.capacity
+	for i := range exps {
+		exps[i] = rb.buffer[(start+i*rb.size/n)%rb.capacity]
+	}
+	return exps
+}
@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestLayerStats(t *testing.T) {
+	net := NewDuelingQNetwork(4, 6, 5, 3, 0.01, 1)
+
+	// Half of layer 1 can never activate
+	for j := range net.HiddenSize1 {
+		for i := range net.InputSize {
+			net.W1[j*net.InputSize+i] = 0
+		}
+		net.B1[j] = 1
+		if j%2 == 0 {
+			net.B1[j] = -1
+		}
+	}
+	stats := net.LayerStats([][]float64{{1, 0, 0, 1}, {0, 1, 1, 0}})
+	names := []string{"layer1", "layer2", "output", "value"}
+	if len(stats) != len(names) {
+		t.Fatalf("%d layers, want %d", len(stats), len(names))
+	}
+	for i, s := range stats {
+		if s.Name != names[i] {
+			t.Errorf("layer %d is %s, want %s", i, s.Name, names[i])
+		}
+	}
+	if stats[0].DeadFraction != 0.5 {
+		t.Errorf("layer 1 dead fraction %v, want 0.5", stats[0].DeadFraction)
+	}
+	if want := math.Sqrt(dot(net.W3, net.W3) + dot(net.B3, net.B3)); math.Abs(stats[2].WeightNorm-want) > 1e-12 {
+		t.Errorf("output weight norm %v, want %v", stats[2].WeightNorm, want)
+	}
+
+	// On a buffer holding exactly one batch, the layers' gradients make up
+	// the norm a mini-batch update records
+	cfg := config.DefaultTrainingConfig()
+	cfg.BatchSize = 16
+	cfg.MiniBatch = true
+	agent := NewDQNAgent(cfg, 1)
+	rng := rand.New(rand.NewSource(2))
+	for range cfg.BatchSize {
+		state, next := make([]float64, cfg.InputSize), make([]float64, cfg.InputSize)
+		for i := range state {
+			state[i], next[i] = rng.Float64(), rng.Float64()
+		}
+		agent.Remember(state, Action(rng.Intn(int(NumActions))), rng.Float64()*2-1, next, rng.Intn(4) == 0)
+	}
+	layers := agent.LayerStats()
+	total := 0.0
+	for _, s := range layers {
+		total += s.GradNorm * s.GradNorm
+	}
+	agent.TrainBatch()
+	if want := agent.Diagnostics().GradNorm; math.Abs(math.Sqrt(total)-want) > 1e-9*want {
+		t.Errorf("layer gradient norms add up to %v, the update's is %v", math.Sqrt(total), want)
+	}
+}
@@ -0,0 +1,162 @@
+package ai
+
+// Dense layer math on flat, row-major weight matrices. A layer with `in`
+// inputs and `out` outputs stores its weights as w[j*in+i], so each output
+// neuron's weights are contiguous and the forward pass is a sequence of dot
+// products over adjacent memory.
+
+// dot returns the dot product of a and b[:len(a)]
+// The loop is unrolled with independent accumulators so the compiler can
+// keep them in registers and overlap the multiply-adds.
+func dot(a, b []float64) float64 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float64
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
+// axpy computes y += alpha * x
+func axpy(alpha float64, x, y []float64) {
+	y = y[:len(x)]
+	i := 0
+	for ; i+4 <= len(x); i += 4 {
+		y[i] += alpha * x[i]
+		y[i+1] += alpha * x[i+1]
+		y[i+2] += alpha * x[i+2]
+		y[i+3] += alpha * x[i+3]
+	}
+	for ; i < len(x); i++ {
+		y[i] += alpha * x[i]
+	}
+}
+
+// matVec computes output = W·input + bias for a flat [out][in] matrix
+func matVec(weights []float64, bias, input, output []float64) {
+	in := len(input)
+	j := 0
+	for ; j+4 <= len(output); j += 4 {
+		s0, s1, s2, s3 := dot4(input, weights[j*in:(j+4)*in])
+		output[j] = bias[j] + s0
+		output[j+1] = bias[j+1] + s1
+		output[j+2] = bias[j+2] + s2
+		output[j+3] = bias[j+3] + s3
+	}
+	for ; j < len(output); j++ {
+		output[j] = bias[j] + dot(input, weights[j*in:(j+1)*in])
+	}
+}
+
+// dot4 returns the dot products of x with the four consecutive rows of
+// rows, len(x) values each. Each x[i] is loaded once for all four rows,
+// which nearly halves the memory traffic of taking them one by one; one
+// accumulator per row is enough to keep the multiply-adds overlapping.
+func dot4(x, rows []float64) (s0, s1, s2, s3 float64) {
+	in := len(x)
+	r0 := rows[:in:in]
+	r1 := rows[in : 2*in : 2*in]
+	r2 := rows[2*in : 3*in : 3*in]
+	r3 := rows[3*in : 4*in : 4*in]
+	for i, v := range x {
+		s0 += v * r0[i]
+		s1 += v * r1[i]
+		s2 += v * r2[i]
+		s3 += v * r3[i]
+	}
+	return s0, s1, s2, s3
+}
+
+// batchMatVec computes outputs[k] = W·inputs[k] + bias for every input,
+// walking each block of weight rows once for the whole batch. It sums in
+// the same order as matVec, so both give identical results.
+func batchMatVec(weights []float64, bias []float64, inputs, outputs [][]float64) {
+	in := len(weights) / len(bias)
+	j := 0
+	for ; j+4 <= len(bias); j += 4 {
+		rows := weights[j*in : (j+4)*in]
+		for k, input := range inputs {
+			s0, s1, s2, s3 := dot4(input, rows)
+			out := outputs[k]
+			out[j] = bias[j] + s0
+			out[j+1] = bias[j+1] + s1
+			out[j+2] = bias[j+2] + s2
+			out[j+3] = bias[j+3] + s3
+		}
+	}
+	for ; j < len(bias); j++ {
+		row := weights[j*in : (j+1)*in]
+		for k, input := range inputs {
+			outputs[k][j] = bias[j] + dot(input, row)
+		}
+	}
+}
+
+// matTVec computes dInput = Wᵀ·dOutput for a flat [out][in] matrix. Rows
+// are added four at a time, in row order, so each dInput[i] is loaded and
+// stored once per block instead of once per row.
+func matTVec(weights []float64, dOutput, dInput []float64) {
+	in := len(dInput)
+	for i := range dInput {
+		dInput[i] = 0
+	}
+	j := 0
+	for ; j+4 <= len(dOutput); j += 4 {
+		d0, d1, d2, d3 := dOutput[j], dOutput[j+1], dOutput[j+2], dOutput[j+3]
+		if d0 == 0 && d1 == 0 && d2 == 0 && d3 == 0 {
+			continue
+		}
+		r0 := weights[j*in : (j+1)*in : (j+1)*in]
+		r1 := weights[(j+1)*in : (j+2)*in : (j+2)*in]
+		r2 := weights[(j+2)*in : (j+3)*in : (j+3)*in]
+		r3 := weights[(j+3)*in : (j+4)*in : (j+4)*in]
+		for i := range dInput {
+			dInput[i] = dInput[i] + d0*r0[i] + d1*r1[i] + d2*r2[i] + d3*r3[i]
+		}
+	}
+	for ; j < len(dOutput); j++ {
+		if d := dOutput[j]; d != 0 {
+			axpy(d, weights[j*in:(j+1)*in], dInput)
+		}
+	}
+}
+
+// rank1Update computes W += alpha · dOutput ⊗ input for a flat [out][in] matrix
+func rank1Update(weights []float64, alpha float64, dOutput, input []float64) {
+	in := len(input)
+	for j, d := range dOutput {
+		if d != 0 {
+			axpy(alpha*d, input, weights[j*in:(j+1)*in])
+		}
+	}
+}
+
+// flatten converts an [in][out] matrix to flat [out][in] storage
+func flatten(m [][]float64, in, out int) []float64 {
+	flat := make([]float64, in*out)
+	for i := 0; i < in; i++ {
+		for j := 0; j < out; j++ {
+			flat[j*in+i] = m[i][j]
+		}
+	}
+	return flat
+}
+
+// unflatten converts flat [out][in] storage back to an [in][out] matrix
+func unflatten(flat []float64, in, out int) [][]float64 {
+	m := make([][]float64, in)
+	for i := 0; i < in; i++ {
+		m[i] = make([]float64, out)
+		for j := 0; j < out; j++ {
+			m[i][j] = flat[j*in+i]
+		}
+	}
+	return m
+}
@@ -0,0 +1,74 @@
+package ai
+
+import "math"
+
+// LR range test defaults
+const (
+	DefaultLRFinderMin   = 1e-6
+	DefaultLRFinderMax   = 1
+	DefaultLRFinderSteps = 200
+
+	// lrSmoothing weights the exponential moving average of the loss
+	lrSmoothing = 0.98
+	// lrDivergence stops the test once the smoothed loss exceeds this
+	// multiple of the best loss seen
+	lrDivergence = 4
+)
+
+// LRPoint is the loss observed at one learning rate
+type LRPoint struct {
+	LR       float64
+	Loss     float64
+	Smoothed float64 // Bias-corrected moving average of Loss
+}
+
+// LRFinder runs a learning rate range test: one batch update per step with
+// the learning rate growing exponentially from Min to Max, recording the
+// loss. The test stops early once the loss blows up.
+type LRFinder struct {
+	Min   float64
+	Max   float64
+	Steps int
+}
+
+// NewLRFinder creates a range test with the default bounds
+func NewLRFinder() LRFinder {
+	return LRFinder{Min: DefaultLRFinderMin, Max: DefaultLRFinderMax, Steps: DefaultLRFinderSteps}
+}
+
+// Run trains agent through the range and returns the observed curve. The
+// agent's replay buffer must already hold at least a batch; its policy
+// network is left overtrained, so use a throwaway agent.
+func (f LRFinder) Run(agent *DQNAgent) []LRPoint {
+	points := make([]LRPoint, 0, f.Steps)
+	growth := math.Pow(f.Max/f.Min, 1/float64(max(f.Steps-1, 1)))
+	lr := f.Min
+	avg, best := 0.0, math.Inf(1)
+	for i := 0; i < f.Steps; i++ {
+		agent.PolicyNet.LearningRate = lr
+		loss := agent.TrainBatch()
+		avg = lrSmoothing*avg + (1-lrSmoothing)*loss
+		smoothed := avg / (1 - math.Pow(lrSmoothing, float64(i+1)))
+		points = append(points, LRPoint{LR: lr, Loss: loss, Smoothed: smoothed})
+
+		if math.IsNaN(smoothed) || math.IsInf(smoothed, 0) || (i > 0 && smoothed > lrDivergence*best) {
+			break
+		}
+		best = math.Min(best, smoothed)
+		lr *= growth
+	}
+	return points
+}
+
+// SuggestLR picks a learning rate from a range test curve: a tenth of the
+// rate with the lowest smoothed loss, safely below where training starts to
+// diverge. It returns 0 for an empty curve.
+func SuggestLR(points []LRPoint) float64 {
+	bestLR, bestLoss := 0.0, math.Inf(1)
+	for _, p := range points {
+		if p.Smoothed < bestLoss {
+			bestLR, bestLoss = p.LR, p.Smoothed
+		}
+	}
+	return bestLR / 10
+}
@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+// rangeTestCurve is a range test over 1e-6..1, ten rates a decade, whose
+// loss sits on a plateau, falls steeply to its lowest at 1e-2 and then
+// diverges, ending on a NaN
+func rangeTestCurve() []LRPoint {
+	var points []LRPoint
+	for e := -60; e <= 0; e++ {
+		lr := math.Pow(10, float64(e)/10)
+		x := math.Log10(lr) + 2 // Decades from the lowest loss
+		loss := 0.1 + 1.9/(1+math.Exp(-4*(-x-1.5))) + math.Max(x, 0)*5
+		points = append(points, LRPoint{LR: lr, Loss: loss, Smoothed: loss})
+	}
+	return append(points, LRPoint{LR: 2, Loss: math.NaN(), Smoothed: math.NaN()})
+}
+
+func TestSuggestLR(t *testing.T) {
+	curve := rangeTestCurve()
+	for _, tc := range []struct {
+		name   string
+		points []LRPoint
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single point", []LRPoint{{LR: 0.5, Smoothed: 1}}, 0.05},
+		{"curve", curve, 1e-3},
+		{"curve cut before the lowest loss", curve[:30], curve[29].LR / 10},
+		{"plateau", []LRPoint{{LR: 1e-3, Smoothed: 1}, {LR: 1e-2, Smoothed: 1}}, 1e-4},
+	} {
+		if got := SuggestLR(tc.points); math.Abs(got-tc.want) > 1e-9*tc.want {
+			t.Errorf("%s: SuggestLR = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"math/rand"
+
+	"autonomous-snake/internal/game"
+)
+
+// ActionMask marks the actions a snake may choose
+type ActionMask [NumActions]bool
+
+// AllActions allows every action
+var AllActions = ActionMask{true, true, true}
+
+// SurvivalMask allows the actions that don't move the snake's head into a
+// wall or a body next turn. When every action is fatal it allows them all,
+// so there is always a choice left.
+func SurvivalMask(state *game.GameState, snakeID int) ActionMask {
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return AllActions
+	}
+	var mask ActionMask
+	safe := false
+	for a := range mask {
+		next := state.NextHead(snake, ActionToDirection(snake.Direction, Action(a)))
+		mask[a] = !isDanger(next, snakeID, state)
+		safe = safe || mask[a]
+	}
+	if !safe {
+		return AllActions
+	}
+	return mask
+}
+
+// Best returns the allowed action with the highest value, the first on ties
+func (m ActionMask) Best(values []float64) Action {
+	best := -1
+	for a, ok := range m {
+		if ok && (best < 0 || values[a] > values[best]) {
+			best = a
+		}
+	}
+	return Action(best)
+}
+
+// Random returns a uniformly drawn allowed action. With every action
+// allowed it draws the same as rng.Intn(NumActions).
+func (m ActionMask) Random(rng *rand.Rand) Action {
+	var allowed [NumActions]Action
+	n := 0
+	for a, ok := range m {
+		if ok {
+			allowed[n] = Action(a)
+			n++
+		}
+	}
+	return allowed[rng.Intn(n)]
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestSurvivalMask(t *testing.T) {
+	// Snake 0 heads up into the top wall with its neck to the right, so
+	// only a left turn survives; snake 1 has room to move
+	state := &game.GameState{
+		Width:  8,
+		Height: 8,
+		Snakes: [2]*game.Snake{
+			{ID: 0, Body: []game.Position{{X: 3, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 1}}, Direction: game.Up, Alive: true},
+			{ID: 1, Body: []game.Position{{X: 4, Y: 5}, {X: 4, Y: 6}}, Direction: game.Up, Alive: true},
+		},
+	}
+	if got, want := SurvivalMask(state, 0), (ActionMask{GoStraight: false, TurnLeft: true, TurnRight: false}); got != want {
+		t.Errorf("SurvivalMask = %v, want %v", got, want)
+	}
+	if got := SurvivalMask(state, 1); got != AllActions {
+		t.Errorf("SurvivalMask with every move safe = %v", got)
+	}
+
+	// The masked choice ignores higher-valued fatal moves, even exploring
+	mask := SurvivalMask(state, 0)
+	if got := mask.Best([]float64{5, 1, 3}); got != TurnLeft {
+		t.Errorf("Best = %v, want %v", got, TurnLeft)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		if got := mask.Random(rng); got != TurnLeft {
+			t.Fatalf("Random = %v, want %v", got, TurnLeft)
+		}
+	}
+
+	// Boxed in, every move is allowed
+	state.Snakes[0].Body = []game.Position{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}, {X: 0, Y: 2}}
+	state.Snakes[0].Direction = game.Left
+	if got := SurvivalMask(state, 0); got != AllActions {
+		t.Errorf("SurvivalMask with no safe move = %v, want all", got)
+	}
+}
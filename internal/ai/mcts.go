@@ -0,0 +1,325 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"autonomous-snake/internal/eval"
+	"autonomous-snake/internal/game"
+)
+
+// MCTS defaults
+const (
+	DefaultMCTSExploration  = 1.4
+	DefaultMCTSRolloutDepth = 20
+
+	// mctsCheckEvery is how many iterations run between clock checks
+	mctsCheckEvery = 16
+)
+
+// MCTS is a search bot using decoupled UCT: both snakes pick their own
+// move at every node and the joint move selects the child. The tree is
+// open-loop (nodes store statistics, not states), so random food spawns
+// don't fragment it. Leaves are scored by a short rollout followed by the
+// eval heuristic. Rollout moves are random unless a network guides them,
+// see Guide.
+//
+// Search stops at the per-move Budget, at MaxIterations, or earlier once
+// the best move can no longer be overtaken in the remaining time. The
+// subtree for the move actually played is kept for the next turn.
+type MCTS struct {
+	Budget        time.Duration // Wall-clock time per move, 0 for no limit
+	MaxIterations int           // Iterations per move, 0 for no limit
+	Exploration   float64       // UCB exploration constant
+	RolloutDepth  int           // Random moves before evaluating a leaf
+	ReuseTree     bool          // Keep the matching subtree between turns
+
+	// LastIterations is the number of iterations run for the previous move
+	LastIterations int
+
+	rng   *rand.Rand
+	guide *NetworkPolicy
+
+	// Tree kept from the previous move
+	root      *mctsNode
+	rootTurn  int
+	rootSnake int
+	rootDirs  [2]game.Direction
+}
+
+// mctsNode holds per-snake action statistics and children by joint action
+type mctsNode struct {
+	visits   int
+	stats    [2][NumActions]mctsStats
+	children map[[2]Action]*mctsNode
+}
+
+// mctsStats accumulates the value of one snake's action at a node
+type mctsStats struct {
+	visits int
+	value  float64
+}
+
+// NewMCTS creates a search bot with the given per-move time budget
+func NewMCTS(budget time.Duration, seed int64) *MCTS {
+	return &MCTS{
+		Budget:       budget,
+		Exploration:  DefaultMCTSExploration,
+		RolloutDepth: DefaultMCTSRolloutDepth,
+		ReuseTree:    true,
+		rng:          rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Guide makes the rollouts follow net: each snake plays the network's
+// highest-valued move that doesn't die at once instead of a random one.
+// The search only reads the network, so guided bots can share it.
+func (m *MCTS) Guide(net *QNetwork) {
+	m.guide = NewNetworkPolicy(net, 0, m.rng.Int63())
+}
+
+// Act searches from state and returns the most visited action for snakeID
+func (m *MCTS) Act(state *game.GameState, snakeID int) Action {
+	root := m.reusedRoot(state, snakeID)
+	if root == nil {
+		root = &mctsNode{}
+	}
+
+	start := time.Now()
+	deadline := start.Add(m.Budget)
+	iterations := 0
+	for m.MaxIterations <= 0 || iterations < m.MaxIterations {
+		if m.Budget > 0 && iterations%mctsCheckEvery == 0 {
+			now := time.Now()
+			if !now.Before(deadline) {
+				break
+			}
+			// Estimate how many more iterations fit in the budget
+			elapsed := now.Sub(start)
+			remaining := m.MaxIterations - iterations
+			if elapsed > 0 {
+				byTime := int(float64(iterations) * float64(deadline.Sub(now)) / float64(elapsed))
+				if m.MaxIterations <= 0 || byTime < remaining {
+					remaining = byTime
+				}
+			}
+			if iterations > 0 && root.decided(snakeID, remaining) {
+				break
+			}
+		}
+		m.iterate(root, state)
+		iterations++
+	}
+	m.LastIterations = iterations
+
+	best := root.bestAction(snakeID)
+	m.root = root
+	m.rootTurn = state.Turn
+	m.rootSnake = snakeID
+	m.rootDirs = [2]game.Direction{state.Snakes[0].Direction, state.Snakes[1].Direction}
+	return best
+}
+
+// Reset discards the tree kept from the previous move
+func (m *MCTS) Reset() {
+	m.root = nil
+}
+
+// reusedRoot returns the subtree for the joint move that led from the
+// previous search's state to state, or nil if it can't be identified
+func (m *MCTS) reusedRoot(state *game.GameState, snakeID int) *mctsNode {
+	if !m.ReuseTree || m.root == nil || m.rootSnake != snakeID || state.Turn != m.rootTurn+1 {
+		return nil
+	}
+	var joint [2]Action
+	for i, snake := range state.Snakes {
+		action, ok := relativeAction(m.rootDirs[i], snake.Direction)
+		if !ok {
+			return nil
+		}
+		joint[i] = action
+	}
+	return m.root.children[joint]
+}
+
+// relativeAction returns the action that turns from into to
+func relativeAction(from, to game.Direction) (Action, bool) {
+	switch to {
+	case from:
+		return GoStraight, true
+	case from.TurnLeft():
+		return TurnLeft, true
+	case from.TurnRight():
+		return TurnRight, true
+	}
+	return GoStraight, false
+}
+
+// iterate runs one selection, expansion, rollout and backup pass
+func (m *MCTS) iterate(root *mctsNode, state *game.GameState) {
+	g := game.NewGameFromState(state.Clone(), m.rng.Int63())
+
+	type step struct {
+		node  *mctsNode
+		joint [2]Action
+	}
+	var path []step
+
+	node := root
+	for !g.State.GameOver {
+		joint := node.selectJoint(g.State, m.Exploration, m.rng)
+		path = append(path, step{node, joint})
+		g.Step(jointDirections(g.State, joint))
+
+		child := node.children[joint]
+		if child == nil {
+			if node.children == nil {
+				node.children = make(map[[2]Action]*mctsNode)
+			}
+			child = &mctsNode{}
+			node.children[joint] = child
+			node = child
+			break
+		}
+		node = child
+	}
+	leaf := node
+
+	// Rollout
+	for depth := 0; depth < m.RolloutDepth && !g.State.GameOver; depth++ {
+		var joint [2]Action
+		for p := range joint {
+			if m.guide != nil {
+				joint[p] = m.guidedAction(g.State, p)
+			} else {
+				joint[p] = rolloutAction(g.State, p, m.rng)
+			}
+		}
+		g.Step(jointDirections(g.State, joint))
+	}
+	values := [2]float64{eval.Evaluate(g.State, 0), eval.Evaluate(g.State, 1)}
+
+	// Backup
+	leaf.visits++
+	for _, s := range path {
+		s.node.visits++
+		for p := 0; p < 2; p++ {
+			st := &s.node.stats[p][s.joint[p]]
+			st.visits++
+			st.value += values[p]
+		}
+	}
+}
+
+// selectJoint picks each living snake's action by UCB
+func (n *mctsNode) selectJoint(state *game.GameState, c float64, rng *rand.Rand) [2]Action {
+	var joint [2]Action
+	for p := 0; p < 2; p++ {
+		if !state.Snakes[p].Alive {
+			continue
+		}
+		joint[p] = n.selectAction(p, c, rng)
+	}
+	return joint
+}
+
+// selectAction returns an untried action for snake p, or the best by UCB
+func (n *mctsNode) selectAction(p int, c float64, rng *rand.Rand) Action {
+	var untried []Action
+	for a := Action(0); a < NumActions; a++ {
+		if n.stats[p][a].visits == 0 {
+			untried = append(untried, a)
+		}
+	}
+	if len(untried) > 0 {
+		return untried[rng.Intn(len(untried))]
+	}
+
+	logN := math.Log(float64(n.visits))
+	best, bestScore := GoStraight, math.Inf(-1)
+	for a := Action(0); a < NumActions; a++ {
+		st := n.stats[p][a]
+		score := st.value/float64(st.visits) + c*math.Sqrt(logN/float64(st.visits))
+		if score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	return best
+}
+
+// bestAction returns snake p's most visited action
+func (n *mctsNode) bestAction(p int) Action {
+	best := GoStraight
+	for a := Action(1); a < NumActions; a++ {
+		if n.stats[p][a].visits > n.stats[p][best].visits {
+			best = a
+		}
+	}
+	return best
+}
+
+// decided reports whether snake p's most visited action keeps its lead
+// even if all remaining iterations go to the runner-up
+func (n *mctsNode) decided(p int, remaining int) bool {
+	first, second := 0, 0
+	for a := Action(0); a < NumActions; a++ {
+		v := n.stats[p][a].visits
+		if v > first {
+			first, second = v, first
+		} else if v > second {
+			second = v
+		}
+	}
+	return first-second > remaining
+}
+
+// jointDirections converts a joint action into absolute directions
+func jointDirections(state *game.GameState, joint [2]Action) [2]game.Direction {
+	return [2]game.Direction{
+		ActionToDirection(state.Snakes[0].Direction, joint[0]),
+		ActionToDirection(state.Snakes[1].Direction, joint[1]),
+	}
+}
+
+// rolloutAction picks a random action that doesn't die immediately, if any
+func rolloutAction(state *game.GameState, snakeID int, rng *rand.Rand) Action {
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return GoStraight
+	}
+	var safe [NumActions]Action
+	count := 0
+	for a := Action(0); a < NumActions; a++ {
+		if !isDanger(state.NextHead(snake, ActionToDirection(snake.Direction, a)), snakeID, state) {
+			safe[count] = a
+			count++
+		}
+	}
+	if count == 0 {
+		return Action(rng.Intn(int(NumActions)))
+	}
+	return safe[rng.Intn(count)]
+}
+
+// guidedAction picks the guide network's highest-valued action that
+// doesn't die immediately, or a random one if every action does
+func (m *MCTS) guidedAction(state *game.GameState, snakeID int) Action {
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return GoStraight
+	}
+	g := m.guide
+	g.encoder.Encode(g.features, state, snakeID)
+	q := g.Net.forwardWith(g.cache, g.features)
+	best, bestQ := Action(0), math.Inf(-1)
+	for a := Action(0); a < NumActions; a++ {
+		if q[a] > bestQ && !isDanger(state.NextHead(snake, ActionToDirection(snake.Direction, a)), snakeID, state) {
+			best, bestQ = a, q[a]
+		}
+	}
+	if math.IsInf(bestQ, -1) {
+		return Action(m.rng.Intn(int(NumActions)))
+	}
+	return best
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"testing"
+	"time"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// cornered returns a game in which snake 0 heads up into the top wall with
+// its neck to the right, so only a left turn survives
+func cornered() *game.Game {
+	g := game.NewGame(config.GameConfig{BoardWidth: 8, BoardHeight: 8}, 1)
+	g.State.Snakes[0].Body = []game.Position{{X: 3, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 1}}
+	g.State.Snakes[0].Direction = game.Up
+	g.State.Snakes[1].Body = []game.Position{{X: 4, Y: 5}, {X: 4, Y: 6}, {X: 4, Y: 7}}
+	g.State.Snakes[1].Direction = game.Up
+	return g
+}
+
+// straightNet returns a network that values going straight far above
+// turning, whatever it sees
+func straightNet() *QNetwork {
+	net := NewQNetwork(StateSize, 8, 6, int(NumActions), 0.01, 1)
+	clear(net.W3)
+	net.B3[GoStraight] = 10
+	return net
+}
+
+func TestMCTSAvoidsFatalMove(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		for _, guided := range []bool{false, true} {
+			m := NewMCTS(0, seed)
+			m.MaxIterations = 300
+			if guided {
+				m.Guide(straightNet())
+			}
+			if got := m.Act(cornered().State, 0); got != TurnLeft {
+				t.Errorf("seed %d, guided %v: chose %v, want the only surviving move %v", seed, guided, got, TurnLeft)
+			}
+		}
+	}
+}
+
+func TestMCTSBudget(t *testing.T) {
+	const budget = 5 * time.Millisecond
+	for _, guided := range []bool{false, true} {
+		bots := [2]*MCTS{NewMCTS(budget, 1), NewMCTS(budget, 2)}
+		if guided {
+			net := straightNet()
+			bots[0].Guide(net)
+			bots[1].Guide(net)
+		}
+		g := game.NewGame(config.GameConfig{BoardWidth: 10, BoardHeight: 10, MaxTurns: 40}, 3)
+		for !g.State.GameOver {
+			var dirs [2]game.Direction
+			for i, bot := range bots {
+				start := time.Now()
+				action := bot.Act(g.State, i)
+				// Generous slack for a loaded machine; an unbounded search
+				// would run far past it
+				if elapsed := time.Since(start); elapsed > budget+100*time.Millisecond {
+					t.Errorf("guided %v, turn %d: snake %d searched for %v on a %v budget", guided, g.State.Turn, i, elapsed, budget)
+				}
+				if action < 0 || action >= NumActions {
+					t.Fatalf("guided %v, turn %d: snake %d chose illegal action %d", guided, g.State.Turn, i, action)
+				}
+				if bot.LastIterations == 0 {
+					t.Errorf("guided %v, turn %d: snake %d ran no iterations", guided, g.State.Turn, i)
+				}
+				dirs[i] = ActionToDirection(g.State.Snakes[i].Direction, action)
+			}
+			g.Step(dirs)
+		}
+	}
+
+	// An iteration cap stops the search without a clock
+	m := NewMCTS(0, 1)
+	m.MaxIterations = 50
+	m.Act(cornered().State, 0)
+	if m.LastIterations > 50 {
+		t.Errorf("ran %d iterations with MaxIterations 50", m.LastIterations)
+	}
+}
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+
+	"autonomous-snake/internal/config"
+)
+
+// StateEncodingVersion numbers the layout of the built-in encoders'
+// features. Bump it when an encoder changes what its inputs mean, so models
+// trained on the old layout are refused instead of playing on inputs they
+// misread.
+const StateEncodingVersion = 1
+
+// ModelMetadata records how a model was trained. Versioned gob and JSON
+// model files carry it beside the weights; flat and npz files, and files
+// written before it existed, have none. Builds that predate it skip it, so
+// adding it didn't change the format version.
+type ModelMetadata struct {
+	Created         time.Time              // When the file was written
+	Episodes        int                    // Training episodes behind the weights, across resumed runs
+	EncodingVersion int                    // StateEncodingVersion the network was trained with
+	Config          *config.TrainingConfig `json:",omitempty"` // Configuration of the run that wrote the file
+}
+
+// NewModelMetadata returns metadata for a model written now after the
+// given number of episodes, under cfg. The snapshot spells out both
+// snakes' rewards, as gob can't hold an unset override.
+func NewModelMetadata(episodes int, cfg config.TrainingConfig) *ModelMetadata {
+	for i := range cfg.SnakeRewards {
+		rewards := cfg.RewardsFor(i)
+		cfg.SnakeRewards[i] = &rewards
+	}
+	return &ModelMetadata{
+		Created:         time.Now().UTC(),
+		Episodes:        episodes,
+		EncodingVersion: StateEncodingVersion,
+		Config:          &cfg,
+	}
+}
+
+// check refuses metadata from a newer state encoding
+func (m *ModelMetadata) check() error {
+	if m != nil && m.EncodingVersion > StateEncodingVersion {
+		return fmt.Errorf("model was trained on state encoding version %d, newer than this build's %d", m.EncodingVersion, StateEncodingVersion)
+	}
+	return nil
+}
+
+// MetadataAgent is an Agent whose saved model carries metadata. Training
+// loops set it before saving.
+type MetadataAgent interface {
+	Agent
+	Metadata() *ModelMetadata
+	SetMetadata(meta *ModelMetadata)
+}
+
+// Metadata returns the policy network's metadata, nil if it has none
+func (a *DQNAgent) Metadata() *ModelMetadata { return a.PolicyNet.Metadata }
+
+// SetMetadata sets the metadata Save writes with the policy network
+func (a *DQNAgent) SetMetadata(meta *ModelMetadata) { a.PolicyNet.Metadata = meta }
+
+// Metadata returns the actor's metadata, nil if it has none
+func (ac *actorCritic) Metadata() *ModelMetadata { return ac.Actor.Metadata }
+
+// SetMetadata sets the metadata Save writes with the actor
+func (ac *actorCritic) SetMetadata(meta *ModelMetadata) { ac.Actor.Metadata = meta }
+
+// Metadata returns the actor's metadata, nil if it has none
+func (a *A2CAgent) Metadata() *ModelMetadata {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.Actor.Metadata
+}
+
+// SetMetadata sets the metadata Save writes with the actor
+func (a *A2CAgent) SetMetadata(meta *ModelMetadata) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Actor.Metadata = meta
+}
@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestModelMetadata(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	cfg.Encoder = WindowEncoder(5)
+	cfg.InputSize = StateSize + 25
+	agent := NewDQNAgent(cfg, 1)
+	agent.SetMetadata(NewModelMetadata(1500, cfg))
+	dir := t.TempDir()
+
+	// Checkpoints and every versioned codec keep the metadata
+	paths := map[string]func(string) error{
+		"agent.gob": agent.Save,
+		"model.json": func(path string) error {
+			return agent.PolicyNet.SaveWithOptions(path, SaveOptions{Codec: CodecJSON})
+		},
+		"model32.gob": func(path string) error {
+			return agent.PolicyNet.SaveWithOptions(path, SaveOptions{Codec: CodecGob, Precision: Float32})
+		},
+	}
+	for name, save := range paths {
+		path := filepath.Join(dir, name)
+		if err := save(path); err != nil {
+			t.Fatal(err)
+		}
+		info, err := InspectModel(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		net, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, meta := range []*ModelMetadata{info.Metadata, net.Metadata} {
+			if meta == nil || meta.Episodes != 1500 || meta.EncodingVersion != StateEncodingVersion ||
+				!meta.Created.Equal(agent.Metadata().Created) || meta.Config == nil || meta.Config.Encoder != cfg.Encoder {
+				t.Errorf("%s: metadata %+v", name, meta)
+			}
+		}
+		if net.Encoder != cfg.Encoder {
+			t.Errorf("%s: encoder %q", name, net.Encoder)
+		}
+	}
+
+	// Unversioned gob files, which predate metadata, still load without it
+	legacyPath := filepath.Join(dir, "legacy.gob")
+	weights := agent.PolicyNet.weights()
+	weights.Metadata = nil
+	file, err := os.Create(legacyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewEncoder(file).Encode(weights); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if net, err := LoadNetwork(legacyPath); err != nil || net.Metadata != nil {
+		t.Errorf("legacy model: %v, metadata %+v", err, net.Metadata)
+	}
+
+	// Models trained on a newer state encoding are refused
+	agent.PolicyNet.Metadata.EncodingVersion = StateEncodingVersion + 1
+	newerPath := filepath.Join(dir, "newer.gob")
+	if err := agent.PolicyNet.Save(newerPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadNetwork(newerPath); err == nil || !strings.Contains(err.Error(), "state encoding version") {
+		t.Errorf("loading a newer encoding gave %v", err)
+	}
+}
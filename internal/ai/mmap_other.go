@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ai
+
+// MapNetwork falls back to a regular heap load on platforms without mmap
+// support in the syscall package. The network is still marked read-only so
+// behavior matches the mapped version.
+func MapNetwork(path string) (*MappedNetwork, error) {
+	net, err := LoadNetwork(path)
+	if err != nil {
+		return nil, err
+	}
+	net.readOnly = true
+	return &MappedNetwork{QNetwork: net}, nil
+}
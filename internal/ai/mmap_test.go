@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMapNetwork(t *testing.T) {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(1))
+	input := make([]float64, StateSize)
+	for i := range input {
+		input[i] = rng.Float64()
+	}
+
+	for _, dueling := range []bool{false, true} {
+		net := NewQNetwork(StateSize, 8, 6, int(NumActions), 0.01, 1)
+		if dueling {
+			net = NewDuelingQNetwork(StateSize, 8, 6, int(NumActions), 0.01, 1)
+		}
+		want := net.Forward(input)
+		path := filepath.Join(dir, "model.bin")
+		if err := net.SaveWithOptions(path, SaveOptions{Codec: CodecFlat, Precision: Float64}); err != nil {
+			t.Fatal(err)
+		}
+
+		mapped, err := MapNetwork(path)
+		if err != nil {
+			t.Fatalf("dueling %v: %v", dueling, err)
+		}
+		if got := mapped.Forward(input); !equalFloats(got, want) {
+			t.Errorf("dueling %v: mapped Forward = %v, want %v", dueling, got, want)
+		}
+
+		// Writes panic, while a clone is an ordinary network that outlives
+		// the mapping
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("dueling %v: CopyFrom wrote to a mapped network", dueling)
+				}
+			}()
+			mapped.CopyFrom(net)
+		}()
+		clone := mapped.Clone()
+		if err := mapped.Close(); err != nil {
+			t.Fatalf("dueling %v: Close: %v", dueling, err)
+		}
+		if mapped.QNetwork != nil {
+			t.Errorf("dueling %v: network still set after Close", dueling)
+		}
+		if err := mapped.Close(); err != nil {
+			t.Errorf("dueling %v: second Close: %v", dueling, err)
+		}
+		if got := clone.Forward(input); !equalFloats(got, want) {
+			t.Errorf("dueling %v: clone Forward = %v after Close, want %v", dueling, got, want)
+		}
+		clone.CopyFrom(net)
+	}
+
+	// Only float64 flat files can be used in place
+	net := NewQNetwork(StateSize, 8, 6, int(NumActions), 0.01, 1)
+	path := filepath.Join(dir, "model32.bin")
+	if err := net.SaveWithOptions(path, SaveOptions{Codec: CodecFlat, Precision: Float32}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mapFlat(data); err == nil || !strings.Contains(err.Error(), "float64") {
+		t.Errorf("mapped a float32 model: %v", err)
+	}
+}
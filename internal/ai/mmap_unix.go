@@ -0,0 +1,43 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package ai
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// MapNetwork memory-maps a float64 flat model file read-only and returns a
+// network that uses the mapped weights in place. Call Close to unmap it.
+func MapNetwork(path string) (*MappedNetwork, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < flatHeaderSize {
+		return nil, fmt.Errorf("invalid model %s: too small for a flat model", path)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("could not map %s: %w", path, err)
+	}
+
+	net, err := mapFlat(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, fmt.Errorf("invalid model %s: %w", path, err)
+	}
+
+	return &MappedNetwork{
+		QNetwork: net,
+		release:  func() error { return syscall.Munmap(data) },
+	}, nil
+}
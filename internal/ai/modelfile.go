@@ -0,0 +1,695 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// Model file layout
+//
+// Versioned gob files start with a fixed header followed by a gob payload:
+//
+//	[4]byte  magic "SLRL"
+//	uint16   format version (little-endian)
+//	uint8    precision (0 = float64, 1 = float32)
+//
+// Versioned JSON files are a single JSON object with "format", "version"
+// and "precision" fields alongside the weights. Files without either marker
+// are treated as unversioned gob (NetworkWeights or the legacy layout).
+// Versioned payloads may also carry ModelMetadata.
+
+// modelMagic identifies versioned binary model files
+const modelMagic = "SLRL"
+
+// ModelFormatVersion is the newest model file format version this build
+// reads. Version 2 added dueling, noisy, distributional, recurrent,
+// convolutional and bootstrapped networks; plain networks are still
+// written as version 1 so older builds can load them.
+const ModelFormatVersion = 2
+
+// formatVersion returns the oldest format version that can hold the network
+func (n *QNetwork) formatVersion() int {
+	if n.Dueling || n.Noisy || n.Atoms > 0 || n.Recurrent || n.convolutional() || n.Heads > 0 {
+		return 2
+	}
+	return 1
+}
+
+// modelFormatName is the "format" field written into JSON model files
+const modelFormatName = "slitherrl-model"
+
+// jsonExtension makes Save write an indented JSON model, for reading,
+// diffing and editing by hand
+const jsonExtension = ".json"
+
+// Codec selects how a model file is encoded
+type Codec string
+
+const (
+	CodecGob  Codec = "gob"
+	CodecJSON Codec = "json"
+)
+
+// Precision selects the floating point width used to store weights
+type Precision string
+
+const (
+	Float64 Precision = "float64"
+	Float32 Precision = "float32"
+)
+
+// ModelKind describes which on-disk layout a model file uses
+type ModelKind int
+
+const (
+	ModelLegacy    ModelKind = iota // gob with unused 2D bias fields
+	ModelGob                        // unversioned gob NetworkWeights
+	ModelVersioned                  // header + payload, see ModelFormatVersion
+
+	// ModelCheckpoint is an agent checkpoint around a versioned model, see
+	// AgentCheckpoint
+	ModelCheckpoint
+
+	// ModelNPZ is a NumPy archive of weights, e.g. trained elsewhere, see
+	// npz.go
+	ModelNPZ
+)
+
+// String returns a human-readable name for the model kind
+func (k ModelKind) String() string {
+	switch k {
+	case ModelLegacy:
+		return "legacy gob"
+	case ModelGob:
+		return "unversioned gob"
+	case ModelVersioned:
+		return "versioned"
+	case ModelCheckpoint:
+		return "agent checkpoint"
+	case ModelNPZ:
+		return "npz archive"
+	}
+	return "unknown"
+}
+
+// SaveOptions controls how a network is written to disk
+type SaveOptions struct {
+	Codec     Codec
+	Precision Precision
+}
+
+// DefaultSaveOptions returns the format used by QNetwork.Save
+func DefaultSaveOptions() SaveOptions {
+	return SaveOptions{Codec: CodecGob, Precision: Float64}
+}
+
+// ModelInfo describes a model file without fully loading it
+type ModelInfo struct {
+	Kind      ModelKind
+	Version   int
+	Codec     Codec
+	Precision Precision
+	Metadata  *ModelMetadata // nil if the file records none
+}
+
+// networkWeights32 is the float32 variant of NetworkWeights
+type networkWeights32 struct {
+	W1           [][]float32
+	B1           []float32
+	W2           [][]float32
+	B2           []float32
+	W3           [][]float32
+	B3           []float32
+	InputSize    int
+	HiddenSize1  int
+	HiddenSize2  int
+	OutputSize   int
+	LearningRate float64
+	Encoder      string
+	Metadata     *ModelMetadata
+	Dueling      bool
+	WV           [][]float32
+	BV           []float32
+	Noisy        bool
+	Sigma        [][]float32
+	Recurrent    bool
+	U2           [][]float32
+	Conv         ConvShape
+	Atoms        int
+	VMin         float64
+	VMax         float64
+	Heads        int
+}
+
+// jsonModelFile is the on-disk layout of a JSON model file
+type jsonModelFile struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	Precision Precision      `json:"precision"`
+	Weights   NetworkWeights `json:"weights"`
+}
+
+// SaveWithOptions writes the network using the given codec and precision.
+// The file is replaced only once the new one is complete.
+func (n *QNetwork) SaveWithOptions(path string, opts SaveOptions) error {
+	return WriteFileAtomic(path, func(w io.Writer) error { return n.writeModel(w, opts) })
+}
+
+// writeModel encodes the network to w in the versioned format
+func (n *QNetwork) writeModel(w io.Writer, opts SaveOptions) error {
+	if opts.Codec == "" {
+		opts.Codec = CodecGob
+	}
+	if opts.Precision == "" {
+		opts.Precision = Float64
+		if opts.Codec == CodecFlat {
+			opts.Precision = Float32
+		}
+	}
+	if opts.Codec == CodecFlat {
+		return n.writeFlat(w, opts.Precision)
+	}
+	if opts.Codec == CodecNPZ {
+		return n.writeNPZ(w, opts.Precision)
+	}
+
+	weights := n.weights()
+	if opts.Precision == Float32 {
+		weights = roundWeights32(weights)
+	} else if opts.Precision != Float64 {
+		return fmt.Errorf("unknown precision %q", opts.Precision)
+	}
+
+	switch opts.Codec {
+	case CodecJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(jsonModelFile{
+			Format:    modelFormatName,
+			Version:   n.formatVersion(),
+			Precision: opts.Precision,
+			Weights:   weights,
+		})
+	case CodecGob:
+		header := make([]byte, 0, 7)
+		header = append(header, modelMagic...)
+		header = binary.LittleEndian.AppendUint16(header, uint16(n.formatVersion()))
+		if opts.Precision == Float32 {
+			header = append(header, 1)
+		} else {
+			header = append(header, 0)
+		}
+		if _, err := w.Write(header); err != nil {
+			return err
+		}
+		encoder := gob.NewEncoder(w)
+		if opts.Precision == Float32 {
+			return encoder.Encode(toWeights32(weights))
+		}
+		return encoder.Encode(weights)
+	}
+	return fmt.Errorf("unknown codec %q", opts.Codec)
+}
+
+// InspectModel reports which format a model file uses
+func InspectModel(path string) (ModelInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ModelInfo{}, err
+	}
+	if bytes.HasPrefix(data, []byte(flatMagic)) {
+		h, err := decodeFlatHeader(data)
+		return h.info(), err
+	}
+	if bytes.HasPrefix(data, []byte(checkpointMagic)) {
+		info, _, err := decodeCheckpoint(data)
+		return info, err
+	}
+	if bytes.HasPrefix(data, []byte(zipMagic)) {
+		info, _, err := decodeNPZ(data)
+		return info, err
+	}
+	info, weights, err := decodeModel(data)
+	info.Metadata = weights.Metadata
+	return info, err
+}
+
+// decodeModel detects the file layout and decodes the weights it contains
+func decodeModel(data []byte) (ModelInfo, NetworkWeights, error) {
+	// Versioned binary
+	if bytes.HasPrefix(data, []byte(modelMagic)) {
+		if len(data) < 7 {
+			return ModelInfo{}, NetworkWeights{}, errors.New("truncated model header")
+		}
+		info := ModelInfo{
+			Kind:    ModelVersioned,
+			Version: int(binary.LittleEndian.Uint16(data[4:6])),
+			Codec:   CodecGob,
+		}
+		if info.Version > ModelFormatVersion {
+			return info, NetworkWeights{}, fmt.Errorf("model format version %d is newer than supported version %d", info.Version, ModelFormatVersion)
+		}
+		decoder := gob.NewDecoder(bytes.NewReader(data[7:]))
+		switch data[6] {
+		case 0:
+			info.Precision = Float64
+			var weights NetworkWeights
+			err := decoder.Decode(&weights)
+			return info, weights, err
+		case 1:
+			info.Precision = Float32
+			var weights32 networkWeights32
+			if err := decoder.Decode(&weights32); err != nil {
+				return info, NetworkWeights{}, err
+			}
+			return info, fromWeights32(weights32), nil
+		}
+		return info, NetworkWeights{}, fmt.Errorf("unknown precision tag %d", data[6])
+	}
+
+	// Versioned JSON
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		var file jsonModelFile
+		if err := json.Unmarshal(trimmed, &file); err != nil {
+			return ModelInfo{}, NetworkWeights{}, err
+		}
+		if file.Format != modelFormatName {
+			return ModelInfo{}, NetworkWeights{}, fmt.Errorf("unrecognized model format %q", file.Format)
+		}
+		info := ModelInfo{
+			Kind:      ModelVersioned,
+			Version:   file.Version,
+			Codec:     CodecJSON,
+			Precision: file.Precision,
+		}
+		if info.Version > ModelFormatVersion {
+			return info, NetworkWeights{}, fmt.Errorf("model format version %d is newer than supported version %d", info.Version, ModelFormatVersion)
+		}
+		return info, file.Weights, nil
+	}
+
+	// Unversioned gob. Try the current layout first, then the legacy one.
+	info := ModelInfo{Kind: ModelGob, Codec: CodecGob, Precision: Float64}
+	var weights NetworkWeights
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&weights); err == nil && weights.B1 != nil {
+		return info, weights, nil
+	}
+
+	info.Kind = ModelLegacy
+	var legacyWeights legacyNetworkWeights
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&legacyWeights); err != nil {
+		return ModelInfo{}, NetworkWeights{}, err
+	}
+	return info, NetworkWeights{
+		W1:           legacyWeights.W1,
+		B1:           legacyWeights.B1Vec,
+		W2:           legacyWeights.W2,
+		B2:           legacyWeights.B2Vec,
+		W3:           legacyWeights.W3,
+		B3:           legacyWeights.B3Vec,
+		InputSize:    legacyWeights.InputSize,
+		HiddenSize1:  legacyWeights.HiddenSize1,
+		HiddenSize2:  legacyWeights.HiddenSize2,
+		OutputSize:   legacyWeights.OutputSize,
+		LearningRate: legacyWeights.LearningRate,
+	}, nil
+}
+
+// readModelFile reads and decodes a model file of any supported layout,
+// or the policy network of an agent checkpoint
+func readModelFile(path string) (ModelInfo, *QNetwork, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ModelInfo{}, nil, err
+	}
+	if bytes.HasPrefix(data, []byte(checkpointMagic)) {
+		info, c, err := decodeCheckpoint(data)
+		if err != nil {
+			return info, nil, err
+		}
+		return info, c.Policy, nil
+	}
+	return decodeNetwork(data)
+}
+
+// decodeNetwork decodes a model file of any supported layout
+func decodeNetwork(data []byte) (ModelInfo, *QNetwork, error) {
+	if bytes.HasPrefix(data, []byte(flatMagic)) {
+		return decodeFlat(data)
+	}
+	if bytes.HasPrefix(data, []byte(zipMagic)) {
+		return decodeNPZ(data)
+	}
+
+	info, weights, err := decodeModel(data)
+	if err != nil {
+		return info, nil, err
+	}
+	info.Metadata = weights.Metadata
+	net, err := networkFromWeights(weights)
+	return info, net, err
+}
+
+// weights returns the serializable form of the network
+func (n *QNetwork) weights() NetworkWeights {
+	in1, out1 := n.Conv.layer1Dims(n.InputSize, n.HiddenSize1)
+	weights := NetworkWeights{
+		W1:           unflatten(n.W1, in1, out1),
+		B1:           n.B1,
+		W2:           unflatten(n.W2, n.HiddenSize1, n.layer2Size()),
+		B2:           n.B2,
+		W3:           unflatten(n.W3, n.HiddenSize2, n.OutputSize),
+		B3:           n.B3,
+		InputSize:    n.InputSize,
+		HiddenSize1:  n.HiddenSize1,
+		HiddenSize2:  n.HiddenSize2,
+		OutputSize:   n.OutputSize,
+		LearningRate: n.LearningRate,
+		Encoder:      n.Encoder,
+		Metadata:     n.Metadata,
+	}
+	if n.Dueling {
+		weights.Dueling = true
+		weights.WV = unflatten(n.WV, n.HiddenSize2, 1)
+		weights.BV = n.BV
+	}
+	if n.Noisy {
+		weights.Noisy = true
+		weights.Sigma = n.Sigma
+	}
+	if n.Recurrent {
+		weights.Recurrent = true
+		weights.U2 = unflatten(n.U2, n.HiddenSize2, 3*n.HiddenSize2)
+	}
+	weights.Conv = n.Conv
+	weights.Atoms, weights.VMin, weights.VMax = n.Atoms, n.VMin, n.VMax
+	weights.Heads = n.Heads
+	return weights
+}
+
+// networkFromWeights builds a network from decoded weights, checking that
+// every matrix matches the declared dimensions
+func networkFromWeights(weights NetworkWeights) (*QNetwork, error) {
+	layer2Size := weights.HiddenSize2
+	if weights.Recurrent {
+		layer2Size *= 3
+	}
+	in1, out1 := weights.Conv.layer1Dims(weights.InputSize, weights.HiddenSize1)
+	layers := []struct {
+		name    string
+		w       [][]float64
+		in, out int
+	}{
+		{"layer 1", weights.W1, in1, out1},
+		{"layer 2", weights.W2, weights.HiddenSize1, layer2Size},
+		{"layer 3", weights.W3, weights.HiddenSize2, weights.OutputSize},
+	}
+	if weights.Dueling {
+		layers = append(layers, struct {
+			name    string
+			w       [][]float64
+			in, out int
+		}{"value stream", weights.WV, weights.HiddenSize2, 1})
+	}
+	if weights.Recurrent {
+		layers = append(layers, struct {
+			name    string
+			w       [][]float64
+			in, out int
+		}{"recurrent weights", weights.U2, weights.HiddenSize2, layer2Size})
+	}
+	for _, l := range layers {
+		if l.in <= 0 || l.out <= 0 {
+			return nil, fmt.Errorf("%s: invalid dimensions %dx%d", l.name, l.in, l.out)
+		}
+		if len(l.w) != l.in {
+			return nil, fmt.Errorf("%s: expected %d weight rows, got %d", l.name, l.in, len(l.w))
+		}
+		for i, row := range l.w {
+			if len(row) != l.out {
+				return nil, fmt.Errorf("%s: row %d has %d weights, expected %d", l.name, i, len(row), l.out)
+			}
+		}
+	}
+	if err := checkSupport(weights.Atoms, weights.OutputSize, weights.VMin, weights.VMax, weights.Dueling); err != nil {
+		return nil, err
+	}
+	if err := checkHeads(weights.Heads, weights.OutputSize, weights.Dueling, weights.Atoms, weights.Recurrent); err != nil {
+		return nil, err
+	}
+	if err := checkRecurrent(weights.Recurrent, weights.Noisy); err != nil {
+		return nil, err
+	}
+	if err := checkConv(weights.Conv, weights.InputSize, weights.HiddenSize1, weights.Noisy); err != nil {
+		return nil, err
+	}
+	if err := weights.Metadata.check(); err != nil {
+		return nil, err
+	}
+
+	net := &QNetwork{
+		W1:           flatten(weights.W1, in1, out1),
+		B1:           weights.B1,
+		W2:           flatten(weights.W2, weights.HiddenSize1, layer2Size),
+		B2:           weights.B2,
+		W3:           flatten(weights.W3, weights.HiddenSize2, weights.OutputSize),
+		B3:           weights.B3,
+		InputSize:    weights.InputSize,
+		HiddenSize1:  weights.HiddenSize1,
+		HiddenSize2:  weights.HiddenSize2,
+		OutputSize:   weights.OutputSize,
+		LearningRate: weights.LearningRate,
+		Encoder:      encoderOrDefault(weights.Encoder),
+		Metadata:     weights.Metadata,
+		Conv:         weights.Conv,
+		Atoms:        weights.Atoms,
+		VMin:         weights.VMin,
+		VMax:         weights.VMax,
+		Heads:        weights.Heads,
+		rng:          rand.New(rand.NewSource(0)),
+	}
+	if weights.Dueling {
+		net.Dueling = true
+		net.WV = flatten(weights.WV, weights.HiddenSize2, 1)
+		net.BV = weights.BV
+	}
+	if weights.Recurrent {
+		net.Recurrent = true
+		net.U2 = flatten(weights.U2, weights.HiddenSize2, layer2Size)
+	}
+	if weights.Noisy {
+		params := net.params()
+		if len(weights.Sigma) != len(params) {
+			return nil, fmt.Errorf("noise scales: expected %d arrays, got %d", len(params), len(weights.Sigma))
+		}
+		net.Noisy = true
+		net.Sigma = weights.Sigma
+	}
+	return net, nil
+}
+
+// encoderOrDefault fills in the encoder for files that predate it
+func encoderOrDefault(name string) string {
+	if name == "" {
+		return DefaultEncoderName
+	}
+	return name
+}
+
+// Validate checks that the network's weight shapes match its declared
+// dimensions and its encoder, and that no weight is NaN or infinite
+func (n *QNetwork) Validate() error {
+	in1, out1 := n.Conv.layer1Dims(n.InputSize, n.HiddenSize1)
+	layers := []struct {
+		name    string
+		w, b    []float64
+		in, out int
+	}{
+		{"layer 1", n.W1, n.B1, in1, out1},
+		{"layer 2", n.W2, n.B2, n.HiddenSize1, n.layer2Size()},
+		{"layer 3", n.W3, n.B3, n.HiddenSize2, n.OutputSize},
+	}
+	if n.Dueling {
+		layers = append(layers, struct {
+			name    string
+			w, b    []float64
+			in, out int
+		}{"value stream", n.WV, n.BV, n.HiddenSize2, 1})
+	}
+	if n.Recurrent {
+		// The recurrent weights have no bias of their own; B2 covers them
+		layers = append(layers, struct {
+			name    string
+			w, b    []float64
+			in, out int
+		}{"recurrent weights", n.U2, n.B2, n.HiddenSize2, n.layer2Size()})
+	}
+
+	for _, l := range layers {
+		if l.in <= 0 || l.out <= 0 {
+			return fmt.Errorf("%s: invalid dimensions %dx%d", l.name, l.in, l.out)
+		}
+		if len(l.w) != l.in*l.out {
+			return fmt.Errorf("%s: expected %d weights, got %d", l.name, l.in*l.out, len(l.w))
+		}
+		for k, v := range l.w {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("%s: weight [%d][%d] is %v", l.name, k/l.in, k%l.in, v)
+			}
+		}
+		if len(l.b) != l.out {
+			return fmt.Errorf("%s: expected %d biases, got %d", l.name, l.out, len(l.b))
+		}
+		for j, v := range l.b {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("%s: bias [%d] is %v", l.name, j, v)
+			}
+		}
+	}
+
+	if err := checkSupport(n.Atoms, n.OutputSize, n.VMin, n.VMax, n.Dueling); err != nil {
+		return err
+	}
+	if err := checkHeads(n.Heads, n.OutputSize, n.Dueling, n.Atoms, n.Recurrent); err != nil {
+		return err
+	}
+	if err := checkRecurrent(n.Recurrent, n.Noisy); err != nil {
+		return err
+	}
+	if err := checkConv(n.Conv, n.InputSize, n.HiddenSize1, n.Noisy); err != nil {
+		return err
+	}
+
+	if n.Noisy {
+		params := n.params()
+		if len(n.Sigma)*2 != len(params) {
+			return fmt.Errorf("noise scales: expected %d arrays, got %d", len(params)/2, len(n.Sigma))
+		}
+		for k, sigma := range n.Sigma {
+			if len(sigma) != len(params[k]) {
+				return fmt.Errorf("noise scales %d: expected %d values, got %d", k, len(params[k]), len(sigma))
+			}
+			for i, v := range sigma {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					return fmt.Errorf("noise scales %d: value [%d] is %v", k, i, v)
+				}
+			}
+		}
+	}
+
+	enc, err := LookupEncoder(n.Encoder)
+	if err != nil {
+		return err
+	}
+	if enc.Size() != n.InputSize {
+		return fmt.Errorf("encoder %q produces %d inputs, network expects %d", enc.Name(), enc.Size(), n.InputSize)
+	}
+	return nil
+}
+
+// roundWeights32 returns a copy of the weights rounded to float32 precision
+func roundWeights32(w NetworkWeights) NetworkWeights {
+	return fromWeights32(toWeights32(w))
+}
+
+// toWeights32 narrows weights to float32
+func toWeights32(w NetworkWeights) networkWeights32 {
+	return networkWeights32{
+		W1:           matrixTo32(w.W1),
+		B1:           vectorTo32(w.B1),
+		W2:           matrixTo32(w.W2),
+		B2:           vectorTo32(w.B2),
+		W3:           matrixTo32(w.W3),
+		B3:           vectorTo32(w.B3),
+		InputSize:    w.InputSize,
+		HiddenSize1:  w.HiddenSize1,
+		HiddenSize2:  w.HiddenSize2,
+		OutputSize:   w.OutputSize,
+		LearningRate: w.LearningRate,
+		Encoder:      w.Encoder,
+		Metadata:     w.Metadata,
+		Dueling:      w.Dueling,
+		WV:           matrixTo32(w.WV),
+		BV:           vectorTo32(w.BV),
+		Noisy:        w.Noisy,
+		Sigma:        matrixTo32(w.Sigma),
+		Recurrent:    w.Recurrent,
+		U2:           matrixTo32(w.U2),
+		Conv:         w.Conv,
+		Atoms:        w.Atoms,
+		VMin:         w.VMin,
+		VMax:         w.VMax,
+		Heads:        w.Heads,
+	}
+}
+
+// fromWeights32 widens float32 weights back to float64
+func fromWeights32(w networkWeights32) NetworkWeights {
+	return NetworkWeights{
+		W1:           matrixTo64(w.W1),
+		B1:           vectorTo64(w.B1),
+		W2:           matrixTo64(w.W2),
+		B2:           vectorTo64(w.B2),
+		W3:           matrixTo64(w.W3),
+		B3:           vectorTo64(w.B3),
+		InputSize:    w.InputSize,
+		HiddenSize1:  w.HiddenSize1,
+		HiddenSize2:  w.HiddenSize2,
+		OutputSize:   w.OutputSize,
+		LearningRate: w.LearningRate,
+		Encoder:      w.Encoder,
+		Metadata:     w.Metadata,
+		Dueling:      w.Dueling,
+		WV:           matrixTo64(w.WV),
+		BV:           vectorTo64(w.BV),
+		Noisy:        w.Noisy,
+		Sigma:        matrixTo64(w.Sigma),
+		Recurrent:    w.Recurrent,
+		U2:           matrixTo64(w.U2),
+		Conv:         w.Conv,
+		Atoms:        w.Atoms,
+		VMin:         w.VMin,
+		VMax:         w.VMax,
+		Heads:        w.Heads,
+	}
+}
+
+func vectorTo32(v []float64) []float32 {
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(x)
+	}
+	return out
+}
+
+func vectorTo64(v []float32) []float64 {
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = float64(x)
+	}
+	return out
+}
+
+func matrixTo32(m [][]float64) [][]float32 {
+	out := make([][]float32, len(m))
+	for i, row := range m {
+		out[i] = vectorTo32(row)
+	}
+	return out
+}
+
+func matrixTo64(m [][]float32) [][]float64 {
+	out := make([][]float64, len(m))
+	for i, row := range m {
+		out[i] = vectorTo64(row)
+	}
+	return out
+}
@@ -1,27 +1,69 @@
 package ai
 
 import (
-	"encoding/gob"
+	"fmt"
 	"math"
 	"math/rand"
-	"os"
 )
 
 // QNetwork represents a feedforward neural network for Q-value estimation
+// Weight matrices are stored flat and row-major by output neuron, see linalg.go
 type QNetwork struct {
 	// Layer 1: Input -> Hidden1
-	W1 [][]float64 // [inputSize][hiddenSize1]
-	B1 []float64   // [hiddenSize1]
+	W1 []float64 // [hiddenSize1][inputSize]
+	B1 []float64 // [hiddenSize1]
 
 	// Layer 2: Hidden1 -> Hidden2
-	W2 [][]float64 // [hiddenSize1][hiddenSize2]
-	B2 []float64   // [hiddenSize2]
+	W2 []float64 // [hiddenSize2][hiddenSize1], [3·hiddenSize2][hiddenSize1] if recurrent
+	B2 []float64 // [hiddenSize2], [3·hiddenSize2] if recurrent
 
 	// Layer 3: Hidden2 -> Output
-	W3 [][]float64 // [hiddenSize2][outputSize]
-	B3 []float64   // [outputSize]
-
-	// Dimensions
+	W3 []float64 // [outputSize][hiddenSize2]
+	B3 []float64 // [outputSize]
+
+	// Dueling networks treat layer 3 as an advantage stream and add a
+	// value stream beside it, combined as Q = V + A - mean(A). WV and BV
+	// are nil for plain networks.
+	Dueling bool
+	WV      []float64 // [1][hiddenSize2]
+	BV      []float64 // [1]
+
+	// Noisy networks learn a noise scale for every parameter, in params
+	// order, see noisy.go. noise turns the noise on for training.
+	Noisy bool
+	Sigma [][]float64
+	noise bool
+
+	// dropout is the probability of dropping each unit of hidden layer 1
+	// in forward passes, for training only, see SetDropout
+	dropout float64
+
+	// Recurrent networks make layer 2 a GRU whose hidden state carries
+	// over between forward passes, with recurrent weights U2, see
+	// recurrent.go. U2 is nil for feedforward networks.
+	Recurrent bool
+	U2        []float64 // [3·hiddenSize2][hiddenSize2]
+
+	// Convolutional networks make layer 1 a convolution over input planes,
+	// whose feature maps form hidden layer 1, see conv.go. W1 then holds
+	// one patch per filter and B1 one bias per filter. Conv.Filters is 0
+	// for dense networks.
+	Conv ConvShape
+
+	// Distributional networks output Atoms logits per action, a softmax
+	// distribution over returns evenly spaced from VMin to VMax, see
+	// distributional.go. Atoms is 0 for networks that output Q-values.
+	Atoms      int
+	VMin, VMax float64
+
+	// Bootstrapped networks output Heads sets of action values from one
+	// shared trunk, head-major, see bootstrap.go; Forward returns their
+	// mean. Heads is 0 for single-headed networks.
+	Heads int
+
+	// Dimensions. OutputSize is the width of layer 3: one value per action,
+	// Atoms per action for distributional networks, or one per action and
+	// head for bootstrapped ones.
 	InputSize   int
 	HiddenSize1 int
 	HiddenSize2 int
@@ -30,8 +72,23 @@ type QNetwork struct {
 	// Learning rate
 	LearningRate float64
 
+	// Encoder names the state encoder the network expects, see LookupEncoder
+	Encoder string
+
+	// Metadata records how the network was trained, nil if unknown, see
+	// ModelMetadata
+	Metadata *ModelMetadata
+
 	// RNG for initialization
 	rng *rand.Rand
+
+	// Reusable forward/backward buffers, allocated on first use
+	cache *forwardCache
+	batch *batchCache
+	grads *gradients // Convolutional and recurrent networks only
+
+	// readOnly is set for memory-mapped networks whose weights must not change
+	readOnly bool
 }
 
 // NewQNetwork creates a new neural network with Xavier initialization
@@ -43,6 +100,7 @@ func NewQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64
 		HiddenSize2:  hiddenSize2,
 		OutputSize:   outputSize,
 		LearningRate: lr,
+		Encoder:      DefaultEncoderName,
 		rng:          rng,
 	}
 
@@ -59,190 +117,574 @@ func NewQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64
 	return net
 }
 
-// xavierInit initializes weights using Xavier/Glorot initialization
-func xavierInit(fanIn, fanOut int, rng *rand.Rand) [][]float64 {
+// NewDuelingQNetwork creates a network whose shared hidden layers feed
+// separate value and advantage streams. Its other weights match a plain
+// network created with the same seed.
+func NewDuelingQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork {
+	net := NewQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize, lr, seed)
+	net.Dueling = true
+	net.WV = xavierInit(hiddenSize2, 1, net.rng)
+	net.BV = make([]float64, 1)
+	return net
+}
+
+// Architecture names the network's output head, "dueling",
+// "distributional", "bootstrapped" or "standard"
+func (n *QNetwork) Architecture() string {
+	if n.Dueling {
+		return "dueling"
+	}
+	if n.Atoms > 0 {
+		return "distributional"
+	}
+	if n.Heads > 0 {
+		return "bootstrapped"
+	}
+	return "standard"
+}
+
+// xavierInit initializes a flat [fanOut][fanIn] matrix using Xavier/Glorot
+// initialization. Values are drawn input-major so a given seed produces the
+// same network as the original nested layout.
+func xavierInit(fanIn, fanOut int, rng *rand.Rand) []float64 {
 	stddev := math.Sqrt(2.0 / float64(fanIn+fanOut))
-	weights := make([][]float64, fanIn)
+	weights := make([]float64, fanIn*fanOut)
 	for i := 0; i < fanIn; i++ {
-		weights[i] = make([]float64, fanOut)
 		for j := 0; j < fanOut; j++ {
-			weights[i][j] = rng.NormFloat64() * stddev
+			weights[j*fanIn+i] = rng.NormFloat64() * stddev
 		}
 	}
 	return weights
 }
 
 // Forward performs a forward pass through the network
+// Hidden activations use the network's scratch buffers; the returned slice
+// is freshly allocated and owned by the caller. Inference loops should use
+// NetworkPolicy or ForwardWithCache, which allocate nothing.
 func (n *QNetwork) Forward(input []float64) []float64 {
-	// Layer 1: input -> hidden1 with ReLU
-	h1 := n.linearForward(input, n.W1, n.B1)
-	h1 = relu(h1)
-
-	// Layer 2: hidden1 -> hidden2 with ReLU
-	h2 := n.linearForward(h1, n.W2, n.B2)
-	h2 = relu(h2)
-
-	// Layer 3: hidden2 -> output (no activation for Q-values)
-	output := n.linearForward(h2, n.W3, n.B3)
-
-	return output
+	output, _ := n.ForwardWithCache(input)
+	result := make([]float64, len(output))
+	copy(result, output)
+	return result
 }
 
 // ForwardWithCache performs forward pass and caches activations for backprop
+// The cache and the returned output are the network's reusable scratch
+// buffers: they stay valid only until the next forward pass on this network,
+// and a network must not be used from several goroutines at once
 func (n *QNetwork) ForwardWithCache(input []float64) ([]float64, *forwardCache) {
-	cache := &forwardCache{
-		input: make([]float64, len(input)),
-	}
+	cache := n.scratch()
+	return n.forwardWith(cache, input), cache
+}
+
+// forwardWith runs a forward pass using the given buffers instead of the
+// network's own, so several goroutines can share read-only weights
+func (n *QNetwork) forwardWith(cache *forwardCache, input []float64) []float64 {
 	copy(cache.input, input)
+	cache.noisy = n.noise && cache.rng != nil
 
 	// Layer 1
-	z1 := n.linearForward(input, n.W1, n.B1)
-	cache.z1 = z1
-	h1 := relu(z1)
-	cache.h1 = h1
+	if n.convolutional() {
+		n.convForward(cache)
+	} else {
+		n.linear(cache, 0, n.W1, n.B1, cache.input, cache.z1)
+	}
+	reluInto(cache.h1, cache.z1)
+	cache.dropped = n.dropout > 0 && cache.rng != nil
+	if cache.dropped {
+		n.dropUnits(cache)
+	}
 
 	// Layer 2
-	z2 := n.linearForward(h1, n.W2, n.B2)
-	cache.z2 = z2
-	h2 := relu(z2)
-	cache.h2 = h2
+	if n.Recurrent {
+		n.gruForward(cache)
+	} else {
+		n.linear(cache, 1, n.W2, n.B2, cache.h1, cache.z2)
+		reluInto(cache.h2, cache.z2)
+	}
 
 	// Layer 3
-	output := n.linearForward(h2, n.W3, n.B3)
+	n.linear(cache, 2, n.W3, n.B3, cache.h2, cache.output)
+	if n.Dueling {
+		n.linear(cache, 3, n.WV, n.BV, cache.h2, cache.value)
+		combineStreams(cache.output, cache.value[0])
+	}
+	if n.Atoms > 0 {
+		n.expectedValues(cache.output, cache.probs, cache.q)
+		return cache.q
+	}
+	if n.Heads > 0 {
+		n.headMean(cache.output, cache.q)
+		return cache.q
+	}
 
-	return output, cache
+	return cache.output
+}
+
+// combineStreams turns advantages into Q-values given the state value
+func combineStreams(advantages []float64, value float64) {
+	mean := 0.0
+	for _, a := range advantages {
+		mean += a
+	}
+	mean /= float64(len(advantages))
+	for j := range advantages {
+		advantages[j] += value - mean
+	}
+}
+
+// splitStreamGradient turns the loss gradient w.r.t. the Q-values in
+// dOutput into the gradient w.r.t. the advantages, in place, and writes the
+// gradient w.r.t. the state value to dValue
+func splitStreamGradient(dOutput, dValue []float64) {
+	sum := 0.0
+	for _, d := range dOutput {
+		sum += d
+	}
+	mean := sum / float64(len(dOutput))
+	for j := range dOutput {
+		dOutput[j] -= mean
+	}
+	dValue[0] = sum
 }
 
+// forwardCache holds the activations of a forward pass plus the gradient
+// buffers needed to backpropagate through it
 type forwardCache struct {
-	input    []float64
-	z1, h1   []float64
-	z2, h2   []float64
+	input  []float64
+	z1, h1 []float64
+	z2, h2 []float64
+	output []float64
+	value  []float64 // Dueling networks only
+
+	// Return distributions and their means, distributional networks only.
+	// output then holds the logits. Bootstrapped networks keep the mean of
+	// their heads in q.
+	probs, q []float64
+
+	// Backward pass scratch
+	dOutput  []float64
+	dH2, dZ2 []float64
+	dH1, dZ1 []float64
+
+	// Value stream gradients, dueling networks only
+	dValue, dHV []float64
+
+	// GRU state and gradients, recurrent networks only. h2 holds the
+	// hidden state between passes and z2 the gates' input from layer 1.
+	hPrev, rh   []float64 // Previous hidden state and its reset part
+	gates, uh   []float64 // Gate activations and recurrent inputs, z r n
+	noBias      []float64 // Zeros for the bias-free recurrent products
+	dHPrev, dRH []float64
+
+	// Weight noise, noisy networks only. noisy records whether the last
+	// forward pass used it.
+	noise []layerNoise
+	rng   *rand.Rand
+	noisy bool
+
+	// Dropout mask of hidden layer 1, the factor each unit was scaled by.
+	// dropped records whether the last forward pass used it.
+	mask    []float64
+	dropped bool
 }
 
-// linearForward computes y = xW + b
-func (n *QNetwork) linearForward(input []float64, weights [][]float64, bias []float64) []float64 {
-	outputSize := len(bias)
-	output := make([]float64, outputSize)
+// newForwardCache allocates scratch buffers sized for the network
+func newForwardCache(n *QNetwork) *forwardCache {
+	cache := newProbeCache(n)
+	if n.noise || n.dropout > 0 {
+		cache.rng = rand.New(rand.NewSource(n.rng.Int63()))
+	}
+	return cache
+}
+
+// newProbeCache allocates scratch buffers without a random source, so
+// passes through them use the mean weights and no dropout and leave the
+// network's random state alone
+func newProbeCache(n *QNetwork) *forwardCache {
+	cache := &forwardCache{
+		input:   make([]float64, n.InputSize),
+		z1:      make([]float64, n.HiddenSize1),
+		h1:      make([]float64, n.HiddenSize1),
+		z2:      make([]float64, n.layer2Size()),
+		h2:      make([]float64, n.HiddenSize2),
+		output:  make([]float64, n.OutputSize),
+		dOutput: make([]float64, n.OutputSize),
+		dH2:     make([]float64, n.HiddenSize2),
+		dZ2:     make([]float64, n.layer2Size()),
+		dH1:     make([]float64, n.HiddenSize1),
+		dZ1:     make([]float64, n.HiddenSize1),
+	}
+	if n.Dueling {
+		cache.value = make([]float64, 1)
+		cache.dValue = make([]float64, 1)
+		cache.dHV = make([]float64, n.HiddenSize2)
+	}
+	if n.Recurrent {
+		cache.hPrev = make([]float64, n.HiddenSize2)
+		cache.rh = make([]float64, n.HiddenSize2)
+		cache.gates = make([]float64, 3*n.HiddenSize2)
+		cache.uh = make([]float64, 3*n.HiddenSize2)
+		cache.noBias = make([]float64, 3*n.HiddenSize2)
+		cache.dHPrev = make([]float64, n.HiddenSize2)
+		cache.dRH = make([]float64, n.HiddenSize2)
+	}
+	if n.Atoms > 0 {
+		cache.probs = make([]float64, n.OutputSize)
+		cache.q = make([]float64, n.Actions())
+	}
+	if n.Heads > 0 {
+		cache.q = make([]float64, n.Actions())
+	}
+	if n.noise {
+		cache.noise = newNoiseBuffers(n)
+	}
+	if n.dropout > 0 {
+		cache.mask = make([]float64, n.HiddenSize1)
+	}
+	return cache
+}
 
-	for j := 0; j < outputSize; j++ {
-		sum := bias[j]
-		for i := 0; i < len(input); i++ {
-			sum += input[i] * weights[i][j]
+// scratch returns the network's scratch buffers, allocating them on first use
+func (n *QNetwork) scratch() *forwardCache {
+	if n.cache == nil {
+		n.cache = newForwardCache(n)
+	}
+	return n.cache
+}
+
+// batchCache holds hidden activations for ForwardBatch, grown as needed
+type batchCache struct {
+	h1, h2 [][]float64
+	value  [][]float64 // Dueling networks only
+}
+
+// batchScratch returns hidden-layer buffers for at least size inputs
+func (n *QNetwork) batchScratch(size int) *batchCache {
+	if n.batch == nil {
+		n.batch = &batchCache{}
+	}
+	b := n.batch
+	for len(b.h1) < size {
+		b.h1 = append(b.h1, make([]float64, n.HiddenSize1))
+		b.h2 = append(b.h2, make([]float64, n.HiddenSize2))
+		b.value = append(b.value, make([]float64, 1))
+	}
+	return b
+}
+
+// ForwardBatch computes Q-values for several inputs in one pass. Each weight
+// row is read once per batch rather than once per input, which matters once
+// the hidden layers no longer fit in cache. Results match Forward exactly,
+// except that noisy networks always use their mean weights here and
+// recurrent networks start every input from a zero hidden state.
+// Convolutional and recurrent networks run the inputs one at a time.
+func (n *QNetwork) ForwardBatch(inputs [][]float64) [][]float64 {
+	if len(inputs) == 0 {
+		return nil
+	}
+	if n.Recurrent || n.convolutional() {
+		cache := newForwardCache(n)
+		outputs := make([][]float64, len(inputs))
+		for k, input := range inputs {
+			cache.resetState()
+			outputs[k] = append([]float64(nil), n.forwardWith(cache, input)...)
 		}
-		output[j] = sum
+		return outputs
 	}
+	b := n.batchScratch(len(inputs))
+	h1, h2 := b.h1[:len(inputs)], b.h2[:len(inputs)]
 
-	return output
+	outputs := make([][]float64, len(inputs))
+	values := make([]float64, len(inputs)*n.OutputSize)
+	for k := range outputs {
+		outputs[k] = values[k*n.OutputSize : (k+1)*n.OutputSize]
+	}
+
+	backend.BatchMatVec(n.W1, n.B1, inputs, h1)
+	for _, h := range h1 {
+		reluInto(h, h)
+	}
+	backend.BatchMatVec(n.W2, n.B2, h1, h2)
+	for _, h := range h2 {
+		reluInto(h, h)
+	}
+	backend.BatchMatVec(n.W3, n.B3, h2, outputs)
+	if n.Dueling {
+		value := b.value[:len(inputs)]
+		backend.BatchMatVec(n.WV, n.BV, h2, value)
+		for k, q := range outputs {
+			combineStreams(q, value[k][0])
+		}
+	}
+	if n.Atoms > 0 {
+		probs := make([]float64, n.OutputSize)
+		qValues := make([]float64, len(inputs)*n.Actions())
+		for k, logits := range outputs {
+			outputs[k] = qValues[k*n.Actions() : (k+1)*n.Actions()]
+			n.expectedValues(logits, probs, outputs[k])
+		}
+	}
+	if n.Heads > 0 {
+		qValues := make([]float64, len(inputs)*n.Actions())
+		for k, heads := range outputs {
+			outputs[k] = qValues[k*n.Actions() : (k+1)*n.Actions()]
+			n.headMean(heads, outputs[k])
+		}
+	}
+
+	return outputs
 }
 
-// relu applies ReLU activation
-func relu(x []float64) []float64 {
-	result := make([]float64, len(x))
+// reluInto writes ReLU(x) into dst
+func reluInto(dst, x []float64) {
 	for i, v := range x {
 		if v > 0 {
-			result[i] = v
+			dst[i] = v
+		} else {
+			dst[i] = 0
 		}
 	}
-	return result
 }
 
-// reluDerivative computes ReLU derivative
-func reluDerivative(z []float64) []float64 {
-	result := make([]float64, len(z))
+// reluBackward writes dOut * ReLU'(z) into dst
+func reluBackward(dst, dOut, z []float64) {
 	for i, v := range z {
 		if v > 0 {
-			result[i] = 1.0
+			dst[i] = dOut[i]
+		} else {
+			dst[i] = 0
 		}
 	}
-	return result
+}
+
+// LossOptions controls how a TD error becomes a weight update. The zero
+// value is plain squared error without clipping.
+type LossOptions struct {
+	// HuberDelta > 0 uses the Huber (smooth L1) loss: quadratic for errors
+	// up to HuberDelta and linear beyond, so the gradient never exceeds it
+	HuberDelta float64
+	// MaxGradNorm > 0 rescales any update whose gradient has a larger L2
+	// norm down to this norm
+	MaxGradNorm float64
+}
+
+// Loss returns the loss for a TD error and its derivative w.r.t. the error
+func (o LossOptions) Loss(diff float64) (float64, float64) {
+	if d := o.HuberDelta; d > 0 && math.Abs(diff) > d {
+		if diff < 0 {
+			return d * (-diff - 0.5*d), -d
+		}
+		return d * (diff - 0.5*d), d
+	}
+	return diff * diff * 0.5, diff
 }
 
 // Backward performs backpropagation and updates weights
 // target is the target Q-value for the taken action
 func (n *QNetwork) Backward(cache *forwardCache, output []float64, targetAction int, targetQ float64) {
+	n.BackwardLoss(cache, output, targetAction, targetQ, LossOptions{})
+}
+
+// BackwardLoss is Backward with the loss and gradient clipping chosen by
+// opts. It returns the loss.
+func (n *QNetwork) BackwardLoss(cache *forwardCache, output []float64, targetAction int, targetQ float64, opts LossOptions) float64 {
+	loss, grad := opts.Loss(output[targetAction] - targetQ)
+	n.backwardError(cache, targetAction, grad, opts.MaxGradNorm)
+	return loss
+}
+
+// backwardError backpropagates an output error on a single action and
+// updates the weights, scaling the update down to maxNorm if it is set
+func (n *QNetwork) backwardError(cache *forwardCache, targetAction int, err, maxNorm float64) {
 	// Compute output layer error (only for the target action)
-	dOutput := make([]float64, n.OutputSize)
-	dOutput[targetAction] = output[targetAction] - targetQ
+	for j := range cache.dOutput {
+		cache.dOutput[j] = 0
+	}
+	cache.dOutput[targetAction] = err
+	n.backwardOutput(cache, maxNorm)
+}
 
-	// Backprop through layer 3
-	dH2 := n.linearBackward(cache.h2, n.W3, n.B3, dOutput, true)
+// backwardOutput backpropagates the error in cache.dOutput and updates the
+// weights, scaling the update down to maxNorm if it is set
+func (n *QNetwork) backwardOutput(cache *forwardCache, maxNorm float64) {
+	n.checkWritable()
+	dOutput := cache.dOutput
+	if n.Recurrent || n.convolutional() {
+		// The GRU's and the filters' gradients are only known once
+		// accumulated
+		if n.grads == nil {
+			n.grads = newGradients(n)
+		}
+		n.grads.zero()
+		n.accumulateGradients(cache, dOutput, n.grads)
+		applyClipped(n, n.grads, 1, maxNorm)
+		return
+	}
+
+	// Backprop through layer 3, and the value stream beside it
+	if n.Dueling {
+		splitStreamGradient(dOutput, cache.dValue)
+		n.linearBack(cache, 3, n.WV, cache.dValue, cache.dHV)
+	}
+	n.linearBack(cache, 2, n.W3, dOutput, cache.dH2)
+	if n.Dueling {
+		backend.Axpy(1, cache.dHV, cache.dH2)
+	}
 
 	// Apply ReLU derivative
-	dZ2 := elementMul(dH2, reluDerivative(cache.z2))
+	reluBackward(cache.dZ2, cache.dH2, cache.z2)
 
 	// Backprop through layer 2
-	dH1 := n.linearBackward(cache.h1, n.W2, n.B2, dZ2, true)
+	n.linearBack(cache, 1, n.W2, cache.dZ2, cache.dH1)
+	dropBackward(cache)
 
 	// Apply ReLU derivative
-	dZ1 := elementMul(dH1, reluDerivative(cache.z1))
+	reluBackward(cache.dZ1, cache.dH1, cache.z1)
+
+	// Layer 1 (input gradient not needed)
+	n.linearBack(cache, 0, n.W1, cache.dZ1, nil)
 
-	// Backprop through layer 1
-	n.linearBackward(cache.input, n.W1, n.B1, dZ1, true)
+	// Every layer's error is known before any weight changes, so the
+	// update can be clipped as a whole
+	lr := n.LearningRate
+	if maxNorm > 0 {
+		if norm := n.sampleGradNorm(cache); norm > maxNorm {
+			lr *= maxNorm / norm
+		}
+	}
+	if n.Dueling {
+		linearUpdate(cache.h2, n.WV, n.BV, cache.dValue, lr)
+		n.noisyUpdate(cache, 3, lr)
+	}
+	linearUpdate(cache.h2, n.W3, n.B3, dOutput, lr)
+	n.noisyUpdate(cache, 2, lr)
+	linearUpdate(cache.h1, n.W2, n.B2, cache.dZ2, lr)
+	n.noisyUpdate(cache, 1, lr)
+	linearUpdate(cache.input, n.W1, n.B1, cache.dZ1, lr)
+	n.noisyUpdate(cache, 0, lr)
 }
 
-// linearBackward computes gradients and updates weights
-func (n *QNetwork) linearBackward(input []float64, weights [][]float64, bias []float64, dOutput []float64, update bool) []float64 {
-	inputSize := len(input)
-	outputSize := len(dOutput)
+// sampleGradNorm returns the L2 norm of the gradient backwardOutput is about
+// to apply. Each layer's weight gradient is the outer product of its error
+// and input, whose norm is the product of theirs.
+func (n *QNetwork) sampleGradNorm(cache *forwardCache) float64 {
+	layers := [][2][]float64{
+		{cache.dOutput, cache.h2},
+		{cache.dZ2, cache.h1},
+		{cache.dZ1, cache.input},
+	}
+	if n.Dueling {
+		layers = append(layers, [2][]float64{cache.dValue, cache.h2})
+	}
+	sum := 0.0
+	for _, l := range layers {
+		errNorm, inNorm := sumSquares(l[0]), sumSquares(l[1])
+		sum += errNorm * (inNorm + 1) // weights and bias
+	}
+	return math.Sqrt(sum + n.noiseGradSquares(cache))
+}
 
-	// Compute gradient w.r.t. input
-	dInput := make([]float64, inputSize)
-	for i := 0; i < inputSize; i++ {
-		for j := 0; j < outputSize; j++ {
-			dInput[i] += weights[i][j] * dOutput[j]
-		}
+// sumSquares returns the squared L2 norm of v
+func sumSquares(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x * x
 	}
+	return sum
+}
 
-	if update {
-		// Update weights and biases
-		lr := n.LearningRate
-		for i := 0; i < inputSize; i++ {
-			for j := 0; j < outputSize; j++ {
-				weights[i][j] -= lr * input[i] * dOutput[j]
-			}
-		}
-		for j := 0; j < outputSize; j++ {
-			bias[j] -= lr * dOutput[j]
-		}
+// linearUpdate applies the SGD update for one layer given its error
+func linearUpdate(input []float64, weights []float64, bias []float64, dOutput []float64, lr float64) {
+	backend.Rank1Update(weights, -lr, dOutput, input)
+	for j, d := range dOutput {
+		bias[j] -= lr * d
 	}
+}
 
-	return dInput
+// checkWritable panics if the network's weights are read-only
+func (n *QNetwork) checkWritable() {
+	if n.readOnly {
+		panic("ai: cannot modify a read-only (memory-mapped) network; Clone it first")
+	}
 }
 
-// elementMul performs element-wise multiplication
-func elementMul(a, b []float64) []float64 {
-	result := make([]float64, len(a))
-	for i := range a {
-		result[i] = a[i] * b[i]
+// params returns the weight and bias slices in serialization order
+func (n *QNetwork) params() [][]float64 {
+	params := [][]float64{n.W1, n.B1, n.W2, n.B2, n.W3, n.B3}
+	if n.Dueling {
+		params = append(params, n.WV, n.BV)
 	}
-	return result
+	if n.Recurrent {
+		params = append(params, n.U2)
+	}
+	if n.Noisy {
+		params = append(params, n.Sigma...)
+	}
+	return params
 }
 
-// CopyFrom copies weights from another network
-func (n *QNetwork) CopyFrom(other *QNetwork) {
-	copyMatrix(n.W1, other.W1)
-	copy(n.B1, other.B1)
-	copyMatrix(n.W2, other.W2)
-	copy(n.B2, other.B2)
-	copyMatrix(n.W3, other.W3)
-	copy(n.B3, other.B3)
+// allocParams allocates zeroed weights and biases for the declared dimensions
+func (n *QNetwork) allocParams() {
+	in1, out1 := n.Conv.layer1Dims(n.InputSize, n.HiddenSize1)
+	n.W1 = make([]float64, out1*in1)
+	n.B1 = make([]float64, out1)
+	n.W2 = make([]float64, n.layer2Size()*n.HiddenSize1)
+	n.B2 = make([]float64, n.layer2Size())
+	n.W3 = make([]float64, n.OutputSize*n.HiddenSize2)
+	n.B3 = make([]float64, n.OutputSize)
+	if n.Dueling {
+		n.WV = make([]float64, n.HiddenSize2)
+		n.BV = make([]float64, 1)
+	}
+	if n.Recurrent {
+		n.U2 = make([]float64, 3*n.HiddenSize2*n.HiddenSize2)
+	}
+	if n.Noisy {
+		n.Sigma = nil
+		for _, p := range n.params() {
+			n.Sigma = append(n.Sigma, make([]float64, len(p)))
+		}
+	}
 }
 
-// copyMatrix copies a 2D matrix
-func copyMatrix(dst, src [][]float64) {
-	for i := range src {
-		copy(dst[i], src[i])
+// CopyFrom copies weights from another network of the same architecture
+func (n *QNetwork) CopyFrom(other *QNetwork) {
+	n.checkWritable()
+	src := other.params()
+	for k, dst := range n.params() {
+		copy(dst, src[k])
 	}
 }
 
 // Clone creates a deep copy of the network
 func (n *QNetwork) Clone() *QNetwork {
-	clone := NewQNetwork(n.InputSize, n.HiddenSize1, n.HiddenSize2, n.OutputSize, n.LearningRate, 0)
+	clone := &QNetwork{
+		InputSize:    n.InputSize,
+		HiddenSize1:  n.HiddenSize1,
+		HiddenSize2:  n.HiddenSize2,
+		OutputSize:   n.OutputSize,
+		LearningRate: n.LearningRate,
+		Encoder:      n.Encoder,
+		Metadata:     n.Metadata,
+		Dueling:      n.Dueling,
+		Noisy:        n.Noisy,
+		Recurrent:    n.Recurrent,
+		Conv:         n.Conv,
+		Atoms:        n.Atoms,
+		VMin:         n.VMin,
+		VMax:         n.VMax,
+		Heads:        n.Heads,
+		rng:          rand.New(rand.NewSource(0)),
+	}
+	clone.allocParams()
 	clone.CopyFrom(n)
 	return clone
 }
 
 // NetworkWeights holds serializable network weights
+// Matrices use the original nested [input][output] layout so existing model
+// files stay readable; QNetwork converts to and from its flat storage
 type NetworkWeights struct {
 	W1           [][]float64
 	B1           []float64
@@ -255,6 +697,37 @@ type NetworkWeights struct {
 	HiddenSize2  int
 	OutputSize   int
 	LearningRate float64
+	Encoder      string // Empty in files saved before encoders were recorded
+
+	// How the network was trained, nil in files saved before it was
+	// recorded
+	Metadata *ModelMetadata `json:",omitempty"`
+
+	// Value stream of dueling networks, see QNetwork.Dueling
+	Dueling bool        `json:",omitempty"`
+	WV      [][]float64 `json:",omitempty"`
+	BV      []float64   `json:",omitempty"`
+
+	// Noise scales of noisy networks, flat in QNetwork.params order
+	Noisy bool        `json:",omitempty"`
+	Sigma [][]float64 `json:",omitempty"`
+
+	// GRU layer of recurrent networks. W2 and B2 then hold the three
+	// gates' weights side by side, z r n, see QNetwork.Recurrent.
+	Recurrent bool        `json:",omitempty"`
+	U2        [][]float64 `json:",omitempty"`
+
+	// Shape of a convolutional layer 1. W1 then holds one patch per
+	// filter, see QNetwork.Conv.
+	Conv ConvShape `json:",omitzero"`
+
+	// Return support of distributional networks, see QNetwork.Atoms
+	Atoms int     `json:",omitempty"`
+	VMin  float64 `json:",omitempty"`
+	VMax  float64 `json:",omitempty"`
+
+	// Q-value heads of bootstrapped networks, see QNetwork.Heads
+	Heads int `json:",omitempty"`
 }
 
 // legacyNetworkWeights is the old format with unused 2D bias fields
@@ -272,83 +745,35 @@ type legacyNetworkWeights struct {
 	LearningRate float64
 }
 
-// Save saves the network weights to a file
-func (n *QNetwork) Save(path string) error {
-	file, err := os.Create(path)
+// StateEncoder returns the encoder the network expects its input from
+func (n *QNetwork) StateEncoder() Encoder {
+	enc, err := LookupEncoder(n.Encoder)
 	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	weights := NetworkWeights{
-		W1:           n.W1,
-		B1:           n.B1,
-		W2:           n.W2,
-		B2:           n.B2,
-		W3:           n.W3,
-		B3:           n.B3,
-		InputSize:    n.InputSize,
-		HiddenSize1:  n.HiddenSize1,
-		HiddenSize2:  n.HiddenSize2,
-		OutputSize:   n.OutputSize,
-		LearningRate: n.LearningRate,
+		// Validate rejects unknown encoders when loading, so this only
+		// happens for hand-built networks
+		return DefaultEncoder()
 	}
+	return enc
+}
 
-	encoder := gob.NewEncoder(file)
-	return encoder.Encode(weights)
+// Save saves the network weights to a file in the current versioned format
+// Paths ending in ".bin" use the compact flat float32 format
+func (n *QNetwork) Save(path string) error {
+	return n.SaveWithOptions(path, saveOptionsForPath(path))
 }
 
 // LoadNetwork loads network weights from a file
-// Supports both new and legacy formats for backward compatibility
+// Supports the versioned format as well as the unversioned and legacy gob
+// layouts for backward compatibility; see cmd/convert to upgrade old files.
+// NumPy archives of weights trained elsewhere load too, see npz.go.
 func LoadNetwork(path string) (*QNetwork, error) {
-	file, err := os.Open(path)
+	_, net, err := readModelFile(path)
 	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	// Try loading with new format first
-	var weights NetworkWeights
-	decoder := gob.NewDecoder(file)
-	if err := decoder.Decode(&weights); err != nil {
-		// If that fails, try legacy format
-		file.Seek(0, 0) // Reset file position
-		var legacyWeights legacyNetworkWeights
-		decoder = gob.NewDecoder(file)
-		if err := decoder.Decode(&legacyWeights); err != nil {
-			return nil, err
-		}
-		// Convert legacy format to new format
-		weights = NetworkWeights{
-			W1:           legacyWeights.W1,
-			B1:           legacyWeights.B1Vec,
-			W2:           legacyWeights.W2,
-			B2:           legacyWeights.B2Vec,
-			W3:           legacyWeights.W3,
-			B3:           legacyWeights.B3Vec,
-			InputSize:    legacyWeights.InputSize,
-			HiddenSize1:  legacyWeights.HiddenSize1,
-			HiddenSize2:  legacyWeights.HiddenSize2,
-			OutputSize:   legacyWeights.OutputSize,
-			LearningRate: legacyWeights.LearningRate,
-		}
+		return nil, fmt.Errorf("invalid model %s: %w", path, err)
 	}
-
-	net := &QNetwork{
-		W1:           weights.W1,
-		B1:           weights.B1,
-		W2:           weights.W2,
-		B2:           weights.B2,
-		W3:           weights.W3,
-		B3:           weights.B3,
-		InputSize:    weights.InputSize,
-		HiddenSize1:  weights.HiddenSize1,
-		HiddenSize2:  weights.HiddenSize2,
-		OutputSize:   weights.OutputSize,
-		LearningRate: weights.LearningRate,
-		rng:          rand.New(rand.NewSource(0)),
+	if err := net.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid model %s: %w", path, err)
 	}
-
 	return net, nil
 }
 
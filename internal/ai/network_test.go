@@ -0,0 +1,547 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestDuelingNetwork(t *testing.T) {
+	net := NewDuelingQNetwork(22, 8, 6, 3, 0.01, 1)
+	input := make([]float64, 22)
+	for i := range input {
+		input[i] = float64(i%5) / 4
+	}
+
+	// The advantage gradient must match finite differences of the loss
+	const action, target = 1, 0.5
+	loss := func() float64 {
+		d := net.forwardWith(newForwardCache(net), input)[action] - target
+		return d * d / 2
+	}
+	cache := newForwardCache(net)
+	output := net.forwardWith(cache, input)
+	dOutput := make([]float64, net.OutputSize)
+	dOutput[action] = output[action] - target
+	g := newGradients(net)
+	net.accumulateGradients(cache, dOutput, g)
+
+	params, grads := net.params(), g.params()
+	for k, p := range params {
+		for _, i := range []int{0, len(p) / 2, len(p) - 1} {
+			orig := p[i]
+			p[i] = orig + 1e-6
+			up := loss()
+			p[i] = orig - 1e-6
+			down := loss()
+			p[i] = orig
+			if numeric := (up - down) / 2e-6; math.Abs(numeric-grads[k][i]) > 1e-6 {
+				t.Errorf("param %d[%d]: gradient %v, finite difference %v", k, i, grads[k][i], numeric)
+			}
+		}
+	}
+
+	// Batched and cloned forward passes agree with Forward
+	want := net.Forward(input)
+	if got := net.ForwardBatch([][]float64{input})[0]; !equalFloats(got, want) {
+		t.Errorf("ForwardBatch = %v, want %v", got, want)
+	}
+	if got := net.Clone().Forward(input); !equalFloats(got, want) {
+		t.Errorf("Clone().Forward = %v, want %v", got, want)
+	}
+
+	// Every codec keeps the value stream
+	dir := t.TempDir()
+	for _, opts := range []SaveOptions{
+		{Codec: CodecGob, Precision: Float64},
+		{Codec: CodecJSON, Precision: Float64},
+		{Codec: CodecFlat, Precision: Float64},
+	} {
+		path := filepath.Join(dir, "model."+string(opts.Codec))
+		if err := net.SaveWithOptions(path, opts); err != nil {
+			t.Fatalf("%s: save: %v", opts.Codec, err)
+		}
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", opts.Codec, err)
+		}
+		if !loaded.Dueling {
+			t.Errorf("%s: loaded network is not dueling", opts.Codec)
+		}
+		if got := loaded.Forward(input); !equalFloats(got, want) {
+			t.Errorf("%s: loaded Forward = %v, want %v", opts.Codec, got, want)
+		}
+		if info, _ := InspectModel(path); info.Version != 2 {
+			t.Errorf("%s: format version %d, want 2", opts.Codec, info.Version)
+		}
+	}
+}
+
+func TestNoisyNetwork(t *testing.T) {
+	net := NewDuelingQNetwork(22, 8, 6, 3, 0.01, 1)
+	input := make([]float64, 22)
+	for i := range input {
+		input[i] = float64(i%5) / 4
+	}
+	want := net.Forward(input)
+	net.MakeNoisy(NoisySigma0)
+	if got := net.Forward(input); !equalFloats(got, want) {
+		t.Errorf("noise off: Forward = %v, want %v", got, want)
+	}
+
+	net.SetNoise(true)
+	if first, second := append([]float64(nil), net.Forward(input)...), net.Forward(input); equalFloats(first, second) {
+		t.Errorf("noise on: two forward passes both gave %v", first)
+	}
+
+	// Gradients, noise scales included, match finite differences when the
+	// noise sample is held fixed
+	noisyCache := func() *forwardCache {
+		cache := newForwardCache(net)
+		cache.rng = rand.New(rand.NewSource(7))
+		return cache
+	}
+	const action, target = 2, 0.5
+	loss := func() float64 {
+		d := net.forwardWith(noisyCache(), input)[action] - target
+		return d * d / 2
+	}
+	cache := noisyCache()
+	output := net.forwardWith(cache, input)
+	dOutput := make([]float64, net.OutputSize)
+	dOutput[action] = output[action] - target
+	g := newGradients(net)
+	net.accumulateGradients(cache, dOutput, g)
+
+	params, grads := net.params(), g.params()
+	if len(params) != 16 || len(grads) != len(params) {
+		t.Fatalf("%d params, %d gradients, want 16", len(params), len(grads))
+	}
+	for k, p := range params {
+		for _, i := range []int{0, len(p) / 2, len(p) - 1} {
+			orig := p[i]
+			p[i] = orig + 1e-6
+			up := loss()
+			p[i] = orig - 1e-6
+			down := loss()
+			p[i] = orig
+			if numeric := (up - down) / 2e-6; math.Abs(numeric-grads[k][i]) > 1e-6 {
+				t.Errorf("param %d[%d]: gradient %v, finite difference %v", k, i, grads[k][i], numeric)
+			}
+		}
+	}
+
+	// Saved models keep their noise scales and load with noise off
+	net.SetNoise(false)
+	want = net.Forward(input)
+	dir := t.TempDir()
+	for _, codec := range []Codec{CodecGob, CodecJSON, CodecFlat} {
+		path := filepath.Join(dir, "model."+string(codec))
+		if err := net.SaveWithOptions(path, SaveOptions{Codec: codec, Precision: Float64}); err != nil {
+			t.Fatalf("%s: save: %v", codec, err)
+		}
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if !loaded.Noisy || !equalFloats(loaded.Sigma[0], net.Sigma[0]) {
+			t.Errorf("%s: noise scales not restored", codec)
+		}
+		if got := loaded.Forward(input); !equalFloats(got, want) {
+			t.Errorf("%s: loaded Forward = %v, want %v", codec, got, want)
+		}
+	}
+}
+
+func TestBackwardLoss(t *testing.T) {
+	huber := LossOptions{HuberDelta: 1}
+	for _, tc := range []struct{ diff, loss, grad float64 }{
+		{0.5, 0.125, 0.5},
+		{3, 2.5, 1},
+		{-3, 2.5, -1},
+	} {
+		if loss, grad := huber.Loss(tc.diff); loss != tc.loss || grad != tc.grad {
+			t.Errorf("Loss(%v) = %v, %v, want %v, %v", tc.diff, loss, grad, tc.loss, tc.grad)
+		}
+	}
+
+	// A clipped update moves the weights by exactly lr * MaxGradNorm
+	for _, dueling := range []bool{false, true} {
+		newNetwork := NewQNetwork
+		if dueling {
+			newNetwork = NewDuelingQNetwork
+		}
+		net := newNetwork(22, 8, 6, 3, 0.1, 1)
+		input := make([]float64, 22)
+		for i := range input {
+			input[i] = 1
+		}
+		before := net.Clone()
+		output, cache := net.ForwardWithCache(input)
+		net.BackwardLoss(cache, output, 0, output[0]+100, LossOptions{MaxGradNorm: 0.5})
+		if moved := paramDistance(net, before) * math.Sqrt(sumParamSquares(before)); math.Abs(moved-0.1*0.5) > 1e-9 {
+			t.Errorf("dueling=%v: clipped update moved weights by %v, want %v", dueling, moved, 0.05)
+		}
+	}
+}
+
+func sumParamSquares(n *QNetwork) float64 {
+	sum := 0.0
+	for _, p := range n.params() {
+		sum += sumSquares(p)
+	}
+	return sum
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRecurrentNetwork(t *testing.T) {
+	net := NewDuelingQNetwork(22, 8, 6, 3, 0.01, 1)
+	net.makeRecurrent()
+	inputs := make([][]float64, 3)
+	for k := range inputs {
+		inputs[k] = make([]float64, 22)
+		for i := range inputs[k] {
+			inputs[k][i] = float64((i+k)%5) / 4
+		}
+	}
+
+	// Gradients backpropagated through time match finite differences of
+	// the loss summed over the sequence
+	actions, targets := []int{1, 0, 2}, []float64{0.5, -0.2, 0.3}
+	loss := func() float64 {
+		cache, sum := newForwardCache(net), 0.0
+		for k, input := range inputs {
+			d := net.forwardWith(cache, input)[actions[k]] - targets[k]
+			sum += d * d / 2
+		}
+		return sum
+	}
+	caches := make([]*forwardCache, len(inputs))
+	for k, input := range inputs {
+		caches[k] = newForwardCache(net)
+		if k > 0 {
+			copy(caches[k].h2, caches[k-1].h2)
+		}
+		output := net.forwardWith(caches[k], input)
+		caches[k].dOutput[actions[k]] = output[actions[k]] - targets[k]
+	}
+	g := newGradients(net)
+	var dHidden []float64
+	for k := len(caches) - 1; k >= 0; k-- {
+		net.accumulateStep(caches[k], caches[k].dOutput, dHidden, g)
+		dHidden = caches[k].dHPrev
+	}
+
+	params, grads := net.params(), g.params()
+	if len(params) != 9 || len(grads) != len(params) {
+		t.Fatalf("%d params, %d gradients, want 9", len(params), len(grads))
+	}
+	for k, p := range params {
+		for _, i := range []int{0, len(p) / 2, len(p) - 1} {
+			orig := p[i]
+			p[i] = orig + 1e-6
+			up := loss()
+			p[i] = orig - 1e-6
+			down := loss()
+			p[i] = orig
+			if numeric := (up - down) / 2e-6; math.Abs(numeric-grads[k][i]) > 1e-6 {
+				t.Errorf("param %d[%d]: gradient %v, finite difference %v", k, i, grads[k][i], numeric)
+			}
+		}
+	}
+
+	// Forward carries the hidden state until it is reset
+	first := net.Forward(inputs[0])
+	if second := net.Forward(inputs[0]); equalFloats(first, second) {
+		t.Errorf("second step gave the same output %v", first)
+	}
+	net.ResetState()
+	if got := net.Forward(inputs[0]); !equalFloats(got, first) {
+		t.Errorf("after ResetState: Forward = %v, want %v", got, first)
+	}
+	if got := net.ForwardBatch(inputs[:1])[0]; !equalFloats(got, first) {
+		t.Errorf("ForwardBatch = %v, want %v", got, first)
+	}
+
+	// Saved models keep the recurrent layer and replay the same sequence
+	net.ResetState()
+	var want [][]float64
+	for _, input := range inputs {
+		want = append(want, net.Forward(input))
+	}
+	dir := t.TempDir()
+	for _, codec := range []Codec{CodecGob, CodecJSON, CodecFlat} {
+		path := filepath.Join(dir, "model."+string(codec))
+		if err := net.SaveWithOptions(path, SaveOptions{Codec: codec, Precision: Float64}); err != nil {
+			t.Fatalf("%s: save: %v", codec, err)
+		}
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if !loaded.Recurrent || !equalFloats(loaded.U2, net.U2) {
+			t.Errorf("%s: recurrent weights not restored", codec)
+		}
+		for k, input := range inputs {
+			if got := loaded.Forward(input); !equalFloats(got, want[k]) {
+				t.Errorf("%s: step %d: loaded Forward = %v, want %v", codec, k, got, want[k])
+			}
+		}
+	}
+}
+
+func TestConvNetwork(t *testing.T) {
+	shape := ConvShape{Channels: GridPlanes, Height: GridSize, Width: GridSize, Filters: 2}
+	net := NewConvQNetwork(shape, 4, 3, 0.01, 1)
+	net.Encoder = GridEncoderName
+	if err := net.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	input := make([]float64, GridStateSize)
+	for i := range input {
+		input[i] = float64(i%7) / 6
+	}
+
+	// Filter gradients match finite differences
+	action, target := 1, 0.5
+	loss := func() float64 {
+		d := net.forwardWith(newForwardCache(net), input)[action] - target
+		return d * d / 2
+	}
+	cache := newForwardCache(net)
+	output := net.forwardWith(cache, input)
+	cache.dOutput[action] = output[action] - target
+	g := newGradients(net)
+	net.accumulateGradients(cache, cache.dOutput, g)
+	params, grads := net.params(), g.params()
+	for k, p := range params[:2] {
+		for i := range p {
+			orig := p[i]
+			p[i] = orig + 1e-6
+			up := loss()
+			p[i] = orig - 1e-6
+			down := loss()
+			p[i] = orig
+			if numeric := (up - down) / 2e-6; math.Abs(numeric-grads[k][i]) > 1e-6 {
+				t.Errorf("param %d[%d]: gradient %v, finite difference %v", k, i, grads[k][i], numeric)
+			}
+		}
+	}
+	if got := net.ForwardBatch([][]float64{input})[0]; !equalFloats(got, output) {
+		t.Errorf("ForwardBatch = %v, want %v", got, output)
+	}
+
+	// An update lowers the loss
+	before := loss()
+	net.Backward(net.scratch(), net.forwardWith(net.scratch(), input), action, target)
+	if after := loss(); after >= before {
+		t.Errorf("loss %v after update, %v before", after, before)
+	}
+
+	// Saved models keep the convolution
+	want := net.Forward(input)
+	dir := t.TempDir()
+	for _, codec := range []Codec{CodecGob, CodecJSON, CodecFlat} {
+		path := filepath.Join(dir, "model."+string(codec))
+		if err := net.SaveWithOptions(path, SaveOptions{Codec: codec, Precision: Float64}); err != nil {
+			t.Fatalf("%s: save: %v", codec, err)
+		}
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if loaded.Conv != shape {
+			t.Errorf("%s: shape %+v, want %+v", codec, loaded.Conv, shape)
+		}
+		if got := loaded.Forward(input); !equalFloats(got, want) {
+			t.Errorf("%s: loaded Forward = %v, want %v", codec, got, want)
+		}
+	}
+}
+
+func TestRegularization(t *testing.T) {
+	net := NewQNetwork(22, 8, 6, 3, 0.1, 1)
+	input := make([]float64, 22)
+	for i := range input {
+		input[i] = float64(i%5) / 4
+	}
+	want := net.Clone().Forward(input)
+
+	// Dropped units pass nothing forward and get no gradient; the others
+	// are scaled up
+	net.SetDropout(0.5)
+	cache := newForwardCache(net)
+	output := net.forwardWith(cache, input)
+	dropped := 0
+	for i, m := range cache.mask {
+		switch {
+		case m == 0:
+			dropped++
+			if cache.h1[i] != 0 {
+				t.Errorf("dropped unit %d outputs %v", i, cache.h1[i])
+			}
+		case m != 2:
+			t.Errorf("unit %d scaled by %v, want 2", i, m)
+		}
+	}
+	if dropped == 0 || dropped == len(cache.mask) {
+		t.Fatalf("%d of %d units dropped", dropped, len(cache.mask))
+	}
+	cache.dOutput[0] = output[0]
+	g := newGradients(net)
+	net.accumulateGradients(cache, cache.dOutput, g)
+	for i, m := range cache.mask {
+		if m == 0 && g.B1[i] != 0 {
+			t.Errorf("dropped unit %d has bias gradient %v", i, g.B1[i])
+		}
+	}
+
+	// Copies and networks with dropout off use every unit
+	if got := net.Clone().Forward(input); !equalFloats(got, want) {
+		t.Errorf("clone: Forward = %v, want %v", got, want)
+	}
+	net.SetDropout(0)
+	if got := net.Forward(input); !equalFloats(got, want) {
+		t.Errorf("dropout off: Forward = %v, want %v", got, want)
+	}
+
+	// Weight decay shrinks weights by LearningRate·lambda, not biases
+	before := net.Clone()
+	net.B1[0] = 1
+	before.B1[0] = 1
+	net.decayWeights(0.5)
+	for k, w := range net.W2 {
+		if math.Abs(w-0.95*before.W2[k]) > 1e-15 {
+			t.Fatalf("W2[%d] = %v after decay, want %v", k, w, 0.95*before.W2[k])
+		}
+	}
+	if net.B1[0] != 1 {
+		t.Errorf("bias decayed to %v", net.B1[0])
+	}
+}
+
+func TestMiniBatch(t *testing.T) {
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 8, 6
+	cfg.BatchSize = 8
+	newAgent := func(workers int, miniBatch bool) *DQNAgent {
+		cfg.GradWorkers, cfg.MiniBatch = workers, miniBatch
+		agent := NewDQNAgent(cfg, 1)
+		rng := rand.New(rand.NewSource(2))
+		for range 2 * cfg.BatchSize {
+			state, next := make([]float64, cfg.InputSize), make([]float64, cfg.InputSize)
+			for i := range state {
+				state[i], next[i] = rng.Float64(), rng.Float64()
+			}
+			agent.Remember(state, Action(rng.Intn(NumActions)), rng.NormFloat64(), next, rng.Intn(4) == 0)
+		}
+		agent.TrainBatch()
+		return agent
+	}
+
+	// One averaged update on one goroutine matches the parallel one, and
+	// moves the weights less than a step per sample
+	single, parallel, perSample := newAgent(1, true), newAgent(3, false), newAgent(1, false)
+	initial := NewDQNAgent(cfg, 1).PolicyNet
+	for k, p := range single.PolicyNet.params() {
+		for i, w := range p {
+			if math.Abs(w-parallel.PolicyNet.params()[k][i]) > 1e-12 {
+				t.Fatalf("param %d[%d] = %v, parallel update gave %v", k, i, w, parallel.PolicyNet.params()[k][i])
+			}
+		}
+	}
+	distance := func(net *QNetwork) float64 {
+		sum := 0.0
+		for k, p := range net.params() {
+			for i, w := range p {
+				sum += (w - initial.params()[k][i]) * (w - initial.params()[k][i])
+			}
+		}
+		return math.Sqrt(sum)
+	}
+	if moved, perSampleMoved := distance(single.PolicyNet), distance(perSample.PolicyNet); moved == 0 || moved >= perSampleMoved {
+		t.Errorf("mini-batch update moved %v, per-sample updates %v", moved, perSampleMoved)
+	}
+}
+
+func TestInferenceAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector's instrumentation allocates")
+	}
+	conv := NewConvQNetwork(ConvShape{Channels: GridPlanes, Height: GridSize, Width: GridSize, Filters: 2}, 4, 3, 0.01, 1)
+	conv.Encoder = GridEncoderName
+	recurrent := NewQNetwork(22, 8, 6, 3, 0.01, 1)
+	recurrent.makeRecurrent()
+	nets := map[string]*QNetwork{
+		"mlp":       NewQNetwork(22, 8, 6, 3, 0.01, 1),
+		"dueling":   NewDuelingQNetwork(22, 8, 6, 3, 0.01, 1),
+		"conv":      conv,
+		"recurrent": recurrent,
+	}
+	states := benchStates(16)
+
+	// Greedy play reuses the policy's buffers from the first step on
+	for name, net := range nets {
+		policy := NewNetworkPolicy(net, 0, 1)
+		policy.Mask = true
+		policy.Act(states[0], 0)
+		i := 0
+		if allocs := testing.AllocsPerRun(50, func() {
+			i++
+			policy.Act(states[i%len(states)], 0)
+		}); allocs != 0 {
+			t.Errorf("%s: Act allocated %v times per call", name, allocs)
+		}
+	}
+
+	agent := NewDQNAgent(config.DefaultTrainingConfig(), 1)
+	state := EncodeState(states[0], 0)
+	if allocs := testing.AllocsPerRun(50, func() { agent.SelectActionGreedy(state) }); allocs != 0 {
+		t.Errorf("SelectActionGreedy allocated %v times per call", allocs)
+	}
+}
+
+func TestSaveByExtension(t *testing.T) {
+	agent := NewDQNAgent(config.DefaultTrainingConfig(), 1)
+	input := make([]float64, agent.PolicyNet.InputSize)
+	for i := range input {
+		input[i] = float64(i%3) / 2
+	}
+	want := agent.PolicyNet.Forward(input)
+
+	dir := t.TempDir()
+	for name, codec := range map[string]Codec{"model.gob": CodecGob, "model.json": CodecJSON, "model.bin": CodecFlat, "model.npz": CodecNPZ} {
+		path := filepath.Join(dir, name)
+		if err := agent.Save(path); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		info, err := InspectModel(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if info.Codec != codec {
+			t.Errorf("%s: saved as %s, want %s", name, info.Codec, codec)
+		}
+		net, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := net.Forward(input); math.Abs(got[0]-want[0]) > 1e-6 {
+			t.Errorf("%s: loaded network gives %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+)
+
+// NoisySigma0 scales the initial noise of noisy layers: each layer starts
+// with sigma = NoisySigma0 / sqrt(fan-in), as in the NoisyNet paper
+const NoisySigma0 = 0.5
+
+// Noisy networks explore through their weights instead of epsilon-greedy.
+// Every linear layer gets a learned noise scale per weight and bias (Sigma,
+// laid out like params) and, while noise is on, each forward pass draws
+// factorized Gaussian noise: one sample per layer input and output, the
+// weight noise being their outer product after f(x) = sgn(x)·sqrt(|x|).
+// Noise is off unless SetNoise turns it on, so loaded networks play with
+// their mean weights.
+
+// MakeNoisy adds noise scales to every layer of a plain network
+func (n *QNetwork) MakeNoisy(sigma0 float64) {
+	if n.Noisy {
+		return
+	}
+	n.Noisy = true
+	n.Sigma = nil
+	for _, dims := range n.layerDims() {
+		scale := sigma0 / math.Sqrt(float64(dims[0]))
+		for _, size := range []int{dims[0] * dims[1], dims[1]} {
+			sigma := make([]float64, size)
+			for i := range sigma {
+				sigma[i] = scale
+			}
+			n.Sigma = append(n.Sigma, sigma)
+		}
+	}
+	n.cache, n.batch = nil, nil
+}
+
+// SetNoise turns weight noise on (for training) or off (for play). It has
+// no effect on networks without noisy layers.
+func (n *QNetwork) SetNoise(on bool) {
+	n.noise = on && n.Noisy
+	n.cache = nil
+}
+
+// layerDims returns the fan-in and fan-out of every linear layer, in
+// params order
+func (n *QNetwork) layerDims() [][2]int {
+	dims := [][2]int{
+		{n.InputSize, n.HiddenSize1},
+		{n.HiddenSize1, n.HiddenSize2},
+		{n.HiddenSize2, n.OutputSize},
+	}
+	if n.Dueling {
+		dims = append(dims, [2]int{n.HiddenSize2, 1})
+	}
+	return dims
+}
+
+// layerNoise holds one layer's noise sample and the buffers to apply it
+type layerNoise struct {
+	epsIn, epsOut []float64 // f(noise) per input and output
+	in            []float64 // input ∘ epsIn
+	out           []float64 // sigmaW·in + sigmaB
+	dOut          []float64 // dOutput ∘ epsOut
+	dIn           []float64 // sigmaWᵀ·dOut
+}
+
+// newNoiseBuffers allocates noise buffers for every layer of n
+func newNoiseBuffers(n *QNetwork) []layerNoise {
+	var noise []layerNoise
+	for _, dims := range n.layerDims() {
+		in, out := dims[0], dims[1]
+		noise = append(noise, layerNoise{
+			epsIn:  make([]float64, in),
+			epsOut: make([]float64, out),
+			in:     make([]float64, in),
+			out:    make([]float64, out),
+			dOut:   make([]float64, out),
+			dIn:    make([]float64, in),
+		})
+	}
+	return noise
+}
+
+// sample draws fresh factorized noise
+func (ln *layerNoise) sample(rng *rand.Rand) {
+	for _, eps := range [][]float64{ln.epsIn, ln.epsOut} {
+		for i := range eps {
+			x := rng.NormFloat64()
+			eps[i] = math.Copysign(math.Sqrt(math.Abs(x)), x)
+		}
+	}
+}
+
+// linear computes output = W·input + b for layer k, plus freshly sampled
+// weight noise when the forward pass is noisy
+func (n *QNetwork) linear(cache *forwardCache, k int, weights, bias, input, output []float64) {
+	backend.MatVec(weights, bias, input, output)
+	if !cache.noisy {
+		return
+	}
+	ln := &cache.noise[k]
+	ln.sample(cache.rng)
+	for i, x := range input {
+		ln.in[i] = x * ln.epsIn[i]
+	}
+	backend.MatVec(n.Sigma[2*k], n.Sigma[2*k+1], ln.in, ln.out)
+	for j := range output {
+		output[j] += ln.epsOut[j] * ln.out[j]
+	}
+}
+
+// linearBack writes the gradient w.r.t. layer k's input into dInput
+// (skipped when nil) and prepares the layer's noise gradients
+func (n *QNetwork) linearBack(cache *forwardCache, k int, weights, dOutput, dInput []float64) {
+	if dInput != nil {
+		backend.MatTVec(weights, dOutput, dInput)
+	}
+	if !cache.noisy {
+		return
+	}
+	ln := &cache.noise[k]
+	for j, d := range dOutput {
+		ln.dOut[j] = d * ln.epsOut[j]
+	}
+	if dInput != nil {
+		backend.MatTVec(n.Sigma[2*k], ln.dOut, ln.dIn)
+		for i, d := range ln.dIn {
+			dInput[i] += d * ln.epsIn[i]
+		}
+	}
+}
+
+// noisyUpdate applies the SGD update to layer k's noise scales
+func (n *QNetwork) noisyUpdate(cache *forwardCache, k int, lr float64) {
+	if !cache.noisy {
+		return
+	}
+	ln := &cache.noise[k]
+	linearUpdate(ln.in, n.Sigma[2*k], n.Sigma[2*k+1], ln.dOut, lr)
+}
+
+// noisyAccumulate adds layer k's noise scale gradients to g
+func (n *QNetwork) noisyAccumulate(cache *forwardCache, k int, g *gradients) {
+	if !cache.noisy {
+		return
+	}
+	ln := &cache.noise[k]
+	backend.Rank1Update(g.Sigma[2*k], 1, ln.dOut, ln.in)
+	backend.Axpy(1, ln.dOut, g.Sigma[2*k+1])
+}
+
+// noiseGradSquares returns the squared norm of the noise scale gradients
+// prepared by linearBack
+func (n *QNetwork) noiseGradSquares(cache *forwardCache) float64 {
+	if !cache.noisy {
+		return 0
+	}
+	sum := 0.0
+	for _, ln := range cache.noise {
+		sum += sumSquares(ln.dOut) * (sumSquares(ln.in) + 1)
+	}
+	return sum
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package ai
+
+// raceEnabled reports whether the tests run under the race detector, whose
+// instrumentation allocates where a normal build does not
+const raceEnabled = false
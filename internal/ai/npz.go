@@ -0,0 +1,406 @@
+package ai
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"autonomous-snake/internal/config"
+)
+
+// NumPy model layout
+//
+// A .npz archive, as numpy.savez writes it, of one array per parameter
+// with weight matrices stored [out, in] like PyTorch's nn.Linear, so a
+// state_dict exports directly:
+//
+//	W1 [hidden1, inputs]   B1 [hidden1]
+//	W2 [hidden2, hidden1]  B2 [hidden2]
+//	W3 [actions, hidden2]  B3 [actions]
+//	WV [1, hidden2]        BV [1]        value stream, dueling networks only
+//	encoder                string        state encoder, "features" if absent
+//
+// Arrays are little-endian float32 or float64. Hidden layers use ReLU and
+// a dueling network combines its streams as Q = V + A − mean(A), like
+// Forward. Only dense and dueling networks fit the layout.
+
+// CodecNPZ stores the weights as a NumPy .npz archive
+const CodecNPZ Codec = "npz"
+
+// npzExtension makes Save write a NumPy archive
+const npzExtension = ".npz"
+
+// zipMagic starts every zip archive, .npz files included
+const zipMagic = "PK\x03\x04"
+
+// npyMagic starts every .npy array
+const npyMagic = "\x93NUMPY"
+
+// npyArray is a decoded .npy array: numbers, or a string for unicode arrays
+type npyArray struct {
+	shape    []int
+	data     []float64
+	str      string
+	isString bool // The array held a unicode string
+	f32      bool // The numbers were float32
+}
+
+// writeNPZ writes the network as a NumPy archive
+func (n *QNetwork) writeNPZ(w io.Writer, precision Precision) error {
+	if n.Noisy || n.Recurrent || n.convolutional() || n.Atoms > 0 || n.Heads > 0 {
+		return fmt.Errorf("%s networks don't fit the npz layout, only standard and dueling ones", n.Architecture())
+	}
+	if precision != Float64 && precision != Float32 {
+		return fmt.Errorf("unknown precision %q", precision)
+	}
+	arrays := []struct {
+		name  string
+		data  []float64
+		shape []int
+	}{
+		{"W1", n.W1, []int{n.HiddenSize1, n.InputSize}},
+		{"B1", n.B1, []int{n.HiddenSize1}},
+		{"W2", n.W2, []int{n.HiddenSize2, n.HiddenSize1}},
+		{"B2", n.B2, []int{n.HiddenSize2}},
+		{"W3", n.W3, []int{n.OutputSize, n.HiddenSize2}},
+		{"B3", n.B3, []int{n.OutputSize}},
+	}
+	if n.Dueling {
+		arrays = append(arrays, []struct {
+			name  string
+			data  []float64
+			shape []int
+		}{{"WV", n.WV, []int{1, n.HiddenSize2}}, {"BV", n.BV, []int{1}}}...)
+	}
+
+	archive := zip.NewWriter(w)
+	create := func(name string) (io.Writer, error) {
+		// numpy.savez stores its arrays uncompressed
+		return archive.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Store})
+	}
+	for _, a := range arrays {
+		f, err := create(a.name)
+		if err != nil {
+			return err
+		}
+		if err := writeNPY(f, a.data, a.shape, precision == Float32); err != nil {
+			return err
+		}
+	}
+	f, err := create("encoder")
+	if err != nil {
+		return err
+	}
+	if err := writeNPYString(f, encoderOrDefault(n.Encoder)); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// writeNPY writes numbers as a version 1.0 .npy array
+func writeNPY(w io.Writer, data []float64, shape []int, f32 bool) error {
+	descr, size := "<f8", 8
+	if f32 {
+		descr, size = "<f4", 4
+	}
+	raw := make([]byte, size*len(data))
+	for i, v := range data {
+		if f32 {
+			binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(float32(v)))
+		} else {
+			binary.LittleEndian.PutUint64(raw[8*i:], math.Float64bits(v))
+		}
+	}
+	return writeNPYData(w, descr, shape, raw)
+}
+
+// writeNPYString writes s as a 0-dimensional unicode .npy array
+func writeNPYString(w io.Writer, s string) error {
+	runes := []rune(s)
+	raw := make([]byte, 4*len(runes))
+	for i, r := range runes {
+		binary.LittleEndian.PutUint32(raw[4*i:], uint32(r))
+	}
+	return writeNPYData(w, "<U"+strconv.Itoa(len(runes)), nil, raw)
+}
+
+// writeNPYData writes a .npy header describing raw, then raw
+func writeNPYData(w io.Writer, descr string, shape []int, raw []byte) error {
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = strconv.Itoa(d)
+	}
+	tuple := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		tuple += ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, tuple)
+
+	// The header is padded with spaces and a newline so the data starts
+	// 64-byte aligned
+	prefix := len(npyMagic) + 4
+	pad := 63 - (prefix+len(header))%64
+	header += strings.Repeat(" ", pad) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	buf.Write(raw)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// decodeNPZ builds a network from a NumPy archive's contents
+func decodeNPZ(data []byte) (ModelInfo, *QNetwork, error) {
+	info := ModelInfo{Kind: ModelNPZ, Codec: CodecNPZ, Precision: Float64}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return info, nil, err
+	}
+	arrays := make(map[string]npyArray)
+	for _, f := range archive.File {
+		name := strings.TrimSuffix(f.Name, ".npy")
+		r, err := f.Open()
+		if err != nil {
+			return info, nil, err
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return info, nil, err
+		}
+		a, err := decodeNPY(content)
+		if err != nil {
+			return info, nil, fmt.Errorf("array %s: %w", name, err)
+		}
+		arrays[name] = a
+		if a.f32 {
+			info.Precision = Float32
+		}
+	}
+
+	weights, err := npzWeights(arrays)
+	if err != nil {
+		return info, nil, err
+	}
+	net, err := networkFromWeights(weights)
+	return info, net, err
+}
+
+// npzWeights checks the archive's arrays against the layout and returns
+// them as NetworkWeights
+func npzWeights(arrays map[string]npyArray) (NetworkWeights, error) {
+	for name := range arrays {
+		if !slices.Contains([]string{"W1", "B1", "W2", "B2", "W3", "B3", "WV", "BV", "encoder"}, name) {
+			return NetworkWeights{}, fmt.Errorf("unexpected array %q; the archive holds W1, B1, W2, B2, W3, B3, optionally WV and BV, and encoder", name)
+		}
+	}
+	_, dueling := arrays["WV"]
+	if _, ok := arrays["BV"]; ok != dueling {
+		return NetworkWeights{}, errors.New("a dueling network needs both WV and BV")
+	}
+
+	// Sizes come from W1's and W3's shapes, which every other array must
+	// agree with
+	w1, ok1 := arrays["W1"]
+	w3, ok3 := arrays["W3"]
+	if !ok1 || !ok3 {
+		return NetworkWeights{}, errors.New("missing array W1 or W3")
+	}
+	if len(w1.shape) != 2 || len(w3.shape) != 2 {
+		return NetworkWeights{}, fmt.Errorf("W1 and W3 must be matrices [out, in], got shapes %v and %v", w1.shape, w3.shape)
+	}
+	hidden1, inputs := w1.shape[0], w1.shape[1]
+	actions, hidden2 := w3.shape[0], w3.shape[1]
+	want := []struct {
+		name  string
+		shape []int
+	}{
+		{"W1", []int{hidden1, inputs}},
+		{"B1", []int{hidden1}},
+		{"W2", []int{hidden2, hidden1}},
+		{"B2", []int{hidden2}},
+		{"W3", []int{actions, hidden2}},
+		{"B3", []int{actions}},
+	}
+	if dueling {
+		want = append(want, []struct {
+			name  string
+			shape []int
+		}{{"WV", []int{1, hidden2}}, {"BV", []int{1}}}...)
+	}
+	for _, w := range want {
+		a, ok := arrays[w.name]
+		switch {
+		case !ok:
+			return NetworkWeights{}, fmt.Errorf("missing array %s", w.name)
+		case a.isString:
+			return NetworkWeights{}, fmt.Errorf("%s holds a string, not numbers", w.name)
+		case !slices.Equal(a.shape, w.shape):
+			return NetworkWeights{}, fmt.Errorf("%s has shape %v, want %v for %d inputs, hidden layers of %d and %d and %d actions",
+				w.name, a.shape, w.shape, inputs, hidden1, hidden2, actions)
+		}
+	}
+	if actions != NumActions {
+		return NetworkWeights{}, fmt.Errorf("W3 has %d outputs, want one per action (%d)", actions, NumActions)
+	}
+
+	weights := NetworkWeights{
+		W1:           unflatten(arrays["W1"].data, inputs, hidden1),
+		B1:           arrays["B1"].data,
+		W2:           unflatten(arrays["W2"].data, hidden1, hidden2),
+		B2:           arrays["B2"].data,
+		W3:           unflatten(arrays["W3"].data, hidden2, actions),
+		B3:           arrays["B3"].data,
+		InputSize:    inputs,
+		HiddenSize1:  hidden1,
+		HiddenSize2:  hidden2,
+		OutputSize:   actions,
+		LearningRate: config.DefaultTrainingConfig().LearningRate,
+	}
+	if enc, ok := arrays["encoder"]; ok {
+		if !enc.isString {
+			return NetworkWeights{}, errors.New("encoder must be a string array")
+		}
+		weights.Encoder = enc.str
+	}
+	if dueling {
+		weights.Dueling = true
+		weights.WV = unflatten(arrays["WV"].data, hidden2, 1)
+		weights.BV = arrays["BV"].data
+	}
+	return weights, nil
+}
+
+// decodeNPY decodes a .npy array of little-endian floats or a unicode
+// string
+func decodeNPY(data []byte) (npyArray, error) {
+	if !bytes.HasPrefix(data, []byte(npyMagic)) || len(data) < 10 {
+		return npyArray{}, errors.New("not a .npy array")
+	}
+	var headerLen, start int
+	switch data[6] {
+	case 1:
+		headerLen, start = int(binary.LittleEndian.Uint16(data[8:10])), 10
+	case 2, 3:
+		if len(data) < 12 {
+			return npyArray{}, errors.New("truncated .npy header")
+		}
+		headerLen, start = int(binary.LittleEndian.Uint32(data[8:12])), 12
+	default:
+		return npyArray{}, fmt.Errorf(".npy version %d is not supported", data[6])
+	}
+	if len(data) < start+headerLen {
+		return npyArray{}, errors.New("truncated .npy header")
+	}
+	header := string(data[start : start+headerLen])
+	raw := data[start+headerLen:]
+
+	descr, err := npyHeaderField(header, "descr")
+	if err != nil {
+		return npyArray{}, err
+	}
+	descr = strings.Trim(descr, "'\"")
+	order, err := npyHeaderField(header, "fortran_order")
+	if err != nil {
+		return npyArray{}, err
+	}
+	if order != "False" {
+		return npyArray{}, errors.New("Fortran-ordered arrays are not supported; save a C-contiguous copy")
+	}
+	shapeField, err := npyHeaderField(header, "shape")
+	if err != nil {
+		return npyArray{}, err
+	}
+	a := npyArray{}
+	count := 1
+	for _, dim := range strings.Split(strings.Trim(shapeField, "()"), ",") {
+		if dim = strings.TrimSpace(dim); dim == "" {
+			continue
+		}
+		d, err := strconv.Atoi(dim)
+		if err != nil || d < 0 {
+			return npyArray{}, fmt.Errorf("invalid shape %s", shapeField)
+		}
+		a.shape = append(a.shape, d)
+		count *= d
+	}
+
+	if width, ok := strings.CutPrefix(descr, "<U"); ok {
+		chars, err := strconv.Atoi(width)
+		if err != nil || count != 1 || len(raw) < 4*chars {
+			return npyArray{}, fmt.Errorf("unsupported string array %s of shape %s", descr, shapeField)
+		}
+		var sb strings.Builder
+		for i := range chars {
+			r := rune(binary.LittleEndian.Uint32(raw[4*i:]))
+			if r == 0 {
+				break
+			}
+			if !utf8.ValidRune(r) {
+				return npyArray{}, errors.New("invalid character in string array")
+			}
+			sb.WriteRune(r)
+		}
+		a.str, a.isString = sb.String(), true
+		return a, nil
+	}
+
+	var size int
+	switch descr {
+	case "<f4":
+		size, a.f32 = 4, true
+	case "<f8":
+		size = 8
+	default:
+		return npyArray{}, fmt.Errorf("dtype %s is not supported, save float32 or float64", descr)
+	}
+	if len(raw) < size*count {
+		return npyArray{}, fmt.Errorf("truncated data: %d bytes for %d values", len(raw), count)
+	}
+	a.data = make([]float64, count)
+	for i := range a.data {
+		if a.f32 {
+			a.data[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:])))
+		} else {
+			a.data[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[8*i:]))
+		}
+		if math.IsNaN(a.data[i]) || math.IsInf(a.data[i], 0) {
+			return npyArray{}, fmt.Errorf("value %d is %v", i, a.data[i])
+		}
+	}
+	return a, nil
+}
+
+// npyHeaderField returns the raw value of key in a .npy header, a Python
+// dict literal like {'descr': '<f4', 'fortran_order': False, 'shape': (3,), }
+func npyHeaderField(header, key string) (string, error) {
+	i := strings.Index(header, "'"+key+"'")
+	if i < 0 {
+		return "", fmt.Errorf(".npy header has no %s", key)
+	}
+	rest := strings.TrimSpace(header[i+len(key)+2:])
+	rest, ok := strings.CutPrefix(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("malformed .npy header %q", header)
+	}
+	rest = strings.TrimSpace(rest)
+	end := strings.IndexByte(rest, ',')
+	if strings.HasPrefix(rest, "(") {
+		end = strings.IndexByte(rest, ')') + 1
+	}
+	if end <= 0 {
+		return "", fmt.Errorf("malformed .npy header %q", header)
+	}
+	return strings.TrimSpace(rest[:end]), nil
+}
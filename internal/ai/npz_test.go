@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNPZ(t *testing.T) {
+	dir := t.TempDir()
+	input := make([]float64, 22)
+	for i := range input {
+		input[i] = float64(i%4) / 3
+	}
+
+	// Round trips keep the Q-values, to float32 rounding with -precision
+	// float32
+	for _, net := range []*QNetwork{NewQNetwork(22, 8, 6, 3, 0.01, 1), NewDuelingQNetwork(22, 8, 6, 3, 0.01, 2)} {
+		net.B2[1] = 0.25
+		for _, precision := range []Precision{Float64, Float32} {
+			path := filepath.Join(dir, "model.npz")
+			if err := net.SaveWithOptions(path, SaveOptions{Codec: CodecNPZ, Precision: precision}); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadNetwork(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info, err := InspectModel(path); err != nil || info.Kind != ModelNPZ || info.Precision != precision {
+				t.Errorf("InspectModel = %+v, %v", info, err)
+			}
+			tolerance := 0.0
+			if precision == Float32 {
+				tolerance = 1e-5
+			}
+			want, got := net.Forward(input), loaded.Forward(input)
+			for j := range want {
+				if d := got[j] - want[j]; d > tolerance || d < -tolerance || loaded.Dueling != net.Dueling {
+					t.Errorf("%s %s: Q %v after loading, want %v", net.Architecture(), precision, got, want)
+					break
+				}
+			}
+		}
+	}
+	if err := NewRecurrentQNetwork(22, 8, 6, 3, 0.01, 1).Save(filepath.Join(dir, "gru.npz")); err == nil {
+		t.Error("saved a recurrent network as npz")
+	}
+
+	// Archives that don't follow the layout are rejected with the reason
+	net := NewQNetwork(22, 8, 6, 3, 0.01, 1)
+	for _, tc := range []struct {
+		name   string
+		arrays map[string][]int // Array shapes
+		want   string
+	}{
+		{"shape", map[string][]int{"W1": {8, 22}, "B1": {8}, "W2": {6, 7}, "B2": {6}, "W3": {3, 6}, "B3": {3}}, "W2 has shape [6 7], want [6 8]"},
+		{"missing", map[string][]int{"W1": {8, 22}, "B1": {8}, "W2": {6, 8}, "W3": {3, 6}, "B3": {3}}, "missing array B2"},
+		{"pytorch", map[string][]int{"fc1.weight": {8, 22}}, `unexpected array "fc1.weight"`},
+		{"actions", map[string][]int{"W1": {8, 22}, "B1": {8}, "W2": {6, 8}, "B2": {6}, "W3": {4, 6}, "B3": {4}}, "W3 has 4 outputs"},
+	} {
+		path := filepath.Join(dir, tc.name+".npz")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive := zip.NewWriter(file)
+		for name, shape := range tc.arrays {
+			size := 1
+			for _, d := range shape {
+				size *= d
+			}
+			f, _ := archive.Create(name + ".npy")
+			if err := writeNPY(f, net.W1[:size], shape, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		archive.Close()
+		file.Close()
+		if _, err := LoadNetwork(path); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: LoadNetwork error %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package ai
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ONNX export
+//
+// ExportONNX writes a network as an ONNX model (IR version 7, opset 13) so
+// trained snakes can run outside Go, e.g. with onnxruntime in Python or the
+// browser. The graph computes what Forward does, in float32:
+//
+//	input  "state"    float32 [batch, InputSize], the encoded state
+//	output "q_values" float32 [batch, actions], in Action order
+//
+// The model's metadata holds the encoder the input must come from
+// ("encoder") and the action names ("actions"). Noisy networks export
+// their mean weights, as they play outside training. Recurrent networks
+// can't be exported: their hidden state carries over between steps.
+//
+// The file is hand-encoded protobuf; only the fields the graph needs are
+// written.
+
+// ONNX graph input and output names
+const (
+	ONNXInput  = "state"
+	ONNXOutput = "q_values"
+)
+
+// ONNX versions the export targets
+const (
+	onnxIRVersion = 7
+	onnxOpset     = 13
+)
+
+// ONNX tensor element types
+const (
+	onnxFloat = 1
+	onnxInt64 = 7
+)
+
+// ExportONNX writes the network as an ONNX model to w
+func (n *QNetwork) ExportONNX(w io.Writer) error {
+	if n.Recurrent {
+		return errors.New("recurrent networks can't be exported to ONNX")
+	}
+	if err := n.Validate(); err != nil {
+		return err
+	}
+
+	var names []string
+	for a := Action(0); a < Action(n.Actions()); a++ {
+		names = append(names, a.String())
+	}
+	var model protoWriter
+	model.varintField(1, onnxIRVersion)
+	model.stringField(2, "slither")
+	model.bytesField(7, n.onnxGraph())
+	var opset protoWriter
+	opset.varintField(2, onnxOpset) // The default domain
+	model.bytesField(8, opset)
+	for _, kv := range [][2]string{{"encoder", encoderOrDefault(n.Encoder)}, {"actions", strings.Join(names, ",")}} {
+		var prop protoWriter
+		prop.stringField(1, kv[0])
+		prop.stringField(2, kv[1])
+		model.bytesField(14, prop)
+	}
+	_, err := w.Write(model)
+	return err
+}
+
+// SaveONNX writes the network as an ONNX model file, see ExportONNX
+func (n *QNetwork) SaveONNX(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := n.ExportONNX(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// onnxGraph encodes the GraphProto computing the network's Q-values
+func (n *QNetwork) onnxGraph() []byte {
+	g := &onnxBuilder{}
+	x := ONNXInput
+
+	// Layer 1
+	if n.convolutional() {
+		s := n.Conv
+		planes := g.node("Reshape", x, g.ints("planes_shape", -1, int64(s.Channels), int64(s.Height), int64(s.Width)))
+		conv := g.node("Conv", planes, g.floats("W1", n.W1, s.Filters, s.Channels, convKernel, convKernel), g.floats("B1", n.B1, s.Filters))
+		g.attr(onnxAttr{name: "kernel_shape", ints: []int64{convKernel, convKernel}})
+		g.attr(onnxAttr{name: "pads", ints: []int64{1, 1, 1, 1}})
+		x = g.node("Flatten", g.node("Relu", conv))
+		g.attr(onnxAttr{name: "axis", i: 1, scalar: true})
+	} else {
+		x = g.node("Relu", g.dense("W1", "B1", x, n.W1, n.B1, n.HiddenSize1, n.InputSize))
+	}
+
+	// Layer 2
+	x = g.node("Relu", g.dense("W2", "B2", x, n.W2, n.B2, n.HiddenSize2, n.HiddenSize1))
+
+	// Layer 3
+	out := g.dense("W3", "B3", x, n.W3, n.B3, n.OutputSize, n.HiddenSize2)
+	if n.Dueling {
+		value := g.dense("WV", "BV", x, n.WV, n.BV, 1, n.HiddenSize2)
+		mean := g.node("ReduceMean", out)
+		g.attr(onnxAttr{name: "axes", ints: []int64{1}})
+		out = g.node("Add", g.node("Sub", out, mean), value)
+	}
+	actions := int64(n.Actions())
+	switch {
+	case n.Atoms > 0:
+		// Expected returns of each action's softmax over the atoms
+		support := make([]float64, n.Atoms)
+		for i := range support {
+			support[i] = n.VMin + float64(i)*n.atomSpacing()
+		}
+		logits := g.node("Reshape", out, g.ints("atoms_shape", -1, actions, int64(n.Atoms)))
+		probs := g.node("Softmax", logits)
+		g.attr(onnxAttr{name: "axis", i: 2, scalar: true})
+		g.node("MatMul", probs, g.floats("support", support, n.Atoms))
+	case n.Heads > 0:
+		heads := g.node("Reshape", out, g.ints("heads_shape", -1, int64(n.Heads), actions))
+		g.node("ReduceMean", heads)
+		g.attr(onnxAttr{name: "axes", ints: []int64{1}})
+		g.attr(onnxAttr{name: "keepdims", i: 0, scalar: true})
+	}
+	g.nodes[len(g.nodes)-1].output = ONNXOutput
+
+	var graph protoWriter
+	for _, node := range g.nodes {
+		graph.bytesField(1, node.encode())
+	}
+	graph.stringField(2, "qnetwork")
+	for _, t := range g.initializers {
+		graph.bytesField(5, t)
+	}
+	graph.bytesField(11, onnxValueInfo(ONNXInput, int64(n.InputSize)))
+	graph.bytesField(12, onnxValueInfo(ONNXOutput, actions))
+	return graph
+}
+
+// onnxBuilder collects the nodes and weights of a graph
+type onnxBuilder struct {
+	nodes        []onnxNode
+	initializers [][]byte
+}
+
+// onnxNode is one operator of the graph
+type onnxNode struct {
+	op     string
+	inputs []string
+	output string
+	attrs  []onnxAttr
+}
+
+// onnxAttr is an integer or integer list attribute of a node
+type onnxAttr struct {
+	name   string
+	i      int64
+	ints   []int64
+	scalar bool // i is set rather than ints
+}
+
+// node adds an operator and returns the name of its output
+func (g *onnxBuilder) node(op string, inputs ...string) string {
+	output := strings.ToLower(op) + "_" + strconv.Itoa(len(g.nodes))
+	g.nodes = append(g.nodes, onnxNode{op: op, inputs: inputs, output: output})
+	return output
+}
+
+// attr adds an attribute to the last node
+func (g *onnxBuilder) attr(a onnxAttr) {
+	last := &g.nodes[len(g.nodes)-1]
+	last.attrs = append(last.attrs, a)
+}
+
+// dense adds a fully connected layer computing W·x + b, returning its
+// output. Weights are flat [out][in] like the network's, which is Gemm's B
+// transposed.
+func (g *onnxBuilder) dense(wName, bName, x string, w, b []float64, out, in int) string {
+	y := g.node("Gemm", x, g.floats(wName, w, out, in), g.floats(bName, b, out))
+	g.attr(onnxAttr{name: "transB", i: 1, scalar: true})
+	return y
+}
+
+// floats adds a float32 initializer of the given shape and returns its name
+func (g *onnxBuilder) floats(name string, values []float64, dims ...int) string {
+	var t protoWriter
+	for _, d := range dims {
+		t.varintField(1, uint64(d))
+	}
+	t.varintField(2, onnxFloat)
+	t.stringField(8, name)
+	raw := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(float32(v)))
+	}
+	t.bytesField(9, raw)
+	g.initializers = append(g.initializers, t)
+	return name
+}
+
+// ints adds an int64 vector initializer and returns its name
+func (g *onnxBuilder) ints(name string, values ...int64) string {
+	var t protoWriter
+	t.varintField(1, uint64(len(values)))
+	t.varintField(2, onnxInt64)
+	t.stringField(8, name)
+	raw := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(raw[8*i:], uint64(v))
+	}
+	t.bytesField(9, raw)
+	g.initializers = append(g.initializers, t)
+	return name
+}
+
+// encode returns the node as a NodeProto
+func (node onnxNode) encode() []byte {
+	var p protoWriter
+	for _, in := range node.inputs {
+		p.stringField(1, in)
+	}
+	p.stringField(2, node.output)
+	p.stringField(3, node.output)
+	p.stringField(4, node.op)
+	for _, a := range node.attrs {
+		var attr protoWriter
+		attr.stringField(1, a.name)
+		if a.scalar {
+			attr.varintField(3, uint64(a.i))
+			attr.varintField(20, 2) // INT
+		} else {
+			for _, v := range a.ints {
+				attr.varintField(8, uint64(v))
+			}
+			attr.varintField(20, 7) // INTS
+		}
+		p.bytesField(5, attr)
+	}
+	return p
+}
+
+// onnxValueInfo returns a ValueInfoProto for a float32 [batch, size] tensor
+func onnxValueInfo(name string, size int64) []byte {
+	var batch, width, shape, tensor, typ, info protoWriter
+	batch.stringField(2, "batch")
+	width.varintField(1, uint64(size))
+	shape.bytesField(1, batch)
+	shape.bytesField(1, width)
+	tensor.varintField(1, onnxFloat)
+	tensor.bytesField(2, shape)
+	typ.bytesField(1, tensor)
+	info.stringField(1, name)
+	info.bytesField(2, typ)
+	return info
+}
+
+// protoWriter appends protobuf wire format fields
+type protoWriter []byte
+
+// varint appends v as a base 128 varint
+func (p *protoWriter) varint(v uint64) {
+	*p = binary.AppendUvarint(*p, v)
+}
+
+// varintField appends a varint field
+func (p *protoWriter) varintField(field int, v uint64) {
+	p.varint(uint64(field)<<3 | 0)
+	p.varint(v)
+}
+
+// bytesField appends a length-delimited field
+func (p *protoWriter) bytesField(field int, b []byte) {
+	p.varint(uint64(field)<<3 | 2)
+	p.varint(uint64(len(b)))
+	*p = append(*p, b...)
+}
+
+// stringField appends a string field
+func (p *protoWriter) stringField(field int, s string) {
+	p.bytesField(field, []byte(s))
+}
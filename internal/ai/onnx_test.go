@@ -0,0 +1,346 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestExportONNX(t *testing.T) {
+	conv := NewConvQNetwork(ConvShape{Channels: GridPlanes, Height: GridSize, Width: GridSize, Filters: 2}, 6, 3, 0.01, 1)
+	conv.Encoder = GridEncoderName
+	noisy := NewQNetwork(22, 8, 6, 3, 0.01, 1)
+	noisy.MakeNoisy(0.5)
+	nets := map[string]*QNetwork{
+		"mlp":            NewQNetwork(22, 8, 6, 3, 0.01, 1),
+		"dueling":        NewDuelingQNetwork(22, 8, 6, 3, 0.01, 1),
+		"noisy":          noisy,
+		"conv":           conv,
+		"distributional": NewDistributionalQNetwork(22, 8, 6, 3, 5, -2, 2, 0.01, 1),
+		"bootstrap":      NewBootstrapQNetwork(22, 8, 6, 3, 4, 0.01, 1),
+	}
+	rng := rand.New(rand.NewSource(1))
+	for name, net := range nets {
+		// Biases start at zero, which would hide a misplaced one
+		for _, b := range [][]float64{net.B1, net.B2, net.B3, net.BV} {
+			for i := range b {
+				b[i] = rng.NormFloat64() * 0.1
+			}
+		}
+		var buf bytes.Buffer
+		if err := net.ExportONNX(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		model := parseONNX(t, buf.Bytes())
+		if model.meta["encoder"] != encoderOrDefault(net.Encoder) || model.meta["actions"] != "straight,left,right" {
+			t.Errorf("%s: metadata %v", name, model.meta)
+		}
+
+		// Run a batch of two through the graph
+		const batch = 2
+		inputs := make([]float64, batch*net.InputSize)
+		for i := range inputs {
+			inputs[i] = rng.Float64()
+		}
+		got := model.run(t, onnxTensor{shape: []int{batch, net.InputSize}, data: inputs})
+		if !slices.Equal(got.shape, []int{batch, net.Actions()}) {
+			t.Fatalf("%s: output shape %v", name, got.shape)
+		}
+		for k := range batch {
+			want := net.Forward(inputs[k*net.InputSize : (k+1)*net.InputSize])
+			for j, q := range want {
+				if d := got.data[k*len(want)+j] - q; math.Abs(d) > 1e-4*(1+math.Abs(q)) {
+					t.Errorf("%s: input %d, action %d: ONNX gives %v, Forward %v", name, k, j, got.data[k*len(want)+j], q)
+				}
+			}
+		}
+	}
+
+	if err := NewRecurrentQNetwork(22, 8, 6, 3, 0.01, 1).ExportONNX(&bytes.Buffer{}); err == nil {
+		t.Error("exported a recurrent network")
+	}
+}
+
+// onnxTensor is a dense float tensor
+type onnxTensor struct {
+	shape []int
+	data  []float64
+}
+
+// onnxModel is the part of a decoded ONNX model the test runs
+type onnxModel struct {
+	nodes   []decodedNode
+	values  map[string]onnxTensor // Initializers
+	outputs []string
+	meta    map[string]string
+}
+
+type decodedNode struct {
+	op      string
+	inputs  []string
+	outputs []string
+	attrs   map[string][]int64
+}
+
+// protoFields splits protobuf wire format into its fields: varints in v,
+// length-delimited fields in b
+type protoField struct {
+	num int
+	v   uint64
+	b   []byte
+}
+
+func protoFields(t *testing.T, data []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.v, n = binary.Uvarint(data)
+			data = data[n:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			f.b, data = data[n:n+int(size)], data[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type in field %d", f.num)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func parseONNX(t *testing.T, data []byte) *onnxModel {
+	m := &onnxModel{values: map[string]onnxTensor{}, meta: map[string]string{}}
+	for _, f := range protoFields(t, data) {
+		switch f.num {
+		case 1:
+			if f.v != onnxIRVersion {
+				t.Errorf("IR version %d", f.v)
+			}
+		case 7:
+			m.parseGraph(t, f.b)
+		case 14:
+			kv := protoFields(t, f.b)
+			m.meta[string(kv[0].b)] = string(kv[1].b)
+		}
+	}
+	return m
+}
+
+func (m *onnxModel) parseGraph(t *testing.T, data []byte) {
+	for _, f := range protoFields(t, data) {
+		switch f.num {
+		case 1:
+			node := decodedNode{attrs: map[string][]int64{}}
+			for _, nf := range protoFields(t, f.b) {
+				switch nf.num {
+				case 1:
+					node.inputs = append(node.inputs, string(nf.b))
+				case 2:
+					node.outputs = append(node.outputs, string(nf.b))
+				case 4:
+					node.op = string(nf.b)
+				case 5:
+					var name string
+					var ints []int64
+					for _, af := range protoFields(t, nf.b) {
+						switch af.num {
+						case 1:
+							name = string(af.b)
+						case 3, 8:
+							ints = append(ints, int64(af.v))
+						}
+					}
+					node.attrs[name] = ints
+				}
+			}
+			m.nodes = append(m.nodes, node)
+		case 5:
+			var name string
+			var tensor onnxTensor
+			var elemType uint64
+			var raw []byte
+			for _, tf := range protoFields(t, f.b) {
+				switch tf.num {
+				case 1:
+					tensor.shape = append(tensor.shape, int(int64(tf.v)))
+				case 2:
+					elemType = tf.v
+				case 8:
+					name = string(tf.b)
+				case 9:
+					raw = tf.b
+				}
+			}
+			if elemType == onnxInt64 {
+				for i := 0; i < len(raw); i += 8 {
+					tensor.data = append(tensor.data, float64(int64(binary.LittleEndian.Uint64(raw[i:]))))
+				}
+			} else {
+				for i := 0; i < len(raw); i += 4 {
+					tensor.data = append(tensor.data, float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i:]))))
+				}
+			}
+			m.values[name] = tensor
+		case 12:
+			m.outputs = append(m.outputs, string(protoFields(t, f.b)[0].b))
+		}
+	}
+}
+
+// run evaluates the graph on input with the ops the export uses
+func (m *onnxModel) run(t *testing.T, input onnxTensor) onnxTensor {
+	t.Helper()
+	values := map[string]onnxTensor{ONNXInput: input}
+	for name, v := range m.values {
+		values[name] = v
+	}
+	for _, node := range m.nodes {
+		in := make([]onnxTensor, len(node.inputs))
+		for i, name := range node.inputs {
+			v, ok := values[name]
+			if !ok {
+				t.Fatalf("%s reads undefined %q", node.op, name)
+			}
+			in[i] = v
+		}
+		values[node.outputs[0]] = evalONNXNode(t, node, in)
+	}
+	if len(m.outputs) != 1 || m.outputs[0] != ONNXOutput {
+		t.Fatalf("graph outputs %v", m.outputs)
+	}
+	return values[ONNXOutput]
+}
+
+func evalONNXNode(t *testing.T, node decodedNode, in []onnxTensor) onnxTensor {
+	x := in[0]
+	size := func(shape []int) int {
+		n := 1
+		for _, d := range shape {
+			n *= d
+		}
+		return n
+	}
+	switch node.op {
+	case "Gemm":
+		w, b := in[1], in[2]
+		if !slices.Equal(node.attrs["transB"], []int64{1}) {
+			t.Fatalf("Gemm without transB")
+		}
+		rows, outs, k := x.shape[0], w.shape[0], w.shape[1]
+		y := onnxTensor{shape: []int{rows, outs}, data: make([]float64, rows*outs)}
+		for r := range rows {
+			for j := range outs {
+				y.data[r*outs+j] = b.data[j] + dot(x.data[r*k:(r+1)*k], w.data[j*k:(j+1)*k])
+			}
+		}
+		return y
+	case "Relu":
+		y := onnxTensor{shape: x.shape, data: make([]float64, len(x.data))}
+		reluInto(y.data, x.data)
+		return y
+	case "Reshape":
+		shape := make([]int, len(in[1].data))
+		known := 1
+		for i, d := range in[1].data {
+			shape[i] = int(d)
+			if d > 0 {
+				known *= int(d)
+			}
+		}
+		if i := slices.Index(shape, -1); i >= 0 {
+			shape[i] = len(x.data) / known
+		}
+		return onnxTensor{shape: shape, data: x.data}
+	case "Flatten":
+		return onnxTensor{shape: []int{x.shape[0], len(x.data) / x.shape[0]}, data: x.data}
+	case "Conv":
+		w, b := in[1], in[2]
+		batch, channels, height, width, filters := x.shape[0], x.shape[1], x.shape[2], x.shape[3], w.shape[0]
+		pad := int(node.attrs["pads"][0])
+		y := onnxTensor{shape: []int{batch, filters, height, width}, data: make([]float64, batch*filters*height*width)}
+		for n := range batch {
+			for f := range filters {
+				for r := range height {
+					for c := range width {
+						sum := b.data[f]
+						for ch := range channels {
+							for ky := range 3 {
+								for kx := range 3 {
+									yy, xx := r+ky-pad, c+kx-pad
+									if yy < 0 || yy >= height || xx < 0 || xx >= width {
+										continue
+									}
+									sum += w.data[((f*channels+ch)*3+ky)*3+kx] * x.data[((n*channels+ch)*height+yy)*width+xx]
+								}
+							}
+						}
+						y.data[((n*filters+f)*height+r)*width+c] = sum
+					}
+				}
+			}
+		}
+		return y
+	case "ReduceMean":
+		if !slices.Equal(node.attrs["axes"], []int64{1}) {
+			t.Fatalf("ReduceMean over axes %v", node.attrs["axes"])
+		}
+		outer, mid := x.shape[0], x.shape[1]
+		inner := len(x.data) / (outer * mid)
+		shape := []int{outer, 1}
+		shape = append(shape, x.shape[2:]...)
+		if keep, ok := node.attrs["keepdims"]; ok && keep[0] == 0 {
+			shape = slices.Delete(shape, 1, 2)
+		}
+		y := onnxTensor{shape: shape, data: make([]float64, outer*inner)}
+		for o := range outer {
+			for m := range mid {
+				for i := range inner {
+					y.data[o*inner+i] += x.data[(o*mid+m)*inner+i] / float64(mid)
+				}
+			}
+		}
+		return y
+	case "Add", "Sub":
+		// b is x's shape or [rows, 1]
+		b := in[1]
+		per := len(x.data) / len(b.data)
+		y := onnxTensor{shape: x.shape, data: make([]float64, len(x.data))}
+		for i, v := range x.data {
+			if node.op == "Add" {
+				y.data[i] = v + b.data[i/per]
+			} else {
+				y.data[i] = v - b.data[i/per]
+			}
+		}
+		return y
+	case "Softmax":
+		if axis := node.attrs["axis"]; int(axis[0]) != len(x.shape)-1 {
+			t.Fatalf("Softmax over axis %d of %v", axis[0], x.shape)
+		}
+		last := x.shape[len(x.shape)-1]
+		y := onnxTensor{shape: x.shape, data: make([]float64, len(x.data))}
+		for i := 0; i < len(x.data); i += last {
+			softmaxInto(y.data[i:i+last], x.data[i:i+last])
+		}
+		return y
+	case "MatMul":
+		k := in[1].shape[0]
+		y := onnxTensor{shape: x.shape[:len(x.shape)-1], data: make([]float64, len(x.data)/k)}
+		for i := range y.data {
+			y.data[i] = dot(x.data[i*k:(i+1)*k], in[1].data)
+		}
+		if size(y.shape) != len(y.data) {
+			t.Fatalf("MatMul: bad shapes %v, %v", x.shape, in[1].shape)
+		}
+		return y
+	}
+	t.Fatalf("unexpected op %s", node.op)
+	return onnxTensor{}
+}
@@ -0,0 +1,189 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"autonomous-snake/internal/game"
+)
+
+// BookMinPlays is how often a position must have been seen before the
+// learned part of an opening book is trusted
+const BookMinPlays = 5
+
+// OpeningBook holds fixed opening sequences and learned opening moves. A
+// fixed sequence forces a snake's first moves, for standardized evaluation
+// starts; learned positions map a state hash to move statistics gathered
+// from self-play and are used for the first Turns turns.
+type OpeningBook struct {
+	Sequences [][]string            `json:"sequences,omitempty"` // Per-snake move names (one entry covers both), see ParseAction
+	Turns     int                   `json:"turns,omitempty"`     // Turns covered by learned positions
+	Positions map[string]*BookEntry `json:"positions,omitempty"` // Keyed by state hash and snake ID
+
+	sequences [2][]Action
+}
+
+// BookEntry records how each action fared from one position
+type BookEntry struct {
+	Plays [NumActions]int     `json:"plays"`
+	Score [NumActions]float64 `json:"score"` // 1 per win, 0.5 per tie
+}
+
+// BookMove is one move played in the opening, recorded for learning
+type BookMove struct {
+	Key    string
+	Snake  int
+	Action Action
+}
+
+// NewOpeningBook creates an empty book that learns the first turns moves
+func NewOpeningBook(turns int) *OpeningBook {
+	return &OpeningBook{Turns: turns, Positions: make(map[string]*BookEntry)}
+}
+
+// ParseOpening builds a book with the same fixed sequence for both snakes
+// from a comma-separated list of moves such as "s,s,l,r"
+func ParseOpening(moves string) (*OpeningBook, error) {
+	var seq []string
+	for _, m := range strings.Split(moves, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			seq = append(seq, m)
+		}
+	}
+	book := &OpeningBook{Sequences: [][]string{seq}}
+	if err := book.compile(); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// LoadOpeningBook reads a JSON opening book
+func LoadOpeningBook(path string) (*OpeningBook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var book OpeningBook
+	if err := json.Unmarshal(data, &book); err != nil {
+		return nil, err
+	}
+	if book.Positions == nil {
+		book.Positions = make(map[string]*BookEntry)
+	}
+	if err := book.compile(); err != nil {
+		return nil, fmt.Errorf("invalid opening book %s: %w", path, err)
+	}
+	return &book, nil
+}
+
+// Save writes the book as JSON
+func (b *OpeningBook) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// compile parses the sequence move names
+func (b *OpeningBook) compile() error {
+	if len(b.Sequences) > 2 {
+		return fmt.Errorf("%d opening sequences given, at most 2 allowed", len(b.Sequences))
+	}
+	for i, seq := range b.Sequences {
+		b.sequences[i] = make([]Action, len(seq))
+		for t, name := range seq {
+			action, err := ParseAction(name)
+			if err != nil {
+				return fmt.Errorf("sequence %d move %d: %w", i+1, t+1, err)
+			}
+			b.sequences[i][t] = action
+		}
+	}
+	if len(b.Sequences) == 1 {
+		b.sequences[1] = b.sequences[0]
+	}
+	return nil
+}
+
+// bookKey identifies a position from one snake's point of view
+func bookKey(state *game.GameState, snakeID int) string {
+	return fmt.Sprintf("%016x:%d", state.Hash(), snakeID)
+}
+
+// Lookup returns the book move for snakeID in state, if there is one
+func (b *OpeningBook) Lookup(state *game.GameState, snakeID int) (Action, bool) {
+	if seq := b.sequences[snakeID]; state.Turn < len(seq) {
+		return seq[state.Turn], true
+	}
+	if state.Turn >= b.Turns {
+		return GoStraight, false
+	}
+	entry := b.Positions[bookKey(state, snakeID)]
+	if entry == nil {
+		return GoStraight, false
+	}
+
+	total := 0
+	best, bestRate := GoStraight, -1.0
+	for a := Action(0); a < NumActions; a++ {
+		plays := entry.Plays[a]
+		total += plays
+		if plays > 0 && entry.Score[a]/float64(plays) > bestRate {
+			best, bestRate = a, entry.Score[a]/float64(plays)
+		}
+	}
+	return best, total >= BookMinPlays
+}
+
+// Observe returns the move to record if state is still within the learned
+// opening
+func (b *OpeningBook) Observe(state *game.GameState, snakeID int, action Action) (BookMove, bool) {
+	if state.Turn >= b.Turns {
+		return BookMove{}, false
+	}
+	return BookMove{Key: bookKey(state, snakeID), Snake: snakeID, Action: action}, true
+}
+
+// RecordGame credits a finished game's opening moves with its result
+func (b *OpeningBook) RecordGame(moves []BookMove, winner int) {
+	for _, m := range moves {
+		entry := b.Positions[m.Key]
+		if entry == nil {
+			entry = &BookEntry{}
+			b.Positions[m.Key] = entry
+		}
+		entry.Plays[m.Action]++
+		switch winner {
+		case m.Snake:
+			entry.Score[m.Action]++
+		case -1:
+			entry.Score[m.Action] += 0.5
+		}
+	}
+}
+
+// OpeningPolicy plays book moves while they last and defers to Fallback
+// afterwards
+type OpeningPolicy struct {
+	Book     *OpeningBook
+	Fallback Policy
+}
+
+// Act returns the book move if there is one, otherwise the fallback's
+func (p *OpeningPolicy) Act(state *game.GameState, snakeID int) Action {
+	if action, ok := p.Book.Lookup(state, snakeID); ok {
+		return action
+	}
+	return p.Fallback.Act(state, snakeID)
+}
+
+// QValues reports the fallback policy's action values, or nil if it has none
+func (p *OpeningPolicy) QValues(state *game.GameState, snakeID int) []float64 {
+	if q, ok := p.Fallback.(QValuer); ok {
+		return q.QValues(state, snakeID)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// constantPolicy always plays the same move
+type constantPolicy Action
+
+func (p constantPolicy) Act(*game.GameState, int) Action {
+	return Action(p)
+}
+
+func TestOpeningBookLookup(t *testing.T) {
+	g := game.NewGame(config.DefaultGameConfig(), 1)
+	book := NewOpeningBook(4)
+
+	// Snake 0 won more often turning right from the start, but the book
+	// only trusts it once it has seen the position BookMinPlays times
+	var moves []BookMove
+	for _, action := range []Action{TurnRight, TurnRight, GoStraight, TurnLeft} {
+		move, ok := book.Observe(g.State, 0, action)
+		if !ok {
+			t.Fatal("first turn not recorded")
+		}
+		moves = append(moves, move)
+	}
+	book.RecordGame(moves[:2], 0)
+	book.RecordGame(moves[2:], 1)
+	if action, ok := book.Lookup(g.State, 0); ok {
+		t.Errorf("book played %v after %d plays", action, len(moves))
+	}
+	move, _ := book.Observe(g.State, 0, TurnRight)
+	book.RecordGame([]BookMove{move}, -1)
+	if action, ok := book.Lookup(g.State, 0); !ok || action != TurnRight {
+		t.Errorf("lookup = %v, %v; want %v", action, ok, TurnRight)
+	}
+
+	// Positions the book hasn't seen, and turns past it, go to the fallback
+	policy := &OpeningPolicy{Book: book, Fallback: constantPolicy(TurnLeft)}
+	if got := policy.Act(g.State, 0); got != TurnRight {
+		t.Errorf("snake 0 played %v, want the book's %v", got, TurnRight)
+	}
+	if got := policy.Act(g.State, 1); got != TurnLeft {
+		t.Errorf("snake 1 played %v from a missing position, want the fallback's %v", got, TurnLeft)
+	}
+	later := g.State.Clone()
+	later.Turn = book.Turns
+	if _, ok := book.Observe(later, 0, GoStraight); ok {
+		t.Error("move recorded past the book's turns")
+	}
+	if _, ok := book.Lookup(later, 0); ok {
+		t.Error("book played past its turns")
+	}
+
+	// Fixed sequences come first, for both snakes unless given per snake
+	fixed, err := ParseOpening("l, s,r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Book = fixed
+	for turn, want := range []Action{TurnLeft, GoStraight, TurnRight, TurnLeft} {
+		state := g.State.Clone()
+		state.Turn = turn
+		for id := range state.Snakes {
+			if got := policy.Act(state, id); got != want {
+				t.Errorf("turn %d, snake %d: played %v, want %v", turn, id, got, want)
+			}
+		}
+	}
+}
+
+func TestOpeningBookLoad(t *testing.T) {
+	dir := t.TempDir()
+	g := game.NewGame(config.DefaultGameConfig(), 1)
+	book := NewOpeningBook(3)
+	book.Sequences = [][]string{{"s"}, {"r", "l"}}
+	var moves []BookMove
+	for range BookMinPlays {
+		move, _ := book.Observe(g.State, 1, TurnLeft)
+		moves = append(moves, move)
+	}
+	book.RecordGame(moves, 1)
+	path := filepath.Join(dir, "book.json")
+	if err := book.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadOpeningBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Turns != 3 || loaded.Positions[moves[0].Key].Plays[TurnLeft] != BookMinPlays {
+		t.Errorf("loaded book %+v", loaded)
+	}
+	state := g.State.Clone()
+	state.Turn = 1
+	if action, ok := loaded.Lookup(state, 1); !ok || action != TurnLeft {
+		t.Errorf("snake 1's sequence: %v, %v; want %v", action, ok, TurnLeft)
+	}
+	if action, ok := loaded.Lookup(state, 0); ok {
+		t.Errorf("snake 0 played %v past its sequence from an unseen position", action)
+	}
+	// Past its sequence snake 1 plays the learned move
+	state.Turn = 2
+	if action, ok := loaded.Lookup(state, 1); !ok || action != TurnLeft {
+		t.Errorf("snake 1's learned move: %v, %v; want %v", action, ok, TurnLeft)
+	}
+
+	tests := map[string]string{
+		"unknown move":    `{"sequences": [["s", "back"]]}`,
+		"three sequences": `{"sequences": [["s"], ["s"], ["s"]]}`,
+		"not json":        `sequences: s`,
+	}
+	for name, data := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadOpeningBook(path); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+	if _, err := LoadOpeningBook(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing book loaded")
+	}
+	if _, err := ParseOpening("s,u"); err == nil {
+		t.Error("unknown opening move parsed")
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"math/rand"
+
+	"autonomous-snake/internal/game"
+)
+
+// Policy chooses an action for one snake given the current game state
+type Policy interface {
+	Act(state *game.GameState, snakeID int) Action
+}
+
+// NetworkPolicy plays a frozen Q-network greedily, with optional epsilon
+// exploration. With Mask set it never picks a move that dies next turn
+// while another move survives, see SurvivalMask. Each NetworkPolicy owns
+// its scratch buffers and state encoder, stacked state history included,
+// so several can share one network across goroutines as long as nothing
+// trains it.
+//
+// A recurrent network keeps a hidden state for every snake it plays. The
+// state is cleared when a snake's game turn goes backwards, i.e. a new game
+// started, and asking again on the same turn repeats that turn's step.
+type NetworkPolicy struct {
+	Net     *QNetwork
+	Epsilon float64
+	Mask    bool
+
+	encoder  Encoder
+	features []float64
+	cache    *forwardCache
+	memory   map[int]*snakeMemory // Recurrent networks only, by snake ID
+	rng      *rand.Rand
+}
+
+// snakeMemory is the hidden state of a recurrent network for one snake
+type snakeMemory struct {
+	cache *forwardCache
+	turn  int // Turn of the last step
+}
+
+// NewNetworkPolicy creates a policy for net
+func NewNetworkPolicy(net *QNetwork, epsilon float64, seed int64) *NetworkPolicy {
+	return &NetworkPolicy{
+		Net:      net,
+		Epsilon:  epsilon,
+		encoder:  net.StateEncoder(),
+		features: make([]float64, net.InputSize),
+		cache:    newForwardCache(net),
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Act encodes the state and returns the highest-valued action
+func (p *NetworkPolicy) Act(state *game.GameState, snakeID int) Action {
+	mask := AllActions
+	if p.Mask {
+		mask = SurvivalMask(state, snakeID)
+	}
+	if p.Epsilon > 0 && p.rng.Float64() < p.Epsilon {
+		return mask.Random(p.rng)
+	}
+	return mask.Best(p.forward(state, snakeID))
+}
+
+// QValues returns a copy of the network's action values for the state
+func (p *NetworkPolicy) QValues(state *game.GameState, snakeID int) []float64 {
+	return append([]float64(nil), p.forward(state, snakeID)...)
+}
+
+// forward runs the network on the encoded state, stepping the snake's
+// hidden state for recurrent networks
+func (p *NetworkPolicy) forward(state *game.GameState, snakeID int) []float64 {
+	p.encoder.Encode(p.features, state, snakeID)
+	if !p.Net.Recurrent {
+		return p.Net.forwardWith(p.cache, p.features)
+	}
+
+	m := p.memory[snakeID]
+	switch {
+	case m == nil:
+		if p.memory == nil {
+			p.memory = make(map[int]*snakeMemory)
+		}
+		m = &snakeMemory{cache: newForwardCache(p.Net)}
+		p.memory[snakeID] = m
+	case state.Turn < m.turn:
+		m.cache.resetState()
+	case state.Turn == m.turn:
+		copy(m.cache.h2, m.cache.hPrev)
+	}
+	m.turn = state.Turn
+	return p.Net.forwardWith(m.cache, p.features)
+}
+
+// ActPair returns the actions policies choose for snakes 0 and 1, the ones
+// their Act methods would. When both play the same feed-forward network
+// without noise or dropout, the two states share a single batched forward
+// pass, see QNetwork.ForwardBatch; like any use of a network's own scratch
+// buffers, that must not happen on several goroutines at once.
+func ActPair(policies [2]*NetworkPolicy, state *game.GameState) [2]Action {
+	a, b := policies[0], policies[1]
+	if n := a.Net; n != b.Net || n.Recurrent || n.noise || n.dropout > 0 {
+		return [2]Action{a.Act(state, 0), b.Act(state, 1)}
+	}
+
+	var actions [2]Action
+	var explored [2]bool
+	var masks [2]ActionMask
+	for i, p := range policies {
+		masks[i] = AllActions
+		if p.Mask {
+			masks[i] = SurvivalMask(state, i)
+		}
+		if p.Epsilon > 0 && p.rng.Float64() < p.Epsilon {
+			actions[i], explored[i] = masks[i].Random(p.rng), true
+			continue
+		}
+		p.encoder.Encode(p.features, state, i)
+	}
+	switch {
+	case explored[0] && explored[1]:
+	case explored[0]:
+		actions[1] = masks[1].Best(b.Net.forwardWith(b.cache, b.features))
+	case explored[1]:
+		actions[0] = masks[0].Best(a.Net.forwardWith(a.cache, a.features))
+	default:
+		qValues := a.Net.ForwardBatch([][]float64{a.features, b.features})
+		actions = [2]Action{masks[0].Best(qValues[0]), masks[1].Best(qValues[1])}
+	}
+	return actions
+}
+
+// RandomPolicy picks uniformly random actions
+type RandomPolicy struct {
+	rng *rand.Rand
+}
+
+// NewRandomPolicy creates a random policy
+func NewRandomPolicy(seed int64) *RandomPolicy {
+	return &RandomPolicy{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Act returns a random action
+func (p *RandomPolicy) Act(state *game.GameState, snakeID int) Action {
+	return Action(p.rng.Intn(int(NumActions)))
+}
@@ -0,0 +1,37 @@
+package ai
+
+import "testing"
+
+func TestActPair(t *testing.T) {
+	states := benchStates(300)
+	net := NewQNetwork(StateSize, 32, 16, int(NumActions), 0.001, 1)
+	other := NewDuelingQNetwork(StateSize, 32, 16, int(NumActions), 0.001, 2)
+	recurrent := NewRecurrentQNetwork(StateSize, 32, 16, int(NumActions), 0.001, 3)
+	for _, tc := range []struct {
+		name    string
+		nets    [2]*QNetwork
+		epsilon float64
+		mask    bool
+	}{
+		{"shared network", [2]*QNetwork{net, net}, 0, false},
+		{"shared network, masked and exploring", [2]*QNetwork{net, net}, 0.3, true},
+		{"different networks", [2]*QNetwork{net, other}, 0, false},
+		{"shared recurrent network", [2]*QNetwork{recurrent, recurrent}, 0.1, false},
+	} {
+		// Twin policies, one pair acting together and one alone
+		var paired, alone [2]*NetworkPolicy
+		for i, n := range tc.nets {
+			paired[i] = NewNetworkPolicy(n, tc.epsilon, int64(i))
+			alone[i] = NewNetworkPolicy(n, tc.epsilon, int64(i))
+			paired[i].Mask, alone[i].Mask = tc.mask, tc.mask
+		}
+		for turn, state := range states {
+			got := ActPair(paired, state)
+			want := [2]Action{alone[0].Act(state, 0), alone[1].Act(state, 1)}
+			if got != want {
+				t.Errorf("%s: state %d: ActPair = %v, want %v", tc.name, turn, got, want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"math/rand"
+	"sync"
+
+	"autonomous-snake/internal/config"
+)
+
+// SnapshotAgent is an Agent that can copy the network it acts with. The
+// copy plays like a saved model, see NewNetworkPolicy, and is unaffected by
+// later training.
+type SnapshotAgent interface {
+	Agent
+	Snapshot() *QNetwork
+}
+
+// OpponentPool keeps frozen snapshots of a learning agent for it to play
+// against. Facing a mix of its past selves rather than a copy of itself
+// that changes with every update gives the learner a steadier target, and
+// keeps it from forgetting how to beat older strategies.
+//
+// Add and Sample are safe to call from different goroutines.
+type OpponentPool struct {
+	size     int
+	sampling string
+
+	mu        sync.Mutex
+	snapshots []*QNetwork // Oldest first
+	rng       *rand.Rand
+}
+
+// NewOpponentPool creates an empty pool with cfg's size and sampling
+func NewOpponentPool(cfg config.PoolConfig, seed int64) *OpponentPool {
+	return &OpponentPool{
+		size:     cfg.Size,
+		sampling: cfg.Sampling,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Add puts a snapshot in the pool, dropping the oldest one when full
+func (p *OpponentPool) Add(net *QNetwork) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.snapshots) == p.size {
+		copy(p.snapshots, p.snapshots[1:])
+		p.snapshots = p.snapshots[:p.size-1]
+	}
+	p.snapshots = append(p.snapshots, net)
+}
+
+// Len returns the number of snapshots in the pool
+func (p *OpponentPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.snapshots)
+}
+
+// Sample draws a snapshot by the pool's sampling strategy, or returns nil
+// while the pool is empty. Snapshots are shared, so callers must not train
+// them.
+func (p *OpponentPool) Sample() *QNetwork {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := len(p.snapshots)
+	if n == 0 {
+		return nil
+	}
+	switch p.sampling {
+	case config.PoolLatest:
+		return p.snapshots[n-1]
+	case config.PoolRecent:
+		// Snapshot i has weight i+1 of the n(n+1)/2 total
+		r := p.rng.Intn(n * (n + 1) / 2)
+		for i := range p.snapshots {
+			if r -= i + 1; r < 0 {
+				return p.snapshots[i]
+			}
+		}
+	}
+	return p.snapshots[p.rng.Intn(n)]
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestOpponentPool(t *testing.T) {
+	cfg := config.PoolConfig{Size: 3, Interval: 1, Sampling: config.PoolUniform}
+	pool := NewOpponentPool(cfg, 1)
+	if pool.Sample() != nil {
+		t.Fatal("empty pool returned a snapshot")
+	}
+
+	nets := make([]*QNetwork, 4)
+	for i := range nets {
+		nets[i] = NewQNetwork(4, 4, 4, 3, 0.01, int64(i))
+		pool.Add(nets[i])
+	}
+	if pool.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", pool.Len())
+	}
+
+	// The oldest snapshot was dropped; the rest are drawn evenly
+	counts := map[*QNetwork]int{}
+	for range 3000 {
+		counts[pool.Sample()]++
+	}
+	if counts[nets[0]] != 0 {
+		t.Error("dropped snapshot was sampled")
+	}
+	for i, net := range nets[1:] {
+		if counts[net] < 900 || counts[net] > 1100 {
+			t.Errorf("uniform sampling drew snapshot %d %d times of 3000", i+1, counts[net])
+		}
+	}
+
+	// Recent sampling draws them 1:2:3, latest only the newest
+	cfg.Sampling = config.PoolRecent
+	recent := NewOpponentPool(cfg, 1)
+	for _, net := range nets[1:] {
+		recent.Add(net)
+	}
+	counts = map[*QNetwork]int{}
+	for range 6000 {
+		counts[recent.Sample()]++
+	}
+	for i, net := range nets[1:] {
+		if want := 1000 * (i + 1); counts[net] < want-150 || counts[net] > want+150 {
+			t.Errorf("recent sampling drew snapshot %d %d times of 6000, want about %d", i+1, counts[net], want)
+		}
+	}
+	cfg.Sampling = config.PoolLatest
+	latest := NewOpponentPool(cfg, 1)
+	for _, net := range nets {
+		latest.Add(net)
+	}
+	if latest.Sample() != nets[3] {
+		t.Error("latest sampling didn't return the newest snapshot")
+	}
+}
@@ -0,0 +1,227 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+
+	"autonomous-snake/internal/config"
+)
+
+// PPOAgent learns a stochastic policy with proximal policy optimization.
+// An actor network outputs action logits and a separate critic network
+// estimates state values. The agent collects PPO.RolloutSteps transitions
+// with its current policy, computes generalized advantage estimates (GAE)
+// along each snake's trajectory, then takes PPO.Epochs passes of
+// mini-batch steps on the clipped surrogate objective before discarding
+// the rollout.
+//
+// A saved actor plays greedily like any DQN model. The critic is saved
+// beside it, see CriticPath.
+type PPOAgent struct {
+	actorCritic
+	Rollout *RolloutBuffer
+
+	Gamma float64
+	PPO   config.PPOConfig
+
+	// MaxGradNorm > 0 clips each network's mini-batch gradient to this norm
+	MaxGradNorm float64
+
+	// WeightDecay > 0 shrinks both networks' weights after every
+	// mini-batch, an L2 penalty, see config.RegularizationConfig
+	WeightDecay float64
+
+	// LRSchedule, when set, updates both networks' learning rate from
+	// StepCount before every update
+	LRSchedule LRSchedule
+
+	StepCount int
+
+	stats PPOStats
+	rng   *rand.Rand
+}
+
+// PPOStats summarizes the most recent PPO update
+type PPOStats struct {
+	Updates      int     // Updates applied so far
+	PolicyLoss   float64 // Mean clipped surrogate loss
+	ValueLoss    float64 // Mean halved squared error of the critic
+	Entropy      float64 // Mean policy entropy
+	ClipFraction float64 // Share of samples whose ratio left the clip range
+}
+
+// NewPPOAgent creates a PPO agent with the given configuration
+func NewPPOAgent(cfg config.TrainingConfig, seed int64) *PPOAgent {
+	rng := rand.New(rand.NewSource(seed))
+	return &PPOAgent{
+		actorCritic: newActorCritic(newActorCriticNetworks(cfg, rng)),
+		Rollout:     NewRolloutBuffer(),
+		Gamma:       cfg.Gamma,
+		PPO:         cfg.PPO,
+		MaxGradNorm: cfg.MaxGradNorm,
+		WeightDecay: cfg.Regularization.WeightDecay,
+		LRSchedule:  NewLRSchedule(cfg.LRSchedule, cfg.LearningRate),
+		rng:         rng,
+	}
+}
+
+// SelectAction samples an action from the policy
+func (a *PPOAgent) SelectAction(state []float64) Action {
+	return a.sample(state, a.rng)
+}
+
+// SelectActionGreedy returns the most likely action
+func (a *PPOAgent) SelectActionGreedy(state []float64) Action {
+	return Action(MaxIndex(a.policy(state)))
+}
+
+// Remember stores a transition of snake 0, see RememberSnake
+func (a *PPOAgent) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
+	a.RememberSnake(0, state, action, reward, nextState, done)
+}
+
+// RememberSnake stores a transition on the snake's trajectory, along with
+// the policy's probability of the action and the critic's values. The
+// networks only change in Train, so these match what SelectAction saw.
+func (a *PPOAgent) RememberSnake(snakeID int, state []float64, action Action, reward float64, nextState []float64, done bool) {
+	a.Rollout.Add(snakeID, a.record(state, action, reward, nextState, done))
+}
+
+// Train counts a step and updates the networks once a full rollout has
+// been collected. It returns the update's mean surrogate loss, or 0.
+func (a *PPOAgent) Train() float64 {
+	a.StepCount++
+	if a.Rollout.Len() < a.PPO.RolloutSteps {
+		return 0
+	}
+	if a.LRSchedule != nil {
+		lr := a.LRSchedule.Rate(a.StepCount)
+		a.Actor.LearningRate, a.Critic.LearningRate = lr, lr
+	}
+	a.update()
+	a.Rollout.Clear()
+	return a.stats.PolicyLoss
+}
+
+// DecayEpsilon marks the end of an episode. PPO explores through its
+// stochastic policy, so there is no epsilon; the open trajectories close.
+func (a *PPOAgent) DecayEpsilon() {
+	a.Rollout.EndEpisode()
+}
+
+// update runs the PPO epochs over the collected rollout
+func (a *PPOAgent) update() {
+	steps := a.Rollout.computeAdvantages(a.Gamma, a.PPO.Lambda)
+
+	// Normalized advantages steady the step size across rollouts
+	advantages := make([]float64, len(steps))
+	mean, std := 0.0, 0.0
+	for _, s := range steps {
+		mean += s.Advantage
+	}
+	mean /= float64(len(steps))
+	for _, s := range steps {
+		std += (s.Advantage - mean) * (s.Advantage - mean)
+	}
+	std = math.Sqrt(std/float64(len(steps))) + 1e-8
+	for i, s := range steps {
+		advantages[i] = (s.Advantage - mean) / std
+	}
+
+	var stats PPOStats
+	samples := 0
+	order := make([]int, len(steps))
+	for i := range order {
+		order[i] = i
+	}
+	for epoch := 0; epoch < a.PPO.Epochs; epoch++ {
+		a.rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		for start := 0; start < len(order); start += a.PPO.MiniBatch {
+			batch := order[start:min(start+a.PPO.MiniBatch, len(order))]
+			a.zeroGradients()
+			for _, i := range batch {
+				policyLoss, valueLoss, entropy, clipped := a.accumulate(steps[i], advantages[i])
+				stats.PolicyLoss += policyLoss
+				stats.ValueLoss += valueLoss
+				stats.Entropy += entropy
+				if clipped {
+					stats.ClipFraction++
+				}
+			}
+			scale := 1.0 / float64(len(batch))
+			applyClipped(a.Actor, a.actorGrads, scale, a.MaxGradNorm)
+			applyClipped(a.Critic, a.criticGrads, scale, a.MaxGradNorm)
+			a.Actor.decayWeights(a.WeightDecay)
+			a.Critic.decayWeights(a.WeightDecay)
+			samples += len(batch)
+		}
+	}
+
+	n := float64(samples)
+	a.stats = PPOStats{
+		Updates:      a.stats.Updates + 1,
+		PolicyLoss:   stats.PolicyLoss / n,
+		ValueLoss:    stats.ValueLoss / n,
+		Entropy:      stats.Entropy / n,
+		ClipFraction: stats.ClipFraction / n,
+	}
+}
+
+// accumulate adds one transition's actor and critic gradients. It returns
+// the sample's surrogate loss, value loss and policy entropy, and whether
+// its probability ratio left the clip range.
+func (a *PPOAgent) accumulate(s *rolloutStep, advantage float64) (float64, float64, float64, bool) {
+	eps := a.PPO.ClipEpsilon
+	probs := a.policy(s.State)
+	ratio := math.Exp(math.Log(math.Max(probs[s.Action], minProb)) - s.LogProb)
+	policyLoss := -math.Min(ratio*advantage, math.Max(1-eps, math.Min(1+eps, ratio))*advantage)
+
+	// The clipped objective has no gradient once the ratio has moved past
+	// the clip range in the direction the advantage favours
+	active := !(advantage > 0 && ratio > 1+eps) && !(advantage < 0 && ratio < 1-eps)
+
+	weight := 0.0
+	if active {
+		weight = advantage * ratio
+	}
+	entropy := a.accumulatePolicy(probs, s.Action, weight, a.PPO.EntropyCoef)
+	valueLoss := a.accumulateValue(s.State, s.Return)
+	return policyLoss, valueLoss, entropy, math.Abs(ratio-1) > eps
+}
+
+// Stats summarizes the most recent update
+func (a *PPOAgent) Stats() PPOStats {
+	return a.stats
+}
+
+// LearningRate returns the actor's current learning rate
+func (a *PPOAgent) LearningRate() float64 {
+	return a.Actor.LearningRate
+}
+
+// Encoder returns the state encoder the agent's networks expect
+func (a *PPOAgent) Encoder() Encoder {
+	return a.Actor.StateEncoder()
+}
+
+// Save writes the actor to path and the critic to CriticPath(path)
+func (a *PPOAgent) Save(path string) error {
+	return a.save(path)
+}
+
+// Snapshot returns a copy of the actor
+func (a *PPOAgent) Snapshot() *QNetwork {
+	return a.Actor.Clone()
+}
+
+// Load replaces the actor with the model at path, which may be any
+// standard or dueling network, e.g. a DQN model to start from. The critic
+// is loaded from CriticPath(path) if it exists and starts afresh otherwise.
+func (a *PPOAgent) Load(path string) error {
+	actor, critic, err := loadActorCritic(path, a.rng.Int63())
+	if err != nil {
+		return err
+	}
+	a.actorCritic = newActorCritic(actor, critic)
+	return nil
+}
@@ -0,0 +1,129 @@
+package ai
+
+import "math"
+
+const (
+	// priorityEpsilon keeps transitions with zero TD error sampleable
+	priorityEpsilon = 1e-3
+	// priorityBetaAnneal is how many batches it takes to anneal the
+	// importance sampling exponent from its start value to 1
+	priorityBetaAnneal = 100000
+)
+
+// PrioritizedReplayBuffer samples experiences in proportion to their last
+// TD error instead of uniformly, so rare but surprising transitions such as
+// deaths are replayed more often. The bias this introduces is corrected with
+// importance sampling weights. Experiences are stored in the embedded
+// ReplayBuffer; priorities live in a sum tree over the same slots.
+type PrioritizedReplayBuffer struct {
+	*ReplayBuffer
+
+	// Alpha sets how strongly priorities skew sampling (0 is uniform) and
+	// Beta how much of the skew the weights correct (1 is fully). Beta
+	// grows towards 1 with every sampled batch.
+	Alpha float64
+	Beta  float64
+
+	betaStep    float64
+	tree        []float64 // Sum tree; leaves start at len(tree)/2
+	maxPriority float64
+}
+
+// NewPrioritizedReplayBuffer creates a prioritized buffer with the given
+// capacity and exponents
+func NewPrioritizedReplayBuffer(capacity int, alpha, beta float64, seed int64) *PrioritizedReplayBuffer {
+	leaves := 1
+	for leaves < capacity {
+		leaves *= 2
+	}
+	return &PrioritizedReplayBuffer{
+		ReplayBuffer: NewReplayBuffer(capacity, seed),
+		Alpha:        alpha,
+		Beta:         beta,
+		betaStep:     (1 - beta) / priorityBetaAnneal,
+		tree:         make([]float64, 2*leaves),
+		maxPriority:  1,
+	}
+}
+
+// Add stores an experience with the highest priority seen so far, so every
+// new transition is replayed at least once soon
+func (pb *PrioritizedReplayBuffer) Add(exp Experience) {
+	idx := pb.position
+	pb.ReplayBuffer.Add(exp)
+	pb.setPriority(idx, pb.maxPriority)
+}
+
+// Sample draws a batch with probability proportional to priority. It
+// returns the slots drawn, for UpdatePriorities, and each experience's
+// importance sampling weight, normalized so the largest in the batch is 1.
+func (pb *PrioritizedReplayBuffer) Sample(batchSize int) ([]Experience, []int, []float64) {
+	if batchSize > pb.size {
+		batchSize = pb.size
+	}
+	batch := make([]Experience, batchSize)
+	indices := make([]int, batchSize)
+	weights := make([]float64, batchSize)
+
+	// Stratified sampling: one draw from each equal slice of the total
+	total := pb.tree[1]
+	segment := total / float64(batchSize)
+	maxWeight := 0.0
+	for i := range batch {
+		idx := pb.find((float64(i) + pb.rng.Float64()) * segment)
+		prob := pb.tree[len(pb.tree)/2+idx] / total
+		indices[i] = idx
+		batch[i] = pb.buffer[idx]
+		weights[i] = math.Pow(float64(pb.size)*prob, -pb.Beta)
+		maxWeight = math.Max(maxWeight, weights[i])
+	}
+	for i := range weights {
+		weights[i] /= maxWeight
+	}
+
+	pb.Beta = math.Min(1, pb.Beta+pb.betaStep)
+	return batch, indices, weights
+}
+
+// UpdatePriorities sets the priorities of sampled slots from the absolute
+// TD errors they just produced
+func (pb *PrioritizedReplayBuffer) UpdatePriorities(indices []int, tdErrors []float64) {
+	for i, idx := range indices {
+		p := math.Pow(math.Abs(tdErrors[i])+priorityEpsilon, pb.Alpha)
+		pb.setPriority(idx, p)
+		pb.maxPriority = math.Max(pb.maxPriority, p)
+	}
+}
+
+// Clear empties the buffer and forgets all priorities
+func (pb *PrioritizedReplayBuffer) Clear() {
+	pb.ReplayBuffer.Clear()
+	for i := range pb.tree {
+		pb.tree[i] = 0
+	}
+	pb.maxPriority = 1
+}
+
+// setPriority sets slot idx's priority and updates the sums above it
+func (pb *PrioritizedReplayBuffer) setPriority(idx int, p float64) {
+	node := len(pb.tree)/2 + idx
+	pb.tree[node] = p
+	for node /= 2; node >= 1; node /= 2 {
+		pb.tree[node] = pb.tree[2*node] + pb.tree[2*node+1]
+	}
+}
+
+// find returns the slot whose priority interval contains mass
+func (pb *PrioritizedReplayBuffer) find(mass float64) int {
+	node := 1
+	for node < len(pb.tree)/2 {
+		left := 2 * node
+		if mass < pb.tree[left] || pb.tree[left+1] == 0 {
+			node = left
+		} else {
+			mass -= pb.tree[left]
+			node = left + 1
+		}
+	}
+	return min(node-len(pb.tree)/2, pb.size-1)
+}
@@ -0,0 +1,46 @@
+package ai
+
+import "testing"
+
+func TestPrioritizedReplayBuffer(t *testing.T) {
+	pb := NewPrioritizedReplayBuffer(8, 1, 1, 1)
+	for i := 0; i < 5; i++ {
+		pb.Add(Experience{State: []float64{float64(i)}, Action: GoStraight})
+	}
+	if pb.Size() != 5 {
+		t.Fatalf("size = %d, want 5", pb.Size())
+	}
+
+	// Slot 3 gets nearly all of the priority mass
+	pb.UpdatePriorities([]int{0, 1, 2, 3, 4}, []float64{0, 0, 0, 10, 0})
+	counts := make([]int, 5)
+	for n := 0; n < 100; n++ {
+		batch, indices, weights := pb.Sample(4)
+		for i, idx := range indices {
+			if batch[i].State[0] != float64(idx) {
+				t.Fatalf("slot %d returned experience %v", idx, batch[i].State)
+			}
+			if weights[i] <= 0 || weights[i] > 1 {
+				t.Fatalf("weight %v outside (0, 1]", weights[i])
+			}
+			counts[idx]++
+		}
+	}
+	if counts[3] < 350 {
+		t.Errorf("high priority slot sampled %d of 400 times, want most", counts[3])
+	}
+
+	// Wrapping around overwrites the oldest slots with maximum priority
+	for i := 5; i < 10; i++ {
+		pb.Add(Experience{State: []float64{float64(i % 8)}, Action: GoStraight})
+	}
+	if pb.Size() != 8 {
+		t.Fatalf("size = %d, want 8", pb.Size())
+	}
+	_, indices, _ := pb.Sample(8)
+	for _, idx := range indices {
+		if idx < 0 || idx >= 8 {
+			t.Fatalf("sampled slot %d out of range", idx)
+		}
+	}
+}
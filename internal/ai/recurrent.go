@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"errors"
+	"math"
+)
+
+// Recurrent networks replace the second hidden layer with a gated recurrent
+// unit (GRU) whose output, the hidden state h, carries over to the next
+// forward pass:
+//
+//	z  = σ(W_z·h1 + b_z + U_z·h)       update gate
+//	r  = σ(W_r·h1 + b_r + U_r·h)       reset gate
+//	n  = tanh(W_n·h1 + b_n + U_n·(r∘h)) candidate
+//	h' = (1-z)∘n + z∘h
+//
+// W2 and B2 stack the z, r and n rows, so layer 2 is three times as wide,
+// and U2 holds the recurrent weights in the same order. The hidden state
+// lives in the forward cache's h2: a fresh cache starts from zero and each
+// forward pass leaves the new state there for the next one.
+
+// NewRecurrentQNetwork creates a network whose second hidden layer is a GRU.
+// Its first layer and head match a plain network created with the same seed.
+func NewRecurrentQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize int, lr float64, seed int64) *QNetwork {
+	net := NewQNetwork(inputSize, hiddenSize1, hiddenSize2, outputSize, lr, seed)
+	net.makeRecurrent()
+	return net
+}
+
+// makeRecurrent replaces layer 2 with freshly initialized GRU weights
+func (n *QNetwork) makeRecurrent() {
+	n.Recurrent = true
+	n.W2 = xavierInit(n.HiddenSize1, 3*n.HiddenSize2, n.rng)
+	n.B2 = make([]float64, 3*n.HiddenSize2)
+	n.U2 = xavierInit(n.HiddenSize2, 3*n.HiddenSize2, n.rng)
+	n.cache, n.batch, n.grads = nil, nil, nil
+}
+
+// layer2Size returns the number of rows of W2: HiddenSize2, or one per gate
+// of every unit for recurrent networks
+func (n *QNetwork) layer2Size() int {
+	if n.Recurrent {
+		return 3 * n.HiddenSize2
+	}
+	return n.HiddenSize2
+}
+
+// checkRecurrent rejects layer combinations recurrent networks don't support
+func checkRecurrent(recurrent, noisy bool) error {
+	if recurrent && noisy {
+		return errors.New("recurrent networks can't be noisy")
+	}
+	return nil
+}
+
+// ResetState clears the hidden state Forward carries between calls, e.g.
+// at the start of an episode. It has no effect on feedforward networks.
+func (n *QNetwork) ResetState() {
+	if n.cache != nil {
+		n.cache.resetState()
+	}
+}
+
+// resetState clears the hidden state a recurrent network left in the cache
+func (c *forwardCache) resetState() {
+	clear(c.h2)
+}
+
+// gruForward runs layer 2 of a recurrent network on cache.h1, taking the
+// previous hidden state from cache.h2 and leaving the new one there
+func (n *QNetwork) gruForward(cache *forwardCache) {
+	size := n.HiddenSize2
+	copy(cache.hPrev, cache.h2)
+	n.linear(cache, 1, n.W2, n.B2, cache.h1, cache.z2)
+
+	// Update and reset gates
+	backend.MatVec(n.U2[:2*size*size], cache.noBias[:2*size], cache.hPrev, cache.uh[:2*size])
+	for i := 0; i < 2*size; i++ {
+		cache.gates[i] = sigmoid(cache.z2[i] + cache.uh[i])
+	}
+
+	// Candidate state from the reset part of the previous one
+	reset := cache.gates[size : 2*size]
+	for i, h := range cache.hPrev {
+		cache.rh[i] = reset[i] * h
+	}
+	backend.MatVec(n.U2[2*size*size:], cache.noBias[:size], cache.rh, cache.uh[2*size:])
+	for i, h := range cache.hPrev {
+		z := cache.gates[i]
+		candidate := math.Tanh(cache.z2[2*size+i] + cache.uh[2*size+i])
+		cache.gates[2*size+i] = candidate
+		cache.h2[i] = (1-z)*candidate + z*h
+	}
+}
+
+// gruBackward turns the gradient w.r.t. the new hidden state in cache.dH2
+// into the gradients w.r.t. the gate pre-activations in cache.dZ2 and the
+// previous hidden state in cache.dHPrev
+func (n *QNetwork) gruBackward(cache *forwardCache) {
+	size := n.HiddenSize2
+	update, reset, candidate := cache.gates[:size], cache.gates[size:2*size], cache.gates[2*size:]
+	dUpdate, dReset, dCandidate := cache.dZ2[:size], cache.dZ2[size:2*size], cache.dZ2[2*size:]
+	for i, dh := range cache.dH2 {
+		z, c := update[i], candidate[i]
+		dCandidate[i] = dh * (1 - z) * (1 - c*c)
+		dUpdate[i] = dh * (cache.hPrev[i] - c) * z * (1 - z)
+	}
+
+	// The candidate saw the previous state through the reset gate
+	backend.MatTVec(n.U2[2*size*size:], dCandidate, cache.dRH)
+	for i, d := range cache.dRH {
+		r := reset[i]
+		dReset[i] = d * cache.hPrev[i] * r * (1 - r)
+	}
+
+	backend.MatTVec(n.U2[:2*size*size], cache.dZ2[:2*size], cache.dHPrev)
+	for i, dh := range cache.dH2 {
+		cache.dHPrev[i] += dh*update[i] + cache.dRH[i]*reset[i]
+	}
+}
+
+// accumulateRecurrent adds the gradient of the recurrent weights, given
+// the gate errors gruBackward left in cache.dZ2
+func accumulateRecurrent(cache *forwardCache, g *gradients, size int) {
+	backend.Rank1Update(g.U2[:2*size*size], 1, cache.dZ2[:2*size], cache.hPrev)
+	backend.Rank1Update(g.U2[2*size*size:], 1, cache.dZ2[2*size:], cache.rh)
+}
+
+// sigmoid is the logistic function
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
@@ -0,0 +1,50 @@
+package ai
+
+// SetDropout makes training forward passes zero each unit of the first
+// hidden layer with probability p, scaling the others by 1/(1-p) so the
+// next layer sees the same expected input. 0 turns dropout off; it is off
+// unless set, so loaded networks play with every unit.
+func (n *QNetwork) SetDropout(p float64) {
+	n.dropout = p
+	n.cache = nil
+}
+
+// dropUnits draws a fresh dropout mask and applies it to cache.h1
+func (n *QNetwork) dropUnits(cache *forwardCache) {
+	keep := 1 / (1 - n.dropout)
+	for i := range cache.mask {
+		if cache.rng.Float64() < n.dropout {
+			cache.mask[i] = 0
+		} else {
+			cache.mask[i] = keep
+		}
+		cache.h1[i] *= cache.mask[i]
+	}
+}
+
+// dropBackward applies the last forward pass's dropout mask, if any, to
+// the gradient w.r.t. the first hidden layer in cache.dH1
+func dropBackward(cache *forwardCache) {
+	if !cache.dropped {
+		return
+	}
+	for i, m := range cache.mask {
+		cache.dH1[i] *= m
+	}
+}
+
+// decayWeights shrinks every weight towards zero by LearningRate·lambda of
+// itself, the update of an L2 penalty lambda/2·‖W‖². Biases and noise
+// scales are left alone.
+func (n *QNetwork) decayWeights(lambda float64) {
+	if lambda <= 0 {
+		return
+	}
+	n.checkWritable()
+	factor := 1 - n.LearningRate*lambda
+	for _, w := range [][]float64{n.W1, n.W2, n.W3, n.WV, n.U2} {
+		for i := range w {
+			w[i] *= factor
+		}
+	}
+}
@@ -9,6 +9,10 @@ type Experience struct {
 	Reward    float64
 	NextState []float64
 	Done      bool
+
+	// Bootstrap mask: bit k set means head k of a bootstrapped network
+	// trains on the transition. 0 trains every head.
+	Heads uint64
 }
 
 // ReplayBuffer stores experiences for training
@@ -46,6 +50,7 @@ func (rb *ReplayBuffer) Add(exp Experience) {
 		Reward:    exp.Reward,
 		NextState: nextStateCopy,
 		Done:      exp.Done,
+		Heads:     exp.Heads,
 	}
 
 	rb.position = (rb.position + 1) % rb.capacity
@@ -75,6 +80,11 @@ func (rb *ReplayBuffer) Size() int {
 	return rb.size
 }
 
+// Capacity returns the most experiences the buffer holds
+func (rb *ReplayBuffer) Capacity() int {
+	return rb.capacity
+}
+
 // IsFull returns true if the buffer has reached capacity
 func (rb *ReplayBuffer) IsFull() bool {
 	return rb.size == rb.capacity
@@ -0,0 +1,291 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// ReplayFormatVersion is the current replay buffer file version
+const ReplayFormatVersion = 1
+
+// replayFormat identifies gob replay buffer files
+const replayFormat = "slitherrl-replay-buffer"
+
+// replayMagic starts binary replay buffer files. Their layout, little-endian:
+//
+//	[4]byte  magic "SLRB"
+//	uint16   format version
+//	uint16   reserved, 0
+//	uint32   state size n
+//	uint32   experience count
+//
+// then, per experience, oldest first: n float64 state values, uint8 action,
+// uint8 done, uint64 bootstrap mask, float64 reward and n float64 next
+// state values.
+var replayMagic = [4]byte{'S', 'L', 'R', 'B'}
+
+// replayHeader is written once at the start of a gob replay buffer file
+type replayHeader struct {
+	Format    string
+	Version   int
+	StateSize int
+	Count     int
+}
+
+// Save writes the buffer's experiences, oldest first, to path with codec
+// CodecGob or CodecFlat (binary), replacing the file only once the new one
+// is complete. Load reads either.
+func (rb *ReplayBuffer) Save(path string, codec Codec) error {
+	return WriteFileAtomic(path, func(file io.Writer) error {
+		w := bufio.NewWriter(file)
+		var err error
+		switch codec {
+		case CodecGob, "":
+			err = rb.writeGob(w)
+		case CodecFlat:
+			err = rb.writeBinary(w)
+		default:
+			err = fmt.Errorf("replay buffers can't be saved as %s", codec)
+		}
+		if err != nil {
+			return err
+		}
+		return w.Flush()
+	})
+}
+
+// Load replaces the buffer's experiences with those saved at path. A file
+// holding more than the buffer's capacity leaves the newest.
+func (rb *ReplayBuffer) Load(path string) error {
+	exps, err := ReadExperiences(path)
+	if err != nil {
+		return err
+	}
+	rb.replace(exps)
+	return nil
+}
+
+// replace empties the buffer and adds exps
+func (rb *ReplayBuffer) replace(exps []Experience) {
+	rb.Clear()
+	for _, exp := range exps {
+		rb.Add(exp)
+	}
+}
+
+// Load replaces the buffer's experiences with those saved at path, like
+// ReplayBuffer.Load. Priorities aren't saved, so every loaded experience
+// starts at the highest priority.
+func (pb *PrioritizedReplayBuffer) Load(path string) error {
+	exps, err := ReadExperiences(path)
+	if err != nil {
+		return err
+	}
+	pb.replace(exps)
+	return nil
+}
+
+// replace empties the buffer and adds exps at the highest priority
+func (pb *PrioritizedReplayBuffer) replace(exps []Experience) {
+	pb.Clear()
+	for _, exp := range exps {
+		pb.Add(exp)
+	}
+}
+
+// stored returns the buffer's experiences, oldest first
+func (rb *ReplayBuffer) stored() []Experience {
+	exps := make([]Experience, rb.size)
+	start := rb.position - rb.size + rb.capacity
+	for i := range exps {
+		exps[i] = rb.buffer[(start+i)%rb.capacity]
+	}
+	return exps
+}
+
+// stateSize returns the length of the stored states, 0 when empty
+func (rb *ReplayBuffer) stateSize() int {
+	if rb.size == 0 {
+		return 0
+	}
+	return len(rb.buffer[0].State)
+}
+
+// writeGob encodes a header and then every experience
+func (rb *ReplayBuffer) writeGob(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+	header := replayHeader{Format: replayFormat, Version: ReplayFormatVersion, StateSize: rb.stateSize(), Count: rb.size}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, exp := range rb.stored() {
+		if err := enc.Encode(exp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBinary writes the binary layout described at replayMagic
+func (rb *ReplayBuffer) writeBinary(w io.Writer) error {
+	n := rb.stateSize()
+	header := make([]byte, 16)
+	copy(header, replayMagic[:])
+	binary.LittleEndian.PutUint16(header[4:], ReplayFormatVersion)
+	binary.LittleEndian.PutUint32(header[8:], uint32(n))
+	binary.LittleEndian.PutUint32(header[12:], uint32(rb.size))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]byte, 8*(2*n+2)+2)
+	for _, exp := range rb.stored() {
+		if len(exp.State) != n || len(exp.NextState) != n {
+			return fmt.Errorf("replay buffer mixes state sizes %d and %d", n, len(exp.State))
+		}
+		b := putFloats(record, exp.State)
+		b[0] = byte(exp.Action)
+		b[1] = 0
+		if exp.Done {
+			b[1] = 1
+		}
+		binary.LittleEndian.PutUint64(b[2:], exp.Heads)
+		binary.LittleEndian.PutUint64(b[10:], math.Float64bits(exp.Reward))
+		putFloats(b[18:], exp.NextState)
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putFloats writes values to b and returns the rest of b
+func putFloats(b []byte, values []float64) []byte {
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+		b = b[8:]
+	}
+	return b
+}
+
+// readFloats fills values from b and returns the rest of b
+func readFloats(b []byte, values []float64) []byte {
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		b = b[8:]
+	}
+	return b
+}
+
+// ReadExperiences reads the experiences saved at path: a replay buffer in
+// either codec, or the transitions of a TransitionWriter
+func ReadExperiences(path string) ([]Experience, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var exps []Experience
+	if len(data) >= len(replayMagic) && bytes.Equal(data[:len(replayMagic)], replayMagic[:]) {
+		exps, err = decodeReplayBinary(data)
+	} else {
+		exps, err = decodeReplayGob(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, exp := range exps {
+		if exp.Action < 0 || exp.Action >= NumActions {
+			return nil, fmt.Errorf("%s: experience %d has invalid action %d", path, i, exp.Action)
+		}
+		if len(exp.State) != len(exps[0].State) || len(exp.NextState) != len(exps[0].State) {
+			return nil, fmt.Errorf("%s: experience %d has a different state size", path, i)
+		}
+	}
+	return exps, nil
+}
+
+// decodeReplayGob decodes a gob replay buffer file
+func decodeReplayGob(data []byte) ([]Experience, error) {
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	var header replayHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("not a replay buffer file: %w", err)
+	}
+	if header.Format == transitionFormat {
+		return decodeTransitions(dec, header.Version)
+	}
+	if header.Format != replayFormat {
+		return nil, fmt.Errorf("not a replay buffer file (format %q)", header.Format)
+	}
+	if header.Version > ReplayFormatVersion {
+		return nil, fmt.Errorf("replay buffer file version %d is newer than supported version %d", header.Version, ReplayFormatVersion)
+	}
+	exps := make([]Experience, 0, header.Count)
+	for range header.Count {
+		var exp Experience
+		if err := dec.Decode(&exp); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("truncated replay buffer file: %w", io.ErrUnexpectedEOF)
+			}
+			return nil, err
+		}
+		exps = append(exps, exp)
+	}
+	return exps, nil
+}
+
+// decodeTransitions decodes the rest of a transition file, whose header
+// dec has read, up to its end
+func decodeTransitions(dec *gob.Decoder, version int) ([]Experience, error) {
+	if version > TransitionFormatVersion {
+		return nil, fmt.Errorf("transition file version %d is newer than supported version %d", version, TransitionFormatVersion)
+	}
+	tr := &TransitionReader{dec: dec}
+	var exps []Experience
+	for {
+		t, err := tr.Read()
+		if errors.Is(err, io.EOF) {
+			return exps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		exps = append(exps, t.Experience)
+	}
+}
+
+// decodeReplayBinary decodes a binary replay buffer file
+func decodeReplayBinary(data []byte) ([]Experience, error) {
+	if len(data) < 16 {
+		return nil, fmt.Errorf("truncated replay buffer file: %w", io.ErrUnexpectedEOF)
+	}
+	if version := binary.LittleEndian.Uint16(data[4:]); version > ReplayFormatVersion {
+		return nil, fmt.Errorf("replay buffer file version %d is newer than supported version %d", version, ReplayFormatVersion)
+	}
+	n := int(binary.LittleEndian.Uint32(data[8:]))
+	count := int(binary.LittleEndian.Uint32(data[12:]))
+	size := 8*(2*n+2) + 2
+	body := data[16:]
+	if len(body) != count*size {
+		return nil, fmt.Errorf("replay buffer file holds %d bytes of experiences, want %d", len(body), count*size)
+	}
+	exps := make([]Experience, count)
+	for i := range exps {
+		exp := &exps[i]
+		exp.State = make([]float64, n)
+		exp.NextState = make([]float64, n)
+		b := readFloats(body[i*size:], exp.State)
+		exp.Action = Action(b[0])
+		exp.Done = b[1] != 0
+		exp.Heads = binary.LittleEndian.Uint64(b[2:])
+		exp.Reward = math.Float64frombits(binary.LittleEndian.Uint64(b[10:]))
+		readFloats(b[18:], exp.NextState)
+	}
+	return exps, nil
+}
@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReplayBufferSaveLoad(t *testing.T) {
+	// A wrapped buffer saves its experiences oldest first
+	rb := NewReplayBuffer(4, 1)
+	for i := range 6 {
+		v := float64(i)
+		rb.Add(Experience{
+			State:     []float64{v, -v},
+			Action:    Action(i % NumActions),
+			Reward:    v / 2,
+			NextState: []float64{v + 1, 0.25},
+			Done:      i%2 == 1,
+			Heads:     uint64(i),
+		})
+	}
+	want := rb.stored()
+	if want[0].State[0] != 2 || want[3].State[0] != 5 {
+		t.Fatalf("stored order %v..%v, want 2..5", want[0].State, want[3].State)
+	}
+
+	dir := t.TempDir()
+	for _, codec := range []Codec{CodecGob, CodecFlat} {
+		path := filepath.Join(dir, "buffer."+string(codec))
+		if err := rb.Save(path, codec); err != nil {
+			t.Fatalf("%s: save: %v", codec, err)
+		}
+		loaded := NewReplayBuffer(4, 2)
+		if err := loaded.Load(path); err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if got := loaded.stored(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: loaded %v, want %v", codec, got, want)
+		}
+
+		// A smaller buffer keeps the newest
+		small := NewReplayBuffer(2, 2)
+		if err := small.Load(path); err != nil {
+			t.Fatalf("%s: load: %v", codec, err)
+		}
+		if got := small.stored(); !reflect.DeepEqual(got, want[2:]) {
+			t.Errorf("%s: small buffer loaded %v, want %v", codec, got, want[2:])
+		}
+	}
+
+	// Prioritized buffers can sample what they load
+	pb := NewPrioritizedReplayBuffer(8, 0.6, 0.4, 1)
+	if err := pb.Load(filepath.Join(dir, "buffer.flat")); err != nil {
+		t.Fatal(err)
+	}
+	if batch, _, _ := pb.Sample(4); pb.Size() != 4 || len(batch) != 4 || batch[0].State == nil {
+		t.Errorf("prioritized buffer holds %d experiences after loading, sampled %v", pb.Size(), batch)
+	}
+
+	// Transition files from selfplay read as experiences too
+	path := filepath.Join(dir, "selfplay.gob")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw, err := NewTransitionWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, exp := range want {
+		if err := tw.Write(Transition{Turn: i, Experience: exp}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+	if got, err := ReadExperiences(path); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadExperiences = %v, %v, want %v", got, err, want)
+	}
+}
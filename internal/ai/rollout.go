@@ -0,0 +1,92 @@
+package ai
+
+// rolloutStep is one transition collected for an on-policy update, with
+// what the policy and critic made of it when it was collected
+type rolloutStep struct {
+	State     []float64
+	Action    Action
+	Reward    float64
+	Done      bool
+	LogProb   float64 // Log probability of Action under the collecting policy
+	Value     float64 // Critic's value of State
+	NextValue float64 // Critic's value of the next state
+
+	// Filled in by computeAdvantages
+	Advantage float64
+	Return    float64
+}
+
+// RolloutBuffer collects on-policy transitions as trajectory segments, one
+// open segment per snake, so advantages follow each snake's own trajectory
+// even when both snakes' transitions arrive interleaved. A segment closes
+// when its snake's episode ends.
+type RolloutBuffer struct {
+	segments [][]rolloutStep
+	open     map[int]int // Snake ID -> index of its open segment
+	size     int
+}
+
+// NewRolloutBuffer creates an empty rollout buffer
+func NewRolloutBuffer() *RolloutBuffer {
+	return &RolloutBuffer{open: make(map[int]int)}
+}
+
+// Add appends a transition to the snake's open segment
+func (b *RolloutBuffer) Add(snakeID int, step rolloutStep) {
+	k, ok := b.open[snakeID]
+	if !ok {
+		k = len(b.segments)
+		b.segments = append(b.segments, nil)
+		b.open[snakeID] = k
+	}
+	b.segments[k] = append(b.segments[k], step)
+	b.size++
+	if step.Done {
+		delete(b.open, snakeID)
+	}
+}
+
+// EndEpisode closes every open segment, e.g. when an episode is cut off
+// without a terminal transition
+func (b *RolloutBuffer) EndEpisode() {
+	clear(b.open)
+}
+
+// Len returns the number of transitions collected
+func (b *RolloutBuffer) Len() int {
+	return b.size
+}
+
+// Clear discards every transition. Open trajectories continue in new
+// segments.
+func (b *RolloutBuffer) Clear() {
+	b.segments = b.segments[:0]
+	clear(b.open)
+	b.size = 0
+}
+
+// computeAdvantages fills in every transition's generalized advantage
+// estimate and return, and returns all transitions. Each segment is worked
+// backwards, bootstrapping from the critic's value of the state after its
+// last transition unless that transition ended the episode.
+func (b *RolloutBuffer) computeAdvantages(gamma, lambda float64) []*rolloutStep {
+	steps := make([]*rolloutStep, 0, b.size)
+	for _, segment := range b.segments {
+		gae := 0.0
+		for t := len(segment) - 1; t >= 0; t-- {
+			s := &segment[t]
+			next := s.NextValue
+			if s.Done {
+				next = 0
+			}
+			delta := s.Reward + gamma*next - s.Value
+			gae = delta + gamma*lambda*gae
+			s.Advantage = gae
+			s.Return = gae + s.Value
+		}
+		for t := range segment {
+			steps = append(steps, &segment[t])
+		}
+	}
+	return steps
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"math"
+
+	"autonomous-snake/internal/config"
+)
+
+// LRSchedule maps the number of environment steps trained on to a learning
+// rate
+type LRSchedule interface {
+	Rate(step int) float64
+}
+
+// NewLRSchedule builds the schedule described by cfg starting from the
+// rate initial. It returns nil for a constant rate, which leaves the
+// network's rate alone. cfg must be valid, see config.LRScheduleConfig.
+func NewLRSchedule(cfg config.LRScheduleConfig, initial float64) LRSchedule {
+	switch cfg.Kind {
+	case config.LRLinear:
+		return LinearLR{Start: initial, End: cfg.MinLR, Steps: cfg.Steps}
+	case config.LRCosine:
+		return CosineLR{Start: initial, End: cfg.MinLR, Steps: cfg.Steps}
+	case config.LRStep:
+		return StepLR{Start: initial, Every: cfg.Steps, Factor: cfg.Factor}
+	}
+	return nil
+}
+
+// LinearLR decays linearly from Start to End over Steps, then stays at End
+type LinearLR struct {
+	Start, End float64
+	Steps      int
+}
+
+// Rate returns the learning rate at step
+func (s LinearLR) Rate(step int) float64 {
+	t := math.Min(float64(step)/float64(s.Steps), 1)
+	return s.Start + (s.End-s.Start)*t
+}
+
+// CosineLR follows half a cosine from Start to End over Steps, decaying
+// slowly at first and last, then stays at End
+type CosineLR struct {
+	Start, End float64
+	Steps      int
+}
+
+// Rate returns the learning rate at step
+func (s CosineLR) Rate(step int) float64 {
+	t := math.Min(float64(step)/float64(s.Steps), 1)
+	return s.End + (s.Start-s.End)*(1+math.Cos(math.Pi*t))/2
+}
+
+// StepLR multiplies the rate by Factor every Every steps
+type StepLR struct {
+	Start  float64
+	Every  int
+	Factor float64
+}
+
+// Rate returns the learning rate at step
+func (s StepLR) Rate(step int) float64 {
+	return s.Start * math.Pow(s.Factor, float64(step/s.Every))
+}
+
+// EpsilonSchedule maps the number of environment steps taken to an
+// exploration rate
+type EpsilonSchedule interface {
+	Epsilon(step int) float64
+}
+
+// NewEpsilonSchedule builds the schedule described by cfg falling from start
+// to end. It returns nil for the per-episode schedule, which DecayEpsilon
+// applies instead. cfg must be valid, see config.EpsilonScheduleConfig.
+func NewEpsilonSchedule(cfg config.EpsilonScheduleConfig, start, end float64) EpsilonSchedule {
+	switch cfg.Kind {
+	case config.EpsilonLinear:
+		return LinearEpsilon{Start: start, End: end, Steps: cfg.Steps}
+	case config.EpsilonExponential:
+		return ExponentialEpsilon{Start: start, End: end, Steps: cfg.Steps}
+	case config.EpsilonPiecewise:
+		points, _ := cfg.ParsePoints()
+		return PiecewiseEpsilon{Points: points}
+	}
+	return nil
+}
+
+// LinearEpsilon falls linearly from Start to End over Steps
+type LinearEpsilon struct {
+	Start, End float64
+	Steps      int
+}
+
+// Epsilon returns the exploration rate at step
+func (s LinearEpsilon) Epsilon(step int) float64 {
+	return LinearLR(s).Rate(step)
+}
+
+// ExponentialEpsilon approaches End from Start with time constant Steps
+type ExponentialEpsilon struct {
+	Start, End float64
+	Steps      int
+}
+
+// Epsilon returns the exploration rate at step
+func (s ExponentialEpsilon) Epsilon(step int) float64 {
+	return s.End + (s.Start-s.End)*math.Exp(-float64(step)/float64(s.Steps))
+}
+
+// PiecewiseEpsilon interpolates linearly between knots, holding the first
+// and last values outside them
+type PiecewiseEpsilon struct {
+	Points []config.EpsilonPoint
+}
+
+// Epsilon returns the exploration rate at step
+func (s PiecewiseEpsilon) Epsilon(step int) float64 {
+	if len(s.Points) == 0 {
+		return 0
+	}
+	if step <= s.Points[0].Step {
+		return s.Points[0].Epsilon
+	}
+	for i := 1; i < len(s.Points); i++ {
+		a, b := s.Points[i-1], s.Points[i]
+		if step < b.Step {
+			t := float64(step-a.Step) / float64(b.Step-a.Step)
+			return a.Epsilon + (b.Epsilon-a.Epsilon)*t
+		}
+	}
+	return s.Points[len(s.Points)-1].Epsilon
+}
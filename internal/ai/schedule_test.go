@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"math"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestLRSchedule(t *testing.T) {
+	if s := NewLRSchedule(config.LRScheduleConfig{Kind: config.LRConstant}, 0.1); s != nil {
+		t.Errorf("constant schedule = %v, want nil", s)
+	}
+	for _, tc := range []struct {
+		cfg  config.LRScheduleConfig
+		step int
+		want float64
+	}{
+		{config.LRScheduleConfig{Kind: config.LRLinear, Steps: 100, MinLR: 0.02}, 0, 0.1},
+		{config.LRScheduleConfig{Kind: config.LRLinear, Steps: 100, MinLR: 0.02}, 50, 0.06},
+		{config.LRScheduleConfig{Kind: config.LRLinear, Steps: 100, MinLR: 0.02}, 500, 0.02},
+		{config.LRScheduleConfig{Kind: config.LRCosine, Steps: 100}, 0, 0.1},
+		{config.LRScheduleConfig{Kind: config.LRCosine, Steps: 100}, 50, 0.05},
+		{config.LRScheduleConfig{Kind: config.LRCosine, Steps: 100}, 100, 0},
+		{config.LRScheduleConfig{Kind: config.LRStep, Steps: 10, Factor: 0.5}, 9, 0.1},
+		{config.LRScheduleConfig{Kind: config.LRStep, Steps: 10, Factor: 0.5}, 25, 0.025},
+	} {
+		if got := NewLRSchedule(tc.cfg, 0.1).Rate(tc.step); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("%s at step %d = %v, want %v", tc.cfg.Kind, tc.step, got, tc.want)
+		}
+	}
+}
+
+func TestEpsilonSchedule(t *testing.T) {
+	piecewise := config.EpsilonScheduleConfig{Kind: config.EpsilonPiecewise, Points: "0:1, 100:0.5,200:0.1"}
+	if err := piecewise.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		cfg  config.EpsilonScheduleConfig
+		step int
+		want float64
+	}{
+		{config.EpsilonScheduleConfig{Kind: config.EpsilonLinear, Steps: 100}, 50, 0.55},
+		{config.EpsilonScheduleConfig{Kind: config.EpsilonLinear, Steps: 100}, 1000, 0.1},
+		{config.EpsilonScheduleConfig{Kind: config.EpsilonExponential, Steps: 100}, 0, 1},
+		{config.EpsilonScheduleConfig{Kind: config.EpsilonExponential, Steps: 100}, 100, 0.1 + 0.9/math.E},
+		{piecewise, 50, 0.75},
+		{piecewise, 150, 0.3},
+		{piecewise, 5000, 0.1},
+	} {
+		if got := NewEpsilonSchedule(tc.cfg, 1, 0.1).Epsilon(tc.step); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("%s at step %d = %v, want %v", tc.cfg.Kind, tc.step, got, tc.want)
+		}
+	}
+
+	for _, points := range []string{"", "0:1,0:0.5", "10:2", "a:1"} {
+		cfg := config.EpsilonScheduleConfig{Kind: config.EpsilonPiecewise, Points: points}
+		if cfg.Validate() == nil {
+			t.Errorf("points %q: expected an error", points)
+		}
+	}
+}
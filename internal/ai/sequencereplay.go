@@ -0,0 +1,100 @@
+package ai
+
+import "math/rand"
+
+// SequenceReplayBuffer stores each snake's transitions as trajectories, one
+// per episode, so a recurrent agent can replay them in order. Once more
+// than capacity transitions are stored the oldest trajectories are dropped.
+type SequenceReplayBuffer struct {
+	trajectories [][]Experience
+	open         map[int]int // Snake ID -> index of its trajectory in progress
+	size         int
+	capacity     int
+	rng          *rand.Rand
+}
+
+// Sequence is a run of consecutive transitions from one trajectory, and
+// the ones before it that only warm up a recurrent network's hidden state
+type Sequence struct {
+	BurnIn []Experience
+	Steps  []Experience
+}
+
+// NewSequenceReplayBuffer creates a sequence replay buffer holding up to
+// capacity transitions
+func NewSequenceReplayBuffer(capacity int, seed int64) *SequenceReplayBuffer {
+	return &SequenceReplayBuffer{
+		open:     make(map[int]int),
+		capacity: capacity,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Add appends a transition to the snake's trajectory. A done transition
+// ends the trajectory; the snake's next one starts a new trajectory.
+func (b *SequenceReplayBuffer) Add(snakeID int, exp Experience) {
+	exp.State = append([]float64(nil), exp.State...)
+	exp.NextState = append([]float64(nil), exp.NextState...)
+
+	k, ok := b.open[snakeID]
+	if !ok {
+		k = len(b.trajectories)
+		b.trajectories = append(b.trajectories, nil)
+		b.open[snakeID] = k
+	}
+	b.trajectories[k] = append(b.trajectories[k], exp)
+	b.size++
+	if exp.Done {
+		delete(b.open, snakeID)
+	}
+
+	// Drop the oldest trajectories, keeping the one just added to
+	for b.size > b.capacity && len(b.trajectories) > 1 {
+		b.size -= len(b.trajectories[0])
+		b.trajectories[0] = nil
+		b.trajectories = b.trajectories[1:]
+		for id, k := range b.open {
+			if k == 0 {
+				delete(b.open, id)
+			} else {
+				b.open[id] = k - 1
+			}
+		}
+	}
+}
+
+// EndEpisode ends every trajectory in progress, e.g. when an episode hits
+// its step limit before any snake is done
+func (b *SequenceReplayBuffer) EndEpisode() {
+	clear(b.open)
+}
+
+// Sample returns count sequences of up to length transitions, each with up
+// to burnIn transitions before it. Every stored transition is equally
+// likely to start a sequence, which stops early at the trajectory's end.
+// The transitions are shared with the buffer and must not be modified.
+func (b *SequenceReplayBuffer) Sample(count, length, burnIn int) []Sequence {
+	if b.size == 0 {
+		return nil
+	}
+	sequences := make([]Sequence, count)
+	for i := range sequences {
+		start := b.rng.Intn(b.size)
+		k := 0
+		for start >= len(b.trajectories[k]) {
+			start -= len(b.trajectories[k])
+			k++
+		}
+		trajectory := b.trajectories[k]
+		sequences[i] = Sequence{
+			BurnIn: trajectory[max(start-burnIn, 0):start],
+			Steps:  trajectory[start:min(start+length, len(trajectory))],
+		}
+	}
+	return sequences
+}
+
+// Size returns the number of transitions stored
+func (b *SequenceReplayBuffer) Size() int {
+	return b.size
+}
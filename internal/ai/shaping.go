@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/eval"
+	"autonomous-snake/internal/game"
+)
+
+// Shaping modes accepted by config.RewardConfig.Shaping
+const (
+	ShapingNone      = "none"
+	ShapingDistance  = "distance"  // Legacy fixed bonus toward/away from food
+	ShapingPotential = "potential" // gamma*phi(s') - phi(s), the default
+)
+
+// Potential estimates how favourable a state is for a snake. Potentials
+// should be bounded; RewardShaper gives dead snakes and finished games
+// zero.
+type Potential func(state *game.GameState, snakeID int) float64
+
+// potentials holds the built-in potential functions by config name
+var potentials = map[string]Potential{
+	"food":  FoodDistancePotential,
+	"space": FreeSpacePotential,
+	"eval":  eval.Evaluate,
+}
+
+// LookupPotential returns the built-in potential with the given name
+func LookupPotential(name string) (Potential, error) {
+	p, ok := potentials[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown potential %q", name)
+	}
+	return p, nil
+}
+
+// FoodDistancePotential is the negated Manhattan distance from the head to
+// the nearest food, normalized to [-1, 0]
+func FoodDistancePotential(state *game.GameState, snakeID int) float64 {
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return 0.0
+	}
+	food, ok := state.NearestFood(snake.Head())
+	if !ok {
+		return 0.0
+	}
+	dist := float64(game.ManhattanDistance(snake.Head(), food))
+	return -dist / float64(state.Width+state.Height)
+}
+
+// FreeSpacePotential is the fraction of the board reachable from the head,
+// in [0, 1]. It rewards keeping escape routes open.
+func FreeSpacePotential(state *game.GameState, snakeID int) float64 {
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return 0.0
+	}
+	return float64(state.ReachableArea(snake.Head())) / float64(state.Width*state.Height)
+}
+
+// weightedPotential pairs a potential with its weight
+type weightedPotential struct {
+	name   string
+	weight float64
+	fn     Potential
+}
+
+// RewardShaper computes the shaping term added to the game's rewards
+type RewardShaper struct {
+	mode       string
+	gamma      float64
+	rewards    config.RewardConfig
+	potentials []weightedPotential
+}
+
+// NewRewardShaper builds a shaper from the reward configuration. gamma must
+// match the agent's discount factor for potential-based shaping to leave the
+// optimal policy unchanged.
+func NewRewardShaper(rewards config.RewardConfig, gamma float64) (*RewardShaper, error) {
+	s := &RewardShaper{
+		mode:    rewards.Shaping,
+		gamma:   gamma,
+		rewards: rewards,
+	}
+	if s.mode == "" {
+		s.mode = ShapingPotential
+	}
+
+	switch s.mode {
+	case ShapingNone, ShapingDistance:
+	case ShapingPotential:
+		// Sort names so the summation order is deterministic
+		names := make([]string, 0, len(rewards.Potentials))
+		for name := range rewards.Potentials {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fn, err := LookupPotential(name)
+			if err != nil {
+				return nil, err
+			}
+			s.potentials = append(s.potentials, weightedPotential{name, rewards.Potentials[name], fn})
+		}
+	default:
+		return nil, fmt.Errorf("unknown shaping mode %q", s.mode)
+	}
+	return s, nil
+}
+
+// Potential returns the combined weighted potential of a state
+func (s *RewardShaper) Potential(state *game.GameState, snakeID int) float64 {
+	// Terminal states have zero potential, the winner's too. Games cut
+	// short by the turn or repeat limit aren't terminal to training, which
+	// bootstraps past them, so they keep theirs.
+	if !state.Snakes[snakeID].Alive || state.GameOver && !state.TimedOut && !state.Repeated {
+		return 0.0
+	}
+	total := 0.0
+	for _, p := range s.potentials {
+		total += p.weight * p.fn(state, snakeID)
+	}
+	return total
+}
+
+// Reward returns the shaping reward for a transition from prevState to
+// newState. Call it after the step with a copy of the state taken before.
+func (s *RewardShaper) Reward(prevState, newState *game.GameState, snakeID int) float64 {
+	switch s.mode {
+	case ShapingDistance:
+		return CalculateShapingReward(prevState, newState, snakeID, s.rewards)
+	case ShapingPotential:
+		return s.gamma*s.Potential(newState, snakeID) - s.Potential(prevState, snakeID)
+	}
+	return 0.0
+}
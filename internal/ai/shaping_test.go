@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"math"
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func TestShapingTerminal(t *testing.T) {
+	rewards := config.DefaultRewardConfig()
+	rewards.Shaping = ShapingPotential
+	rewards.Potentials = map[string]float64{"food": 1, "space": 1}
+	shaper, err := NewRewardShaper(rewards, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := game.NewGame(config.DefaultGameConfig(), 1).State
+	if phi := shaper.Potential(prev, 0); phi == 0 {
+		t.Fatal("a live snake in a game in progress has zero potential")
+	}
+
+	// Snake 0 wins: it is still alive, but the game is over
+	final := prev.Clone()
+	final.Snakes[1].Alive = false
+	final.GameOver, final.Winner = true, 0
+	for id := range final.Snakes {
+		if phi := shaper.Potential(final, id); phi != 0 {
+			t.Errorf("snake %d: potential %v of a finished game, want 0", id, phi)
+		}
+		want := -shaper.Potential(prev, id)
+		if got := shaper.Reward(prev, final, id); math.Abs(got-want) > 1e-12 {
+			t.Errorf("snake %d: final shaping reward %v, want %v", id, got, want)
+		}
+	}
+
+	// A game ended by the turn limit is cut short, not finished
+	timedOut := prev.Clone()
+	timedOut.GameOver, timedOut.TimedOut, timedOut.Winner = true, true, 0
+	for id := range timedOut.Snakes {
+		if got, want := shaper.Potential(timedOut, id), shaper.Potential(prev, id); got != want {
+			t.Errorf("snake %d: potential %v on the turn limit, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"autonomous-snake/internal/game"
+)
+
+// stackSeparator joins a base encoder's name and the number of states a
+// StateStack keeps, e.g. "features*4"
+const stackSeparator = "*"
+
+// MaxStack is the most states a StateStack keeps
+const MaxStack = 16
+
+// StackedEncoderName returns the name of the encoder stacking the last k
+// states of the named encoder. k below 2 stacks nothing and returns base.
+func StackedEncoderName(base string, k int) string {
+	if k < 2 {
+		return base
+	}
+	return base + stackSeparator + strconv.Itoa(k)
+}
+
+// BaseEncoderName returns the name of the encoder a stacked encoder name
+// stacks, or name itself
+func BaseEncoderName(name string) string {
+	base, _, _ := strings.Cut(name, stackSeparator)
+	return base
+}
+
+// parseStack splits a stacked encoder name into its base name and depth.
+// ok is false for names that don't stack.
+func parseStack(name string) (base string, k int, ok bool, err error) {
+	base, depth, ok := strings.Cut(name, stackSeparator)
+	if !ok {
+		return name, 1, false, nil
+	}
+	k, err = strconv.Atoi(depth)
+	if err != nil || k < 2 || k > MaxStack {
+		return base, 0, true, fmt.Errorf("state encoder %q must stack 2 to %d states", name, MaxStack)
+	}
+	return base, k, true, nil
+}
+
+// StateStack is an Encoder that shows a network the last K states of
+// another encoder, newest first, so it can tell which way the opponent is
+// heading. Networks record it by its stacked name, and LookupEncoder
+// returns a new StateStack for every call, so training, play and the
+// renderer get one each without doing anything different.
+//
+// It keeps the history of every snake it encodes for by snake ID and
+// follows the game by turn: a later turn pushes a new state, the same turn
+// again replaces the newest, and an earlier turn means a new game, which
+// starts over with the current state repeated. A StateStack must not be
+// shared between goroutines.
+type StateStack struct {
+	base    Encoder
+	k       int
+	history map[int]*stackHistory
+}
+
+// stackHistory is one snake's recent states
+type stackHistory struct {
+	frames [][]float64 // Newest first
+	turn   int         // Turn of frames[0]
+}
+
+// NewStateStack creates an encoder stacking the last k states of base
+func NewStateStack(base Encoder, k int) *StateStack {
+	return &StateStack{base: base, k: k, history: make(map[int]*stackHistory)}
+}
+
+// Name returns the stacked encoder name, see StackedEncoderName
+func (s *StateStack) Name() string { return StackedEncoderName(s.base.Name(), s.k) }
+
+// Size returns the number of values in all stacked states
+func (s *StateStack) Size() int { return s.k * s.base.Size() }
+
+// Base returns the encoder whose states are stacked
+func (s *StateStack) Base() Encoder { return s.base }
+
+// Encode writes the snake's last K states into dst, newest first
+func (s *StateStack) Encode(dst []float64, state *game.GameState, snakeID int) {
+	h := s.history[snakeID]
+	fresh := false
+	switch {
+	case h == nil:
+		h = &stackHistory{frames: make([][]float64, s.k)}
+		for i := range h.frames {
+			h.frames[i] = make([]float64, s.base.Size())
+		}
+		s.history[snakeID] = h
+		fresh = true
+	case state.Turn < h.turn:
+		fresh = true
+	case state.Turn > h.turn:
+		oldest := h.frames[s.k-1]
+		copy(h.frames[1:], h.frames[:s.k-1])
+		h.frames[0] = oldest
+	}
+	h.turn = state.Turn
+
+	s.base.Encode(h.frames[0], state, snakeID)
+	if fresh {
+		for _, frame := range h.frames[1:] {
+			copy(frame, h.frames[0])
+		}
+	}
+	for i, frame := range h.frames {
+		copy(dst[i*len(frame):], frame)
+	}
+}
+
+// planeStack is a StateStack of a PlaneEncoder, whose stacked states are
+// still planes: K times the base encoder's
+type planeStack struct {
+	*StateStack
+}
+
+// Planes returns the number and size of the stacked planes
+func (p planeStack) Planes() (channels, height, width int) {
+	channels, height, width = p.base.(PlaneEncoder).Planes()
+	return p.k * channels, height, width
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+// turnEncoder encodes a state as its turn and the snake ID
+type turnEncoder struct{}
+
+func (turnEncoder) Name() string { return "turn" }
+func (turnEncoder) Size() int    { return 2 }
+func (turnEncoder) Encode(dst []float64, state *game.GameState, snakeID int) {
+	dst[0], dst[1] = float64(state.Turn), float64(snakeID)
+}
+
+func TestStateStack(t *testing.T) {
+	stack := NewStateStack(turnEncoder{}, 3)
+	if stack.Size() != 6 || stack.Name() != "turn*3" {
+		t.Fatalf("stack is %s of size %d, want turn*3 of size 6", stack.Name(), stack.Size())
+	}
+	dst := make([]float64, stack.Size())
+	encode := func(turn, snakeID int, want ...float64) {
+		t.Helper()
+		stack.Encode(dst, &game.GameState{Turn: turn}, snakeID)
+		if !slices.Equal(dst, want) {
+			t.Errorf("turn %d, snake %d: encoded %v, want %v", turn, snakeID, dst, want)
+		}
+	}
+
+	encode(0, 0, 0, 0, 0, 0, 0, 0) // The first state fills the stack
+	encode(1, 0, 1, 0, 0, 0, 0, 0)
+	encode(2, 0, 2, 0, 1, 0, 0, 0)
+	encode(2, 0, 2, 0, 1, 0, 0, 0) // Same turn again replaces the newest
+	encode(0, 1, 0, 1, 0, 1, 0, 1) // Each snake has its own history
+	encode(3, 0, 3, 0, 2, 0, 1, 0)
+	encode(1, 0, 1, 0, 1, 0, 1, 0) // A new game starts over
+
+	// Stacked names look up fresh stacks of planes when the base has them
+	enc, err := LookupEncoder(StackedEncoderName(GridEncoderName, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	planes, ok := enc.(PlaneEncoder)
+	if !ok {
+		t.Fatalf("%s doesn't produce planes", enc.Name())
+	}
+	channels, height, width := planes.Planes()
+	if enc.Size() != 2*GridPlanes*GridSize*GridSize || channels*height*width != enc.Size() {
+		t.Errorf("%s has size %d and %dx%dx%d planes", enc.Name(), enc.Size(), channels, height, width)
+	}
+	if again, _ := LookupEncoder(enc.Name()); again == enc {
+		t.Error("LookupEncoder shared a state stack")
+	}
+	if BaseEncoderName(enc.Name()) != GridEncoderName || StackedEncoderName("features", 1) != "features" {
+		t.Errorf("stacked names don't round trip")
+	}
+	for _, name := range []string{"features*1", "features*x", "features*99", "nope*2"} {
+		if _, err := LookupEncoder(name); err == nil {
+			t.Errorf("LookupEncoder(%q) succeeded", name)
+		}
+	}
+}
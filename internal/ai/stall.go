@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// StallPenalty penalizes snakes that circle safely instead of competing:
+// revisiting a head position seen within the last RevisitWindow turns, and
+// every turn beyond StarvationTurns without eating. It keeps per-episode
+// history, so call Reset at the start of each episode.
+type StallPenalty struct {
+	rewards [2]config.RewardConfig
+
+	recent      [2][]game.Position // Ring buffer of recent head positions
+	next        [2]int             // Next ring buffer slot to overwrite
+	sinceEating [2]int
+}
+
+// NewStallPenalty creates a stall tracker from each snake's reward configuration
+func NewStallPenalty(rewards [2]config.RewardConfig) *StallPenalty {
+	s := &StallPenalty{rewards: rewards}
+	s.Reset()
+	return s
+}
+
+// Enabled reports whether any stall penalty is configured for either snake
+func (s *StallPenalty) Enabled() bool {
+	for _, r := range s.rewards {
+		if (r.RevisitPenalty != 0 && r.RevisitWindow > 0) ||
+			(r.StarvationPenalty != 0 && r.StarvationTurns > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset clears the history for a new episode
+func (s *StallPenalty) Reset() {
+	for i := 0; i < 2; i++ {
+		s.recent[i] = s.recent[i][:0]
+		s.next[i] = 0
+		s.sinceEating[i] = 0
+	}
+}
+
+// Penalty records the snake's new head position and returns the penalty for
+// this turn. Call it once per snake after every step.
+func (s *StallPenalty) Penalty(state *game.GameState, snakeID int, ateFood bool) float64 {
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return 0.0
+	}
+
+	rewards := s.rewards[snakeID]
+	penalty := 0.0
+
+	// Starvation: long stretches without eating
+	if ateFood {
+		s.sinceEating[snakeID] = 0
+	} else {
+		s.sinceEating[snakeID]++
+	}
+	if rewards.StarvationTurns > 0 && s.sinceEating[snakeID] > rewards.StarvationTurns {
+		penalty += rewards.StarvationPenalty
+	}
+
+	// Revisits: head returns to a recently occupied cell
+	window := rewards.RevisitWindow
+	if window > 0 {
+		head := snake.Head()
+		for _, pos := range s.recent[snakeID] {
+			if pos == head {
+				penalty += rewards.RevisitPenalty
+				break
+			}
+		}
+		if len(s.recent[snakeID]) < window {
+			s.recent[snakeID] = append(s.recent[snakeID], head)
+		} else {
+			s.recent[snakeID][s.next[snakeID]] = head
+			s.next[snakeID] = (s.next[snakeID] + 1) % window
+		}
+	}
+
+	return penalty
+}
@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func TestStallPenalty(t *testing.T) {
+	state := game.NewGame(config.DefaultGameConfig(), 1).State
+	head := func(x, y int) {
+		state.Snakes[0].Body[0] = game.Position{X: x, Y: y}
+	}
+
+	// Snake 0 starves after 3 food-less turns; snake 1 has no penalties
+	var rewards [2]config.RewardConfig
+	rewards[0].StarvationTurns, rewards[0].StarvationPenalty = 3, -0.5
+	stall := NewStallPenalty(rewards)
+	if !stall.Enabled() || NewStallPenalty([2]config.RewardConfig{}).Enabled() {
+		t.Error("Enabled doesn't follow the configured penalties")
+	}
+
+	// Moving on to a new cell every turn, so only starvation counts
+	tests := []struct {
+		ate  bool
+		want float64
+	}{
+		{false, 0}, {false, 0}, {false, 0}, {false, -0.5}, {false, -0.5},
+		// Eating resets the count
+		{true, 0}, {false, 0}, {false, 0}, {false, 0}, {false, -0.5},
+	}
+	for turn, tt := range tests {
+		head(turn, 0)
+		if got := stall.Penalty(state, 0, tt.ate); got != tt.want {
+			t.Errorf("turn %d, ate %v: penalty %v, want %v", turn+1, tt.ate, got, tt.want)
+		}
+		if got := stall.Penalty(state, 1, false); got != 0 {
+			t.Errorf("turn %d: snake 1 penalized %v without penalties", turn+1, got)
+		}
+	}
+	stall.Reset()
+	head(0, 1)
+	if got := stall.Penalty(state, 0, false); got != 0 {
+		t.Errorf("penalty %v after Reset, want 0", got)
+	}
+
+	// Revisiting a cell within the last 2 turns
+	rewards[0] = config.RewardConfig{RevisitWindow: 2, RevisitPenalty: -1}
+	stall = NewStallPenalty(rewards)
+	for turn, tt := range []struct {
+		x    int
+		want float64
+	}{{0, 0}, {1, 0}, {0, -1}, {2, 0}, {1, 0}, {1, -1}} {
+		head(tt.x, 0)
+		if got := stall.Penalty(state, 0, false); got != tt.want {
+			t.Errorf("revisit turn %d at x %d: penalty %v, want %v", turn+1, tt.x, got, tt.want)
+		}
+	}
+
+	// Dead snakes aren't penalized
+	state.Snakes[0].Alive = false
+	if got := stall.Penalty(state, 0, false); got != 0 {
+		t.Errorf("dead snake penalized %v", got)
+	}
+}
@@ -1,6 +1,10 @@
 package ai
 
 import (
+	"fmt"
+	"strings"
+
+	"autonomous-snake/internal/config"
 	"autonomous-snake/internal/game"
 )
 
@@ -14,6 +18,32 @@ const (
 	NumActions = 3
 )
 
+// String returns the action's name as used in opening books
+func (a Action) String() string {
+	switch a {
+	case GoStraight:
+		return "straight"
+	case TurnLeft:
+		return "left"
+	case TurnRight:
+		return "right"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ParseAction parses an action name or its first letter (s, l, r)
+func ParseAction(name string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "straight", "s":
+		return GoStraight, nil
+	case "left", "l":
+		return TurnLeft, nil
+	case "right", "r":
+		return TurnRight, nil
+	}
+	return GoStraight, fmt.Errorf("unknown action %q", name)
+}
+
 // ActionToDirection converts a relative action to absolute direction
 func ActionToDirection(currentDir game.Direction, action Action) game.Direction {
 	switch action {
@@ -34,22 +64,34 @@ const StateSize = 22
 // The state is encoded from the perspective of the specified snake
 func EncodeState(state *game.GameState, snakeID int) []float64 {
 	features := make([]float64, StateSize)
+	EncodeStateInto(features, state, snakeID)
+	return features
+}
+
+// EncodeStateInto writes the encoded state into features, which must have
+// length StateSize. It performs no allocations, so hot loops can reuse one
+// buffer per snake.
+func EncodeStateInto(features []float64, state *game.GameState, snakeID int) {
+	features = features[:StateSize]
+	for i := range features {
+		features[i] = 0
+	}
 	idx := 0
 
 	snake := state.Snakes[snakeID]
 	otherSnake := state.Snakes[1-snakeID]
 
 	if !snake.Alive {
-		return features // All zeros for dead snake
+		return // All zeros for dead snake
 	}
 
 	head := snake.Head()
 	dir := snake.Direction
 
 	// 1. Danger detection - straight, left, right (3 values) [0-2]
-	straightPos := snake.NextHead(dir)
-	leftPos := snake.NextHead(dir.TurnLeft())
-	rightPos := snake.NextHead(dir.TurnRight())
+	straightPos := state.NextHead(snake, dir)
+	leftPos := state.NextHead(snake, dir.TurnLeft())
+	rightPos := state.NextHead(snake, dir.TurnRight())
 
 	features[idx] = boolToFloat(isDanger(straightPos, snakeID, state))
 	idx++
@@ -62,9 +104,8 @@ func EncodeState(state *game.GameState, snakeID int) []float64 {
 	features[idx+int(dir)] = 1.0
 	idx += 4
 
-	// 3. Food direction relative to head (4 values) [7-10]
-	if state.Food.Active {
-		foodPos := state.Food.Position
+	// 3. Direction of the nearest food relative to head (4 values) [7-10]
+	if foodPos, ok := state.NearestFood(head); ok {
 		features[idx] = boolToFloat(foodPos.Y < head.Y)   // Food up
 		features[idx+1] = boolToFloat(foodPos.Y > head.Y) // Food down
 		features[idx+2] = boolToFloat(foodPos.X < head.X) // Food left
@@ -118,26 +159,20 @@ func EncodeState(state *game.GameState, snakeID int) []float64 {
 
 	// 9. Extended danger - 2 steps ahead (3 values) [19-21]
 	// Check if going straight would lead to danger in 2 steps
-	// Create temporary snake instances to use NextHead method
-	tempSnake := &game.Snake{Body: []game.Position{straightPos}}
-	straight2 := tempSnake.NextHead(dir)
-	tempSnake.Body[0] = leftPos
-	left2 := tempSnake.NextHead(dir.TurnLeft())
-	tempSnake.Body[0] = rightPos
-	right2 := tempSnake.NextHead(dir.TurnRight())
+	straight2 := straightPos.Neighbor(dir)
+	left2 := leftPos.Neighbor(dir.TurnLeft())
+	right2 := rightPos.Neighbor(dir.TurnRight())
 
 	features[idx] = boolToFloat(isDangerExtended(straightPos, straight2, snakeID, state))
 	idx++
 	features[idx] = boolToFloat(isDangerExtended(leftPos, left2, snakeID, state))
 	idx++
 	features[idx] = boolToFloat(isDangerExtended(rightPos, right2, snakeID, state))
-
-	return features
 }
 
 // isDanger checks if a position is dangerous
 func isDanger(pos game.Position, snakeID int, state *game.GameState) bool {
-	return game.IsDangerPosition(pos, snakeID, state.Snakes, state.Width, state.Height)
+	return game.IsDangerPosition(pos, snakeID, state.Snakes, state.Width, state.Height) || state.IsObstacle(pos)
 }
 
 // isDangerExtended checks if both step1 and step2 positions are dangerous
@@ -159,7 +194,7 @@ func boolToFloat(b bool) float64 {
 
 // CalculateShapingReward computes distance-based reward shaping
 // Call this BEFORE the step to compare with AFTER
-func CalculateShapingReward(prevState, newState *game.GameState, snakeID int) float64 {
+func CalculateShapingReward(prevState, newState *game.GameState, snakeID int, rewards config.RewardConfig) float64 {
 	prevSnake := prevState.Snakes[snakeID]
 	newSnake := newState.Snakes[snakeID]
 
@@ -167,17 +202,22 @@ func CalculateShapingReward(prevState, newState *game.GameState, snakeID int) fl
 		return 0.0
 	}
 
-	if !prevState.Food.Active || !newState.Food.Active {
+	prevFood, ok := prevState.NearestFood(prevSnake.Head())
+	if !ok {
+		return 0.0
+	}
+	newFood, ok := newState.NearestFood(newSnake.Head())
+	if !ok {
 		return 0.0
 	}
 
-	prevDist := game.ManhattanDistance(prevSnake.Head(), prevState.Food.Position)
-	newDist := game.ManhattanDistance(newSnake.Head(), newState.Food.Position)
+	prevDist := game.ManhattanDistance(prevSnake.Head(), prevFood)
+	newDist := game.ManhattanDistance(newSnake.Head(), newFood)
 
 	if newDist < prevDist {
-		return 0.1 // Moving toward food
+		return rewards.ShapingToward // Moving toward food
 	} else if newDist > prevDist {
-		return -0.1 // Moving away from food
+		return rewards.ShapingAway // Moving away from food
 	}
 	return 0.0
 }
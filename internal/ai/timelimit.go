@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"time"
+
+	"autonomous-snake/internal/game"
+)
+
+// TimedPolicy enforces a per-move deadline on another policy. The wrapped
+// policy thinks on its own copy of the state; if it hasn't answered within
+// Limit, SafeAction is played instead and the late answer is discarded.
+// Moves never wait for a late search: the busy policy is abandoned to
+// finish in the background and New builds a fresh one, or without New the
+// safe default is played until the search ends. Either way the wrapped
+// policy never runs twice at once, and a search that never returns only
+// costs its goroutine.
+type TimedPolicy struct {
+	Policy Policy
+	Limit  time.Duration
+
+	// New builds a replacement for a policy still busy with a late search.
+	// Replacements start afresh, so recurrent policies lose their memory.
+	New func() Policy
+
+	// Moves and Timeouts count decisions and how many missed the deadline
+	Moves    int
+	Timeouts int
+
+	pending chan Action // Answer of a search that missed its deadline
+}
+
+// NewTimedPolicy wraps a policy from newPolicy with a per-move limit,
+// calling it again to replace a policy left busy by a late search; a zero
+// limit disables it
+func NewTimedPolicy(newPolicy func() Policy, limit time.Duration) *TimedPolicy {
+	return &TimedPolicy{Policy: newPolicy(), Limit: limit, New: newPolicy}
+}
+
+// Act returns the wrapped policy's action, or a safe default if it is late
+func (p *TimedPolicy) Act(state *game.GameState, snakeID int) Action {
+	p.Moves++
+	if p.Limit <= 0 {
+		return p.Policy.Act(state, snakeID)
+	}
+	if !p.ready() {
+		p.Timeouts++
+		return SafeAction(state, snakeID)
+	}
+
+	answer := make(chan Action, 1)
+	policy, snapshot := p.Policy, state.Clone()
+	go func() { answer <- policy.Act(snapshot, snakeID) }()
+
+	timer := time.NewTimer(p.Limit)
+	defer timer.Stop()
+	select {
+	case action := <-answer:
+		return action
+	case <-timer.C:
+		p.Timeouts++
+		p.pending = answer
+		return SafeAction(state, snakeID)
+	}
+}
+
+// QValues reports the wrapped policy's action values, or nil if it has none
+// or is still busy with a late search. Values are not subject to the time
+// limit.
+func (p *TimedPolicy) QValues(state *game.GameState, snakeID int) []float64 {
+	q, ok := p.Policy.(QValuer)
+	if !ok || !p.ready() {
+		return nil
+	}
+	return q.QValues(state, snakeID)
+}
+
+// ready reports whether the wrapped policy is free to act, replacing it if
+// a late search still holds it and New is set
+func (p *TimedPolicy) ready() bool {
+	if p.pending == nil {
+		return true
+	}
+	select {
+	case <-p.pending:
+	default:
+		if p.New == nil {
+			return false
+		}
+		p.Policy = p.New()
+	}
+	p.pending = nil
+	return true
+}
+
+// SafeAction returns the first action, trying straight first, that doesn't
+// die immediately, or straight if every move is fatal
+func SafeAction(state *game.GameState, snakeID int) Action {
+	snake := state.Snakes[snakeID]
+	for _, a := range []Action{GoStraight, TurnLeft, TurnRight} {
+		if !isDanger(state.NextHead(snake, ActionToDirection(snake.Direction, a)), snakeID, state) {
+			return a
+		}
+	}
+	return GoStraight
+}
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// stuckPolicy searches until released, like a search that overruns any
+// budget
+type stuckPolicy struct {
+	release <-chan struct{}
+	started *atomic.Int32
+}
+
+func (p stuckPolicy) Act(*game.GameState, int) Action {
+	p.started.Add(1)
+	<-p.release
+	return TurnLeft
+}
+
+func TestTimedPolicyDoesNotWait(t *testing.T) {
+	const limit = 20 * time.Millisecond
+	const moves = 5
+	state := game.NewGame(config.DefaultGameConfig(), 1).State
+
+	for _, replace := range []bool{false, true} {
+		release := make(chan struct{})
+		var started, built atomic.Int32
+		newPolicy := func() Policy {
+			built.Add(1)
+			return stuckPolicy{release: release, started: &started}
+		}
+		p := NewTimedPolicy(newPolicy, limit)
+		if !replace {
+			p.New = nil
+		}
+
+		start := time.Now()
+		for range moves {
+			if got, want := p.Act(state, 0), SafeAction(state, 0); got != want {
+				t.Errorf("replace=%v: Act = %v, want the safe default %v", replace, got, want)
+			}
+		}
+		elapsed := time.Since(start)
+		close(release)
+
+		// Only searches that were started wait out the limit, and none
+		// waits for an earlier one
+		searches := 1
+		if replace {
+			searches = moves
+		}
+		if max := time.Duration(searches)*limit + 200*time.Millisecond; elapsed > max {
+			t.Errorf("replace=%v: %d moves took %v, want at most %v", replace, moves, elapsed, max)
+		}
+		if p.Timeouts != moves {
+			t.Errorf("replace=%v: Timeouts = %d, want %d", replace, p.Timeouts, moves)
+		}
+		if got := int(started.Load()); got != searches {
+			t.Errorf("replace=%v: %d searches started, want %d", replace, got, searches)
+		}
+		if got := int(built.Load()); got != searches {
+			t.Errorf("replace=%v: %d policies built, want %d", replace, got, searches)
+		}
+	}
+}
+
+func TestTimedPolicyResumesAfterLateSearch(t *testing.T) {
+	// A search that finishes late leaves the policy free for the next move
+	state := game.NewGame(config.DefaultGameConfig(), 1).State
+	release := make(chan struct{})
+	var started atomic.Int32
+	p := NewTimedPolicy(func() Policy { return stuckPolicy{release: release, started: &started} }, 20*time.Millisecond)
+	p.New = nil
+	p.Act(state, 0)
+	close(release)
+	for p.pending != nil && len(p.pending) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := p.Act(state, 0); got != TurnLeft {
+		t.Errorf("Act after the late search = %v, want the policy's %v", got, TurnLeft)
+	}
+	if started.Load() != 2 {
+		t.Errorf("%d searches started, want 2", started.Load())
+	}
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"math"
+
+	"autonomous-snake/internal/config"
+)
+
+// TraceAgent learns online with Watkins's Q(λ) instead of replaying
+// one-step transitions. Every snake keeps an eligibility trace, a decaying
+// sum of the gradients of the Q-values it chose, and each step's TD error
+// updates the network along that trace, so a reward reaches the moves that
+// led to it at once rather than one replayed step per backup. That matters
+// on the long runs between food pellets, where one-step backups need many
+// passes to carry the reward back to the turn that set the snake on course.
+//
+// Traces decay by Gamma·Lambda per step and are cut when a snake explores,
+// as an exploratory move says nothing about the greedy policy's return, and
+// when its trajectory ends. TD targets come from the target network like
+// DQNAgent's, and Loss shapes the TD error. Nothing is replayed, so the
+// replay buffer, prioritized replay and gradient workers go unused.
+type TraceAgent struct {
+	*DQNAgent
+
+	// Lambda weighs longer returns: 0 is one-step Q-learning, 1 follows
+	// the trace all the way back to the last exploratory move
+	Lambda float64
+
+	// Eligibility traces of the snakes being played, by snake ID
+	traces map[int]*gradients
+
+	// Update scratch
+	policyCache, targetCache *forwardCache
+	grads                    *gradients
+	dOutput                  []float64
+	loss                     float64 // Sum since the last Train call
+}
+
+// NewTraceAgent creates a Q(λ) agent with cfg.TraceLambda
+func NewTraceAgent(cfg config.TrainingConfig, seed int64) *TraceAgent {
+	return &TraceAgent{DQNAgent: NewDQNAgent(cfg, seed), Lambda: cfg.TraceLambda}
+}
+
+// Remember learns from a transition of snake 0, see RememberSnake
+func (t *TraceAgent) Remember(state []float64, action Action, reward float64, nextState []float64, done bool) {
+	t.RememberSnake(0, state, action, reward, nextState, done)
+}
+
+// RememberSnake adds the gradient of the taken action's Q-value to the
+// snake's trace and moves the weights along the trace by the TD error
+func (t *TraceAgent) RememberSnake(snakeID int, state []float64, action Action, reward float64, nextState []float64, done bool) {
+	if t.grads == nil {
+		t.policyCache, t.targetCache = newForwardCache(t.PolicyNet), newForwardCache(t.TargetNet)
+		t.grads = newGradients(t.PolicyNet)
+		t.dOutput = make([]float64, t.PolicyNet.OutputSize)
+		t.traces = make(map[int]*gradients)
+	}
+	trace := t.traces[snakeID]
+	if trace == nil {
+		trace = newGradients(t.PolicyNet)
+		t.traces[snakeID] = trace
+	}
+
+	exp := Experience{State: state, Action: action, Reward: reward, NextState: nextState, Done: done}
+	target := t.targetValue(exp, t.policyCache, t.targetCache)
+	q := t.PolicyNet.forwardWith(t.policyCache, state)
+	diff := q[action] - target
+	greedy := q[action] >= Max(q)
+	loss, grad := t.Loss.Loss(diff)
+
+	// e ← γλ·e + ∇Q(s,a)
+	clear(t.dOutput)
+	t.dOutput[action] = 1
+	t.grads.zero()
+	t.PolicyNet.accumulateGradients(t.policyCache, t.dOutput, t.grads)
+	decay := t.Gamma * t.Lambda
+	traceParams := trace.params()
+	for k, g := range t.grads.params() {
+		for i, v := range g {
+			traceParams[k][i] = decay*traceParams[k][i] + v
+		}
+	}
+
+	// The update is the loss gradient w.r.t. Q(s,a) times the trace
+	norm := gradientNorm(trace, math.Abs(grad))
+	scale := grad
+	if maxNorm := t.Loss.MaxGradNorm; maxNorm > 0 && norm > maxNorm {
+		scale *= maxNorm / norm
+	}
+	t.PolicyNet.applyGradients(trace, scale)
+	t.PolicyNet.decayWeights(t.Regularization.WeightDecay)
+
+	if done || !greedy {
+		trace.zero()
+	}
+	t.loss += loss
+	t.stats.updates++
+	t.stats.losses.add(loss)
+	t.stats.tdErrors.add(math.Abs(diff))
+	t.stats.gradNorms.add(norm)
+}
+
+// Train counts a step, following the exploration and learning rate
+// schedules and syncing the target network when due. The learning happened
+// in RememberSnake; Train returns the loss of the steps since its last
+// call.
+func (t *TraceAgent) Train() float64 {
+	t.StepCount++
+	if t.EpsilonSchedule != nil {
+		t.Epsilon = t.EpsilonSchedule.Epsilon(t.StepCount)
+	}
+	if t.LRSchedule != nil {
+		t.PolicyNet.LearningRate = t.LRSchedule.Rate(t.StepCount)
+	}
+	if t.StepCount%t.TargetUpdate == 0 {
+		t.UpdateTargetNetwork()
+	}
+	loss := t.loss
+	t.loss = 0
+	return loss
+}
+
+// DecayEpsilon ends the episode: every trace is cleared and exploration
+// decays like DQNAgent's
+func (t *TraceAgent) DecayEpsilon() {
+	for _, trace := range t.traces {
+		trace.zero()
+	}
+	t.DQNAgent.DecayEpsilon()
+}
+
+// Load replaces the agent's networks with the model at path, like
+// DQNAgent.Load
+func (t *TraceAgent) Load(path string) error {
+	if err := t.DQNAgent.Load(path); err != nil {
+		return err
+	}
+
+	// Scratch buffers and traces are sized for the old networks
+	t.grads, t.traces = nil, nil
+	return nil
+}
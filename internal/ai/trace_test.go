@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestTraceAgent(t *testing.T) {
+	// A corridor of 6 cells, rewarded only at its end
+	const length = 6
+	cfg := config.DefaultTrainingConfig()
+	cfg.HiddenSize1, cfg.HiddenSize2 = 16, 8
+	cfg.LearningRate = 0.01
+	cell := func(i int) []float64 {
+		state := make([]float64, cfg.InputSize)
+		state[i] = 1
+		return state
+	}
+	train := func(lambda, goal float64) *TraceAgent {
+		cfg.TraceLambda = lambda
+		agent := NewTraceAgent(cfg, 1)
+		agent.PolicyNet.B3[GoStraight] += 1 // Going straight is greedy
+		agent.UpdateTargetNetwork()
+		for range 5 {
+			for i := 0; i < length; i++ {
+				reward, done := 0.0, i == length-1
+				if done {
+					reward = goal
+				}
+				agent.RememberSnake(0, cell(i), GoStraight, reward, cell(min(i+1, length-1)), done)
+				agent.Train()
+			}
+			agent.DecayEpsilon()
+		}
+		return agent
+	}
+
+	// The trace carries the reward back to the start of the corridor,
+	// where one-step updates against the fixed target network only see it
+	// through weights the cells share
+	effect := func(lambda float64) float64 {
+		return train(lambda, 1).GetQValues(cell(0))[GoStraight] - train(lambda, 0).GetQValues(cell(0))[GoStraight]
+	}
+	if traced, oneStep := effect(0.9), effect(0); traced <= 2*oneStep {
+		t.Errorf("the reward raised the start's Q-value by %v with traces, %v without", traced, oneStep)
+	}
+
+	// An exploratory move cuts the trace
+	agent := train(0.9, 1)
+	agent.RememberSnake(1, cell(0), TurnLeft, 0, cell(1), false)
+	if gradientNorm(agent.traces[1], 1) != 0 {
+		t.Error("exploratory move left the trace in place")
+	}
+}
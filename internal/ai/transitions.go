@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TransitionFormatVersion is the current transition file version
+const TransitionFormatVersion = 1
+
+// transitionFormat identifies transition files
+const transitionFormat = "slitherrl-transitions"
+
+// Transition is one snake's experience from a logged game
+type Transition struct {
+	Game  int // Index of the game within the run
+	Turn  int
+	Snake int
+	Experience
+}
+
+// transitionHeader is written once at the start of a transition file
+type transitionHeader struct {
+	Format    string
+	Version   int
+	StateSize int
+}
+
+// TransitionWriter streams transitions to a gob-encoded file
+type TransitionWriter struct {
+	enc *gob.Encoder
+}
+
+// NewTransitionWriter writes the file header and returns a writer
+func NewTransitionWriter(w io.Writer) (*TransitionWriter, error) {
+	enc := gob.NewEncoder(w)
+	header := transitionHeader{Format: transitionFormat, Version: TransitionFormatVersion, StateSize: StateSize}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return &TransitionWriter{enc: enc}, nil
+}
+
+// Write appends a transition
+func (tw *TransitionWriter) Write(t Transition) error {
+	return tw.enc.Encode(t)
+}
+
+// TransitionReader reads transitions written by TransitionWriter
+type TransitionReader struct {
+	dec       *gob.Decoder
+	StateSize int
+}
+
+// NewTransitionReader checks the file header and returns a reader
+func NewTransitionReader(r io.Reader) (*TransitionReader, error) {
+	dec := gob.NewDecoder(r)
+	var header transitionHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("not a transition file: %w", err)
+	}
+	if header.Format != transitionFormat {
+		return nil, fmt.Errorf("not a transition file (format %q)", header.Format)
+	}
+	if header.Version > TransitionFormatVersion {
+		return nil, fmt.Errorf("transition file version %d is newer than supported version %d", header.Version, TransitionFormatVersion)
+	}
+	return &TransitionReader{dec: dec, StateSize: header.StateSize}, nil
+}
+
+// Read returns the next transition, or io.EOF at the end of the file
+func (tr *TransitionReader) Read() (Transition, error) {
+	var t Transition
+	err := tr.dec.Decode(&t)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return t, fmt.Errorf("truncated transition file: %w", err)
+	}
+	return t, err
+}
@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"autonomous-snake/internal/game"
+)
+
+// WindowEncoderName is the encoder that adds a window of the cells around
+// the head to the 22 features, see EncodeStateWindowInto. Followed by an
+// odd number, e.g. "window9", it names a window of that width.
+const WindowEncoderName = "window"
+
+// Window widths: DefaultWindow is what plain "window" shows, and widths
+// run from MinWindow to MaxWindow
+const (
+	DefaultWindow = 7
+	MinWindow     = 3
+	MaxWindow     = 15
+)
+
+// WindowEncoder returns the name of the encoder with a window n cells wide
+func WindowEncoder(n int) string {
+	if n == DefaultWindow {
+		return WindowEncoderName
+	}
+	return WindowEncoderName + strconv.Itoa(n)
+}
+
+// parseWindow returns the window encoder a name like "window9" asks for.
+// ok is false for other names.
+func parseWindow(name string) (enc Encoder, ok bool, err error) {
+	width, ok := strings.CutPrefix(name, WindowEncoderName)
+	if !ok || width == "" {
+		return nil, false, nil
+	}
+	n, err := strconv.Atoi(width)
+	if err != nil || n < MinWindow || n > MaxWindow || n%2 == 0 {
+		return nil, true, fmt.Errorf("state encoder %q needs an odd window width from %d to %d", name, MinWindow, MaxWindow)
+	}
+	return windowEncoder{n: n}, true, nil
+}
+
+// EncodeStateWindowInto writes the 22 features of EncodeStateInto and then
+// an n×n window into dst, which must have length StateSize+n*n. The window
+// is centered on the snake's head and turned so the snake heads up it, row
+// by row from the farthest row ahead; a cell is 1 where moving into it
+// would kill the snake, a wall or any body, like the danger features, and
+// 0 where it is free. Unlike those, which look two cells ahead, the window
+// sees as far on every board size. A dead snake sees all zeros.
+func EncodeStateWindowInto(dst []float64, state *game.GameState, snakeID int, n int) {
+	EncodeStateInto(dst, state, snakeID)
+	window := dst[StateSize : StateSize+n*n]
+	clear(window)
+
+	snake := state.Snakes[snakeID]
+	if !snake.Alive {
+		return
+	}
+	head := snake.Head()
+	ahead, right := directionOffset(snake.Direction), directionOffset(snake.Direction.TurnRight())
+	c := n / 2
+	for row := range n {
+		for col := range n {
+			forward, side := c-row, col-c
+			pos := head.Add(forward*ahead.X+side*right.X, forward*ahead.Y+side*right.Y)
+			if isDanger(pos, snakeID, state) {
+				window[row*n+col] = 1
+			}
+		}
+	}
+}
+
+// directionOffset returns the one-cell step in direction dir
+func directionOffset(dir game.Direction) game.Position {
+	return game.Position{}.Neighbor(dir)
+}
+
+// windowEncoder is the features-plus-window encoder
+type windowEncoder struct {
+	n int // Window width
+}
+
+// Name returns the encoder's name
+func (e windowEncoder) Name() string { return WindowEncoder(e.n) }
+
+// Size returns the number of features and window cells
+func (e windowEncoder) Size() int { return StateSize + e.n*e.n }
+
+// Encode writes the features and window into dst
+func (e windowEncoder) Encode(dst []float64, state *game.GameState, snakeID int) {
+	EncodeStateWindowInto(dst, state, snakeID, e.n)
+}
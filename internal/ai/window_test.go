@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestWindowEncoder(t *testing.T) {
+	// Snake 0 heads left along the top wall of a 6x4 board, with snake 1
+	// below it
+	state := &game.GameState{
+		Width:  6,
+		Height: 4,
+		Snakes: [2]*game.Snake{
+			{ID: 0, Body: []game.Position{{X: 2, Y: 0}, {X: 3, Y: 0}, {X: 4, Y: 0}}, Direction: game.Left, Alive: true},
+			{ID: 1, Body: []game.Position{{X: 2, Y: 2}, {X: 3, Y: 2}}, Direction: game.Left, Alive: true},
+		},
+		Food: game.Food{Position: game.Position{X: 5, Y: 3}, Active: true},
+	}
+	enc, err := LookupEncoder(WindowEncoder(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Name() != "window3" || enc.Size() != StateSize+9 {
+		t.Fatalf("encoder %s has size %d", enc.Name(), enc.Size())
+	}
+	dst := make([]float64, enc.Size())
+	enc.Encode(dst, state, 0)
+
+	features := EncodeState(state, 0)
+	for i, v := range features {
+		if dst[i] != v {
+			t.Fatalf("feature %d is %v, want %v", i, dst[i], v)
+		}
+	}
+
+	// Turned so the snake heads up: the wall is on its right, its body
+	// behind it and the opponent two cells to its left
+	want := []float64{
+		0, 0, 1,
+		0, 0, 1,
+		0, 1, 1,
+	}
+	for i, v := range want {
+		if got := dst[StateSize+i]; got != v {
+			t.Errorf("window cell (%d,%d) is %v, want %v", i%3, i/3, got, v)
+		}
+	}
+
+	// The opponent is in the 5x5 window
+	wide := make([]float64, StateSize+25)
+	EncodeStateWindowInto(wide, state, 0, 5)
+	if wide[StateSize+2*5+0] != 1 {
+		t.Error("5x5 window misses the opponent's head")
+	}
+
+	if enc, err := LookupEncoder(WindowEncoderName); err != nil || enc.Size() != StateSize+DefaultWindow*DefaultWindow {
+		t.Errorf("LookupEncoder(%q) = %v, %v", WindowEncoderName, enc, err)
+	}
+	for _, name := range []string{"window4", "window1", "window17", "windowx"} {
+		if _, err := LookupEncoder(name); err == nil {
+			t.Errorf("LookupEncoder(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package analysis summarizes logged agent decisions to find uncertain
+// choices and systematic biases.
+package analysis
+
+import (
+	"sort"
+
+	"autonomous-snake/internal/ai"
+)
+
+// Summary describes a set of logged decisions
+type Summary struct {
+	Decisions int
+	Chosen    [ai.NumActions]int     // Actions actually played
+	Greedy    [ai.NumActions]int     // Highest-valued actions
+	Explored  int                    // Decisions where the played action wasn't greedy
+	MeanQ     [ai.NumActions]float64 // Average value of each action
+	MeanGap   float64                // Average best-minus-second margin
+
+	// Near ties are decisions whose margin is below the tie threshold; an
+	// unbiased network would break them evenly between left and right
+	NearTies      int
+	NearTieGreedy [ai.NumActions]int
+
+	Uncertain []ai.Decision // Smallest margins first
+	Contested []Contested   // Most visits first
+}
+
+// Contested is a position where the same snake played different actions
+// on different visits
+type Contested struct {
+	Hash    uint64
+	Snake   int
+	Visits  int
+	Actions [ai.NumActions]int
+	MeanQ   [ai.NumActions]float64
+}
+
+// TurnBias returns the share of left turns among left and right turns in
+// counts, 0.5 meaning no preference
+func TurnBias(counts [ai.NumActions]int) float64 {
+	turns := counts[ai.TurnLeft] + counts[ai.TurnRight]
+	if turns == 0 {
+		return 0.5
+	}
+	return float64(counts[ai.TurnLeft]) / float64(turns)
+}
+
+// Summarize analyzes decisions, listing up to top uncertain decisions and
+// contested positions. Margins below tieMargin count as near ties.
+func Summarize(decisions []ai.Decision, top int, tieMargin float64) Summary {
+	s := Summary{Decisions: len(decisions)}
+	if len(decisions) == 0 {
+		return s
+	}
+
+	type key struct {
+		hash  uint64
+		snake int
+	}
+	positions := make(map[key]*Contested)
+
+	for _, d := range decisions {
+		greedy := d.Greedy()
+		margin := d.Margin()
+		s.Chosen[d.Action]++
+		s.Greedy[greedy]++
+		if d.Action != greedy {
+			s.Explored++
+		}
+		for a, q := range d.Q {
+			s.MeanQ[a] += q
+		}
+		s.MeanGap += margin
+		if margin < tieMargin {
+			s.NearTies++
+			s.NearTieGreedy[greedy]++
+		}
+
+		k := key{d.Hash, d.Snake}
+		c := positions[k]
+		if c == nil {
+			c = &Contested{Hash: d.Hash, Snake: d.Snake}
+			positions[k] = c
+		}
+		c.Visits++
+		c.Actions[d.Action]++
+		for a, q := range d.Q {
+			c.MeanQ[a] += q
+		}
+	}
+
+	n := float64(len(decisions))
+	for a := range s.MeanQ {
+		s.MeanQ[a] /= n
+	}
+	s.MeanGap /= n
+
+	uncertain := append([]ai.Decision(nil), decisions...)
+	sort.SliceStable(uncertain, func(i, j int) bool { return uncertain[i].Margin() < uncertain[j].Margin() })
+	s.Uncertain = uncertain[:min(top, len(uncertain))]
+
+	for _, c := range positions {
+		distinct := 0
+		for _, count := range c.Actions {
+			if count > 0 {
+				distinct++
+			}
+		}
+		if distinct < 2 {
+			continue
+		}
+		for a := range c.MeanQ {
+			c.MeanQ[a] /= float64(c.Visits)
+		}
+		s.Contested = append(s.Contested, *c)
+	}
+	sort.Slice(s.Contested, func(i, j int) bool {
+		if s.Contested[i].Visits != s.Contested[j].Visits {
+			return s.Contested[i].Visits > s.Contested[j].Visits
+		}
+		return s.Contested[i].Hash < s.Contested[j].Hash
+	})
+	s.Contested = s.Contested[:min(top, len(s.Contested))]
+	return s
+}
@@ -0,0 +1,33 @@
+package analysis
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/ai"
+)
+
+func TestSummarize(t *testing.T) {
+	decisions := []ai.Decision{
+		{Hash: 1, Q: []float64{1, 0.995, 0}, Action: ai.GoStraight},
+		{Hash: 1, Q: []float64{1, 0.995, 0}, Action: ai.TurnLeft},
+		{Hash: 2, Q: []float64{0, 0.5, 1}, Action: ai.TurnRight},
+		{Hash: 3, Snake: 1, Q: []float64{0, 0.2, 0.2}, Action: ai.TurnLeft},
+	}
+	s := Summarize(decisions, 2, 0.01)
+
+	if s.Explored != 1 {
+		t.Errorf("expected 1 non-greedy decision, got %d", s.Explored)
+	}
+	if s.NearTies != 3 {
+		t.Errorf("expected 3 near ties, got %d", s.NearTies)
+	}
+	if len(s.Uncertain) != 2 || s.Uncertain[0].Hash != 3 {
+		t.Errorf("expected the exact tie first, got %+v", s.Uncertain)
+	}
+	if len(s.Contested) != 1 || s.Contested[0].Hash != 1 || s.Contested[0].Visits != 2 {
+		t.Errorf("expected position 1 to be contested, got %+v", s.Contested)
+	}
+	if got := TurnBias(s.Chosen); got != 2.0/3 {
+		t.Errorf("expected left share 2/3, got %v", got)
+	}
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+
+	"autonomous-snake/internal/game"
+)
+
+// Heatmap counts head visits and deaths per board cell
+type Heatmap struct {
+	Width, Height int
+	Visits        []int // Row-major, one entry per cell
+	Deaths        []int
+}
+
+// NewHeatmap creates an empty heatmap for a board
+func NewHeatmap(width, height int) *Heatmap {
+	return &Heatmap{
+		Width:  width,
+		Height: height,
+		Visits: make([]int, width*height),
+		Deaths: make([]int, width*height),
+	}
+}
+
+// index returns the cell index of pos, clamped onto the board so deaths
+// against a wall count at the wall cell
+func (h *Heatmap) index(pos game.Position) int {
+	x := min(max(pos.X, 0), h.Width-1)
+	y := min(max(pos.Y, 0), h.Height-1)
+	return y*h.Width + x
+}
+
+// Record counts the head of every living snake, and the death location of
+// every snake that died in the step that produced state
+func (h *Heatmap) Record(state *game.GameState, last game.StepResult) {
+	for i, snake := range state.Snakes {
+		if snake.Alive {
+			h.Visits[h.index(snake.Head())]++
+		} else if last.Died[i] {
+			h.Deaths[h.index(snake.Head())]++
+		}
+	}
+}
+
+// Add merges another heatmap of the same size into h
+func (h *Heatmap) Add(other *Heatmap) error {
+	if other.Width != h.Width || other.Height != h.Height {
+		return fmt.Errorf("heatmap size %dx%d does not match %dx%d", other.Width, other.Height, h.Width, h.Height)
+	}
+	for i := range h.Visits {
+		h.Visits[i] += other.Visits[i]
+		h.Deaths[i] += other.Deaths[i]
+	}
+	return nil
+}
+
+// WriteCSV writes counts as a grid with one CSV row per board row
+func (h *Heatmap) WriteCSV(w io.Writer, counts []int) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, h.Width)
+	for y := 0; y < h.Height; y++ {
+		for x := 0; x < h.Width; x++ {
+			row[x] = strconv.Itoa(counts[y*h.Width+x])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WritePNG draws counts as a heat image with scale pixels per cell, from
+// dark blue for unvisited cells to yellow for the busiest one
+func (h *Heatmap) WritePNG(w io.Writer, counts []int, scale int) error {
+	peak := 0
+	for _, c := range counts {
+		peak = max(peak, c)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, h.Width*scale, h.Height*scale))
+	for y := 0; y < h.Height; y++ {
+		for x := 0; x < h.Width; x++ {
+			t := 0.0
+			if peak > 0 {
+				t = float64(counts[y*h.Width+x]) / float64(peak)
+			}
+			c := heatColor(t)
+			for py := y * scale; py < (y+1)*scale; py++ {
+				for px := x * scale; px < (x+1)*scale; px++ {
+					img.Set(px, py, c)
+				}
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// heatColor maps t in [0, 1] through dark blue, red and yellow
+func heatColor(t float64) color.RGBA {
+	lerp := func(a, b uint8, t float64) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t) }
+	if t < 0.5 {
+		t *= 2
+		return color.RGBA{lerp(20, 220, t), lerp(20, 40, t), lerp(60, 40, t), 255}
+	}
+	t = (t - 0.5) * 2
+	return color.RGBA{lerp(220, 255, t), lerp(40, 230, t), lerp(40, 60, t), 255}
+}
@@ -0,0 +1,32 @@
+package analysis
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestHeatmap(t *testing.T) {
+	state := &game.GameState{Width: 4, Height: 3}
+	state.Snakes[0] = game.NewSnake(0, game.Position{X: 1, Y: 1}, game.Right, 1)
+	state.Snakes[1] = game.NewSnake(1, game.Position{X: 4, Y: 2}, game.Right, 1)
+	state.Snakes[1].Alive = false
+	last := game.StepResult{Died: [2]bool{false, true}}
+
+	h := NewHeatmap(4, 3)
+	h.Record(state, last)
+	if h.Visits[1*4+1] != 1 {
+		t.Errorf("expected a visit at (1,1), got %v", h.Visits)
+	}
+	// A snake that ran off the board dies at the wall cell
+	if h.Deaths[2*4+3] != 1 {
+		t.Errorf("expected a death at (3,2), got %v", h.Deaths)
+	}
+
+	if err := h.Add(h); err != nil || h.Visits[5] != 2 {
+		t.Errorf("expected merged visits, got %v (err %v)", h.Visits, err)
+	}
+	if err := h.Add(NewHeatmap(3, 3)); err == nil {
+		t.Error("expected size mismatch error")
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/analysis"
+)
+
+func init() {
+	Register(Command{
+		Name:    "analyze",
+		Summary: "Find uncertain decisions and action biases in a decision log",
+		Run:     runAnalyze,
+	})
+}
+
+// runAnalyze implements the analyze subcommand
+func runAnalyze(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("analyze")
+	inPath := fs.String("in", "data/decisions.jsonl", "Decision log written by selfplay -decisions")
+	top := fs.Int("top", 10, "Uncertain decisions and contested positions to list")
+	tieMargin := fs.Float64("tie-margin", 0.01, "Q-value margin below which a decision counts as a near tie")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	file, err := os.Open(*inPath)
+	if err != nil {
+		return err
+	}
+	decisions, err := ai.ReadDecisions(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", *inPath, err)
+	}
+	if len(decisions) == 0 {
+		return fmt.Errorf("%s holds no decisions", *inPath)
+	}
+
+	s := analysis.Summarize(decisions, *top, *tieMargin)
+	n := float64(s.Decisions)
+
+	fmt.Printf("=== Decisions ===\n")
+	fmt.Printf("Decisions: %d  Explored (non-greedy): %d (%.1f%%)\n", s.Decisions, s.Explored, 100*float64(s.Explored)/n)
+	fmt.Printf("Mean margin between best and second action: %.4f\n\n", s.MeanGap)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tPLAYED\tGREEDY\tNEAR-TIE GREEDY\tMEAN Q")
+	for a := ai.Action(0); a < ai.NumActions; a++ {
+		fmt.Fprintf(w, "%s\t%.1f%%\t%.1f%%\t%s\t%+.4f\n", a, 100*float64(s.Chosen[a])/n, 100*float64(s.Greedy[a])/n,
+			share(s.NearTieGreedy[a], s.NearTies), s.MeanQ[a])
+	}
+	w.Flush()
+
+	fmt.Printf("\n=== Biases ===\n")
+	fmt.Printf("Left share of turns played: %.1f%%\n", 100*analysis.TurnBias(s.Chosen))
+	if s.NearTies > 0 {
+		fmt.Printf("Left share of turns in %d near ties: %.1f%%\n", s.NearTies, 100*analysis.TurnBias(s.NearTieGreedy))
+	}
+
+	fmt.Printf("\n=== Most Uncertain Decisions ===\n")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GAME\tTURN\tSNAKE\tHASH\tQ (S/L/R)\tMARGIN\tPLAYED")
+	for _, d := range s.Uncertain {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%016x\t%s\t%.5f\t%s\n", d.Game, d.Turn, d.Snake, d.Hash, formatQ(d.Q[:]), d.Margin(), d.Action)
+	}
+	w.Flush()
+
+	if len(s.Contested) > 0 {
+		fmt.Printf("\n=== Contested Positions ===\n")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "HASH\tSNAKE\tVISITS\tPLAYED (S/L/R)\tMEAN Q (S/L/R)")
+		for _, c := range s.Contested {
+			fmt.Fprintf(w, "%016x\t%d\t%d\t%d/%d/%d\t%s\n", c.Hash, c.Snake, c.Visits,
+				c.Actions[ai.GoStraight], c.Actions[ai.TurnLeft], c.Actions[ai.TurnRight], formatQ(c.MeanQ[:]))
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// share formats count as a percentage of total
+func share(count, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(count)/float64(total))
+}
+
+// formatQ formats Q-values compactly
+func formatQ(q []float64) string {
+	parts := make([]string, len(q))
+	for i, v := range q {
+		parts[i] = fmt.Sprintf("%+.3f", v)
+	}
+	return strings.Join(parts, " ")
+}
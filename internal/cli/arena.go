@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "arena",
+		Summary: "Play a round-robin tournament between models and bots",
+		Run:     runArena,
+	})
+}
+
+// arenaEntrant is one policy's tally over the tournament
+type arenaEntrant struct {
+	name               string
+	games              int
+	wins, losses, ties int
+}
+
+// score returns the entrant's share of points, ties counting half
+func (e arenaEntrant) score() float64 {
+	if e.games == 0 {
+		return 0
+	}
+	return (float64(e.wins) + float64(e.ties)/2) / float64(e.games)
+}
+
+// runArena implements the arena subcommand
+func runArena(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("arena")
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	models := fs.String("models", "", `Comma-separated entrants: model files, "random", "mcts" or "mcts:MODEL"`)
+	pairs := fs.Int("pairs", 10, "Seeds every pairing plays; each is played twice with the sides swapped")
+	workers := fs.Int("workers", runtime.NumCPU(), "Games played in parallel")
+	maxTurns := fs.Int("max-turns", 1000, "Turns before a game is declared a tie")
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for mcts policies")
+	moveLimit := fs.Duration("move-limit", 0, "Play a safe default move when a policy takes longer than this (0 for no limit)")
+	resultsPath := fs.String("results", "", "Record every game in this results database, updating its ladder")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range strings.Split(*models, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) < 2 || *pairs < 1 {
+		fmt.Fprintln(fs.Output(), "-models needs at least two entrants and -pairs at least one seed")
+		fs.Usage()
+		return errUsage
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	seed := gameFlags.ResolveSeed()
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+	factories := make([]PolicyFactory, len(names))
+	entrants := make([]arenaEntrant, len(names))
+	for i, name := range names {
+		if factories[i], err = LoadPolicy(name, 0, *mctsBudget); err != nil {
+			return err
+		}
+		entrants[i].name = name
+	}
+
+	pairings := len(names) * (len(names) - 1) / 2
+	log.Printf("Arena: %d entrants, %d pairings of %d seeds x 2 sides on %d workers", len(names), pairings, *pairs, *workers)
+	startTime := time.Now()
+
+	// Every pairing plays the same seeds, so all entrants face the same
+	// boards
+	var matches []*results.Match
+	for a := range names {
+		for b := a + 1; b < len(names); b++ {
+			played := playEvalPairs(gameCfg, [2]PolicyFactory{factories[a], factories[b]}, *pairs, 2, *workers, seed, *maxTurns, *moveLimit)
+			var aWins, bWins, ties int
+			for _, pair := range played {
+				for g := range pair.games {
+					switch pair.scoreA(g) {
+					case 1:
+						aWins++
+					case 0:
+						bWins++
+					default:
+						ties++
+					}
+					if *resultsPath != "" {
+						bySnake := [2]string{names[a], names[b]}
+						if g == 1 {
+							bySnake = [2]string{names[b], names[a]}
+						}
+						m := results.NewMatch(results.SourceArena, bySnake, pair.seed, 2*pair.idx+g, pair.games[g].state, pair.games[g].last)
+						matches = append(matches, &m)
+					}
+				}
+			}
+			games := 2 * len(played)
+			entrants[a].games += games
+			entrants[a].wins += aWins
+			entrants[a].losses += bWins
+			entrants[a].ties += ties
+			entrants[b].games += games
+			entrants[b].wins += bWins
+			entrants[b].losses += aWins
+			entrants[b].ties += ties
+			log.Printf("%s vs %s: %d-%d, %d ties", names[a], names[b], aWins, bWins, ties)
+		}
+	}
+
+	if *resultsPath != "" {
+		if err := recordMatches(*resultsPath, matches); err != nil {
+			return err
+		}
+	}
+
+	sort.SliceStable(entrants, func(i, j int) bool { return entrants[i].score() > entrants[j].score() })
+	fmt.Printf("\n=== Arena (%v) ===\n", time.Since(startTime).Round(time.Second))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tMODEL\tGAMES\tWINS\tLOSSES\tTIES\tSCORE")
+	for i, e := range entrants {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%.1f%%\n", i+1, e.name, e.games, e.wins, e.losses, e.ties, 100*e.score())
+	}
+	return w.Flush()
+}
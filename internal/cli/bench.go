@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func init() {
+	Register(Command{
+		Name:    "bench",
+		Summary: "Measure game, inference and training throughput for a configuration",
+		Run:     runBench,
+	})
+}
+
+// runBench implements the bench subcommand
+func runBench(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("bench")
+	trainCfg := config.DefaultTrainingConfig()
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	duration := fs.Duration("duration", 2*time.Second, "How long to run each measurement")
+	fs.StringVar(&trainCfg.Encoder, "encoder", ai.DefaultEncoderName, "State encoder: features, grid, window or food")
+	fs.BoolVar(&trainCfg.Dueling, "dueling", false, "Use a dueling network")
+	fs.IntVar(&trainCfg.BatchSize, "batch", trainCfg.BatchSize, "Training batch size")
+	fs.IntVar(&trainCfg.GradWorkers, "workers", 1, "Goroutines computing batch gradients (>1 uses averaged mini-batch updates, 0 one per GOMAXPROCS)")
+	fs.BoolVar(&trainCfg.MiniBatch, "minibatch", false, "Apply one averaged update per batch instead of one per sample (implied by -workers above 1)")
+	fs.StringVar(&trainCfg.Backend, "backend", "go", "Linear algebra backend (builds with -tags blas add \"blas\")")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *duration <= 0 || trainCfg.BatchSize < 1 {
+		fs.Usage()
+		return errUsage
+	}
+	seed := gameFlags.ResolveSeed()
+
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+	trainCfg.GradWorkers = resolveWorkers(trainCfg.GradWorkers)
+
+	// Hyperparameters without a flag can still be set from the environment
+	if err := applyConfigEnv(fs, &trainCfg); err != nil {
+		return fmt.Errorf("invalid environment override: %w", err)
+	}
+	if err := ai.SetBackend(trainCfg.Backend); err != nil {
+		return err
+	}
+	encoder, err := ai.LookupEncoder(trainCfg.Encoder)
+	if err != nil {
+		return err
+	}
+	trainCfg.InputSize = encoder.Size()
+	if ai.BaseEncoderName(encoder.Name()) == ai.GridEncoderName && max(gameCfg.BoardWidth, gameCfg.BoardHeight) > ai.GridMaxBoard {
+		return fmt.Errorf("the grid encoder only shows boards up to %dx%d", ai.GridMaxBoard, ai.GridMaxBoard)
+	}
+	agent := ai.NewDQNAgent(trainCfg, seed)
+	agent.TrainInterval = 1
+
+	fmt.Printf("%dx%d board, %d food, %s network on %s, %s backend, batch %d, %d workers, %v per measurement\n",
+		gameCfg.BoardWidth, gameCfg.BoardHeight, gameCfg.FoodCount, agent.PolicyNet.Architecture(), encoder.Name(),
+		ai.ActiveBackend(), trainCfg.BatchSize, trainCfg.GradWorkers, *duration)
+
+	// Game steps with random moves
+	g := game.NewGame(gameCfg, seed)
+	rng := rand.New(rand.NewSource(seed))
+	randomActions := func() (actions [2]ai.Action) {
+		for id := range actions {
+			actions[id] = ai.Action(rng.Intn(int(ai.NumActions)))
+		}
+		return actions
+	}
+	step := func(actions [2]ai.Action) {
+		if g.State.GameOver {
+			g.Reset()
+		}
+		var directions [2]game.Direction
+		for id, snake := range g.State.Snakes {
+			directions[id] = ai.ActionToDirection(snake.Direction, actions[id])
+		}
+		g.Step(directions)
+	}
+	measureRate("game steps", 1, *duration, func() { step(randomActions()) })
+
+	// Game steps with both snakes encoding the state and picking a move
+	policy := ai.NewNetworkPolicy(agent.PolicyNet, 0, seed)
+	measureRate("policy steps", 1, *duration, func() {
+		step([2]ai.Action{policy.Act(g.State, 0), policy.Act(g.State, 1)})
+	})
+
+	// Batch updates from a buffer of random play
+	state, next := make([]float64, encoder.Size()), make([]float64, encoder.Size())
+	for agent.ReplayBuffer.Size() < trainCfg.BatchSize*10 {
+		if g.State.GameOver {
+			g.Reset()
+		}
+		encoder.Encode(state, g.State, 0)
+		actions := randomActions()
+		step(actions)
+		encoder.Encode(next, g.State, 0)
+		agent.Remember(append([]float64(nil), state...), actions[0], rng.Float64()*2-1, append([]float64(nil), next...), g.State.GameOver)
+	}
+	measureRate("training steps", trainCfg.BatchSize, *duration, func() { agent.TrainBatch() })
+	return nil
+}
+
+// measureRate runs op for about d and prints how many times a second it
+// ran, and how many samples a second that is when each run handles more
+// than one
+func measureRate(name string, samples int, d time.Duration, op func()) {
+	runs := 0
+	start := time.Now()
+	for time.Since(start) < d {
+		for range 16 {
+			op()
+		}
+		runs += 16
+	}
+	perSec := float64(runs) / time.Since(start).Seconds()
+	if samples > 1 {
+		fmt.Printf("%-15s %12.0f/s  (%.0f samples/s)\n", name, perSec, perSec*float64(samples))
+		return
+	}
+	fmt.Printf("%-15s %12.0f/s\n", name, perSec)
+}
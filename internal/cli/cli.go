@@ -0,0 +1,148 @@
+// Package cli implements the subcommands of the slither binary. The
+// standalone cmd/train, cmd/play and cmd/convert mains are thin wrappers
+// around the same commands.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"autonomous-snake/internal/config"
+)
+
+// Command is a single slither subcommand
+type Command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+// commands holds every registered subcommand by name
+var commands = map[string]Command{}
+
+// Register adds a subcommand. It panics on duplicate names since that is
+// always a programming error.
+func Register(cmd Command) {
+	if _, exists := commands[cmd.Name]; exists {
+		panic("cli: duplicate command " + cmd.Name)
+	}
+	commands[cmd.Name] = cmd
+}
+
+// Commands returns the registered subcommands sorted by name
+func Commands() []Command {
+	list := make([]Command, 0, len(commands))
+	for _, cmd := range commands {
+		list = append(list, cmd)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Run executes the named subcommand and returns a process exit code
+func Run(name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "slither: unknown command %q\n\n", name)
+		Usage(os.Stderr)
+		return 2
+	}
+
+	err := cmd.Run(args)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "slither %s: %v\n", name, err)
+	return 1
+}
+
+// Main dispatches os.Args-style arguments (without the program name) to a
+// subcommand and returns a process exit code
+func Main(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		Usage(os.Stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	return Run(args[0], args[1:])
+}
+
+// Usage prints the list of subcommands
+func Usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: slither <command> [flags]\n\nCommands:\n")
+	for _, cmd := range Commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "\nRun 'slither <command> -h' for command flags.\n")
+}
+
+// errUsage signals that a command was invoked incorrectly and has already
+// printed its usage
+var errUsage = errors.New("invalid usage")
+
+// NewFlagSet creates a flag set for a subcommand that reports errors to the
+// caller instead of exiting
+func NewFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("slither "+name, flag.ContinueOnError)
+}
+
+// ParseFlags parses args into fs, mapping parse failures to errUsage so Run
+// exits with status 2 without printing the error twice. Afterwards every
+// flag left off the command line takes the value of its SLITHER_*
+// environment variable (e.g. -save-freq and SLITHER_SAVE_FREQ) if set, so
+// containerized jobs can be configured without changing their command line.
+func ParseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return applyEnvFlags(fs)
+}
+
+// applyEnvFlags sets the flags not given on the command line from their
+// SLITHER_* environment variables
+func applyEnvFlags(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] {
+			return
+		}
+		key := config.EnvName(f.Name)
+		if raw, ok := os.LookupEnv(key); ok {
+			if setErr := fs.Set(f.Name, raw); setErr != nil {
+				err = fmt.Errorf("%s: %w", key, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// applyConfigEnv overrides the settings of cfg without a flag in fs from
+// their SLITHER_* environment variables, see config.ApplyEnv. Settings a
+// flag of fs is bound to answer to the flag's variable alone, which
+// ParseFlags has applied.
+func applyConfigEnv(fs *flag.FlagSet, cfg *config.TrainingConfig) error {
+	bound := map[uintptr]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if v := reflect.ValueOf(f.Value); v.Kind() == reflect.Pointer {
+			bound[v.Pointer()] = f.Name
+		}
+	})
+	return config.ApplyEnv(cfg, bound)
+}
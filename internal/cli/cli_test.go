@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestParseFlagsEnv(t *testing.T) {
+	tests := []struct {
+		args []string
+		env  map[string]string
+
+		episodes int
+		algo     string
+		gamma    float64
+		err      string // Part of the error, if any
+	}{
+		{episodes: 10, algo: "dqn", gamma: 0.99},
+		// Variables named after flags set them, and those without a flag
+		// the config fields named after them
+		{env: map[string]string{"SLITHER_EPISODES": "50", "SLITHER_ALGO": "c51", "SLITHER_GAMMA": "0.9"}, episodes: 50, algo: "c51", gamma: 0.9},
+		// Flags on the command line win
+		{args: []string{"-episodes", "7"}, env: map[string]string{"SLITHER_EPISODES": "50"}, episodes: 7, algo: "dqn", gamma: 0.99},
+		{args: []string{"-episodes", "7"}, env: map[string]string{"SLITHER_EPISODES": "many"}, episodes: 7, algo: "dqn", gamma: 0.99},
+		// Bad values name their variable
+		{env: map[string]string{"SLITHER_EPISODES": "many"}, err: "SLITHER_EPISODES"},
+		{env: map[string]string{"SLITHER_GAMMA": "high"}, err: "SLITHER_GAMMA"},
+		// A field with a flag answers to the flag's variable only
+		{env: map[string]string{"SLITHER_ALGORITHM": "c51"}, err: "through SLITHER_ALGO"},
+	}
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			fs := NewFlagSet("test")
+			cfg := config.DefaultTrainingConfig()
+			fs.IntVar(&cfg.Episodes, "episodes", 10, "")
+			fs.StringVar(&cfg.Algorithm, "algo", config.AlgoDQN, "")
+			err := ParseFlags(fs, tt.args)
+			if err == nil {
+				err = applyConfigEnv(fs, &cfg)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("%v %v: error %v, want one naming %s", tt.args, tt.env, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%v %v: %v", tt.args, tt.env, err)
+			}
+			if cfg.Episodes != tt.episodes || cfg.Algorithm != tt.algo || cfg.Gamma != tt.gamma {
+				t.Errorf("%v %v: episodes %d, algo %s, gamma %v; want %d, %s, %v", tt.args, tt.env,
+					cfg.Episodes, cfg.Algorithm, cfg.Gamma, tt.episodes, tt.algo, tt.gamma)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+
+	"autonomous-snake/internal/config"
+)
+
+// applyConfigFile loads the game and training settings of the YAML or TOML
+// file at path (see config.LoadFile) over the flags' values, then sets the
+// flags given on the command line or by SLITHER_* variables again so they
+// win over the file
+func applyConfigFile(fs *flag.FlagSet, path string, gameFlags *GameFlags, trainCfg *config.TrainingConfig) error {
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
+
+	gameCfg := gameFlags.config(0)
+	if err := config.LoadFile(path, &gameCfg, trainCfg); err != nil {
+		return err
+	}
+	gameFlags.SetConfig(gameCfg)
+	for name, value := range set {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+	// -board sizes both sides unless -width or -height sizes one
+	if _, ok := set["board"]; ok {
+		if _, ok := set["width"]; !ok {
+			gameFlags.Width = 0
+		}
+		if _, ok := set["height"]; !ok {
+			gameFlags.Height = 0
+		}
+	}
+	return nil
+}
+
+// configFilePath returns where train writes the settings a model was
+// trained with: beside the model, with a .yaml extension
+func configFilePath(modelPath string) string {
+	return strings.TrimSuffix(modelPath, filepath.Ext(modelPath)) + ".yaml"
+}
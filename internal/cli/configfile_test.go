@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.yaml")
+	content := `
+game:
+  board_width: 12
+  board_height: 12
+  food_count: 3
+  max_turns: 400
+training:
+  episodes: 2000
+  gamma: 0.9
+  learning_rate: 0.0005
+  grad_workers: 4
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		env  map[string]string
+
+		width, height, food, turns int
+		episodes, workers          int
+	}{
+		// The file beats the flag defaults
+		{width: 12, height: 12, food: 3, turns: 400, episodes: 2000, workers: 4},
+		// Flags beat the file
+		{args: []string{"-food", "2", "-episodes", "50"}, width: 12, height: 12, food: 2, turns: 400, episodes: 50, workers: 4},
+		// -board sizes both sides over the file's width and height
+		{args: []string{"-board", "16"}, width: 16, height: 16, food: 3, turns: 400, episodes: 2000, workers: 4},
+		{args: []string{"-board", "16", "-height", "10"}, width: 16, height: 10, food: 3, turns: 400, episodes: 2000, workers: 4},
+		// An explicit flag wins even when it repeats its default
+		{args: []string{"-turn-limit", "0", "-workers", "1"}, width: 12, height: 12, food: 3, turns: 0, episodes: 2000, workers: 1},
+		// So do SLITHER_* variables, which stay set for the rest of the test
+		{env: map[string]string{"SLITHER_EPISODES": "75"}, width: 12, height: 12, food: 3, turns: 400, episodes: 75, workers: 4},
+	}
+	for _, tt := range tests {
+		for key, value := range tt.env {
+			t.Setenv(key, value)
+		}
+		fs := NewFlagSet("test")
+		var gameFlags GameFlags
+		gameFlags.Register(fs)
+		trainCfg := config.DefaultTrainingConfig()
+		fs.IntVar(&trainCfg.Episodes, "episodes", 10000, "")
+		fs.IntVar(&trainCfg.GradWorkers, "workers", 1, "")
+		if err := ParseFlags(fs, tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if err := applyConfigFile(fs, path, &gameFlags, &trainCfg); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+
+		gameCfg, err := gameFlags.GameConfig(20)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if gameCfg.BoardWidth != tt.width || gameCfg.BoardHeight != tt.height || gameCfg.FoodCount != tt.food || gameCfg.MaxTurns != tt.turns {
+			t.Errorf("%v %v: board %dx%d, %d food, %d turns; want %dx%d, %d, %d", tt.args, tt.env,
+				gameCfg.BoardWidth, gameCfg.BoardHeight, gameCfg.FoodCount, gameCfg.MaxTurns, tt.width, tt.height, tt.food, tt.turns)
+		}
+		if trainCfg.Episodes != tt.episodes || trainCfg.GradWorkers != tt.workers {
+			t.Errorf("%v %v: %d episodes, %d workers; want %d, %d", tt.args, tt.env, trainCfg.Episodes, trainCfg.GradWorkers, tt.episodes, tt.workers)
+		}
+		// Settings without a flag come from the file
+		if trainCfg.Gamma != 0.9 || trainCfg.LearningRate != 0.0005 {
+			t.Errorf("%v: gamma %v, learning rate %v; want the file's", tt.args, trainCfg.Gamma, trainCfg.LearningRate)
+		}
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	tests := map[string]string{
+		"models/snake_dqn.gob": "models/snake_dqn.yaml",
+		"run/" + runModelFile:  "run/" + runConfigFile,
+		"model":                "model.yaml",
+	}
+	for in, want := range tests {
+		if got := configFilePath(in); got != want {
+			t.Errorf("configFilePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
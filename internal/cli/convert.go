@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"time"
+
+	"autonomous-snake/internal/ai"
+)
+
+func init() {
+	Register(Command{
+		Name:    "convert",
+		Summary: "Upgrade or convert a saved model between formats",
+		Run:     runConvert,
+	})
+}
+
+// runConvert implements the convert subcommand
+func runConvert(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("convert")
+	inPath := fs.String("in", "", "Path of the model to convert")
+	outPath := fs.String("out", "", "Path to write the converted model (default: overwrite -in)")
+	codec := fs.String("codec", "gob", "Output encoding: gob, json, flat or npz")
+	precision := fs.String("precision", "", "Output weight precision: float64 or float32 (default float32 for flat, float64 otherwise)")
+	samples := fs.Int("samples", 100, "Random inputs used to validate the converted model")
+	tolerance := fs.Float64("tolerance", 1e-3, "Maximum allowed Q-value difference after conversion")
+	info := fs.Bool("info", false, "Print the model's format and training metadata and exit")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *inPath == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *outPath == "" {
+		*outPath = *inPath
+	}
+
+	srcInfo, err := ai.InspectModel(*inPath)
+	if err != nil {
+		return fmt.Errorf("could not read model %s: %w", *inPath, err)
+	}
+	if *info {
+		fmt.Printf("%s: %s (version %d, %s, %s)\n", *inPath, srcInfo.Kind, srcInfo.Version, srcInfo.Codec, srcInfo.Precision)
+		if meta := srcInfo.Metadata; meta != nil {
+			fmt.Printf("Created %s after %d episodes, state encoding version %d\n",
+				meta.Created.Format(time.RFC3339), meta.Episodes, meta.EncodingVersion)
+			if cfg := meta.Config; cfg != nil {
+				fmt.Printf("Trained with %s, encoder %s, learning rate %g, gamma %g, batch size %d\n",
+					cfg.Algorithm, cfg.Encoder, cfg.LearningRate, cfg.Gamma, cfg.BatchSize)
+			}
+		}
+		return nil
+	}
+
+	src, err := ai.LoadNetwork(*inPath)
+	if err != nil {
+		return fmt.Errorf("could not load model %s: %w", *inPath, err)
+	}
+
+	opts := ai.SaveOptions{
+		Codec:     ai.Codec(*codec),
+		Precision: ai.Precision(*precision),
+	}
+
+	// Write to a temporary file first so a failed conversion never clobbers the input
+	tmpPath := *outPath + ".tmp"
+	if err := src.SaveWithOptions(tmpPath, opts); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not write model: %w", err)
+	}
+
+	// Validate the round trip before replacing the output
+	dst, err := ai.LoadNetwork(tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("converted model does not load: %w", err)
+	}
+	maxDiff := compareNetworks(src, dst, *samples)
+	if maxDiff > *tolerance {
+		os.Remove(tmpPath)
+		return fmt.Errorf("converted model differs by %.6g (tolerance %.6g)", maxDiff, *tolerance)
+	}
+
+	dstInfo, err := ai.InspectModel(tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("converted model does not load: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, *outPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("could not move converted model into place: %w", err)
+	}
+
+	log.Printf("Converted %s (%s) -> %s (version %d, %s, %s), max Q difference %.3g",
+		*inPath, srcInfo.Kind, *outPath, dstInfo.Version, dstInfo.Codec, dstInfo.Precision, maxDiff)
+	return nil
+}
+
+// compareNetworks returns the largest Q-value difference between two networks
+// over random inputs shaped like encoded states
+func compareNetworks(a, b *ai.QNetwork, samples int) float64 {
+	if a.InputSize != b.InputSize || a.OutputSize != b.OutputSize {
+		return math.Inf(1)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	input := make([]float64, a.InputSize)
+	maxDiff := 0.0
+	for s := 0; s < samples; s++ {
+		for i := range input {
+			input[i] = rng.Float64()
+		}
+		qa := a.Forward(input)
+		qb := b.Forward(input)
+		for i := range qa {
+			if d := math.Abs(qa[i] - qb[i]); d > maxDiff {
+				maxDiff = d
+			}
+		}
+	}
+	return maxDiff
+}
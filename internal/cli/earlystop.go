@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+)
+
+// errStoppedEarly stops the training loop when a stopRules criterion is met
+var errStoppedEarly = errors.New("training stopped early")
+
+// evalResult is how a snapshot of the learning agent did in an evaluation
+type evalResult struct {
+	Score   float64 // Ties count half, as in the eval command's A score
+	WinRate float64 // Games won outright
+}
+
+// evaluateSnapshot plays net greedily against opponent on pairs seeds from
+// seed on, each from both sides, on every CPU. Evaluations with the same
+// seed play the same games, so their results compare.
+func evaluateSnapshot(cfg config.GameConfig, net *ai.QNetwork, mask bool, opponent PolicyFactory,
+	pairs int, seed int64, maxTurns int) evalResult {
+	jobs := make(chan int)
+	scores := make([]float64, pairs)
+	wins := make([]int, pairs)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), pairs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			policy := ai.NewNetworkPolicy(net, 0, seed)
+			policy.Mask = mask
+			for idx := range jobs {
+				// The opponent is seeded by the game, not the worker
+				// that happens to play it
+				pair := evalPair{idx: idx, seed: seed + int64(idx)}
+				other := opponent(pair.seed)
+				for g := range pair.games {
+					bySnake := [2]ai.Policy{policy, other}
+					if g == 1 {
+						bySnake = [2]ai.Policy{other, policy}
+					}
+					pair.games[g].state, pair.games[g].last = playEvalGame(cfg, pair.seed, bySnake, maxTurns)
+					score := pair.scoreA(g)
+					scores[idx] += score / 2
+					if score == 1 {
+						wins[idx]++
+					}
+				}
+			}
+		}()
+	}
+	for idx := 0; idx < pairs; idx++ {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	var r evalResult
+	for i := range scores {
+		r.Score += scores[i]
+		r.WinRate += float64(wins[i])
+	}
+	r.Score /= float64(pairs)
+	r.WinRate /= float64(2 * pairs)
+	return r
+}
+
+// stopRules are train's early stopping criteria, each off when zero
+type stopRules struct {
+	Patience      int           // Evaluations in a row without a better score
+	TargetWinRate float64       // Evaluation win rate that is good enough
+	Budget        time.Duration // Wall-clock time to train for
+
+	stopState
+}
+
+// stopState is what stopRules remember between evaluations, saved with a
+// run's progress so a resumed run keeps its patience count
+type stopState struct {
+	Best        float64 `json:"best"`
+	BestEpisode int     `json:"best_episode"` // 0 before the first evaluation
+	Stale       int     `json:"stale"`        // Evaluations since the best
+}
+
+// evaluated records the evaluation after episode ep and returns why
+// training should stop, or "" to go on
+func (r *stopRules) evaluated(ep int, result evalResult) string {
+	if r.BestEpisode == 0 || result.Score > r.Best {
+		r.Best, r.BestEpisode, r.Stale = result.Score, ep, 0
+	} else {
+		r.Stale++
+	}
+	switch {
+	case r.TargetWinRate > 0 && result.WinRate >= r.TargetWinRate:
+		return fmt.Sprintf("evaluation win rate %.1f%% reached the target of %.1f%%", 100*result.WinRate, 100*r.TargetWinRate)
+	case r.Patience > 0 && r.Stale >= r.Patience:
+		return fmt.Sprintf("no better evaluation score in %d evaluations; the best was %.1f%% after episode %d", r.Stale, 100*r.Best, r.BestEpisode)
+	}
+	return ""
+}
+
+// timeUp returns why training should stop once elapsed exceeds the
+// budget, or ""
+func (r *stopRules) timeUp(elapsed time.Duration) string {
+	if r.Budget > 0 && elapsed >= r.Budget {
+		return fmt.Sprintf("the time budget of %v is spent", r.Budget)
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStopRulesEvaluated(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   stopRules
+		from    int          // Episodes before the first evaluation's 100
+		results []evalResult // One per evaluation, every 100 episodes
+		stopAt  int          // Evaluation that stops training, -1 for none
+		reason  string
+		want    stopState // After the last evaluation
+	}{
+		{
+			name:    "no rules",
+			results: []evalResult{{0.5, 0.4}, {0.4, 0.3}, {0.3, 0.9}},
+			stopAt:  -1,
+			want:    stopState{Best: 0.5, BestEpisode: 100, Stale: 2},
+		},
+		{
+			name:    "patience runs out",
+			rules:   stopRules{Patience: 2},
+			results: []evalResult{{0.5, 0.4}, {0.6, 0.5}, {0.6, 0.5}, {0.55, 0.5}},
+			stopAt:  3,
+			reason:  "no better evaluation score in 2 evaluations; the best was 60.0% after episode 200",
+			want:    stopState{Best: 0.6, BestEpisode: 200, Stale: 2},
+		},
+		{
+			name:    "improvement resets patience",
+			rules:   stopRules{Patience: 2},
+			results: []evalResult{{0.5, 0.4}, {0.4, 0.3}, {0.7, 0.6}, {0.6, 0.5}},
+			stopAt:  -1,
+			want:    stopState{Best: 0.7, BestEpisode: 300, Stale: 1},
+		},
+		{
+			name:    "first evaluation sets the best",
+			rules:   stopRules{Patience: 1},
+			results: []evalResult{{0, 0}, {0, 0}},
+			stopAt:  1,
+			reason:  "no better evaluation score in 1 evaluations; the best was 0.0% after episode 100",
+			want:    stopState{Best: 0, BestEpisode: 100, Stale: 1},
+		},
+		{
+			name:    "target win rate reached",
+			rules:   stopRules{TargetWinRate: 0.8},
+			results: []evalResult{{0.7, 0.6}, {0.85, 0.79}, {0.9, 0.8}},
+			stopAt:  2,
+			reason:  "evaluation win rate 80.0% reached the target of 80.0%",
+			want:    stopState{Best: 0.9, BestEpisode: 300},
+		},
+		{
+			name:    "target before patience",
+			rules:   stopRules{Patience: 1, TargetWinRate: 0.5},
+			results: []evalResult{{0.8, 0.7}, {0.7, 0.6}},
+			stopAt:  0,
+			reason:  "evaluation win rate 70.0% reached the target of 50.0%",
+			want:    stopState{Best: 0.8, BestEpisode: 100},
+		},
+		{
+			name:    "resumed patience count",
+			rules:   stopRules{Patience: 3, stopState: stopState{Best: 0.9, BestEpisode: 200, Stale: 2}},
+			from:    400,
+			results: []evalResult{{0.8, 0.7}},
+			stopAt:  0,
+			reason:  "no better evaluation score in 3 evaluations; the best was 90.0% after episode 200",
+			want:    stopState{Best: 0.9, BestEpisode: 200, Stale: 3},
+		},
+	}
+	for _, tt := range tests {
+		rules := tt.rules
+		stopAt, reason := -1, ""
+		for i, result := range tt.results {
+			if reason = rules.evaluated(tt.from+100*(i+1), result); reason != "" {
+				stopAt = i
+				break
+			}
+		}
+		if stopAt != tt.stopAt || reason != tt.reason {
+			t.Errorf("%s: stopped at evaluation %d with %q, want %d with %q", tt.name, stopAt, reason, tt.stopAt, tt.reason)
+		}
+		if rules.stopState != tt.want {
+			t.Errorf("%s: state %+v, want %+v", tt.name, rules.stopState, tt.want)
+		}
+	}
+}
+
+func TestStopRulesTimeUp(t *testing.T) {
+	tests := []struct {
+		budget, elapsed time.Duration
+		stop            bool
+	}{
+		{0, 100 * time.Hour, false},
+		{time.Hour, 59 * time.Minute, false},
+		{time.Hour, time.Hour, true},
+		{time.Hour, 2 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		rules := stopRules{Budget: tt.budget}
+		reason := rules.timeUp(tt.elapsed)
+		if (reason != "") != tt.stop {
+			t.Errorf("budget %v after %v: %q, want stop %v", tt.budget, tt.elapsed, reason, tt.stop)
+		}
+		if tt.stop && !strings.Contains(reason, tt.budget.String()) {
+			t.Errorf("budget %v: reason %q doesn't name it", tt.budget, reason)
+		}
+	}
+}
+
+func TestStopStateSaved(t *testing.T) {
+	dir := t.TempDir()
+	want := trainProgress{Seed: 3, Episodes: 400, Stop: stopState{Best: 0.625, BestEpisode: 200, Stale: 2}}
+	if err := want.save(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadProgress(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+}
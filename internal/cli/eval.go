@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "eval",
+		Summary: "Compare two policies over mirrored pairs of games (headless)",
+		Run:     runEval,
+	})
+}
+
+// evalGame is one finished evaluation game
+type evalGame struct {
+	state *game.GameState
+	last  game.StepResult
+}
+
+// evalPair is a seed played twice: first with policy A as snake 0, then
+// with the sides swapped
+type evalPair struct {
+	idx   int
+	seed  int64
+	games [2]evalGame
+}
+
+// scoreA returns policy A's score in game g: 1 for a win, 0.5 for a tie
+func (p evalPair) scoreA(g int) float64 {
+	side := g // A plays snake 0 in the first game and snake 1 in the second
+	switch p.games[g].state.Winner {
+	case side:
+		return 1
+	case 1 - side:
+		return 0
+	}
+	return 0.5
+}
+
+// score returns policy A's average score over the first sides games of the
+// pair, the seed's result with the sides' advantages cancelled out
+func (p evalPair) score(sides int) float64 {
+	total := 0.0
+	for g := 0; g < sides; g++ {
+		total += p.scoreA(g)
+	}
+	return total / float64(sides)
+}
+
+// runEval implements the eval subcommand
+func runEval(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("eval")
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	modelPath := fs.String("model", "models/snake_dqn.gob", `Policy A: a model file, "random", "mcts" or "mcts:MODEL"`)
+	opponentPath := fs.String("opponent", MCTSPolicyName, `Policy B: a model file, "random", "mcts" or "mcts:MODEL"`)
+	pairs := fs.Int("pairs", 100, "Seeds to play; each is played twice with the sides swapped")
+	mirror := fs.Bool("mirror", true, "Play every seed from both sides (false plays A as snake 0 only)")
+	workers := fs.Int("workers", runtime.NumCPU(), "Games played in parallel")
+	maxTurns := fs.Int("max-turns", 1000, "Turns before a game is declared a tie")
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for mcts policies")
+	moveLimit := fs.Duration("move-limit", 0, "Play a safe default move when a policy takes longer than this (0 for no limit)")
+	resultsPath := fs.String("results", "", "Record every game in this results database")
+	mask := fs.Bool("mask", false, "Never let policy A's model choose a move into a wall or body while another survives")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	seed := gameFlags.ResolveSeed()
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+	var factories [2]PolicyFactory
+	for i, path := range []string{*modelPath, *opponentPath} {
+		if factories[i], err = LoadPolicy(path, 0, *mctsBudget); err != nil {
+			return err
+		}
+	}
+	if *mask {
+		factories[0] = MaskPolicy(factories[0])
+	}
+	sides := 1
+	if *mirror {
+		sides = 2
+	}
+
+	log.Printf("Evaluating %s vs %s over %d seeds x %d sides on %d workers", *modelPath, *opponentPath, *pairs, sides, *workers)
+	startTime := time.Now()
+
+	played := playEvalPairs(gameCfg, factories, *pairs, sides, *workers, seed, *maxTurns, *moveLimit)
+
+	// Per game: A's results and which side won
+	var aWins, bWins, ties, games int
+	var sideWins [2]int
+	// Per pair: A won both, B won both, anything else
+	var sweepsA, sweepsB, splits int
+	var scores []float64
+	var matches []*results.Match
+	for _, pair := range played {
+		for g := 0; g < sides; g++ {
+			games++
+			switch pair.scoreA(g) {
+			case 1:
+				aWins++
+			case 0:
+				bWins++
+			default:
+				ties++
+			}
+			if winner := pair.games[g].state.Winner; winner >= 0 {
+				sideWins[winner]++
+			}
+			if *resultsPath != "" {
+				models := [2]string{*modelPath, *opponentPath}
+				if g == 1 {
+					models = [2]string{*opponentPath, *modelPath}
+				}
+				m := results.NewMatch(results.SourceEval, models, pair.seed, 2*pair.idx+g, pair.games[g].state, pair.games[g].last)
+				matches = append(matches, &m)
+			}
+		}
+		pairScore := pair.score(sides)
+		scores = append(scores, pairScore)
+		if *mirror {
+			switch pairScore {
+			case 1:
+				sweepsA++
+			case 0:
+				sweepsB++
+			default:
+				splits++
+			}
+		}
+	}
+
+	if *resultsPath != "" {
+		if err := recordMatches(*resultsPath, matches); err != nil {
+			return err
+		}
+	}
+
+	mean, stderr := meanStderr(scores)
+	fmt.Printf("\n=== Evaluation (%v) ===\n", time.Since(startTime).Round(time.Second))
+	fmt.Printf("A: %s\nB: %s\n", *modelPath, *opponentPath)
+	fmt.Printf("Games: %d  A wins: %d  B wins: %d  Ties: %d\n", games, aWins, bWins, ties)
+	fmt.Printf("Side bias: snake 0 won %d, snake 1 won %d\n", sideWins[0], sideWins[1])
+	if *mirror {
+		fmt.Printf("Pairs: %d  A won both: %d  B won both: %d  Split or tied: %d\n", len(played), sweepsA, sweepsB, splits)
+	}
+	fmt.Printf("A score: %.1f%% ± %.1f%% (ties count half, ± one standard error over seeds)\n", 100*mean, 100*stderr)
+	return nil
+}
+
+// playEvalPairs plays pairs seeds from seed on between the policies of
+// factories, A and B, on workers goroutines. Each seed is played from sides
+// sides: A as snake 0, then with the sides swapped. Pairs come back in the
+// order they finish.
+func playEvalPairs(cfg config.GameConfig, factories [2]PolicyFactory, pairs, sides, workers int, seed int64,
+	maxTurns int, moveLimit time.Duration) []evalPair {
+	jobs := make(chan int)
+	finished := make(chan evalPair, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			workerSeed := seed + int64(w)*1_000_003
+			var policies [2]ai.Policy // A, B
+			for i, factory := range factories {
+				policies[i] = TimedPolicy(factory, workerSeed+int64(i), moveLimit)
+			}
+			for idx := range jobs {
+				pair := evalPair{idx: idx, seed: seed + int64(idx)}
+				for g := 0; g < sides; g++ {
+					bySnake := [2]ai.Policy{policies[g], policies[1-g]}
+					pair.games[g].state, pair.games[g].last = playEvalGame(cfg, pair.seed, bySnake, maxTurns)
+				}
+				finished <- pair
+			}
+		}(w)
+	}
+	go func() {
+		for idx := 0; idx < pairs; idx++ {
+			jobs <- idx
+		}
+		close(jobs)
+		wg.Wait()
+		close(finished)
+	}()
+
+	var played []evalPair
+	for pair := range finished {
+		played = append(played, pair)
+	}
+	return played
+}
+
+// playEvalGame plays one game and returns its final state and last step
+func playEvalGame(cfg config.GameConfig, seed int64, policies [2]ai.Policy, maxTurns int) (*game.GameState, game.StepResult) {
+	g := game.NewGame(cfg, seed)
+	var last game.StepResult
+	for !g.State.GameOver && g.State.Turn < maxTurns {
+		var dirs [2]game.Direction
+		for i, policy := range policies {
+			dirs[i] = ai.ActionToDirection(g.State.Snakes[i].Direction, policy.Act(g.State, i))
+		}
+		last = g.Step(dirs)
+	}
+	return g.State, last
+}
+
+// meanStderr returns the mean of values and its standard error
+func meanStderr(values []float64) (mean, stderr float64) {
+	n := float64(len(values))
+	if n == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= n
+	if n < 2 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / (n - 1) / n)
+}
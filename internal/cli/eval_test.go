@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"math"
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestMeanStderr(t *testing.T) {
+	tests := []struct {
+		values       []float64
+		mean, stderr float64
+	}{
+		{nil, 0, 0},
+		{[]float64{0.5}, 0.5, 0},
+		{[]float64{1, 1, 1}, 1, 0},
+		{[]float64{1, 0}, 0.5, 0.5},
+		// Sample variance 0.5/3, over 4 seeds
+		{[]float64{1, 0, 0.5, 0.5}, 0.5, math.Sqrt(0.5 / 3 / 4)},
+		{[]float64{0, 0.5, 1, 1, 1}, 0.7, 0.2}, // Sample variance 0.8/4, over 5 seeds
+	}
+	for _, tt := range tests {
+		mean, stderr := meanStderr(tt.values)
+		if math.Abs(mean-tt.mean) > 1e-12 || math.Abs(stderr-tt.stderr) > 1e-12 {
+			t.Errorf("meanStderr(%v) = %v, %v, want %v, %v", tt.values, mean, stderr, tt.mean, tt.stderr)
+		}
+	}
+}
+
+func TestEvalPairScore(t *testing.T) {
+	// A is snake 0 in the first game and snake 1 in the mirrored one
+	tests := []struct {
+		winners [2]int // Winning snake of each game, -1 for a tie
+		scores  [2]float64
+		pair    float64
+	}{
+		{[2]int{0, 1}, [2]float64{1, 1}, 1},
+		{[2]int{1, 0}, [2]float64{0, 0}, 0},
+		{[2]int{0, 0}, [2]float64{1, 0}, 0.5}, // Snake 0 wins from either side
+		{[2]int{-1, -1}, [2]float64{0.5, 0.5}, 0.5},
+		{[2]int{0, -1}, [2]float64{1, 0.5}, 0.75},
+		{[2]int{-1, 0}, [2]float64{0.5, 0}, 0.25},
+	}
+	for _, tt := range tests {
+		pair := evalPair{games: [2]evalGame{
+			{state: &game.GameState{Winner: tt.winners[0]}},
+			{state: &game.GameState{Winner: tt.winners[1]}},
+		}}
+		for g := range pair.games {
+			if got := pair.scoreA(g); got != tt.scores[g] {
+				t.Errorf("winners %v: game %d scores %v for A, want %v", tt.winners, g, got, tt.scores[g])
+			}
+		}
+		if got := pair.score(2); got != tt.pair {
+			t.Errorf("winners %v: pair scores %v for A, want %v", tt.winners, got, tt.pair)
+		}
+		if got := pair.score(1); got != tt.scores[0] {
+			t.Errorf("winners %v: unmirrored pair scores %v for A, want %v", tt.winners, got, tt.scores[0])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"autonomous-snake/internal/ai"
+)
+
+func init() {
+	Register(Command{
+		Name:    "export",
+		Summary: "Export a saved model as an ONNX graph",
+		Run:     runExport,
+	})
+}
+
+// runExport implements the export subcommand
+func runExport(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("export")
+	modelPath := fs.String("model", "models/snake_dqn.gob", "Model to export")
+	outPath := fs.String("out", "", "Path of the ONNX file (default: -model with the extension .onnx)")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *outPath == "" {
+		*outPath = strings.TrimSuffix(*modelPath, filepath.Ext(*modelPath)) + ".onnx"
+	}
+
+	net, err := ai.LoadNetwork(*modelPath)
+	if err != nil {
+		return fmt.Errorf("could not load model %s: %w", *modelPath, err)
+	}
+	if err := net.SaveONNX(*outPath); err != nil {
+		return fmt.Errorf("could not export %s: %w", *modelPath, err)
+	}
+	log.Printf("Exported %s model %s -> %s (input %q [batch, %d] from the %s encoder, output %q [batch, %d])",
+		net.Architecture(), *modelPath, *outPath, ai.ONNXInput, net.InputSize, net.StateEncoder().Name(), ai.ONNXOutput, net.Actions())
+	return nil
+}
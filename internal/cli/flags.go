@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// minBoard is the shortest long side of a board both starting snakes fit
+// along
+const minBoard = 8
+
+// GameFlags holds the board and seed flags shared by every subcommand that
+// creates a game
+type GameFlags struct {
+	Board       int
+	Width       int
+	Height      int
+	Food        int
+	Spawn       config.FoodSpawnConfig
+	Value       config.FoodValueConfig
+	Turns       int
+	RepeatLimit int
+	Respawn     config.RespawnConfig
+	Rules       string
+	Map         string
+	Seed        int64
+}
+
+// Register adds the shared game flags to fs
+func (f *GameFlags) Register(fs *flag.FlagSet) {
+	fs.IntVar(&f.Board, "board", 20, "Board width and height")
+	fs.IntVar(&f.Width, "width", 0, "Board width (0 for -board)")
+	fs.IntVar(&f.Height, "height", 0, "Board height (0 for -board)")
+	fs.IntVar(&f.Food, "food", 1, "Food pellets kept on the board")
+	fs.StringVar(&f.Spawn.Pattern, "food-pattern", config.FoodPatternRandom, "Food spawn pattern: random or cluster")
+	fs.IntVar(&f.Spawn.ClusterRadius, "food-radius", 2, "Radius of a food cluster")
+	fs.IntVar(&f.Spawn.Lifetime, "food-lifetime", 0, "Turns before uneaten food expires (0 for never)")
+	fs.IntVar(&f.Spawn.RelocateEvery, "food-relocate", 0, "Move all food every N turns (0 for never)")
+	fs.IntVar(&f.Value.Points, "food-points", 1, "Score per pellet, which scales the food reward")
+	fs.IntVar(&f.Value.Growth, "food-growth", 1, "Segments a pellet grows a snake by")
+	fs.Float64Var(&f.Value.GoldenChance, "golden-chance", 0, "Chance a new pellet is golden")
+	fs.IntVar(&f.Value.GoldenPoints, "golden-points", config.DefaultGoldenPoints, "Score per golden pellet")
+	fs.IntVar(&f.Value.GoldenGrowth, "golden-growth", 0, "Segments a golden pellet grows a snake by (0 for -food-growth)")
+	fs.IntVar(&f.Turns, "turn-limit", 0, "End games after N turns, won by score then length (0 for no limit)")
+	fs.IntVar(&f.RepeatLimit, "repeat-limit", 0, "End games in a draw once a position occurs N times (0 for no limit)")
+	fs.BoolVar(&f.Respawn.Enabled, "respawn", false, "Bring dead snakes back instead of ending the game")
+	fs.IntVar(&f.Respawn.Delay, "respawn-delay", 5, "Turns a dead snake sits out before respawning")
+	fs.IntVar(&f.Respawn.ScoreTarget, "score-target", 0, "End respawn games once a snake scores N (0 for no target)")
+	fs.StringVar(&f.Rules, "rules", game.RulesStandard, "Ruleset: "+strings.Join(game.RulesNames(), ", "))
+	fs.StringVar(&f.Map, "map", "", "Board map: "+strings.Join(game.MapNames(), ", ")+" or a map file (overrides -board, -width and -height)")
+	fs.Int64Var(&f.Seed, "seed", 0, "Random seed (0 for time-based)")
+}
+
+// ResolveSeed replaces a zero seed with a time-based one and returns it
+func (f *GameFlags) ResolveSeed() int64 {
+	if f.Seed == 0 {
+		f.Seed = time.Now().UnixNano()
+	}
+	return f.Seed
+}
+
+// SetConfig sets the flags to the game configuration cfg, as a config
+// file describes it
+func (f *GameFlags) SetConfig(cfg config.GameConfig) {
+	f.Width, f.Height = cfg.BoardWidth, cfg.BoardHeight
+	f.Food = cfg.FoodCount
+	f.Spawn = cfg.Spawn
+	f.Value = cfg.Value
+	f.Turns = cfg.MaxTurns
+	f.RepeatLimit = cfg.RepeatLimit
+	f.Respawn = cfg.Respawn
+	f.Rules = cfg.Rules
+	f.Map = cfg.Map
+}
+
+// GameConfig builds the game configuration described by the flags
+func (f *GameFlags) GameConfig(gridSize int) (config.GameConfig, error) {
+	cfg := f.config(gridSize)
+	if err := cfg.Spawn.Validate(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Value.Validate(); err != nil {
+		return cfg, err
+	}
+	if cfg.Map == "" && (min(cfg.BoardWidth, cfg.BoardHeight) < 1 || max(cfg.BoardWidth, cfg.BoardHeight) < minBoard) {
+		return cfg, fmt.Errorf("a %dx%d board leaves no room for both snakes, the longer side needs %d cells", cfg.BoardWidth, cfg.BoardHeight, minBoard)
+	}
+	if cfg.MaxTurns < 0 {
+		return cfg, fmt.Errorf("-turn-limit must not be negative")
+	}
+	if cfg.RepeatLimit < 0 {
+		return cfg, fmt.Errorf("-repeat-limit must not be negative")
+	}
+	if _, err := game.LookupRules(cfg.Rules); err != nil {
+		return cfg, err
+	}
+	if cfg.Respawn.Enabled {
+		if cfg.Rules == game.RulesSolo {
+			return cfg, fmt.Errorf("-respawn does not apply to -rules %s", game.RulesSolo)
+		}
+		if cfg.Respawn.Delay < 1 {
+			return cfg, fmt.Errorf("-respawn-delay must be at least 1")
+		}
+		if cfg.Respawn.ScoreTarget < 0 {
+			return cfg, fmt.Errorf("-score-target must not be negative")
+		}
+	} else {
+		cfg.Respawn = config.RespawnConfig{}
+	}
+	if cfg.Map != "" {
+		board, err := game.LoadMap(cfg.Map)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.BoardWidth, cfg.BoardHeight = board.Width, board.Height
+	}
+	return cfg, nil
+}
+
+// config returns the game configuration the flags spell out, unchecked
+func (f *GameFlags) config(gridSize int) config.GameConfig {
+	return config.GameConfig{
+		BoardWidth:  cmp.Or(f.Width, f.Board),
+		BoardHeight: cmp.Or(f.Height, f.Board),
+		GridSize:    gridSize,
+		FoodCount:   f.Food,
+		Spawn:       f.Spawn,
+		Value:       f.Value,
+		MaxTurns:    f.Turns,
+		RepeatLimit: f.RepeatLimit,
+		Respawn:     f.Respawn,
+		Rules:       f.Rules,
+		Map:         f.Map,
+	}
+}
+
+// parseRange parses "MIN-MAX" into an inclusive integer range. An empty
+// string yields 0, 0.
+func parseRange(s string) (lo, hi int, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	first, second, isRange := strings.Cut(s, "-")
+	if !isRange {
+		return 0, 0, fmt.Errorf("invalid range %q: want MIN-MAX", s)
+	}
+	if lo, err = strconv.Atoi(strings.TrimSpace(first)); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+	if hi, err = strconv.Atoi(strings.TrimSpace(second)); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+	if lo > hi {
+		return 0, 0, fmt.Errorf("invalid range %q: minimum exceeds maximum", s)
+	}
+	return lo, hi, nil
+}
+
+// parseFloats parses a comma separated list of numbers
+func parseFloats(s string) ([]float64, error) {
+	var values []float64
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number list %q: %w", s, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// resolveWorkers replaces a zero worker count with one goroutine per
+// processor Go may run on, GOMAXPROCS
+func resolveWorkers(n int) int {
+	if n == 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
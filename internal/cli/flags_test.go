@@ -0,0 +1,32 @@
+package cli
+
+import "testing"
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		in     string
+		lo, hi int
+		err    bool
+	}{
+		{in: "", lo: 0, hi: 0},
+		{in: "12-28", lo: 12, hi: 28},
+		{in: " 1 - 3 ", lo: 1, hi: 3},
+		{in: "4-4", lo: 4, hi: 4},
+		{in: "5", err: true},
+		{in: "9-3", err: true},
+		{in: "a-b", err: true},
+		{in: "1-b", err: true},
+		{in: "-3", err: true},
+		{in: "1-2-3", err: true},
+	}
+	for _, tt := range tests {
+		lo, hi, err := parseRange(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("parseRange(%q) error = %v, want error %v", tt.in, err, tt.err)
+			continue
+		}
+		if !tt.err && (lo != tt.lo || hi != tt.hi) {
+			t.Errorf("parseRange(%q) = %d, %d, want %d, %d", tt.in, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
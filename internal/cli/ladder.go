@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "ladder",
+		Summary: "Show the Elo ladder of every model in the match history",
+		Run:     runLadder,
+	})
+}
+
+// runLadder implements the ladder subcommand
+func runLadder(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("ladder")
+	dbPath := fs.String("db", results.DefaultPath, "Results database holding the ladder")
+	register := fs.String("register", "", "Comma-separated models or bots to add at the initial rating")
+	rebuild := fs.Bool("rebuild", false, "Recompute every rating from the full match history")
+	minGames := fs.Int("min-games", 0, "Hide models with fewer rated games")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	store, err := results.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var names []string
+	for _, name := range strings.Split(*register, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		if err := store.Register(names...); err != nil {
+			return fmt.Errorf("could not register models: %w", err)
+		}
+	}
+	if *rebuild {
+		if err := store.RebuildLadder(); err != nil {
+			return fmt.Errorf("could not rebuild ladder: %w", err)
+		}
+	}
+
+	ladder, err := store.Ladder()
+	if err != nil {
+		return fmt.Errorf("could not read ladder: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tMODEL\tRATING\tGAMES\tWINS\tLOSSES\tTIES")
+	rank := 0
+	for _, r := range ladder {
+		if r.Games < *minGames {
+			continue
+		}
+		rank++
+		fmt.Fprintf(w, "%d\t%s\t%.0f\t%d\t%d\t%d\t%d\n", rank, r.Model, r.Rating, r.Games, r.Wins, r.Losses, r.Ties)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/netplay"
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "match",
+		Summary: "Play lockstep games against a policy in another process over TCP",
+		Run:     runMatch,
+	})
+}
+
+// runMatch implements the match subcommand
+func runMatch(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("match")
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	hostAddr := fs.String("host", "", "Listen on this address and run the authoritative game, e.g. :7777")
+	connectAddr := fs.String("connect", "", "Join a match hosted at this address")
+	modelPath := fs.String("model", "models/snake_dqn.gob", `Model playing for this side, "random" or "mcts"`)
+	name := fs.String("name", "", "Name reported to the host (default: the -model value)")
+	games := fs.Int("games", 10, "Games to play (host only)")
+	maxTurns := fs.Int("max-turns", 1000, "Turns before a game is declared a tie (host only)")
+	timeout := fs.Duration("timeout", 5*time.Second, "Longest the host waits for a client move")
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for mcts policies")
+	moveLimit := fs.Duration("move-limit", 0, "Play a safe default move when this side's policy takes longer than this")
+	resultsPath := fs.String("results", "", "Record every game in this results database (host only)")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if (*hostAddr == "") == (*connectAddr == "") {
+		fmt.Fprintln(fs.Output(), "exactly one of -host and -connect is required")
+		fs.Usage()
+		return errUsage
+	}
+	if *name == "" {
+		*name = *modelPath
+	}
+
+	seed := gameFlags.ResolveSeed()
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+	factory, err := LoadPolicy(*modelPath, 0, *mctsBudget)
+	if err != nil {
+		return err
+	}
+	policy := TimedPolicy(factory, seed, *moveLimit)
+
+	if *connectAddr != "" {
+		nc, err := net.Dial("tcp", *connectAddr)
+		if err != nil {
+			return err
+		}
+		conn := netplay.NewConn(nc)
+		defer conn.Close()
+
+		log.Printf("Connected to %s as %s", *connectAddr, *name)
+		played, err := netplay.Play(conn, policy, *name)
+		if err != nil {
+			return fmt.Errorf("match aborted after %d games: %w", played, err)
+		}
+		log.Printf("Match finished after %d games", played)
+		logTimeouts(policy)
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", *hostAddr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Waiting for an opponent on %s", listener.Addr())
+	nc, err := listener.Accept()
+	listener.Close()
+	if err != nil {
+		return err
+	}
+	conn := netplay.NewConn(nc)
+	conn.Timeout = *timeout
+	defer conn.Close()
+
+	var played []*results.Match
+	result, err := netplay.Host(conn, policy, netplay.HostConfig{
+		Game:     gameCfg,
+		Games:    *games,
+		Seed:     seed,
+		MaxTurns: *maxTurns,
+		OnGameOver: func(idx int, state *game.GameState, last game.StepResult) {
+			m := results.NewMatch(results.SourceMatch, [2]string{*name, ""}, seed, idx, state, last)
+			played = append(played, &m)
+		},
+	})
+	// Keep the games finished before any error
+	if *resultsPath != "" && len(played) > 0 {
+		for _, m := range played {
+			m.Models[1] = result.Opponent
+		}
+		if recordErr := recordMatches(*resultsPath, played); recordErr != nil {
+			log.Printf("Warning: %v", recordErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("match aborted after %d games: %w", result.Games, err)
+	}
+
+	fmt.Printf("\n=== Match Summary ===\n")
+	fmt.Printf("%s (host) vs %s\n", *name, result.Opponent)
+	fmt.Printf("Games: %d\n", result.Games)
+	fmt.Printf("Host Wins: %d  Client Wins: %d  Ties: %d\n", result.Wins[0], result.Wins[1], result.Ties)
+	logTimeouts(policy)
+	return nil
+}
+
+// logTimeouts reports how often a time-limited policy missed its deadline
+func logTimeouts(policy *ai.TimedPolicy) {
+	if policy.Limit > 0 {
+		log.Printf("Moves over %v: %d/%d", policy.Limit, policy.Timeouts, policy.Moves)
+	}
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+)
+
+func init() {
+	Register(Command{
+		Name:    "offline",
+		Summary: "Train a DQN agent from saved transitions without playing",
+		Run:     runOffline,
+	})
+}
+
+// runOffline implements the offline subcommand
+func runOffline(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("offline")
+	trainCfg := config.DefaultTrainingConfig()
+	dataPaths := fs.String("data", "", "Comma-separated transition files (selfplay -out) or replay buffers (train -buffer) to learn from")
+	fs.StringVar(&trainCfg.ModelPath, "model", "models/snake_offline.gob", "Path to save the model to")
+	loadModel := fs.String("load", "", "Model or checkpoint to continue training")
+	steps := fs.Int("steps", 100000, "Batch updates to train for")
+	fs.Float64Var(&trainCfg.Conservative, "cql", 0, "Weight of the conservative Q-learning penalty that keeps Q-values of actions missing from the data down (0 to disable)")
+	fs.StringVar(&trainCfg.Encoder, "encoder", ai.DefaultEncoderName, "State encoder the data was recorded with (ignored with -load)")
+	fs.BoolVar(&trainCfg.Dueling, "dueling", false, "Use a dueling network with separate value and advantage streams (ignored with -load)")
+	fs.BoolVar(&trainCfg.DoubleDQN, "double", false, "Double DQN targets: the policy network picks the next action, the target network values it")
+	fs.Float64Var(&trainCfg.HuberDelta, "huber", 0, "Train on the Huber loss with this delta instead of squared error (0 for squared error)")
+	fs.IntVar(&trainCfg.GradWorkers, "workers", 1, "Goroutines computing batch gradients (>1 uses averaged mini-batch updates, 0 one per GOMAXPROCS)")
+	fs.BoolVar(&trainCfg.MiniBatch, "minibatch", false, "Apply one averaged update per batch instead of one per sample (implied by -workers above 1)")
+	saveFreq := fs.Int("save-freq", 10000, "Save the model every N updates")
+	logFreq := fs.Int("log-freq", 1000, "Log stats every N updates")
+	seed := fs.Int64("seed", 0, "Random seed for batch sampling and initial weights (0 for time-based)")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dataPaths == "" || *steps <= 0 || *saveFreq <= 0 || *logFreq <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if trainCfg.Conservative < 0 {
+		return fmt.Errorf("-cql must not be negative")
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	trainCfg.GradWorkers = resolveWorkers(trainCfg.GradWorkers)
+
+	// Read every dataset before building the agent, so the replay buffer
+	// holds all of them
+	var exps []ai.Experience
+	for _, path := range strings.Split(*dataPaths, ",") {
+		data, err := ai.ReadExperiences(path)
+		if err != nil {
+			return fmt.Errorf("could not read transitions: %w", err)
+		}
+		if len(exps) > 0 && len(data) > 0 && len(data[0].State) != len(exps[0].State) {
+			return fmt.Errorf("%s holds states of size %d, earlier data %d", path, len(data[0].State), len(exps[0].State))
+		}
+		log.Printf("Read %d transitions from %s", len(data), path)
+		exps = append(exps, data...)
+	}
+
+	// Hyperparameters without a flag can still be set from the environment
+	if err := applyConfigEnv(fs, &trainCfg); err != nil {
+		return fmt.Errorf("invalid environment override: %w", err)
+	}
+	if err := ai.SetBackend(trainCfg.Backend); err != nil {
+		return err
+	}
+	if err := trainCfg.LRSchedule.Validate(); err != nil {
+		return err
+	}
+	if err := trainCfg.Regularization.Validate(); err != nil {
+		return err
+	}
+	if len(exps) < trainCfg.BatchSize {
+		return fmt.Errorf("%d transitions don't fill a batch of %d", len(exps), trainCfg.BatchSize)
+	}
+
+	encoder, err := ai.LookupEncoder(trainCfg.Encoder)
+	if err != nil {
+		return err
+	}
+	trainCfg.InputSize = encoder.Size()
+	trainCfg.BufferSize = len(exps)
+	agent := ai.NewDQNAgent(trainCfg, *seed)
+	if *loadModel != "" {
+		if err := agent.Load(*loadModel); err != nil {
+			return fmt.Errorf("could not load model from %s: %w", *loadModel, err)
+		}
+		log.Printf("Loaded %s model from %s", agent.PolicyNet.Architecture(), *loadModel)
+	}
+	if size := len(exps[0].State); size != agent.PolicyNet.InputSize {
+		return fmt.Errorf("the transitions hold states of size %d, but %s encodes %d values",
+			size, agent.Encoder().Name(), agent.PolicyNet.InputSize)
+	}
+	for _, exp := range exps {
+		agent.Remember(exp.State, exp.Action, exp.Reward, exp.NextState, exp.Done)
+	}
+
+	// Every step trains, and nothing explores
+	agent.TrainInterval = 1
+	agent.SetEpsilon(0)
+
+	// Offline training plays no episodes, so they stay at the loaded model's
+	stampModel := modelStamper(agent, trainCfg)
+	save := func() error {
+		stampModel(0)
+		if dir := filepath.Dir(trainCfg.ModelPath); dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		return agent.Save(trainCfg.ModelPath)
+	}
+
+	log.Printf("Training on %d transitions for %d updates (CQL weight %g)", len(exps), *steps, trainCfg.Conservative)
+	startTime := time.Now()
+	for step := 1; step <= *steps; step++ {
+		agent.Train()
+
+		if step%*logFreq == 0 {
+			diag := agent.Diagnostics()
+			log.Printf("Update %d/%d | Loss: %.5f | TD |err| mean %.4f p90 %.4f | Grad norm: %.4f | %.0f updates/s",
+				step, *steps, diag.AvgLoss, diag.TDError.Mean, diag.TDError.P90, diag.GradNorm,
+				float64(step)/time.Since(startTime).Seconds())
+		}
+		if step%*saveFreq == 0 && step < *steps {
+			if err := save(); err != nil {
+				log.Printf("Warning: Could not save model: %v", err)
+			} else {
+				log.Printf("Saved model to %s", trainCfg.ModelPath)
+			}
+		}
+	}
+
+	if err := save(); err != nil {
+		return fmt.Errorf("could not save model: %w", err)
+	}
+	log.Printf("Training complete in %s. Model saved to %s", time.Since(startTime).Round(time.Second), trainCfg.ModelPath)
+	return nil
+}
@@ -0,0 +1,184 @@
+// Package playcmd registers the play subcommand. It lives apart from package
+// cli so that headless commands do not link the Ebiten renderer.
+package playcmd
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/cli"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/render"
+	"autonomous-snake/internal/replay"
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	cli.Register(cli.Command{
+		Name:    "play",
+		Summary: "Watch trained agents battle in a window",
+		Run:     runPlay,
+	})
+}
+
+// runPlay implements the play subcommand
+func runPlay(args []string) error {
+	// Parse command line flags
+	fs := cli.NewFlagSet("play")
+	var gameFlags cli.GameFlags
+	gameFlags.Register(fs)
+	modelPath := fs.String("model", "models/snake_dqn.gob", "Model for snake 0, or \"random\" or \"mcts\"")
+	opponentPath := fs.String("opponent", "", "Model or policy for snake 1 (default: same as -model)")
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for the \"mcts\" policy")
+	gridSize := fs.Int("grid", 20, "Cell size in pixels")
+	noModel := fs.Bool("random", false, "Run with random actions (no model)")
+	maxSteps := fs.Int("max-steps", 0, "End a game after this many turns, won on score (0 for no limit)")
+	stallTurns := fs.Int("stall", 300, "End a game once no food has been eaten for this many turns, won on score (0 to disable)")
+	summaryPath := fs.String("summary", "", "Write a JSON session summary to this path on exit")
+	resultsPath := fs.String("results", "", "Record every game in this results database")
+	recordPath := fs.String("record", "", "Record every finished game as a compact replay in this file")
+	mask := fs.Bool("mask", false, "Never let a model choose a move into a wall or body while another survives")
+	if err := cli.ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	seed := gameFlags.ResolveSeed()
+	if *noModel {
+		*modelPath, *opponentPath = cli.RandomPolicyName, cli.RandomPolicyName
+	}
+	if *opponentPath == "" {
+		*opponentPath = *modelPath
+	}
+	models := [2]string{*modelPath, *opponentPath}
+
+	// Configuration
+	gameCfg, err := gameFlags.GameConfig(*gridSize)
+	if err != nil {
+		return err
+	}
+	trainCfg := config.DefaultTrainingConfig()
+
+	// Create game
+	g := game.NewGame(gameCfg, seed)
+
+	// Load policies. A model playing both snakes is loaded once, so the
+	// renderer can run both through one batched forward pass.
+	var policies [2]ai.Policy
+	var factories [2]cli.PolicyFactory
+	var loadErrs [2]error
+	for i, path := range models {
+		if i == 1 && path == models[0] {
+			factories[1], loadErrs[1] = factories[0], loadErrs[0]
+		} else if factories[i], loadErrs[i] = cli.LoadPolicy(path, 0, *mctsBudget); loadErrs[i] != nil {
+			log.Printf("Warning: %v", loadErrs[i])
+			log.Printf("Running snake %d with an untrained agent (random-ish behavior)", i)
+			net := ai.NewDQNAgent(trainCfg, seed).PolicyNet
+			factories[i] = func(seed int64) ai.Policy { return ai.NewNetworkPolicy(net, 0, seed) }
+		}
+		factory, err := factories[i], loadErrs[i]
+		if *mask {
+			factory = cli.MaskPolicy(factory)
+		}
+		policies[i] = factory(seed + int64(i))
+
+		switch policy := policies[i].(type) {
+		case *ai.NetworkPolicy:
+			if err == nil {
+				net := policy.Net
+				log.Printf("Snake %d: loaded model from %s (%s encoder, %s %d-%d-%d-%d)", i, path, net.Encoder,
+					net.Architecture(), net.InputSize, net.HiddenSize1, net.HiddenSize2, net.OutputSize)
+			}
+		default:
+			log.Printf("Snake %d: %s policy", i, path)
+		}
+	}
+
+	// Create and run renderer
+	renderer := render.NewRenderer(g, policies, gameCfg)
+	renderer.MaxSteps = *maxSteps
+	renderer.StallTurns = *stallTurns
+
+	if *resultsPath != "" {
+		store, err := results.Open(*resultsPath)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		games := 0
+		renderer.OnGameOver = func(state *game.GameState, last game.StepResult) {
+			games++
+			m := results.NewMatch(results.SourcePlay, models, seed, games, state, last)
+			if err := store.Add(&m); err != nil {
+				log.Printf("Warning: could not record game %d: %v", games, err)
+			}
+		}
+	}
+
+	if *recordPath != "" {
+		file, err := os.Create(*recordPath)
+		if err != nil {
+			return fmt.Errorf("could not create replay file: %w", err)
+		}
+		defer file.Close()
+		replays, err := replay.NewWriter(file)
+		if err != nil {
+			return fmt.Errorf("could not write replay file: %w", err)
+		}
+		defer replays.Flush()
+
+		// Every game is reseeded from its own seed so it can be replayed
+		// alone
+		gameSeeds := rand.New(rand.NewSource(seed + 4))
+		var current *replay.Replay
+		recorded := 0
+		renderer.OnReset = func() {
+			gameSeed := gameSeeds.Int63()
+			g.Reseed(gameSeed)
+			current = &replay.Replay{Index: recorded + 1, Seed: gameSeed, Game: gameCfg}
+		}
+		renderer.OnReset()
+		g.Reset()
+		renderer.OnStep = func(actions [2]ai.Action) { current.Add(actions) }
+
+		onGameOver := renderer.OnGameOver
+		renderer.OnGameOver = func(state *game.GameState, last game.StepResult) {
+			if onGameOver != nil {
+				onGameOver(state, last)
+			}
+			recorded++
+			current.Finish(state)
+			if err := replays.Write(current); err != nil {
+				log.Printf("Warning: could not record replay of game %d: %v", recorded, err)
+			}
+		}
+	}
+
+	log.Printf("Starting game...")
+	log.Printf("Controls: Space=Pause, Up/Down=Speed, R=Reset, Q=Quit")
+
+	if err := renderer.Run(); err != nil {
+		log.Printf("Game ended: %v", err)
+	}
+
+	if *summaryPath != "" {
+		summary := renderer.Summary()
+		summary.Seed = seed
+		if !*noModel {
+			summary.Models = models[:]
+			if models[0] == models[1] {
+				summary.Models = models[:1]
+			}
+		}
+		if err := render.WriteSummary(*summaryPath, summary); err != nil {
+			return fmt.Errorf("could not write session summary: %w", err)
+		}
+		log.Printf("Wrote session summary to %s", *summaryPath)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"autonomous-snake/internal/ai"
+)
+
+// Policy names accepted instead of a model file
+const (
+	RandomPolicyName = "random"
+	MCTSPolicyName   = "mcts"
+)
+
+// PolicyFactory builds a fresh policy, e.g. one per worker goroutine
+type PolicyFactory func(seed int64) ai.Policy
+
+// LoadPolicy returns a factory for the policy named by path: "random",
+// "mcts" with the given per-move budget, "mcts:MODEL" for the same search
+// with rollouts guided by a model, or a model file played greedily with the
+// given exploration rate
+func LoadPolicy(path string, epsilon float64, budget time.Duration) (PolicyFactory, error) {
+	switch path {
+	case RandomPolicyName:
+		return func(seed int64) ai.Policy { return ai.NewRandomPolicy(seed) }, nil
+	case MCTSPolicyName:
+		return func(seed int64) ai.Policy { return ai.NewMCTS(budget, seed) }, nil
+	}
+	if guidePath, ok := strings.CutPrefix(path, MCTSPolicyName+":"); ok {
+		net, err := ai.LoadNetwork(guidePath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load MCTS guide %s: %w", guidePath, err)
+		}
+		return func(seed int64) ai.Policy {
+			m := ai.NewMCTS(budget, seed)
+			m.Guide(net)
+			return m
+		}, nil
+	}
+	net, err := ai.LoadNetwork(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load model %s: %w", path, err)
+	}
+	return func(seed int64) ai.Policy { return ai.NewNetworkPolicy(net, epsilon, seed) }, nil
+}
+
+// MaskPolicy returns a factory for factory's policies that, if they play a
+// model, never choose a move into a wall or body while another survives
+func MaskPolicy(factory PolicyFactory) PolicyFactory {
+	return func(seed int64) ai.Policy {
+		policy := factory(seed)
+		if p, ok := policy.(*ai.NetworkPolicy); ok {
+			p.Mask = true
+		}
+		return policy
+	}
+}
+
+// TimedPolicy returns a policy of factory's with a per-move limit, replaced
+// by a fresh one with the same seed when a late search leaves it busy
+func TimedPolicy(factory PolicyFactory, seed int64, limit time.Duration) *ai.TimedPolicy {
+	return ai.NewTimedPolicy(func() ai.Policy { return factory(seed) }, limit)
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"autonomous-snake/internal/replay"
+	"autonomous-snake/internal/report"
+)
+
+func init() {
+	Register(Command{
+		Name:    "replay",
+		Summary: "List, verify or render games recorded with train -replays",
+		Run:     runReplay,
+	})
+}
+
+// runReplay implements the replay subcommand
+func runReplay(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("replay")
+	inPath := fs.String("in", "data/replays.slr", "Replay file written by train -replays")
+	list := fs.Bool("list", false, "List every recorded game")
+	verify := fs.Bool("verify", false, "Replay every game and check it reaches its recorded final position")
+	index := fs.Int("game", -1, "Index of the game to render with -gif")
+	gifPath := fs.String("gif", "", "Render the game selected with -game as an animated GIF")
+	cellSize := fs.Int("cell", 12, "Pixels per board cell in the GIF")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	file, err := os.Open(*inPath)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	replays, err := replay.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", *inPath, err)
+	}
+
+	turns := 0
+	for _, r := range replays {
+		turns += len(r.Actions)
+	}
+	fmt.Printf("%d games, %d turns in %d bytes", len(replays), turns, info.Size())
+	if len(replays) > 0 {
+		fmt.Printf(" (%.1f bytes per game)", float64(info.Size())/float64(len(replays)))
+	}
+	fmt.Println()
+
+	if *list {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INDEX\tSEED\tBOARD\tFOOD\tTURNS\tWINNER")
+		for _, r := range replays {
+			fmt.Fprintf(w, "%d\t%d\t%dx%d\t%d\t%d\t%s\n", r.Index, r.Seed, r.Game.BoardWidth, r.Game.BoardHeight,
+				r.Game.FoodCount, len(r.Actions), winnerName(r.Winner))
+		}
+		w.Flush()
+	}
+
+	if *verify {
+		diverged := 0
+		for _, r := range replays {
+			if _, err := r.Play(); errors.Is(err, replay.ErrDiverged) {
+				fmt.Printf("Game %d (seed %d) no longer reproduces\n", r.Index, r.Seed)
+				diverged++
+			}
+		}
+		fmt.Printf("Verified %d games: %d diverged\n", len(replays), diverged)
+		if diverged > 0 {
+			return fmt.Errorf("%d replays diverged", diverged)
+		}
+	}
+
+	if *gifPath != "" {
+		var selected *replay.Replay
+		for _, r := range replays {
+			if r.Index == *index {
+				selected = r
+				break
+			}
+		}
+		if selected == nil {
+			return fmt.Errorf("no game with index %d in %s", *index, *inPath)
+		}
+		frames, err := selected.Play()
+		if err != nil {
+			return fmt.Errorf("game %d: %w", *index, err)
+		}
+		data, err := report.EncodeGIF(frames, *cellSize, 8)
+		if err != nil {
+			return fmt.Errorf("could not encode GIF: %w", err)
+		}
+		if err := os.WriteFile(*gifPath, data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote game %d (%d turns) to %s\n", *index, len(selected.Actions), *gifPath)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/metrics"
+	"autonomous-snake/internal/report"
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "report",
+		Summary: "Write a self-contained HTML report of results, learning curves and replays",
+		Run:     runReport,
+	})
+}
+
+// runReport implements the report subcommand
+func runReport(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("report")
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	outPath := fs.String("out", "report.html", "HTML file to write")
+	title := fs.String("title", "SlitherRL Experiment Report", "Report title")
+	dbPath := fs.String("db", results.DefaultPath, "Results database for the ladder and standings (skipped if missing)")
+	source := fs.String("source", "", "Only standings from this source: play, selfplay, match, eval or arena")
+	metricsPaths := fs.String("metrics", "", "Comma-separated training metrics CSV files to chart")
+	modelPath := fs.String("model", "", `Model for snake 0 in replays, "random" or "mcts" (no replays if empty)`)
+	opponentPath := fs.String("opponent", "", "Model for snake 1 in replays (default: same as -model)")
+	replays := fs.Int("replays", 1, "Replay games to record")
+	maxTurns := fs.Int("max-turns", 300, "Turns recorded per replay")
+	cellSize := fs.Int("cell", 12, "Replay cell size in pixels")
+	mctsBudget := fs.Duration("mcts-budget", 20*time.Millisecond, "Search time per move for mcts policies")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	seed := gameFlags.ResolveSeed()
+	gameCfg, err := gameFlags.GameConfig(*cellSize)
+	if err != nil {
+		return err
+	}
+
+	rep := &report.Report{Title: *title, Generated: time.Now()}
+
+	if _, err := os.Stat(*dbPath); err == nil {
+		store, err := results.Open(*dbPath)
+		if err != nil {
+			return err
+		}
+		matches, err := store.Query(results.Filter{Source: *source})
+		if err == nil {
+			rep.Ladder, err = store.Ladder()
+		}
+		store.Close()
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", *dbPath, err)
+		}
+		rep.Standings = results.Standings(matches)
+	} else {
+		log.Printf("No results database at %s, skipping ladder and standings", *dbPath)
+	}
+
+	if *metricsPaths != "" {
+		for _, path := range strings.Split(*metricsPaths, ",") {
+			rows, err := metrics.Read(path)
+			if err != nil {
+				return fmt.Errorf("could not read metrics: %w", err)
+			}
+			rep.Curves = append(rep.Curves, report.Curve{Name: filepath.Base(path), Rows: rows})
+		}
+	}
+
+	if *modelPath != "" {
+		if *opponentPath == "" {
+			*opponentPath = *modelPath
+		}
+		var factories [2]PolicyFactory
+		for i, path := range []string{*modelPath, *opponentPath} {
+			if factories[i], err = LoadPolicy(path, 0, *mctsBudget); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < *replays; i++ {
+			gameSeed := seed + int64(i)
+			frames := report.PlayReplay(gameCfg, gameSeed, [2]ai.Policy{factories[0](gameSeed), factories[1](gameSeed + 1)}, *maxTurns)
+			data, err := report.EncodeGIF(frames, *cellSize, 8)
+			if err != nil {
+				return fmt.Errorf("could not encode replay: %w", err)
+			}
+			final := frames[len(frames)-1]
+			rep.Replays = append(rep.Replays, report.Replay{
+				Caption: fmt.Sprintf("%s vs %s, seed %d: %s after %d turns",
+					*modelPath, *opponentPath, gameSeed, winnerName(final.Winner), final.Turn),
+				GIF: data,
+			})
+		}
+	}
+
+	file, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(file, rep); err != nil {
+		file.Close()
+		return fmt.Errorf("could not write report: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	log.Printf("Wrote report to %s", *outPath)
+	return nil
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "results",
+		Summary: "Query the recorded match history",
+		Run:     runResults,
+	})
+}
+
+// runResults implements the results subcommand
+func runResults(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("results")
+	dbPath := fs.String("db", results.DefaultPath, "Results database to read")
+	source := fs.String("source", "", "Only matches from this source: play, selfplay, match, eval or arena")
+	model := fs.String("model", "", "Only matches involving this model")
+	since := fs.Duration("since", 0, "Only matches from this long ago or later, e.g. 168h")
+	list := fs.Bool("list", false, "List individual matches instead of standings")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return fmt.Errorf("no results database: %w", err)
+	}
+	store, err := results.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	filter := results.Filter{Source: *source, Model: *model}
+	if *since > 0 {
+		filter.Since = time.Now().Add(-*since)
+	}
+	matches, err := store.Query(filter)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", *dbPath, err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *list {
+		fmt.Fprintln(w, "ID\tTIME\tSOURCE\tSNAKE 0\tSNAKE 1\tSEED\tWINNER\tTURNS\tLENGTHS\tDEATHS")
+		for _, m := range matches {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\t%d\t%d/%d\t%s\n",
+				m.ID, m.Time.Format(time.DateTime), m.Source, m.Models[0], m.Models[1], m.Seed,
+				winnerName(m.Winner), m.Turns, m.Lengths[0], m.Lengths[1], deathSummary(m.Deaths))
+		}
+		return w.Flush()
+	}
+
+	fmt.Fprintln(w, "MODEL A\tMODEL B\tGAMES\tA WINS\tB WINS\tTIES\tA SCORE\tAVG TURNS\tLAST PLAYED")
+	for _, st := range results.Standings(matches) {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%.1f%%\t%.1f\t%s\n",
+			st.Models[0], st.Models[1], st.Games, st.Wins[0], st.Wins[1], st.Ties,
+			100*st.WinRate(), st.AvgTurns, st.LastSeen.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+// recordMatches appends finished matches to the results database at path
+func recordMatches(path string, matches []*results.Match) error {
+	store, err := results.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := store.Add(matches...); err != nil {
+		store.Close()
+		return fmt.Errorf("could not record results in %s: %w", path, err)
+	}
+	return store.Close()
+}
+
+// winnerName describes a match winner for listings
+func winnerName(winner int) string {
+	if winner < 0 {
+		return "tie"
+	}
+	return fmt.Sprintf("snake %d", winner)
+}
+
+// deathSummary describes how each snake died, "-" for survivors
+func deathSummary(deaths [2]string) string {
+	var parts [2]string
+	for i, d := range deaths {
+		parts[i] = d
+		if d == "" {
+			parts[i] = "-"
+		}
+	}
+	return parts[0] + "/" + parts[1]
+}
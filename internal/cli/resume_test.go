@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/metrics"
+)
+
+func TestTrainResume(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// Greedy play with no batch ever filled makes each episode depend on
+	// its seed alone, so a resumed run can match an uninterrupted one
+	// exactly
+	t.Setenv("SLITHER_EPSILON_START", "0")
+	t.Setenv("SLITHER_EPSILON_MIN", "0")
+	t.Setenv("SLITHER_BATCH_SIZE", "50000")
+	const episodes = 12
+
+	train := func(dir string, args ...string) {
+		t.Helper()
+		args = append(args, "-resume", dir, "-replays", filepath.Join(dir, "replays.bin"), "-events", filepath.Join(dir, "events.jsonl"),
+			"-board", "10", "-turn-limit", "100", "-log-freq", "3", "-save-freq", "3")
+		if err := runTrain(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	train("whole", "-seed", "9", "-episodes", "12")
+	train("split", "-seed", "9", "-episodes", "6")
+
+	// The first half crashed after its last checkpoint, leaving rows of an
+	// episode the resumed run plays again
+	appendString(t, filepath.Join("split", runMetricsFile), "9,0,5,0,0,1,0,0,0,1\n")
+	appendString(t, filepath.Join("split", runEpisodesFile), "7,5,0,0,-1,0\n")
+
+	// Resuming needs neither the seed nor the other flags again
+	train("split", "-episodes", "12")
+
+	whole, err := loadProgress("whole")
+	if err != nil {
+		t.Fatal(err)
+	}
+	split, err := loadProgress("split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whole.Episodes != episodes {
+		t.Errorf("uninterrupted run finished %d episodes, want %d", whole.Episodes, episodes)
+	}
+	if split != whole {
+		t.Errorf("resumed run's progress %+v, want %+v", split, whole)
+	}
+
+	// The run directory records the settings it last trained with
+	gameCfg, trainCfg := config.DefaultGameConfig(), config.DefaultTrainingConfig()
+	if err := config.LoadFile(filepath.Join("split", runConfigFile), &gameCfg, &trainCfg); err != nil {
+		t.Fatal(err)
+	}
+	if gameCfg.BoardWidth != 10 || gameCfg.MaxTurns != 100 || trainCfg.Episodes != episodes || trainCfg.EpsilonStart != 0 {
+		t.Errorf("run directory settings: %dx%d board, %d turns, %d episodes, epsilon %v; want 10x10, 100, %d, 0",
+			gameCfg.BoardWidth, gameCfg.BoardHeight, gameCfg.MaxTurns, trainCfg.Episodes, trainCfg.EpsilonStart, episodes)
+	}
+
+	// Episode logs match row for row, and learning curves but for speed
+	wholeEpisodes := readFile(t, filepath.Join("whole", runEpisodesFile))
+	if got := readFile(t, filepath.Join("split", runEpisodesFile)); got != wholeEpisodes {
+		t.Errorf("resumed episode log:\n%s\nwant:\n%s", got, wholeEpisodes)
+	}
+	// The replays and events of the first half survive the second
+	for _, name := range []string{"replays.bin", "events.jsonl"} {
+		if got, want := readFile(t, filepath.Join("split", name)), readFile(t, filepath.Join("whole", name)); got != want {
+			t.Errorf("resumed run's %s differs from the uninterrupted run's", name)
+		}
+	}
+	wholeRows := readRows(t, filepath.Join("whole", runMetricsFile))
+	splitRows := readRows(t, filepath.Join("split", runMetricsFile))
+	if len(wholeRows) != episodes/3 || len(splitRows) != len(wholeRows) {
+		t.Fatalf("%d resumed and %d uninterrupted learning curve rows, want %d", len(splitRows), len(wholeRows), episodes/3)
+	}
+	for i := range wholeRows {
+		if splitRows[i] != wholeRows[i] {
+			t.Errorf("resumed learning curve row %d = %+v, want %+v", i, splitRows[i], wholeRows[i])
+		}
+	}
+}
+
+// readFile returns the contents of the file at path
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// readRows reads a learning curve, zeroing the speed, which no two runs
+// share
+func readRows(t *testing.T, path string) []metrics.Row {
+	t.Helper()
+	rows, err := metrics.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rows {
+		rows[i].EpsPerSec = 0
+	}
+	return rows
+}
+
+// appendString appends s to the file at path
+func appendString(t *testing.T, path, s string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"autonomous-snake/internal/ai"
+)
+
+// Files train keeps in a run directory, see -resume
+const (
+	runModelFile    = "model.gob"
+	runBufferFile   = "buffer.bin"
+	runProgressFile = "progress.json"
+	runConfigFile   = "model.yaml" // configFilePath(runModelFile)
+	runMetricsFile  = "metrics.csv"
+	runEpisodesFile = "episodes.csv"
+)
+
+// trainProgress is the training loop's own state, saved in a run directory
+// beside the agent's checkpoint so a resumed run carries on counting
+// episodes and results instead of starting over
+type trainProgress struct {
+	Seed     int64      `json:"seed"`
+	Episodes int        `json:"episodes"` // Episodes finished
+	Steps    int        `json:"steps"`
+	Wins     [2]int     `json:"wins"`
+	Ties     int        `json:"ties"`
+	Repeated int        `json:"repeated"` // Ties drawn on a repeated position
+	Rewards  [2]float64 `json:"rewards"`
+	Stop     stopState  `json:"stop"` // Early stopping's evaluations so far
+}
+
+// loadProgress reads the progress saved in run directory dir. A directory
+// without one, like a new run's, gives zero progress.
+func loadProgress(dir string) (trainProgress, error) {
+	var p trainProgress
+	data, err := os.ReadFile(filepath.Join(dir, runProgressFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("invalid %s: %w", runProgressFile, err)
+	}
+	return p, nil
+}
+
+// save writes the progress into run directory dir, replacing the last
+// save only once the new one is complete
+func (p trainProgress) save(dir string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ai.WriteFileAtomic(filepath.Join(dir, runProgressFile), func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+}
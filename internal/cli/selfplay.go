@@ -0,0 +1,413 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/analysis"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/results"
+)
+
+func init() {
+	Register(Command{
+		Name:    "selfplay",
+		Summary: "Generate transitions by playing frozen models against each other (headless)",
+		Run:     runSelfPlay,
+	})
+}
+
+// selfPlayGame is the transitions and outcome of one finished game
+type selfPlayGame struct {
+	idx         int
+	seed        int64
+	transitions []ai.Transition
+	bookMoves   []ai.BookMove
+	decisions   []ai.Decision
+	heatmap     *analysis.Heatmap
+	winner      int
+	state       *game.GameState
+	last        game.StepResult // Result of the final step
+}
+
+// runSelfPlay implements the selfplay subcommand
+func runSelfPlay(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("selfplay")
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	modelPath := fs.String("model", "models/snake_dqn.gob", `Model controlling snake 0, "random" or "mcts"`)
+	opponentPath := fs.String("opponent", "", `Model controlling snake 1, "random" or "mcts" (default: same as -model)`)
+	games := fs.Int("games", 1000, "Number of games to play")
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "Games played in parallel")
+	outPath := fs.String("out", "data/selfplay.gob", "Path to write the transitions to")
+	epsilon := fs.Float64("epsilon", 0.05, "Probability of a random action, for state coverage")
+	maxSteps := fs.Int("max-steps", config.DefaultTrainingConfig().MaxStepsPerEp, "Maximum turns per game")
+	rewardsPath := fs.String("rewards", "", "JSON file overriding reward values (see config.RewardConfig)")
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for mcts policies")
+	moveLimit := fs.Duration("move-limit", 0, "Play a safe default move when a policy takes longer than this (0 for no limit)")
+	opening := fs.String("opening", "", `Fixed opening moves for both snakes, e.g. "s,s,l,r"`)
+	bookPath := fs.String("book", "", "JSON opening book to play from")
+	bookOut := fs.String("book-out", "", "Write an opening book learned from these games to this path")
+	bookTurns := fs.Int("book-turns", 8, "Turns recorded in the learned opening book")
+	resultsPath := fs.String("results", "", "Record every game in this results database")
+	decisionsPath := fs.String("decisions", "", "Log every model decision's Q-values as JSON lines to this path")
+	heatmapDir := fs.String("heatmap", "", "Write head visit and death heatmaps (PNG and CSV) to this directory")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *games <= 0 || *workers <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if *opponentPath == "" {
+		*opponentPath = *modelPath
+	}
+
+	seed := gameFlags.ResolveSeed()
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+
+	rewards := config.DefaultRewardConfig()
+	if *rewardsPath != "" {
+		var err error
+		if rewards, err = config.LoadRewardConfig(*rewardsPath); err != nil {
+			return fmt.Errorf("could not load rewards from %s: %w", *rewardsPath, err)
+		}
+	}
+	shaper, err := ai.NewRewardShaper(rewards, config.DefaultTrainingConfig().Gamma)
+	if err != nil {
+		return fmt.Errorf("invalid reward shaping: %w", err)
+	}
+
+	var factories [2]PolicyFactory
+	for i, path := range []string{*modelPath, *opponentPath} {
+		factory, err := LoadPolicy(path, *epsilon, *mctsBudget)
+		if err != nil {
+			return err
+		}
+		factories[i] = factory
+	}
+
+	book, err := loadOpeningBook(*opening, *bookPath)
+	if err != nil {
+		return err
+	}
+	if book != nil {
+		for i, factory := range factories {
+			factories[i] = func(seed int64) ai.Policy {
+				return &ai.OpeningPolicy{Book: book, Fallback: factory(seed)}
+			}
+		}
+	}
+	var learned *ai.OpeningBook
+	if *bookOut != "" {
+		// Start from the existing book so statistics accumulate across runs
+		if learned, err = ai.LoadOpeningBook(*bookOut); err != nil {
+			if !os.IsNotExist(err) {
+				return fmt.Errorf("could not load opening book %s: %w", *bookOut, err)
+			}
+			learned = ai.NewOpeningBook(*bookTurns)
+		}
+		learned.Turns = *bookTurns
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*outPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	buf := bufio.NewWriter(file)
+	writer, err := ai.NewTransitionWriter(buf)
+	if err != nil {
+		return err
+	}
+
+	var decisions *ai.DecisionWriter
+	var decisionBuf *bufio.Writer
+	if *decisionsPath != "" {
+		decisionFile, err := os.Create(*decisionsPath)
+		if err != nil {
+			return err
+		}
+		defer decisionFile.Close()
+		decisionBuf = bufio.NewWriter(decisionFile)
+		decisions = ai.NewDecisionWriter(decisionBuf)
+	}
+
+	log.Printf("Playing %d games on %d workers: %s vs %s", *games, *workers, *modelPath, *opponentPath)
+	startTime := time.Now()
+
+	jobs := make(chan int)
+	finished := make(chan selfPlayGame, *workers)
+	timed := make([][2]*ai.TimedPolicy, *workers)
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			workerSeed := seed + int64(w)*1_000_003
+			for i := range timed[w] {
+				timed[w][i] = TimedPolicy(factories[i], workerSeed+int64(i), *moveLimit)
+			}
+			policies := [2]ai.Policy{timed[w][0], timed[w][1]}
+			for idx := range jobs {
+				finished <- playSelfPlayGame(idx, seed+int64(idx), gameCfg, rewards, shaper, policies, *maxSteps, learned,
+					decisions != nil, *heatmapDir != "")
+			}
+		}(w)
+	}
+	go func() {
+		for idx := 0; idx < *games; idx++ {
+			jobs <- idx
+		}
+		close(jobs)
+		wg.Wait()
+		close(finished)
+	}()
+
+	// Write results as they arrive; keep draining after an error so the
+	// workers can finish
+	var writeErr, decisionErr error
+	var matches []*results.Match
+	var heatmap *analysis.Heatmap
+	if *heatmapDir != "" {
+		heatmap = analysis.NewHeatmap(gameCfg.BoardWidth, gameCfg.BoardHeight)
+	}
+	wins := [2]int{}
+	ties, played, transitions := 0, 0, 0
+	for result := range finished {
+		played++
+		switch result.winner {
+		case 0, 1:
+			wins[result.winner]++
+		default:
+			ties++
+		}
+		for _, t := range result.transitions {
+			if writeErr == nil {
+				writeErr = writer.Write(t)
+			}
+		}
+		transitions += len(result.transitions)
+		if heatmap != nil {
+			// Every game uses the same board, so sizes always match
+			heatmap.Add(result.heatmap)
+		}
+		for _, d := range result.decisions {
+			if decisionErr == nil {
+				decisionErr = decisions.Write(d)
+			}
+		}
+		if learned != nil {
+			learned.RecordGame(result.bookMoves, result.winner)
+		}
+		if *resultsPath != "" {
+			m := results.NewMatch(results.SourceSelfPlay, [2]string{*modelPath, *opponentPath},
+				result.seed, result.idx, result.state, result.last)
+			matches = append(matches, &m)
+		}
+	}
+	if writeErr != nil {
+		return fmt.Errorf("could not write %s: %w", *outPath, writeErr)
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("could not write %s: %w", *outPath, err)
+	}
+	if decisionBuf != nil {
+		if decisionErr == nil {
+			decisionErr = decisionBuf.Flush()
+		}
+		if err := decisionErr; err != nil {
+			return fmt.Errorf("could not write %s: %w", *decisionsPath, err)
+		}
+	}
+
+	if *resultsPath != "" {
+		if err := recordMatches(*resultsPath, matches); err != nil {
+			return err
+		}
+	}
+
+	if heatmap != nil {
+		if err := writeHeatmaps(*heatmapDir, heatmap); err != nil {
+			return err
+		}
+	}
+
+	elapsed := time.Since(startTime)
+	fmt.Printf("\n=== Self-Play Summary ===\n")
+	fmt.Printf("Games: %d (%.1f games/sec)\n", played, float64(played)/elapsed.Seconds())
+	fmt.Printf("Snake 0 Wins: %d  Snake 1 Wins: %d  Ties: %d\n", wins[0], wins[1], ties)
+	fmt.Printf("Transitions: %d written to %s\n", transitions, *outPath)
+	if *moveLimit > 0 {
+		var moves, timeouts [2]int
+		for _, pair := range timed {
+			for i, p := range pair {
+				moves[i] += p.Moves
+				timeouts[i] += p.Timeouts
+			}
+		}
+		fmt.Printf("Moves over %v: snake 0 %d/%d  snake 1 %d/%d\n", *moveLimit, timeouts[0], moves[0], timeouts[1], moves[1])
+	}
+
+	if heatmap != nil {
+		fmt.Printf("Heatmaps: written to %s\n", *heatmapDir)
+	}
+
+	if learned != nil {
+		if err := learned.Save(*bookOut); err != nil {
+			return fmt.Errorf("could not write opening book %s: %w", *bookOut, err)
+		}
+		fmt.Printf("Opening book: %d positions written to %s\n", len(learned.Positions), *bookOut)
+	}
+	return nil
+}
+
+// writeHeatmaps writes visits and deaths heatmaps as PNG and CSV into dir
+func writeHeatmaps(dir string, heatmap *analysis.Heatmap) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	grids := map[string][]int{"visits": heatmap.Visits, "deaths": heatmap.Deaths}
+	for name, counts := range grids {
+		for _, ext := range []string{".png", ".csv"} {
+			path := filepath.Join(dir, name+ext)
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			if ext == ".png" {
+				err = heatmap.WritePNG(file, counts, 16)
+			} else {
+				err = heatmap.WriteCSV(file, counts)
+			}
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("could not write %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadOpeningBook builds the opening book from the -opening and -book flags
+func loadOpeningBook(opening, path string) (*ai.OpeningBook, error) {
+	switch {
+	case opening != "" && path != "":
+		return nil, fmt.Errorf("-opening and -book cannot be combined")
+	case opening != "":
+		book, err := ai.ParseOpening(opening)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -opening: %w", err)
+		}
+		return book, nil
+	case path != "":
+		book, err := ai.LoadOpeningBook(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load opening book %s: %w", path, err)
+		}
+		return book, nil
+	}
+	return nil, nil
+}
+
+// playSelfPlayGame plays one game and records both snakes' transitions.
+// The shaper only reads its configuration, so workers share one.
+func playSelfPlayGame(idx int, seed int64, gameCfg config.GameConfig, rewards config.RewardConfig,
+	shaper *ai.RewardShaper, policies [2]ai.Policy, maxSteps int, book *ai.OpeningBook, logDecisions, heat bool) selfPlayGame {
+	g := game.NewGame(gameCfg, seed)
+	g.SetRewards(rewards)
+	state := g.State
+
+	stall := ai.NewStallPenalty(g.Rewards)
+
+	var transitions []ai.Transition
+	var bookMoves []ai.BookMove
+	var decisions []ai.Decision
+	var result game.StepResult
+	var heatmap *analysis.Heatmap
+	if heat {
+		heatmap = analysis.NewHeatmap(state.Width, state.Height)
+		heatmap.Record(state, result)
+	}
+	for steps := 0; !state.GameOver && steps < maxSteps; steps++ {
+		var actions [2]ai.Action
+		var dirs [2]game.Direction
+		var encoded [2][]float64
+		for i := 0; i < 2; i++ {
+			var values []float64
+			if q, ok := policies[i].(ai.QValuer); ok && logDecisions {
+				values = q.QValues(state, i)
+			}
+			actions[i] = policies[i].Act(state, i)
+			if values != nil {
+				decisions = append(decisions, ai.Decision{
+					Game: idx, Turn: state.Turn, Snake: i, Hash: state.Hash(), Q: values, Action: actions[i],
+				})
+			}
+			dirs[i] = ai.ActionToDirection(state.Snakes[i].Direction, actions[i])
+			encoded[i] = ai.EncodeState(state, i)
+			if book != nil {
+				if move, ok := book.Observe(state, i, actions[i]); ok {
+					bookMoves = append(bookMoves, move)
+				}
+			}
+		}
+
+		prevState := g.Clone().State
+		result = g.Step(dirs)
+		if heatmap != nil {
+			heatmap.Record(state, result)
+		}
+
+		for i := 0; i < 2; i++ {
+			if !prevState.Snakes[i].Alive {
+				continue
+			}
+			reward := result.Rewards[i] + shaper.Reward(prevState, state, i)
+			if stall.Enabled() {
+				reward += stall.Penalty(state, i, result.AteFood[i])
+			}
+			transitions = append(transitions, ai.Transition{
+				Game:  idx,
+				Turn:  state.Turn,
+				Snake: i,
+				Experience: ai.Experience{
+					State:     encoded[i],
+					Action:    actions[i],
+					Reward:    reward,
+					NextState: ai.EncodeState(state, i),
+					Done:      result.Died[i] || result.GameOver && !result.TimedOut && !result.Repeated,
+				},
+			})
+		}
+	}
+
+	return selfPlayGame{
+		idx:         idx,
+		seed:        seed,
+		transitions: transitions,
+		bookMoves:   bookMoves,
+		decisions:   decisions,
+		heatmap:     heatmap,
+		winner:      state.Winner,
+		state:       state,
+		last:        result,
+	}
+}
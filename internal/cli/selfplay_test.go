@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"autonomous-snake/internal/ai"
+)
+
+func TestSelfPlay(t *testing.T) {
+	dir := t.TempDir()
+	const games, maxSteps = 3, 40
+
+	// readTransitions returns a run's transitions by game
+	readTransitions := func(path string) map[int][]ai.Transition {
+		t.Helper()
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		reader, err := ai.NewTransitionReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byGame := make(map[int][]ai.Transition)
+		for {
+			tr, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return byGame
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			byGame[tr.Game] = append(byGame[tr.Game], tr)
+		}
+	}
+	selfPlay := func(out, workers string) map[int][]ai.Transition {
+		t.Helper()
+		err := runSelfPlay([]string{"-model", "random", "-games", strconv.Itoa(games), "-workers", workers, "-max-steps", strconv.Itoa(maxSteps),
+			"-board", "10", "-seed", "5", "-out", out, "-book-out", filepath.Join(dir, "book.json")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return readTransitions(out)
+	}
+
+	byGame := selfPlay(filepath.Join(dir, "one.gob"), "1")
+	if len(byGame) != games {
+		t.Fatalf("transitions from %d games, want %d", len(byGame), games)
+	}
+	for idx, records := range byGame {
+		var ended [2]bool
+		for _, tr := range records {
+			if tr.Snake != 0 && tr.Snake != 1 || tr.Turn < 1 || tr.Turn > maxSteps {
+				t.Fatalf("game %d: record for snake %d on turn %d", idx, tr.Snake, tr.Turn)
+			}
+			if len(tr.State) != ai.StateSize || len(tr.NextState) != ai.StateSize || tr.Action >= ai.NumActions {
+				t.Fatalf("game %d, turn %d: state %d, next state %d, action %d", idx, tr.Turn, len(tr.State), len(tr.NextState), tr.Action)
+			}
+			if ended[tr.Snake] {
+				t.Errorf("game %d: snake %d has a record on turn %d after its last", idx, tr.Snake, tr.Turn)
+			}
+			ended[tr.Snake] = tr.Done
+		}
+		if first := records[0]; first.Turn != 1 {
+			t.Errorf("game %d starts on turn %d", idx, first.Turn)
+		}
+		if last := records[len(records)-1]; !last.Done && last.Turn != maxSteps {
+			t.Errorf("game %d ends on turn %d without a terminal record", idx, last.Turn)
+		}
+	}
+	book, err := ai.LoadOpeningBook(filepath.Join(dir, "book.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Positions) == 0 {
+		t.Error("no opening positions learned")
+	}
+
+	// A second run plays in parallel and adds to the book
+	if byGame := selfPlay(filepath.Join(dir, "two.gob"), "2"); len(byGame) != games {
+		t.Errorf("transitions from %d games on 2 workers, want %d", len(byGame), games)
+	}
+	more, err := ai.LoadOpeningBook(filepath.Join(dir, "book.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(more.Positions) < len(book.Positions) {
+		t.Errorf("book shrank from %d positions to %d", len(book.Positions), len(more.Positions))
+	}
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/game"
+)
+
+func init() {
+	Register(Command{
+		Name:    "serve",
+		Summary: "Serve a model's moves over HTTP for bots and programs in other languages",
+		Run:     runServe,
+	})
+}
+
+// moveRequest asks for snake Snake's next move in State
+type moveRequest struct {
+	State *game.GameState `json:"state"`
+	Snake int             `json:"snake"`
+}
+
+// moveResponse is the move chosen, relative to the snake's heading and as
+// the direction it then moves in
+type moveResponse struct {
+	Action    string `json:"action"`
+	Direction string `json:"direction"`
+}
+
+// directionNames name directions in move responses
+var directionNames = map[game.Direction]string{
+	game.Up:    "up",
+	game.Down:  "down",
+	game.Left:  "left",
+	game.Right: "right",
+}
+
+// moveServer answers move requests with one policy. Requests are served one
+// at a time, which also keeps a recurrent model's memory in step with the
+// game it is asked about.
+type moveServer struct {
+	name string
+
+	mu     sync.Mutex
+	policy ai.Policy
+}
+
+// ServeHTTP answers GET / with the model's name and POST /move with a move
+func (s *moveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"model": s.name})
+	case r.URL.Path == "/move" && r.Method == http.MethodPost:
+		var req moveRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			http.Error(w, "invalid move request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := checkMoveRequest(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		action := s.policy.Act(req.State, req.Snake)
+		s.mu.Unlock()
+		snake := req.State.Snakes[req.Snake]
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(moveResponse{
+			Action:    action.String(),
+			Direction: directionNames[ai.ActionToDirection(snake.Direction, action)],
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// checkMoveRequest rejects requests the policies can't act on
+func checkMoveRequest(req moveRequest) error {
+	if req.State == nil {
+		return fmt.Errorf("missing state")
+	}
+	if req.Snake != 0 && req.Snake != 1 {
+		return fmt.Errorf("snake must be 0 or 1, got %d", req.Snake)
+	}
+	for i, snake := range req.State.Snakes {
+		if snake == nil || len(snake.Body) == 0 {
+			return fmt.Errorf("snake %d has no body", i)
+		}
+	}
+	if !req.State.Snakes[req.Snake].Alive {
+		return fmt.Errorf("snake %d is dead", req.Snake)
+	}
+	return req.State.Validate()
+}
+
+// runServe implements the serve subcommand
+func runServe(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("serve")
+	seed := fs.Int64("seed", 0, "Random seed for random and mcts policies (0 for time-based)")
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	modelPath := fs.String("model", "models/snake_dqn.gob", `Model answering move requests, "random" or "mcts"`)
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for mcts policies")
+	moveLimit := fs.Duration("move-limit", 0, "Answer with a safe default move when the policy takes longer than this (0 for no limit)")
+	mask := fs.Bool("mask", false, "Never let the model choose a move into a wall or body while another survives")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	factory, err := LoadPolicy(*modelPath, 0, *mctsBudget)
+	if err != nil {
+		return err
+	}
+	if *mask {
+		factory = MaskPolicy(factory)
+	}
+	server := &moveServer{name: *modelPath, policy: TimedPolicy(factory, *seed, *moveLimit)}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Serving %s moves at http://%s/move", *modelPath, ln.Addr())
+	return http.Serve(ln, server)
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func TestServeMove(t *testing.T) {
+	factory, err := LoadPolicy("random", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(&moveServer{name: "random", policy: factory(1)})
+	defer server.Close()
+	state := game.NewGame(config.DefaultGameConfig(), 3).State
+
+	post := func(body any) *http.Response {
+		t.Helper()
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.Post(server.URL+"/move", "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := post(moveRequest{State: state, Snake: 1})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var move moveResponse
+	if err := json.NewDecoder(resp.Body).Decode(&move); err != nil {
+		t.Fatal(err)
+	}
+	action, err := ai.ParseAction(move.Action)
+	if err != nil {
+		t.Fatalf("action %q: %v", move.Action, err)
+	}
+	if want := directionNames[ai.ActionToDirection(state.Snakes[1].Direction, action)]; move.Direction != want {
+		t.Errorf("direction %q for action %s, want %q", move.Direction, move.Action, want)
+	}
+
+	for name, req := range map[string]moveRequest{
+		"no state":  {Snake: 0},
+		"bad snake": {State: state, Snake: 2},
+	} {
+		if resp := post(req); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", name, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
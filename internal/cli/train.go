@@ -0,0 +1,858 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func init() {
+	Register(Command{
+		Name:    "train",
+		Summary: "Train a DQN, C51, bootstrapped DQN, PPO or A2C agent through self-play (headless)",
+		Run:     runTrain,
+	})
+}
+
+// runTrain implements the train subcommand
+func runTrain(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("train")
+	trainCfg := config.DefaultTrainingConfig()
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	fs.IntVar(&trainCfg.Episodes, "episodes", 10000, "Number of training episodes")
+	fs.StringVar(&trainCfg.Algorithm, "algo", config.AlgoDQN, "Learning algorithm: dqn, c51 (distributional), bootstrap (bootstrapped DQN), ppo or a2c")
+	fs.Float64Var(&trainCfg.Distribution.VMin, "v-min", -10, "Lowest return on the c51 support")
+	fs.Float64Var(&trainCfg.Distribution.VMax, "v-max", 10, "Highest return on the c51 support")
+	fs.IntVar(&trainCfg.Bootstrap.Heads, "heads", 10, "Q-value heads of -algo bootstrap")
+	fs.Float64Var(&trainCfg.Bootstrap.MaskProb, "head-prob", 0.5, "Probability of each bootstrap head training on a transition")
+	fs.StringVar(&trainCfg.ModelPath, "model", "models/snake_dqn.gob", "Path to save/load model")
+	loadModel := fs.String("load", "", "Path to load existing model from")
+	fs.IntVar(&trainCfg.SaveFrequency, "save-freq", 500, "Save model every N episodes")
+	keepCheckpoints := fs.Int("keep-checkpoints", 0, "Keep this many earlier saves of the model as -model.1, -model.2, ... (0 for none)")
+	bufferPath := fs.String("buffer", "", "Save the replay buffer to this path with every model save and resume from it if it exists (.bin for binary, gob otherwise)")
+	configPath := fs.String("config", "", "Read game and training settings from this YAML or TOML file; flags given on the command line win")
+	resumeDir := fs.String("resume", "", "Keep the model, replay buffer and progress in this run directory, continuing the run saved there (replaces -model)")
+	logFreq := fs.Int("log-freq", 100, "Log stats every N episodes")
+	metricsPath := fs.String("metrics", "", "Write a learning curve row every -log-freq episodes to this path (JSON lines for .jsonl, CSV otherwise)")
+	dashboardAddr := fs.String("dashboard", "", "Serve a live dashboard of the learning curves and the latest episode's board on this address, e.g. :8080")
+	prometheusAddr := fs.String("prometheus", "", "Serve training counters and gauges for Prometheus at /metrics on this address, e.g. :9090 (may share -dashboard's)")
+	tensorboardDir := fs.String("tensorboard", "", "Write the -metrics and -episode-metrics values as TensorBoard scalars to an event file in this directory")
+	episodeMetricsPath := fs.String("episode-metrics", "", "Write a row per episode (length, rewards, winner, epsilon) to this path (JSON lines for .jsonl, CSV otherwise)")
+	fs.IntVar(&trainCfg.GradWorkers, "workers", 1, "Goroutines computing batch gradients (>1 uses averaged mini-batch updates, 0 one per GOMAXPROCS), or games played in parallel with -algo a2c")
+	fs.BoolVar(&trainCfg.MiniBatch, "minibatch", false, "Apply one averaged update per batch instead of one per sample (implied by -workers above 1)")
+	rewardsPath := fs.String("rewards", "", "JSON file overriding reward values (see config.RewardConfig)")
+	fs.Float64Var(&trainCfg.TraceLambda, "lambda", 0, "Learn online with Q(lambda) eligibility traces of this lambda instead of one-step replay (-algo dqn; 0 to disable)")
+	fs.BoolVar(&trainCfg.DoubleDQN, "double", false, "Double DQN targets: the policy network picks the next action, the target network values it")
+	fs.StringVar(&trainCfg.EpsilonSchedule.Kind, "epsilon-schedule", config.EpsilonPerEpisode, "Exploration schedule: episode, linear, exponential or piecewise")
+	fs.IntVar(&trainCfg.EpsilonSchedule.Steps, "epsilon-steps", 200000, "Environment steps for the linear schedule, or the exponential schedule's time constant")
+	fs.StringVar(&trainCfg.EpsilonSchedule.Points, "epsilon-points", "", "step:epsilon pairs for the piecewise schedule, e.g. 0:1,100000:0.1,500000:0.01")
+	fs.StringVar(&trainCfg.LRSchedule.Kind, "lr-schedule", config.LRConstant, "Learning rate schedule: constant, linear, cosine or step")
+	fs.IntVar(&trainCfg.LRSchedule.Steps, "lr-steps", 1000000, "Environment steps to decay the learning rate over (step: between halvings)")
+	fs.Float64Var(&trainCfg.LRSchedule.MinLR, "lr-min", 0, "Final learning rate for the linear and cosine schedules")
+	fs.Float64Var(&trainCfg.HuberDelta, "huber", 0, "Train on the Huber loss with this delta instead of squared error (0 for squared error)")
+	fs.Float64Var(&trainCfg.MaxGradNorm, "clip-grad", 0, "Clip each update's gradient to this L2 norm (0 to disable)")
+	fs.Float64Var(&trainCfg.Regularization.Dropout, "dropout", 0, "Probability of dropping each first-layer hidden unit in training forward passes")
+	fs.Float64Var(&trainCfg.Curiosity.Weight, "curiosity", 0, "Weight of the curiosity (ICM) intrinsic reward added to the learning snakes' rewards (0 to disable)")
+	fs.Float64Var(&trainCfg.Regularization.WeightDecay, "weight-decay", 0, "L2 weight decay strength applied in every update (0 to disable)")
+	fs.BoolVar(&trainCfg.Dueling, "dueling", false, "Use a dueling network with separate value and advantage streams (ignored with -load)")
+	fs.BoolVar(&trainCfg.ActionMask, "mask", false, "Never choose a move into a wall or body while another move survives, exploring included (dqn, c51 and bootstrap)")
+	fs.BoolVar(&trainCfg.Noisy, "noisy", false, "Explore with noisy network layers instead of epsilon-greedy (ignored with -load)")
+	fs.StringVar(&trainCfg.Encoder, "encoder", ai.DefaultEncoderName, "State encoder: features (22 hand-made features), grid (board planes read by a convolutional network), window (the features and a 7x7 window around the head; windowN for NxN) or food (the features, golden food and food lifetimes) (ignored with -load)")
+	fs.IntVar(&trainCfg.StackStates, "stack", 1, "Stack the last N encoded states into the network input so it can see movement (ignored with -load)")
+	fs.BoolVar(&trainCfg.Recurrent, "recurrent", false, "Use a recurrent (GRU) network that remembers earlier steps, trained on replayed sequences")
+	fs.BoolVar(&trainCfg.PrioritizedReplay, "per", false, "Sample replay by TD error (prioritized experience replay)")
+	fs.StringVar(&trainCfg.Backend, "backend", "go", "Linear algebra backend (builds with -tags blas add \"blas\")")
+	fs.Float64Var(&trainCfg.ObsNoise, "obs-noise", 0, "Standard deviation of Gaussian noise added to encoded features")
+	fs.Float64Var(&trainCfg.FeatureDropout, "feature-dropout", 0, "Probability of zeroing each encoded feature")
+	boardRange := fs.String("board-range", "", "Draw each episode's square board size from MIN-MAX")
+	foodRange := fs.String("food-range", "", "Draw each episode's pellet count from MIN-MAX")
+	findLR := fs.Bool("find-lr", false, "Run a learning rate range test first and train with the rate it picks (-episodes 0 to only test)")
+	findLRSteps := fs.Int("find-lr-steps", ai.DefaultLRFinderSteps, "Batch updates in the learning rate range test")
+	opponentPath := fs.String("opponent", "", "Train snake 0 against a fixed model, \"random\" or \"mcts\" instead of itself")
+	mctsBudget := fs.Duration("mcts-budget", 50*time.Millisecond, "Search time per move for an \"mcts\" opponent")
+	fs.IntVar(&trainCfg.Pool.Size, "pool", 0, "Train snake 0 against frozen snapshots of itself, keeping up to this many (0 for plain self-play)")
+	fs.IntVar(&trainCfg.Pool.Interval, "pool-interval", 500, "Episodes between opponent pool snapshots")
+	fs.StringVar(&trainCfg.Pool.Sampling, "pool-sampling", config.PoolUniform, "How snake 1's snapshot is drawn: uniform, recent (favouring newer) or latest")
+	evalFreq := fs.Int("eval-freq", 0, "Evaluate a greedy snapshot of the agent against -eval-opponent every N episodes (0 to disable)")
+	evalPairs := fs.Int("eval-pairs", 25, "Seeds each evaluation plays, each from both sides")
+	evalOpponentPath := fs.String("eval-opponent", RandomPolicyName, "Evaluation opponent: a model file, \"random\" or \"mcts\"")
+	patience := fs.Int("patience", 0, "Stop after this many evaluations in a row without a better score (0 to disable)")
+	targetWinRate := fs.Float64("target-win-rate", 0, "Stop once an evaluation's win rate reaches this fraction (0 to disable)")
+	timeBudget := fs.Duration("time-budget", 0, "Stop after training this long, e.g. 2h (0 for no limit)")
+	replaysPath := fs.String("replays", "", "Record every episode as a compact replay in this file")
+	replayEvery := fs.Int("replay-every", 1, "Record only every Nth episode in -replays")
+	eventsPath := fs.String("events", "", "Log every game event (food, collisions, wins) to this file as JSON lines")
+	debug := fs.Bool("debug", false, "Validate the game state after every step and stop on the first violation")
+	var snakeRewardsPath [2]string
+	fs.StringVar(&snakeRewardsPath[0], "rewards0", "", "JSON file with reward values for snake 0 only")
+	fs.StringVar(&snakeRewardsPath[1], "rewards1", "", "JSON file with reward values for snake 1 only")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// A resumed run reads the settings it was started with unless given others
+	if *configPath == "" && *resumeDir != "" {
+		if _, err := os.Stat(filepath.Join(*resumeDir, runConfigFile)); err == nil {
+			*configPath = filepath.Join(*resumeDir, runConfigFile)
+		}
+	}
+	if *configPath != "" {
+		if err := applyConfigFile(fs, *configPath, &gameFlags, &trainCfg); err != nil {
+			return fmt.Errorf("-config: %w", err)
+		}
+		log.Printf("Loaded settings from %s", *configPath)
+	}
+
+	if *replayEvery < 1 {
+		return fmt.Errorf("-replay-every must be at least 1")
+	}
+	trainCfg.GradWorkers = resolveWorkers(trainCfg.GradWorkers)
+
+	// A run directory holds everything needed to carry on an interrupted
+	// run: the agent's checkpoint, its replay buffer and the loop's progress
+	var progress trainProgress
+	if *resumeDir != "" {
+		if *loadModel != "" || *bufferPath != "" {
+			return fmt.Errorf("-resume keeps the model and replay buffer in the run directory; drop -load and -buffer")
+		}
+		if err := os.MkdirAll(*resumeDir, 0755); err != nil {
+			return fmt.Errorf("could not create run directory: %w", err)
+		}
+		p, err := loadProgress(*resumeDir)
+		if err != nil {
+			return err
+		}
+		if p.Episodes >= trainCfg.Episodes {
+			return fmt.Errorf("the run in %s has finished %d episodes already; raise -episodes to train further", *resumeDir, p.Episodes)
+		}
+		progress = p
+		trainCfg.ModelPath = filepath.Join(*resumeDir, runModelFile)
+		if *metricsPath == "" {
+			*metricsPath = filepath.Join(*resumeDir, runMetricsFile)
+		}
+		if *episodeMetricsPath == "" {
+			*episodeMetricsPath = filepath.Join(*resumeDir, runEpisodesFile)
+		}
+		if progress.Episodes > 0 {
+			*loadModel = trainCfg.ModelPath
+			if gameFlags.Seed == 0 {
+				gameFlags.Seed = progress.Seed
+			}
+		}
+	}
+	seed := gameFlags.ResolveSeed()
+
+	// Configuration
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+
+	if *boardRange != "" {
+		if trainCfg.BoardSizeMin, trainCfg.BoardSizeMax, err = parseRange(*boardRange); err != nil {
+			return fmt.Errorf("-board-range: %w", err)
+		}
+	}
+	if *foodRange != "" {
+		if trainCfg.FoodCountMin, trainCfg.FoodCountMax, err = parseRange(*foodRange); err != nil {
+			return fmt.Errorf("-food-range: %w", err)
+		}
+	}
+	if *rewardsPath != "" {
+		rewards, err := config.LoadRewardConfig(*rewardsPath)
+		if err != nil {
+			return fmt.Errorf("could not load rewards from %s: %w", *rewardsPath, err)
+		}
+		trainCfg.Rewards = rewards
+		log.Printf("Loaded rewards from %s: %+v", *rewardsPath, rewards)
+	}
+	for i, path := range snakeRewardsPath {
+		if path == "" {
+			continue
+		}
+		rewards, err := config.LoadRewardConfig(path)
+		if err != nil {
+			return fmt.Errorf("could not load snake %d rewards from %s: %w", i, path, err)
+		}
+		trainCfg.SnakeRewards[i] = &rewards
+		log.Printf("Loaded snake %d rewards from %s: %+v", i, path, rewards)
+	}
+
+	// Hyperparameters without a flag can still be set from the environment
+	if err := applyConfigEnv(fs, &trainCfg); err != nil {
+		return fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := ai.SetBackend(trainCfg.Backend); err != nil {
+		return err
+	}
+	if err := trainCfg.LRSchedule.Validate(); err != nil {
+		return err
+	}
+	if err := trainCfg.EpsilonSchedule.Validate(); err != nil {
+		return err
+	}
+	if err := trainCfg.Regularization.Validate(); err != nil {
+		return err
+	}
+	if err := trainCfg.Curiosity.Validate(); err != nil {
+		return err
+	}
+	if err := trainCfg.Pool.Validate(); err != nil {
+		return err
+	}
+	if trainCfg.Pool.Size > 0 && *opponentPath != "" {
+		return fmt.Errorf("-pool and -opponent both choose snake 1's policy; use one")
+	}
+	switch trainCfg.Algorithm {
+	case config.AlgoDQN:
+		if !trainCfg.Recurrent {
+			break
+		}
+		if err := trainCfg.Sequence.Validate(); err != nil {
+			return err
+		}
+		if trainCfg.Noisy || trainCfg.PrioritizedReplay || trainCfg.GradWorkers > 1 || trainCfg.MiniBatch {
+			return fmt.Errorf("-noisy, -per, -workers and -minibatch are not supported with -recurrent")
+		}
+	case config.AlgoC51:
+		if err := trainCfg.Distribution.Validate(); err != nil {
+			return err
+		}
+		if trainCfg.Dueling || trainCfg.HuberDelta > 0 {
+			return fmt.Errorf("-dueling and -huber are not supported with -algo %s", config.AlgoC51)
+		}
+	case config.AlgoBootstrap:
+		if err := trainCfg.Bootstrap.Validate(); err != nil {
+			return err
+		}
+		if trainCfg.Dueling {
+			return fmt.Errorf("-dueling is not supported with -algo %s", config.AlgoBootstrap)
+		}
+	case config.AlgoPPO, config.AlgoA2C:
+		validate := trainCfg.PPO.Validate
+		if trainCfg.Algorithm == config.AlgoA2C {
+			validate = trainCfg.A2C.Validate
+		}
+		if err := validate(); err != nil {
+			return err
+		}
+		if trainCfg.Dueling || trainCfg.Noisy || trainCfg.DoubleDQN || trainCfg.PrioritizedReplay || trainCfg.HuberDelta > 0 ||
+			trainCfg.Regularization.Dropout > 0 || trainCfg.MiniBatch {
+			return fmt.Errorf("-dueling, -noisy, -double, -per, -huber, -dropout and -minibatch are not supported with -algo %s", trainCfg.Algorithm)
+		}
+	default:
+		return fmt.Errorf("unknown algorithm %q (want %s, %s, %s, %s or %s)", trainCfg.Algorithm,
+			config.AlgoDQN, config.AlgoC51, config.AlgoBootstrap, config.AlgoPPO, config.AlgoA2C)
+	}
+	if trainCfg.TraceLambda != 0 {
+		if trainCfg.TraceLambda < 0 || trainCfg.TraceLambda > 1 {
+			return fmt.Errorf("-lambda must be between 0 and 1")
+		}
+		if trainCfg.Algorithm != config.AlgoDQN || trainCfg.Recurrent {
+			return fmt.Errorf("-lambda only supports -algo %s without -recurrent", config.AlgoDQN)
+		}
+		if trainCfg.Noisy || trainCfg.PrioritizedReplay || trainCfg.GradWorkers > 1 || trainCfg.MiniBatch || *bufferPath != "" || *findLR {
+			return fmt.Errorf("-noisy, -per, -workers, -minibatch, -buffer and -find-lr are not supported with -lambda")
+		}
+	}
+	if trainCfg.Recurrent && trainCfg.Algorithm != config.AlgoDQN {
+		return fmt.Errorf("-recurrent only supports -algo %s", config.AlgoDQN)
+	}
+	if trainCfg.ActionMask && (trainCfg.Recurrent || trainCfg.Algorithm == config.AlgoPPO || trainCfg.Algorithm == config.AlgoA2C) {
+		return fmt.Errorf("-mask only supports -algo %s, %s and %s without -recurrent", config.AlgoDQN, config.AlgoC51, config.AlgoBootstrap)
+	}
+	if *bufferPath != "" && (trainCfg.Algorithm == config.AlgoPPO || trainCfg.Algorithm == config.AlgoA2C || trainCfg.Recurrent) {
+		return fmt.Errorf("-buffer only supports -algo %s, %s and %s without -recurrent", config.AlgoDQN, config.AlgoC51, config.AlgoBootstrap)
+	}
+	if *findLR && (trainCfg.Algorithm != config.AlgoDQN || trainCfg.Recurrent) {
+		return fmt.Errorf("-find-lr only supports -algo %s without -recurrent", config.AlgoDQN)
+	}
+	if trainCfg.BoardSizeMax > 0 && trainCfg.BoardSizeMin < minBoard {
+		return fmt.Errorf("board sizes below %d leave no room for both snakes", minBoard)
+	}
+	if trainCfg.BoardSizeMax > 0 && gameCfg.Map != "" {
+		return fmt.Errorf("-board-range can't resize a -map board")
+	}
+
+	trainCfg.Encoder = ai.StackedEncoderName(trainCfg.Encoder, trainCfg.StackStates)
+	encoder, err := ai.LookupEncoder(trainCfg.Encoder)
+	if err != nil {
+		return err
+	}
+	trainCfg.InputSize = encoder.Size()
+	if ai.BaseEncoderName(encoder.Name()) == ai.GridEncoderName {
+		if max(gameCfg.BoardWidth, gameCfg.BoardHeight, trainCfg.BoardSizeMax) > ai.GridMaxBoard {
+			return fmt.Errorf("the grid encoder only shows boards up to %dx%d", ai.GridMaxBoard, ai.GridMaxBoard)
+		}
+		if trainCfg.Noisy && trainCfg.ConvFilters > 0 {
+			return fmt.Errorf("-noisy is not supported with -encoder %s", ai.GridEncoderName)
+		}
+	}
+
+	snakeRewards := [2]config.RewardConfig{trainCfg.RewardsFor(0), trainCfg.RewardsFor(1)}
+	var shapers [2]*ai.RewardShaper
+	for i := range shapers {
+		shaper, err := ai.NewRewardShaper(snakeRewards[i], trainCfg.Gamma)
+		if err != nil {
+			return fmt.Errorf("invalid reward shaping for snake %d: %w", i, err)
+		}
+		shapers[i] = shaper
+	}
+
+	if *findLR {
+		lr, err := findLearningRate(trainCfg, gameCfg, encoder, shapers, seed, *findLRSteps)
+		if err != nil {
+			return err
+		}
+		trainCfg.LearningRate = lr
+		if trainCfg.Episodes == 0 {
+			return nil
+		}
+	}
+
+	// Value-based agents keep their replay buffer in the run directory
+	if *resumeDir != "" && trainCfg.Algorithm != config.AlgoPPO && trainCfg.Algorithm != config.AlgoA2C &&
+		!trainCfg.Recurrent && trainCfg.TraceLambda == 0 {
+		*bufferPath = filepath.Join(*resumeDir, runBufferFile)
+	}
+
+	// Create agent. dqn is the value-based core of DQN, DRQN, Q(λ), C51 and
+	// bootstrapped DQN, ppo and a2c
+	// the actor-critic agents; they report exploration and diagnostics.
+	var agent ai.Agent
+	var dqn *ai.DQNAgent
+	var ppo *ai.PPOAgent
+	var a2c *ai.A2CAgent
+	switch trainCfg.Algorithm {
+	case config.AlgoC51:
+		c51 := ai.NewC51Agent(trainCfg, seed)
+		agent, dqn = c51, c51.DQNAgent
+	case config.AlgoBootstrap:
+		boot := ai.NewBootstrapAgent(trainCfg, seed)
+		agent, dqn = boot, boot.DQNAgent
+	case config.AlgoPPO:
+		ppo = ai.NewPPOAgent(trainCfg, seed)
+		agent = ppo
+	case config.AlgoA2C:
+		a2c = ai.NewA2CAgent(trainCfg, seed)
+		agent = a2c
+	default:
+		if trainCfg.Recurrent {
+			drqn := ai.NewDRQNAgent(trainCfg, seed)
+			agent, dqn = drqn, drqn.DQNAgent
+			break
+		}
+		if trainCfg.TraceLambda > 0 {
+			trace := ai.NewTraceAgent(trainCfg, seed)
+			agent, dqn = trace, trace.DQNAgent
+			break
+		}
+		dqn = ai.NewDQNAgent(trainCfg, seed)
+		agent = dqn
+	}
+	epsilon := func() float64 {
+		if dqn == nil {
+			return 0
+		}
+		return dqn.Epsilon
+	}
+
+	// Load existing model if specified
+	if *loadModel != "" {
+		if err := agent.Load(*loadModel); err != nil && progress.Episodes > 0 {
+			return fmt.Errorf("could not resume from %s: %w", *loadModel, err)
+		} else if err != nil {
+			log.Printf("Warning: Could not load model from %s: %v", *loadModel, err)
+		} else if dqn != nil {
+			log.Printf("Loaded %s model from %s", dqn.PolicyNet.Architecture(), *loadModel)
+			if dqn.StepCount > 0 {
+				log.Printf("Resuming from step %d with epsilon %.4f", dqn.StepCount, dqn.Epsilon)
+			}
+		} else {
+			log.Printf("Loaded model from %s", *loadModel)
+		}
+	}
+	encoder = agent.Encoder()
+	stampModel := modelStamper(agent, trainCfg)
+
+	// saveModel writes the model after moving the last save to the backups.
+	// Actor-critic agents keep their critic beside it. A resumed model's
+	// metadata already counts the episodes before this run.
+	saveModel := func(episodes int) error {
+		for _, path := range []string{trainCfg.ModelPath, ai.CriticPath(trainCfg.ModelPath)} {
+			if err := ai.RotateBackups(path, *keepCheckpoints); err != nil {
+				return fmt.Errorf("could not keep a backup of %s: %w", path, err)
+			}
+		}
+		stampModel(episodes - progress.Episodes)
+		return agent.Save(trainCfg.ModelPath)
+	}
+
+	// Resume the replay buffer saved by an earlier run, so training doesn't
+	// start over from an empty one
+	bufferCodec := ai.CodecGob
+	if filepath.Ext(*bufferPath) == ".bin" {
+		bufferCodec = ai.CodecFlat
+	}
+	if *bufferPath != "" {
+		if _, err := os.Stat(*bufferPath); err == nil {
+			if err := dqn.LoadReplay(*bufferPath); err != nil {
+				log.Printf("Warning: Could not load replay buffer: %v", err)
+			} else {
+				log.Printf("Loaded %d experiences from %s", dqn.ReplayBuffer.Size(), *bufferPath)
+			}
+		}
+	}
+	saveBuffer := func() {
+		if *bufferPath == "" {
+			return
+		}
+		if err := dqn.SaveReplay(*bufferPath, bufferCodec); err != nil {
+			log.Printf("Warning: Could not save replay buffer: %v", err)
+		}
+	}
+
+	// Snake 1 plays itself unless given a fixed opponent
+	var opponent PolicyFactory
+	if *opponentPath != "" {
+		if opponent, err = LoadPolicy(*opponentPath, 0, *mctsBudget); err != nil {
+			return err
+		}
+		log.Printf("Training snake 0 against %s", *opponentPath)
+	}
+
+	// Early stopping judges evaluations of snapshots against a fixed
+	// opponent, besides the clock. A resumed run carries on the patience
+	// count.
+	stop := stopRules{Patience: *patience, TargetWinRate: *targetWinRate, Budget: *timeBudget, stopState: progress.Stop}
+	var evalOpponent PolicyFactory
+	if *evalFreq > 0 {
+		if _, ok := agent.(ai.SnapshotAgent); !ok {
+			return fmt.Errorf("-eval-freq is not supported with -algo %s", trainCfg.Algorithm)
+		}
+		if *evalPairs < 1 {
+			return fmt.Errorf("-eval-pairs must be at least 1")
+		}
+		if evalOpponent, err = LoadPolicy(*evalOpponentPath, 0, *mctsBudget); err != nil {
+			return err
+		}
+	} else if *patience > 0 || *targetWinRate > 0 {
+		return fmt.Errorf("-patience and -target-win-rate judge evaluations; set -eval-freq too")
+	}
+	if *targetWinRate < 0 || *targetWinRate > 1 {
+		return fmt.Errorf("-target-win-rate must be between 0 and 1")
+	}
+
+	// With a pool, snake 1 plays past snapshots of the agent once the
+	// first is taken
+	var pool *ai.OpponentPool
+	var snapshots ai.SnapshotAgent
+	if trainCfg.Pool.Size > 0 {
+		var ok bool
+		if snapshots, ok = agent.(ai.SnapshotAgent); !ok {
+			return fmt.Errorf("-pool is not supported with -algo %s", trainCfg.Algorithm)
+		}
+		pool = ai.NewOpponentPool(trainCfg.Pool, seed+7)
+		log.Printf("Training snake 0 against up to %d snapshots taken every %d episodes (%s sampling)",
+			trainCfg.Pool.Size, trainCfg.Pool.Interval, trainCfg.Pool.Sampling)
+	}
+
+	// A2C plays -workers games at once, each worker in its own goroutine
+	// and environment; every other agent plays one
+	envWorkers := 1
+	if a2c != nil {
+		envWorkers = max(trainCfg.GradWorkers, 1)
+	}
+	newEnv := func(w int) *trainEnv {
+		// Worker 0 keeps the seeds of single-environment training
+		workerSeed := seed + int64(w)*1_000_003
+		env := &trainEnv{
+			game:     game.NewGame(gameCfg, seed),
+			gameCfg:  gameCfg,
+			encoder:  agent.Encoder(), // Its own state history
+			shapers:  shapers,
+			stall:    ai.NewStallPenalty(snakeRewards),
+			noise:    ai.NewObservationNoise(trainCfg.ObsNoise, trainCfg.FeatureDropout, workerSeed+1),
+			mask:     trainCfg.ActionMask,
+			maxSteps: trainCfg.MaxStepsPerEp,
+			debug:    *debug,
+		}
+		env.game.Rewards = snakeRewards
+		if *eventsPath != "" {
+			env.game.AddListener(func(e game.Event) { env.events = append(env.events, e) })
+		}
+		if trainCfg.Curiosity.Weight > 0 {
+			env.curiosity = ai.NewCuriosity(trainCfg.Curiosity, encoder.Size(), workerSeed+6)
+		}
+		if opponent != nil {
+			env.opponents[1] = opponent(workerSeed + 3)
+		}
+		for i := range env.states {
+			env.states[i] = make([]float64, encoder.Size())
+			env.nextStates[i] = make([]float64, encoder.Size())
+		}
+		return env
+	}
+
+	// Domain randomization
+	domainRng := rand.New(rand.NewSource(seed + 2))
+
+	// Every episode gets its own game seed so it can be replayed alone
+	episodeSeeds := rand.New(rand.NewSource(seed + 4))
+	// The run's totals, carried on from a resumed run, and the sinks it
+	// reports to besides the log
+	progress.Seed = seed
+	run, err := newTrainRun(progress, trainSinkPaths{
+		Metrics:        *metricsPath,
+		EpisodeMetrics: *episodeMetricsPath,
+		TensorBoard:    *tensorboardDir,
+		Dashboard:      *dashboardAddr,
+		Prometheus:     *prometheusAddr,
+		Replays:        *replaysPath,
+		Events:         *eventsPath,
+	})
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	run.epsilon = epsilon
+	run.loss = func() float64 {
+		switch {
+		case dqn != nil:
+			return dqn.Diagnostics().AvgLoss
+		case ppo != nil:
+			return ppo.Stats().ValueLoss
+		case a2c != nil:
+			return a2c.Stats().ValueLoss
+		}
+		return 0
+	}
+	if dqn != nil {
+		run.buffer = func() (int, int) { return dqn.ReplayBuffer.Size(), dqn.ReplayBuffer.Capacity() }
+	}
+
+	// nextEpisode draws the setup of episode ep. Episodes are drawn in
+	// order, so they get the same setups however many workers play them.
+	nextEpisode := func(ep int) *trainEpisode {
+		e := &trainEpisode{index: ep, foodCount: gameCfg.FoodCount, record: run.replays != nil && ep%*replayEvery == 0, snapshot: run.dash != nil}
+		if trainCfg.BoardSizeMax > 0 {
+			e.boardSize = randomInRange(domainRng, trainCfg.BoardSizeMin, trainCfg.BoardSizeMax)
+		}
+		if trainCfg.FoodCountMax > 0 {
+			e.foodCount = randomInRange(domainRng, trainCfg.FoodCountMin, trainCfg.FoodCountMax)
+		}
+		e.seed = episodeSeeds.Int63()
+		if pool != nil {
+			e.opponent = pool.Sample()
+		}
+		return e
+	}
+
+	// saveProgress records the episodes finished in the run directory, once
+	// the model and buffer they trained are saved
+	saveProgress := func() {
+		if *resumeDir == "" {
+			return
+		}
+		if err := run.saveProgress(*resumeDir, stop.stopState); err != nil {
+			log.Printf("Warning: Could not save progress: %v", err)
+		}
+	}
+
+	if progress.Episodes > 0 {
+		log.Printf("Resuming the run in %s at episode %d", *resumeDir, progress.Episodes+1)
+	}
+	// Record the settings the model trains with beside it, for -config
+	configFile := configFilePath(trainCfg.ModelPath)
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		log.Printf("Warning: Could not create %s: %v", filepath.Dir(configFile), err)
+	}
+	if err := config.WriteFile(configFile, gameCfg, trainCfg); err != nil {
+		log.Printf("Warning: Could not write %s: %v", configFile, err)
+	}
+	log.Printf("Starting training for %d episodes...", trainCfg.Episodes)
+	log.Printf("Board: %dx%d, Epsilon: %.2f -> %.2f", gameCfg.BoardWidth, gameCfg.BoardHeight, trainCfg.EpsilonStart, trainCfg.EpsilonMin)
+	if gameCfg.Map != "" {
+		log.Printf("Map: %s", gameCfg.Map)
+	}
+	if envWorkers > 1 {
+		log.Printf("Playing on %d workers", envWorkers)
+	}
+
+	run.start = time.Now()
+	startTime := run.start
+
+	// Ctrl-C or SIGTERM stops training once the episode in play is done,
+	// saving and summarizing it like a finished run; a second one quits
+	// at once
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	// finishEpisode records a played episode as the ep-th to finish, then
+	// logs and saves on schedule
+	finishEpisode := func(e *trainEpisode, ep int) error {
+		if e.err != nil {
+			return e.err
+		}
+		if err := run.record(e, ep); err != nil {
+			return err
+		}
+
+		// Log progress
+		if ep%*logFreq == 0 {
+			row, interval := run.endInterval(ep)
+			totals := run.totals
+			lrNote := ""
+			switch {
+			case dqn != nil && dqn.LRSchedule != nil:
+				lrNote = fmt.Sprintf(" | LR: %.3g", dqn.LearningRate())
+			case ppo != nil && ppo.LRSchedule != nil:
+				lrNote = fmt.Sprintf(" | LR: %.3g", ppo.LearningRate())
+			case a2c != nil && a2c.LRSchedule != nil:
+				lrNote = fmt.Sprintf(" | LR: %.3g", a2c.LearningRate())
+			}
+			log.Printf("Episode %d/%d | Epsilon: %.4f | Avg Length: %.1f | Wins: %d/%d | Ties: %d (%d repeated) | %.1f eps/s%s",
+				ep, trainCfg.Episodes, row.Epsilon, row.AvgLength, totals.Wins[0], totals.Wins[1], totals.Ties, totals.Repeated, row.EpsPerSec, lrNote)
+			if dqn != nil {
+				if diag := dqn.Diagnostics(); diag.Updates > 0 {
+					log.Printf("  Loss: %.5f | TD |err| mean %.4f p50 %.4f p90 %.4f max %.4f | Grad norm: %.4f | Target drift: %.2f%%",
+						diag.AvgLoss, diag.TDError.Mean, diag.TDError.P50, diag.TDError.P90, diag.TDError.Max,
+						diag.GradNorm, 100*diag.TargetDivergence)
+				}
+			}
+			if measured, ok := agent.(ai.LayerStatsAgent); ok && dqn.ReplayBuffer.Size() > 0 {
+				var layers []string
+				for _, layer := range measured.LayerStats() {
+					s := fmt.Sprintf("%s |W| %.3g |grad| %.3g", layer.Name, layer.WeightNorm, layer.GradNorm)
+					if layer.Name == "layer1" || layer.Name == "layer2" && !dqn.PolicyNet.Recurrent {
+						s += fmt.Sprintf(" dead %.1f%%", 100*layer.DeadFraction)
+					}
+					layers = append(layers, s)
+				}
+				log.Printf("  Layers: %s", strings.Join(layers, " | "))
+			}
+			if ppo != nil {
+				if stats := ppo.Stats(); stats.Updates > 0 {
+					log.Printf("  Updates: %d | Policy loss: %.5f | Value loss: %.5f | Entropy: %.3f | Clipped: %.1f%%",
+						stats.Updates, stats.PolicyLoss, stats.ValueLoss, stats.Entropy, 100*stats.ClipFraction)
+				}
+			}
+			if a2c != nil {
+				if stats := a2c.Stats(); stats.Updates > 0 {
+					log.Printf("  Updates: %d | Policy loss: %.5f | Value loss: %.5f | Entropy: %.3f",
+						stats.Updates, stats.PolicyLoss, stats.ValueLoss, stats.Entropy)
+				}
+			}
+
+			if trainCfg.Curiosity.Weight > 0 {
+				log.Printf("  Curiosity reward: %.4f per turn", interval.intrinsic/(row.AvgLength*float64(len(interval.lengths))))
+			}
+			if pool != nil {
+				log.Printf("  Opponent pool: %d snapshots", pool.Len())
+			}
+		}
+
+		if pool != nil && ep%trainCfg.Pool.Interval == 0 {
+			pool.Add(snapshots.Snapshot())
+		}
+
+		// Evaluate before saving, so the saved progress counts the
+		// evaluation towards -patience
+		if *evalFreq > 0 && ep%*evalFreq == 0 {
+			snapshot := agent.(ai.SnapshotAgent).Snapshot()
+			result := evaluateSnapshot(gameCfg, snapshot, trainCfg.ActionMask, evalOpponent, *evalPairs, seed+11, trainCfg.MaxStepsPerEp)
+			log.Printf("  Evaluation vs %s: score %.1f%% | win rate %.1f%%", *evalOpponentPath, 100*result.Score, 100*result.WinRate)
+			if reason := stop.evaluated(ep, result); reason != "" {
+				log.Printf("Stopping after episode %d: %s", ep, reason)
+				return errStoppedEarly
+			}
+		}
+
+		// Save model
+		if ep%trainCfg.SaveFrequency == 0 {
+			if err := os.MkdirAll("models", 0755); err != nil {
+				log.Printf("Warning: Could not create models directory: %v", err)
+			}
+			err := saveModel(ep)
+			if err != nil {
+				log.Printf("Warning: Could not save model: %v", err)
+			} else {
+				log.Printf("Saved model to %s", trainCfg.ModelPath)
+			}
+			saveBuffer()
+			if err == nil {
+				saveProgress()
+			}
+		}
+		if reason := stop.timeUp(time.Since(startTime)); reason != "" {
+			log.Printf("Stopping after episode %d: %s", ep, reason)
+			return errStoppedEarly
+		}
+		select {
+		case sig := <-interrupt:
+			signal.Stop(interrupt)
+			log.Printf("Received %v, stopping after episode %d (again to quit without saving)", sig, ep)
+			return errInterrupted
+		default:
+		}
+		return nil
+	}
+
+	// Draw the finished episodes' setups again, so a resumed run plays the
+	// same boards and seeds as one that was never interrupted
+	first := progress.Episodes + 1
+	for ep := 1; ep < first; ep++ {
+		nextEpisode(ep)
+	}
+
+	var runErr error
+	if envWorkers == 1 {
+		env := newEnv(0)
+		for ep := first; ep <= trainCfg.Episodes; ep++ {
+			e := nextEpisode(ep)
+			env.play(e, agent)
+			if runErr = finishEpisode(e, ep); runErr != nil {
+				break
+			}
+		}
+	} else {
+		runErr = trainParallel(a2c, envWorkers, first, trainCfg.Episodes, seed, newEnv, nextEpisode, finishEpisode)
+	}
+	if runErr != nil && !errors.Is(runErr, errInterrupted) && !errors.Is(runErr, errStoppedEarly) {
+		return runErr
+	}
+
+	// Final save
+	finished := run.totals.Episodes
+	if err := os.MkdirAll("models", 0755); err != nil {
+		log.Printf("Warning: Could not create models directory: %v", err)
+	}
+	err = saveModel(finished)
+	if err != nil {
+		log.Printf("Error saving final model: %v", err)
+	} else if finished < trainCfg.Episodes {
+		log.Printf("Training stopped after %d of %d episodes. Model saved to %s", finished, trainCfg.Episodes, trainCfg.ModelPath)
+	} else {
+		log.Printf("Training complete. Model saved to %s", trainCfg.ModelPath)
+	}
+	saveBuffer()
+	if err == nil {
+		saveProgress()
+	}
+
+	// Print final stats
+	elapsed := time.Since(startTime)
+	fmt.Printf("\n=== Training Summary ===\n")
+	fmt.Printf("Episodes: %d\n", finished)
+	fmt.Printf("Total Time: %v\n", elapsed.Round(time.Second))
+	fmt.Printf("Episodes/sec: %.1f\n", float64(finished-progress.Episodes)/elapsed.Seconds())
+	totals := run.totals
+	fmt.Printf("Snake 0 Wins: %d (%.1f%%)\n", totals.Wins[0], 100*float64(totals.Wins[0])/float64(finished))
+	fmt.Printf("Snake 1 Wins: %d (%.1f%%)\n", totals.Wins[1], 100*float64(totals.Wins[1])/float64(finished))
+	fmt.Printf("Ties: %d (%.1f%%)\n", totals.Ties, 100*float64(totals.Ties)/float64(finished))
+	fmt.Printf("Repeated Positions: %d (%.1f%%)\n", totals.Repeated, 100*float64(totals.Repeated)/float64(finished))
+	fmt.Printf("Final Epsilon: %.4f\n", epsilon())
+	return nil
+}
+
+// errInterrupted stops the training loop after a signal
+var errInterrupted = errors.New("training interrupted")
+
+// randomInRange returns a uniform integer in [lo, hi]
+func randomInRange(rng *rand.Rand, lo, hi int) int {
+	return lo + rng.Intn(hi-lo+1)
+}
+
+// lrFinderWarmup is how many random-play transitions are collected before a
+// learning rate range test
+const lrFinderWarmup = 5000
+
+// findLearningRate fills a throwaway agent's replay buffer with random play,
+// runs a learning rate range test on it and returns the suggested rate
+func findLearningRate(cfg config.TrainingConfig, gameCfg config.GameConfig, encoder ai.Encoder,
+	shapers [2]*ai.RewardShaper, seed int64, steps int) (float64, error) {
+	agent := ai.NewDQNAgent(cfg, seed)
+	agent.SetEpsilon(1)
+	g := game.NewGame(gameCfg, seed)
+	g.Rewards = [2]config.RewardConfig{cfg.RewardsFor(0), cfg.RewardsFor(1)}
+
+	var states, nextStates [2][]float64
+	for i := range states {
+		states[i] = make([]float64, encoder.Size())
+		nextStates[i] = make([]float64, encoder.Size())
+	}
+	var actions [2]ai.Action
+	var dirs [2]game.Direction
+	for agent.ReplayBuffer.Size() < max(lrFinderWarmup, cfg.BatchSize) {
+		state := g.Reset()
+		for turn := 0; !state.GameOver && turn < cfg.MaxStepsPerEp; turn++ {
+			for i := range actions {
+				encoder.Encode(states[i], state, i)
+				actions[i] = agent.SelectAction(states[i])
+				dirs[i] = ai.ActionToDirection(state.Snakes[i].Direction, actions[i])
+			}
+			prevState := g.State.Clone()
+			result := g.Step(dirs)
+			for i := range actions {
+				if !prevState.Snakes[i].Alive {
+					continue
+				}
+				encoder.Encode(nextStates[i], state, i)
+				reward := result.Rewards[i] + shapers[i].Reward(prevState, state, i)
+				agent.Remember(states[i], actions[i], reward, nextStates[i], result.Died[i] || result.GameOver)
+			}
+		}
+	}
+
+	finder := ai.NewLRFinder()
+	finder.Steps = steps
+	log.Printf("Learning rate range test: %d updates from %g to %g on %d transitions",
+		finder.Steps, finder.Min, finder.Max, agent.ReplayBuffer.Size())
+	points := finder.Run(agent)
+	lr := ai.SuggestLR(points)
+	if lr == 0 {
+		return 0, fmt.Errorf("learning rate range test recorded no usable loss")
+	}
+
+	// Log about ten points along the curve
+	every := max(len(points)/10, 1)
+	for i, p := range points {
+		if i%every == 0 || i == len(points)-1 {
+			log.Printf("  lr %.2e  loss %.5f  smoothed %.5f", p.LR, p.Loss, p.Smoothed)
+		}
+	}
+	log.Printf("Suggested learning rate: %.3g", lr)
+	return lr, nil
+}
+
+// modelStamper returns a function that sets the metadata the agent's next
+// save records after the given episodes of this run, counted on from those
+// of a loaded model. Agents without metadata ignore it.
+func modelStamper(agent ai.Agent, cfg config.TrainingConfig) func(episodes int) {
+	meta, ok := agent.(ai.MetadataAgent)
+	if !ok {
+		return func(int) {}
+	}
+	prior := 0
+	if m := meta.Metadata(); m != nil {
+		prior = m.Episodes
+	}
+	return func(episodes int) {
+		meta.SetMetadata(ai.NewModelMetadata(prior+episodes, cfg))
+	}
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/replay"
+)
+
+// trainEnv is a game the train command plays episodes in, with everything
+// that keeps per-game state. Parallel training gives every worker its own.
+type trainEnv struct {
+	game      *game.Game
+	gameCfg   config.GameConfig
+	encoder   ai.Encoder
+	opponents [2]ai.Policy // Fixed policies; nil snakes learn
+	shapers   [2]*ai.RewardShaper
+	stall     *ai.StallPenalty
+	curiosity *ai.Curiosity // nil without an intrinsic reward
+	noise     *ai.ObservationNoise
+	mask      bool // Learning snakes never choose a move that dies next turn, see ai.SurvivalMask
+	maxSteps  int
+	debug     bool
+
+	// Game events of the episode in play, when they are logged
+	events []game.Event
+
+	// Encoded state buffers, reused every step. Remember copies them, so
+	// overwriting them afterwards is safe.
+	states, nextStates [2][]float64
+}
+
+// trainEpisode is the setup of one training episode and, once played, its
+// outcome
+type trainEpisode struct {
+	index     int
+	seed      int64
+	boardSize int // 0 keeps the configured board
+	foodCount int
+	record    bool         // Record a replay
+	snapshot  bool         // Keep the final position
+	opponent  *ai.QNetwork // Frozen snapshot playing snake 1, or nil
+
+	steps  int
+	reward [2]float64
+	winner int // Winning snake, or -1
+	replay *replay.Replay
+	final  *game.GameState // The last position, with snapshot
+	events []game.Event    // Logged game events, see game.EventListener
+	err    error
+
+	// repeated marks a tie drawn on a repeated position
+	repeated bool
+
+	// Curiosity reward given to the learning snakes, not part of reward
+	intrinsic float64
+}
+
+// play runs episode e, letting agent act for and learn from every snake
+// without a fixed opponent or e.opponent, and fills in its outcome
+func (env *trainEnv) play(e *trainEpisode, agent ai.Agent) {
+	g := env.game
+	if e.boardSize > 0 {
+		g.Resize(e.boardSize, e.boardSize)
+	}
+	g.SetFoodCount(e.foodCount)
+	g.Reseed(e.seed)
+	state := g.Reset()
+	if e.record {
+		recCfg := env.gameCfg
+		recCfg.BoardWidth, recCfg.BoardHeight, recCfg.FoodCount = state.Width, state.Height, e.foodCount
+		e.replay = &replay.Replay{Index: e.index, Seed: e.seed, Game: recCfg}
+	}
+	env.stall.Reset()
+	snakeAgent, _ := agent.(ai.SnakeAgent)
+	recurrentAgent, _ := agent.(ai.RecurrentAgent)
+	maskedAgent, _ := agent.(ai.MaskedAgent)
+	var actions [2]ai.Action
+	opponents := env.opponents
+	if e.opponent != nil {
+		opponents[1] = ai.NewNetworkPolicy(e.opponent, 0, e.seed)
+	}
+
+	for !state.GameOver && e.steps < env.maxSteps {
+		e.steps++
+
+		// Learning snakes act on their encoded state, fixed opponents
+		// on the game state
+		for i, opponent := range opponents {
+			if opponent != nil {
+				actions[i] = opponent.Act(state, i)
+				continue
+			}
+			env.encoder.Encode(env.states[i], state, i)
+			if env.noise.Enabled() {
+				env.noise.Apply(env.states[i])
+			}
+			switch {
+			case recurrentAgent != nil:
+				actions[i] = recurrentAgent.SelectActionSnake(i, env.states[i])
+			case env.mask && maskedAgent != nil:
+				actions[i] = maskedAgent.SelectActionMasked(env.states[i], ai.SurvivalMask(state, i))
+			default:
+				actions[i] = agent.SelectAction(env.states[i])
+			}
+		}
+
+		// Convert to directions
+		var dirs [2]game.Direction
+		for i, action := range actions {
+			dirs[i] = ai.ActionToDirection(state.Snakes[i].Direction, action)
+		}
+
+		// Store previous state for shaping reward. Cloning the state
+		// rather than the game leaves the food RNG alone, keeping the
+		// episode replayable.
+		prevState := state.Clone()
+
+		// Step game
+		result := g.Step(dirs)
+		if e.replay != nil {
+			e.replay.Add(actions)
+		}
+		if env.debug {
+			if err := state.Validate(); err != nil {
+				e.err = fmt.Errorf("episode %d, step %d: invalid game state: %w", e.index, e.steps, err)
+				return
+			}
+		}
+
+		for i := range actions {
+			// Calculate total rewards including shaping
+			reward := result.Rewards[i] + env.shapers[i].Reward(prevState, state, i)
+			if env.stall.Enabled() {
+				reward += env.stall.Penalty(state, i, result.AteFood[i])
+			}
+			e.reward[i] += reward
+
+			// Store the learning snakes' experiences; a snake already out
+			// of the game, like snake 1 under the solo rules, has none
+			if opponents[i] == nil && prevState.Snakes[i].Alive {
+				env.encoder.Encode(env.nextStates[i], state, i)
+				if env.noise.Enabled() {
+					env.noise.Apply(env.nextStates[i])
+				}
+				// Nothing in the state tells a snake the turn limit is
+				// near, or how often it has seen a position, so the
+				// return past either is bootstrapped like the one past
+				// -max-steps
+				done := result.Died[i] || result.GameOver && !result.TimedOut && !result.Repeated
+				if env.curiosity != nil {
+					bonus := env.curiosity.Reward(env.states[i], actions[i], env.nextStates[i])
+					reward += bonus
+					e.intrinsic += bonus
+				}
+				if snakeAgent != nil {
+					snakeAgent.RememberSnake(i, env.states[i], actions[i], reward, env.nextStates[i], done)
+				} else {
+					agent.Remember(env.states[i], actions[i], reward, env.nextStates[i], done)
+				}
+			}
+		}
+
+		// Train
+		agent.Train()
+	}
+
+	if e.replay != nil {
+		e.replay.Finish(state)
+	}
+	if e.snapshot {
+		e.final = state.Clone()
+	}
+	e.events, env.events = env.events, nil
+	e.winner = state.Winner
+	e.repeated = state.Repeated
+
+	// Decay epsilon
+	agent.DecayEpsilon()
+}
+
+// trainParallel plays episodes first through episodes on workers
+// goroutines, each with its own environment and A2C worker, and hands them
+// to finish in the order they end. The first error stops the run once the
+// episodes in play are done.
+func trainParallel(agent *ai.A2CAgent, workers, first, episodes int, seed int64, newEnv func(w int) *trainEnv,
+	nextEpisode func(ep int) *trainEpisode, finish func(e *trainEpisode, ep int) error) error {
+	jobs := make(chan *trainEpisode)
+	finished := make(chan *trainEpisode, workers)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		env, worker := newEnv(w), agent.NewWorker(seed+int64(w)*1_000_003+5)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				env.play(e, worker)
+				finished <- e
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for ep := first; ep <= episodes; ep++ {
+			select {
+			case jobs <- nextEpisode(ep):
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	// Keep draining after an error so the workers can finish
+	var err error
+	done := first - 1
+	for e := range finished {
+		if err != nil {
+			continue
+		}
+		done++
+		if err = finish(e, done); err != nil {
+			close(stop)
+		}
+	}
+	return err
+}
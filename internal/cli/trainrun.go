@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"autonomous-snake/internal/dashboard"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/metrics"
+	"autonomous-snake/internal/replay"
+)
+
+// trainSinkPaths say where a training run reports to besides the log, each
+// off when empty
+type trainSinkPaths struct {
+	Metrics        string // Learning curve rows, one per log interval
+	EpisodeMetrics string // A row per episode
+	TensorBoard    string // Directory of the event file
+	Dashboard      string // Address of the live dashboard
+	Prometheus     string // Address of /metrics, possibly the dashboard's
+	Replays        string // Replays of recorded episodes
+	Events         string // Game events as JSON lines
+}
+
+// trainInterval is what a log interval's episodes add up to
+type trainInterval struct {
+	lengths   []int // Turns of each episode
+	wins      [2]int
+	ties      int
+	rewards   [2]float64
+	intrinsic float64 // Curiosity reward, see trainEpisode
+}
+
+// trainRun is the bookkeeping of a training run: its totals, the log
+// interval in progress and the sinks both are reported to. Training hands
+// it finished episodes; what it needs of the agent it asks through the
+// hooks, so it can be driven without training anything.
+type trainRun struct {
+	totals  trainProgress // Carried on from a resumed run
+	resumed trainProgress // The totals the run started from
+	start   time.Time
+	current trainInterval
+
+	// Hooks reading the agent, which may be left nil
+	epsilon func() float64
+	loss    func() float64         // Mean recent training loss
+	buffer  func() (size, cap int) // Replay buffer fill, for agents with one
+
+	curve, episodeLog *metrics.Writer
+	board             *metrics.EventWriter
+	dash              *dashboard.Server
+	exporter          *metrics.Exporter
+	replays           *replay.Writer
+	events            *json.Encoder
+	closers           []func() error // Called last first, like deferred calls
+}
+
+// newTrainRun opens the sinks named by paths for a run continuing from
+// progress, which is zero for a new one. The run starts its clock now and
+// must be closed.
+func newTrainRun(progress trainProgress, paths trainSinkPaths) (r *trainRun, err error) {
+	r = &trainRun{totals: progress, resumed: progress, start: time.Now()}
+	defer func() {
+		if err != nil {
+			r.Close()
+		}
+	}()
+
+	// Learning curve, summarizing each log interval, and the episodes
+	// themselves. A resumed run adds to the files it started, past the
+	// episodes it has saved.
+	if paths.Metrics != "" {
+		if r.curve, err = metrics.OpenRows(paths.Metrics, progress.Episodes); err != nil {
+			return r, fmt.Errorf("could not open metrics file: %w", err)
+		}
+		r.closers = append(r.closers, r.curve.Close)
+	}
+	if paths.EpisodeMetrics != "" {
+		if r.episodeLog, err = metrics.OpenEpisodes(paths.EpisodeMetrics, progress.Episodes); err != nil {
+			return r, fmt.Errorf("could not open episode metrics file: %w", err)
+		}
+		r.closers = append(r.closers, r.episodeLog.Close)
+	}
+	if paths.TensorBoard != "" {
+		if r.board, err = metrics.NewEventWriter(paths.TensorBoard); err != nil {
+			return r, fmt.Errorf("could not create TensorBoard event file: %w", err)
+		}
+		r.closers = append(r.closers, r.board.Close)
+		log.Printf("TensorBoard scalars: %s", r.board.Path)
+	}
+	if paths.Dashboard != "" {
+		r.dash = dashboard.New()
+		addr, err := r.dash.Listen(paths.Dashboard)
+		if err != nil {
+			return r, fmt.Errorf("could not start dashboard: %w", err)
+		}
+		r.closers = append(r.closers, r.dash.Close)
+		log.Printf("Dashboard: http://%s/", addr)
+	}
+	if paths.Prometheus != "" {
+		r.exporter = metrics.NewExporter()
+		if r.dash != nil && paths.Prometheus == paths.Dashboard {
+			r.dash.Handle("GET /metrics", r.exporter)
+		} else {
+			mux := http.NewServeMux()
+			mux.Handle("GET /metrics", r.exporter)
+			ln, err := net.Listen("tcp", paths.Prometheus)
+			if err != nil {
+				return r, fmt.Errorf("could not serve Prometheus metrics: %w", err)
+			}
+			server := &http.Server{Handler: mux}
+			go server.Serve(ln)
+			r.closers = append(r.closers, server.Close)
+			log.Printf("Prometheus metrics: http://%s/metrics", ln.Addr())
+		}
+	}
+	// A resumed run appends to its replays and events
+	resume := progress.Episodes > 0
+	if paths.Replays != "" {
+		file, err := openSink(paths.Replays, resume, os.O_RDWR)
+		if err != nil {
+			return r, fmt.Errorf("could not open replay file: %w", err)
+		}
+		r.closers = append(r.closers, file.Close)
+		if resume {
+			r.replays, err = replay.NewAppendWriter(file)
+		} else {
+			r.replays, err = replay.NewWriter(file)
+		}
+		if err != nil {
+			return r, fmt.Errorf("could not write replay file: %w", err)
+		}
+		r.closers = append(r.closers, r.replays.Flush)
+	}
+	if paths.Events != "" {
+		file, err := openSink(paths.Events, resume, os.O_WRONLY)
+		if err != nil {
+			return r, fmt.Errorf("could not open event log: %w", err)
+		}
+		w := bufio.NewWriter(file)
+		r.closers = append(r.closers, file.Close, w.Flush)
+		r.events = json.NewEncoder(w)
+	}
+	return r, nil
+}
+
+// openSink opens the file at path in mode, os.O_WRONLY or os.O_RDWR, to
+// append to it when resuming and to start it over otherwise
+func openSink(path string, resume bool, mode int) (*os.File, error) {
+	if resume {
+		return os.OpenFile(path, mode|os.O_APPEND|os.O_CREATE, 0644)
+	}
+	return os.OpenFile(path, mode|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// Close flushes and closes the sinks, returning the first error
+func (r *trainRun) Close() error {
+	var first error
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if err := r.closers[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+	r.closers = nil
+	return first
+}
+
+// currentEpsilon returns the agent's exploration rate, or 0 without a hook
+func (r *trainRun) currentEpsilon() float64 {
+	if r.epsilon == nil {
+		return 0
+	}
+	return r.epsilon()
+}
+
+// record adds a played episode, the ep-th to finish, to the totals and the
+// interval and reports it to the sinks. Only a failed replay or event log
+// is an error; the metrics sinks warn and go on.
+func (r *trainRun) record(e *trainEpisode, ep int) error {
+	if e.replay != nil && r.replays != nil {
+		if err := r.replays.Write(e.replay); err != nil {
+			return fmt.Errorf("could not write replay: %w", err)
+		}
+	}
+	if r.events != nil {
+		for _, event := range e.events {
+			line := struct {
+				Episode int `json:"episode"`
+				game.Event
+			}{e.index, event}
+			if err := r.events.Encode(line); err != nil {
+				return fmt.Errorf("could not write event log: %w", err)
+			}
+		}
+	}
+
+	r.totals.Episodes = ep
+	r.totals.Steps += e.steps
+	for i := range r.totals.Rewards {
+		r.totals.Rewards[i] += e.reward[i]
+		r.current.rewards[i] += e.reward[i]
+	}
+	r.current.lengths = append(r.current.lengths, e.steps)
+	r.current.intrinsic += e.intrinsic
+	if e.winner == 0 || e.winner == 1 {
+		r.totals.Wins[e.winner]++
+		r.current.wins[e.winner]++
+	} else {
+		r.totals.Ties++
+		r.current.ties++
+	}
+	if e.repeated {
+		r.totals.Repeated++
+	}
+
+	if r.dash != nil && e.final != nil {
+		r.dash.SetBoard(dashboard.NewBoard(ep, e.final))
+	}
+	if r.exporter != nil {
+		p := metrics.Progress{
+			Episodes:    ep,
+			Steps:       r.totals.Steps,
+			Wins:        r.totals.Wins,
+			Ties:        r.totals.Ties,
+			Epsilon:     r.currentEpsilon(),
+			StepsPerSec: float64(r.totals.Steps-r.resumed.Steps) / time.Since(r.start).Seconds(),
+		}
+		if r.buffer != nil {
+			p.BufferSize, p.BufferCapacity = r.buffer()
+		}
+		r.exporter.SetProgress(p)
+	}
+	if r.episodeLog != nil || r.board != nil {
+		row := metrics.Episode{Episode: ep, Length: e.steps, Reward: e.reward, Winner: e.winner, Epsilon: r.currentEpsilon()}
+		if r.episodeLog != nil {
+			if err := r.episodeLog.WriteEpisode(row); err != nil {
+				log.Printf("Warning: Could not write episode metrics: %v", err)
+			}
+		}
+		if r.board != nil {
+			if err := r.board.WriteEpisode(row); err != nil {
+				log.Printf("Warning: Could not write TensorBoard scalars: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// endInterval closes the log interval at episode ep: it summarizes the
+// interval's episodes in a learning curve row, reports the row to the
+// sinks and returns it with the interval, then starts the next one
+func (r *trainRun) endInterval(ep int) (metrics.Row, trainInterval) {
+	iv := r.current
+	r.current = trainInterval{lengths: make([]int, 0, cap(iv.lengths))}
+
+	n := float64(len(iv.lengths))
+	row := metrics.Row{
+		Episode:   ep,
+		Epsilon:   r.currentEpsilon(),
+		EpsPerSec: float64(ep-r.resumed.Episodes) / time.Since(r.start).Seconds(),
+	}
+	if n > 0 {
+		for _, l := range iv.lengths {
+			row.AvgLength += float64(l)
+		}
+		row.AvgLength /= n
+		row.WinRate = [2]float64{float64(iv.wins[0]) / n, float64(iv.wins[1]) / n}
+		row.TieRate = float64(iv.ties) / n
+		row.AvgReward = [2]float64{iv.rewards[0] / n, iv.rewards[1] / n}
+	}
+	if r.loss != nil {
+		row.Loss = r.loss()
+	}
+
+	if r.curve != nil {
+		if err := r.curve.Write(row); err != nil {
+			log.Printf("Warning: Could not write metrics: %v", err)
+		}
+	}
+	if r.board != nil {
+		if err := r.board.Write(row); err != nil {
+			log.Printf("Warning: Could not write TensorBoard scalars: %v", err)
+		}
+	}
+	if r.dash != nil {
+		r.dash.AddRow(row)
+	}
+	if r.exporter != nil {
+		r.exporter.SetRow(row)
+	}
+	return row, iv
+}
+
+// saveProgress records the totals in run directory dir, with stop's
+// evaluations so far, once the model and buffer of the episodes recorded
+// are saved
+func (r *trainRun) saveProgress(dir string, stop stopState) error {
+	p := r.totals
+	p.Stop = stop
+	return p.save(dir)
+}
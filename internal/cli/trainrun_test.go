@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/metrics"
+)
+
+func TestTrainRun(t *testing.T) {
+	dir := t.TempDir()
+	paths := trainSinkPaths{
+		Metrics:        filepath.Join(dir, "metrics.csv"),
+		EpisodeMetrics: filepath.Join(dir, "episodes.jsonl"),
+		TensorBoard:    filepath.Join(dir, "tb"),
+		Prometheus:     "127.0.0.1:0",
+		Events:         filepath.Join(dir, "events.jsonl"),
+	}
+	run, err := newTrainRun(trainProgress{Seed: 5}, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer run.Close()
+	run.epsilon = func() float64 { return 0.5 }
+	run.loss = func() float64 { return 0.25 }
+	run.buffer = func() (int, int) { return 30, 120 }
+
+	played := []*trainEpisode{
+		{index: 1, steps: 10, reward: [2]float64{1, -1}, winner: 0, events: []game.Event{{Type: game.EventFoodEaten, Turn: 3}}},
+		{index: 2, steps: 20, reward: [2]float64{-1, 2}, winner: 1},
+		{index: 3, steps: 30, reward: [2]float64{0.5, 0.5}, winner: -1, repeated: true, intrinsic: 0.3},
+		{index: 4, steps: 40, reward: [2]float64{2, -2}, winner: 0},
+	}
+	var rows []metrics.Row
+	for i, e := range played {
+		ep := i + 1
+		if err := run.record(e, ep); err != nil {
+			t.Fatal(err)
+		}
+		if ep%2 == 0 {
+			row, interval := run.endInterval(ep)
+			if len(interval.lengths) != 2 {
+				t.Errorf("interval at %d had %d episodes, want 2", ep, len(interval.lengths))
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	want := trainProgress{Seed: 5, Episodes: 4, Steps: 100, Wins: [2]int{2, 1}, Ties: 1, Repeated: 1, Rewards: [2]float64{2.5, -0.5}}
+	if run.totals != want {
+		t.Errorf("totals %+v, want %+v", run.totals, want)
+	}
+	wantRows := []metrics.Row{
+		{Episode: 2, Epsilon: 0.5, AvgLength: 15, WinRate: [2]float64{0.5, 0.5}, AvgReward: [2]float64{0, 0.5}, Loss: 0.25},
+		{Episode: 4, Epsilon: 0.5, AvgLength: 35, WinRate: [2]float64{0.5, 0}, TieRate: 0.5, AvgReward: [2]float64{1.25, -0.75}, Loss: 0.25},
+	}
+	for i := range rows {
+		if rows[i].EpsPerSec <= 0 {
+			t.Errorf("row %d: %v episodes per second", i, rows[i].EpsPerSec)
+		}
+		rows[i].EpsPerSec = 0
+		if rows[i] != wantRows[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], wantRows[i])
+		}
+	}
+
+	var prom bytes.Buffer
+	run.exporter.WriteTo(&prom)
+	for _, line := range []string{"slither_train_episodes_total 4", "slither_train_steps_total 100", "slither_train_replay_buffer_fill_ratio 0.25", "slither_train_tie_rate 0.5"} {
+		if !strings.Contains(prom.String(), line+"\n") {
+			t.Errorf("Prometheus metrics lack %q:\n%s", line, prom.String())
+		}
+	}
+
+	if err := run.Close(); err != nil {
+		t.Fatal(err)
+	}
+	written, err := metrics.Read(paths.Metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != len(wantRows) || written[1].TieRate != 0.5 {
+		t.Errorf("metrics file rows %+v, want %d like %+v", written, len(wantRows), wantRows)
+	}
+	if lines := readLines(t, paths.EpisodeMetrics); len(lines) != len(played) {
+		t.Errorf("%d episode metrics lines, want %d", len(lines), len(played))
+	}
+	lines := readLines(t, paths.Events)
+	if len(lines) != 1 {
+		t.Fatalf("%d events logged, want 1", len(lines))
+	}
+	var event struct {
+		Episode int    `json:"episode"`
+		Type    string `json:"type"`
+		Turn    int    `json:"turn"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil || event.Episode != 1 || event.Type != "food" || event.Turn != 3 {
+		t.Errorf("event %s: %+v, %v", lines[0], event, err)
+	}
+	if entries, err := os.ReadDir(paths.TensorBoard); err != nil || len(entries) != 1 {
+		t.Errorf("TensorBoard directory: %v, %v", entries, err)
+	}
+}
+
+func TestTrainRunSaveProgress(t *testing.T) {
+	dir := t.TempDir()
+	run, err := newTrainRun(trainProgress{Seed: 2, Episodes: 10, Steps: 500, Ties: 10}, trainSinkPaths{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer run.Close()
+	if err := run.record(&trainEpisode{steps: 7, winner: 1}, 11); err != nil {
+		t.Fatal(err)
+	}
+	stop := stopState{Best: 0.5, BestEpisode: 10, Stale: 1}
+	if err := run.saveProgress(dir, stop); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadProgress(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := trainProgress{Seed: 2, Episodes: 11, Steps: 507, Wins: [2]int{0, 1}, Ties: 10, Stop: stop}
+	if got != want {
+		t.Errorf("saved %+v, want %+v", got, want)
+	}
+}
+
+// readLines returns the lines of the file at path
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
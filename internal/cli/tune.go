@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/tune"
+)
+
+func init() {
+	Register(Command{
+		Name:    "tune",
+		Summary: "Run short training probes and recommend a learning rate and gamma",
+		Run:     runTune,
+	})
+}
+
+// runTune implements the tune subcommand
+func runTune(args []string) error {
+	// Parse command line flags
+	fs := NewFlagSet("tune")
+	var gameFlags GameFlags
+	gameFlags.Register(fs)
+	lrList := fs.String("lr", "0.0003,0.001,0.003", "Comma separated learning rates to probe")
+	gammaList := fs.String("gamma", "0.9,0.95,0.99", "Comma separated discount factors to probe")
+	episodes := fs.Int("episodes", 150, "Training episodes per probe")
+	workers := fs.Int("workers", runtime.NumCPU(), "Probes run in parallel")
+	rewardsPath := fs.String("rewards", "", "JSON file overriding reward values")
+	if err := ParseFlags(fs, args); err != nil {
+		return err
+	}
+	if *episodes < 2 {
+		return fmt.Errorf("-episodes must be at least 2")
+	}
+	if *workers < 1 {
+		*workers = 1
+	}
+
+	learningRates, err := parseFloats(*lrList)
+	if err != nil {
+		return fmt.Errorf("-lr: %w", err)
+	}
+	gammas, err := parseFloats(*gammaList)
+	if err != nil {
+		return fmt.Errorf("-gamma: %w", err)
+	}
+
+	seed := gameFlags.ResolveSeed()
+	gameCfg, err := gameFlags.GameConfig(20)
+	if err != nil {
+		return err
+	}
+	trainCfg := config.DefaultTrainingConfig()
+	if *rewardsPath != "" {
+		if trainCfg.Rewards, err = config.LoadRewardConfig(*rewardsPath); err != nil {
+			return fmt.Errorf("could not load rewards from %s: %w", *rewardsPath, err)
+		}
+	}
+	if err := applyConfigEnv(fs, &trainCfg); err != nil {
+		return fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	// Every probe uses the same seed so they differ only in hyperparameters
+	candidates := tune.Grid(learningRates, gammas)
+	log.Printf("Running %d probes of %d episodes on %d workers", len(candidates), *episodes, *workers)
+	startTime := time.Now()
+
+	probes := make([]tune.Probe, len(candidates))
+	errs := make([]error, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				probes[i], errs[i] = tune.Run(trainCfg, gameCfg, candidates[i], *episodes, seed)
+				if errs[i] == nil {
+					log.Printf("Probe lr=%g gamma=%g done", candidates[i].LearningRate, candidates[i].Gamma)
+				}
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	tune.Rank(probes)
+	fmt.Printf("\n=== Probes (%v) ===\n", time.Since(startTime).Round(time.Second))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tLR\tGAMMA\tSCORE SLOPE\tFINAL SCORE\tLOSS TREND\tSTABLE")
+	for i, p := range probes {
+		fmt.Fprintf(w, "%d\t%g\t%g\t%+.4f\t%.2f\t%+.1f%%\t%v\n", i+1, p.LearningRate, p.Gamma,
+			p.ScoreSlope, p.FinalScore, 100*p.LossSlope*float64(len(p.Losses)), p.Stable)
+	}
+	w.Flush()
+
+	best := probes[0]
+	if !best.Stable {
+		return fmt.Errorf("every probe diverged; try smaller learning rates")
+	}
+	fmt.Printf("\nRecommended starting point: learning rate %g, gamma %g\n", best.LearningRate, best.Gamma)
+	fmt.Printf("  %s=%g %s=%g go run ./cmd/train\n",
+		config.EnvName("LearningRate"), best.LearningRate, config.EnvName("Gamma"), best.Gamma)
+	fmt.Println("Probes are short and noisy; confirm the pick with a longer run before committing to it.")
+	return nil
+}
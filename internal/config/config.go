@@ -1,10 +1,125 @@
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
 // GameConfig holds game-related configuration
 type GameConfig struct {
 	BoardWidth  int
 	BoardHeight int
 	GridSize    int // pixels per cell for rendering
+	FoodCount   int // pellets kept on the board, 0 means 1
+	Spawn       FoodSpawnConfig
+	Value       FoodValueConfig
+	MaxTurns    int // turns after which the game ends, won by score then length; 0 for no limit
+	RepeatLimit int // times a position may occur before the game ends in a draw; 0 for no limit
+
+	// Rules names the ruleset, see game.LookupRules; empty for the
+	// standard rules
+	Rules string
+
+	// Map names a bundled board map or a map file, see game.LoadMap; its
+	// size replaces BoardWidth and BoardHeight. Empty for an open board.
+	Map string
+
+	// Respawn, when enabled, brings dead snakes back and plays the match
+	// to a score target instead of ending it on the first death
+	Respawn RespawnConfig
+}
+
+// RespawnConfig sets up endless matches, where a dead snake sits out Delay
+// turns and then comes back at its starting length on a safe spot, keeping
+// its score
+type RespawnConfig struct {
+	Enabled     bool `json:"enabled"`
+	Delay       int  `json:"delay"`        // Turns a dead snake sits out
+	ScoreTarget int  `json:"score_target"` // Score that wins the match, won by score then length; 0 plays on until stopped
+}
+
+// Food spawn patterns
+const (
+	FoodPatternRandom  = "random"  // Each pellet anywhere, replaced as soon as it's eaten
+	FoodPatternCluster = "cluster" // All pellets near one spot, replaced once all are gone
+)
+
+// FoodSpawnConfig controls where food appears and how long it stays
+type FoodSpawnConfig struct {
+	Pattern       string `json:"pattern"`        // FoodPatternRandom (default) or FoodPatternCluster
+	ClusterRadius int    `json:"cluster_radius"` // Manhattan radius of a cluster
+	Lifetime      int    `json:"lifetime"`       // Turns before an uneaten pellet expires, 0 for never
+	RelocateEvery int    `json:"relocate_every"` // Move all pellets every N turns, 0 for never
+}
+
+// Validate checks the spawn settings
+func (c FoodSpawnConfig) Validate() error {
+	switch c.Pattern {
+	case "", FoodPatternRandom, FoodPatternCluster:
+	default:
+		return fmt.Errorf("unknown food pattern %q (want %s or %s)", c.Pattern, FoodPatternRandom, FoodPatternCluster)
+	}
+	if c.ClusterRadius < 0 || c.Lifetime < 0 || c.RelocateEvery < 0 {
+		return fmt.Errorf("food cluster radius, lifetime and relocation interval must not be negative")
+	}
+	return nil
+}
+
+// Lifespan returns the most turns a pellet stays where it is before it
+// expires or is relocated, 0 when food stays until eaten
+func (c FoodSpawnConfig) Lifespan() int {
+	switch {
+	case c.Lifetime > 0 && c.RelocateEvery > 0:
+		return min(c.Lifetime, c.RelocateEvery)
+	case c.Lifetime > 0:
+		return c.Lifetime
+	}
+	return c.RelocateEvery
+}
+
+// DefaultGoldenPoints is what a golden pellet scores unless configured
+const DefaultGoldenPoints = 5
+
+// FoodValueConfig sets what pellets are worth. A new pellet is golden with
+// probability GoldenChance.
+type FoodValueConfig struct {
+	Points       int     `json:"points"`        // Score per pellet, which scales the food reward; 0 means 1
+	Growth       int     `json:"growth"`        // Segments a pellet grows the snake by; 0 means 1
+	GoldenChance float64 `json:"golden_chance"` // Chance a new pellet is golden, 0 for never
+	GoldenPoints int     `json:"golden_points"` // Score per golden pellet; 0 means DefaultGoldenPoints
+	GoldenGrowth int     `json:"golden_growth"` // Segments a golden pellet grows the snake by; 0 means Growth
+}
+
+// Validate checks the food values
+func (c FoodValueConfig) Validate() error {
+	if c.Points < 0 || c.Growth < 0 || c.GoldenPoints < 0 || c.GoldenGrowth < 0 {
+		return fmt.Errorf("food points and growth must not be negative")
+	}
+	if !(c.GoldenChance >= 0 && c.GoldenChance <= 1) {
+		return fmt.Errorf("golden food chance %g is not between 0 and 1", c.GoldenChance)
+	}
+	return nil
+}
+
+// Pellet returns the score and growth of a pellet, golden or not, with
+// the zero values replaced by their defaults
+func (c FoodValueConfig) Pellet(golden bool) (points, growth int) {
+	points, growth = max(c.Points, 1), max(c.Growth, 1)
+	if golden {
+		if c.GoldenPoints > 0 {
+			points = c.GoldenPoints
+		} else {
+			points = DefaultGoldenPoints
+		}
+		if c.GoldenGrowth > 0 {
+			growth = c.GoldenGrowth
+		}
+	}
+	return points, growth
 }
 
 // DefaultGameConfig returns sensible defaults
@@ -18,18 +133,59 @@ func DefaultGameConfig() GameConfig {
 
 // TrainingConfig holds training hyperparameters
 type TrainingConfig struct {
+	// Algorithm selects the learning agent, AlgoDQN, AlgoC51,
+	// AlgoBootstrap, AlgoPPO or AlgoA2C
+	Algorithm    string
+	Distribution DistributionConfig // Return support of AlgoC51
+	Bootstrap    BootstrapConfig    // Q-value heads of AlgoBootstrap
+	PPO          PPOConfig          // Hyperparameters of AlgoPPO
+	A2C          A2CConfig          // Hyperparameters of AlgoA2C
+
 	// Neural Network
 	InputSize    int
 	HiddenSize1  int
 	HiddenSize2  int
 	OutputSize   int
 	LearningRate float64
+	LRSchedule   LRScheduleConfig // How the learning rate changes over training
+	Encoder      string           // State encoder name, "features", "grid" or "window"; InputSize must match its size
+	StackStates  int              // Encoded states stacked into the input, see ai.StateStack; below 2 stacks nothing
+	ConvFilters  int              // Filters of the convolutional layer 1 that networks reading "grid" planes get; 0 keeps it dense
+	Dueling      bool             // Separate value and advantage streams, see ai.NewDuelingQNetwork
+	Noisy        bool             // Noisy layers explore instead of epsilon-greedy, see ai.QNetwork.MakeNoisy
+	Recurrent    bool             // GRU second layer that remembers earlier steps, see ai.DRQNAgent
+	Sequence     SequenceConfig   // Sequence replay of Recurrent networks
+
+	// Regularization against overfitting to the opponent's current policy
+	Regularization RegularizationConfig
+
+	// Curiosity mixes an intrinsic exploration reward into the rewards
+	// learning snakes are trained on
+	Curiosity CuriosityConfig
+
+	// Pool, when its Size is set, has snake 1 play frozen snapshots of the
+	// learner instead of learning alongside it
+	Pool PoolConfig
 
 	// DQN
-	Gamma        float64
-	EpsilonStart float64
-	EpsilonMin   float64
-	EpsilonDecay float64
+	Gamma           float64
+	EpsilonStart    float64
+	EpsilonMin      float64
+	EpsilonDecay    float64               // Per-episode multiplier for the default schedule
+	EpsilonSchedule EpsilonScheduleConfig // How exploration falls from EpsilonStart
+	DoubleDQN       bool                  // Policy network picks the next action, target network values it
+	ActionMask      bool                  // Never choose a move that dies next turn while another survives
+	TraceLambda     float64               // > 0 learns online with Q(λ) eligibility traces instead of replay, see ai.TraceAgent
+
+	// HuberDelta > 0 trains on the Huber loss instead of squared error and
+	// MaxGradNorm > 0 clips each update's gradient norm, see ai.LossOptions
+	HuberDelta  float64
+	MaxGradNorm float64
+
+	// Conservative > 0 adds a conservative Q-learning (CQL) penalty of that
+	// weight to every update, for offline training; see
+	// ai.DQNAgent.Conservative
+	Conservative float64
 
 	// Training
 	BatchSize     int
@@ -38,26 +194,369 @@ type TrainingConfig struct {
 	Episodes      int
 	MaxStepsPerEp int
 
+	// GradWorkers > 1 computes each batch's gradients on that many
+	// goroutines and applies a single averaged update per batch
+	GradWorkers int
+	// MiniBatch applies the single averaged update on one goroutine too,
+	// instead of an update after every sample
+	MiniBatch bool
+
+	// PrioritizedReplay samples transitions in proportion to
+	// |TD error|^PriorityAlpha and corrects the bias with importance
+	// sampling weights, whose exponent anneals from PriorityBeta to 1
+	PrioritizedReplay bool
+	PriorityAlpha     float64
+	PriorityBeta      float64
+
+	// Backend names the linear algebra backend, see ai.SetBackend
+	Backend string
+
+	// Domain randomization. ObsNoise is the standard deviation of Gaussian
+	// noise added to every encoded feature and FeatureDropout the chance of
+	// zeroing each one. When BoardSizeMax or FoodCountMax is set, every
+	// episode draws its board size or pellet count uniformly from the range.
+	ObsNoise       float64
+	FeatureDropout float64
+	BoardSizeMin   int
+	BoardSizeMax   int
+	FoodCountMin   int
+	FoodCountMax   int
+
+	// Rewards. SnakeRewards optionally overrides Rewards for one snake so
+	// the two can be trained with different objectives (handicaps).
+	Rewards      RewardConfig
+	SnakeRewards [2]*RewardConfig
+
 	// Persistence
 	SaveFrequency int
 	ModelPath     string
 }
 
+// Learning algorithms
+const (
+	AlgoDQN = "dqn" // Deep Q-Network, see ai.DQNAgent
+	AlgoC51 = "c51" // Categorical distributional DQN, see ai.C51Agent
+	AlgoPPO = "ppo" // Proximal policy optimization, see ai.PPOAgent
+	AlgoA2C = "a2c" // Advantage actor-critic, see ai.A2CAgent
+
+	AlgoBootstrap = "bootstrap" // Bootstrapped DQN, see ai.BootstrapAgent
+)
+
+// BootstrapConfig holds the hyperparameters of AlgoBootstrap: Heads
+// Q-value heads share one network, and each transition trains each head
+// with probability MaskProb
+type BootstrapConfig struct {
+	Heads    int
+	MaskProb float64
+}
+
+// Validate checks the bootstrap settings
+func (c BootstrapConfig) Validate() error {
+	if c.Heads < 1 || c.Heads > 64 {
+		return fmt.Errorf("bootstrap heads must be between 1 and 64, got %d", c.Heads)
+	}
+	if c.MaskProb <= 0 || c.MaskProb > 1 {
+		return fmt.Errorf("bootstrap mask probability must be in (0, 1], got %v", c.MaskProb)
+	}
+	return nil
+}
+
+// DistributionConfig sets the support of a distributional agent: Atoms
+// evenly spaced returns from VMin to VMax
+type DistributionConfig struct {
+	Atoms int
+	VMin  float64
+	VMax  float64
+}
+
+// Validate checks the support settings
+func (c DistributionConfig) Validate() error {
+	if c.Atoms < 2 {
+		return fmt.Errorf("a distribution needs at least 2 atoms, got %d", c.Atoms)
+	}
+	if c.VMin >= c.VMax {
+		return fmt.Errorf("distribution support minimum %v must be below its maximum %v", c.VMin, c.VMax)
+	}
+	return nil
+}
+
+// PPOConfig holds the hyperparameters of AlgoPPO
+type PPOConfig struct {
+	RolloutSteps int     // Transitions collected between updates
+	Epochs       int     // Passes over each rollout
+	MiniBatch    int     // Transitions per gradient step
+	ClipEpsilon  float64 // Probability ratios are clipped to 1 ± ClipEpsilon
+	Lambda       float64 // GAE lambda: 0 is one-step TD, 1 is Monte Carlo
+	EntropyCoef  float64 // Weight of the entropy bonus
+}
+
+// Validate checks the PPO settings
+func (c PPOConfig) Validate() error {
+	if c.RolloutSteps <= 0 || c.Epochs <= 0 || c.MiniBatch <= 0 {
+		return fmt.Errorf("PPO rollout steps, epochs and mini-batch size must be positive")
+	}
+	if c.ClipEpsilon <= 0 || c.Lambda < 0 || c.Lambda > 1 || c.EntropyCoef < 0 {
+		return fmt.Errorf("PPO needs a positive clip range, lambda in [0, 1] and a non-negative entropy weight")
+	}
+	return nil
+}
+
+// A2CConfig holds the hyperparameters of AlgoA2C
+type A2CConfig struct {
+	RolloutSteps int     // Transitions a worker collects per update
+	Lambda       float64 // GAE lambda; 1 gives plain n-step returns
+	EntropyCoef  float64 // Weight of the entropy bonus
+}
+
+// Validate checks the A2C settings
+func (c A2CConfig) Validate() error {
+	if c.RolloutSteps <= 0 {
+		return fmt.Errorf("A2C rollout steps must be positive")
+	}
+	if c.Lambda < 0 || c.Lambda > 1 || c.EntropyCoef < 0 {
+		return fmt.Errorf("A2C needs lambda in [0, 1] and a non-negative entropy weight")
+	}
+	return nil
+}
+
+// SequenceConfig sets how a recurrent agent replays its trajectories:
+// sequences of Length transitions, each preceded by up to BurnIn earlier
+// ones that only warm up the hidden state
+type SequenceConfig struct {
+	Length int
+	BurnIn int
+}
+
+// Validate checks the sequence settings
+func (c SequenceConfig) Validate() error {
+	if c.Length <= 0 || c.BurnIn < 0 {
+		return fmt.Errorf("sequence length must be positive and burn-in not negative")
+	}
+	return nil
+}
+
+// RegularizationConfig sets how training resists overfitting: Dropout is
+// the probability of zeroing each unit of the first hidden layer in
+// training forward passes, and WeightDecay the strength λ of an L2 penalty
+// λ/2·‖W‖² on the weights, applied in every update. Zero disables either.
+type RegularizationConfig struct {
+	Dropout     float64
+	WeightDecay float64
+}
+
+// Validate checks the regularization settings
+func (c RegularizationConfig) Validate() error {
+	if c.Dropout < 0 || c.Dropout >= 1 {
+		return fmt.Errorf("dropout must be in [0, 1)")
+	}
+	if c.WeightDecay < 0 {
+		return fmt.Errorf("weight decay must not be negative")
+	}
+	return nil
+}
+
+// CuriosityConfig sets up the intrinsic curiosity module, see
+// ai.Curiosity. Weight scales its reward before it is added to the
+// extrinsic one; 0 turns it off. Its networks embed states into Features
+// values and have Hidden units per hidden layer.
+type CuriosityConfig struct {
+	Weight       float64
+	Features     int
+	Hidden       int
+	LearningRate float64
+}
+
+// Validate checks the curiosity settings
+func (c CuriosityConfig) Validate() error {
+	if c.Weight < 0 {
+		return fmt.Errorf("curiosity weight must not be negative")
+	}
+	if c.Weight > 0 && (c.Features <= 0 || c.Hidden <= 0 || c.LearningRate <= 0) {
+		return fmt.Errorf("curiosity needs positive feature and hidden sizes and learning rate")
+	}
+	return nil
+}
+
+// Opponent pool sampling strategies
+const (
+	PoolUniform = "uniform" // Every snapshot equally likely
+	PoolRecent  = "recent"  // Likelihood growing linearly with snapshot age rank, newest most likely
+	PoolLatest  = "latest"  // Always the newest snapshot
+)
+
+// PoolConfig sets up the opponent pool, see ai.OpponentPool. Every Interval
+// episodes the learner's policy is copied into the pool, which keeps the
+// latest Size copies; each episode snake 1 plays one drawn by Sampling.
+// Size 0 turns the pool off.
+type PoolConfig struct {
+	Size     int
+	Interval int
+	Sampling string
+}
+
+// Validate checks the opponent pool settings
+func (c PoolConfig) Validate() error {
+	if c.Size < 0 {
+		return fmt.Errorf("opponent pool size must not be negative")
+	}
+	if c.Size == 0 {
+		return nil
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("opponent pool snapshot interval must be positive")
+	}
+	switch c.Sampling {
+	case PoolUniform, PoolRecent, PoolLatest:
+	default:
+		return fmt.Errorf("unknown opponent pool sampling %q (want %s, %s or %s)", c.Sampling, PoolUniform, PoolRecent, PoolLatest)
+	}
+	return nil
+}
+
+// Learning rate schedules
+const (
+	LRConstant = "constant" // Keep the initial rate
+	LRLinear   = "linear"   // Decay linearly to MinLR over Steps
+	LRCosine   = "cosine"   // Follow half a cosine down to MinLR over Steps
+	LRStep     = "step"     // Multiply by Factor every Steps
+)
+
+// LRScheduleConfig selects how the learning rate changes with the number of
+// environment steps trained on. Rates start at TrainingConfig.LearningRate.
+type LRScheduleConfig struct {
+	Kind   string  // LRConstant (default), LRLinear, LRCosine or LRStep
+	Steps  int     // Decay length, or the interval between decays for LRStep
+	MinLR  float64 // Final rate for LRLinear and LRCosine
+	Factor float64 // Decay multiplier for LRStep
+}
+
+// Validate checks the schedule settings
+func (c LRScheduleConfig) Validate() error {
+	switch c.Kind {
+	case "", LRConstant:
+		return nil
+	case LRLinear, LRCosine, LRStep:
+	default:
+		return fmt.Errorf("unknown learning rate schedule %q (want %s, %s, %s or %s)", c.Kind, LRConstant, LRLinear, LRCosine, LRStep)
+	}
+	if c.Steps <= 0 {
+		return fmt.Errorf("learning rate schedule %q needs a positive step count", c.Kind)
+	}
+	if c.MinLR < 0 || (c.Kind == LRStep && (c.Factor <= 0 || c.Factor > 1)) {
+		return fmt.Errorf("learning rate schedule needs MinLR >= 0 and a step factor in (0, 1]")
+	}
+	return nil
+}
+
+// Exploration schedules
+const (
+	EpsilonPerEpisode  = "episode"     // Multiply by EpsilonDecay after every episode
+	EpsilonLinear      = "linear"      // Fall linearly to EpsilonMin over Steps
+	EpsilonExponential = "exponential" // Approach EpsilonMin, closing 63% of the gap every Steps
+	EpsilonPiecewise   = "piecewise"   // Interpolate linearly between Points
+)
+
+// EpsilonScheduleConfig selects how the exploration rate falls. Every kind
+// but EpsilonPerEpisode is keyed on environment steps.
+type EpsilonScheduleConfig struct {
+	Kind  string // EpsilonPerEpisode (default), EpsilonLinear, EpsilonExponential or EpsilonPiecewise
+	Steps int    // Decay length for EpsilonLinear, time constant for EpsilonExponential
+
+	// Points lists "step:epsilon" pairs for EpsilonPiecewise, e.g.
+	// "0:1,100000:0.1,500000:0.01". Epsilon holds at the last value.
+	Points string
+}
+
+// EpsilonPoint is one knot of a piecewise exploration schedule
+type EpsilonPoint struct {
+	Step    int
+	Epsilon float64
+}
+
+// ParsePoints parses Points into knots ordered by step
+func (c EpsilonScheduleConfig) ParsePoints() ([]EpsilonPoint, error) {
+	var points []EpsilonPoint
+	for _, field := range strings.Split(c.Points, ",") {
+		step, eps, ok := strings.Cut(strings.TrimSpace(field), ":")
+		if !ok {
+			return nil, fmt.Errorf("epsilon point %q is not step:epsilon", field)
+		}
+		var p EpsilonPoint
+		var err error
+		if p.Step, err = strconv.Atoi(step); err != nil {
+			return nil, fmt.Errorf("epsilon point %q: %w", field, err)
+		}
+		if p.Epsilon, err = strconv.ParseFloat(eps, 64); err != nil {
+			return nil, fmt.Errorf("epsilon point %q: %w", field, err)
+		}
+		if p.Epsilon < 0 || p.Epsilon > 1 || (len(points) > 0 && p.Step <= points[len(points)-1].Step) {
+			return nil, fmt.Errorf("epsilon points need increasing steps and values in [0, 1]")
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// Validate checks the schedule settings
+func (c EpsilonScheduleConfig) Validate() error {
+	switch c.Kind {
+	case "", EpsilonPerEpisode:
+	case EpsilonLinear, EpsilonExponential:
+		if c.Steps <= 0 {
+			return fmt.Errorf("epsilon schedule %q needs a positive step count", c.Kind)
+		}
+	case EpsilonPiecewise:
+		_, err := c.ParsePoints()
+		return err
+	default:
+		return fmt.Errorf("unknown epsilon schedule %q (want %s, %s, %s or %s)", c.Kind,
+			EpsilonPerEpisode, EpsilonLinear, EpsilonExponential, EpsilonPiecewise)
+	}
+	return nil
+}
+
 // DefaultTrainingConfig returns sensible defaults
 func DefaultTrainingConfig() TrainingConfig {
 	return TrainingConfig{
+		Algorithm:    AlgoDQN,
+		Distribution: DistributionConfig{Atoms: 51, VMin: -10, VMax: 10},
+		Bootstrap:    BootstrapConfig{Heads: 10, MaskProb: 0.5},
+		PPO: PPOConfig{
+			RolloutSteps: 2048,
+			Epochs:       4,
+			MiniBatch:    64,
+			ClipEpsilon:  0.2,
+			Lambda:       0.95,
+			EntropyCoef:  0.01,
+		},
+		A2C:      A2CConfig{RolloutSteps: 32, Lambda: 1, EntropyCoef: 0.01},
+		Sequence: SequenceConfig{Length: 8, BurnIn: 4},
+		Curiosity: CuriosityConfig{
+			Features:     16,
+			Hidden:       64,
+			LearningRate: 0.001,
+		},
+		Pool: PoolConfig{Interval: 500, Sampling: PoolUniform},
+
 		// Neural Network
 		InputSize:    22,
 		HiddenSize1:  128,
 		HiddenSize2:  64,
 		OutputSize:   3,
 		LearningRate: 0.001,
+		LRSchedule:   LRScheduleConfig{Kind: LRConstant, Steps: 1000000, Factor: 0.5},
+		Encoder:      "features",
+		ConvFilters:  8,
 
 		// DQN
 		Gamma:        0.99,
 		EpsilonStart: 1.0,
 		EpsilonMin:   0.01,
 		EpsilonDecay: 0.995,
+		EpsilonSchedule: EpsilonScheduleConfig{
+			Kind:  EpsilonPerEpisode,
+			Steps: 200000,
+		},
 
 		// Training
 		BatchSize:     64,
@@ -65,9 +564,114 @@ func DefaultTrainingConfig() TrainingConfig {
 		TargetUpdate:  1000,
 		Episodes:      10000,
 		MaxStepsPerEp: 1000,
+		GradWorkers:   1,
+		Backend:       "go",
+
+		// Prioritized replay, off unless enabled
+		PriorityAlpha: 0.6,
+		PriorityBeta:  0.4,
+
+		// Rewards
+		Rewards: DefaultRewardConfig(),
 
 		// Persistence
 		SaveFrequency: 500,
 		ModelPath:     "models/snake_dqn.gob",
 	}
 }
+
+// RewardsFor returns the reward values used for the given snake
+func (c TrainingConfig) RewardsFor(snakeID int) RewardConfig {
+	if c.SnakeRewards[snakeID] != nil {
+		return *c.SnakeRewards[snakeID]
+	}
+	return c.Rewards
+}
+
+// RewardConfig holds the reward values used during training
+type RewardConfig struct {
+	Death    float64 `json:"death"`    // Applied to a snake on the turn it dies
+	Survival float64 `json:"survival"` // Applied every turn a snake stays alive
+	Food     float64 `json:"food"`     // Added when a snake eats
+	Win      float64 `json:"win"`      // Added to the survivor when the opponent dies
+
+	// Kill attribution, added on top of Win. Kill applies when the opponent
+	// died by running into this snake's body; OpponentBlunder applies when
+	// it hit a wall or itself. Negative values discourage that outcome.
+	Kill            float64 `json:"kill"`
+	OpponentBlunder float64 `json:"opponent_blunder"`
+
+	// DeathBy replaces Death for deaths by the causes it names, one of
+	// DeathCauses. A death with several causes is charged for the first
+	// one the collision checks found, as game.StepResult.DeathCause reports.
+	DeathBy map[string]float64 `json:"death_by"`
+
+	// Shaping selects the shaping term: "potential" (gamma*phi(s') - phi(s)
+	// over Potentials), "distance" (the legacy fixed bonus toward or away
+	// from food, which can change the optimal policy) or "none"
+	Shaping string `json:"shaping"`
+
+	// Distance shaping, added when the head moves toward or away from food
+	// in "distance" mode
+	ShapingToward float64 `json:"shaping_toward"`
+	ShapingAway   float64 `json:"shaping_away"`
+
+	// Potentials maps potential names ("food", "space") to their weights for
+	// potential-based shaping
+	Potentials map[string]float64 `json:"potentials"`
+
+	// Anti-stalling penalties (disabled when zero). RevisitPenalty applies
+	// when the head returns to a cell it occupied within the last
+	// RevisitWindow turns; StarvationPenalty applies every turn after
+	// StarvationTurns turns without eating.
+	RevisitPenalty    float64 `json:"revisit_penalty"`
+	RevisitWindow     int     `json:"revisit_window"`
+	StarvationPenalty float64 `json:"starvation_penalty"`
+	StarvationTurns   int     `json:"starvation_turns"`
+}
+
+// DeathCauses are the causes of death RewardConfig.DeathBy can price, the
+// names of game.CollisionType
+var DeathCauses = []string{"wall", "self", "other-snake", "head-to-head"}
+
+// DefaultRewardConfig returns the default reward values. Models trained
+// before potential-based shaping became the default used "distance".
+func DefaultRewardConfig() RewardConfig {
+	return RewardConfig{
+		Death:         -1.0,
+		Survival:      0.01,
+		Food:          0.5,
+		Win:           1.0,
+		Shaping:       "potential",
+		ShapingToward: 0.1,
+		ShapingAway:   -0.1,
+		Potentials:    map[string]float64{"food": 1.0},
+	}
+}
+
+// LoadRewardConfig reads reward values from a JSON file. Fields missing from
+// the file keep their default values; a "potentials" object replaces the
+// default set entirely.
+func LoadRewardConfig(path string) (RewardConfig, error) {
+	rewards := DefaultRewardConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rewards, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return rewards, err
+	}
+	if _, ok := raw["potentials"]; ok {
+		rewards.Potentials = nil
+	}
+	if err := json.Unmarshal(data, &rewards); err != nil {
+		return rewards, err
+	}
+	for cause := range rewards.DeathBy {
+		if !slices.Contains(DeathCauses, cause) {
+			return rewards, fmt.Errorf("unknown cause of death %q in death_by (want %s)", cause, strings.Join(DeathCauses, ", "))
+		}
+	}
+	return rewards, nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix is the prefix for environment variable overrides
+const EnvPrefix = "SLITHER_"
+
+// EnvName converts a Go field or flag name to its environment variable,
+// e.g. LearningRate -> SLITHER_LEARNING_RATE and save-freq -> SLITHER_SAVE_FREQ
+func EnvName(name string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			// Start a new word at a lower->upper or acronym->word boundary
+			if i > 0 && runes[i-1] != '-' && runes[i-1] != '.' && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// ApplyEnv overrides fields of the struct pointed to by cfg with SLITHER_*
+// environment variables named after the fields. Nested structs use the
+// parent field name as a prefix, e.g. SLITHER_REWARDS_FOOD sets
+// TrainingConfig.Rewards.Food. Fields of unsupported kinds are skipped.
+//
+// A setting has one variable, named after its flag if it has one: flags
+// maps the addresses of the fields flags set to the flags' names, and
+// those fields are left to the flags' own variables (see EnvName). Setting
+// such a field's variable is an error naming the flag's.
+func ApplyEnv(cfg interface{}, flags map[uintptr]string) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ApplyEnv: expected pointer to struct, got %T", cfg)
+	}
+	return applyEnvStruct(v.Elem(), "", flags)
+}
+
+// applyEnvStruct walks a struct value applying overrides to each field
+func applyEnvStruct(v reflect.Value, prefix string, flags map[uintptr]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + field.Name
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnvStruct(fv, name+".", flags); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := EnvName(name)
+		raw, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if flag, ok := flags[fv.Addr().Pointer()]; ok {
+			if EnvName(flag) != key {
+				return fmt.Errorf("%s: -%s sets this, through %s", key, flag, EnvName(flag))
+			}
+			continue
+		}
+		if err := setFromString(fv, raw); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// setFromString parses raw into a field of a supported scalar kind
+func setFromString(fv reflect.Value, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field kind %s", fv.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"save-freq":                  "SLITHER_SAVE_FREQ",
+		"episodes":                   "SLITHER_EPISODES",
+		"LearningRate":               "SLITHER_LEARNING_RATE",
+		"Rewards.Food":               "SLITHER_REWARDS_FOOD",
+		"LRSchedule.MinLR":           "SLITHER_LR_SCHEDULE_MIN_LR",
+		"A2C.RolloutSteps":           "SLITHER_A2C_ROLLOUT_STEPS",
+		"PPO.EntropyCoef":            "SLITHER_PPO_ENTROPY_COEF",
+		"HiddenSize1":                "SLITHER_HIDDEN_SIZE1",
+		"Distribution.VMin":          "SLITHER_DISTRIBUTION_V_MIN",
+		"Regularization.WeightDecay": "SLITHER_REGULARIZATION_WEIGHT_DECAY",
+	}
+	for in, want := range tests {
+		if got := EnvName(in); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("SLITHER_LEARNING_RATE", "0.0005")
+	t.Setenv("SLITHER_DOUBLE_DQN", "true")
+	t.Setenv("SLITHER_BATCH_SIZE", "16")
+	t.Setenv("SLITHER_REWARDS_FOOD", "2")
+	t.Setenv("SLITHER_A2C_LAMBDA", "0.9")
+	t.Setenv("SLITHER_ENCODER", "grid")
+	t.Setenv("SLITHER_EPISODES", "5")
+
+	cfg := DefaultTrainingConfig()
+	// -episodes is bound to Episodes and applies SLITHER_EPISODES itself
+	flags := map[uintptr]string{reflect.ValueOf(&cfg.Episodes).Pointer(): "episodes"}
+	if err := ApplyEnv(&cfg, flags); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LearningRate != 0.0005 || !cfg.DoubleDQN || cfg.BatchSize != 16 || cfg.Rewards.Food != 2 || cfg.A2C.Lambda != 0.9 || cfg.Encoder != "grid" {
+		t.Errorf("overrides not applied: %+v", cfg)
+	}
+	if cfg.Episodes != DefaultTrainingConfig().Episodes {
+		t.Errorf("Episodes = %d, want the flag's field left alone", cfg.Episodes)
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+	}{
+		{"SLITHER_BATCH_SIZE", "many", "SLITHER_BATCH_SIZE"},
+		{"SLITHER_DUELING", "maybe", "SLITHER_DUELING"},
+		{"SLITHER_GAMMA", "0.9x", "SLITHER_GAMMA"},
+		// A setting with a flag answers to the flag's variable only
+		{"SLITHER_GRAD_WORKERS", "4", "SLITHER_WORKERS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			cfg := DefaultTrainingConfig()
+			flags := map[uintptr]string{reflect.ValueOf(&cfg.GradWorkers).Pointer(): "workers"}
+			err := ApplyEnv(&cfg, flags)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s=%s: error %v, want one naming %s", tt.key, tt.value, err, tt.want)
+			}
+		})
+	}
+	if err := ApplyEnv(DefaultTrainingConfig(), nil); err == nil {
+		t.Error("ApplyEnv accepted a struct that isn't a pointer")
+	}
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// runFile is the layout of a config file: a game section and a training
+// section, each holding fields of GameConfig and TrainingConfig
+type runFile struct {
+	Game     *GameConfig     `json:"game"`
+	Training *TrainingConfig `json:"training"`
+}
+
+// LoadFile sets the fields of game and training that the YAML (.yaml,
+// .yml) or TOML (.toml) file at path names, leaving the others as they
+// are. Keys are field names in any case, with or without underscores, e.g.
+// learning_rate or LearningRate; a map the file sets, like potentials,
+// replaces the one in place. Unknown keys are errors.
+func LoadFile(path string, game *GameConfig, training *TrainingConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("%s: unknown config file type %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Snake reward overrides start from the defaults, as -rewards0 files do
+	if _, ok := lookupKey(raw, "training", "SnakeRewards"); ok {
+		for i := range training.SnakeRewards {
+			rewards := DefaultRewardConfig()
+			training.SnakeRewards[i] = &rewards
+		}
+	}
+	file := runFile{Game: game, Training: training}
+	keys, err := matchKeys(raw, reflect.ValueOf(&file).Elem(), "")
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	// encoding/json does the decoding, so keys resolve to fields as they
+	// do in a model's JSON metadata
+	canonical, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(canonical))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteFile writes game and training to path as a YAML config file that
+// LoadFile reads back, with snake_case keys
+func WriteFile(path string, game GameConfig, training TrainingConfig) error {
+	data, err := json.Marshal(runFile{Game: &game, Training: &training})
+	if err != nil {
+		return err
+	}
+	// JSON is YAML; reading it as a node tree keeps the fields in order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// blockStyle restyles a node tree parsed from JSON as block YAML with
+// snake_case keys and unquoted values where YAML allows
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for i, child := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			child.Value = snakeCase(child.Value)
+		}
+		blockStyle(child)
+	}
+}
+
+// snakeCase spells a Go field name in snake_case, e.g. LRSchedule ->
+// lr_schedule; names already in lower case are kept
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// matchKeys returns the section m of a config file with its keys renamed
+// to those encoding/json decodes the fields of struct v by. Maps the file
+// sets are cleared in v so the file's replace them.
+func matchKeys(m map[string]any, v reflect.Value, prefix string) (map[string]any, error) {
+	out := make(map[string]any, len(m))
+	for key, value := range m {
+		field, name, ok := fieldFor(v.Type(), key)
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %s%s", prefix, key)
+		}
+		matched, err := matchValue(value, v.Field(field), prefix+snakeCase(name)+".")
+		if err != nil {
+			return nil, err
+		}
+		out[name] = matched
+	}
+	return out, nil
+}
+
+// matchValue renames the keys of a value bound for field fv
+func matchValue(value any, fv reflect.Value, prefix string) (any, error) {
+	switch fv.Kind() {
+	case reflect.Struct:
+		if m, ok := value.(map[string]any); ok {
+			return matchKeys(m, fv, prefix)
+		}
+	case reflect.Pointer:
+		if !fv.IsNil() {
+			return matchValue(value, fv.Elem(), prefix)
+		}
+	case reflect.Map:
+		if value != nil {
+			fv.SetZero()
+		}
+	case reflect.Array, reflect.Slice:
+		var list []any
+		switch value := value.(type) {
+		case []any:
+			list = value
+		case []map[string]any: // TOML arrays of tables
+			for _, m := range value {
+				list = append(list, m)
+			}
+		}
+		for i := range min(len(list), fv.Len()) {
+			matched, err := matchValue(list[i], fv.Index(i), prefix)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = matched
+		}
+		if list != nil {
+			return list, nil
+		}
+	}
+	return value, nil
+}
+
+// fieldFor finds the field of struct type t a config file key names,
+// returning its index and JSON name
+func fieldFor(t reflect.Type, key string) (int, string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" {
+			name = tag
+		}
+		if foldKey(name) == foldKey(key) {
+			return i, name, true
+		}
+	}
+	return 0, "", false
+}
+
+// foldKey lowercases a key and drops its underscores and dashes
+func foldKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, key)
+}
+
+// lookupKey reports whether the file sets the field at path, a key per
+// level matched as LoadFile matches them
+func lookupKey(m map[string]any, path ...string) (any, bool) {
+	var value any = m
+	for _, key := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for k, v := range m {
+			if foldKey(k) == foldKey(key) {
+				value, found = v, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
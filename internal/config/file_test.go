@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content to a file named name in a temporary directory
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name, content string
+	}{
+		{"run.yaml", `
+game:
+  board_width: 12
+  BoardHeight: 14
+  respawn:
+    enabled: true
+    delay: 3
+training:
+  algorithm: c51
+  learning-rate: 0.0005
+  distribution:
+    v_min: -5
+  lr_schedule:
+    kind: cosine
+  rewards:
+    food: 2
+    potentials:
+      space: 0.5
+  snake_rewards:
+    - null
+    - death: -3
+`},
+		{"run.toml", `
+[game]
+board_width = 12
+BoardHeight = 14
+[game.respawn]
+enabled = true
+delay = 3
+
+[training]
+algorithm = "c51"
+learning-rate = 0.0005
+distribution = { v_min = -5 }
+lr_schedule = { kind = "cosine" }
+rewards = { food = 2, potentials = { space = 0.5 } }
+snake_rewards = [{}, { death = -3 }]
+`},
+	}
+	for _, tt := range tests {
+		game, training := DefaultGameConfig(), DefaultTrainingConfig()
+		if err := LoadFile(writeConfig(t, tt.name, tt.content), &game, &training); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if game.BoardWidth != 12 || game.BoardHeight != 14 || !game.Respawn.Enabled || game.Respawn.Delay != 3 {
+			t.Errorf("%s: game = %+v", tt.name, game)
+		}
+		if game.GridSize != DefaultGameConfig().GridSize {
+			t.Errorf("%s: unset GridSize = %d, want the default", tt.name, game.GridSize)
+		}
+		if training.Algorithm != AlgoC51 || training.LearningRate != 0.0005 || training.LRSchedule.Kind != LRCosine {
+			t.Errorf("%s: training = %+v", tt.name, training)
+		}
+		if want := DefaultTrainingConfig().Distribution; training.Distribution.VMin != -5 || training.Distribution.VMax != want.VMax || training.Distribution.Atoms != want.Atoms {
+			t.Errorf("%s: distribution = %+v, want only VMin changed", tt.name, training.Distribution)
+		}
+		if training.Rewards.Food != 2 || training.Rewards.Death != DefaultRewardConfig().Death {
+			t.Errorf("%s: rewards = %+v", tt.name, training.Rewards)
+		}
+		if !reflect.DeepEqual(training.Rewards.Potentials, map[string]float64{"space": 0.5}) {
+			t.Errorf("%s: potentials = %v, want the file's map alone", tt.name, training.Rewards.Potentials)
+		}
+		if r := training.SnakeRewards[1]; r == nil || r.Death != -3 || r.Food != DefaultRewardConfig().Food {
+			t.Errorf("%s: snake 1 rewards = %+v, want the defaults with death -3", tt.name, r)
+		}
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"bad.yaml", "training:\n  learning_rat: 0.1\n", "training.learning_rat"},
+		{"bad.yaml", "game:\n  respawn:\n    delays: 2\n", "game.respawn.delays"},
+		{"bad.yaml", "trainng:\n  gamma: 0.9\n", "trainng"},
+		{"bad.toml", "[training]\nepisodes = \"many\"\n", "Episodes"},
+		{"bad.yaml", "training: [\n", "bad.yaml"},
+		{"bad.json", "{}", "unknown config file type"},
+	}
+	for _, tt := range tests {
+		game, training := DefaultGameConfig(), DefaultTrainingConfig()
+		err := LoadFile(writeConfig(t, tt.name, tt.content), &game, &training)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error = %v, want one naming %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestWriteFileRoundTrip(t *testing.T) {
+	game, training := DefaultGameConfig(), DefaultTrainingConfig()
+	game.BoardWidth, game.Map = 16, "rooms"
+	game.Respawn = RespawnConfig{Enabled: true, Delay: 4, ScoreTarget: 30}
+	training.Algorithm = AlgoA2C
+	training.A2C.Lambda = 0.9
+	training.EpsilonSchedule.Points = "0:1,1000:0.1"
+	training.Rewards.DeathBy = map[string]float64{"wall": -2}
+	snake := DefaultRewardConfig()
+	snake.Win = 7
+	training.SnakeRewards[0] = &snake
+
+	path := filepath.Join(t.TempDir(), "model.yaml")
+	if err := WriteFile(path, game, training); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"board_width: 16", "a2c:", "lr_schedule:", "v_min:", "epsilon_schedule:"} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("written config lacks %q:\n%s", key, data)
+		}
+	}
+
+	// Load onto configs that differ everywhere the written ones do
+	gotGame, gotTraining := DefaultGameConfig(), DefaultTrainingConfig()
+	gotTraining.Rewards.Potentials = map[string]float64{"stale": 1}
+	if err := LoadFile(path, &gotGame, &gotTraining); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotGame, game) {
+		t.Errorf("game = %+v, want %+v", gotGame, game)
+	}
+	if !reflect.DeepEqual(gotTraining, training) {
+		t.Errorf("training = %+v, want %+v", gotTraining, training)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"BoardWidth":    "board_width",
+		"LRSchedule":    "lr_schedule",
+		"VMin":          "v_min",
+		"A2C":           "a2c",
+		"MaxStepsPerEp": "max_steps_per_ep",
+		"score_target":  "score_target",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
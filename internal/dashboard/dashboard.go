@@ -0,0 +1,284 @@
+// Package dashboard serves a live view of a training run: learning curve
+// charts and the board the latest episode ended on, pushed to browsers
+// over a WebSocket as training goes.
+package dashboard
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/metrics"
+)
+
+// BoardInterval is the least time between boards sent to browsers, which
+// couldn't follow one per episode
+const BoardInterval = 250 * time.Millisecond
+
+// clientBuffer is the number of messages a browser may fall behind by
+// before it is dropped
+const clientBuffer = 64
+
+// Board is a finished episode's last position
+type Board struct {
+	Episode   int         `json:"episode"`
+	Width     int         `json:"width"`
+	Height    int         `json:"height"`
+	Turns     int         `json:"turns"`
+	Snakes    [2][][2]int `json:"snakes"` // Bodies as x,y pairs, head first
+	Alive     [2]bool     `json:"alive"`
+	Scores    [2]int      `json:"scores"`
+	Food      [][2]int    `json:"food"`
+	Obstacles [][2]int    `json:"obstacles"`
+	Winner    int         `json:"winner"` // -1 for a tie
+}
+
+// NewBoard captures state, the end of episode episode
+func NewBoard(episode int, state *game.GameState) *Board {
+	b := &Board{
+		Episode:   episode,
+		Width:     state.Width,
+		Height:    state.Height,
+		Turns:     state.Turn,
+		Food:      pairs(state.FoodPositions()),
+		Obstacles: pairs(state.Obstacles),
+		Winner:    state.Winner,
+	}
+	for i, snake := range state.Snakes {
+		b.Snakes[i] = pairs(snake.Body)
+		b.Alive[i] = snake.Alive
+		b.Scores[i] = snake.Score
+	}
+	return b
+}
+
+// pairs turns positions into x,y pairs, which encode compactly
+func pairs(positions []game.Position) [][2]int {
+	out := make([][2]int, len(positions))
+	for i, p := range positions {
+		out[i] = [2]int{p.X, p.Y}
+	}
+	return out
+}
+
+// message is what browsers receive: learning curve rows, a board, or both
+// when they first connect
+type message struct {
+	Rows  []chartRow `json:"rows,omitempty"`
+	Board *Board     `json:"board,omitempty"`
+}
+
+// chartRow is a learning curve row as browsers get it. Values that aren't
+// finite, like the loss of a diverging run, have no JSON number and go as
+// null, which the charts leave a gap for.
+type chartRow metrics.Row
+
+// MarshalJSON encodes the row like metrics.Row, non-finite values as null
+func (r chartRow) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Episode   int           `json:"episode"`
+		Epsilon   chartValue    `json:"epsilon"`
+		AvgLength chartValue    `json:"avg_length"`
+		WinRate   [2]chartValue `json:"win_rate"`
+		TieRate   chartValue    `json:"tie_rate"`
+		AvgReward [2]chartValue `json:"avg_reward"`
+		Loss      chartValue    `json:"loss"`
+		EpsPerSec chartValue    `json:"eps_per_sec"`
+	}{
+		r.Episode, chartValue(r.Epsilon), chartValue(r.AvgLength),
+		[2]chartValue{chartValue(r.WinRate[0]), chartValue(r.WinRate[1])}, chartValue(r.TieRate),
+		[2]chartValue{chartValue(r.AvgReward[0]), chartValue(r.AvgReward[1])},
+		chartValue(r.Loss), chartValue(r.EpsPerSec),
+	})
+}
+
+// chartValue is a float encoded as null unless it is finite
+type chartValue float64
+
+// MarshalJSON encodes v as a number, or null for NaN and infinities
+func (v chartValue) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(v))
+}
+
+// client is a connected browser and the frames queued for it
+type client struct {
+	send chan frame
+}
+
+// Server is the dashboard, an http.Handler serving the page at / and its
+// WebSocket at /ws. It keeps every row and the latest board so browsers
+// opened mid-run catch up.
+type Server struct {
+	mux *http.ServeMux
+	srv *http.Server // Serving since Listen
+
+	mu        sync.Mutex
+	rows      []chartRow
+	board     *Board
+	boardSent time.Time
+	clients   map[*client]struct{}
+	failed    bool // A message failed to encode, which is logged once
+}
+
+// New creates a dashboard with nothing to show yet
+func New() *Server {
+	s := &Server{mux: http.NewServeMux(), clients: map[*client]struct{}{}}
+	s.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	s.mux.HandleFunc("GET /ws", s.serveSocket)
+	return s
+}
+
+// Listen serves the dashboard on addr in the background, returning once
+// the address is bound so a taken port is reported up front
+func (s *Server) Listen(addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s.srv = &http.Server{Handler: s}
+	go s.srv.Serve(ln)
+	return ln.Addr(), nil
+}
+
+// Close stops the server Listen started and disconnects every browser
+func (s *Server) Close() error {
+	var err error
+	if s.srv != nil {
+		err = s.srv.Close()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		s.drop(c)
+	}
+	return err
+}
+
+// Handle serves another handler beside the dashboard, like the Prometheus
+// metrics of the same run
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// ServeHTTP serves the page and its WebSocket
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// AddRow adds a learning curve row and sends it to every browser
+func (s *Server) AddRow(r metrics.Row) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, chartRow(r))
+	s.broadcast(message{Rows: []chartRow{chartRow(r)}})
+}
+
+// SetBoard makes b the latest board, sending it on unless one went out
+// within BoardInterval
+func (s *Server) SetBoard(b *Board) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.board = b
+	if now := time.Now(); now.Sub(s.boardSent) >= BoardInterval {
+		s.boardSent = now
+		s.broadcast(message{Board: b})
+	}
+}
+
+// broadcast queues m for every browser, dropping those too far behind.
+// The caller holds s.mu.
+func (s *Server) broadcast(m message) {
+	if len(s.clients) == 0 {
+		return
+	}
+	data, ok := s.encode(m)
+	if !ok {
+		return
+	}
+	for c := range s.clients {
+		select {
+		case c.send <- frame{opText, data}:
+		default:
+			s.drop(c)
+		}
+	}
+}
+
+// encode marshals m, logging the first failure. The caller holds s.mu.
+func (s *Server) encode(m message) ([]byte, bool) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		if !s.failed {
+			s.failed = true
+			log.Printf("Warning: Could not encode a dashboard message: %v", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// drop forgets a browser, ending its writer. The caller holds s.mu.
+func (s *Server) drop(c *client) {
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c.send)
+	}
+}
+
+// serveSocket upgrades a browser's connection, sends it everything so far
+// and then the updates as they come
+func (s *Server) serveSocket(w http.ResponseWriter, r *http.Request) {
+	conn, reader, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	c := &client{send: make(chan frame, clientBuffer)}
+	s.mu.Lock()
+	if data, ok := s.encode(message{Rows: s.rows, Board: s.board}); ok {
+		c.send <- frame{opText, data}
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	// The browser only ever pings or closes
+	go func() {
+		for {
+			f, err := readFrame(reader)
+			s.mu.Lock()
+			if err != nil || f.op == opClose {
+				s.drop(c)
+				s.mu.Unlock()
+				return
+			}
+			if _, ok := s.clients[c]; ok && f.op == opPing {
+				select {
+				case c.send <- frame{opPong, f.data}:
+				default:
+				}
+			}
+			s.mu.Unlock()
+		}
+	}()
+
+	for f := range c.send {
+		if err := writeFrame(conn, f); err != nil {
+			s.mu.Lock()
+			s.drop(c)
+			s.mu.Unlock()
+			break
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+	"autonomous-snake/internal/metrics"
+)
+
+// dial opens the dashboard's WebSocket with the handshake from RFC 6455
+func dial(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: dashboard\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake accept %q", accept)
+	}
+	return conn, reader
+}
+
+// receive reads one server frame as a message
+func receive(t *testing.T, r *bufio.Reader) message {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0] != 0x80|opText || head[1]&0x80 != 0 {
+		t.Fatalf("frame header %x", head)
+	}
+	n := int(head[1])
+	if n == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		t.Fatal(err)
+	}
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDashboard(t *testing.T) {
+	dash := New()
+	server := httptest.NewServer(dash)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("page: status %d, type %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// A browser joining late catches up on the rows and board so far
+	dash.AddRow(metrics.Row{Episode: 100, Epsilon: 0.5})
+	g := game.NewGame(config.DefaultGameConfig(), 1)
+	dash.SetBoard(NewBoard(7, g.State))
+	_, reader := dial(t, server.URL)
+	first := receive(t, reader)
+	if len(first.Rows) != 1 || first.Rows[0].Episode != 100 || first.Board == nil || first.Board.Episode != 7 {
+		t.Fatalf("first message %+v", first)
+	}
+	if b := first.Board; b.Width != 20 || len(b.Snakes[0]) != len(g.State.Snakes[0].Body) || len(b.Food) != 1 {
+		t.Errorf("board %+v", b)
+	}
+
+	// and then gets the rows as they come
+	dash.AddRow(metrics.Row{Episode: 200, Epsilon: 0.25})
+	if next := receive(t, reader); len(next.Rows) != 1 || next.Rows[0].Episode != 200 || next.Board != nil {
+		t.Errorf("next message %+v", next)
+	}
+}
+
+func TestClose(t *testing.T) {
+	dash := New()
+	addr, err := dash.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + addr.String()
+	_, reader := dial(t, url)
+	receive(t, reader)
+
+	if err := dash.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The browser is hung up on and nobody else gets in
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("read after Close: %v, want EOF", err)
+	}
+	if resp, err := http.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("dashboard still serving after Close")
+	}
+}
+
+func TestUpgradeRefused(t *testing.T) {
+	server := httptest.NewServer(New())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET of the socket: status %d", resp.StatusCode)
+	}
+}
+
+func TestNonFiniteRows(t *testing.T) {
+	// A diverging run's loss has no JSON number; it goes as null
+	row := metrics.Row{Episode: 100, Epsilon: 0.5, Loss: math.NaN(), EpsPerSec: math.Inf(1), AvgReward: [2]float64{math.Inf(-1), 2}}
+	data, err := json.Marshal(message{Rows: []chartRow{chartRow(row)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"loss":null`, `"eps_per_sec":null`, `"avg_reward":[null,2]`, `"epsilon":0.5`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s lacks %s", data, want)
+		}
+	}
+
+	// and neither it nor the rows after it are lost
+	dash := New()
+	server := httptest.NewServer(dash)
+	defer server.Close()
+	dash.AddRow(row)
+	_, reader := dial(t, server.URL)
+	if first := receive(t, reader); len(first.Rows) != 1 || first.Rows[0].Episode != 100 {
+		t.Fatalf("first message %+v", first)
+	}
+	dash.AddRow(metrics.Row{Episode: 200, Loss: math.NaN()})
+	dash.AddRow(metrics.Row{Episode: 300, Loss: 0.5})
+	for _, want := range []int{200, 300} {
+		if next := receive(t, reader); len(next.Rows) != 1 || next.Rows[0].Episode != want {
+			t.Errorf("next message %+v, want episode %d", next, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package dashboard
+
+// page is the dashboard, self-contained so it works offline. It draws the
+// charts and board on canvases from the rows and boards the WebSocket
+// pushes, reconnecting if training restarts.
+const page = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Training dashboard</title>
+<style>
+body { font-family: sans-serif; background: #141414; color: #eee; max-width: 1100px; margin: 1.5em auto; padding: 0 1em; }
+h1 { font-weight: normal; font-size: 1.4em; }
+#status { color: #999; margin-bottom: 1em; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(340px, 1fr)); gap: 1em; }
+.panel { background: #1c1c1c; border: 1px solid #333; padding: 0.5em; }
+.panel h2 { font-weight: normal; font-size: 1em; margin: 0 0 0.4em; }
+canvas { width: 100%; display: block; }
+.legend span { margin-right: 1em; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>Training dashboard</h1>
+<div id="status">Connecting...</div>
+<div class="grid">
+<div class="panel"><h2>Average episode reward</h2><canvas id="reward" width="520" height="260"></canvas><div class="legend" id="reward-legend"></div></div>
+<div class="panel"><h2>Outcome rates</h2><canvas id="wins" width="520" height="260"></canvas><div class="legend" id="wins-legend"></div></div>
+<div class="panel"><h2>Epsilon</h2><canvas id="epsilon" width="520" height="260"></canvas><div class="legend" id="epsilon-legend"></div></div>
+<div class="panel"><h2>Loss</h2><canvas id="loss" width="520" height="260"></canvas><div class="legend" id="loss-legend"></div></div>
+<div class="panel"><h2 id="board-title">Latest episode</h2><canvas id="board" width="520" height="520"></canvas></div>
+</div>
+<script>
+const colors = ["#4caf50", "#2196f3", "#f44336", "#ff9800"];
+const charts = {
+  reward: [["snake 0", r => r.avg_reward[0]], ["snake 1", r => r.avg_reward[1]]],
+  wins: [["snake 0 wins", r => r.win_rate[0]], ["snake 1 wins", r => r.win_rate[1]], ["ties", r => r.tie_rate]],
+  epsilon: [["epsilon", r => r.epsilon]],
+  loss: [["loss", r => r.loss]],
+};
+let rows = [];
+
+for (const [id, series] of Object.entries(charts)) {
+  document.getElementById(id + "-legend").innerHTML = series.map(([name], i) =>
+    '<span style="color:' + colors[i] + '">&#9632; ' + name + '</span>').join("");
+}
+
+function drawChart(id, series) {
+  const canvas = document.getElementById(id), ctx = canvas.getContext("2d");
+  const w = canvas.width, h = canvas.height, left = 56, bottom = 24, top = 8, right = 8;
+  ctx.fillStyle = "#1c1c1c";
+  ctx.fillRect(0, 0, w, h);
+  if (rows.length === 0) return;
+  // Values that weren't finite come as null and leave gaps
+  let lo = Infinity, hi = -Infinity;
+  for (const r of rows) for (const [, f] of series) {
+    const v = f(r);
+    if (v !== null) { lo = Math.min(lo, v); hi = Math.max(hi, v); }
+  }
+  if (lo > hi) { lo = 0; hi = 1; }
+  if (lo === hi) { lo -= 1; hi += 1; }
+  const x0 = rows[0].episode, x1 = Math.max(rows[rows.length - 1].episode, x0 + 1);
+  const px = e => left + (e - x0) / (x1 - x0) * (w - left - right);
+  const py = v => top + (hi - v) / (hi - lo) * (h - top - bottom);
+
+  ctx.strokeStyle = "#333";
+  ctx.fillStyle = "#999";
+  ctx.font = "11px sans-serif";
+  for (let i = 0; i <= 4; i++) {
+    const v = lo + (hi - lo) * i / 4, y = py(v);
+    ctx.beginPath(); ctx.moveTo(left, y); ctx.lineTo(w - right, y); ctx.stroke();
+    ctx.fillText(v.toPrecision(3), 4, y + 4);
+  }
+  ctx.fillText(x0, left, h - 6);
+  ctx.fillText(x1, w - right - ctx.measureText(String(x1)).width, h - 6);
+
+  series.forEach(([, f], i) => {
+    ctx.strokeStyle = colors[i];
+    ctx.lineWidth = 1.5;
+    ctx.beginPath();
+    let drawing = false;
+    for (const r of rows) {
+      const v = f(r);
+      if (v === null) { drawing = false; continue; }
+      drawing ? ctx.lineTo(px(r.episode), py(v)) : ctx.moveTo(px(r.episode), py(v));
+      drawing = true;
+    }
+    ctx.stroke();
+  });
+}
+
+function drawBoard(b) {
+  const canvas = document.getElementById("board"), ctx = canvas.getContext("2d");
+  const cell = Math.floor(Math.min(canvas.width / b.width, canvas.height / b.height));
+  canvas.height = cell * b.height;
+  ctx.fillStyle = "#141414";
+  ctx.fillRect(0, 0, canvas.width, canvas.height);
+  const ox = Math.floor((canvas.width - cell * b.width) / 2);
+  const fill = (p, color, pad) => { ctx.fillStyle = color; ctx.fillRect(ox + p[0] * cell + pad, p[1] * cell + pad, cell - 2 * pad, cell - 2 * pad); };
+  ctx.strokeStyle = "#282828";
+  ctx.strokeRect(ox, 0, cell * b.width, cell * b.height);
+  b.obstacles.forEach(p => fill(p, "#606060", 0));
+  b.food.forEach(p => fill(p, "#f44336", Math.floor(cell / 4)));
+  b.snakes.forEach((body, i) => {
+    const [color, head] = b.alive[i] ? [["#4caf50", "#2196f3"][i], ["#81c784", "#64b5f6"][i]] : ["#808080", "#a0a0a0"];
+    body.forEach((p, j) => fill(p, j ? color : head, 1));
+  });
+  const outcome = b.winner < 0 ? "tie" : "snake " + b.winner + " won";
+  document.getElementById("board-title").textContent =
+    "Episode " + b.episode + ": " + outcome + " after " + b.turns + " turns, scores " + b.scores.join("-");
+}
+
+function fixed(v, digits) {
+  return v === null ? "n/a" : v.toFixed(digits);
+}
+
+function connect() {
+  const ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+  ws.onopen = () => { document.getElementById("status").textContent = "Connected, waiting for the first log interval..."; };
+  ws.onmessage = event => {
+    const m = JSON.parse(event.data);
+    if (m.rows) {
+      rows = rows.concat(m.rows);
+      for (const [id, series] of Object.entries(charts)) drawChart(id, series);
+      if (rows.length) {
+        const r = rows[rows.length - 1];
+        document.getElementById("status").textContent =
+          "Episode " + r.episode + " | epsilon " + fixed(r.epsilon, 4) + " | average length " + fixed(r.avg_length, 1) +
+          " | " + fixed(r.eps_per_sec, 1) + " episodes/s";
+      }
+    }
+    if (m.board) drawBoard(m.board);
+  };
+  ws.onclose = () => {
+    document.getElementById("status").textContent = "Disconnected, retrying...";
+    rows = [];
+    setTimeout(connect, 2000);
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`
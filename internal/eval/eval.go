@@ -0,0 +1,158 @@
+// Package eval scores game positions with hand-written heuristics. The
+// scores are shared by search bots, reward shaping and the renderer.
+package eval
+
+import "autonomous-snake/internal/game"
+
+// Weights controls how much each heuristic term contributes to the score
+type Weights struct {
+	Control float64 `json:"control"` // Share of the board reachable first
+	Length  float64 `json:"length"`  // Length advantage over the opponent
+	Food    float64 `json:"food"`    // Closeness to the food
+}
+
+// DefaultWeights returns the weights used by Evaluate
+func DefaultWeights() Weights {
+	return Weights{
+		Control: 1.0,
+		Length:  0.5,
+		Food:    0.25,
+	}
+}
+
+// Breakdown holds each normalized term and the combined score. Terms and
+// score are in [-1, 1] from the evaluated snake's point of view.
+type Breakdown struct {
+	Control float64
+	Length  float64
+	Food    float64
+	Score   float64
+}
+
+// Evaluate scores the state for snakeID with the default weights: -1 is a
+// lost position, +1 a won one. The engine has no health mechanic, so hunger
+// is left to the stall penalties rather than scored here.
+func Evaluate(state *game.GameState, snakeID int) float64 {
+	return EvaluateWith(state, snakeID, DefaultWeights()).Score
+}
+
+// EvaluateWith scores the state for snakeID with custom weights
+func EvaluateWith(state *game.GameState, snakeID int, w Weights) Breakdown {
+	own := state.Snakes[snakeID]
+	opp := state.Snakes[1-snakeID]
+
+	// Terminal positions
+	switch {
+	case !own.Alive:
+		return Breakdown{Score: -1}
+	case !opp.Alive:
+		return Breakdown{Score: 1}
+	}
+
+	var b Breakdown
+	ownCells, oppCells := BoardControl(state, snakeID)
+	if total := ownCells + oppCells; total > 0 {
+		b.Control = float64(ownCells-oppCells) / float64(total)
+	}
+	b.Length = float64(own.Length()-opp.Length()) / float64(own.Length()+opp.Length())
+	if food, ok := state.NearestFood(own.Head()); ok {
+		dist := game.ManhattanDistance(own.Head(), food)
+		b.Food = 1 - 2*float64(dist)/float64(state.Width+state.Height)
+	}
+
+	if sum := w.Control + w.Length + w.Food; sum > 0 {
+		b.Score = (w.Control*b.Control + w.Length*b.Length + w.Food*b.Food) / sum
+	}
+	return b
+}
+
+// Cell owners used by BoardControl
+const (
+	cellFree int8 = iota
+	cellOwn
+	cellOpponent
+	cellContested
+	cellBlocked
+)
+
+// BoardControl splits the empty cells between the two snakes: a cell belongs
+// to whichever living head can reach it in fewer moves, and ties belong to
+// neither. Living bodies and obstacles block movement; dead bodies are
+// treated as empty.
+func BoardControl(state *game.GameState, snakeID int) (own, opp int) {
+	width, height := state.Width, state.Height
+	owner := make([]int8, width*height)
+	for _, snake := range state.Snakes {
+		if !snake.Alive {
+			continue
+		}
+		for _, pos := range snake.Body {
+			if !game.CheckWallCollision(pos, width, height) {
+				owner[pos.Y*width+pos.X] = cellBlocked
+			}
+		}
+	}
+	for _, pos := range state.Obstacles {
+		owner[pos.Y*width+pos.X] = cellBlocked
+	}
+
+	type node struct {
+		pos   game.Position
+		owner int8
+	}
+	var frontier []node
+	for id, snake := range state.Snakes {
+		if !snake.Alive {
+			continue
+		}
+		o := cellOpponent
+		if id == snakeID {
+			o = cellOwn
+		}
+		frontier = append(frontier, node{snake.Head(), o})
+	}
+
+	// Breadth-first search from both heads at once, one ring per iteration
+	directions := []game.Direction{game.Up, game.Down, game.Left, game.Right}
+	for len(frontier) > 0 {
+		claimed := make(map[int]int8)
+		var reached []game.Position
+		for _, n := range frontier {
+			for _, dir := range directions {
+				pos := n.pos.Neighbor(dir)
+				if game.CheckWallCollision(pos, width, height) {
+					continue
+				}
+				idx := pos.Y*width + pos.X
+				if owner[idx] != cellFree {
+					continue
+				}
+				if prev, ok := claimed[idx]; ok {
+					if prev != n.owner {
+						claimed[idx] = cellContested
+					}
+					continue
+				}
+				claimed[idx] = n.owner
+				reached = append(reached, pos)
+			}
+		}
+
+		// Contested cells are settled but not expanded from
+		frontier = frontier[:0]
+		for _, pos := range reached {
+			o := claimed[pos.Y*width+pos.X]
+			owner[pos.Y*width+pos.X] = o
+			switch o {
+			case cellOwn:
+				own++
+			case cellOpponent:
+				opp++
+			default:
+				continue
+			}
+			frontier = append(frontier, node{pos, o})
+		}
+	}
+	return own, opp
+}
@@ -0,0 +1,52 @@
+package eval
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func TestBoardControlSymmetricStart(t *testing.T) {
+	cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20}
+	g := game.NewGame(cfg, 42)
+
+	own, opp := BoardControl(g.State, 0)
+	if own != opp {
+		t.Errorf("expected equal control in the mirrored start, got %d vs %d", own, opp)
+	}
+	free := cfg.BoardWidth*cfg.BoardHeight - g.State.Snakes[0].Length() - g.State.Snakes[1].Length()
+	if own+opp > free {
+		t.Errorf("controlled %d cells but only %d are free", own+opp, free)
+	}
+}
+
+func TestEvaluateTerminal(t *testing.T) {
+	cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20}
+	g := game.NewGame(cfg, 42)
+	g.State.Snakes[1].Kill()
+
+	if got := Evaluate(g.State, 0); got != 1 {
+		t.Errorf("expected +1 for the survivor, got %v", got)
+	}
+	if got := Evaluate(g.State, 1); got != -1 {
+		t.Errorf("expected -1 for the dead snake, got %v", got)
+	}
+}
+
+func TestEvaluateCornered(t *testing.T) {
+	cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20}
+	g := game.NewGame(cfg, 42)
+	g.State.Food.Active = false
+
+	// Snake 0 in the corner, snake 1 in the middle
+	g.State.Snakes[0] = game.NewSnake(0, game.Position{X: 0, Y: 2}, game.Up, 3)
+	g.State.Snakes[1] = game.NewSnake(1, game.Position{X: 5, Y: 5}, game.Left, 3)
+
+	if score := Evaluate(g.State, 0); score >= 0 {
+		t.Errorf("expected cornered snake to score below 0, got %v", score)
+	}
+	if a, b := Evaluate(g.State, 0), Evaluate(g.State, 1); a != -b {
+		t.Errorf("expected zero-sum scores without food, got %v and %v", a, b)
+	}
+}
@@ -0,0 +1,30 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func BenchmarkStep(b *testing.B) {
+	g := NewGame(config.DefaultGameConfig(), 42)
+	rng := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if g.State.GameOver {
+			g.Reset()
+		}
+		var actions [2]Direction
+		for id, snake := range g.State.Snakes {
+			// Mostly keep going straight so games last long enough to matter
+			actions[id] = snake.Direction
+			if rng.Intn(4) == 0 {
+				actions[id] = snake.Direction.TurnLeft()
+			}
+		}
+		g.Step(actions)
+	}
+}
@@ -0,0 +1,196 @@
+package game
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BoardMap is a board layout: its size, the obstacles on it, where the
+// snakes start and where food may appear
+type BoardMap struct {
+	Name          string
+	Width, Height int
+	Obstacles     []Position // Wall cells inside the board, in reading order
+	Spawns        []Spawn    // Starting heads of snakes 0 and 1, or empty for the usual spots
+	FoodZone      []Position // Cells food may appear on, in reading order; empty for any free cell
+}
+
+// Spawn is where a snake starts and which way it faces
+type Spawn struct {
+	Head      Position
+	Direction Direction
+}
+
+// Map cells. A map file is a grid of these, one line per row, and every
+// row must be as wide as the first. Blank lines and lines starting with '#'
+// are skipped.
+const (
+	MapFloor   = '.'
+	MapWall    = 'X'
+	MapFood    = '*' // Floor food may appear on; without any, it may appear on every free cell
+	MapSnake0  = '0' // Snake 0's head, facing right in the left half of the board and left in the right half
+	MapSnake1  = '1' // Snake 1's head, likewise
+	mapComment = '#'
+)
+
+// spawnLength is how long snakes are when a game starts
+const spawnLength = 3
+
+//go:embed maps/*.map
+var bundledMaps embed.FS
+
+// loadedMaps caches maps by name, so every game on a map shares one copy
+var loadedMaps = struct {
+	sync.Mutex
+	m map[string]*BoardMap
+}{m: make(map[string]*BoardMap)}
+
+// LoadMap returns the bundled map with the given name, or else the map in
+// the file at that path. Maps are read once and shared; don't modify them.
+func LoadMap(name string) (*BoardMap, error) {
+	loadedMaps.Lock()
+	defer loadedMaps.Unlock()
+	if m, ok := loadedMaps.m[name]; ok {
+		return m, nil
+	}
+
+	file, err := bundledMaps.Open(path.Join("maps", name+".map"))
+	if err != nil {
+		if file, err = os.Open(name); err != nil {
+			return nil, fmt.Errorf("no bundled map %q (want %s) and %w", name, strings.Join(MapNames(), ", "), err)
+		}
+	}
+	defer file.Close()
+	m, err := ParseMap(strings.TrimSuffix(path.Base(name), ".map"), file)
+	if err != nil {
+		return nil, fmt.Errorf("map %s: %w", name, err)
+	}
+	loadedMaps.m[name] = m
+	return m, nil
+}
+
+// mustLoadMap is LoadMap for names that were checked already
+func mustLoadMap(name string) *BoardMap {
+	m, err := LoadMap(name)
+	if err != nil {
+		panic("game: " + err.Error())
+	}
+	return m
+}
+
+// MapNames returns the names of the bundled maps, sorted
+func MapNames() []string {
+	entries, _ := fs.ReadDir(bundledMaps, "maps")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".map"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseMap reads a map in the grid format of the Map cell constants. Snakes
+// start where the map marks them, or at the usual spots when it marks
+// neither; either way each needs room for its body behind its head.
+func ParseMap(name string, r io.Reader) (*BoardMap, error) {
+	m := &BoardMap{Name: name}
+	var heads []Position
+	var ids []int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || line[0] == mapComment {
+			continue
+		}
+		if m.Height == 0 {
+			m.Width = len(line)
+		} else if len(line) != m.Width {
+			return nil, fmt.Errorf("row %d is %d cells wide, want %d", m.Height+1, len(line), m.Width)
+		}
+		for x, c := range []byte(line) {
+			pos := Position{X: x, Y: m.Height}
+			switch c {
+			case MapFloor:
+			case MapWall:
+				m.Obstacles = append(m.Obstacles, pos)
+			case MapFood:
+				m.FoodZone = append(m.FoodZone, pos)
+			case MapSnake0, MapSnake1:
+				heads = append(heads, pos)
+				ids = append(ids, int(c-MapSnake0))
+			default:
+				return nil, fmt.Errorf("unknown cell %q at %v", c, pos)
+			}
+		}
+		m.Height++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.Width < 5 || m.Height < 5 {
+		return nil, fmt.Errorf("board is %dx%d, want at least 5x5", m.Width, m.Height)
+	}
+
+	switch {
+	case len(heads) == 2 && ids[0] != ids[1]:
+		m.Spawns = make([]Spawn, 2)
+		for i, head := range heads {
+			dir := Right
+			if head.X >= m.Width/2 {
+				dir = Left
+			}
+			m.Spawns[ids[i]] = Spawn{Head: head, Direction: dir}
+		}
+	case len(heads) != 0:
+		return nil, fmt.Errorf("map marks %d snake heads, want one %c and one %c or neither", len(heads), MapSnake0, MapSnake1)
+	}
+
+	// The snakes must fit on the floor where Reset puts them
+	state := &GameState{Width: m.Width, Height: m.Height, Obstacles: m.Obstacles}
+	for id, spawn := range m.spawns() {
+		for _, pos := range NewSnake(id, spawn.Head, spawn.Direction, spawnLength).Body {
+			if CheckWallCollision(pos, m.Width, m.Height) || state.IsObstacle(pos) {
+				return nil, fmt.Errorf("snake %d's starting body at %v is off the floor", id, pos)
+			}
+		}
+	}
+	return m, nil
+}
+
+// spawns returns where the snakes start on the map
+func (m *BoardMap) spawns() [2]Spawn {
+	if len(m.Spawns) == 2 {
+		return [2]Spawn{m.Spawns[0], m.Spawns[1]}
+	}
+	return defaultSpawns(m.Width, m.Height)
+}
+
+// defaultSpawns returns the usual starting spots: snake 0 on the left
+// facing right and snake 1 on the right facing left, or on a board taller
+// than it is wide, snake 0 at the top facing down and snake 1 at the
+// bottom facing up, so the snakes start along the longer side
+func defaultSpawns(width, height int) [2]Spawn {
+	if height > width {
+		return [2]Spawn{
+			{Head: Position{X: width / 2, Y: 3}, Direction: Down},
+			{Head: Position{X: width / 2, Y: height - 4}, Direction: Up},
+		}
+	}
+	return [2]Spawn{
+		{Head: Position{X: 3, Y: height / 2}, Direction: Right},
+		{Head: Position{X: width - 4, Y: height / 2}, Direction: Left},
+	}
+}
+
+// IsObstacle reports whether a map wall stands at pos
+func (s *GameState) IsObstacle(pos Position) bool {
+	return slices.Contains(s.Obstacles, pos)
+}
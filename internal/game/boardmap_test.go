@@ -0,0 +1,90 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func newMapGame(t *testing.T, name string) *Game {
+	t.Helper()
+	if _, err := LoadMap(name); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultGameConfig()
+	cfg.Map = name
+	return NewGame(cfg, 1)
+}
+
+func TestBundledMaps(t *testing.T) {
+	names := MapNames()
+	if len(names) == 0 {
+		t.Fatal("no bundled maps")
+	}
+	for _, name := range names {
+		g := newMapGame(t, name)
+		if err := g.State.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestMapObstacleKills(t *testing.T) {
+	g := newMapGame(t, "rooms")
+	if head := g.State.Snakes[0].Head(); head != (Position{X: 3, Y: 2}) {
+		t.Fatalf("snake 0 starts at %v, want the map's (3,2)", head)
+	}
+
+	// Snake 0 reaches the wall between the top rooms a turn before snake 1
+	var result StepResult
+	for range 6 {
+		result = g.Step([2]Direction{Right, Left})
+	}
+	if !result.Died[0] || result.Died[1] || result.Winner != 1 {
+		t.Fatalf("died %v winner %d, want snake 0 dead and snake 1 the winner", result.Died, result.Winner)
+	}
+	if c := result.Collisions[0]; len(c) != 1 || c[0].Type != WallCollision || c[0].Position != (Position{X: 9, Y: 2}) {
+		t.Errorf("collisions %v, want a wall at (9,2)", c)
+	}
+}
+
+func TestMapFoodZone(t *testing.T) {
+	g := newMapGame(t, "oasis")
+	board, _ := LoadMap("oasis")
+	for range 50 {
+		g.clearFood()
+		g.spawnFood()
+		if food := g.State.Food.Position; !slices.Contains(board.FoodZone, food) {
+			t.Fatalf("food at %v is outside the map's food zone", food)
+		}
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	valid := "# comment\n.......\n..0.1..\n...X...\n.......\n.......\n"
+	m, err := ParseMap("test", strings.NewReader(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Width != 7 || m.Height != 5 || len(m.Obstacles) != 1 || m.Obstacles[0] != (Position{X: 3, Y: 2}) {
+		t.Errorf("parsed %dx%d with obstacles %v", m.Width, m.Height, m.Obstacles)
+	}
+	if s := m.Spawns; len(s) != 2 || s[0] != (Spawn{Position{X: 2, Y: 1}, Right}) || s[1] != (Spawn{Position{X: 4, Y: 1}, Left}) {
+		t.Errorf("spawns %v, want snake 0 at (2,1) facing right and snake 1 at (4,1) facing left", s)
+	}
+
+	for name, text := range map[string]string{
+		"ragged":     ".....\n....\n.....\n.....\n.....\n",
+		"small":      "....\n....\n....\n....\n",
+		"unknown":    ".....\n..?..\n.....\n.....\n.....\n",
+		"one head":   ".....\n.0...\n.....\n.....\n.....\n",
+		"same heads": ".....\n.0.0.\n.....\n.....\n.....\n",
+		"blocked":    ".....\n.X0.1\n.....\n.....\n.....\n",
+	} {
+		if _, err := ParseMap(name, strings.NewReader(text)); err == nil {
+			t.Errorf("%s map parsed without error", name)
+		}
+	}
+}
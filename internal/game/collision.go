@@ -11,6 +11,23 @@ const (
 	HeadToHeadCollision
 )
 
+// String returns a short name for the collision type
+func (t CollisionType) String() string {
+	switch t {
+	case NoCollision:
+		return "none"
+	case WallCollision:
+		return "wall"
+	case SelfCollision:
+		return "self"
+	case OtherSnakeCollision:
+		return "other-snake"
+	case HeadToHeadCollision:
+		return "head-to-head"
+	}
+	return "unknown"
+}
+
 // CollisionResult contains information about a collision check
 type CollisionResult struct {
 	Type     CollisionType
@@ -62,6 +79,8 @@ func CheckFoodCollision(snake *Snake, foodPos Position) bool {
 
 // CheckAllCollisions performs all collision checks for a game state
 // Returns collision results for each snake
+// Note: Called after both snakes have moved, so a head may take the cell a
+// tail left this turn; a snake that grew kept its tail and blocks it
 func CheckAllCollisions(snakes [2]*Snake, width, height int) [2][]CollisionResult {
 	var results [2][]CollisionResult
 
@@ -129,6 +148,9 @@ func CheckAllCollisions(snakes [2]*Snake, width, height int) [2][]CollisionResul
 
 // IsDangerPosition checks if a position would be dangerous for a snake
 // Used for state encoding
+// Note: The snake's own tail is safe when it isn't growing, as it moves on
+// before collisions are checked. The other snake's tail stays dangerous,
+// since it keeps it if it eats this turn.
 func IsDangerPosition(pos Position, snakeID int, snakes [2]*Snake, width, height int) bool {
 	// Wall danger
 	if CheckWallCollision(pos, width, height) {
@@ -137,7 +159,7 @@ func IsDangerPosition(pos Position, snakeID int, snakes [2]*Snake, width, height
 
 	// Self-body danger (excluding head since we're checking future position)
 	ownSnake := snakes[snakeID]
-	if ownSnake.ContainsPosition(pos, true) {
+	if ownSnake.ContainsPosition(pos, true) && !(ownSnake.TailLeaves() && pos == ownSnake.Tail()) {
 		return true
 	}
 
@@ -0,0 +1,119 @@
+package game
+
+// EventType identifies what happened in an Event
+type EventType int
+
+const (
+	EventFoodEaten     EventType = iota // Snake ate the pellet at Position
+	EventWallHit                        // Snake's head left the board, or entered a closed royale ring, at Position
+	EventSelfCollision                  // Snake ran into its own body at Position
+	EventSnakeHit                       // Snake ran into the body of snake Other at Position
+	EventHeadToHead                     // Snake's head met snake Other's at Position
+	EventWin                            // Snake won the game
+	EventTie                            // The game ended without a winner
+	EventRespawn                        // Snake came back at Position in an endless match
+)
+
+// String returns a short name for the event type
+func (t EventType) String() string {
+	switch t {
+	case EventFoodEaten:
+		return "food"
+	case EventWallHit:
+		return "wall"
+	case EventSelfCollision:
+		return "self"
+	case EventSnakeHit:
+		return "other-snake"
+	case EventHeadToHead:
+		return "head-to-head"
+	case EventWin:
+		return "win"
+	case EventTie:
+		return "tie"
+	case EventRespawn:
+		return "respawn"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the type by name, so logged events read as text
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// Event is one thing that happened during a turn
+type Event struct {
+	Type     EventType `json:"type"`
+	Turn     int       `json:"turn"`
+	Snake    int       `json:"snake"`               // Snake the event happened to, -1 for a tie
+	Other    int       `json:"other"`               // Other snake involved, the loser for a win, or -1
+	Position Position  `json:"position"`            // Where it happened; unset for wins and ties
+	TimedOut bool      `json:"timed_out,omitempty"` // A win or tie came from the turn limit or EndByScore
+	Repeated bool      `json:"repeated,omitempty"`  // A tie came from a repeated position
+}
+
+// EventListener receives the events of every turn, in the order they
+// happened: food first, then collisions and respawns, then the end of the
+// game
+type EventListener func(Event)
+
+// AddListener registers l to receive the game's events from the next Step
+// on. Clones and games made by NewGameFromState start without listeners, so
+// searches and simulations don't report their lookahead.
+func (g *Game) AddListener(l EventListener) {
+	g.listeners = append(g.listeners, l)
+}
+
+// emit sends e to every listener
+func (g *Game) emit(e Event) {
+	for _, l := range g.listeners {
+		l(e)
+	}
+}
+
+// emitStep reports the events of the turn that produced result, up to a
+// game over by the rules; EndByScore reports its own
+func (g *Game) emitStep(result StepResult) {
+	turn := g.State.Turn
+	for i, ate := range result.AteFood {
+		if ate {
+			g.emit(Event{Type: EventFoodEaten, Turn: turn, Snake: i, Other: -1, Position: g.State.Snakes[i].Head()})
+		}
+	}
+	for i, collisions := range result.Collisions {
+		for _, c := range collisions {
+			e := Event{Turn: turn, Snake: i, Other: -1, Position: c.Position}
+			switch c.Type {
+			case WallCollision:
+				e.Type = EventWallHit
+			case SelfCollision:
+				e.Type = EventSelfCollision
+			case OtherSnakeCollision:
+				e.Type, e.Other = EventSnakeHit, c.SnakeID
+			case HeadToHeadCollision:
+				e.Type, e.Other = EventHeadToHead, c.SnakeID
+			default:
+				continue
+			}
+			g.emit(e)
+		}
+	}
+	for i, back := range result.Respawned {
+		if back {
+			g.emit(Event{Type: EventRespawn, Turn: turn, Snake: i, Other: -1, Position: g.State.Snakes[i].Head()})
+		}
+	}
+	if result.GameOver {
+		g.emitOutcome()
+	}
+}
+
+// emitOutcome reports how the finished game ended
+func (g *Game) emitOutcome() {
+	e := Event{Type: EventTie, Turn: g.State.Turn, Snake: -1, Other: -1, TimedOut: g.State.TimedOut, Repeated: g.State.Repeated}
+	if winner := g.State.Winner; winner >= 0 {
+		e.Type, e.Snake, e.Other = EventWin, winner, 1-winner
+	}
+	g.emit(e)
+}
@@ -0,0 +1,45 @@
+package game
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestEvents(t *testing.T) {
+	g := NewGame(config.DefaultGameConfig(), 1)
+	g.State.Food = Food{Position: Position{X: 3, Y: 9}, Active: true}
+	g.State.ExtraFood = nil
+	var events []Event
+	g.AddListener(func(e Event) { events = append(events, e) })
+
+	// Snake 0 eats straight away, snake 1 runs off the bottom on turn 10
+	for !g.State.GameOver {
+		g.Step([2]Direction{Up, Down})
+	}
+	if len(events) < 3 {
+		t.Fatalf("%d events: %v", len(events), events)
+	}
+	if want := (Event{Type: EventFoodEaten, Turn: 1, Snake: 0, Other: -1, Position: Position{X: 3, Y: 9}}); events[0] != want {
+		t.Errorf("first event %+v, want %+v", events[0], want)
+	}
+	end := events[len(events)-2:]
+	if want := (Event{Type: EventWallHit, Turn: 10, Snake: 1, Other: -1, Position: Position{X: 16, Y: 20}}); end[0] != want {
+		t.Errorf("collision event %+v, want %+v", end[0], want)
+	}
+	if want := (Event{Type: EventWin, Turn: 10, Snake: 0, Other: 1}); end[1] != want {
+		t.Errorf("last event %+v, want %+v", end[1], want)
+	}
+
+	// Simulations stay quiet, and games ended by score report the result
+	g.Reset()
+	events = nil
+	g.Clone().Step([2]Direction{Up, Up})
+	if len(events) != 0 {
+		t.Errorf("clone reported %v", events)
+	}
+	g.EndByScore()
+	if len(events) != 1 || events[0].Type != EventTie || !events[0].TimedOut {
+		t.Errorf("EndByScore reported %+v, want a timed out tie", events)
+	}
+}
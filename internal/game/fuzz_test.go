@@ -0,0 +1,114 @@
+package game
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+// FuzzStep plays random action sequences and checks the rule invariants
+// after every turn. Each byte of moves encodes both snakes' directions.
+func FuzzStep(f *testing.F) {
+	f.Add(int64(42), []byte{0x00, 0x00, 0x00})
+	f.Add(int64(1), []byte{0x0c, 0x0c, 0x03, 0x03, 0x0c, 0x0c})
+	f.Add(int64(7), []byte{0x31, 0x31, 0x20, 0x20, 0x02, 0x02, 0x13, 0x13})
+
+	f.Fuzz(func(t *testing.T, seed int64, moves []byte) {
+		cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20}
+		g := NewGame(cfg, seed)
+
+		for i, m := range moves {
+			if g.State.GameOver {
+				g.Reset()
+			}
+			actions := [2]Direction{Direction(m & 3), Direction((m >> 2) & 3)}
+
+			var before [2][]Position
+			var alive [2]bool
+			for id, snake := range g.State.Snakes {
+				before[id] = append([]Position(nil), snake.Body...)
+				alive[id] = snake.Alive
+			}
+			turn := g.State.Turn
+
+			result := g.Step(actions)
+			state := g.State
+
+			if state.Turn != turn+1 {
+				t.Fatalf("move %d: turn went from %d to %d", i, turn, state.Turn)
+			}
+			for id, snake := range state.Snakes {
+				if snake.Length() <= 0 {
+					t.Fatalf("move %d: snake %d has length %d", i, id, snake.Length())
+				}
+				if alive[id] && snake.Length() < len(before[id]) {
+					t.Fatalf("move %d: snake %d shrank from %d to %d", i, id, len(before[id]), snake.Length())
+				}
+				if !alive[id] && !samePositions(before[id], snake.Body) {
+					t.Fatalf("move %d: dead snake %d moved", i, id)
+				}
+				if result.Died[id] && snake.Alive {
+					t.Fatalf("move %d: snake %d reported dead but is alive", i, id)
+				}
+			}
+
+			if state.Food.Active {
+				food := state.Food.Position
+				if CheckWallCollision(food, state.Width, state.Height) {
+					t.Fatalf("move %d: food out of bounds at %v", i, food)
+				}
+				for id, snake := range state.Snakes {
+					for _, pos := range snake.Body {
+						if pos == food {
+							t.Fatalf("move %d: food at %v is on snake %d", i, food, id)
+						}
+					}
+				}
+			}
+
+			checkWinner(t, i, state, result)
+			if err := state.Validate(); err != nil {
+				t.Fatalf("move %d: %v", i, err)
+			}
+		}
+	})
+}
+
+// checkWinner verifies the game-over flags agree with which snakes are alive
+func checkWinner(t *testing.T, move int, state *GameState, result StepResult) {
+	t.Helper()
+	alive0, alive1 := state.Snakes[0].Alive, state.Snakes[1].Alive
+
+	want := -1
+	switch {
+	case alive0 && alive1:
+		if state.GameOver || result.GameOver {
+			t.Fatalf("move %d: game over with both snakes alive", move)
+		}
+		return
+	case alive0:
+		want = 0
+	case alive1:
+		want = 1
+	}
+
+	if !state.GameOver || !result.GameOver {
+		t.Fatalf("move %d: game not over with a dead snake", move)
+	}
+	if state.Winner != want || result.Winner != want {
+		t.Fatalf("move %d: winner %d (result %d), want %d", move, state.Winner, result.Winner, want)
+	}
+}
+
+// samePositions reports whether two bodies are identical
+func samePositions(a, b []Position) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
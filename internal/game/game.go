@@ -1,7 +1,11 @@
 package game
 
 import (
+	"cmp"
+	"encoding/json"
+	"maps"
 	"math/rand"
+	"slices"
 
 	"autonomous-snake/internal/config"
 )
@@ -21,62 +25,445 @@ type GameState struct {
 	Turn     int
 	GameOver bool
 	Winner   int // -1 = tie, 0 = snake 0 wins, 1 = snake 1 wins
+
+	// TimedOut records that the game ended on the turn limit or EndByScore
+	// rather than a death; Winner is then the Leader
+	TimedOut bool
+
+	// Repeated records that the game ended in a draw because the same
+	// position came up too often, see GameConfig.RepeatLimit
+	Repeated bool
+
+	// Respawn brings dead snakes back in endless matches, which end on
+	// its score target rather than a death
+	Respawn config.RespawnConfig
+
+	// Rules names the ruleset the game is played by, see LookupRules;
+	// empty for the standard rules
+	Rules string
+
+	// Obstacles are the wall cells a board map puts inside the board, see
+	// BoardMap. Clones share the slice; it never changes during a game.
+	Obstacles []Position
+
+	// ExtraFood holds the pellets beyond Food when the game keeps more than
+	// one on the board
+	ExtraFood []Position
+
+	// Golden lists the pellets that are golden, worth the golden food
+	// values of config.FoodValueConfig
+	Golden []Position
+
+	// FoodExpires holds the turn each pellet expires or is relocated on
+	// when food doesn't stay put, see config.FoodSpawnConfig, and
+	// FoodLifespan the most turns a pellet lasts then; 0 when food stays
+	FoodExpires  FoodExpiries
+	FoodLifespan int
+
+	// setup holds the settings of the game the state comes from, which
+	// NewGameFromState carries over; nil for a state built by hand. Clones
+	// share it.
+	setup *gameSetup
+}
+
+// gameSetup is how a Game is configured beyond what its state holds
+type gameSetup struct {
+	foodCount   int
+	maxTurns    int
+	repeatLimit int
+	board       *BoardMap
+	spawn       config.FoodSpawnConfig
+	value       config.FoodValueConfig
+}
+
+// FoodExpiries maps pellets to the turn they expire or are relocated on.
+// It encodes to JSON as a list, since JSON objects can't have positions as
+// keys.
+type FoodExpiries map[Position]int
+
+// foodExpiry is one FoodExpiries entry in JSON
+type foodExpiry struct {
+	Pos  Position `json:"pos"`
+	Turn int      `json:"turn"`
+}
+
+// MarshalJSON encodes the expiries as a list ordered by position
+func (f FoodExpiries) MarshalJSON() ([]byte, error) {
+	if f == nil {
+		return []byte("null"), nil
+	}
+	list := make([]foodExpiry, 0, len(f))
+	for pos, turn := range f {
+		list = append(list, foodExpiry{pos, turn})
+	}
+	slices.SortFunc(list, func(a, b foodExpiry) int {
+		return cmp.Or(cmp.Compare(a.Pos.Y, b.Pos.Y), cmp.Compare(a.Pos.X, b.Pos.X))
+	})
+	return json.Marshal(list)
+}
+
+// UnmarshalJSON decodes a list written by MarshalJSON
+func (f *FoodExpiries) UnmarshalJSON(data []byte) error {
+	var list []foodExpiry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	if list == nil {
+		*f = nil
+		return nil
+	}
+	*f = make(FoodExpiries, len(list))
+	for _, e := range list {
+		(*f)[e.Pos] = e.Turn
+	}
+	return nil
+}
+
+// FoodPositions returns the positions of all pellets on the board
+func (s *GameState) FoodPositions() []Position {
+	var positions []Position
+	if s.Food.Active {
+		positions = append(positions, s.Food.Position)
+	}
+	return append(positions, s.ExtraFood...)
+}
+
+// NextHead returns where snake's head lands moving in dir under the
+// state's rules
+func (s *GameState) NextHead(snake *Snake, dir Direction) Position {
+	return s.rules().Move(s, snake.Head(), dir)
+}
+
+// outcome reports whether the game is decided and who won: by the rules,
+// or in an endless match once a snake reaches the score target
+func (s *GameState) outcome() (bool, int) {
+	if !s.Respawn.Enabled {
+		return s.rules().Outcome(s)
+	}
+	target := s.Respawn.ScoreTarget
+	if target > 0 && (s.Snakes[0].Score >= target || s.Snakes[1].Score >= target) {
+		return true, s.Leader()
+	}
+	return false, -1
+}
+
+// rules returns the state's ruleset
+func (s *GameState) rules() Rules {
+	if s.Rules == "" {
+		return Standard{}
+	}
+	return mustLookupRules(s.Rules)
+}
+
+// NearestFood returns the pellet closest to from by Manhattan distance, or
+// false if the board has no food
+func (s *GameState) NearestFood(from Position) (Position, bool) {
+	best, found := Position{}, false
+	bestDist := 0
+	for _, pos := range s.FoodPositions() {
+		if dist := ManhattanDistance(from, pos); !found || dist < bestDist {
+			best, bestDist, found = pos, dist, true
+		}
+	}
+	return best, found
+}
+
+// HasFoodAt reports whether a pellet lies at pos
+func (s *GameState) HasFoodAt(pos Position) bool {
+	if s.Food.Active && s.Food.Position.Equals(pos) {
+		return true
+	}
+	for _, food := range s.ExtraFood {
+		if food.Equals(pos) {
+			return true
+		}
+	}
+	return false
+}
+
+// FoodTimeLeft returns the fraction of its lifespan the pellet at pos has
+// left before it expires or is relocated, 1 for food that stays put
+func (s *GameState) FoodTimeLeft(pos Position) float64 {
+	expires, ok := s.FoodExpires[pos]
+	if !ok || s.FoodLifespan <= 0 {
+		return 1
+	}
+	return float64(max(expires-s.Turn, 0)) / float64(s.FoodLifespan)
+}
+
+// IsGolden reports whether the pellet at pos is golden
+func (s *GameState) IsGolden(pos Position) bool {
+	return slices.Contains(s.Golden, pos)
+}
+
+// removeFoodAt removes the pellet at pos, if any
+func (s *GameState) removeFoodAt(pos Position) {
+	if i := slices.Index(s.Golden, pos); i >= 0 {
+		s.Golden = slices.Delete(s.Golden, i, i+1)
+	}
+	delete(s.FoodExpires, pos)
+	if s.Food.Active && s.Food.Position.Equals(pos) {
+		s.Food.Active = false
+		return
+	}
+	for i, food := range s.ExtraFood {
+		if food.Equals(pos) {
+			s.ExtraFood = append(s.ExtraFood[:i], s.ExtraFood[i+1:]...)
+			return
+		}
+	}
 }
 
 // StepResult contains the result of a game step
 type StepResult struct {
-	Rewards  [2]float64
-	AteFood  [2]bool
-	Died     [2]bool
-	GameOver bool
-	Winner   int
+	Rewards    [2]float64
+	AteFood    [2]bool
+	FoodPoints [2]int // Score of the pellet each snake ate, 0 if it ate none
+	Died       [2]bool
+	Collisions [2][]CollisionResult // Why each snake died this turn, empty if it survived
+	GameOver   bool
+	Winner     int
+	TimedOut   bool    // The game ended on the turn limit, see GameState.TimedOut
+	Repeated   bool    // The game ended in a draw on a repeated position, see GameState.Repeated
+	Respawned  [2]bool // Snakes that came back this turn in an endless match, see GameState.Respawn
+}
+
+// DeathCause returns why snake id died this turn, the first collision found
+// for it, or NoCollision if it survived
+func (r StepResult) DeathCause(id int) CollisionType {
+	if len(r.Collisions[id]) == 0 {
+		return NoCollision
+	}
+	return r.Collisions[id][0].Type
 }
 
 // Game manages the game logic
 type Game struct {
-	State *GameState
-	rng   *rand.Rand
+	State   *GameState
+	Rewards [2]config.RewardConfig // Per-snake reward values
+	rng     *rand.Rand
+
+	// foodCount is the number of pellets kept on the board
+	foodCount int
+
+	// maxTurns ends the game by score when reached, 0 for never
+	maxTurns int
+
+	// repeatLimit ends the game in a draw once a position has occurred
+	// that often, 0 for never; positions counts each position's Hash
+	repeatLimit int
+	positions   map[uint64]int
+
+	// rules decides movement, collisions, rewards and the winner
+	rules Rules
+
+	// board is the map the game is played on, nil for an open board
+	board *BoardMap
+
+	// listeners receive the events of every step, see AddListener
+	listeners []EventListener
+
+	// spawn controls food placement, expiry and relocation
+	spawn config.FoodSpawnConfig
+
+	// value sets what pellets score and grow snakes by
+	value config.FoodValueConfig
 }
 
-// NewGame creates a new game instance
+// NewGame creates a new game instance. A board map sets the board size in
+// place of cfg's. It panics if cfg.Rules names no ruleset or cfg.Map no
+// map; check them with LookupRules and LoadMap first.
 func NewGame(cfg config.GameConfig, seed int64) *Game {
 	rng := rand.New(rand.NewSource(seed))
+	var board *BoardMap
+	if cfg.Map != "" {
+		board = mustLoadMap(cfg.Map)
+		cfg.BoardWidth, cfg.BoardHeight = board.Width, board.Height
+	}
 	g := &Game{
 		State: &GameState{
 			Width:  cfg.BoardWidth,
 			Height: cfg.BoardHeight,
+			Rules:  cfg.Rules,
 		},
-		rng: rng,
+		Rewards:   [2]config.RewardConfig{config.DefaultRewardConfig(), config.DefaultRewardConfig()},
+		rng:       rng,
+		foodCount: max(cfg.FoodCount, 1),
+		maxTurns:  cfg.MaxTurns,
+		spawn:     cfg.Spawn,
+		rules:     mustLookupRules(cfg.Rules),
+		board:     board,
+
+		repeatLimit: cfg.RepeatLimit,
+		value:       cfg.Value,
+	}
+	if board != nil {
+		g.State.Obstacles = board.Obstacles
 	}
+	g.State.FoodLifespan = cfg.Spawn.Lifespan()
+	g.State.Respawn = cfg.Respawn
+	g.recordSetup()
 	g.Reset()
 	return g
 }
 
+// recordSetup stores the game's settings on its state for NewGameFromState
+func (g *Game) recordSetup() {
+	g.State.setup = &gameSetup{
+		foodCount:   g.foodCount,
+		maxTurns:    g.maxTurns,
+		repeatLimit: g.repeatLimit,
+		board:       g.board,
+		spawn:       g.spawn,
+		value:       g.value,
+	}
+}
+
 // Reset resets the game to initial state
 func (g *Game) Reset() *GameState {
 	width := g.State.Width
 	height := g.State.Height
 
-	// Place snake 0 on the left side, facing right
-	snake0Start := Position{X: 3, Y: height / 2}
-	g.State.Snakes[0] = NewSnake(0, snake0Start, Right, 3)
+	// Place snake 0 on the left side facing right and snake 1 on the right
+	// facing left, or top and bottom on a tall board, unless the map says
+	// otherwise
+	spawns := defaultSpawns(width, height)
+	if g.board != nil {
+		spawns = g.board.spawns()
+	}
+	for i, spawn := range spawns {
+		g.State.Snakes[i] = NewSnake(i, spawn.Head, spawn.Direction, spawnLength)
+	}
+	g.rules.Setup(g.State)
 
-	// Place snake 1 on the right side, facing left
-	snake1Start := Position{X: width - 4, Y: height / 2}
-	g.State.Snakes[1] = NewSnake(1, snake1Start, Left, 3)
+	g.State.Turn = 0
+	g.State.GameOver = false
+	g.State.Winner = -1
+	g.State.TimedOut = false
+	g.State.Repeated = false
 
 	// Spawn initial food
+	g.clearFood()
 	g.spawnFood()
 
-	g.State.Turn = 0
-	g.State.GameOver = false
-	g.State.Winner = -1
+	if g.repeatLimit > 0 {
+		g.positions = map[uint64]int{g.State.Hash(): 1}
+	}
 
 	return g.State
 }
 
-// spawnFood places food at a random empty position
+// Resize changes the board size used from the next Reset on. Games on a
+// board map keep the map's size.
+func (g *Game) Resize(width, height int) {
+	if g.board != nil {
+		return
+	}
+	g.State.Width = width
+	g.State.Height = height
+}
+
+// SetFoodCount sets how many pellets are kept on the board, from the next
+// Reset or pellet eaten on. Values below one mean one.
+func (g *Game) SetFoodCount(n int) {
+	g.foodCount = max(n, 1)
+	g.recordSetup()
+}
+
+// Reseed restarts the game's random number generator. Reseeding before
+// Reset makes the next game reproducible from the seed and the moves alone,
+// as long as nothing else draws from the generator (Clone does).
+func (g *Game) Reseed(seed int64) {
+	g.rng = rand.New(rand.NewSource(seed))
+}
+
+// spawnFood tops the board up to foodCount pellets at random empty
+// positions. Food is filled first, then ExtraFood. Pellets beyond the
+// count are left in place and are not replaced once eaten.
+//
+// With the cluster pattern nothing spawns until the board is empty; then a
+// whole cluster appears within ClusterRadius of a random center.
 func (g *Game) spawnFood() {
+	cluster := g.spawn.Pattern == config.FoodPatternCluster
+	if cluster && len(g.State.FoodPositions()) > 0 {
+		return
+	}
+
+	var center Position
+	for !g.State.Food.Active || 1+len(g.State.ExtraFood) < g.foodCount {
+		pos, ok := Position{}, false
+		if cluster && g.State.Food.Active {
+			pos, ok = g.randomEmptyPosition(center, g.spawn.ClusterRadius)
+		}
+		if !ok {
+			pos, ok = g.randomEmptyPosition(Position{}, -1)
+		}
+		if !ok {
+			return
+		}
+
+		if !g.State.Food.Active {
+			g.State.Food = Food{Position: pos, Active: true}
+			center = pos
+		} else {
+			g.State.ExtraFood = append(g.State.ExtraFood, pos)
+		}
+		if g.value.GoldenChance > 0 && g.rng.Float64() < g.value.GoldenChance {
+			g.State.Golden = append(g.State.Golden, pos)
+		}
+		if g.State.FoodExpires != nil {
+			g.State.FoodExpires[pos] = g.expiry()
+		}
+	}
+}
+
+// expiry returns the turn a pellet spawning now expires or is relocated on
+func (g *Game) expiry() int {
+	turn := g.State.Turn
+	expires := turn + g.spawn.Lifetime
+	if every := g.spawn.RelocateEvery; every > 0 {
+		next := (turn/every + 1) * every
+		if g.spawn.Lifetime <= 0 || next < expires {
+			expires = next
+		}
+	}
+	return expires
+}
+
+// clearFood removes every pellet from the board
+func (g *Game) clearFood() {
+	g.State.Food.Active = false
+	g.State.ExtraFood = nil
+	g.State.Golden = nil
+	if g.spawn.Lifetime > 0 || g.spawn.RelocateEvery > 0 {
+		g.State.FoodExpires = make(FoodExpiries)
+	}
+}
+
+// updateFood relocates or expires pellets as configured and spawns
+// replacements
+func (g *Game) updateFood() {
+	turn := g.State.Turn
+	switch {
+	case g.spawn.RelocateEvery > 0 && turn%g.spawn.RelocateEvery == 0:
+		g.clearFood()
+	case g.spawn.Lifetime > 0:
+		// Relocation was handled above, so a pellet due now expired
+		for _, pos := range g.State.FoodPositions() {
+			if expires, ok := g.State.FoodExpires[pos]; ok && turn >= expires {
+				g.State.removeFoodAt(pos)
+			}
+		}
+	default:
+		return
+	}
+	g.spawnFood()
+}
+
+// randomEmptyPosition picks a random cell not covered by a snake or food.
+// A non-negative radius limits the choice to cells within that Manhattan
+// distance of center.
+func (g *Game) randomEmptyPosition(center Position, radius int) (Position, bool) {
 	// Collect all occupied positions
 	occupied := make(map[Position]bool)
 	for _, snake := range g.State.Snakes {
@@ -86,28 +473,40 @@ func (g *Game) spawnFood() {
 			}
 		}
 	}
+	for _, pos := range g.State.FoodPositions() {
+		occupied[pos] = true
+	}
+	for _, pos := range g.State.Obstacles {
+		occupied[pos] = true
+	}
 
-	// Find all empty positions
+	// Find all empty positions, in the map's food zone if it has one
 	var emptyPositions []Position
-	for x := 0; x < g.State.Width; x++ {
-		for y := 0; y < g.State.Height; y++ {
-			pos := Position{X: x, Y: y}
-			if !occupied[pos] {
-				emptyPositions = append(emptyPositions, pos)
+	consider := func(pos Position) {
+		if radius >= 0 && ManhattanDistance(pos, center) > radius {
+			return
+		}
+		if !occupied[pos] {
+			emptyPositions = append(emptyPositions, pos)
+		}
+	}
+	if g.board != nil && len(g.board.FoodZone) > 0 {
+		for _, pos := range g.board.FoodZone {
+			consider(pos)
+		}
+	} else {
+		for x := 0; x < g.State.Width; x++ {
+			for y := 0; y < g.State.Height; y++ {
+				consider(Position{X: x, Y: y})
 			}
 		}
 	}
 
 	// Pick a random empty position
-	if len(emptyPositions) > 0 {
-		idx := g.rng.Intn(len(emptyPositions))
-		g.State.Food = Food{
-			Position: emptyPositions[idx],
-			Active:   true,
-		}
-	} else {
-		g.State.Food.Active = false
+	if len(emptyPositions) == 0 {
+		return Position{}, false
 	}
+	return emptyPositions[g.rng.Intn(len(emptyPositions))], true
 }
 
 // Step advances the game by one turn
@@ -127,21 +526,32 @@ func (g *Game) Step(actions [2]Direction) StepResult {
 
 	// Check which snakes will eat food this turn (before moving)
 	willEat := [2]bool{false, false}
+	var growth [2]int
+	var dirs [2]Direction
+	var next [2]Position
 	for i := 0; i < 2; i++ {
 		snake := g.State.Snakes[i]
 		if snake.Alive {
-			nextHead := snake.NextHead(actions[i])
-			if g.State.Food.Active && nextHead.Equals(g.State.Food.Position) {
-				willEat[i] = true
+			dirs[i] = snake.ResolveDirection(actions[i])
+			next[i] = g.rules.Move(g.State, snake.Head(), dirs[i])
+			if willEat[i] = g.State.HasFoodAt(next[i]); willEat[i] {
+				result.FoodPoints[i], growth[i] = g.value.Pellet(g.State.IsGolden(next[i]))
 			}
 		}
 	}
 
-	// Move both snakes simultaneously
+	// Move both snakes simultaneously. A snake grows a segment on the turn
+	// it eats and on each turn after while the pellet's growth lasts.
 	for i := 0; i < 2; i++ {
 		snake := g.State.Snakes[i]
 		if snake.Alive {
-			snake.Move(actions[i], willEat[i])
+			grow := willEat[i] || snake.Growing > 0
+			if willEat[i] {
+				snake.Growing += growth[i] - 1
+			} else if grow {
+				snake.Growing--
+			}
+			snake.advance(dirs[i], next[i], grow)
 		}
 	}
 
@@ -149,79 +559,122 @@ func (g *Game) Step(actions [2]Direction) StepResult {
 	for i := 0; i < 2; i++ {
 		if willEat[i] {
 			result.AteFood[i] = true
-			g.State.Snakes[i].Score++
+			g.State.Snakes[i].Score += result.FoodPoints[i]
 		}
 	}
 
 	// Spawn new food if eaten
 	if willEat[0] || willEat[1] {
+		for i := 0; i < 2; i++ {
+			if willEat[i] {
+				g.State.removeFoodAt(next[i])
+			}
+		}
 		g.spawnFood()
 	}
+	g.updateFood()
 
 	// Check collisions
-	collisions := CheckAllCollisions(g.State.Snakes, g.State.Width, g.State.Height)
+	collisions := g.rules.Collisions(g.State)
 
 	// Process deaths
 	for i := 0; i < 2; i++ {
 		if len(collisions[i]) > 0 {
 			g.State.Snakes[i].Kill()
+			g.State.Snakes[i].DiedOn = g.State.Turn
 			result.Died[i] = true
 		}
 	}
+	result.Collisions = collisions
 
 	// Calculate rewards
-	result.Rewards = g.calculateRewards(result.AteFood, result.Died)
+	result.Rewards = g.rules.Rewards(g.Rewards, g.State, result.FoodPoints, collisions)
 
 	// Check game over
-	alive0 := g.State.Snakes[0].Alive
-	alive1 := g.State.Snakes[1].Alive
-
-	if !alive0 && !alive1 {
-		g.State.GameOver = true
-		g.State.Winner = -1 // Tie
-		result.GameOver = true
-		result.Winner = -1
-	} else if !alive0 {
+	if over, winner := g.State.outcome(); over {
 		g.State.GameOver = true
-		g.State.Winner = 1 // Snake 1 wins
+		g.State.Winner = winner
 		result.GameOver = true
-		result.Winner = 1
-	} else if !alive1 {
-		g.State.GameOver = true
-		g.State.Winner = 0 // Snake 0 wins
+		result.Winner = winner
+	} else if g.State.Respawn.Enabled {
+		result.Respawned = g.respawnSnakes()
+	}
+
+	// Snakes circling without eating repeat positions forever
+	if !g.State.GameOver && g.repeatLimit > 0 {
+		hash := g.State.Hash()
+		g.positions[hash]++
+		if g.positions[hash] >= g.repeatLimit {
+			g.State.GameOver = true
+			g.State.Winner = -1
+			g.State.Repeated = true
+			result.GameOver = true
+			result.Repeated = true
+		}
+	}
+	if len(g.listeners) > 0 {
+		g.emitStep(result)
+	}
+
+	// The game is still on after the last turn allowed
+	if !g.State.GameOver && g.maxTurns > 0 && g.State.Turn >= g.maxTurns {
+		g.EndByScore()
 		result.GameOver = true
-		result.Winner = 0
+		result.Winner = g.State.Winner
+		result.TimedOut = true
 	}
 
 	return result
 }
 
-// calculateRewards computes rewards for each snake
-func (g *Game) calculateRewards(ateFood, died [2]bool) [2]float64 {
-	var rewards [2]float64
-
-	for i := 0; i < 2; i++ {
-		otherIdx := 1 - i
+// EndByScore ends a game that ran into a turn or stall limit, awarding it
+// to the snake with the higher score, then to the longer one; snakes equal
+// on both tie
+func (g *Game) EndByScore() {
+	if g.State.GameOver {
+		return
+	}
+	g.State.GameOver = true
+	g.State.TimedOut = true
+	g.State.Winner = g.State.Leader()
+	if len(g.listeners) > 0 {
+		g.emitOutcome()
+	}
+}
 
-		if died[i] {
-			rewards[i] = -1.0 // Death penalty
-		} else {
-			// Survival bonus
-			rewards[i] = 0.01
+// Leader returns the snake ahead on score, then length, or -1 when they
+// are level
+func (s *GameState) Leader() int {
+	s0, s1 := s.Snakes[0], s.Snakes[1]
+	switch {
+	case s0.Score != s1.Score:
+		if s0.Score > s1.Score {
+			return 0
+		}
+		return 1
+	case len(s0.Body) != len(s1.Body):
+		if len(s0.Body) > len(s1.Body) {
+			return 0
+		}
+		return 1
+	}
+	return -1
+}
 
-			// Food reward
-			if ateFood[i] {
-				rewards[i] += 0.5
-			}
+// SetRewards uses the same reward values for both snakes
+func (g *Game) SetRewards(rewards config.RewardConfig) {
+	g.Rewards = [2]config.RewardConfig{rewards, rewards}
+}
 
-			// Win bonus if opponent died
-			if died[otherIdx] {
-				rewards[i] += 1.0
-			}
+// KilledBy reports whether a snake's death was caused by running into the
+// body of snake killerID, as opposed to a wall or its own body
+func KilledBy(collisions []CollisionResult, killerID int) bool {
+	for _, c := range collisions {
+		if c.Type == OtherSnakeCollision && c.SnakeID == killerID {
+			return true
 		}
 	}
-
-	return rewards
+	return false
 }
 
 // GetState returns a copy of the current game state
@@ -229,39 +682,78 @@ func (g *Game) GetState() *GameState {
 	return g.State
 }
 
-// Clone creates a deep copy of the game for simulation
+// Clone creates a deep copy of the game for simulation. The copy's random
+// number generator is seeded from the game's, so cloning draws from it.
 func (g *Game) Clone() *Game {
-	newGame := &Game{
-		State: &GameState{
-			Width:    g.State.Width,
-			Height:   g.State.Height,
-			Turn:     g.State.Turn,
-			GameOver: g.State.GameOver,
-			Winner:   g.State.Winner,
-			Food: Food{
-				Position: g.State.Food.Position,
-				Active:   g.State.Food.Active,
-			},
-		},
-		rng: rand.New(rand.NewSource(g.rng.Int63())),
+	return g.cloneWithSeed(g.rng.Int63())
+}
+
+// cloneWithSeed copies the game with a random number generator seeded from
+// seed
+func (g *Game) cloneWithSeed(seed int64) *Game {
+	return &Game{
+		State:     g.State.Clone(),
+		Rewards:   g.Rewards,
+		rng:       rand.New(rand.NewSource(seed)),
+		foodCount: g.foodCount,
+		maxTurns:  g.maxTurns,
+		spawn:     g.spawn,
+		rules:     g.rules,
+		board:     g.board,
+
+		repeatLimit: g.repeatLimit,
+		positions:   maps.Clone(g.positions),
+		value:       g.value,
 	}
+}
 
-	// Deep copy snakes
-	for i := 0; i < 2; i++ {
-		s := g.State.Snakes[i]
-		body := make([]Position, len(s.Body))
-		copy(body, s.Body)
-		newGame.State.Snakes[i] = &Snake{
-			ID:        s.ID,
-			Body:      body,
-			Direction: s.Direction,
-			Alive:     s.Alive,
-			Score:     s.Score,
-			Grew:      s.Grew,
-		}
+// Clone creates a deep copy of the state
+func (s *GameState) Clone() *GameState {
+	clone := *s
+	for i, snake := range s.Snakes {
+		body := make([]Position, len(snake.Body))
+		copy(body, snake.Body)
+		copied := *snake
+		copied.Body = body
+		clone.Snakes[i] = &copied
+	}
+	if s.ExtraFood != nil {
+		clone.ExtraFood = append([]Position(nil), s.ExtraFood...)
 	}
+	if s.Golden != nil {
+		clone.Golden = append([]Position(nil), s.Golden...)
+	}
+	clone.FoodExpires = maps.Clone(s.FoodExpires)
+	return &clone
+}
 
-	return newGame
+// NewGameFromState creates a game that continues from state, for search
+// and analysis. The game takes ownership of state; pass a clone to keep the
+// original unchanged. It plays by the settings of the game state came
+// from: its turn and repeat limits, food settings and map. The repeat
+// limit counts positions from state on, as the earlier ones aren't known.
+// A state built by hand gets default settings and keeps as many pellets as
+// it has.
+func NewGameFromState(state *GameState, seed int64) *Game {
+	g := &Game{
+		State:     state,
+		Rewards:   [2]config.RewardConfig{config.DefaultRewardConfig(), config.DefaultRewardConfig()},
+		rng:       rand.New(rand.NewSource(seed)),
+		foodCount: 1 + len(state.ExtraFood),
+		rules:     state.rules(),
+	}
+	if setup := state.setup; setup != nil {
+		g.foodCount = setup.foodCount
+		g.maxTurns = setup.maxTurns
+		g.repeatLimit = setup.repeatLimit
+		g.board = setup.board
+		g.spawn = setup.spawn
+		g.value = setup.value
+	}
+	if g.repeatLimit > 0 {
+		g.positions = map[uint64]int{state.Hash(): 1}
+	}
+	return g
 }
 
 // IsValidAction checks if an action is valid for a snake (not a 180-degree turn)
@@ -1,6 +1,8 @@
 package game
 
 import (
+	"math/rand"
+	"slices"
 	"testing"
 
 	"autonomous-snake/internal/config"
@@ -149,6 +151,25 @@ func TestNewGame(t *testing.T) {
 	}
 }
 
+func TestRectangularBoards(t *testing.T) {
+	for _, size := range [][2]int{{24, 9}, {9, 24}, {5, 10}} {
+		cfg := config.GameConfig{BoardWidth: size[0], BoardHeight: size[1], GridSize: 20}
+		g := NewGame(cfg, 1)
+		if err := g.State.Validate(); err != nil {
+			t.Errorf("%dx%d: %v", size[0], size[1], err)
+		}
+
+		// The snakes start along the longer side, facing each other
+		s0, s1 := g.State.Snakes[0], g.State.Snakes[1]
+		if tall := size[1] > size[0]; tall != (s0.Direction == Down && s1.Direction == Up) {
+			t.Errorf("%dx%d: snakes face %v and %v", size[0], size[1], s0.Direction, s1.Direction)
+		}
+		if result := g.Step([2]Direction{s0.Direction, s1.Direction}); result.Died[0] || result.Died[1] {
+			t.Errorf("%dx%d: a snake died on the first move", size[0], size[1])
+		}
+	}
+}
+
 func TestGameStep(t *testing.T) {
 	cfg := config.GameConfig{
 		BoardWidth:  20,
@@ -213,6 +234,40 @@ func TestManhattanDistance(t *testing.T) {
 	}
 }
 
+func TestTailSafety(t *testing.T) {
+	// Snake 0 curls into a 2x2 square with its head next to its tail
+	curl := func(growing int) *Game {
+		g := NewGame(config.DefaultGameConfig(), 1)
+		g.State.Food = Food{Position: Position{X: 10, Y: 0}, Active: true}
+		s := g.State.Snakes[0]
+		s.Body = []Position{{X: 3, Y: 10}, {X: 3, Y: 11}, {X: 4, Y: 11}, {X: 4, Y: 10}}
+		s.Direction = Up
+		s.Growing = growing
+		return g
+	}
+	tail := Position{X: 4, Y: 10}
+
+	g := curl(0)
+	if IsDangerPosition(tail, 0, g.State.Snakes, g.State.Width, g.State.Height) {
+		t.Error("own tail that moves on is a danger")
+	}
+	if !IsDangerPosition(tail, 1, g.State.Snakes, g.State.Width, g.State.Height) {
+		t.Error("other snake's tail is not a danger")
+	}
+	if result := g.Step([2]Direction{Right, Left}); result.Died[0] {
+		t.Errorf("snake 0 died moving into its own tail: %v", result.Collisions[0])
+	}
+
+	// A growing snake keeps its tail
+	g = curl(1)
+	if !IsDangerPosition(tail, 0, g.State.Snakes, g.State.Width, g.State.Height) {
+		t.Error("growing snake's tail is not a danger")
+	}
+	if result := g.Step([2]Direction{Right, Left}); !result.Died[0] || result.DeathCause(0) != SelfCollision {
+		t.Errorf("died %v, want snake 0 to hit its own kept tail", result.Died)
+	}
+}
+
 func TestHeadToHeadCollision(t *testing.T) {
 	snake1 := NewSnake(0, Position{X: 5, Y: 5}, Right, 3)
 	snake2 := NewSnake(1, Position{X: 7, Y: 5}, Left, 3)
@@ -232,3 +287,339 @@ func TestHeadToHeadCollision(t *testing.T) {
 			snake1.Head(), snake2.Head())
 	}
 }
+
+func TestKillAttributionRewards(t *testing.T) {
+	cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20}
+	g := NewGame(cfg, 42)
+	g.Rewards[0].Kill = 0.5
+	g.Rewards[0].OpponentBlunder = -0.25
+
+	// Snake 1's head runs into snake 0's body
+	g.State.Snakes[0] = NewSnake(0, Position{X: 5, Y: 5}, Right, 3)
+	g.State.Snakes[1] = NewSnake(1, Position{X: 4, Y: 4}, Down, 3)
+	g.State.Food.Active = false
+
+	result := g.Step([2]Direction{Right, Down})
+
+	if !result.Died[1] || result.Died[0] {
+		t.Fatalf("expected only snake 1 to die, got %v", result.Died)
+	}
+	if !KilledBy(result.Collisions[1], 0) {
+		t.Errorf("expected snake 1's death to be attributed to snake 0, got %+v", result.Collisions[1])
+	}
+	want := g.Rewards[0].Survival + g.Rewards[0].Win + g.Rewards[0].Kill
+	if result.Rewards[0] != want {
+		t.Errorf("expected kill reward %v, got %v", want, result.Rewards[0])
+	}
+
+	// Snake 1 drives into the wall on its own
+	g.Reset()
+	g.State.Snakes[1] = NewSnake(1, Position{X: 9, Y: 5}, Right, 3)
+	g.State.Food.Active = false
+	result = g.Step([2]Direction{Right, Right})
+
+	if KilledBy(result.Collisions[1], 0) {
+		t.Error("expected wall death not to be attributed to snake 0")
+	}
+	want = g.Rewards[0].Survival + g.Rewards[0].Win + g.Rewards[0].OpponentBlunder
+	if result.Rewards[0] != want {
+		t.Errorf("expected blunder reward %v, got %v", want, result.Rewards[0])
+	}
+}
+
+func TestGameStateValidate(t *testing.T) {
+	cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20}
+	g := NewGame(cfg, 42)
+
+	if err := g.State.Validate(); err != nil {
+		t.Fatalf("expected new game to be valid, got %v", err)
+	}
+
+	// Break the body apart
+	g.State.Snakes[0].Body[1] = Position{X: 0, Y: 0}
+	if err := g.State.Validate(); err == nil {
+		t.Error("expected error for non-contiguous body")
+	}
+
+	// Put the food on a snake
+	g.Reset()
+	g.State.Food = Food{Position: g.State.Snakes[1].Body[2], Active: true}
+	if err := g.State.Validate(); err == nil {
+		t.Error("expected error for food on a snake")
+	}
+
+	// Dead snake without the game being over
+	g.Reset()
+	g.State.Snakes[1].Kill()
+	if err := g.State.Validate(); err == nil {
+		t.Error("expected error for dead snake in a running game")
+	}
+}
+
+func TestMultipleFood(t *testing.T) {
+	cfg := config.GameConfig{BoardWidth: 10, BoardHeight: 10, GridSize: 20, FoodCount: 3}
+	g := NewGame(cfg, 42)
+
+	if got := len(g.State.FoodPositions()); got != 3 {
+		t.Fatalf("expected 3 pellets, got %d", got)
+	}
+	if err := g.State.Validate(); err != nil {
+		t.Fatalf("expected valid state, got %v", err)
+	}
+
+	// Put an extra pellet in front of snake 0 and eat it
+	head := g.State.Snakes[0].Head()
+	target := Position{X: head.X + 1, Y: head.Y}
+	g.State.ExtraFood[0] = target
+	result := g.Step([2]Direction{Right, Left})
+
+	if !result.AteFood[0] {
+		t.Fatal("expected snake 0 to eat the extra pellet")
+	}
+	if got := len(g.State.FoodPositions()); got != 3 {
+		t.Errorf("expected the eaten pellet to be replaced, got %d pellets", got)
+	}
+	if g.State.HasFoodAt(target) {
+		t.Error("expected the eaten pellet to be gone")
+	}
+	if nearest, ok := g.State.NearestFood(g.State.Snakes[0].Head()); !ok || nearest == target {
+		t.Errorf("unexpected nearest food %v, %v", nearest, ok)
+	}
+}
+
+func TestFoodSpawnPatterns(t *testing.T) {
+	// A cluster surrounds the first pellet and is only replaced once empty
+	cfg := config.GameConfig{BoardWidth: 20, BoardHeight: 20, GridSize: 20, FoodCount: 4}
+	cfg.Spawn = config.FoodSpawnConfig{Pattern: config.FoodPatternCluster, ClusterRadius: 2}
+	g := NewGame(cfg, 42)
+
+	center := g.State.Food.Position
+	for _, pos := range g.State.ExtraFood {
+		if d := ManhattanDistance(center, pos); d > 2 {
+			t.Errorf("cluster pellet %v is %d cells from %v", pos, d, center)
+		}
+	}
+	g.State.removeFoodAt(center)
+	g.spawnFood()
+	if got := len(g.State.FoodPositions()); got != 3 {
+		t.Errorf("expected no respawn while the cluster has food, got %d pellets", got)
+	}
+
+	// Uneaten pellets expire after their lifetime and are replaced
+	cfg = config.GameConfig{BoardWidth: 20, BoardHeight: 20, GridSize: 20}
+	cfg.Spawn.Lifetime = 3
+	g = NewGame(cfg, 42)
+	g.State.Food.Position = Position{X: 10, Y: 0}
+	g.State.FoodExpires = map[Position]int{g.State.Food.Position: 3}
+	for i := 0; i < 2; i++ {
+		g.Step([2]Direction{Right, Left})
+		if !g.State.HasFoodAt(Position{X: 10, Y: 0}) {
+			t.Fatalf("pellet expired early on turn %d", g.State.Turn)
+		}
+	}
+	g.Step([2]Direction{Right, Left})
+	if g.State.HasFoodAt(Position{X: 10, Y: 0}) {
+		t.Error("expected the pellet to expire after 3 turns")
+	}
+	if !g.State.Food.Active {
+		t.Error("expected the expired pellet to be replaced")
+	}
+}
+
+func TestEndByScore(t *testing.T) {
+	g := NewGame(config.DefaultGameConfig(), 1)
+	g.State.Snakes[1].Score = 2
+	g.EndByScore()
+	if !g.State.GameOver || g.State.Winner != 1 {
+		t.Errorf("expected snake 1 to win on score, got over=%v winner=%d", g.State.GameOver, g.State.Winner)
+	}
+
+	g.Reset()
+	g.EndByScore()
+	if g.State.Winner != -1 {
+		t.Errorf("expected a tie on equal scores, got winner %d", g.State.Winner)
+	}
+}
+
+func TestTurnLimit(t *testing.T) {
+	cfg := config.DefaultGameConfig()
+	cfg.MaxTurns = 3
+	g := NewGame(cfg, 1)
+
+	// Equal scores go to the longer snake
+	g.State.Snakes[0].Move(Right, true)
+	for turn := 1; turn <= cfg.MaxTurns; turn++ {
+		result := g.Step([2]Direction{Up, Up})
+		if turn < cfg.MaxTurns {
+			if result.GameOver || result.TimedOut {
+				t.Fatalf("game ended on turn %d", turn)
+			}
+			continue
+		}
+		if !result.GameOver || !result.TimedOut || result.Winner != 0 {
+			t.Errorf("turn limit: over=%v timed out=%v winner=%d, want snake 0 to win on length", result.GameOver, result.TimedOut, result.Winner)
+		}
+	}
+	if !g.State.TimedOut || g.State.Winner != 0 {
+		t.Errorf("state: timed out=%v winner=%d", g.State.TimedOut, g.State.Winner)
+	}
+	if err := g.State.Validate(); err != nil {
+		t.Errorf("timed out game is invalid: %v", err)
+	}
+
+	// A higher score beats length, and the limit starts over with a new game
+	g.Reset()
+	if g.State.TimedOut {
+		t.Error("Reset kept TimedOut")
+	}
+	g.State.Snakes[0].Move(Right, true)
+	g.State.Snakes[1].Score = 1
+	for !g.State.GameOver {
+		g.Step([2]Direction{Up, Up})
+	}
+	if g.State.Turn != cfg.MaxTurns || g.State.Winner != 1 {
+		t.Errorf("game ended on turn %d with winner %d, want turn %d and snake 1", g.State.Turn, g.State.Winner, cfg.MaxTurns)
+	}
+}
+
+func TestRepeatLimit(t *testing.T) {
+	cfg := config.DefaultGameConfig()
+	cfg.RepeatLimit = 3
+	g := NewGame(cfg, 1)
+	g.State.Food = Food{Position: Position{X: 10, Y: 0}, Active: true}
+
+	// Both snakes circle a 2x2 square with their tails in it after one
+	// move, so turns 1, 5 and 9 reach the same position
+	circle := [][2]Direction{{Up, Up}, {Left, Right}, {Down, Down}, {Right, Left}}
+	var result StepResult
+	for turn := 1; turn <= 9; turn++ {
+		result = g.Step(circle[(turn-1)%len(circle)])
+		if turn < 9 && result.GameOver {
+			t.Fatalf("game ended on turn %d", turn)
+		}
+	}
+	if !result.GameOver || !result.Repeated || result.Winner != -1 || result.TimedOut {
+		t.Errorf("over=%v repeated=%v winner=%d timed out=%v, want a repeated draw", result.GameOver, result.Repeated, result.Winner, result.TimedOut)
+	}
+	if !g.State.Repeated || g.State.Winner != -1 {
+		t.Errorf("state: repeated=%v winner=%d", g.State.Repeated, g.State.Winner)
+	}
+
+	g.Reset()
+	if g.State.Repeated {
+		t.Error("Reset kept Repeated")
+	}
+}
+
+func TestGoldenFood(t *testing.T) {
+	cfg := config.DefaultGameConfig()
+	cfg.Value = config.FoodValueConfig{GoldenChance: 1, GoldenPoints: 3, GoldenGrowth: 2}
+	g := NewGame(cfg, 1)
+	if !g.State.IsGolden(g.State.Food.Position) {
+		t.Fatal("pellet is not golden with a golden chance of 1")
+	}
+
+	// Put the golden pellet in front of snake 0
+	g.clearFood()
+	g.State.Food = Food{Position: Position{X: 4, Y: 10}, Active: true}
+	g.State.Golden = []Position{g.State.Food.Position}
+	result := g.Step([2]Direction{Right, Left})
+	if result.FoodPoints[0] != 3 || g.State.Snakes[0].Score != 3 {
+		t.Errorf("ate %d points for a score of %d, want 3", result.FoodPoints[0], g.State.Snakes[0].Score)
+	}
+	if want := g.Rewards[0].Survival + 3*g.Rewards[0].Food; result.Rewards[0] != want {
+		t.Errorf("reward %v, want %v", result.Rewards[0], want)
+	}
+	if g.State.IsGolden(Position{X: 4, Y: 10}) {
+		t.Error("eaten pellet is still golden")
+	}
+
+	// It grows a segment this turn and the next, then stops
+	for turn, want := range []int{4, 5, 5} {
+		if turn > 0 {
+			g.Step([2]Direction{Right, Left})
+		}
+		if n := g.State.Snakes[0].Length(); n != want {
+			t.Errorf("length %d after %d turns, want %d", n, turn+1, want)
+		}
+	}
+	if err := g.State.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFoodTimeLeft(t *testing.T) {
+	cfg := config.DefaultGameConfig()
+	cfg.Spawn = config.FoodSpawnConfig{Lifetime: 6, RelocateEvery: 4}
+	g := NewGame(cfg, 1)
+	food := g.State.Food.Position
+	if g.State.FoodLifespan != 4 || g.State.FoodExpires[food] != 4 {
+		t.Fatalf("lifespan %d, expires %d, want both 4", g.State.FoodLifespan, g.State.FoodExpires[food])
+	}
+	g.Step([2]Direction{Up, Down})
+	if left := g.State.FoodTimeLeft(food); left != 0.75 {
+		t.Errorf("time left %v after a turn, want 0.75", left)
+	}
+
+	// Food that stays put always has all its time
+	g = NewGame(config.DefaultGameConfig(), 1)
+	if left := g.State.FoodTimeLeft(g.State.Food.Position); left != 1 {
+		t.Errorf("time left %v without expiry, want 1", left)
+	}
+}
+
+func TestNewGameFromState(t *testing.T) {
+	cfg := config.DefaultGameConfig()
+	cfg.Map = "pillars"
+	cfg.FoodCount = 3
+	cfg.MaxTurns = 30
+	cfg.RepeatLimit = 4
+	cfg.Spawn = config.FoodSpawnConfig{Lifetime: 6}
+	cfg.Value = config.FoodValueConfig{GoldenChance: 0.5, GoldenPoints: 4}
+
+	// Every turn, a game made from the state steps like the real one, but
+	// for where new food appears. The snakes wander at random, avoiding
+	// walls and bodies when they can, so most games reach the turn limit.
+	rng := rand.New(rand.NewSource(1))
+	wander := func(s *GameState, id int) Direction {
+		snake := s.Snakes[id]
+		var safe []Direction
+		for _, dir := range []Direction{Up, Down, Left, Right} {
+			if dir != snake.Direction.Opposite() && s.Passable(snake.Head().Neighbor(dir)) {
+				safe = append(safe, dir)
+			}
+		}
+		if len(safe) == 0 {
+			return snake.Direction
+		}
+		return safe[rng.Intn(len(safe))]
+	}
+	timedOut := 0
+	for seed := int64(1); seed <= 5; seed++ {
+		g := NewGame(cfg, seed)
+		for !g.State.GameOver {
+			sim := NewGameFromState(g.State.Clone(), seed)
+			moves := [2]Direction{wander(g.State, 0), wander(g.State, 1)}
+			want := g.Step(moves)
+			got := sim.Step(moves)
+			if got.GameOver != want.GameOver || got.Winner != want.Winner || got.TimedOut != want.TimedOut ||
+				got.Died != want.Died || got.FoodPoints != want.FoodPoints || got.Rewards != want.Rewards {
+				t.Fatalf("seed %d, turn %d: simulated step %+v, real step %+v", seed, g.State.Turn, got, want)
+			}
+			for i, snake := range g.State.Snakes {
+				if !slices.Equal(sim.State.Snakes[i].Body, snake.Body) || sim.State.Snakes[i].Score != snake.Score {
+					t.Fatalf("seed %d, turn %d: simulated snake %d %+v, real %+v", seed, g.State.Turn, i, sim.State.Snakes[i], snake)
+				}
+			}
+			if len(sim.State.FoodPositions()) != len(g.State.FoodPositions()) {
+				t.Fatalf("seed %d, turn %d: %d simulated pellets, %d real", seed, g.State.Turn, len(sim.State.FoodPositions()), len(g.State.FoodPositions()))
+			}
+		}
+		if g.State.TimedOut {
+			timedOut++
+		}
+	}
+	if timedOut == 0 {
+		t.Error("no game reached the turn limit")
+	}
+}
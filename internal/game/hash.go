@@ -0,0 +1,57 @@
+package game
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Hash returns a 64-bit FNV-1a hash of the board position: the board size,
+// food, each snake's status, direction, body and growth still due, and any
+// obstacles and golden pellets. The turn number and scores are left out so
+// the same position reached by different move orders hashes the same.
+func (s *GameState) Hash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	write := func(v int) {
+		binary.LittleEndian.PutUint64(buf[:], uint64(int64(v)))
+		h.Write(buf[:])
+	}
+
+	write(s.Width)
+	write(s.Height)
+	if s.Food.Active {
+		write(s.Food.Position.X)
+		write(s.Food.Position.Y)
+	} else {
+		write(-1)
+	}
+	for _, pos := range s.ExtraFood {
+		write(pos.X)
+		write(pos.Y)
+	}
+	for _, snake := range s.Snakes {
+		alive := 0
+		if snake.Alive {
+			alive = 1
+		}
+		write(alive)
+		write(int(snake.Direction))
+		write(len(snake.Body))
+		for _, pos := range snake.Body {
+			write(pos.X)
+			write(pos.Y)
+		}
+		if snake.Growing > 0 {
+			write(-snake.Growing)
+		}
+	}
+	for _, pos := range s.Obstacles {
+		write(pos.X)
+		write(pos.Y)
+	}
+	for _, pos := range s.Golden {
+		write(pos.X)
+		write(pos.Y)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,196 @@
+package game
+
+import "container/heap"
+
+// Passable reports whether a snake could move onto pos: it is on the
+// board, off the map's obstacles and off every living snake's body. Bodies
+// stay blocked even where a tail moves on next turn.
+func (s *GameState) Passable(pos Position) bool {
+	if CheckWallCollision(pos, s.Width, s.Height) || s.IsObstacle(pos) {
+		return false
+	}
+	for _, snake := range s.Snakes {
+		if snake.Alive && snake.ContainsPosition(pos, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedCells returns which cells are not Passable, indexed y*Width+x
+func (s *GameState) blockedCells() []bool {
+	blocked := make([]bool, s.Width*s.Height)
+	for _, snake := range s.Snakes {
+		if !snake.Alive {
+			continue
+		}
+		for _, pos := range snake.Body {
+			if !CheckWallCollision(pos, s.Width, s.Height) {
+				blocked[pos.Y*s.Width+pos.X] = true
+			}
+		}
+	}
+	for _, pos := range s.Obstacles {
+		blocked[pos.Y*s.Width+pos.X] = true
+	}
+	return blocked
+}
+
+// neighbors returns the cells one move from pos under the state's rules,
+// leaving out moves off the board
+func (s *GameState) neighbors(pos Position) []Position {
+	rules := s.rules()
+	next := make([]Position, 0, 4)
+	for _, dir := range []Direction{Up, Down, Left, Right} {
+		if n := rules.Move(s, pos, dir); !CheckWallCollision(n, s.Width, s.Height) {
+			next = append(next, n)
+		}
+	}
+	return next
+}
+
+// ReachableArea counts the Passable cells reachable from start, start
+// itself left out. Start may be blocked, like a snake's head.
+func (s *GameState) ReachableArea(start Position) int {
+	blocked := s.blockedCells()
+	if !CheckWallCollision(start, s.Width, s.Height) {
+		blocked[start.Y*s.Width+start.X] = true
+	}
+
+	count := 0
+	stack := []Position{start}
+	for len(stack) > 0 {
+		pos := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, next := range s.neighbors(pos) {
+			idx := next.Y*s.Width + next.X
+			if blocked[idx] {
+				continue
+			}
+			blocked[idx] = true
+			count++
+			stack = append(stack, next)
+		}
+	}
+	return count
+}
+
+// Distances returns the fewest moves from start to every cell over
+// Passable cells, indexed y*Width+x, with -1 for cells out of reach and
+// for blocked cells other than start
+func (s *GameState) Distances(start Position) []int {
+	dist := make([]int, s.Width*s.Height)
+	for i := range dist {
+		dist[i] = -1
+	}
+	if CheckWallCollision(start, s.Width, s.Height) {
+		return dist
+	}
+	blocked := s.blockedCells()
+	dist[start.Y*s.Width+start.X] = 0
+
+	queue := []Position{start}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		d := dist[pos.Y*s.Width+pos.X]
+		for _, next := range s.neighbors(pos) {
+			idx := next.Y*s.Width + next.X
+			if blocked[idx] || dist[idx] >= 0 {
+				continue
+			}
+			dist[idx] = d + 1
+			queue = append(queue, next)
+		}
+	}
+	return dist
+}
+
+// ShortestPath returns a shortest route from from to to over Passable
+// cells, found by A*: the cells entered in order, ending with to. It
+// returns false when to is blocked or out of reach. From may be blocked,
+// like a snake's head.
+func (s *GameState) ShortestPath(from, to Position) ([]Position, bool) {
+	if CheckWallCollision(from, s.Width, s.Height) || !s.Passable(to) {
+		return nil, false
+	}
+	if from == to {
+		return []Position{}, true
+	}
+	blocked := s.blockedCells()
+	index := func(p Position) int { return p.Y*s.Width + p.X }
+
+	cost := make([]int, s.Width*s.Height)
+	for i := range cost {
+		cost[i] = -1
+	}
+	prev := make([]Position, s.Width*s.Height)
+	cost[index(from)] = 0
+	open := &pathQueue{{pos: from, estimate: s.pathEstimate(from, to)}}
+	for open.Len() > 0 {
+		node := heap.Pop(open).(pathNode)
+		if node.pos == to {
+			break
+		}
+		if node.estimate-s.pathEstimate(node.pos, to) > cost[index(node.pos)] {
+			continue // A cheaper route to this cell was queued later
+		}
+		for _, next := range s.neighbors(node.pos) {
+			idx := index(next)
+			c := cost[index(node.pos)] + 1
+			if blocked[idx] || cost[idx] >= 0 && cost[idx] <= c {
+				continue
+			}
+			cost[idx] = c
+			prev[idx] = node.pos
+			heap.Push(open, pathNode{pos: next, estimate: c + s.pathEstimate(next, to)})
+		}
+	}
+	if cost[index(to)] < 0 {
+		return nil, false
+	}
+
+	path := make([]Position, cost[index(to)])
+	for pos, i := to, len(path)-1; i >= 0; pos, i = prev[index(pos)], i-1 {
+		path[i] = pos
+	}
+	return path, true
+}
+
+// pathEstimate is A*'s lower bound on the moves from a to b: the Manhattan
+// distance, measured around the edges on a wrapped board
+func (s *GameState) pathEstimate(a, b Position) int {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if s.Rules == RulesWrapped {
+		dx, dy = min(dx, s.Width-dx), min(dy, s.Height-dy)
+	}
+	return dx + dy
+}
+
+// abs returns the absolute value of x
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// pathNode is a cell on A*'s open list with its estimated route length
+type pathNode struct {
+	pos      Position
+	estimate int
+}
+
+// pathQueue is a min-heap of pathNodes by estimate
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].estimate < q[j].estimate }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
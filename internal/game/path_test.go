@@ -0,0 +1,71 @@
+package game
+
+import "testing"
+
+// pathState returns a 7x5 board with a wall down column 3 open only on the
+// top row, snake 0 at (1,3) with its tail above and snake 1 dead
+func pathState() *GameState {
+	s := &GameState{Width: 7, Height: 5}
+	for y := 1; y < 5; y++ {
+		s.Obstacles = append(s.Obstacles, Position{X: 3, Y: y})
+	}
+	s.Snakes[0] = NewSnake(0, Position{X: 1, Y: 3}, Down, 2)
+	s.Snakes[1] = NewSnake(1, Position{X: 6, Y: 4}, Left, 1)
+	s.Snakes[1].Kill()
+	return s
+}
+
+func TestShortestPath(t *testing.T) {
+	s := pathState()
+	from, to := Position{X: 1, Y: 3}, Position{X: 4, Y: 3}
+	path, ok := s.ShortestPath(from, to)
+	if !ok {
+		t.Fatal("no path around the wall")
+	}
+	if len(path) != 9 || path[len(path)-1] != to {
+		t.Fatalf("path %v, want 9 moves ending at %v", path, to)
+	}
+	for i, pos := range path {
+		prev := from
+		if i > 0 {
+			prev = path[i-1]
+		}
+		if ManhattanDistance(prev, pos) != 1 || !s.Passable(pos) {
+			t.Errorf("step %d to %v is not a move onto a free cell", i, pos)
+		}
+	}
+
+	if _, ok := s.ShortestPath(from, Position{X: 3, Y: 2}); ok {
+		t.Error("found a path onto the wall")
+	}
+	if _, ok := s.ShortestPath(from, Position{X: 1, Y: 2}); ok {
+		t.Error("found a path onto the snake's own body")
+	}
+
+	// Wrapped boards join the edges, so going out the left edge is shorter
+	s.Rules = RulesWrapped
+	if path, ok := s.ShortestPath(from, to); !ok || len(path) != 4 {
+		t.Errorf("wrapped path %v, want 4 moves", path)
+	}
+}
+
+func TestDistancesAndReachableArea(t *testing.T) {
+	s := pathState()
+	from := Position{X: 1, Y: 3}
+	dist := s.Distances(from)
+	at := func(x, y int) int { return dist[y*s.Width+x] }
+	if at(1, 3) != 0 || at(3, 0) != 5 || at(4, 3) != 9 || at(3, 2) != -1 || at(1, 2) != -1 {
+		t.Errorf("distances %d %d %d %d %d, want 0 5 9 -1 -1", at(1, 3), at(3, 0), at(4, 3), at(3, 2), at(1, 2))
+	}
+
+	// Everything but the wall and the snake
+	if area := s.ReachableArea(from); area != 7*5-4-2 {
+		t.Errorf("reachable area %d, want %d", area, 7*5-4-2)
+	}
+
+	// Closing the gap cuts the board in two
+	s.Obstacles = append(s.Obstacles, Position{X: 3, Y: 0})
+	if area := s.ReachableArea(from); area != 3*5-2 {
+		t.Errorf("reachable area %d with the gap closed, want %d", area, 3*5-2)
+	}
+}
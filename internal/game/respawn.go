@@ -0,0 +1,76 @@
+package game
+
+// respawnSnakes brings back the snakes that have sat out the respawn delay
+// and reports which came back. A snake that finds no safe spot stays out
+// and tries again next turn.
+func (g *Game) respawnSnakes() [2]bool {
+	var respawned [2]bool
+	for i, snake := range g.State.Snakes {
+		if snake.Alive || g.State.Turn-snake.DiedOn < g.State.Respawn.Delay {
+			continue
+		}
+		spawn, ok := g.State.safeSpawn(i)
+		if !ok {
+			continue
+		}
+		fresh := NewSnake(i, spawn.Head, spawn.Direction, spawnLength)
+		fresh.Score = snake.Score
+		g.State.Snakes[i] = fresh
+		respawned[i] = true
+	}
+	return respawned
+}
+
+// safeSpawn finds where snake id can come back: a head and direction whose
+// starting body and the cell ahead are all free of walls, bodies and food,
+// as far as possible from the other snake's head, or from the board's
+// center when the other snake is dead too. Ties go to the first spot in
+// reading order.
+func (s *GameState) safeSpawn(id int) (Spawn, bool) {
+	away := Position{X: s.Width / 2, Y: s.Height / 2}
+	if other := s.Snakes[1-id]; other.Alive {
+		away = other.Head()
+	}
+	free := func(pos Position) bool {
+		return s.Passable(pos) && !s.HasFoodAt(pos)
+	}
+
+	best, bestDist, found := Spawn{}, -1, false
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			head := Position{X: x, Y: y}
+			if !free(head) || ManhattanDistance(head, away) <= bestDist {
+				continue
+			}
+			for _, dir := range []Direction{Up, Down, Left, Right} {
+				if !free(s.rules().Move(s, head, dir)) || !allFree(NewSnake(id, head, dir, spawnLength).Body, free) {
+					continue
+				}
+				best, bestDist, found = Spawn{Head: head, Direction: dir}, ManhattanDistance(head, away), true
+				break
+			}
+		}
+	}
+	return best, found
+}
+
+// allFree reports whether free holds for every position
+func allFree(positions []Position, free func(Position) bool) bool {
+	for _, pos := range positions {
+		if !free(pos) {
+			return false
+		}
+	}
+	return true
+}
+
+// RespawnIn returns the turns until dead snake id may respawn, 0 once it is
+// due, or -1 when it will not come back: it is alive or the game is not an
+// endless match
+func (s *GameState) RespawnIn(id int) int {
+	snake := s.Snakes[id]
+	if snake.Alive || !s.Respawn.Enabled || s.GameOver {
+		return -1
+	}
+	return max(0, s.Respawn.Delay-(s.Turn-snake.DiedOn))
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestRespawn(t *testing.T) {
+	cfg := config.DefaultGameConfig()
+	cfg.Respawn = config.RespawnConfig{Enabled: true, Delay: 2, ScoreTarget: 2}
+	g := NewGame(cfg, 1)
+	g.State.Food = Food{Position: Position{X: 10, Y: 0}, Active: true}
+	g.State.Snakes[0].Score = 1
+
+	// Snake 0 runs into the top wall on turn 11 while snake 1 circles
+	circle := []Direction{Up, Right, Down, Left}
+	var result StepResult
+	for turn := 1; turn <= 11; turn++ {
+		result = g.Step([2]Direction{Up, circle[(turn-1)%len(circle)]})
+	}
+	if !result.Died[0] || result.GameOver {
+		t.Fatalf("died %v over %v, want snake 0 dead and the game going on", result.Died, result.GameOver)
+	}
+	if n := g.State.RespawnIn(0); n != 2 {
+		t.Errorf("RespawnIn = %d right after dying, want 2", n)
+	}
+	if err := g.State.Validate(); err != nil {
+		t.Errorf("state with a dead snake: %v", err)
+	}
+
+	result = g.Step([2]Direction{Up, circle[11%len(circle)]})
+	if result.Respawned[0] || g.State.Snakes[0].Alive {
+		t.Fatal("snake 0 respawned before its delay")
+	}
+	result = g.Step([2]Direction{Up, circle[12%len(circle)]})
+	if !result.Respawned[0] || !g.State.Snakes[0].Alive {
+		t.Fatal("snake 0 did not respawn after its delay")
+	}
+	snake := g.State.Snakes[0]
+	if snake.Score != 1 || snake.Length() != spawnLength {
+		t.Errorf("respawned with score %d length %d, want score 1 length %d", snake.Score, snake.Length(), spawnLength)
+	}
+	if err := g.State.Validate(); err != nil {
+		t.Errorf("state after respawning: %v", err)
+	}
+
+	// Reaching the score target ends the match
+	g.State.Snakes[1].Score = 2
+	result = g.Step([2]Direction{snake.Direction, circle[13%len(circle)]})
+	if !result.GameOver || result.Winner != 1 {
+		t.Errorf("over %v winner %d, want snake 1 to win on the score target", result.GameOver, result.Winner)
+	}
+}
@@ -0,0 +1,249 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"autonomous-snake/internal/config"
+)
+
+// Rules decides how a turn plays out: where a moving head lands, which
+// collisions kill a snake, what each snake earns and when the game is
+// decided. Game.Step runs every turn in the same order under any ruleset,
+// so a variant only needs a Rules implementation and an entry in rulesets.
+type Rules interface {
+	// Name returns the name LookupRules knows the ruleset by
+	Name() string
+
+	// Setup adjusts a game that was just reset, before the first turn
+	Setup(state *GameState)
+
+	// Move returns where a head at pos lands moving in dir
+	Move(state *GameState, pos Position, dir Direction) Position
+
+	// Collisions returns why each living snake dies once both have moved
+	Collisions(state *GameState) [2][]CollisionResult
+
+	// Rewards returns each snake's reward for the turn given the points of
+	// the pellet it ate, 0 if none, and the collisions that killed snakes
+	// this turn
+	Rewards(cfg [2]config.RewardConfig, state *GameState, foodPoints [2]int, collisions [2][]CollisionResult) [2]float64
+
+	// Outcome reports whether the snakes that are dead end the game, and
+	// who won it then
+	Outcome(state *GameState) (over bool, winner int)
+}
+
+// Ruleset names
+const (
+	RulesStandard = "standard" // Walls kill, the last snake alive wins
+	RulesWrapped  = "wrapped"  // Heads leaving the board come back on the opposite edge
+	RulesRoyale   = "royale"   // The board's edges close in over time
+	RulesSolo     = "solo"     // Snake 0 plays alone until it dies
+)
+
+// DefaultShrinkEvery is how many turns the royale board takes to shrink by
+// a cell on each side
+const DefaultShrinkEvery = 25
+
+var rulesets = map[string]Rules{
+	RulesStandard: Standard{},
+	RulesWrapped:  Wrapped{},
+	RulesRoyale:   Royale{ShrinkEvery: DefaultShrinkEvery},
+	RulesSolo:     Solo{},
+}
+
+// LookupRules returns the ruleset with the given name. An empty name
+// selects the standard rules.
+func LookupRules(name string) (Rules, error) {
+	if name == "" {
+		return Standard{}, nil
+	}
+	rules, ok := rulesets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ruleset %q (want %s)", name, strings.Join(RulesNames(), ", "))
+	}
+	return rules, nil
+}
+
+// RulesNames returns the names of all rulesets, sorted
+func RulesNames() []string {
+	names := make([]string, 0, len(rulesets))
+	for name := range rulesets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// mustLookupRules is LookupRules for names that were checked already
+func mustLookupRules(name string) Rules {
+	rules, err := LookupRules(name)
+	if err != nil {
+		panic("game: " + err.Error())
+	}
+	return rules
+}
+
+// Standard is the classic two-snake game: the board's edges are walls, a
+// snake dies running into a wall, itself or the other snake, and the last
+// snake alive wins
+type Standard struct{}
+
+// Name returns RulesStandard
+func (Standard) Name() string { return RulesStandard }
+
+// Setup leaves the game as Reset placed it
+func (Standard) Setup(state *GameState) {}
+
+// Move returns the neighboring cell, which may be off the board
+func (Standard) Move(state *GameState, pos Position, dir Direction) Position {
+	return pos.Neighbor(dir)
+}
+
+// Collisions returns wall, self, head-to-head and other-snake collisions.
+// Running into a map obstacle is a wall collision.
+func (Standard) Collisions(state *GameState) [2][]CollisionResult {
+	collisions := CheckAllCollisions(state.Snakes, state.Width, state.Height)
+	for i, snake := range state.Snakes {
+		if snake.Alive && state.IsObstacle(snake.Head()) {
+			collisions[i] = append(collisions[i], CollisionResult{Type: WallCollision, Position: snake.Head()})
+		}
+	}
+	return collisions
+}
+
+// Rewards pays a snake that dies the death penalty for its cause, and one
+// that lives the survival bonus plus the food reward per point eaten, and
+// the win bonus with a kill or blunder bonus when the other snake died.
+// Snakes that were already dead earn nothing.
+func (Standard) Rewards(cfg [2]config.RewardConfig, state *GameState, foodPoints [2]int, collisions [2][]CollisionResult) [2]float64 {
+	var rewards [2]float64
+	died := [2]bool{len(collisions[0]) > 0, len(collisions[1]) > 0}
+
+	for i := 0; i < 2; i++ {
+		otherIdx := 1 - i
+		cfg := cfg[i]
+
+		if died[i] {
+			rewards[i] = deathPenalty(cfg, collisions[i])
+		} else if state.Snakes[i].Alive {
+			// Survival bonus
+			rewards[i] = cfg.Survival
+
+			// Food reward
+			rewards[i] += cfg.Food * float64(foodPoints[i])
+
+			// Win bonus if opponent died, plus an attribution bonus
+			// depending on whether we killed it or it died on its own
+			if died[otherIdx] {
+				rewards[i] += cfg.Win
+				if KilledBy(collisions[otherIdx], i) {
+					rewards[i] += cfg.Kill
+				} else {
+					rewards[i] += cfg.OpponentBlunder
+				}
+			}
+		}
+	}
+
+	return rewards
+}
+
+// deathPenalty returns cfg's penalty for a death by collisions: the DeathBy
+// value of the first cause if it has one, Death otherwise
+func deathPenalty(cfg config.RewardConfig, collisions []CollisionResult) float64 {
+	if penalty, ok := cfg.DeathBy[collisions[0].Type.String()]; ok {
+		return penalty
+	}
+	return cfg.Death
+}
+
+// Outcome ends the game once either snake is dead; the survivor wins, and
+// snakes that die together tie
+func (Standard) Outcome(state *GameState) (bool, int) {
+	alive0, alive1 := state.Snakes[0].Alive, state.Snakes[1].Alive
+	switch {
+	case alive0 && alive1:
+		return false, -1
+	case alive0:
+		return true, 0
+	case alive1:
+		return true, 1
+	}
+	return true, -1
+}
+
+// Wrapped plays like Standard on a board without walls: a head leaving one
+// edge comes back on the opposite one
+type Wrapped struct{ Standard }
+
+// Name returns RulesWrapped
+func (Wrapped) Name() string { return RulesWrapped }
+
+// Move returns the neighboring cell, wrapped around the board
+func (Wrapped) Move(state *GameState, pos Position, dir Direction) Position {
+	next := pos.Neighbor(dir)
+	next.X = (next.X + state.Width) % state.Width
+	next.Y = (next.Y + state.Height) % state.Height
+	return next
+}
+
+// Royale plays like Standard while the board shrinks: every ShrinkEvery
+// turns another ring of cells along the edges becomes deadly, until 4 rows
+// and columns are left. A head in the ring counts as a wall collision.
+type Royale struct {
+	Standard
+	ShrinkEvery int
+}
+
+// Name returns RulesRoyale
+func (Royale) Name() string { return RulesRoyale }
+
+// Margin returns how many rings of cells along the edges are deadly on the
+// state's turn
+func (r Royale) Margin(state *GameState) int {
+	if r.ShrinkEvery <= 0 {
+		return 0
+	}
+	return max(min(state.Turn/r.ShrinkEvery, (min(state.Width, state.Height)-4)/2), 0)
+}
+
+// Safe reports whether pos lies inside the part of the board that has not
+// closed in yet
+func (r Royale) Safe(state *GameState, pos Position) bool {
+	m := r.Margin(state)
+	return pos.X >= m && pos.X < state.Width-m && pos.Y >= m && pos.Y < state.Height-m
+}
+
+// Collisions adds a wall collision for living snakes whose head is on the
+// board but outside the safe area
+func (r Royale) Collisions(state *GameState) [2][]CollisionResult {
+	collisions := r.Standard.Collisions(state)
+	for i, snake := range state.Snakes {
+		head := snake.Head()
+		if snake.Alive && !CheckWallCollision(head, state.Width, state.Height) && !r.Safe(state, head) {
+			collisions[i] = append(collisions[i], CollisionResult{Type: WallCollision, Position: head})
+		}
+	}
+	return collisions
+}
+
+// Solo takes snake 1 off the game: it is dead from the start, and the game
+// goes on until snake 0 dies or the turn limit is reached. Nobody wins a
+// solo game that ends in a death.
+type Solo struct{ Standard }
+
+// Name returns RulesSolo
+func (Solo) Name() string { return RulesSolo }
+
+// Setup kills snake 1. Its body stays where Reset put it but blocks nothing.
+func (Solo) Setup(state *GameState) {
+	state.Snakes[1].Kill()
+}
+
+// Outcome ends the game when snake 0 dies
+func (Solo) Outcome(state *GameState) (bool, int) {
+	return !state.Snakes[0].Alive, -1
+}
@@ -0,0 +1,115 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func newRulesGame(rules string) *Game {
+	cfg := config.DefaultGameConfig()
+	cfg.Rules = rules
+	return NewGame(cfg, 1)
+}
+
+func TestWrappedRules(t *testing.T) {
+	g := newRulesGame(RulesWrapped)
+	// Snake 0 leaves the top edge on turn 11 and comes back at the bottom
+	for range 11 {
+		g.Step([2]Direction{Up, Down})
+		if err := g.State.Validate(); err != nil {
+			t.Fatalf("turn %d: %v", g.State.Turn, err)
+		}
+	}
+	if !g.State.Snakes[0].Alive || g.State.Snakes[0].Head() != (Position{X: 3, Y: 19}) {
+		t.Errorf("snake 0 alive=%v at %v, want alive at (3,19)", g.State.Snakes[0].Alive, g.State.Snakes[0].Head())
+	}
+	if !g.State.Snakes[1].Alive || g.State.Snakes[1].Head().Y != 1 {
+		t.Errorf("snake 1 alive=%v at %v, want alive on row 1", g.State.Snakes[1].Alive, g.State.Snakes[1].Head())
+	}
+}
+
+func TestRoyaleRules(t *testing.T) {
+	g := newRulesGame(RulesRoyale)
+	g.State.Turn = 2 * DefaultShrinkEvery
+
+	// Two rings are closed, so row 1 is deadly while row 2 is not
+	for range 8 {
+		if result := g.Step([2]Direction{Up, Left}); result.GameOver {
+			t.Fatalf("game ended on turn %d", g.State.Turn)
+		}
+	}
+	result := g.Step([2]Direction{Up, Left})
+	if !result.Died[0] || result.Died[1] || result.Winner != 1 {
+		t.Fatalf("died %v winner %d, want snake 0 dead and snake 1 the winner", result.Died, result.Winner)
+	}
+	if c := result.Collisions[0]; len(c) != 1 || c[0].Type != WallCollision || c[0].Position != (Position{X: 3, Y: 1}) {
+		t.Errorf("collisions %v, want a wall at (3,1)", c)
+	}
+
+	r := Royale{ShrinkEvery: DefaultShrinkEvery}
+	if m := r.Margin(&GameState{Width: 10, Height: 8, Turn: 1000}); m != 2 {
+		t.Errorf("margin %d, want it to stop at 2 with 4 rows left", m)
+	}
+}
+
+func TestSoloRules(t *testing.T) {
+	g := newRulesGame(RulesSolo)
+	if g.State.Snakes[1].Alive {
+		t.Fatal("snake 1 is in a solo game")
+	}
+	for !g.State.GameOver {
+		result := g.Step([2]Direction{Up, Up})
+		if result.Rewards[1] != 0 {
+			t.Errorf("turn %d: snake 1 earned %v", g.State.Turn, result.Rewards[1])
+		}
+		if err := g.State.Validate(); err != nil {
+			t.Fatalf("turn %d: %v", g.State.Turn, err)
+		}
+	}
+	if g.State.Turn != 11 || g.State.Winner != -1 {
+		t.Errorf("game ended on turn %d with winner %d, want turn 11 and no winner", g.State.Turn, g.State.Winner)
+	}
+}
+
+func TestLookupRules(t *testing.T) {
+	for _, name := range RulesNames() {
+		rules, err := LookupRules(name)
+		if err != nil || rules.Name() != name {
+			t.Errorf("LookupRules(%q) = %v, %v", name, rules, err)
+		}
+	}
+	if rules, err := LookupRules(""); err != nil || rules.Name() != RulesStandard {
+		t.Errorf("empty name gave %v, %v", rules, err)
+	}
+	if _, err := LookupRules("chess"); err == nil {
+		t.Error("unknown ruleset accepted")
+	}
+}
+
+func TestDeathPenaltyByCause(t *testing.T) {
+	g := newRulesGame(RulesStandard)
+	rewards := config.DefaultRewardConfig()
+	rewards.DeathBy = map[string]float64{"wall": -3}
+	g.SetRewards(rewards)
+
+	// Snake 1 runs off the bottom on turn 10
+	var result StepResult
+	for !g.State.GameOver {
+		result = g.Step([2]Direction{Up, Down})
+	}
+	if result.DeathCause(1) != WallCollision || result.DeathCause(0) != NoCollision {
+		t.Fatalf("death causes %v, %v", result.DeathCause(0), result.DeathCause(1))
+	}
+	if result.Rewards[1] != -3 {
+		t.Errorf("wall death paid %v, want -3", result.Rewards[1])
+	}
+
+	// Every collision type can be priced
+	for c := WallCollision; c <= HeadToHeadCollision; c++ {
+		if !slices.Contains(config.DeathCauses, c.String()) {
+			t.Errorf("%v is missing from config.DeathCauses", c)
+		}
+	}
+}
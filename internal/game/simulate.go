@@ -0,0 +1,29 @@
+package game
+
+// SimulatedTurn is one turn played by Simulate: its result and a copy of
+// the state after it
+type SimulatedTurn struct {
+	Result StepResult
+	State  *GameState
+}
+
+// Simulate plays moves, one pair of directions per turn, on a copy of the
+// game and returns every turn played. It stops early once the game is
+// over, so fewer turns than moves come back when a move ends it.
+//
+// The game itself is left untouched, its random number generator included:
+// the copy's food comes from a generator seeded with the position, so
+// simulating the same moves from the same position always gives the same
+// turns. Listeners are not told about simulated turns.
+func (g *Game) Simulate(moves [][2]Direction) []SimulatedTurn {
+	sim := g.cloneWithSeed(int64(g.State.Hash()) ^ int64(g.State.Turn))
+	turns := make([]SimulatedTurn, 0, len(moves))
+	for _, dirs := range moves {
+		if sim.State.GameOver {
+			break
+		}
+		result := sim.Step(dirs)
+		turns = append(turns, SimulatedTurn{Result: result, State: sim.State.Clone()})
+	}
+	return turns
+}
@@ -0,0 +1,44 @@
+package game
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/config"
+)
+
+func TestSimulate(t *testing.T) {
+	g := NewGame(config.DefaultGameConfig(), 1)
+	control := NewGame(config.DefaultGameConfig(), 1)
+	hash := g.State.Hash()
+
+	// Both snakes run into the top wall on turn 11; the rest is never played
+	moves := make([][2]Direction, 15)
+	for i := range moves {
+		moves[i] = [2]Direction{Up, Up}
+	}
+	turns := g.Simulate(moves)
+	if len(turns) != 11 {
+		t.Fatalf("simulated %d turns, want 11", len(turns))
+	}
+	for i, turn := range turns {
+		if turn.State.Turn != i+1 {
+			t.Errorf("turn %d holds the state of turn %d", i+1, turn.State.Turn)
+		}
+	}
+	last := turns[len(turns)-1]
+	if !last.Result.GameOver || last.Result.Died != [2]bool{true, true} || !last.State.GameOver {
+		t.Errorf("last turn: over=%v died=%v, want both snakes dead and the game over", last.Result.GameOver, last.Result.Died)
+	}
+	if again := g.Simulate(moves); again[5].State.Hash() != turns[5].State.Hash() {
+		t.Error("simulating the same moves twice gave different turns")
+	}
+
+	// The real game is where it was, and its food comes out as if nothing
+	// had been simulated
+	if g.State.Turn != 0 || g.State.Hash() != hash {
+		t.Fatalf("simulation moved the game to turn %d", g.State.Turn)
+	}
+	if g.rng.Int63() != control.rng.Int63() {
+		t.Error("simulation drew from the game's random number generator")
+	}
+}
@@ -65,6 +65,21 @@ func (p Position) Add(dx, dy int) Position {
 	return Position{X: p.X + dx, Y: p.Y + dy}
 }
 
+// Neighbor returns the adjacent position in the given direction
+func (p Position) Neighbor(dir Direction) Position {
+	switch dir {
+	case Up:
+		return p.Add(0, -1)
+	case Down:
+		return p.Add(0, 1)
+	case Left:
+		return p.Add(-1, 0)
+	case Right:
+		return p.Add(1, 0)
+	}
+	return p
+}
+
 // Equals checks if two positions are the same
 func (p Position) Equals(other Position) bool {
 	return p.X == other.X && p.Y == other.Y
@@ -78,6 +93,8 @@ type Snake struct {
 	Alive     bool
 	Score     int
 	Grew      bool // Whether snake grew this turn (for collision resolution)
+	Growing   int  // Segments still to grow on later turns from pellets worth more than one
+	DiedOn    int  // Turn the snake died on, for respawning
 }
 
 // NewSnake creates a new snake at the given position
@@ -125,20 +142,30 @@ func (s *Snake) Length() int {
 	return len(s.Body)
 }
 
+// Tail returns the snake's last body segment
+func (s *Snake) Tail() Position {
+	return s.Body[len(s.Body)-1]
+}
+
+// TailLeaves reports whether the tail moves off its cell on the snake's
+// next move when it doesn't eat, freeing the cell for a head; a snake
+// still growing from an earlier pellet keeps its tail
+func (s *Snake) TailLeaves() bool {
+	return s.Alive && s.Growing == 0
+}
+
 // NextHead returns where the head will be after moving in the given direction
 func (s *Snake) NextHead(dir Direction) Position {
-	head := s.Head()
-	switch dir {
-	case Up:
-		return head.Add(0, -1)
-	case Down:
-		return head.Add(0, 1)
-	case Left:
-		return head.Add(-1, 0)
-	case Right:
-		return head.Add(1, 0)
+	return s.Head().Neighbor(dir)
+}
+
+// ResolveDirection returns the direction the snake will actually move in,
+// ignoring 180-degree turns
+func (s *Snake) ResolveDirection(dir Direction) Direction {
+	if dir == s.Direction.Opposite() {
+		return s.Direction
 	}
-	return head
+	return dir
 }
 
 // Move moves the snake in the given direction
@@ -148,12 +175,12 @@ func (s *Snake) Move(dir Direction, grow bool) {
 		return
 	}
 
-	// Prevent 180-degree turns
-	if dir == s.Direction.Opposite() {
-		dir = s.Direction
-	}
+	dir = s.ResolveDirection(dir)
+	s.advance(dir, s.NextHead(dir), grow)
+}
 
-	newHead := s.NextHead(dir)
+// advance moves the head to newHead, which the snake reaches going in dir
+func (s *Snake) advance(dir Direction, newHead Position, grow bool) {
 	s.Direction = dir
 	s.Grew = grow
 
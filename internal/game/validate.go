@@ -0,0 +1,124 @@
+package game
+
+import "fmt"
+
+// Validate checks the structural invariants of the state: both snakes exist
+// with contiguous bodies, living snakes lie fully on the board without
+// overlapping themselves, active food sits on an empty cell, and the
+// game-over flags agree with which snakes are alive under the state's
+// rules. It returns the first violation found, or nil.
+func (s *GameState) Validate() error {
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("invalid board size %dx%d", s.Width, s.Height)
+	}
+	if _, err := LookupRules(s.Rules); err != nil {
+		return err
+	}
+	for _, pos := range s.Obstacles {
+		if CheckWallCollision(pos, s.Width, s.Height) {
+			return fmt.Errorf("obstacle at %v is off the board", pos)
+		}
+	}
+
+	for id, snake := range s.Snakes {
+		if snake == nil {
+			return fmt.Errorf("snake %d is missing", id)
+		}
+		if err := s.validateSnake(snake); err != nil {
+			return fmt.Errorf("snake %d: %w", id, err)
+		}
+	}
+
+	for _, pos := range s.Golden {
+		if !s.HasFoodAt(pos) {
+			return fmt.Errorf("golden pellet at %v is not on the board", pos)
+		}
+	}
+
+	seen := make(map[Position]bool)
+	for _, food := range s.FoodPositions() {
+		if CheckWallCollision(food, s.Width, s.Height) {
+			return fmt.Errorf("food at %v is off the board", food)
+		}
+		if seen[food] {
+			return fmt.Errorf("two pellets at %v", food)
+		}
+		if s.IsObstacle(food) {
+			return fmt.Errorf("food at %v is on an obstacle", food)
+		}
+		seen[food] = true
+		for id, snake := range s.Snakes {
+			if snake.ContainsPosition(food, false) {
+				return fmt.Errorf("food at %v is on snake %d", food, id)
+			}
+		}
+	}
+
+	return s.validateOutcome()
+}
+
+// validateSnake checks a single snake's body
+func (s *GameState) validateSnake(snake *Snake) error {
+	if len(snake.Body) == 0 {
+		return fmt.Errorf("empty body")
+	}
+	if snake.Growing < 0 {
+		return fmt.Errorf("negative growth %d", snake.Growing)
+	}
+
+	for i, pos := range snake.Body {
+		// A dead snake's head may have left the board when it hit the wall
+		if CheckWallCollision(pos, s.Width, s.Height) && (snake.Alive || i > 0) {
+			return fmt.Errorf("segment %d at %v is off the board", i, pos)
+		}
+		if i > 0 && !s.adjacent(snake.Body[i-1], pos) {
+			return fmt.Errorf("segments %d and %d (%v, %v) are not adjacent", i-1, i, snake.Body[i-1], pos)
+		}
+	}
+
+	if snake.Alive && CheckSelfCollision(snake) {
+		return fmt.Errorf("living snake overlaps itself at %v", snake.Head())
+	}
+	for i, pos := range snake.Body {
+		if s.IsObstacle(pos) && (snake.Alive || i > 0) {
+			return fmt.Errorf("segment %d at %v is on an obstacle", i, pos)
+		}
+	}
+	return nil
+}
+
+// adjacent reports whether a snake can move from a to b in one turn
+func (s *GameState) adjacent(a, b Position) bool {
+	rules := s.rules()
+	for _, dir := range []Direction{Up, Down, Left, Right} {
+		if rules.Move(s, a, dir) == b {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOutcome checks GameOver and Winner against the alive flags, or
+// the scores in an endless match
+func (s *GameState) validateOutcome() error {
+	over, want := s.outcome()
+	if !over {
+		switch {
+		case s.TimedOut && s.Winner != s.Leader():
+			return fmt.Errorf("timed out with winner %d, want %d", s.Winner, s.Leader())
+		case s.Repeated && s.Winner != -1:
+			return fmt.Errorf("drawn on a repeated position with winner %d", s.Winner)
+		case s.GameOver != (s.TimedOut || s.Repeated):
+			return fmt.Errorf("game over is %v with the snakes playing on, timed out %v and repeated %v", s.GameOver, s.TimedOut, s.Repeated)
+		}
+		return nil
+	}
+
+	if !s.GameOver {
+		return fmt.Errorf("game not over though it is decided")
+	}
+	if s.Winner != want {
+		return fmt.Errorf("winner is %d, want %d", s.Winner, want)
+	}
+	return nil
+}
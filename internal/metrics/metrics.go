@@ -0,0 +1,296 @@
+// Package metrics records training progress: CSV or JSON lines learning
+// curves, TensorBoard event files and Prometheus metrics.
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Metrics file formats, see FormatOf
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// header names the CSV columns in Row field order. Files written before
+// loss and eps_per_sec existed end at avg_reward1.
+var header = []string{
+	"episode", "epsilon", "avg_length", "win_rate0", "win_rate1", "tie_rate", "avg_reward0", "avg_reward1",
+	"loss", "eps_per_sec",
+}
+
+// legacyColumns is the number of columns of the original Row files
+const legacyColumns = 8
+
+// Row summarizes the episodes since the previous row
+type Row struct {
+	Episode   int        `json:"episode"`
+	Epsilon   float64    `json:"epsilon"`
+	AvgLength float64    `json:"avg_length"`
+	WinRate   [2]float64 `json:"win_rate"`
+	TieRate   float64    `json:"tie_rate"`
+	AvgReward [2]float64 `json:"avg_reward"`
+	Loss      float64    `json:"loss"`        // Mean training loss over the interval, 0 before the first update
+	EpsPerSec float64    `json:"eps_per_sec"` // Episodes per second since training started
+}
+
+// values returns the row's columns as strings
+func (r Row) values() []string {
+	return []string{
+		strconv.Itoa(r.Episode), formatFloat(r.Epsilon), formatFloat(r.AvgLength),
+		formatFloat(r.WinRate[0]), formatFloat(r.WinRate[1]), formatFloat(r.TieRate),
+		formatFloat(r.AvgReward[0]), formatFloat(r.AvgReward[1]),
+		formatFloat(r.Loss), formatFloat(r.EpsPerSec),
+	}
+}
+
+// episodeHeader names the CSV columns in Episode field order
+var episodeHeader = []string{"episode", "length", "reward0", "reward1", "winner", "epsilon"}
+
+// Episode records one finished training episode
+type Episode struct {
+	Episode int        `json:"episode"`
+	Length  int        `json:"length"` // Turns played
+	Reward  [2]float64 `json:"reward"`
+	Winner  int        `json:"winner"` // -1 for a tie
+	Epsilon float64    `json:"epsilon"`
+}
+
+// values returns the episode's columns as strings
+func (e Episode) values() []string {
+	return []string{
+		strconv.Itoa(e.Episode), strconv.Itoa(e.Length), formatFloat(e.Reward[0]), formatFloat(e.Reward[1]),
+		strconv.Itoa(e.Winner), formatFloat(e.Epsilon),
+	}
+}
+
+// formatFloat formats a column value compactly
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// record is a Row or an Episode
+type record interface {
+	values() []string
+}
+
+// FormatOf returns the format of the metrics file at path: JSON lines for
+// .jsonl and .ndjson, CSV otherwise
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	}
+	return FormatCSV
+}
+
+// Writer appends Rows or Episodes to a CSV or JSON lines stream
+type Writer struct {
+	csv  *csv.Writer
+	json *json.Encoder
+	file *os.File // Closed by Close, when the Writer opened it
+}
+
+// NewWriter writes the Row header to w and returns a CSV row writer
+func NewWriter(w io.Writer) (*Writer, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{csv: cw}, nil
+}
+
+// OpenRows opens a Row file at path in the format FormatOf picks. A
+// resumed run passes the episodes it has finished: the rows of those are
+// kept and added to, and any after them, written since the last checkpoint
+// of a run that then crashed, are dropped. With 0 the file starts afresh.
+func OpenRows(path string, episodes int) (*Writer, error) {
+	return open(path, header, episodes)
+}
+
+// OpenEpisodes opens an Episode file at path like OpenRows
+func OpenEpisodes(path string, episodes int) (*Writer, error) {
+	return open(path, episodeHeader, episodes)
+}
+
+// open opens a metrics file whose CSV columns are columns, keeping the
+// records of its first episodes episodes and writing the header unless it
+// continues a file that has one. A CSV file with other columns is refused
+// rather than mixed with new rows.
+func open(path string, columns []string, episodes int) (*Writer, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if episodes > 0 {
+		flags = os.O_CREATE | os.O_RDWR | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	hasHeader := false
+	if episodes > 0 {
+		if hasHeader, err = keepEpisodes(file, path, columns, episodes); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if FormatOf(path) == FormatJSONL {
+		return &Writer{json: json.NewEncoder(file), file: file}, nil
+	}
+
+	w := &Writer{csv: csv.NewWriter(file), file: file}
+	if !hasHeader {
+		if err = w.csv.Write(columns); err == nil {
+			w.csv.Flush()
+			err = w.csv.Error()
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// keepEpisodes truncates a resumed metrics file after the last record of
+// its first episodes episodes, which also drops a last line cut short, and
+// reports whether it keeps a CSV header. Records are in episode order, as
+// every run appends to the ones before it.
+func keepEpisodes(file *os.File, path string, columns []string, episodes int) (hasHeader bool, err error) {
+	jsonl := FormatOf(path) == FormatJSONL
+	reader := bufio.NewReader(file)
+	var kept int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		if !jsonl && !hasHeader {
+			// A resumed file's first line is its header
+			if strings.TrimSpace(line) != strings.Join(columns, ",") {
+				return false, fmt.Errorf("%s has other columns than %s", path, strings.Join(columns, ","))
+			}
+			hasHeader = true
+		} else if ep, ok := recordEpisode(line, jsonl); !ok || ep > episodes {
+			break
+		}
+		kept += int64(len(line))
+	}
+	return hasHeader, file.Truncate(kept)
+}
+
+// recordEpisode returns the episode of a Row or Episode line
+func recordEpisode(line string, jsonl bool) (int, bool) {
+	if jsonl {
+		var r struct {
+			Episode *int `json:"episode"`
+		}
+		if json.Unmarshal([]byte(line), &r) != nil || r.Episode == nil {
+			return 0, false
+		}
+		return *r.Episode, true
+	}
+	field, _, _ := strings.Cut(line, ",")
+	ep, err := strconv.Atoi(strings.TrimSpace(field))
+	return ep, err == nil
+}
+
+// Write appends one row and flushes it so curves can be watched live
+func (w *Writer) Write(r Row) error {
+	return w.write(r)
+}
+
+// WriteEpisode appends one episode and flushes it
+func (w *Writer) WriteEpisode(e Episode) error {
+	return w.write(e)
+}
+
+// write appends one record in the writer's format
+func (w *Writer) write(r record) error {
+	if w.json != nil {
+		return w.json.Encode(r)
+	}
+	if err := w.csv.Write(r.values()); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// Close closes the file the writer opened, if any
+func (w *Writer) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+// Read parses a metrics file of Rows, CSV or JSON lines by FormatOf. CSV
+// files from before the loss and eps_per_sec columns read with them zero.
+func Read(path string) ([]Row, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if FormatOf(path) == FormatJSONL {
+		var rows []Row
+		dec := json.NewDecoder(file)
+		for {
+			var r Row
+			if err := dec.Decode(&r); err == io.EOF {
+				return rows, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			rows = append(rows, r)
+		}
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(records) == 0 || len(records[0]) < legacyColumns || len(records[0]) > len(header) || !slices.Equal(records[0], header[:len(records[0])]) {
+		return nil, fmt.Errorf("%s: not a metrics file", path)
+	}
+	columns := len(records[0])
+
+	rows := make([]Row, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		if len(rec) != columns {
+			return nil, fmt.Errorf("%s line %d: %d columns, want %d", path, i+2, len(rec), columns)
+		}
+		var r Row
+		nums := make([]float64, len(header)-1)
+		for j := range columns - 1 {
+			if nums[j], err = strconv.ParseFloat(rec[j+1], 64); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+			}
+		}
+		if r.Episode, err = strconv.Atoi(rec[0]); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+		r.Epsilon, r.AvgLength = nums[0], nums[1]
+		r.WinRate = [2]float64{nums[2], nums[3]}
+		r.TieRate = nums[4]
+		r.AvgReward = [2]float64{nums[5], nums[6]}
+		r.Loss, r.EpsPerSec = nums[7], nums[8]
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Row{
+		{Episode: 100, Epsilon: 0.6, AvgLength: 31.5, WinRate: [2]float64{0.4, 0.5}, TieRate: 0.1, AvgReward: [2]float64{-0.25, 0.75}, Loss: 0.02, EpsPerSec: 41.5},
+		{Episode: 200, Epsilon: 0.36, AvgLength: 48, WinRate: [2]float64{0.5, 0.5}, AvgReward: [2]float64{1, 1.5}},
+	}
+	for _, r := range want {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOpenResume(t *testing.T) {
+	for _, name := range []string{"metrics.csv", "metrics.jsonl"} {
+		path := filepath.Join(t.TempDir(), name)
+		rows := []Row{{Episode: 10, Epsilon: 0.9}, {Episode: 20, Epsilon: 0.8, Loss: 0.5}, {Episode: 30, Epsilon: 0.7}}
+		rerun := Row{Episode: 30, Epsilon: 0.6}
+
+		// A run logs three rows but crashes after its checkpoint at episode
+		// 20, part way through writing a fourth
+		w, err := OpenRows(path, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range rows {
+			if err := w.Write(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		w.Close()
+		appendString(t, path, "40,0.5")
+
+		// Resuming drops the rows after the checkpoint before adding its own
+		if w, err = OpenRows(path, 20); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(rerun); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		got, err := Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []Row{rows[0], rows[1], rerun}; !slices.Equal(got, want) {
+			t.Errorf("%s: resumed read %+v, want %+v", name, got, want)
+		}
+
+		// A fresh run starts over
+		if w, err = OpenRows(path, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(rows[2]); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		if got, err = Read(path); err != nil {
+			t.Fatal(err)
+		}
+		if want := []Row{rows[2]}; !slices.Equal(got, want) {
+			t.Errorf("%s: restarted read %+v, want %+v", name, got, want)
+		}
+	}
+
+	// Episodes don't mix into a row file, which is left alone
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	w, err := OpenRows(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(Row{Episode: 10})
+	w.Close()
+	if _, err := OpenEpisodes(path, 5); err == nil {
+		t.Error("episodes appended to a row file")
+	}
+	if got, err := Read(path); err != nil || len(got) != 1 {
+		t.Errorf("row file after a refused resume: %+v, %v", got, err)
+	}
+}
+
+// appendString appends s to the file at path
+func appendString(t *testing.T, path, s string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.csv")
+	legacy := "episode,epsilon,avg_length,win_rate0,win_rate1,tie_rate,avg_reward0,avg_reward1\n100,0.5,20,0.4,0.5,0.1,1,2\n"
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Row{Episode: 100, Epsilon: 0.5, AvgLength: 20, WinRate: [2]float64{0.4, 0.5}, TieRate: 0.1, AvgReward: [2]float64{1, 2}}
+	if len(rows) != 1 || rows[0] != want {
+		t.Errorf("read %+v, want %+v", rows, want)
+	}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Progress is the state of a training run as it goes, updated every
+// episode
+type Progress struct {
+	Episodes       int // Episodes finished, a resumed run's earlier ones included
+	Steps          int // Environment steps taken
+	Wins           [2]int
+	Ties           int
+	Epsilon        float64
+	StepsPerSec    float64 // Since training started
+	BufferSize     int     // Experiences in the replay buffer
+	BufferCapacity int     // 0 for agents without one
+}
+
+// Exporter serves a training run's Progress and latest Row at /metrics in
+// the Prometheus text format, so existing Prometheus and Grafana setups can
+// watch long runs
+type Exporter struct {
+	mu       sync.Mutex
+	progress Progress
+	row      *Row
+}
+
+// NewExporter returns an exporter with nothing recorded yet
+func NewExporter() *Exporter {
+	return &Exporter{}
+}
+
+// SetProgress records the run's progress
+func (e *Exporter) SetProgress(p Progress) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.progress = p
+}
+
+// SetRow records the summary of the latest log interval
+func (e *Exporter) SetRow(r Row) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.row = &r
+}
+
+// ServeHTTP writes the metrics
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format. Interval
+// metrics are left out until the first interval ends, and buffer metrics
+// for agents without a replay buffer.
+func (e *Exporter) WriteTo(w io.Writer) (int64, error) {
+	e.mu.Lock()
+	p, row := e.progress, e.row
+	e.mu.Unlock()
+
+	pw := &promWriter{w: w}
+	pw.metric("slither_train_episodes_total", "counter", "Training episodes finished.", float64(p.Episodes))
+	pw.metric("slither_train_steps_total", "counter", "Environment steps taken.", float64(p.Steps))
+	pw.labeled("slither_train_wins_total", "counter", "Episodes won by each snake.", "snake", []float64{float64(p.Wins[0]), float64(p.Wins[1])})
+	pw.metric("slither_train_ties_total", "counter", "Episodes drawn.", float64(p.Ties))
+	pw.metric("slither_train_epsilon", "gauge", "Current exploration rate.", p.Epsilon)
+	pw.metric("slither_train_steps_per_second", "gauge", "Environment steps per second since training started.", p.StepsPerSec)
+	if p.BufferCapacity > 0 {
+		pw.metric("slither_train_replay_buffer_size", "gauge", "Experiences in the replay buffer.", float64(p.BufferSize))
+		pw.metric("slither_train_replay_buffer_fill_ratio", "gauge", "Fraction of the replay buffer filled.", float64(p.BufferSize)/float64(p.BufferCapacity))
+	}
+	if row != nil {
+		pw.labeled("slither_train_avg_reward", "gauge", "Average episode reward over the last log interval.", "snake", row.AvgReward[:])
+		pw.labeled("slither_train_win_rate", "gauge", "Win rate over the last log interval.", "snake", row.WinRate[:])
+		pw.metric("slither_train_tie_rate", "gauge", "Tie rate over the last log interval.", row.TieRate)
+		pw.metric("slither_train_avg_episode_length", "gauge", "Average episode length in turns over the last log interval.", row.AvgLength)
+		pw.metric("slither_train_loss", "gauge", "Mean recent training loss.", row.Loss)
+		pw.metric("slither_train_episodes_per_second", "gauge", "Episodes per second since training started.", row.EpsPerSec)
+	}
+	return pw.n, pw.err
+}
+
+// promWriter writes metrics in the text format, keeping the first error
+type promWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (pw *promWriter) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+// metric writes a metric without labels
+func (pw *promWriter) metric(name, kind, help string, value float64) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
+
+// labeled writes a metric with one value per index of label
+func (pw *promWriter) labeled(name, kind, help, label string, values []float64) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for i, v := range values {
+		pw.printf("%s{%s=\"%d\"} %g\n", name, label, i, v)
+	}
+}
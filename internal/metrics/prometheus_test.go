@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExporter(t *testing.T) {
+	e := NewExporter()
+	e.SetProgress(Progress{Episodes: 120, Steps: 4000, Wins: [2]int{70, 40}, Ties: 10, Epsilon: 0.25})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE slither_train_episodes_total counter\nslither_train_episodes_total 120\n",
+		"slither_train_steps_total 4000\n",
+		"slither_train_wins_total{snake=\"0\"} 70\nslither_train_wins_total{snake=\"1\"} 40\n",
+		"# TYPE slither_train_epsilon gauge\nslither_train_epsilon 0.25\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+	// Nothing is made up before there is a buffer or a finished interval
+	for _, absent := range []string{"replay_buffer", "avg_reward", "loss"} {
+		if strings.Contains(body, absent) {
+			t.Errorf("metrics have %s too early:\n%s", absent, body)
+		}
+	}
+
+	e.SetProgress(Progress{Episodes: 200, BufferSize: 250, BufferCapacity: 1000})
+	e.SetRow(Row{Episode: 200, AvgReward: [2]float64{1.5, -0.5}, Loss: 0.125})
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body = rec.Body.String()
+	for _, want := range []string{
+		"slither_train_replay_buffer_fill_ratio 0.25\n",
+		"slither_train_avg_reward{snake=\"1\"} -0.5\n",
+		"slither_train_loss 0.125\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
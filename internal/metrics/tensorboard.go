@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EventWriter writes scalars to a TensorBoard event file, the TFRecord
+// stream of Event protocol buffers TensorBoard and tbparse read, so Go
+// training shows up beside Python runs under tensorboard --logdir
+type EventWriter struct {
+	Path string // The event file
+	file *os.File
+}
+
+// castagnoli is the CRC-32C table TFRecord checksums use
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// NewEventWriter creates dir if needed and starts a new event file in it,
+// named the way TensorBoard looks for them. A resumed run gets a new file
+// beside the old ones; TensorBoard joins them by step.
+func NewEventWriter(dir string) (*EventWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("events.out.tfevents.%010d.%s.%d", now.Unix(), host, os.Getpid()))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &EventWriter{file: file, Path: path}
+
+	// Every event file opens with its format version
+	var event []byte
+	event = appendDouble(event, 1, wallTime(now))
+	event = appendBytes(event, 3, []byte("brain.Event:2"))
+	if err := w.record(event); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Scalars writes one event holding the named values at step
+func (w *EventWriter) Scalars(step int, tags []string, values []float64) error {
+	var summary []byte
+	for i, tag := range tags {
+		var value []byte
+		value = appendBytes(value, 1, []byte(tag))
+		value = appendFloat(value, 2, float32(values[i]))
+		summary = appendBytes(summary, 1, value)
+	}
+	var event []byte
+	event = appendDouble(event, 1, wallTime(time.Now()))
+	event = appendVarint(event, 2, uint64(step))
+	event = appendBytes(event, 5, summary)
+	return w.record(event)
+}
+
+// Write records a Row's values under train/, at its episode
+func (w *EventWriter) Write(r Row) error {
+	values := []float64{
+		r.Epsilon, r.AvgLength, r.WinRate[0], r.WinRate[1], r.TieRate,
+		r.AvgReward[0], r.AvgReward[1], r.Loss, r.EpsPerSec,
+	}
+	return w.Scalars(r.Episode, prefixed("train/", header[1:]), values)
+}
+
+// WriteEpisode records an Episode's values under episode/, at its number
+func (w *EventWriter) WriteEpisode(e Episode) error {
+	values := []float64{float64(e.Length), e.Reward[0], e.Reward[1], float64(e.Winner), e.Epsilon}
+	return w.Scalars(e.Episode, prefixed("episode/", episodeHeader[1:]), values)
+}
+
+// Close closes the event file
+func (w *EventWriter) Close() error {
+	return w.file.Close()
+}
+
+// record appends data as one TFRecord: its length, the length's checksum,
+// the data and the data's checksum
+func (w *EventWriter) record(data []byte) error {
+	buf := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+	buf = binary.LittleEndian.AppendUint32(buf, maskedCRC(buf))
+	buf = append(buf, data...)
+	buf = binary.LittleEndian.AppendUint32(buf, maskedCRC(data))
+	_, err := w.file.Write(buf)
+	return err
+}
+
+// maskedCRC is the rotated and offset CRC-32C TFRecord stores, so that
+// checksums of data holding checksums stay well spread
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, castagnoli)
+	return (crc>>15 | crc<<17) + 0xa282ead8
+}
+
+// wallTime is t in fractional seconds since the epoch
+func wallTime(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// prefixed returns names each with prefix in front
+func prefixed(prefix string, names []string) []string {
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = prefix + name
+	}
+	return tags
+}
+
+// Protocol buffer wire encoding of the few Event and Summary fields used
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, 0), v)
+}
+
+func appendDouble(b []byte, field int, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(appendTag(b, field, 1), math.Float64bits(v))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, 2), uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendFloat(b []byte, field int, v float32) []byte {
+	return binary.LittleEndian.AppendUint32(appendTag(b, field, 5), math.Float32bits(v))
+}
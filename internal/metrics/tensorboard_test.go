@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scalarEvent is a decoded event: its step and summary values, or the
+// file version of the first event
+type scalarEvent struct {
+	step    int
+	version string
+	values  map[string]float32
+}
+
+// readEvents decodes an event file, checking every record's checksums
+func readEvents(t *testing.T, path string) []scalarEvent {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []scalarEvent
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("truncated record header")
+		}
+		n := int(binary.LittleEndian.Uint64(data))
+		if binary.LittleEndian.Uint32(data[8:]) != maskedCRC(data[:8]) {
+			t.Fatal("bad length checksum")
+		}
+		record := data[12 : 12+n]
+		if binary.LittleEndian.Uint32(data[12+n:]) != maskedCRC(record) {
+			t.Fatal("bad data checksum")
+		}
+		data = data[16+n:]
+
+		e := scalarEvent{values: map[string]float32{}}
+		for field, v := range fields(t, record) {
+			switch field {
+			case 2:
+				e.step = int(v.varint)
+			case 3:
+				e.version = string(v.bytes)
+			case 5:
+				for _, value := range fields(t, v.bytes) {
+					var tag string
+					var x float32
+					for f, v := range fields(t, value.bytes) {
+						if f == 1 {
+							tag = string(v.bytes)
+						} else if f == 2 {
+							x = math.Float32frombits(uint32(v.varint))
+						}
+					}
+					e.values[tag] = x
+				}
+			}
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// wireValue is a decoded protocol buffer field, fixed-size values in varint
+type wireValue struct {
+	varint uint64
+	bytes  []byte
+}
+
+// fields decodes a protocol buffer message into its fields in order
+func fields(t *testing.T, b []byte) func(yield func(int, wireValue) bool) {
+	return func(yield func(int, wireValue) bool) {
+		for len(b) > 0 {
+			key, n := binary.Uvarint(b)
+			b = b[n:]
+			var v wireValue
+			switch key & 7 {
+			case 0:
+				v.varint, n = binary.Uvarint(b)
+			case 1:
+				v.varint, n = binary.LittleEndian.Uint64(b), 8
+			case 2:
+				size, m := binary.Uvarint(b)
+				v.bytes, n = b[m:m+int(size)], m+int(size)
+			case 5:
+				v.varint, n = uint64(binary.LittleEndian.Uint32(b)), 4
+			default:
+				t.Fatalf("unexpected wire type %d", key&7)
+			}
+			b = b[n:]
+			if !yield(int(key>>3), v) {
+				return
+			}
+		}
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tb")
+	w, err := NewEventWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(filepath.Base(w.Path), "events.out.tfevents.") {
+		t.Errorf("event file %s not named for TensorBoard", w.Path)
+	}
+	if err := w.Write(Row{Episode: 100, Epsilon: 0.5, WinRate: [2]float64{0.25, 0.75}, Loss: 0.125}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteEpisode(Episode{Episode: 101, Length: 40, Reward: [2]float64{1.5, -1}, Winner: -1}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	events := readEvents(t, w.Path)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].version != "brain.Event:2" {
+		t.Errorf("first event version %q", events[0].version)
+	}
+	row := events[1]
+	if row.step != 100 || len(row.values) != len(header)-1 {
+		t.Errorf("row event at step %d with %d values", row.step, len(row.values))
+	}
+	if row.values["train/epsilon"] != 0.5 || row.values["train/win_rate1"] != 0.75 || row.values["train/loss"] != 0.125 {
+		t.Errorf("row values %v", row.values)
+	}
+	ep := events[2]
+	if ep.step != 101 || ep.values["episode/length"] != 40 || ep.values["episode/reward0"] != 1.5 || ep.values["episode/winner"] != -1 {
+		t.Errorf("episode event at step %d: %v", ep.step, ep.values)
+	}
+}
@@ -0,0 +1,44 @@
+package netplay
+
+import (
+	"fmt"
+
+	"autonomous-snake/internal/ai"
+)
+
+// Play joins a hosted match on conn and answers every state with policy's
+// move until the host finishes. It returns the number of games played.
+func Play(conn *Conn, policy ai.Policy, name string) (int, error) {
+	if err := conn.Send(Message{Type: MsgHello, Name: name, Version: ProtocolVersion}); err != nil {
+		return 0, err
+	}
+
+	games := 0
+	snakeID := 1
+	for {
+		msg, err := conn.Receive("")
+		if err != nil {
+			return games, err
+		}
+
+		switch msg.Type {
+		case MsgStart:
+			snakeID = msg.Snake
+		case MsgState:
+			if msg.State == nil {
+				return games, fmt.Errorf("state message without a state")
+			}
+			action := policy.Act(msg.State, snakeID)
+			reply := Message{Type: MsgMove, Game: msg.Game, Turn: msg.State.Turn, Action: action.String()}
+			if err := conn.Send(reply); err != nil {
+				return games, err
+			}
+		case MsgEnd:
+			games++
+		case MsgDone:
+			return games, nil
+		default:
+			return games, fmt.Errorf("unexpected %q message", msg.Type)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package netplay
+
+import (
+	"fmt"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// Result summarizes a finished match from the host's point of view
+type Result struct {
+	Opponent string // Name sent by the client
+	Games    int
+	Wins     [2]int // Indexed by snake: 0 is the host, 1 the client
+	Ties     int
+}
+
+// HostConfig controls a hosted match
+type HostConfig struct {
+	Game     config.GameConfig
+	Games    int
+	Seed     int64
+	MaxTurns int // Turns before a game is declared a tie, 0 for no limit
+
+	// OnGameOver, if set, is called after each game with its final state
+	// and the result of its last step
+	OnGameOver func(idx int, state *game.GameState, last game.StepResult)
+}
+
+// Host plays a match on conn with policy controlling snake 0 and the remote
+// client controlling snake 1
+func Host(conn *Conn, policy ai.Policy, cfg HostConfig) (Result, error) {
+	var result Result
+
+	hello, err := conn.Receive(MsgHello)
+	if err != nil {
+		return result, fmt.Errorf("handshake: %w", err)
+	}
+	if hello.Version != ProtocolVersion {
+		return result, fmt.Errorf("client speaks protocol version %d, host speaks %d", hello.Version, ProtocolVersion)
+	}
+	if hello.Name == "" {
+		return result, fmt.Errorf("handshake: client sent no name")
+	}
+	result.Opponent = hello.Name
+
+	g := game.NewGame(cfg.Game, cfg.Seed)
+	for idx := 1; idx <= cfg.Games; idx++ {
+		g.Reset()
+		start := Message{Type: MsgStart, Game: idx, Snake: 1, Width: cfg.Game.BoardWidth, Height: cfg.Game.BoardHeight}
+		if err := conn.Send(start); err != nil {
+			return result, err
+		}
+
+		var last game.StepResult
+		for !g.State.GameOver && (cfg.MaxTurns <= 0 || g.State.Turn < cfg.MaxTurns) {
+			if err := conn.Send(Message{Type: MsgState, Game: idx, State: g.State}); err != nil {
+				return result, err
+			}
+			local := policy.Act(g.State, 0)
+
+			move, err := conn.Receive(MsgMove)
+			if err != nil {
+				return result, fmt.Errorf("game %d turn %d: %w", idx, g.State.Turn, err)
+			}
+			if move.Game != idx || move.Turn != g.State.Turn {
+				return result, fmt.Errorf("game %d turn %d: client answered for game %d turn %d", idx, g.State.Turn, move.Game, move.Turn)
+			}
+			remote, err := ai.ParseAction(move.Action)
+			if err != nil {
+				return result, fmt.Errorf("game %d turn %d: %w", idx, g.State.Turn, err)
+			}
+
+			last = g.Step([2]game.Direction{
+				ai.ActionToDirection(g.State.Snakes[0].Direction, local),
+				ai.ActionToDirection(g.State.Snakes[1].Direction, remote),
+			})
+		}
+
+		winner := -1
+		if g.State.GameOver {
+			winner = g.State.Winner
+		}
+		switch winner {
+		case 0, 1:
+			result.Wins[winner]++
+		default:
+			result.Ties++
+		}
+		result.Games++
+		if cfg.OnGameOver != nil {
+			cfg.OnGameOver(idx, g.State, last)
+		}
+		if err := conn.Send(Message{Type: MsgEnd, Game: idx, Winner: winner}); err != nil {
+			return result, err
+		}
+	}
+
+	return result, conn.Send(Message{Type: MsgDone})
+}
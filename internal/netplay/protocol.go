@@ -0,0 +1,94 @@
+// Package netplay runs lockstep matches between policies in separate
+// processes. The host owns the authoritative game; the client receives the
+// full state every turn and answers with a move, so the two sides may run
+// different code versions or models as long as they share the protocol.
+//
+// Messages are newline-delimited JSON over TCP:
+//
+//	client -> host  hello  {name, version}
+//	host -> client  start  {game, snake, width, height}
+//	host -> client  state  {game, state}         client answers move {game, turn, action}
+//	host -> client  end    {game, winner}
+//	host -> client  done   {}
+package netplay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"autonomous-snake/internal/game"
+)
+
+// ProtocolVersion is bumped whenever a message changes incompatibly
+const ProtocolVersion = 1
+
+// Message types
+const (
+	MsgHello = "hello"
+	MsgStart = "start"
+	MsgState = "state"
+	MsgMove  = "move"
+	MsgEnd   = "end"
+	MsgDone  = "done"
+)
+
+// Message is the single envelope used for every message; unused fields are
+// omitted
+type Message struct {
+	Type    string          `json:"type"`
+	Name    string          `json:"name,omitempty"`
+	Version int             `json:"version,omitempty"`
+	Game    int             `json:"game,omitempty"`
+	Snake   int             `json:"snake,omitempty"`
+	Width   int             `json:"width,omitempty"`
+	Height  int             `json:"height,omitempty"`
+	Turn    int             `json:"turn,omitempty"`
+	Action  string          `json:"action,omitempty"`
+	Winner  int             `json:"winner,omitempty"`
+	State   *game.GameState `json:"state,omitempty"`
+}
+
+// Conn exchanges messages over a network connection
+type Conn struct {
+	conn    net.Conn
+	enc     *json.Encoder
+	dec     *json.Decoder
+	Timeout time.Duration // Per-message read deadline, 0 for none
+}
+
+// NewConn wraps a network connection
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(bufio.NewReader(conn)),
+	}
+}
+
+// Send writes one message
+func (c *Conn) Send(msg Message) error {
+	return c.enc.Encode(msg)
+}
+
+// Receive reads one message, checking its type if want is not empty
+func (c *Conn) Receive(want string) (Message, error) {
+	if c.Timeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.Timeout))
+	}
+	var msg Message
+	if err := c.dec.Decode(&msg); err != nil {
+		return msg, err
+	}
+	if want != "" && msg.Type != want {
+		return msg, fmt.Errorf("expected %q message, got %q", want, msg.Type)
+	}
+	return msg, nil
+}
+
+// Close closes the underlying connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
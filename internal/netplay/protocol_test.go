@@ -0,0 +1,50 @@
+package netplay
+
+import (
+	"maps"
+	"net"
+	"testing"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	// Food lifetimes keep a map of pellet expiries in the state
+	cfg := config.DefaultGameConfig()
+	cfg.FoodCount = 3
+	cfg.Spawn.Lifetime = 12
+	g := game.NewGame(cfg, 5)
+	g.Step([2]game.Direction{g.State.Snakes[0].Direction, g.State.Snakes[1].Direction})
+	if len(g.State.FoodExpires) == 0 {
+		t.Fatal("no food expiries to send")
+	}
+
+	a, b := net.Pipe()
+	host, client := NewConn(a), NewConn(b)
+	defer host.Close()
+	defer client.Close()
+	sent := make(chan error, 1)
+	go func() {
+		err := host.Send(Message{Type: MsgState, Game: 1, State: g.State})
+		if err != nil {
+			host.Close()
+		}
+		sent <- err
+	}()
+
+	msg, err := client.Receive(MsgState)
+	if err := <-sent; err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := msg.State
+	if !maps.Equal(got.FoodExpires, g.State.FoodExpires) || got.FoodLifespan != g.State.FoodLifespan {
+		t.Errorf("expiries %v lifespan %d, sent %v lifespan %d", got.FoodExpires, got.FoodLifespan, g.State.FoodExpires, g.State.FoodLifespan)
+	}
+	if got.Turn != g.State.Turn || got.Hash() != g.State.Hash() {
+		t.Errorf("received state differs from the sent one")
+	}
+}
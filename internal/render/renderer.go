@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"image/color"
 	"math/rand"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -12,6 +13,7 @@ import (
 
 	"autonomous-snake/internal/ai"
 	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/eval"
 	"autonomous-snake/internal/game"
 )
 
@@ -22,19 +24,21 @@ var ErrQuit = errors.New("user quit game")
 var (
 	ColorBackground = color.RGBA{20, 20, 20, 255}
 	ColorGrid       = color.RGBA{40, 40, 40, 255}
-	ColorSnake0     = color.RGBA{76, 175, 80, 255}  // Green
+	ColorSnake0     = color.RGBA{76, 175, 80, 255} // Green
 	ColorSnake0Head = color.RGBA{129, 199, 132, 255}
 	ColorSnake1     = color.RGBA{33, 150, 243, 255} // Blue
 	ColorSnake1Head = color.RGBA{100, 181, 246, 255}
-	ColorFood       = color.RGBA{244, 67, 54, 255}  // Red
+	ColorFood       = color.RGBA{244, 67, 54, 255} // Red
 	ColorDead       = color.RGBA{128, 128, 128, 255}
+	ColorWall       = color.RGBA{96, 96, 96, 255}
+	ColorGolden     = color.RGBA{255, 193, 7, 255} // Amber
 	ColorText       = color.RGBA{255, 255, 255, 255}
 )
 
 // GameRenderer handles rendering the game using Ebiten
 type GameRenderer struct {
 	game     *game.Game
-	agent    *ai.DQNAgent
+	policies [2]ai.Policy // nil snakes move randomly
 	cfg      config.GameConfig
 	trainCfg config.TrainingConfig
 
@@ -52,40 +56,74 @@ type GameRenderer struct {
 	speed        int // 1-5, where 3 is normal
 
 	// Stats
-	gamesPlayed int
-	wins        [2]int
-	ties        int
+	stats sessionStats
 
 	// Game over pause
 	gameOverPause bool
 	gameOverTicks int
+
+	// Actions for the current turn, computed once and reused across sub-ticks
+	planned     [2]ai.Action
+	havePlanned bool
+
+	// Heuristic position scores for the current turn, shown in the header
+	evals [2]float64
+
+	// Result of the latest step, passed to OnGameOver
+	lastResult game.StepResult
+
+	// OnGameOver, if set, is called once per finished game with its final
+	// state and the result of its last step
+	OnGameOver func(state *game.GameState, last game.StepResult)
+
+	// OnStep, if set, is called after every turn with both snakes'
+	// actions, and OnReset before the game is reset for the next one,
+	// whether it finished or was restarted
+	OnStep  func(actions [2]ai.Action)
+	OnReset func()
+
+	// MaxSteps ends a game after this many turns and StallTurns once
+	// neither snake has eaten for this many turns; either way the higher
+	// score wins. Zero disables the limit.
+	MaxSteps   int
+	StallTurns int
+
+	// Turn of the latest meal, and why the current game ended early
+	lastMeal  int
+	endReason string
 }
 
-// NewRenderer creates a new game renderer
-func NewRenderer(g *game.Game, agent *ai.DQNAgent, cfg config.GameConfig) *GameRenderer {
+// minScreenWidth is the narrowest window that fits both snakes' stats
+// side by side
+const minScreenWidth = 440
+
+// NewRenderer creates a new game renderer with one policy per snake; a nil
+// policy plays random moves
+func NewRenderer(g *game.Game, policies [2]ai.Policy, cfg config.GameConfig) *GameRenderer {
 	cellSize := cfg.GridSize
 	boardWidth := cfg.BoardWidth * cellSize
 	boardHeight := cfg.BoardHeight * cellSize
 
-	// Add padding for UI (top header + bottom stats/controls)
-	screenWidth := boardWidth + 40
+	// Add padding for UI (top header + bottom stats/controls), widening
+	// narrow boards' windows to fit the stats with the board centered
+	screenWidth := max(boardWidth+40, minScreenWidth)
 	screenHeight := boardHeight + 100
 
 	return &GameRenderer{
 		game:         g,
-		agent:        agent,
+		policies:     policies,
 		cfg:          cfg,
 		trainCfg:     config.DefaultTrainingConfig(),
 		screenWidth:  screenWidth,
 		screenHeight: screenHeight,
 		cellSize:     cellSize,
-		offsetX:      20,
+		offsetX:      (screenWidth - boardWidth) / 2,
 		offsetY:      60,
 		ticksPerStep: 10,
 		tickCount:    0,
 		paused:       false,
 		speed:        3,
-		gamesPlayed:  0,
+		stats:        newSessionStats(),
 	}
 }
 
@@ -109,11 +147,17 @@ func (r *GameRenderer) Update() error {
 		if r.gameOverTicks >= gameOverDelayTicks {
 			r.gameOverPause = false
 			r.gameOverTicks = 0
-			r.game.Reset()
+			r.resetGame()
 		}
 		return nil
 	}
 
+	// Plan on the first sub-tick of each turn so the step tick only has
+	// to apply the cached actions
+	if !r.game.State.GameOver && !r.havePlanned {
+		r.planActions()
+	}
+
 	r.tickCount++
 	if r.tickCount < r.ticksPerStep {
 		return nil
@@ -123,13 +167,9 @@ func (r *GameRenderer) Update() error {
 	// Check if game is over
 	if r.game.State.GameOver {
 		// Record result
-		r.gamesPlayed++
-		if r.game.State.Winner == 0 {
-			r.wins[0]++
-		} else if r.game.State.Winner == 1 {
-			r.wins[1]++
-		} else {
-			r.ties++
+		r.stats.recordGame(r.game.State)
+		if r.OnGameOver != nil {
+			r.OnGameOver(r.game.State, r.lastResult)
 		}
 
 		// Start game over pause
@@ -138,29 +178,78 @@ func (r *GameRenderer) Update() error {
 		return nil
 	}
 
-	// Get AI actions
+	// Convert to directions
 	state := r.game.State
-	state0 := ai.EncodeState(state, 0)
-	state1 := ai.EncodeState(state, 1)
+	dir0 := ai.ActionToDirection(state.Snakes[0].Direction, r.planned[0])
+	dir1 := ai.ActionToDirection(state.Snakes[1].Direction, r.planned[1])
 
-	var action0, action1 ai.Action
-	if r.agent != nil {
-		action0 = r.agent.SelectActionGreedy(state0)
-		action1 = r.agent.SelectActionGreedy(state1)
-	} else {
-		// Random actions if no agent
-		action0 = ai.Action(rand.Intn(int(ai.NumActions)))
-		action1 = ai.Action(rand.Intn(int(ai.NumActions)))
+	// Step game
+	result := r.game.Step([2]game.Direction{dir0, dir1})
+	if r.OnStep != nil {
+		r.OnStep(r.planned)
 	}
+	r.stats.recordStep(result)
+	r.lastResult = result
+	r.havePlanned = false
+	r.checkLimits(result)
 
-	// Convert to directions
-	dir0 := ai.ActionToDirection(state.Snakes[0].Direction, action0)
-	dir1 := ai.ActionToDirection(state.Snakes[1].Direction, action1)
+	return nil
+}
 
-	// Step game
-	r.game.Step([2]game.Direction{dir0, dir1})
+// planActions asks each snake's policy for its action this turn
+func (r *GameRenderer) planActions() {
+	state := r.game.State
+	r.evals = [2]float64{eval.Evaluate(state, 0), eval.Evaluate(state, 1)}
+
+	// Two snakes playing one model share a batched forward pass
+	a, aNet := r.policies[0].(*ai.NetworkPolicy)
+	b, bNet := r.policies[1].(*ai.NetworkPolicy)
+	if aNet && bNet {
+		r.planned = ai.ActPair([2]*ai.NetworkPolicy{a, b}, state)
+		r.havePlanned = true
+		return
+	}
 
-	return nil
+	for i, policy := range r.policies {
+		if policy != nil {
+			r.planned[i] = policy.Act(state, i)
+		} else {
+			// Random actions if no policy
+			r.planned[i] = ai.Action(rand.Intn(int(ai.NumActions)))
+		}
+	}
+	r.havePlanned = true
+}
+
+// checkLimits ends a game that has run too long or in which nobody eats
+func (r *GameRenderer) checkLimits(result game.StepResult) {
+	state := r.game.State
+	if result.AteFood[0] || result.AteFood[1] {
+		r.lastMeal = state.Turn
+	}
+	if state.GameOver {
+		return
+	}
+	switch {
+	case r.MaxSteps > 0 && state.Turn >= r.MaxSteps:
+		r.endReason = "turn limit"
+	case r.StallTurns > 0 && state.Turn-r.lastMeal >= r.StallTurns:
+		r.endReason = "stalled"
+	default:
+		return
+	}
+	r.game.EndByScore()
+}
+
+// resetGame starts a new game and drops actions planned for the old one
+func (r *GameRenderer) resetGame() {
+	if r.OnReset != nil {
+		r.OnReset()
+	}
+	r.game.Reset()
+	r.havePlanned = false
+	r.lastMeal = 0
+	r.endReason = ""
 }
 
 // handleInput processes keyboard input
@@ -188,7 +277,7 @@ func (r *GameRenderer) handleInput() error {
 
 	// Reset game
 	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
-		r.game.Reset()
+		r.resetGame()
 	}
 
 	// Quit
@@ -213,7 +302,8 @@ func (r *GameRenderer) Draw(screen *ebiten.Image) {
 	// Draw grid
 	r.drawGrid(screen)
 
-	// Draw food
+	// Draw map obstacles and food
+	r.drawObstacles(screen)
 	r.drawFood(screen)
 
 	// Draw snakes
@@ -240,14 +330,22 @@ func (r *GameRenderer) drawGrid(screen *ebiten.Image) {
 	}
 }
 
-// drawFood draws the food
-func (r *GameRenderer) drawFood(screen *ebiten.Image) {
-	if !r.game.State.Food.Active {
-		return
+// drawObstacles draws the walls of the board map
+func (r *GameRenderer) drawObstacles(screen *ebiten.Image) {
+	for _, pos := range r.game.State.Obstacles {
+		r.drawCell(screen, pos.X, pos.Y, ColorWall, 0)
 	}
+}
 
-	pos := r.game.State.Food.Position
-	r.drawCell(screen, pos.X, pos.Y, ColorFood, 2)
+// drawFood draws every pellet on the board, golden ones in their own color
+func (r *GameRenderer) drawFood(screen *ebiten.Image) {
+	for _, pos := range r.game.State.FoodPositions() {
+		c := ColorFood
+		if r.game.State.IsGolden(pos) {
+			c = ColorGolden
+		}
+		r.drawCell(screen, pos.X, pos.Y, c, 2)
+	}
 }
 
 // drawSnake draws a snake
@@ -300,7 +398,7 @@ func (r *GameRenderer) drawUI(screen *ebiten.Image) {
 	// Snake stats
 	snake0Info := fmt.Sprintf("Green Snake: Length %d, Score %d", state.Snakes[0].Length(), state.Snakes[0].Score)
 	if !state.Snakes[0].Alive {
-		snake0Info += " [DEAD]"
+		snake0Info += deadLabel(state, 0)
 	}
 	ebitenutil.DebugPrintAt(screen, snake0Info, 10, 30)
 
@@ -309,10 +407,14 @@ func (r *GameRenderer) drawUI(screen *ebiten.Image) {
 
 	snake1Info := fmt.Sprintf("Blue Snake: Length %d, Score %d", state.Snakes[1].Length(), state.Snakes[1].Score)
 	if !state.Snakes[1].Alive {
-		snake1Info += " [DEAD]"
+		snake1Info += deadLabel(state, 1)
 	}
 	ebitenutil.DebugPrintAt(screen, snake1Info, r.screenWidth/2+5, 30)
 
+	// Heuristic evaluation of the position from each snake's view
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Eval: %+.2f", r.evals[0]), 10, 44)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Eval: %+.2f", r.evals[1]), r.screenWidth/2+5, 44)
+
 	// Game over message
 	if state.GameOver {
 		var msg string
@@ -323,15 +425,34 @@ func (r *GameRenderer) drawUI(screen *ebiten.Image) {
 		} else {
 			msg = "TIE!"
 		}
+		if r.endReason != "" {
+			msg += " (" + r.endReason + ", by score)"
+		} else if state.Repeated {
+			msg += " (repeated position)"
+		} else if state.Respawn.Enabled {
+			msg += " (score target)"
+		}
 		centerX := r.screenWidth/2 - len(msg)*3
 		centerY := r.screenHeight / 2
 		ebitenutil.DebugPrintAt(screen, msg, centerX, centerY)
+
+		// Why each snake that died on the last turn died
+		var causes []string
+		for i, name := range []string{"Green", "Blue"} {
+			if cause := r.lastResult.DeathCause(i); r.lastResult.Died[i] && cause != game.NoCollision {
+				causes = append(causes, name+": "+cause.String())
+			}
+		}
+		if len(causes) > 0 {
+			msg := strings.Join(causes, "   ")
+			ebitenutil.DebugPrintAt(screen, msg, r.screenWidth/2-len(msg)*3, centerY+16)
+		}
 	}
 
 	// Bottom stats (first line below board)
 	statsY := r.offsetY + r.cfg.BoardHeight*r.cellSize + 8
 	statsInfo := fmt.Sprintf("Games: %d   Green Wins: %d   Blue Wins: %d   Ties: %d   Turn: %d",
-		r.gamesPlayed, r.wins[0], r.wins[1], r.ties, state.Turn)
+		r.stats.gamesPlayed, r.stats.wins[0], r.stats.wins[1], r.stats.ties, state.Turn)
 	ebitenutil.DebugPrintAt(screen, statsInfo, 10, statsY)
 
 	// Controls help (second line below board)
@@ -340,6 +461,15 @@ func (r *GameRenderer) drawUI(screen *ebiten.Image) {
 	ebitenutil.DebugPrintAt(screen, help, 10, helpY)
 }
 
+// deadLabel marks dead snake id in the stats line, with the turns until
+// it respawns in an endless match
+func deadLabel(state *game.GameState, id int) string {
+	if n := state.RespawnIn(id); n >= 0 {
+		return fmt.Sprintf(" [RESPAWN IN %d]", n)
+	}
+	return " [DEAD]"
+}
+
 // Layout returns the game's screen dimensions
 func (r *GameRenderer) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return r.screenWidth, r.screenHeight
@@ -0,0 +1,99 @@
+package render
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"autonomous-snake/internal/game"
+)
+
+// SessionSummary describes everything played in one renderer session
+type SessionSummary struct {
+	StartedAt   time.Time         `json:"started_at"`
+	EndedAt     time.Time         `json:"ended_at"`
+	Seed        int64             `json:"seed"`
+	Models      []string          `json:"models"`
+	GamesPlayed int               `json:"games_played"`
+	Wins        [2]int            `json:"wins"`
+	Ties        int               `json:"ties"`
+	AvgLength   [2]float64        `json:"avg_length"` // Final snake length per game
+	AvgTurns    float64           `json:"avg_turns"`
+	DeathCauses [2]map[string]int `json:"death_causes"` // Counted by game.CollisionType name
+}
+
+// sessionStats accumulates per-game results for the session summary
+type sessionStats struct {
+	startedAt   time.Time
+	gamesPlayed int
+	wins        [2]int
+	ties        int
+	totalLength [2]int
+	totalTurns  int
+	deathCauses [2]map[string]int
+}
+
+// newSessionStats creates empty session statistics
+func newSessionStats() sessionStats {
+	return sessionStats{
+		startedAt:   time.Now(),
+		deathCauses: [2]map[string]int{{}, {}},
+	}
+}
+
+// recordStep counts the causes of any deaths in a step result
+func (s *sessionStats) recordStep(result game.StepResult) {
+	for i := 0; i < 2; i++ {
+		if cause := result.DeathCause(i); result.Died[i] && cause != game.NoCollision {
+			s.deathCauses[i][cause.String()]++
+		}
+	}
+}
+
+// recordGame adds a finished game's result, lengths and duration
+func (s *sessionStats) recordGame(state *game.GameState) {
+	s.gamesPlayed++
+	if state.Winner == 0 || state.Winner == 1 {
+		s.wins[state.Winner]++
+	} else {
+		s.ties++
+	}
+	for i := 0; i < 2; i++ {
+		s.totalLength[i] += state.Snakes[i].Length()
+	}
+	s.totalTurns += state.Turn
+}
+
+// Summary returns the statistics for every game finished so far
+func (r *GameRenderer) Summary() SessionSummary {
+	return r.stats.summary(time.Now())
+}
+
+// summary returns the statistics gathered so far for a session ending at
+// endedAt
+func (s *sessionStats) summary(endedAt time.Time) SessionSummary {
+	summary := SessionSummary{
+		StartedAt:   s.startedAt,
+		EndedAt:     endedAt,
+		GamesPlayed: s.gamesPlayed,
+		Wins:        s.wins,
+		Ties:        s.ties,
+		DeathCauses: s.deathCauses,
+	}
+	if s.gamesPlayed > 0 {
+		for i := 0; i < 2; i++ {
+			summary.AvgLength[i] = float64(s.totalLength[i]) / float64(s.gamesPlayed)
+		}
+		summary.AvgTurns = float64(s.totalTurns) / float64(s.gamesPlayed)
+	}
+	return summary
+}
+
+// WriteSummary writes a session summary as indented JSON
+func WriteSummary(path string, summary SessionSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+func TestSessionSummary(t *testing.T) {
+	stats := newSessionStats()
+
+	// Both snakes run at a wall until the game ends: snake 1 reaches the
+	// top first, then snake 0 the bottom, then both the top at once
+	games := []struct {
+		moves  [2]game.Direction
+		winner int
+	}{
+		{[2]game.Direction{game.Up, game.Down}, 0},
+		{[2]game.Direction{game.Down, game.Up}, 1},
+		{[2]game.Direction{game.Up, game.Up}, -1},
+	}
+	var lengths [2]int
+	turns := 0
+	for i, tt := range games {
+		g := game.NewGame(config.DefaultGameConfig(), int64(i+1))
+		for !g.State.GameOver {
+			stats.recordStep(g.Step(tt.moves))
+		}
+		if g.State.Winner != tt.winner {
+			t.Fatalf("game %d: snake %d won, want %d", i+1, g.State.Winner, tt.winner)
+		}
+		stats.recordGame(g.State)
+		for id, snake := range g.State.Snakes {
+			lengths[id] += snake.Length()
+		}
+		turns += g.State.Turn
+	}
+
+	end := stats.startedAt.Add(time.Minute)
+	summary := stats.summary(end)
+	summary.Seed = 7
+	summary.Models = []string{"model.gob"}
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := WriteSummary(path, summary); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		StartedAt   time.Time         `json:"started_at"`
+		EndedAt     time.Time         `json:"ended_at"`
+		Seed        int64             `json:"seed"`
+		Models      []string          `json:"models"`
+		GamesPlayed int               `json:"games_played"`
+		Wins        [2]int            `json:"wins"`
+		Ties        int               `json:"ties"`
+		AvgLength   [2]float64        `json:"avg_length"`
+		AvgTurns    float64           `json:"avg_turns"`
+		DeathCauses [2]map[string]int `json:"death_causes"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.GamesPlayed != 3 || got.Wins != [2]int{1, 1} || got.Ties != 1 {
+		t.Errorf("played %d, wins %v, ties %d; want 3, [1 1], 1", got.GamesPlayed, got.Wins, got.Ties)
+	}
+	if got.Seed != 7 || len(got.Models) != 1 || got.Models[0] != "model.gob" {
+		t.Errorf("seed %d, models %v", got.Seed, got.Models)
+	}
+	if !got.StartedAt.Equal(stats.startedAt) || !got.EndedAt.Equal(end) {
+		t.Errorf("session from %v to %v, want %v to %v", got.StartedAt, got.EndedAt, stats.startedAt, end)
+	}
+	for id := range lengths {
+		if want := float64(lengths[id]) / 3; got.AvgLength[id] != want {
+			t.Errorf("snake %d: average length %v, want %v", id, got.AvgLength[id], want)
+		}
+	}
+	if want := float64(turns) / 3; got.AvgTurns != want {
+		t.Errorf("average turns %v, want %v", got.AvgTurns, want)
+	}
+	for id, want := range [2]int{2, 2} {
+		if n := got.DeathCauses[id]["wall"]; n != want || len(got.DeathCauses[id]) != 1 {
+			t.Errorf("snake %d: death causes %v, want %d wall deaths", id, got.DeathCauses[id], want)
+		}
+	}
+
+	// No games, no averages
+	empty := newSessionStats()
+	if s := empty.summary(end); s.GamesPlayed != 0 || s.AvgTurns != 0 || s.AvgLength != [2]float64{} {
+		t.Errorf("empty session: %+v", s)
+	}
+}
@@ -0,0 +1,395 @@
+// Package replay stores finished games compactly: the game seed and
+// configuration plus run-length encoded action pairs, which is enough to
+// replay a game move for move. Each record carries a CRC32 of its bytes and
+// the hash of the final position, so corrupted files and replays that no
+// longer reproduce under changed game rules are both detected.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// magic starts every replay file; the last byte is the format version.
+// Version 2 added the turn and repeat limits; version 1 files still read,
+// as games without either.
+var magic = []byte("SLRP\x02")
+
+// maxRecordSize bounds a single record so a corrupt length can't trigger a
+// huge allocation
+const maxRecordSize = 1 << 24
+
+var (
+	// ErrCorrupt is returned for records whose checksum doesn't match
+	ErrCorrupt = errors.New("replay: corrupt record")
+	// ErrDiverged is returned when replaying doesn't reach the recorded
+	// final position
+	ErrDiverged = errors.New("replay: game diverged from the recording")
+)
+
+// Replay is one recorded game
+type Replay struct {
+	Index   int               // Episode or game number within its session
+	Seed    int64             // Seed the game was created or reseeded with
+	Game    config.GameConfig // Everything but GridSize is stored
+	Actions [][2]ai.Action    // Both snakes' actions, one pair per turn
+	Winner  int               // -1 for a tie or unfinished game
+	Hash    uint64            // game.GameState.Hash of the final position
+}
+
+// Add records one turn's actions
+func (r *Replay) Add(actions [2]ai.Action) {
+	r.Actions = append(r.Actions, actions)
+}
+
+// Finish records the outcome of the game from its final state
+func (r *Replay) Finish(state *game.GameState) {
+	r.Winner = -1
+	if state.GameOver {
+		r.Winner = state.Winner
+	}
+	r.Hash = state.Hash()
+}
+
+// Play replays the game and returns the state before every turn and after
+// the last one. It returns ErrDiverged, along with the frames, if the final
+// position doesn't match the recording.
+func (r *Replay) Play() ([]*game.GameState, error) {
+	g := game.NewGame(r.Game, r.Seed)
+	frames := []*game.GameState{g.State.Clone()}
+	for _, actions := range r.Actions {
+		var dirs [2]game.Direction
+		for i, action := range actions {
+			dirs[i] = ai.ActionToDirection(g.State.Snakes[i].Direction, action)
+		}
+		g.Step(dirs)
+		frames = append(frames, g.State.Clone())
+	}
+	if g.State.Hash() != r.Hash {
+		return frames, ErrDiverged
+	}
+	return frames, nil
+}
+
+// Writer appends replays to a stream
+type Writer struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriter writes the file header to w and returns a replay writer. Call
+// Flush when done.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(magic); err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+// NewAppendWriter returns a replay writer adding to the end of rw, a
+// replay file opened for reading and appending, after checking its header.
+// An empty file gets one, as from NewWriter. Call Flush when done.
+func NewAppendWriter(rw io.ReadWriter) (*Writer, error) {
+	header := make([]byte, len(magic))
+	switch _, err := io.ReadFull(rw, header); {
+	case errors.Is(err, io.EOF):
+		return NewWriter(rw)
+	case err != nil:
+		return nil, fmt.Errorf("replay: reading header: %w", err)
+	case !bytes.Equal(header, magic):
+		return nil, fmt.Errorf("replay: can only append to a version %d replay file", magic[len(magic)-1])
+	}
+	return &Writer{w: bufio.NewWriter(rw)}, nil
+}
+
+// Write appends one replay
+func (w *Writer) Write(r *Replay) error {
+	body := r.encode(w.buf[:0])
+	w.buf = body
+
+	var frame [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(frame[:], uint64(len(body)))
+	if _, err := w.w.Write(frame[:n]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(body); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(body))
+	_, err := w.w.Write(sum[:])
+	return err
+}
+
+// Flush writes any buffered replays to the underlying writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads replays written by Writer
+type Reader struct {
+	r       *bufio.Reader
+	version byte // Format version of the file
+}
+
+// NewReader checks the file header and returns a replay reader
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("replay: reading header: %w", err)
+	}
+	prefix, version := header[:len(magic)-1], header[len(magic)-1]
+	if string(prefix) != string(magic[:len(magic)-1]) || version < 1 || version > magic[len(magic)-1] {
+		return nil, fmt.Errorf("replay: not a replay file or unsupported version")
+	}
+	return &Reader{r: br, version: version}, nil
+}
+
+// Next returns the next replay, or io.EOF after the last one
+func (rd *Reader) Next() (*Replay, error) {
+	size, err := binary.ReadUvarint(rd.r)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRecordSize {
+		return nil, ErrCorrupt
+	}
+	record := make([]byte, size+4)
+	if _, err := io.ReadFull(rd.r, record); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body := record[:size]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(record[size:]) {
+		return nil, ErrCorrupt
+	}
+	r, err := decode(body, rd.version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return r, nil
+}
+
+// ReadAll reads every replay from r
+func ReadAll(r io.Reader) ([]*Replay, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var replays []*Replay
+	for {
+		replay, err := rd.Next()
+		if err == io.EOF {
+			return replays, nil
+		}
+		if err != nil {
+			return replays, fmt.Errorf("replay %d: %w", len(replays), err)
+		}
+		replays = append(replays, replay)
+	}
+}
+
+// encode appends the replay's body to buf. Action pairs are packed into one
+// code per turn (3 * snake 0's action + snake 1's) and stored as runs of
+// equal codes, which are long because snakes mostly go straight. A ruleset
+// other than the standard one follows the hash, then a board map's name,
+// then food values when they change the game, then the respawn settings
+// of an endless match, so standard replays on an open board read the same
+// as before any of these existed.
+func (r *Replay) encode(buf []byte) []byte {
+	putUvarint := func(v int) { buf = binary.AppendUvarint(buf, uint64(v)) }
+	buf = binary.AppendVarint(buf, r.Seed)
+	putUvarint(r.Index)
+	putUvarint(r.Game.BoardWidth)
+	putUvarint(r.Game.BoardHeight)
+	putUvarint(r.Game.FoodCount)
+	putUvarint(len(r.Game.Spawn.Pattern))
+	buf = append(buf, r.Game.Spawn.Pattern...)
+	putUvarint(r.Game.Spawn.ClusterRadius)
+	putUvarint(r.Game.Spawn.Lifetime)
+	putUvarint(r.Game.Spawn.RelocateEvery)
+	putUvarint(r.Game.MaxTurns)
+	putUvarint(r.Game.RepeatLimit)
+	buf = binary.AppendVarint(buf, int64(r.Winner))
+	putUvarint(len(r.Actions))
+
+	var runs [][2]int // code, length
+	for _, pair := range r.Actions {
+		code := int(pair[0])*int(ai.NumActions) + int(pair[1])
+		if n := len(runs); n > 0 && runs[n-1][0] == code {
+			runs[n-1][1]++
+		} else {
+			runs = append(runs, [2]int{code, 1})
+		}
+	}
+	putUvarint(len(runs))
+	for _, run := range runs {
+		buf = append(buf, byte(run[0]))
+		putUvarint(run[1])
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, r.Hash)
+	// Golden values only matter when pellets can be golden
+	value := r.Game.Value.Points > 1 || r.Game.Value.Growth > 1 || r.Game.Value.GoldenChance > 0
+	respawn := r.Game.Respawn.Enabled
+	if r.Game.Rules != "" || r.Game.Map != "" || value || respawn {
+		putUvarint(len(r.Game.Rules))
+		buf = append(buf, r.Game.Rules...)
+	}
+	if r.Game.Map != "" || value || respawn {
+		putUvarint(len(r.Game.Map))
+		buf = append(buf, r.Game.Map...)
+	}
+	if value || respawn {
+		putUvarint(r.Game.Value.Points)
+		putUvarint(r.Game.Value.Growth)
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(r.Game.Value.GoldenChance))
+		putUvarint(r.Game.Value.GoldenPoints)
+		putUvarint(r.Game.Value.GoldenGrowth)
+	}
+	if respawn {
+		putUvarint(r.Game.Respawn.Delay)
+		putUvarint(r.Game.Respawn.ScoreTarget)
+	}
+	return buf
+}
+
+// decode parses a replay body written by encode, or by the encode of an
+// earlier format version
+func decode(body []byte, version byte) (*Replay, error) {
+	d := decoder{buf: body}
+	r := &Replay{}
+	r.Seed = d.varint()
+	r.Index = d.uvarint()
+	r.Game.BoardWidth = d.uvarint()
+	r.Game.BoardHeight = d.uvarint()
+	r.Game.FoodCount = d.uvarint()
+	r.Game.Spawn.Pattern = string(d.next(d.uvarint()))
+	r.Game.Spawn.ClusterRadius = d.uvarint()
+	r.Game.Spawn.Lifetime = d.uvarint()
+	r.Game.Spawn.RelocateEvery = d.uvarint()
+	if version >= 2 {
+		r.Game.MaxTurns = d.uvarint()
+		r.Game.RepeatLimit = d.uvarint()
+	}
+	r.Winner = int(d.varint())
+
+	turns, runs := d.uvarint(), d.uvarint()
+	if d.err == nil && turns > maxRecordSize {
+		return nil, fmt.Errorf("%d turns", turns)
+	}
+	r.Actions = make([][2]ai.Action, 0, turns)
+	for i := 0; i < runs && d.err == nil; i++ {
+		code := -1
+		if b := d.next(1); b != nil {
+			code = int(b[0])
+		}
+		length := d.uvarint()
+		if d.err != nil {
+			break
+		}
+		if code < 0 || code >= int(ai.NumActions*ai.NumActions) || len(r.Actions)+length > turns {
+			return nil, fmt.Errorf("invalid run")
+		}
+		pair := [2]ai.Action{ai.Action(code / int(ai.NumActions)), ai.Action(code % int(ai.NumActions))}
+		for j := 0; j < length; j++ {
+			r.Actions = append(r.Actions, pair)
+		}
+	}
+	hash := d.next(8)
+	if d.err == nil && len(d.buf) > 0 {
+		r.Game.Rules = string(d.next(d.uvarint()))
+	}
+	if d.err == nil && len(d.buf) > 0 {
+		r.Game.Map = string(d.next(d.uvarint()))
+	}
+	if d.err == nil && len(d.buf) > 0 {
+		r.Game.Value.Points = d.uvarint()
+		r.Game.Value.Growth = d.uvarint()
+		if chance := d.next(8); chance != nil {
+			r.Game.Value.GoldenChance = math.Float64frombits(binary.LittleEndian.Uint64(chance))
+		}
+		r.Game.Value.GoldenPoints = d.uvarint()
+		r.Game.Value.GoldenGrowth = d.uvarint()
+	}
+	if d.err == nil && len(d.buf) > 0 {
+		r.Game.Respawn.Enabled = true
+		r.Game.Respawn.Delay = d.uvarint()
+		r.Game.Respawn.ScoreTarget = d.uvarint()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := r.Game.Value.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := game.LookupRules(r.Game.Rules); err != nil {
+		return nil, err
+	}
+	if r.Game.Map != "" {
+		if _, err := game.LoadMap(r.Game.Map); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.Actions) != turns || len(d.buf) != 0 {
+		return nil, fmt.Errorf("length mismatch")
+	}
+	r.Hash = binary.LittleEndian.Uint64(hash)
+	return r, nil
+}
+
+// decoder reads fields from a record body, remembering the first error
+type decoder struct {
+	buf []byte
+	err error
+}
+
+// uvarint reads a non-negative integer
+func (d *decoder) uvarint() int {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 || v > math.MaxInt32 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return int(v)
+}
+
+// varint reads a signed integer
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// next reads n raw bytes
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || n > len(d.buf) {
+		d.fail()
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+// fail records a truncated or malformed field
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = fmt.Errorf("truncated record")
+	}
+}
@@ -0,0 +1,201 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// record plays a game of random moves and records it
+func record(t *testing.T, seed int64) *Replay {
+	t.Helper()
+	cfg := config.GameConfig{BoardWidth: 12, BoardHeight: 10, FoodCount: 2}
+	if seed%2 == 0 {
+		// Even seeds play on a wrapped board, so the ruleset round-trips too
+		cfg.Rules = game.RulesWrapped
+	}
+	if seed%3 == 0 {
+		// and multiples of three on a board map
+		cfg.Map, cfg.BoardWidth, cfg.BoardHeight = "pillars", 20, 20
+	}
+	if seed == 4 {
+		// and seed 4 as an endless match
+		cfg.Respawn = config.RespawnConfig{Enabled: true, Delay: 3, ScoreTarget: 2}
+	}
+	if seed == 2 {
+		// and seed 2 with turn and repeat limits
+		cfg.MaxTurns, cfg.RepeatLimit = 40, 3
+	}
+	if seed%5 == 0 {
+		// and multiples of five with golden food
+		cfg.Value = config.FoodValueConfig{Growth: 2, GoldenChance: 0.5, GoldenPoints: 3}
+	}
+	r := &Replay{Index: int(seed), Seed: seed, Game: cfg}
+	g := game.NewGame(cfg, seed)
+	rng := rand.New(rand.NewSource(seed))
+	for !g.State.GameOver && g.State.Turn < 200 {
+		actions := [2]ai.Action{ai.SafeAction(g.State, 0), ai.Action(rng.Intn(int(ai.NumActions)))}
+		r.Add(actions)
+		g.Step([2]game.Direction{
+			ai.ActionToDirection(g.State.Snakes[0].Direction, actions[0]),
+			ai.ActionToDirection(g.State.Snakes[1].Direction, actions[1]),
+		})
+	}
+	r.Finish(g.State)
+	return r
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded []*Replay
+	for seed := int64(1); seed <= 5; seed++ {
+		r := record(t, seed)
+		recorded = append(recorded, r)
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	replays, err := ReadAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replays) != len(recorded) {
+		t.Fatalf("expected %d replays, got %d", len(recorded), len(replays))
+	}
+	for i, r := range replays {
+		want := recorded[i]
+		if r.Seed != want.Seed || r.Game != want.Game || r.Winner != want.Winner || len(r.Actions) != len(want.Actions) {
+			t.Errorf("replay %d: got %+v, want %+v", i, r, want)
+			continue
+		}
+		frames, err := r.Play()
+		if err != nil {
+			t.Errorf("replay %d: %v", i, err)
+		}
+		if len(frames) != len(r.Actions)+1 {
+			t.Errorf("replay %d: expected %d frames, got %d", i, len(r.Actions)+1, len(frames))
+		}
+	}
+
+	// Flip a byte in the middle of the first record
+	data := bytes.Clone(buf.Bytes())
+	data[len(magic)+10] ^= 0xff
+	if _, err := ReadAll(bytes.NewReader(data)); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+
+	// A replay whose moves changed no longer reaches the recorded position
+	r := replays[0]
+	r.Actions[0][1] = (r.Actions[0][1] + 1) % ai.NumActions
+	if _, err := r.Play(); !errors.Is(err, ErrDiverged) {
+		t.Errorf("expected ErrDiverged, got %v", err)
+	}
+}
+
+func TestReadVersion1(t *testing.T) {
+	r := record(t, 1)
+
+	// A version 1 record is today's without the limits following the food
+	// spawn settings
+	body := r.encode(nil)
+	d := decoder{buf: body}
+	d.varint()
+	for range 4 {
+		d.uvarint()
+	}
+	d.next(d.uvarint())
+	for range 3 {
+		d.uvarint()
+	}
+	limits := len(body) - len(d.buf)
+	body = append(body[:limits:limits], body[limits+2:]...)
+
+	file := []byte("SLRP\x01")
+	file = binary.AppendUvarint(file, uint64(len(body)))
+	file = append(file, body...)
+	file = binary.LittleEndian.AppendUint32(file, crc32.ChecksumIEEE(body))
+	replays, err := ReadAll(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replays) != 1 || replays[0].Game != r.Game || len(replays[0].Actions) != len(r.Actions) {
+		t.Fatalf("read %+v, want %+v", replays, r)
+	}
+	if _, err := replays[0].Play(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := ReadAll(bytes.NewReader([]byte("SLRP\x03"))); err == nil {
+		t.Error("read a file of a future version")
+	}
+}
+
+func TestAppendWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replays.bin")
+	appendReplays := func(seeds ...int64) error {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w, err := NewAppendWriter(file)
+		if err != nil {
+			return err
+		}
+		for _, seed := range seeds {
+			if err := w.Write(record(t, seed)); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}
+
+	// The first call starts the file, the second adds to it
+	if err := appendReplays(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendReplays(3); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replays, err := ReadAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replays) != 3 {
+		t.Fatalf("read %d replays, want 3", len(replays))
+	}
+	for i, r := range replays {
+		if r.Seed != int64(i+1) {
+			t.Errorf("replay %d has seed %d, want %d", i, r.Seed, i+1)
+		}
+	}
+
+	// Records of another version can't follow a file's own
+	if err := os.WriteFile(path, []byte("SLRP\x01"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendReplays(4); err == nil {
+		t.Error("appended to a version 1 file")
+	}
+}
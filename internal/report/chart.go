@@ -0,0 +1,82 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Chart dimensions in SVG user units
+const (
+	chartWidth   = 640
+	chartHeight  = 240
+	chartPadding = 40
+)
+
+// seriesColors cycles through line colors
+var seriesColors = []string{"#4caf50", "#2196f3", "#f44336", "#ff9800", "#9c27b0", "#607d8b"}
+
+// Series is one named line on a chart
+type Series struct {
+	Name string
+	X, Y []float64
+}
+
+// LineChart renders series as an inline SVG line chart with a legend
+func LineChart(title string, series []Series) template.HTML {
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for i := range s.X {
+			minX, maxX = math.Min(minX, s.X[i]), math.Max(maxX, s.X[i])
+			minY, maxY = math.Min(minY, s.Y[i]), math.Max(maxY, s.Y[i])
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return ""
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+	if maxY == minY {
+		maxY = minY + 1
+	}
+
+	plotW := float64(chartWidth - 2*chartPadding)
+	plotH := float64(chartHeight - 2*chartPadding)
+	px := func(x float64) float64 { return chartPadding + (x-minX)/(maxX-minX)*plotW }
+	py := func(y float64) float64 { return chartHeight - chartPadding - (y-minY)/(maxY-minY)*plotH }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="20" class="title">%s</text>`, chartPadding, template.HTMLEscapeString(title))
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" class="plot"/>`, chartPadding, chartPadding, plotW, plotH)
+	fmt.Fprintf(&b, `<text x="%d" y="%.0f" class="axis" text-anchor="end">%s</text>`, chartPadding-4, py(maxY)+4, formatTick(maxY))
+	fmt.Fprintf(&b, `<text x="%d" y="%.0f" class="axis" text-anchor="end">%s</text>`, chartPadding-4, py(minY)+4, formatTick(minY))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" class="axis">%s</text>`, chartPadding, chartHeight-chartPadding+16, formatTick(minX))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" class="axis" text-anchor="end">%s</text>`, chartWidth-chartPadding, chartHeight-chartPadding+16, formatTick(maxX))
+
+	for i, s := range series {
+		color := seriesColors[i%len(seriesColors)]
+		points := make([]string, len(s.X))
+		for j := range s.X {
+			points[j] = fmt.Sprintf("%.1f,%.1f", px(s.X[j]), py(s.Y[j]))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(points, " "))
+		legendY := chartHeight - 10
+		legendX := chartPadding + i*150
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, legendX, legendY-9, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="axis">%s</text>`, legendX+14, legendY, template.HTMLEscapeString(s.Name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// formatTick formats an axis label compactly
+func formatTick(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e6 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.3g", v)
+}
@@ -0,0 +1,110 @@
+package report
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// Replay colors, matching the renderer
+var replayPalette = color.Palette{
+	color.RGBA{20, 20, 20, 255},    // Background
+	color.RGBA{76, 175, 80, 255},   // Snake 0
+	color.RGBA{129, 199, 132, 255}, // Snake 0 head
+	color.RGBA{33, 150, 243, 255},  // Snake 1
+	color.RGBA{100, 181, 246, 255}, // Snake 1 head
+	color.RGBA{244, 67, 54, 255},   // Food
+	color.RGBA{128, 128, 128, 255}, // Dead snake
+	color.RGBA{255, 193, 7, 255},   // Golden food
+}
+
+// Palette indexes
+const (
+	paletteBackground = iota
+	paletteSnake0
+	paletteSnake0Head
+	paletteSnake1
+	paletteSnake1Head
+	paletteFood
+	paletteDead
+	paletteGolden
+)
+
+// PlayReplay plays one game between policies and returns a copy of the
+// state before every turn and after the last one
+func PlayReplay(cfg config.GameConfig, seed int64, policies [2]ai.Policy, maxTurns int) []*game.GameState {
+	g := game.NewGame(cfg, seed)
+	frames := []*game.GameState{g.State.Clone()}
+	for !g.State.GameOver && (maxTurns <= 0 || g.State.Turn < maxTurns) {
+		var dirs [2]game.Direction
+		for i, policy := range policies {
+			dirs[i] = ai.ActionToDirection(g.State.Snakes[i].Direction, policy.Act(g.State, i))
+		}
+		g.Step(dirs)
+		frames = append(frames, g.State.Clone())
+	}
+	return frames
+}
+
+// EncodeGIF draws frames as an animated GIF with cellSize pixels per cell
+// and delay hundredths of a second per frame. The last frame is held for
+// two seconds.
+func EncodeGIF(frames []*game.GameState, cellSize, delay int) ([]byte, error) {
+	anim := &gif.GIF{}
+	for i, state := range frames {
+		anim.Image = append(anim.Image, drawFrame(state, cellSize))
+		if i == len(frames)-1 {
+			anim.Delay = append(anim.Delay, 200)
+		} else {
+			anim.Delay = append(anim.Delay, delay)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawFrame renders one state as a paletted image
+func drawFrame(state *game.GameState, cellSize int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, state.Width*cellSize, state.Height*cellSize), replayPalette)
+	fill := func(pos game.Position, idx uint8, padding int) {
+		if game.CheckWallCollision(pos, state.Width, state.Height) {
+			return
+		}
+		for y := pos.Y*cellSize + padding; y < (pos.Y+1)*cellSize-padding; y++ {
+			for x := pos.X*cellSize + padding; x < (pos.X+1)*cellSize-padding; x++ {
+				img.SetColorIndex(x, y, idx)
+			}
+		}
+	}
+
+	for _, pos := range state.FoodPositions() {
+		idx := uint8(paletteFood)
+		if state.IsGolden(pos) {
+			idx = paletteGolden
+		}
+		fill(pos, idx, cellSize/5)
+	}
+	bodyColors := [2][2]uint8{{paletteSnake0, paletteSnake0Head}, {paletteSnake1, paletteSnake1Head}}
+	for id, snake := range state.Snakes {
+		body, head := bodyColors[id][0], bodyColors[id][1]
+		if !snake.Alive {
+			body, head = paletteDead, paletteDead
+		}
+		for i := len(snake.Body) - 1; i >= 0; i-- {
+			idx := body
+			if i == 0 {
+				idx = head
+			}
+			fill(snake.Body[i], idx, 1)
+		}
+	}
+	return img
+}
@@ -0,0 +1,160 @@
+// Package report renders experiment results as a self-contained HTML page:
+// the ladder and head-to-head standings from the results store, learning
+// curves from training metrics, and animated replays.
+package report
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+	"time"
+
+	"autonomous-snake/internal/metrics"
+	"autonomous-snake/internal/results"
+)
+
+// Report is everything shown on the page. Empty sections are left out.
+type Report struct {
+	Title     string
+	Generated time.Time
+	Ladder    []results.Rating
+	Standings []results.Standing
+	Curves    []Curve
+	Replays   []Replay
+}
+
+// Curve is one training run's learning curve
+type Curve struct {
+	Name string
+	Rows []metrics.Row
+}
+
+// Replay is one recorded game encoded as a GIF
+type Replay struct {
+	Caption string
+	GIF     []byte
+}
+
+// chartSet is the charts drawn for one learning curve
+type chartSet struct {
+	Name   string
+	Charts []template.HTML
+}
+
+// Write renders the report as HTML
+func Write(w io.Writer, r *Report) error {
+	data := struct {
+		*Report
+		Charts  []chartSet
+		Replays []replayView
+	}{Report: r}
+
+	for _, c := range r.Curves {
+		data.Charts = append(data.Charts, curveCharts(c))
+	}
+	for _, replay := range r.Replays {
+		src := "data:image/gif;base64," + base64.StdEncoding.EncodeToString(replay.GIF)
+		data.Replays = append(data.Replays, replayView{Caption: replay.Caption, Src: template.URL(src)})
+	}
+	return page.Execute(w, data)
+}
+
+// replayView is a replay ready for the template
+type replayView struct {
+	Caption string
+	Src     template.URL
+}
+
+// curveCharts draws the length, win rate and reward charts for a curve
+func curveCharts(c Curve) chartSet {
+	n := len(c.Rows)
+	episodes := make([]float64, n)
+	length := make([]float64, n)
+	var wins, rewards [2][]float64
+	ties := make([]float64, n)
+	for i := range wins {
+		wins[i] = make([]float64, n)
+		rewards[i] = make([]float64, n)
+	}
+	for i, row := range c.Rows {
+		episodes[i] = float64(row.Episode)
+		length[i] = row.AvgLength
+		ties[i] = row.TieRate
+		for s := 0; s < 2; s++ {
+			wins[s][i] = row.WinRate[s]
+			rewards[s][i] = row.AvgReward[s]
+		}
+	}
+
+	return chartSet{
+		Name: c.Name,
+		Charts: []template.HTML{
+			LineChart("Average episode length", []Series{{Name: "turns", X: episodes, Y: length}}),
+			LineChart("Outcome rates", []Series{
+				{Name: "snake 0 wins", X: episodes, Y: wins[0]},
+				{Name: "snake 1 wins", X: episodes, Y: wins[1]},
+				{Name: "ties", X: episodes, Y: ties},
+			}),
+			LineChart("Average episode reward", []Series{
+				{Name: "snake 0", X: episodes, Y: rewards[0]},
+				{Name: "snake 1", X: episodes, Y: rewards[1]},
+			}),
+		},
+	}
+}
+
+// page is the report template
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct": func(v float64) float64 { return 100 * v },
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; background: #141414; color: #eee; max-width: 1000px; margin: 2em auto; padding: 0 1em; }
+h1, h2, h3 { font-weight: normal; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { padding: 4px 12px; border-bottom: 1px solid #333; text-align: right; }
+th:first-child, td:first-child, .name { text-align: left; }
+.chart { width: 100%; max-width: 640px; display: block; margin-bottom: 1em; }
+.chart .plot { fill: #1c1c1c; stroke: #333; }
+.chart text { fill: #bbb; font-size: 11px; }
+.chart .title { font-size: 13px; fill: #eee; }
+figure { display: inline-block; margin: 0 1em 1em 0; }
+figcaption { font-size: 0.9em; color: #aaa; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="muted">Generated {{.Generated.Format "2006-01-02 15:04"}}</p>
+{{if .Ladder}}
+<h2>Ladder</h2>
+<table>
+<tr><th>Rank</th><th class="name">Model</th><th>Rating</th><th>Games</th><th>Wins</th><th>Losses</th><th>Ties</th></tr>
+{{range $i, $r := .Ladder}}<tr><td>{{inc $i}}</td><td class="name">{{$r.Model}}</td><td>{{printf "%.0f" $r.Rating}}</td><td>{{$r.Games}}</td><td>{{$r.Wins}}</td><td>{{$r.Losses}}</td><td>{{$r.Ties}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Standings}}
+<h2>Head to head</h2>
+<table>
+<tr><th class="name">Model A</th><th class="name">Model B</th><th>Games</th><th>A wins</th><th>B wins</th><th>Ties</th><th>A score</th><th>Avg turns</th></tr>
+{{range .Standings}}<tr><td class="name">{{index .Models 0}}</td><td class="name">{{index .Models 1}}</td><td>{{.Games}}</td><td>{{index .Wins 0}}</td><td>{{index .Wins 1}}</td><td>{{.Ties}}</td><td>{{printf "%.1f%%" (pct .WinRate)}}</td><td>{{printf "%.1f" .AvgTurns}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Charts}}
+<h2>Learning curves</h2>
+{{range .Charts}}<h3>{{.Name}}</h3>
+{{range .Charts}}{{.}}
+{{end}}{{end}}
+{{end}}
+{{if .Replays}}
+<h2>Replays</h2>
+{{range .Replays}}<figure><img src="{{.Src}}" alt="{{.Caption}}"><figcaption>{{.Caption}}</figcaption></figure>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
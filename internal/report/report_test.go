@@ -0,0 +1,121 @@
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/metrics"
+	"autonomous-snake/internal/results"
+)
+
+// metricsFixture is a short training run's learning curve
+const metricsFixture = `episode,epsilon,avg_length,win_rate0,win_rate1,tie_rate,avg_reward0,avg_reward1,loss,eps_per_sec
+100,0.9,12.5,0.4,0.45,0.15,-0.5,-0.4,0.2,50
+200,0.8,20,0.5,0.4,0.1,0.3,0.1,0.15,52
+300,0.7,31.5,0.55,0.35,0.1,1.2,0.6,0.1,51
+`
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.csv")
+	if err := os.WriteFile(path, []byte(metricsFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := metrics.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultGameConfig()
+	cfg.BoardWidth, cfg.BoardHeight = 10, 10
+	frames := PlayReplay(cfg, 3, [2]ai.Policy{ai.NewRandomPolicy(1), ai.NewRandomPolicy(2)}, 30)
+	if len(frames) < 2 || len(frames) > 31 {
+		t.Fatalf("%d frames of a game of at most 30 turns", len(frames))
+	}
+	const cell = 6
+	data, err := EncodeGIF(frames, cell, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rep := &Report{
+		Title:     "Baseline <run>",
+		Generated: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		Ladder:    []results.Rating{{Model: "models/a.gob", Rating: 1540, Games: 10, Wins: 6, Losses: 3, Ties: 1}},
+		Standings: []results.Standing{{Models: [2]string{"models/a.gob", "random"}, Games: 10, Wins: [2]int{6, 3}, Ties: 1, AvgTurns: 42}},
+		Curves:    []Curve{{Name: "baseline.csv", Rows: rows}},
+		Replays:   []Replay{{Caption: "seed 3", GIF: data}},
+	}
+	var out bytes.Buffer
+	if err := Write(&out, rep); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+	for _, want := range []string{"Baseline &lt;run&gt;", "Generated 2026-10-16 12:00", "models/a.gob", "1540", "65.0%",
+		"Average episode length", "Outcome rates", "Average episode reward"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if n := strings.Count(page, "<svg"); n != 3 {
+		t.Errorf("%d charts, want 3", n)
+	}
+
+	// The page parses as HTML throughout, and its replay decodes back to
+	// every frame
+	dec := xml.NewDecoder(strings.NewReader(page))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	var src string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("report HTML: %v", err)
+		}
+		if el, ok := tok.(xml.StartElement); ok && el.Name.Local == "img" {
+			for _, attr := range el.Attr {
+				if attr.Name.Local == "src" {
+					src = attr.Value
+				}
+			}
+		}
+	}
+	encoded, ok := strings.CutPrefix(src, "data:image/gif;base64,")
+	if !ok {
+		t.Fatalf("replay source %.40q", src)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != len(frames) {
+		t.Fatalf("GIF has %d frames, want %d", len(anim.Image), len(frames))
+	}
+	if b := anim.Image[0].Bounds(); b.Dx() != 10*cell || b.Dy() != 10*cell {
+		t.Errorf("GIF frames are %v, want %dx%d", b, 10*cell, 10*cell)
+	}
+	if last := anim.Delay[len(anim.Delay)-1]; last != 200 || anim.Delay[0] != 8 {
+		t.Errorf("delays %d first and %d last, want 8 and 200", anim.Delay[0], last)
+	}
+	head := frames[0].Snakes[0].Body[0]
+	if got := anim.Image[0].ColorIndexAt(head.X*cell+cell/2, head.Y*cell+cell/2); got != paletteSnake0Head {
+		t.Errorf("snake 0's head is drawn in color %d, want %d", got, paletteSnake0Head)
+	}
+}
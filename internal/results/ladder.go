@@ -0,0 +1,166 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Elo parameters for the ladder
+const (
+	InitialRating = 1500.0
+	EloK          = 16.0 // Largest rating change from a single game
+)
+
+// Rating is a model's standing on the ladder
+type Rating struct {
+	Model  string  `json:"model"`
+	Rating float64 `json:"rating"`
+	Games  int     `json:"games"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+	Ties   int     `json:"ties"`
+}
+
+// ExpectedScore is the Elo expected score of a player rated a against b
+func ExpectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Ladder returns every rated model, best first
+func (s *Store) Ladder() ([]Rating, error) {
+	var ladder []Rating
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(ratingsBucket).ForEach(func(key, data []byte) error {
+			var r Rating
+			if err := json.Unmarshal(data, &r); err != nil {
+				return fmt.Errorf("rating for %s: %w", key, err)
+			}
+			ladder = append(ladder, r)
+			return nil
+		})
+	})
+	sort.Slice(ladder, func(i, j int) bool {
+		if ladder[i].Rating != ladder[j].Rating {
+			return ladder[i].Rating > ladder[j].Rating
+		}
+		return ladder[i].Model < ladder[j].Model
+	})
+	return ladder, err
+}
+
+// RebuildLadder recomputes every rating by replaying the whole history in
+// the order it was recorded
+func (s *Store) RebuildLadder() error {
+	return s.db.Update(rebuildRatings)
+}
+
+// rebuildRatings replaces the ratings bucket with ratings replayed from the
+// matches bucket
+func rebuildRatings(tx *bolt.Tx) error {
+	if tx.Bucket(ratingsBucket) != nil {
+		if err := tx.DeleteBucket(ratingsBucket); err != nil {
+			return err
+		}
+	}
+	ratings, err := tx.CreateBucket(ratingsBucket)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(matchesBucket).ForEach(func(_, data []byte) error {
+		var m Match
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		return rate(ratings, &m)
+	})
+}
+
+// rate applies one game's Elo update to both models. Games between a model
+// and itself carry no information and are skipped.
+func rate(bucket *bolt.Bucket, m *Match) error {
+	if m.Models[0] == m.Models[1] {
+		return nil
+	}
+
+	var players [2]Rating
+	for i, model := range m.Models {
+		r, err := loadRating(bucket, model)
+		if err != nil {
+			return err
+		}
+		players[i] = r
+	}
+
+	var scores [2]float64
+	switch m.Winner {
+	case 0, 1:
+		scores[m.Winner] = 1
+		players[m.Winner].Wins++
+		players[1-m.Winner].Losses++
+	default:
+		scores = [2]float64{0.5, 0.5}
+		players[0].Ties++
+		players[1].Ties++
+	}
+
+	expected := ExpectedScore(players[0].Rating, players[1].Rating)
+	delta := EloK * (scores[0] - expected)
+	players[0].Rating += delta
+	players[1].Rating -= delta
+
+	for _, p := range players {
+		p.Games++
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(p.Model), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRating returns a model's rating, or a fresh one if it is unrated
+func loadRating(bucket *bolt.Bucket, model string) (Rating, error) {
+	data := bucket.Get([]byte(model))
+	if data == nil {
+		return Rating{Model: model, Rating: InitialRating}, nil
+	}
+	var r Rating
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("rating for %s: %w", model, err)
+	}
+	return r, nil
+}
+
+// Register adds models to the ladder at the initial rating. Models already
+// on the ladder are left unchanged, and nothing is added if a name is
+// empty.
+func (s *Store) Register(models ...string) error {
+	for _, model := range models {
+		if model == "" {
+			return fmt.Errorf("empty model name")
+		}
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ratingsBucket)
+		for _, model := range models {
+			if bucket.Get([]byte(model)) != nil {
+				continue
+			}
+			data, err := json.Marshal(Rating{Model: model, Rating: InitialRating})
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(model), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,259 @@
+// Package results keeps a persistent history of finished games so models
+// can be compared over time.
+package results
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"autonomous-snake/internal/game"
+)
+
+// Sources of recorded matches
+const (
+	SourcePlay     = "play"
+	SourceSelfPlay = "selfplay"
+	SourceMatch    = "match"
+	SourceEval     = "eval"
+	SourceArena    = "arena"
+)
+
+// DefaultPath is where commands store results unless told otherwise
+const DefaultPath = "data/results.db"
+
+// Buckets: every match keyed by its big-endian ID, and the ladder rating of
+// every model keyed by name
+var (
+	matchesBucket = []byte("matches")
+	ratingsBucket = []byte("ratings")
+)
+
+// Match is one finished game
+type Match struct {
+	ID       uint64    `json:"id"`
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Models   [2]string `json:"models"` // Per snake: model path or policy name
+	Seed     int64     `json:"seed"`
+	Game     int       `json:"game"` // Index of the game within its session
+	Board    [2]int    `json:"board"`
+	Winner   int       `json:"winner"` // -1 for a tie
+	Turns    int       `json:"turns"`
+	Lengths  [2]int    `json:"lengths"`
+	Scores   [2]int    `json:"scores"`
+	Deaths   [2]string `json:"deaths,omitempty"` // Collision type that killed each snake, if any
+	Finished bool      `json:"finished"`         // False when the game hit a turn limit
+}
+
+// NewMatch describes a finished game from its final state and the result of
+// its last step
+func NewMatch(source string, models [2]string, seed int64, idx int, state *game.GameState, last game.StepResult) Match {
+	m := Match{
+		Time:     time.Now(),
+		Source:   source,
+		Models:   models,
+		Seed:     seed,
+		Game:     idx,
+		Board:    [2]int{state.Width, state.Height},
+		Winner:   -1,
+		Turns:    state.Turn,
+		Finished: state.GameOver,
+	}
+	if state.GameOver {
+		m.Winner = state.Winner
+	}
+	for i, snake := range state.Snakes {
+		m.Lengths[i] = snake.Length()
+		m.Scores[i] = snake.Score
+		if last.Died[i] && len(last.Collisions[i]) > 0 {
+			m.Deaths[i] = last.Collisions[i][0].Type.String()
+		}
+	}
+	return m
+}
+
+// Store is a results database backed by a single bbolt file
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the results database at path
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open results database %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(matchesBucket); err != nil {
+			return err
+		}
+		// Databases written before the ladder existed get rated from
+		// their history
+		if tx.Bucket(ratingsBucket) == nil {
+			return rebuildRatings(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add stores matches in one transaction, assigning their IDs and updating
+// the ladder. Every match must name both models; otherwise nothing is
+// stored.
+func (s *Store) Add(matches ...*Match) error {
+	for i, m := range matches {
+		for snake, model := range m.Models {
+			if model == "" {
+				return fmt.Errorf("match %d has no model name for snake %d", i, snake)
+			}
+		}
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(matchesBucket)
+		ratings := tx.Bucket(ratingsBucket)
+		for _, m := range matches {
+			id, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			m.ID = id
+			data, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+			var key [8]byte
+			binary.BigEndian.PutUint64(key[:], id)
+			if err := bucket.Put(key[:], data); err != nil {
+				return err
+			}
+			if err := rate(ratings, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Filter selects matches; zero fields match everything
+type Filter struct {
+	Source string
+	Model  string // Matches if either snake used this model
+	Since  time.Time
+}
+
+// matches reports whether m passes the filter
+func (f Filter) matches(m *Match) bool {
+	if f.Source != "" && m.Source != f.Source {
+		return false
+	}
+	if f.Model != "" && m.Models[0] != f.Model && m.Models[1] != f.Model {
+		return false
+	}
+	return f.Since.IsZero() || !m.Time.Before(f.Since)
+}
+
+// Query returns the matching matches, oldest first
+func (s *Store) Query(filter Filter) ([]Match, error) {
+	var matches []Match
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(matchesBucket).ForEach(func(key, data []byte) error {
+			var m Match
+			if err := json.Unmarshal(data, &m); err != nil {
+				return fmt.Errorf("match %d: %w", binary.BigEndian.Uint64(key), err)
+			}
+			if filter.matches(&m) {
+				matches = append(matches, m)
+			}
+			return nil
+		})
+	})
+	return matches, err
+}
+
+// Standing aggregates the matches between one pair of models
+type Standing struct {
+	Models    [2]string
+	Games     int
+	Wins      [2]int
+	Ties      int
+	AvgTurns  float64
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// WinRate returns the share of games won by Models[0], counting ties as half
+func (s Standing) WinRate() float64 {
+	if s.Games == 0 {
+		return 0
+	}
+	return (float64(s.Wins[0]) + 0.5*float64(s.Ties)) / float64(s.Games)
+}
+
+// Standings groups matches by model pair. Pairs are ordered by name so a
+// pairing counts the same whichever snake each model controlled; the result
+// is sorted by number of games, most first.
+func Standings(matches []Match) []Standing {
+	byPair := make(map[[2]string]*Standing)
+	turns := make(map[[2]string]int)
+	for _, m := range matches {
+		pair, winner := m.Models, m.Winner
+		if strings.Compare(pair[0], pair[1]) > 0 {
+			pair[0], pair[1] = pair[1], pair[0]
+			if winner >= 0 {
+				winner = 1 - winner
+			}
+		}
+
+		st := byPair[pair]
+		if st == nil {
+			st = &Standing{Models: pair, FirstSeen: m.Time}
+			byPair[pair] = st
+		}
+		st.Games++
+		if winner >= 0 {
+			st.Wins[winner]++
+		} else {
+			st.Ties++
+		}
+		turns[pair] += m.Turns
+		if m.Time.Before(st.FirstSeen) {
+			st.FirstSeen = m.Time
+		}
+		if m.Time.After(st.LastSeen) {
+			st.LastSeen = m.Time
+		}
+	}
+
+	standings := make([]Standing, 0, len(byPair))
+	for pair, st := range byPair {
+		st.AvgTurns = float64(turns[pair]) / float64(st.Games)
+		standings = append(standings, *st)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Games != standings[j].Games {
+			return standings[i].Games > standings[j].Games
+		}
+		return standings[i].Models[0]+standings[i].Models[1] < standings[j].Models[0]+standings[j].Models[1]
+	})
+	return standings
+}
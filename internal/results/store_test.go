@@ -0,0 +1,104 @@
+package results
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreQueryAndStandings(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	matches := []*Match{
+		{Source: SourceSelfPlay, Models: [2]string{"a", "b"}, Winner: 0, Turns: 10},
+		{Source: SourceSelfPlay, Models: [2]string{"b", "a"}, Winner: 0, Turns: 20},
+		{Source: SourceMatch, Models: [2]string{"a", "c"}, Winner: -1, Turns: 30},
+	}
+	if err := store.Add(matches...); err != nil {
+		t.Fatal(err)
+	}
+	if matches[2].ID != 3 {
+		t.Errorf("expected sequential IDs, got %d", matches[2].ID)
+	}
+
+	got, err := store.Query(Filter{Model: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 matches with model b, got %d", len(got))
+	}
+
+	standings := Standings(got)
+	if len(standings) != 1 {
+		t.Fatalf("expected one pairing, got %d", len(standings))
+	}
+	st := standings[0]
+	if st.Models != [2]string{"a", "b"} || st.Wins != [2]int{1, 1} || st.AvgTurns != 15 {
+		t.Errorf("unexpected standing %+v", st)
+	}
+
+	got, err = store.Query(Filter{Source: SourceMatch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Winner != -1 {
+		t.Errorf("expected the tied match, got %+v", got)
+	}
+}
+
+func TestLadder(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err := store.Register("idle"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Register("unnamed", ""); err == nil {
+		t.Error("registered an empty model name")
+	}
+	if err := store.Add(&Match{Models: [2]string{"unnamed", "idle"}}, &Match{Models: [2]string{"idle", ""}}); err == nil {
+		t.Error("added a match with an empty model name")
+	}
+	err = store.Add(
+		&Match{Models: [2]string{"strong", "weak"}, Winner: 0},
+		&Match{Models: [2]string{"weak", "strong"}, Winner: 1},
+		&Match{Models: [2]string{"weak", "weak"}, Winner: 0},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ladder, err := store.Ladder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ladder) != 3 || ladder[0].Model != "strong" || ladder[2].Model != "weak" {
+		t.Fatalf("unexpected ladder order %+v", ladder)
+	}
+	if ladder[0].Games != 2 || ladder[0].Wins != 2 || ladder[2].Losses != 2 {
+		t.Errorf("self-play game should not be rated, got %+v", ladder)
+	}
+	if sum := ladder[0].Rating + ladder[2].Rating; sum != 2*InitialRating {
+		t.Errorf("expected rating points to be conserved, got total %v", sum)
+	}
+
+	before := ladder
+	if err := store.RebuildLadder(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := store.Ladder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Registration alone isn't part of the history
+	if len(after) != 2 || after[0] != before[0] || after[1] != before[2] {
+		t.Errorf("rebuilt ladder %+v differs from %+v", after, before)
+	}
+}
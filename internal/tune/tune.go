@@ -0,0 +1,178 @@
+// Package tune runs short training probes over a grid of hyperparameters
+// and recommends a starting configuration from their early learning trends.
+package tune
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// divergenceGrowth is how much the fitted loss may grow over a probe,
+// relative to its mean, before the probe counts as diverging
+const divergenceGrowth = 1.0
+
+// Candidate is one hyperparameter combination to probe
+type Candidate struct {
+	LearningRate float64
+	Gamma        float64
+}
+
+// Grid returns every combination of the given learning rates and gammas
+func Grid(learningRates, gammas []float64) []Candidate {
+	candidates := make([]Candidate, 0, len(learningRates)*len(gammas))
+	for _, lr := range learningRates {
+		for _, gamma := range gammas {
+			candidates = append(candidates, Candidate{LearningRate: lr, Gamma: gamma})
+		}
+	}
+	return candidates
+}
+
+// Probe is the outcome of one short training run
+type Probe struct {
+	Candidate
+	Losses []float64 // Mean training loss per episode, skipping episodes without updates
+	Scores []float64 // Mean food eaten per snake, per episode
+
+	LossSlope  float64 // Fitted loss change per episode, relative to the mean loss
+	ScoreSlope float64 // Fitted score change per episode
+	FinalScore float64 // Mean score over the last quarter of the probe
+	Stable     bool    // False when the loss diverged or went non-finite
+}
+
+// Run trains a fresh agent for the given number of episodes with the
+// candidate's hyperparameters and summarizes its trend. Epsilon decays so it
+// reaches its minimum by the end of the probe, so even short probes spend
+// their second half mostly exploiting what they learned.
+func Run(cfg config.TrainingConfig, gameCfg config.GameConfig, c Candidate, episodes int, seed int64) (Probe, error) {
+	cfg.LearningRate = c.LearningRate
+	cfg.Gamma = c.Gamma
+	if episodes > 1 && cfg.EpsilonMin > 0 {
+		cfg.EpsilonDecay = math.Pow(cfg.EpsilonMin/cfg.EpsilonStart, 1/float64(episodes-1))
+	}
+
+	encoder, err := ai.LookupEncoder(cfg.Encoder)
+	if err != nil {
+		return Probe{}, err
+	}
+	cfg.InputSize = encoder.Size()
+
+	rewards := [2]config.RewardConfig{cfg.RewardsFor(0), cfg.RewardsFor(1)}
+	var shapers [2]*ai.RewardShaper
+	for i := range shapers {
+		if shapers[i], err = ai.NewRewardShaper(rewards[i], cfg.Gamma); err != nil {
+			return Probe{}, fmt.Errorf("invalid reward shaping for snake %d: %w", i, err)
+		}
+	}
+	stall := ai.NewStallPenalty(rewards)
+
+	agent := ai.NewDQNAgent(cfg, seed)
+	g := game.NewGame(gameCfg, seed)
+	g.Rewards = rewards
+
+	probe := Probe{Candidate: c}
+	var states, nextStates [2][]float64
+	for i := range states {
+		states[i] = make([]float64, encoder.Size())
+		nextStates[i] = make([]float64, encoder.Size())
+	}
+	var actions [2]ai.Action
+	var dirs [2]game.Direction
+
+	for ep := 0; ep < episodes; ep++ {
+		state := g.Reset()
+		stall.Reset()
+		lossSum, updates := 0.0, 0
+		for steps := 0; !state.GameOver && steps < cfg.MaxStepsPerEp; steps++ {
+			for i := range actions {
+				encoder.Encode(states[i], state, i)
+				actions[i] = agent.SelectAction(states[i])
+				dirs[i] = ai.ActionToDirection(state.Snakes[i].Direction, actions[i])
+			}
+			prevState := g.Clone().State
+			result := g.Step(dirs)
+			for i := range actions {
+				encoder.Encode(nextStates[i], state, i)
+				reward := result.Rewards[i] + shapers[i].Reward(prevState, state, i)
+				if stall.Enabled() {
+					reward += stall.Penalty(state, i, result.AteFood[i])
+				}
+				agent.Remember(states[i], actions[i], reward, nextStates[i], result.Died[i] || result.GameOver)
+			}
+			if loss := agent.Train(); loss != 0 {
+				lossSum += loss
+				updates++
+			}
+		}
+		agent.DecayEpsilon()
+
+		if updates > 0 {
+			probe.Losses = append(probe.Losses, lossSum/float64(updates))
+		}
+		probe.Scores = append(probe.Scores, float64(state.Snakes[0].Score+state.Snakes[1].Score)/2)
+	}
+
+	probe.summarize()
+	return probe, nil
+}
+
+// summarize fits the loss and score trends
+func (p *Probe) summarize() {
+	p.Stable = true
+	if len(p.Losses) > 1 {
+		mean := 0.0
+		for _, l := range p.Losses {
+			mean += l
+		}
+		mean /= float64(len(p.Losses))
+		p.LossSlope = Slope(p.Losses) / mean
+		if math.IsNaN(p.LossSlope) || math.IsInf(p.LossSlope, 0) ||
+			p.LossSlope*float64(len(p.Losses)) > divergenceGrowth {
+			p.Stable = false
+		}
+	}
+	p.ScoreSlope = Slope(p.Scores)
+
+	tail := p.Scores[len(p.Scores)-max(len(p.Scores)/4, 1):]
+	for _, s := range tail {
+		p.FinalScore += s
+	}
+	p.FinalScore /= float64(len(tail))
+}
+
+// Slope returns the least-squares slope of ys against their index
+func Slope(ys []float64) float64 {
+	n := float64(len(ys))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range ys {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
+
+// Rank orders probes from most to least promising: stable probes first, then
+// by how fast the score improved, then by the score they ended on
+func Rank(probes []Probe) {
+	sort.SliceStable(probes, func(i, j int) bool {
+		a, b := probes[i], probes[j]
+		if a.Stable != b.Stable {
+			return a.Stable
+		}
+		if a.ScoreSlope != b.ScoreSlope {
+			return a.ScoreSlope > b.ScoreSlope
+		}
+		return a.FinalScore > b.FinalScore
+	})
+}
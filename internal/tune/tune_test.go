@@ -0,0 +1,37 @@
+package tune
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSlope(t *testing.T) {
+	if got := Slope([]float64{1, 3, 5, 7}); math.Abs(got-2) > 1e-9 {
+		t.Errorf("expected slope 2, got %v", got)
+	}
+	if got := Slope([]float64{4}); got != 0 {
+		t.Errorf("expected slope 0 for one point, got %v", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	probes := []Probe{
+		{Candidate: Candidate{LearningRate: 0.01}, Losses: []float64{1, 2, 4, 8}, Scores: []float64{0, 1, 2, 3}},
+		{Candidate: Candidate{LearningRate: 0.001}, Losses: []float64{4, 3, 2, 1}, Scores: []float64{0, 0.5, 1, 1.5}},
+		{Candidate: Candidate{LearningRate: 0.0001}, Losses: []float64{4, 4, 4, 4}, Scores: []float64{0, 0, 0, 0}},
+	}
+	for i := range probes {
+		probes[i].summarize()
+	}
+	if probes[0].Stable {
+		t.Error("expected a loss that grows eightfold to count as diverging")
+	}
+
+	Rank(probes)
+	want := []float64{0.001, 0.0001, 0.01}
+	for i, p := range probes {
+		if p.LearningRate != want[i] {
+			t.Errorf("rank %d: expected lr %g, got %g", i+1, want[i], p.LearningRate)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package snake
+
+import (
+	"fmt"
+
+	"autonomous-snake/internal/ai"
+)
+
+// Agent picks moves for one snake
+type Agent interface {
+	// Move returns the direction snake snakeID heads next from state, a
+	// snapshot of the game. A move straight back into the snake's neck
+	// keeps it going its current way.
+	Move(state *State, snakeID int) Direction
+}
+
+// AgentFunc adapts a function to an Agent
+type AgentFunc func(state *State, snakeID int) Direction
+
+// Move calls f
+func (f AgentFunc) Move(state *State, snakeID int) Direction {
+	return f(state, snakeID)
+}
+
+// policyAgent plays one of the engine's policies, which answer with a turn
+// relative to the snake's heading
+type policyAgent struct {
+	policy ai.Policy
+}
+
+// Move turns the policy's action into a direction
+func (a policyAgent) Move(state *State, snakeID int) Direction {
+	heading := state.engine.Snakes[snakeID].Direction
+	return Direction(ai.ActionToDirection(heading, a.policy.Act(state.engine, snakeID)))
+}
+
+// RandomAgent returns an agent that goes straight or turns at random
+func RandomAgent(seed int64) Agent {
+	return policyAgent{ai.NewRandomPolicy(seed)}
+}
+
+// LoadAgent returns an agent playing the trained model saved at path
+// greedily, never moving into a wall or body while another move survives
+func LoadAgent(path string) (Agent, error) {
+	net, err := ai.LoadNetwork(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load model %s: %w", path, err)
+	}
+	policy := ai.NewNetworkPolicy(net, 0, 0)
+	policy.Mask = true
+	return policyAgent{policy}, nil
+}
+
+// Play steps g with the agents' moves until the game is over and returns
+// the last turn's result. Set Config.MaxTurns or Config.RepeatLimit to
+// bound games between agents that may circle forever.
+func Play(g *Game, agents [2]Agent) StepResult {
+	var result StepResult
+	for !g.Over() {
+		state := g.State()
+		var moves [2]Direction
+		for i, agent := range agents {
+			if snake := state.Snakes[i]; snake.Alive {
+				moves[i] = agent.Move(state, i)
+			} else {
+				moves[i] = snake.Direction
+			}
+		}
+		result = g.Step(moves)
+	}
+	return result
+}
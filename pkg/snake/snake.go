@@ -0,0 +1,288 @@
+// Package snake is the public face of the two-snake engine, for Go projects
+// outside this module that want to embed the environment in their own
+// reinforcement learning experiments. Its types are its own, kept stable
+// across releases and converted to and from the engine's at the package
+// boundary; the engine stays under internal/, which the rest of this
+// repository goes on using directly.
+//
+// A game steps both snakes at once:
+//
+//	g, err := snake.New(snake.DefaultConfig(), 1)
+//	if err != nil {
+//		return err
+//	}
+//	for !g.Over() {
+//		result := g.Step([2]snake.Direction{snake.Up, snake.Down})
+//		_ = result.Rewards
+//	}
+package snake
+
+import (
+	"autonomous-snake/internal/ai"
+	"autonomous-snake/internal/config"
+	"autonomous-snake/internal/game"
+)
+
+// Direction is the way a snake heads
+type Direction int
+
+// Directions
+const (
+	Up Direction = iota
+	Down
+	Left
+	Right
+)
+
+// CollisionType says what a snake ran into
+type CollisionType int
+
+// Collision types, see StepResult.DeathCause
+const (
+	NoCollision CollisionType = iota
+	WallCollision
+	SelfCollision
+	OtherSnakeCollision
+	HeadToHeadCollision
+)
+
+// String returns a short name for the collision type, like "wall"
+func (t CollisionType) String() string {
+	return game.CollisionType(t).String()
+}
+
+// Position is a board cell, with 0,0 the top left
+type Position struct {
+	X, Y int
+}
+
+// Config describes a game
+type Config struct {
+	Width, Height int    // Board size in cells; a map sets its own
+	Food          int    // Pellets kept on the board, 0 means 1
+	MaxTurns      int    // Turns after which the game ends, won by score then length; 0 for no limit
+	RepeatLimit   int    // Times a position may occur before the game ends in a draw; 0 for no limit
+	Rules         string // Ruleset, one of Rulesets; empty for the standard rules
+	Map           string // Bundled map, one of Maps, or a map file; empty for an open board
+
+	FoodSpawn FoodSpawn
+	FoodValue FoodValue
+	Respawn   Respawn
+}
+
+// FoodSpawn controls where food appears and how long it lasts
+type FoodSpawn struct {
+	Pattern       string // "random" (default) or "cluster"
+	ClusterRadius int    // Manhattan radius of a cluster
+	Lifetime      int    // Turns before an uneaten pellet expires, 0 for never
+	RelocateEvery int    // Move all pellets every N turns, 0 for never
+}
+
+// FoodValue sets what pellets score and grow snakes by
+type FoodValue struct {
+	Points       int     // Score per pellet; 0 means 1
+	Growth       int     // Segments a pellet grows a snake by; 0 means 1
+	GoldenChance float64 // Chance a new pellet is golden, 0 for never
+	GoldenPoints int     // Score per golden pellet; 0 means 5
+	GoldenGrowth int     // Segments a golden pellet grows a snake by; 0 means Growth
+}
+
+// Respawn, when enabled, brings dead snakes back and plays the match to a
+// score target instead of ending it on the first death
+type Respawn struct {
+	Enabled     bool
+	Delay       int // Turns a dead snake sits out
+	ScoreTarget int // Score that wins the match; 0 plays on until MaxTurns
+}
+
+// Rewards are the values Step hands a snake. Reward shaping toward food
+// comes on top of them, as the bundled models were trained with.
+type Rewards struct {
+	Death    float64 // On the turn the snake dies
+	Survival float64 // Every turn it stays alive
+	Food     float64 // When it eats
+	Win      float64 // When the other snake dies and it survives
+}
+
+// DefaultConfig returns the standard 20x20 game with one pellet
+func DefaultConfig() Config {
+	cfg := config.DefaultGameConfig()
+	return Config{Width: cfg.BoardWidth, Height: cfg.BoardHeight, Food: cfg.FoodCount}
+}
+
+// DefaultRewards returns the reward values games start with
+func DefaultRewards() Rewards {
+	r := config.DefaultRewardConfig()
+	return Rewards{Death: r.Death, Survival: r.Survival, Food: r.Food, Win: r.Win}
+}
+
+// engine returns the engine's configuration for c
+func (c Config) engine() config.GameConfig {
+	cfg := config.DefaultGameConfig()
+	cfg.BoardWidth, cfg.BoardHeight = c.Width, c.Height
+	cfg.FoodCount = c.Food
+	cfg.MaxTurns = c.MaxTurns
+	cfg.RepeatLimit = c.RepeatLimit
+	cfg.Rules = c.Rules
+	cfg.Map = c.Map
+	cfg.Spawn = config.FoodSpawnConfig(c.FoodSpawn)
+	cfg.Value = config.FoodValueConfig(c.FoodValue)
+	cfg.Respawn = config.RespawnConfig(c.Respawn)
+	return cfg
+}
+
+// Snake is one snake of a State
+type Snake struct {
+	Body      []Position // Head first
+	Direction Direction
+	Alive     bool
+	Score     int
+}
+
+// State is a snapshot of a game, taken by Game.State; later steps don't
+// change it
+type State struct {
+	Width, Height int
+	Turn          int
+	Snakes        [2]Snake
+	Food          []Position
+	Obstacles     []Position // Wall cells a map puts inside the board
+	GameOver      bool
+	Winner        int // 0 or 1, or -1 for a tie or a game in play
+
+	// engine is the engine's state the snapshot was taken of, which
+	// Observe and the bundled agents read
+	engine *game.GameState
+}
+
+// newState takes a snapshot of the engine's state s
+func newState(s *game.GameState) *State {
+	s = s.Clone()
+	state := &State{
+		Width:     s.Width,
+		Height:    s.Height,
+		Turn:      s.Turn,
+		Food:      positions(s.FoodPositions()),
+		Obstacles: positions(s.Obstacles),
+		GameOver:  s.GameOver,
+		Winner:    s.Winner,
+		engine:    s,
+	}
+	for i, snake := range s.Snakes {
+		state.Snakes[i] = Snake{
+			Body:      positions(snake.Body),
+			Direction: Direction(snake.Direction),
+			Alive:     snake.Alive,
+			Score:     snake.Score,
+		}
+	}
+	return state
+}
+
+// positions copies engine positions
+func positions(in []game.Position) []Position {
+	out := make([]Position, len(in))
+	for i, p := range in {
+		out[i] = Position(p)
+	}
+	return out
+}
+
+// StepResult is what happened on one turn
+type StepResult struct {
+	Rewards    [2]float64
+	AteFood    [2]bool
+	Died       [2]bool
+	DeathCause [2]CollisionType // What each snake that died ran into
+	GameOver   bool
+	Winner     int // 0 or 1, or -1 for a tie or a game in play
+}
+
+// Game is a running match, see New
+type Game struct {
+	game *game.Game
+}
+
+// New creates a game from cfg, seeded so the same seed and moves replay
+// the same game. It returns an error for an unknown ruleset or map and for
+// invalid food settings.
+func New(cfg Config, seed int64) (*Game, error) {
+	engine := cfg.engine()
+	if _, err := game.LookupRules(engine.Rules); err != nil {
+		return nil, err
+	}
+	if engine.Map != "" {
+		if _, err := game.LoadMap(engine.Map); err != nil {
+			return nil, err
+		}
+	}
+	if err := engine.Spawn.Validate(); err != nil {
+		return nil, err
+	}
+	if err := engine.Value.Validate(); err != nil {
+		return nil, err
+	}
+	return &Game{game.NewGame(engine, seed)}, nil
+}
+
+// Step moves both snakes, snake i in direction moves[i], and returns the
+// turn's result. A move straight back into a snake's neck keeps it going
+// its current way; dead snakes' moves are ignored.
+func (g *Game) Step(moves [2]Direction) StepResult {
+	r := g.game.Step([2]game.Direction{game.Direction(moves[0]), game.Direction(moves[1])})
+	result := StepResult{
+		Rewards:  r.Rewards,
+		AteFood:  r.AteFood,
+		Died:     r.Died,
+		GameOver: r.GameOver,
+		Winner:   r.Winner,
+	}
+	for i := range result.DeathCause {
+		result.DeathCause[i] = CollisionType(r.DeathCause(i))
+	}
+	return result
+}
+
+// State returns a snapshot of the game as it stands
+func (g *Game) State() *State {
+	return newState(g.game.State)
+}
+
+// Over reports whether the game has ended
+func (g *Game) Over() bool {
+	return g.game.State.GameOver
+}
+
+// Reset starts the game over on the same board, drawing food from where
+// its random source left off
+func (g *Game) Reset() {
+	g.game.Reset()
+}
+
+// SetRewards sets the reward values Step hands snake snakeID
+func (g *Game) SetRewards(snakeID int, r Rewards) {
+	rewards := &g.game.Rewards[snakeID]
+	rewards.Death, rewards.Survival, rewards.Food, rewards.Win = r.Death, r.Survival, r.Food, r.Win
+}
+
+// Rulesets returns the names Config.Rules accepts
+func Rulesets() []string {
+	return game.RulesNames()
+}
+
+// Maps returns the names of the bundled board maps Config.Map accepts
+// besides map file paths
+func Maps() []string {
+	return game.MapNames()
+}
+
+// ObservationSize is the length of an Observe vector
+const ObservationSize = ai.StateSize
+
+// Observe encodes state from snake snakeID's view as the ObservationSize
+// features the bundled models are trained on: dangers around the head,
+// its direction, where the food and the other snake are, and both
+// lengths. A dead snake sees all zeros. state must come from Game.State.
+func Observe(state *State, snakeID int) []float64 {
+	return ai.EncodeState(state.engine, snakeID)
+}
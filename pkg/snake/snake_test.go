@@ -0,0 +1,102 @@
+package snake
+
+import (
+	"testing"
+
+	"autonomous-snake/internal/game"
+)
+
+func TestPlay(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxTurns = 200
+	g, err := New(cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(Observe(g.State(), 0)); n != ObservationSize {
+		t.Errorf("observation has %d features, want %d", n, ObservationSize)
+	}
+
+	up := AgentFunc(func(*State, int) Direction { return Up })
+	result := Play(g, [2]Agent{RandomAgent(1), up})
+	state := g.State()
+	if !result.GameOver || !g.Over() || !state.GameOver || state.Turn > cfg.MaxTurns {
+		t.Errorf("game not over after Play, turn %d", state.Turn)
+	}
+	if state.Turn > state.Height/2+1 {
+		t.Errorf("snake 1 outlasted its run into the wall, turn %d", state.Turn)
+	}
+}
+
+func TestStep(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Width, cfg.Height = 10, 10
+	g, err := New(cfg, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetRewards(1, Rewards{Death: -7})
+	before := g.State()
+	head := before.Snakes[0].Body[0]
+
+	// Snake 0 starts on the left facing right, so Left keeps it going
+	// right, while snake 1 runs up into the wall
+	var result StepResult
+	for turns := 0; !result.GameOver && turns < cfg.Height; turns++ {
+		result = g.Step([2]Direction{Left, Up})
+	}
+	if !result.GameOver || !result.Died[1] || result.DeathCause[1] != WallCollision || result.Winner != 0 {
+		t.Errorf("result %+v, want snake 1 dead on the wall and snake 0 the winner", result)
+	}
+	if result.DeathCause[0] != NoCollision || result.Rewards[0] <= 0 || result.Rewards[1] != -7 {
+		t.Errorf("cause %v, rewards %v; want none, a positive reward and snake 1's death reward -7", result.DeathCause[0], result.Rewards)
+	}
+
+	// A snapshot doesn't follow the game
+	if before.Turn != 0 || before.Snakes[0].Body[0] != head || !before.Snakes[0].Alive {
+		t.Errorf("snapshot changed: turn %d, head %v", before.Turn, before.Snakes[0].Body[0])
+	}
+	after := g.State()
+	if after.Snakes[1].Alive || after.Winner != 0 || after.Snakes[0].Body[0] == head {
+		t.Errorf("state after the game: %+v", after)
+	}
+
+	g.Reset()
+	if g.Over() || g.State().Turn != 0 {
+		t.Error("game still over after Reset")
+	}
+}
+
+func TestEngineValues(t *testing.T) {
+	// The package's enumerations convert to the engine's by value
+	for d, want := range map[Direction]game.Direction{Up: game.Up, Down: game.Down, Left: game.Left, Right: game.Right} {
+		if game.Direction(d) != want {
+			t.Errorf("direction %d is the engine's %d", d, want)
+		}
+	}
+	for c, want := range map[CollisionType]game.CollisionType{
+		NoCollision: game.NoCollision, WallCollision: game.WallCollision, SelfCollision: game.SelfCollision,
+		OtherSnakeCollision: game.OtherSnakeCollision, HeadToHeadCollision: game.HeadToHeadCollision,
+	} {
+		if game.CollisionType(c) != want {
+			t.Errorf("collision %v is the engine's %v", c, want)
+		}
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	for name, edit := range map[string]func(*Config){
+		"rules": func(c *Config) { c.Rules = "nonsense" },
+		"map":   func(c *Config) { c.Map = "nonsense" },
+		"food":  func(c *Config) { c.FoodSpawn.Pattern = "nonsense" },
+	} {
+		cfg := DefaultConfig()
+		edit(&cfg)
+		if _, err := New(cfg, 1); err == nil {
+			t.Errorf("bad %s accepted", name)
+		}
+	}
+	if _, err := LoadAgent("testdata/missing.gob"); err == nil {
+		t.Error("missing model loaded")
+	}
+}